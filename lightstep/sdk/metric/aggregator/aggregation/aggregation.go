@@ -53,6 +53,16 @@ type (
 		Gauge() number.Number
 	}
 
+	// GaugeCount is a Gauge that counts the observations of its
+	// value, see aggregator.Config.GaugeCounts.
+	GaugeCount interface {
+		Gauge
+
+		// Count returns the number of observations, or zero
+		// when they are not counted.
+		Count() uint64
+	}
+
 	// Histogram returns the count of events in exponential-scale
 	// buckets defined as a function of a scale parameter.  See a
 	// detailed explanation in the OpenTelemetry metrics data
@@ -88,6 +98,17 @@ type (
 		Min() number.Number
 		Max() number.Number
 	}
+
+	// ExplicitHistogram returns the count of events in buckets
+	// with fixed boundaries.  Bucket i counts the values in
+	// (Boundaries()[i-1], Boundaries()[i]], where the first
+	// bucket is unbounded below and the last bucket, one more
+	// than the boundaries, is unbounded above.
+	ExplicitHistogram interface {
+		MinMaxSumCount
+		Boundaries() []float64
+		BucketCounts() []uint64
+	}
 )
 
 // Category constants describe semantic kind.  For the histogram
@@ -114,6 +135,19 @@ const (
 	GaugeKind
 	HistogramKind
 	MinMaxSumCountKind
+
+	// CustomKind is a user-defined aggregation, configured
+	// through aggregator.Config.Custom.  Custom aggregations have
+	// no Category; they are permitted with every instrument kind.
+	CustomKind
+
+	// ExplicitHistogramKind is a histogram with fixed bucket
+	// boundaries, configured through aggregator.Config.Explicit.
+	ExplicitHistogramKind
+
+	// CountKind counts the measurements of a histogram without
+	// their values, reported as a monotonic sum.
+	CountKind
 )
 
 func (k Kind) Category(ik sdkinstrument.Kind) Category {
@@ -132,7 +166,7 @@ func (k Kind) Category(ik sdkinstrument.Kind) Category {
 		return NonMonotonicSumCategory
 	case GaugeKind:
 		return GaugeCategory
-	case HistogramKind, MinMaxSumCountKind:
+	case HistogramKind, MinMaxSumCountKind, ExplicitHistogramKind, CountKind:
 		return HistogramCategory
 	default:
 		return UndefinedCategory
@@ -148,7 +182,8 @@ func (k Kind) Valid() bool {
 	switch k {
 	case UndefinedKind, DropKind, AnySumKind,
 		MonotonicSumKind, NonMonotonicSumKind,
-		GaugeKind, HistogramKind, MinMaxSumCountKind:
+		GaugeKind, HistogramKind, MinMaxSumCountKind,
+		CustomKind, ExplicitHistogramKind, CountKind:
 		return true
 	}
 	return false
@@ -172,6 +207,8 @@ func ParseKind(str string) (Kind, bool) {
 		return HistogramKind, true
 	case "minmaxsumcount":
 		return MinMaxSumCountKind, true
+	case "count":
+		return CountKind, true
 	}
 	return UndefinedKind, false
 }
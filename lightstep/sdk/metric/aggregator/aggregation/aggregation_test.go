@@ -35,6 +35,7 @@ func TestParseAggregation(t *testing.T) {
 		{"exponential_histogram", HistogramKind, true},
 		{"histogram", HistogramKind, true},
 		{"minmaxsumcount", MinMaxSumCountKind, true},
+		{"count", CountKind, true},
 		{"otherthing", UndefinedKind, false},
 	} {
 		k, ok := ParseKind(test.input)
@@ -37,11 +37,14 @@ func _() {
 	_ = x[GaugeKind-5]
 	_ = x[HistogramKind-6]
 	_ = x[MinMaxSumCountKind-7]
+	_ = x[CustomKind-8]
+	_ = x[ExplicitHistogramKind-9]
+	_ = x[CountKind-10]
 }
 
-const _Kind_name = "UndefinedKindDropKindAnySumKindMonotonicSumKindNonMonotonicSumKindGaugeKindHistogramKindMinMaxSumCountKind"
+const _Kind_name = "UndefinedKindDropKindAnySumKindMonotonicSumKindNonMonotonicSumKindGaugeKindHistogramKindMinMaxSumCountKindCustomKindExplicitHistogramKindCountKind"
 
-var _Kind_index = [...]uint8{0, 13, 21, 31, 47, 66, 75, 88, 106}
+var _Kind_index = [...]uint8{0, 13, 21, 31, 47, 66, 75, 88, 106, 116, 137, 146}
 
 func (i Kind) String() string {
 	if i < 0 || i >= Kind(len(_Kind_index)-1) {
@@ -16,8 +16,14 @@ package aggregator // import "github.com/lightstep/otel-launcher-go/lightstep/sd
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/lightstep/go-expohisto/mapping/exponent"
+	"github.com/lightstep/go-expohisto/mapping/logarithm"
 	histostruct "github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
@@ -26,6 +32,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
 )
 
 // Sentinel errors for Aggregator interface.
@@ -33,8 +40,90 @@ var (
 	ErrNegativeInput = fmt.Errorf("negative value is out of range for this instrument")
 	ErrNaNInput      = fmt.Errorf("NaN value is an invalid input")
 	ErrInfInput      = fmt.Errorf("±Inf value is an invalid input")
+
+	// ErrCardinalityLimitExceeded is reported when a new attribute
+	// set is dropped because the view disables overflow.
+	ErrCardinalityLimitExceeded = fmt.Errorf("cardinality limit exceeded with overflow disabled")
+
+	// ErrSumOverflow is reported when an integer sum saturates
+	// because of SumOverflowSaturate.
+	ErrSumOverflow = fmt.Errorf("integer sum overflow, saturated")
+
+	// ErrMergeFailed is returned when an accumulator's snapshot
+	// could not be merged into the instrument's output, meaning
+	// the measurements of the snapshot were lost.
+	ErrMergeFailed = fmt.Errorf("accumulator merge failed, data lost")
+
+	// ErrAggregationFailed is reported when an aggregation
+	// panics and its series switches to the fallback
+	// aggregation.
+	ErrAggregationFailed = fmt.Errorf("aggregation failed, using fallback")
+
+	// ErrAggregationKindMismatch is reported when an aggregation
+	// output by an instrument is not of its storage type, which
+	// means the Methods' ToAggregation and ToStorage disagree.
+	ErrAggregationKindMismatch = fmt.Errorf("aggregation does not match the storage type")
+)
+
+// SumOverflowPolicy determines what happens when an integer sum
+// would exceed the range of int64.
+type SumOverflowPolicy int
+
+const (
+	// SumOverflowWrap is the default, in which the sum wraps
+	// around using two's complement arithmetic.
+	SumOverflowWrap SumOverflowPolicy = iota
+
+	// SumOverflowSaturate keeps the sum at the maximum (or
+	// minimum) int64 value and reports ErrSumOverflow through
+	// otel.Handle.
+	SumOverflowSaturate
+
+	// SumOverflowPromote keeps the excess as a float64, so that
+	// the sum is exported as a floating point value once it
+	// leaves the range of int64.  The exported value preserves
+	// magnitude with the 53-bit precision of float64.
+	SumOverflowPromote
+)
+
+// NonFinitePolicy determines how Float64 sum and histogram
+// aggregations treat NaN and ±Inf values passed to Update.  The API
+// rejects these values before they reach an aggregator, but the
+// aggregators are also used directly, for example in stages.
+type NonFinitePolicy int
+
+const (
+	// NonFinitePassThrough is the default, in which non-finite
+	// values are aggregated.
+	NonFinitePassThrough NonFinitePolicy = iota
+
+	// NonFiniteDrop ignores non-finite values.
+	NonFiniteDrop
+
+	// NonFiniteDropAndCount ignores non-finite values and counts
+	// them, returned by the aggregation's Dropped() method.
+	NonFiniteDropAndCount
 )
 
+// RejectNonFinite returns true when the policy rejects the value,
+// counting it in dropped for NonFiniteDropAndCount.
+func RejectNonFinite[N number.Any, Traits number.Traits[N]](policy NonFinitePolicy, value N, dropped *uint64) bool {
+	return RejectNonFiniteCount[N, Traits](policy, value, 1, dropped)
+}
+
+// RejectNonFiniteCount is RejectNonFinite for count measurements of
+// the same value, which are counted as count drops.
+func RejectNonFiniteCount[N number.Any, Traits number.Traits[N]](policy NonFinitePolicy, value N, count uint64, dropped *uint64) bool {
+	var traits Traits
+	if policy == NonFinitePassThrough || !(traits.IsNaN(value) || traits.IsInf(value)) {
+		return false
+	}
+	if policy == NonFiniteDropAndCount {
+		atomic.AddUint64(dropped, count)
+	}
+	return true
+}
+
 // ExemplarFilterKind determines which events are eligible for
 // becoming exemplars.
 type ExemplarFilterKind int
@@ -53,10 +142,40 @@ const (
 	WhenTracedKind
 )
 
+// ReservoirKind determines which algorithm selects exemplars from
+// the eligible events.
+type ReservoirKind int
+
+const (
+	// DefaultReservoirKind keeps the last exemplar when the
+	// reservoir size is 1, otherwise it uses weighted sampling.
+	DefaultReservoirKind ReservoirKind = iota
+
+	// UniformReservoirKind selects a uniform random sample of
+	// the events.
+	UniformReservoirKind
+
+	// MaxReservoirKind keeps the events with the largest values,
+	// suitable for latency outliers.
+	MaxReservoirKind
+
+	// BucketReservoirKind keeps the last event in each
+	// power-of-two bucket, suitable for histograms.
+	BucketReservoirKind
+
+	// ScaledBucketReservoirKind keeps the last event in each
+	// bucket of an exponential histogram at its current scale.
+	ScaledBucketReservoirKind
+)
+
 // DefaultExemplarReservoirSize determines how many exemplars will be
 // selected per instrument.
 const DefaultExemplarReservoirSize = 10
 
+// DefaultExemplarMaxLinks determines how many trace references will
+// be kept per exemplar.
+const DefaultExemplarMaxLinks = 8
+
 // RangeTest is a common routine for testing for valid input values.
 // This rejects NaN and Inf values.  This rejects negative values when the
 // aggregation does not support negative values, including
@@ -98,12 +217,19 @@ type ExemplarConfig struct {
 	Filter ExemplarFilterKind
 	// Size determines limits how many exemplars per timeseries.
 	Size uint32
+	// MaxLinks limits how many trace references are kept per
+	// exemplar, beyond the span of the measurement context.
+	MaxLinks uint32
+	// Reservoir determines the selection algorithm.
+	Reservoir ReservoirKind
 }
 
 // JSONExemplarConfig configures exemplar selection.
 type JSONExemplarConfig struct {
-	Filter string `json:"filter"`
-	Size   uint32 `json:"size"`
+	Filter    string `json:"filter"`
+	Size      uint32 `json:"size"`
+	MaxLinks  uint32 `json:"max_links"`
+	Reservoir string `json:"reservoir"`
 }
 
 // JSONHistogramConfig configures the exponential histogram.
@@ -127,8 +253,154 @@ type Config struct {
 	// aggregator in a given view.
 	CardinalityLimit uint32
 
+	// DisableOverflow changes the behavior at CardinalityLimit.
+	// Instead of folding new attribute sets into the overflow
+	// attribute set, new attribute sets are dropped and
+	// ErrCardinalityLimitExceeded is reported through
+	// otel.Handle.  This is meant for instruments that are
+	// expected never to reach the limit.
+	DisableOverflow bool
+
+	// DropOverflow is like DisableOverflow, except that nothing
+	// is reported when new attribute sets are dropped.  This is
+	// meant for instruments that are expected to reach the limit,
+	// when an overflow series would confuse the destination.  In
+	// both cases, the dropped measurements are counted in the
+	// DroppedMeasurements field of the instrument's output.
+	DropOverflow bool
+
 	// ExemplarFilter enables or disables exemplars
 	Exemplar ExemplarConfig
+
+	// HistogramExtremes enables recording the time (and exemplar
+	// information, if sampled) of the minimum and maximum
+	// observations of a histogram aggregation.  They are
+	// returned as exemplars and through the histogram's MinTime()
+	// and MaxTime() methods.
+	HistogramExtremes bool
+
+	// GaugeTimestamps enables recording the time of each gauge
+	// observation, taken from ExemplarBits.Time or else the time
+	// of the update.  The value with the latest time is kept,
+	// regardless of the order in which updates and merges are
+	// applied.  The time is returned through the gauge's Time()
+	// method and data.Point.Observed.
+	GaugeTimestamps bool
+
+	// GaugeCounts enables counting the observations of each
+	// gauge since its storage was reset, which is each collection
+	// interval for asynchronous gauges and for synchronous gauges
+	// with delta temporality.  Merged gauges sum their counts.
+	// The count is returned through the gauge's Count() method
+	// and data.Point.Observations.
+	GaugeCounts bool
+
+	// HistogramWeighted enables a second histogram, in which each
+	// observation counts its ExemplarBits.SecondaryWeight instead
+	// of one.  It is returned through the histogram's Weighted()
+	// method.
+	HistogramWeighted bool
+
+	// HistogramSparse stores only the populated buckets of a
+	// histogram, instead of a counter for every bucket between
+	// the lowest and highest populated index.  This saves memory
+	// when observations are few and widely spread, at some cost
+	// per update.  The exported result is the same.
+	HistogramSparse bool
+
+	// HistogramCarryScale starts each interval of a sparse
+	// histogram at the scale reached by the previous one, instead
+	// of the maximum scale, so that the first observations of a
+	// delta interval are not placed at a fine scale that is
+	// repeatedly reduced.  The scale is never increased again, so
+	// a distribution that narrows keeps the coarser resolution
+	// until the series is reset.  This has no effect without
+	// HistogramSparse, since the dense structure restarts at the
+	// maximum scale.
+	HistogramCarryScale bool
+
+	// HistogramZeroThreshold is the magnitude at or below which
+	// histogram observations are counted in the zero bucket,
+	// instead of the bucket of the smallest positive or negative
+	// values, which would lower the scale of the histogram.  The
+	// sum, minimum, and maximum include these observations
+	// exactly.  It must be finite and not negative; zero counts
+	// only exact zeros there.
+	HistogramZeroThreshold float64
+
+	// HistogramLimitScale enables HistogramMaxScale, which is
+	// otherwise ignored, because zero is a valid scale.
+	HistogramLimitScale bool
+
+	// HistogramMaxScale is the largest scale of a histogram
+	// aggregation, used in place of the maximum scale supported
+	// when HistogramLimitScale is set.  The histogram starts at
+	// this scale and is reduced from it as usual, when the
+	// observations exceed the maximum size.  The limit is
+	// applied by the sparse storage, which is used regardless of
+	// HistogramSparse.  It must lie between the minimum and
+	// maximum scale of the exponential mapping.
+	HistogramMaxScale int32
+
+	// SumOverflow determines the behavior of Int64 sum
+	// aggregations at the limits of the int64 range.  It has no
+	// effect on Float64 sums.
+	SumOverflow SumOverflowPolicy
+
+	// NonFinite determines whether Float64 sum and histogram
+	// aggregations accept NaN and ±Inf values.
+	NonFinite NonFinitePolicy
+
+	// SumMinMax enables tracking the minimum and maximum of the
+	// individual values added to a sum aggregation, returned by
+	// the sum's MinMax() method.  The storage is only allocated
+	// when this is set.
+	SumMinMax bool
+
+	// Custom configures a user-defined aggregation, used when
+	// the aggregation Kind is aggregation.CustomKind.  This is a
+	// pointer so that Config remains comparable.
+	Custom *CustomConfig
+
+	// Explicit configures the bucket boundaries of an explicit
+	// histogram, used when the aggregation Kind is
+	// aggregation.ExplicitHistogramKind.  This is a pointer so
+	// that Config remains comparable.
+	Explicit *ExplicitConfig
+}
+
+// ExplicitConfig configures an explicit histogram.
+type ExplicitConfig struct {
+	// Boundaries are the upper-inclusive bounds of the buckets,
+	// in increasing order.  A final bucket counts the values
+	// greater than every boundary, so that an empty list has a
+	// single bucket.
+	Boundaries []float64
+}
+
+// Validate returns a valid configuration along with an error if the
+// boundaries were not finite and strictly increasing.  The valid
+// boundaries are sorted, without repeated or non-finite values.
+func (c ExplicitConfig) Validate() (ExplicitConfig, error) {
+	valid := true
+	for i, b := range c.Boundaries {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= c.Boundaries[i-1]) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return c, nil
+	}
+	bounds := make([]float64, 0, len(c.Boundaries))
+	for _, b := range c.Boundaries {
+		if !math.IsNaN(b) && !math.IsInf(b, 0) {
+			bounds = append(bounds, b)
+		}
+	}
+	sort.Float64s(bounds)
+	bounds = slices.Compact(bounds)
+	return ExplicitConfig{Boundaries: bounds}, fmt.Errorf("explicit histogram boundaries are not finite and increasing: %v", c.Boundaries)
 }
 
 // Valid returns true for valid configurations.
@@ -143,6 +415,16 @@ func (c Config) Validate() (Config, error) {
 	var err error
 	c.Histogram, err = c.Histogram.Validate()
 
+	if t := c.HistogramZeroThreshold; math.IsNaN(t) || math.IsInf(t, 0) || t < 0 {
+		err = multierr.Append(err, fmt.Errorf("invalid histogram zero threshold: %v", t))
+		c.HistogramZeroThreshold = 0
+	}
+
+	if s := c.HistogramMaxScale; c.HistogramLimitScale && (s < exponent.MinScale || s > logarithm.MaxScale) {
+		err = multierr.Append(err, fmt.Errorf("invalid histogram max scale: %v", s))
+		c.HistogramMaxScale = min(max(s, exponent.MinScale), logarithm.MaxScale)
+	}
+
 	if c.CardinalityLimit == 0 {
 		c.CardinalityLimit = sdkinstrument.DefaultAggregatorCardinalityLimit
 	}
@@ -184,6 +466,12 @@ type Methods[N number.Any, Storage any] interface {
 	// concurrent Move(), Copy(), and Update() operations.
 	Update(ptr *Storage, number N, ex ExemplarBits)
 
+	// UpdateBatch captures count measurements of the same
+	// number, with the effect of count calls to Update.  An
+	// exemplar considers the batch as one event.  Synchronized
+	// as Update().
+	UpdateBatch(ptr *Storage, number N, count uint64, ex ExemplarBits)
+
 	// Move atomically copies `input` to `output` and resets
 	// `input` to the zero state.  The change to `input` is
 	// synchronized against concurrent `Update()` and `Merge()`
@@ -224,6 +512,12 @@ type Methods[N number.Any, Storage any] interface {
 	// be called after subtraction.  Not synchronized.
 	HasChange(ptr *Storage) bool
 
+	// SizeOf estimates the memory used by Storage in bytes,
+	// including the memory it references, such as histogram
+	// buckets and exemplars.  The read of Storage is
+	// synchronized with concurrent Update() and Merge() calls.
+	SizeOf(ptr *Storage) int
+
 	// Exemplars returns sample points included in this aggregation.
 	Exemplars(ptr *Storage, in []WeightedExemplarBits) []WeightedExemplarBits
 
@@ -236,6 +530,10 @@ type Methods[N number.Any, Storage any] interface {
 // ConfigSelector is a per-instrument-kind, per-number-kind Config choice.
 type ConfigSelector func(sdkinstrument.Kind) (int64Config, float64Config Config)
 
+// HistogramUnitSelector is a per-unit histogram Config choice.  It
+// returns false for units that it does not recognize.
+type HistogramUnitSelector func(unit string) (histostruct.Config, bool)
+
 // ExemplarBits conducts extra information into the aggregation pipeline.
 //
 // Note: we could opt for an allocation instead of copying this struct
@@ -254,6 +552,23 @@ type ExemplarBits struct {
 
 	// Number is the input value.
 	Number number.Number
+
+	// Links are references to other traces that contributed to
+	// the measurement, for example the requests in a batch.
+	Links []trace.SpanContext
+
+	// DroppedLinks counts the Links that were dropped because
+	// of the ExemplarConfig.MaxLinks limit.
+	DroppedLinks uint32
+
+	// SecondaryWeight is the weight of the measurement in a
+	// histogram configured with HistogramWeighted, when
+	// HasSecondaryWeight is set.  Synchronous instruments set
+	// these for every measurement whose context carries a weight,
+	// whether or not it is sampled.  Other measurements have a
+	// weight of one.
+	SecondaryWeight    uint64
+	HasSecondaryWeight bool
 }
 
 // WeightedExemplarBits are the exemplar and its calculated sample weight.
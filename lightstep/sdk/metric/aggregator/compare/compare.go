@@ -0,0 +1,226 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compare quantifies the divergence between two aggregations
+// of the same measurements, for example a view's primary and shadow
+// aggregations, to support choosing between aggregations.
+package compare // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/compare"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrIncomparable is returned for an aggregation without a
+	// count, sum, min and max.
+	ErrIncomparable = errors.New("aggregation is not comparable")
+
+	// ErrMissingPoint is returned when the instruments compared
+	// do not have points with the same attributes.
+	ErrMissingPoint = errors.New("point is missing from the comparison")
+)
+
+// Divergence describes how far a shadow aggregation is from the
+// primary aggregation of the same measurements.  Errors are relative
+// to the primary value; they are zero when both values are zero and
+// +Inf when only the primary value is zero.
+type Divergence struct {
+	// Count is the shadow count minus the primary count.
+	Count int64
+
+	// Sum is the relative error of the shadow sum.
+	Sum float64
+
+	// Min is the relative error of the shadow minimum.
+	Min float64
+
+	// Max is the relative error of the shadow maximum.
+	Max float64
+
+	// Quantiles is the relative error of the shadow's estimate at
+	// each quantile requested, NaN when either aggregation cannot
+	// estimate the quantile.
+	Quantiles []float64
+}
+
+// MaxQuantileError returns the largest of the quantile errors, or
+// zero when none was computed.  NaN errors are ignored.
+func (d Divergence) MaxQuantileError() float64 {
+	var m float64
+	for _, e := range d.Quantiles {
+		if !math.IsNaN(e) {
+			m = max(m, e)
+		}
+	}
+	return m
+}
+
+// summary is the state common to the histogram and MinMaxSumCount
+// aggregations.
+type summary interface {
+	aggregation.Aggregation
+	Count() uint64
+	aggregation.HasASum
+	Min() number.Number
+	Max() number.Number
+}
+
+// Aggregations returns the divergence of shadow from primary, both
+// holding numbers of kind nk.  Each aggregation must be a Histogram or
+// a MinMaxSumCount.  Quantiles are estimated from histogram buckets;
+// a MinMaxSumCount only estimates quantiles 0 and 1.
+func Aggregations(nk number.Kind, primary, shadow aggregation.Aggregation, quantiles ...float64) (Divergence, error) {
+	p, ok := unwrap(primary).(summary)
+	if !ok {
+		return Divergence{}, fmt.Errorf("%w: %T", ErrIncomparable, primary)
+	}
+	s, ok := unwrap(shadow).(summary)
+	if !ok {
+		return Divergence{}, fmt.Errorf("%w: %T", ErrIncomparable, shadow)
+	}
+	d := Divergence{
+		Count: int64(s.Count()) - int64(p.Count()),
+		Sum:   relativeError(toFloat64(nk, p.Sum()), toFloat64(nk, s.Sum())),
+	}
+	if p.Count() != 0 && s.Count() != 0 {
+		d.Min = relativeError(toFloat64(nk, p.Min()), toFloat64(nk, s.Min()))
+		d.Max = relativeError(toFloat64(nk, p.Max()), toFloat64(nk, s.Max()))
+	}
+	for _, q := range quantiles {
+		pq, pok := Quantile(nk, p, q)
+		sq, sok := Quantile(nk, s, q)
+		if !pok || !sok {
+			d.Quantiles = append(d.Quantiles, math.NaN())
+			continue
+		}
+		d.Quantiles = append(d.Quantiles, relativeError(pq, sq))
+	}
+	return d, nil
+}
+
+// Instruments returns the divergence of each point of shadow from the
+// point of primary with the same attributes, for example the outputs
+// of a view with a shadow aggregation in one collection.
+func Instruments(primary, shadow data.Instrument, quantiles ...float64) (map[attribute.Set]Divergence, error) {
+	if len(primary.Points) != len(shadow.Points) {
+		return nil, fmt.Errorf("%w: %d primary and %d shadow points",
+			ErrMissingPoint, len(primary.Points), len(shadow.Points))
+	}
+	shadows := make(map[attribute.Set]aggregation.Aggregation, len(shadow.Points))
+	for _, pt := range shadow.Points {
+		shadows[pt.Attributes] = pt.Aggregation
+	}
+	nk := primary.Descriptor.NumberKind
+	out := make(map[attribute.Set]Divergence, len(primary.Points))
+	for _, pt := range primary.Points {
+		sagg, ok := shadows[pt.Attributes]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingPoint, pt.Attributes.Encoded(attribute.DefaultEncoder()))
+		}
+		d, err := Aggregations(nk, pt.Aggregation, sagg, quantiles...)
+		if err != nil {
+			return nil, err
+		}
+		out[pt.Attributes] = d
+	}
+	return out, nil
+}
+
+// Quantile estimates quantile q of a Histogram or MinMaxSumCount
+// holding numbers of kind nk, returning false when it cannot.  The
+// histogram estimate is the log-scale midpoint of the bucket
+// containing the quantile, limited to the minimum and maximum.
+func Quantile(nk number.Kind, agg aggregation.Aggregation, q float64) (float64, bool) {
+	s, ok := unwrap(agg).(summary)
+	if !ok || s.Count() == 0 || q < 0 || q > 1 {
+		return 0, false
+	}
+	lo := toFloat64(nk, s.Min())
+	hi := toFloat64(nk, s.Max())
+	switch {
+	case q == 0:
+		return lo, true
+	case q == 1:
+		return hi, true
+	}
+	h, ok := s.(aggregation.Histogram)
+	if !ok {
+		return 0, false
+	}
+	rank := max(1, uint64(math.Ceil(q*float64(h.Count()))))
+	value, ok := bucketAt(h, rank)
+	if !ok {
+		return 0, false
+	}
+	return min(max(value, lo), hi), true
+}
+
+// bucketAt returns the midpoint of the bucket holding the value with
+// the 1-based rank, in increasing order of value.
+func bucketAt(h aggregation.Histogram, rank uint64) (float64, bool) {
+	var seen uint64
+	neg := h.Negative()
+	for i := neg.Len(); i > 0; i-- {
+		if seen += neg.At(i - 1); seen >= rank {
+			return -midpoint(h.Scale(), neg.Offset()+int32(i-1)), true
+		}
+	}
+	if seen += h.ZeroCount(); seen >= rank {
+		return 0, true
+	}
+	pos := h.Positive()
+	for i := uint32(0); i < pos.Len(); i++ {
+		if seen += pos.At(i); seen >= rank {
+			return midpoint(h.Scale(), pos.Offset()+int32(i)), true
+		}
+	}
+	return 0, false
+}
+
+// midpoint returns the log-scale midpoint of the bucket with index
+// at scale, which covers (base**index, base**(index+1)] where base is
+// 2**(2**-scale).
+func midpoint(scale, index int32) float64 {
+	return math.Exp2((float64(index) + 0.5) * math.Exp2(-float64(scale)))
+}
+
+// toFloat64 converts n of kind nk.
+func toFloat64(nk number.Kind, n number.Number) float64 {
+	if nk == number.Int64Kind {
+		return float64(number.ToInt64(n))
+	}
+	return number.ToFloat64(n)
+}
+
+func relativeError(primary, shadow float64) float64 {
+	if primary == shadow {
+		return 0
+	}
+	return math.Abs(shadow-primary) / math.Abs(primary)
+}
+
+func unwrap(agg aggregation.Aggregation) aggregation.Aggregation {
+	if unwr, ok := agg.(exemplar.Unwrapper); ok {
+		return unwr.Unwrap()
+	}
+	return agg
+}
@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// uniform returns the values 1 through n.
+func uniform(n int) []float64 {
+	var vals []float64
+	for i := 1; i <= n; i++ {
+		vals = append(vals, float64(i))
+	}
+	return vals
+}
+
+func TestQuantile(t *testing.T) {
+	h := histogram.NewFloat64(histogram.NewConfig(), uniform(1000)...)
+
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		est, ok := Quantile(number.Float64Kind, h, q)
+		require.True(t, ok)
+		require.InEpsilon(t, q*1000, est, 0.05, "quantile %v", q)
+	}
+
+	est, ok := Quantile(number.Float64Kind, h, 0)
+	require.True(t, ok)
+	require.Equal(t, 1.0, est)
+
+	est, ok = Quantile(number.Float64Kind, h, 1)
+	require.True(t, ok)
+	require.Equal(t, 1000.0, est)
+
+	neg := histogram.NewInt64(histogram.NewConfig(), -4, -2, 0, 0, 2)
+	est, ok = Quantile(number.Int64Kind, neg, 0.2)
+	require.True(t, ok)
+	require.InEpsilon(t, -4, est, 0.1)
+
+	est, ok = Quantile(number.Int64Kind, neg, 0.5)
+	require.True(t, ok)
+	require.Equal(t, 0.0, est)
+
+	// MinMaxSumCount only estimates the extremes.
+	mmsc := minmaxsumcount.NewFloat64(uniform(10)...)
+	_, ok = Quantile(number.Float64Kind, mmsc, 0.5)
+	require.False(t, ok)
+	est, ok = Quantile(number.Float64Kind, mmsc, 1)
+	require.True(t, ok)
+	require.Equal(t, 10.0, est)
+
+	_, ok = Quantile(number.Float64Kind, histogram.NewFloat64(histogram.NewConfig()), 0.5)
+	require.False(t, ok)
+}
+
+func TestAggregations(t *testing.T) {
+	vals := uniform(1000)
+	primary := histogram.NewFloat64(histogram.NewConfig(), vals...)
+	shadow := histogram.NewFloat64(histogram.NewConfig(histogram.WithMaxSize(4)), vals...)
+
+	d, err := Aggregations(number.Float64Kind, primary, primary, 0.5, 0.9)
+	require.NoError(t, err)
+	require.Equal(t, Divergence{Quantiles: []float64{0, 0}}, d)
+
+	d, err = Aggregations(number.Float64Kind, primary, shadow, 0.5, 0.9)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), d.Count)
+	require.Equal(t, 0.0, d.Sum)
+	require.Equal(t, 0.0, d.Min)
+	require.Equal(t, 0.0, d.Max)
+	require.Len(t, d.Quantiles, 2)
+	require.Greater(t, d.MaxQuantileError(), 0.1)
+
+	d, err = Aggregations(number.Float64Kind, primary, minmaxsumcount.NewFloat64(vals[1:]...), 0.5, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), d.Count)
+	require.InEpsilon(t, 1.0/500500, d.Sum, 1e-9)
+	require.Equal(t, 1.0, d.Min)
+	require.True(t, math.IsNaN(d.Quantiles[0]))
+	require.Equal(t, 0.0, d.Quantiles[1])
+	require.Equal(t, 0.0, d.MaxQuantileError())
+
+	_, err = Aggregations(number.Float64Kind, primary, sum.NewMonotonicFloat64(1))
+	require.ErrorIs(t, err, ErrIncomparable)
+}
+
+func TestInstruments(t *testing.T) {
+	point := func(attrs attribute.Set, vals ...float64) data.Point {
+		return data.Point{
+			Attributes:  attrs,
+			Aggregation: histogram.NewFloat64(histogram.NewConfig(), vals...),
+		}
+	}
+	a := attribute.NewSet(attribute.String("k", "a"))
+	b := attribute.NewSet(attribute.String("k", "b"))
+
+	primary := data.Instrument{Points: []data.Point{point(a, 1, 2), point(b, 3)}}
+	shadow := data.Instrument{Points: []data.Point{point(b, 3), point(a, 1, 2)}}
+
+	divs, err := Instruments(primary, shadow, 0.5)
+	require.NoError(t, err)
+	require.Equal(t, map[attribute.Set]Divergence{
+		a: {Quantiles: []float64{0}},
+		b: {Quantiles: []float64{0}},
+	}, divs)
+
+	_, err = Instruments(primary, data.Instrument{Points: shadow.Points[:1]})
+	require.ErrorIs(t, err, ErrMissingPoint)
+
+	shadow.Points[0].Attributes = attribute.NewSet()
+	_, err = Instruments(primary, shadow)
+	require.ErrorIs(t, err, ErrMissingPoint)
+}
@@ -0,0 +1,60 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type measurementKey struct{}
+
+// MeasurementContext holds the values a context carries to the
+// measurements made with it, which are set by
+// exemplar.ContextWithLinks, exemplar.ContextWithTimestamp,
+// histogram.ContextWithWeight, and metric.ContextWithSeriesMetadata.
+// They share one context value, so that each measurement finds them
+// with a single lookup.
+type MeasurementContext struct {
+	// Links are references to other traces.
+	Links []trace.SpanContext
+
+	// Time is when the measurement happened, if not zero.
+	Time time.Time
+
+	// Weight is the secondary weight, when HasWeight is set.
+	Weight    uint64
+	HasWeight bool
+
+	// Metadata are the non-identifying attributes of the
+	// series that the measurement creates.
+	Metadata attribute.Set
+}
+
+// ContextWithMeasurement returns a context carrying m, replacing
+// any values carried by ctx.
+func ContextWithMeasurement(ctx context.Context, m MeasurementContext) context.Context {
+	return context.WithValue(ctx, measurementKey{}, m)
+}
+
+// MeasurementFromContext returns the values set by
+// ContextWithMeasurement, or the zero value.
+func MeasurementFromContext(ctx context.Context) MeasurementContext {
+	m, _ := ctx.Value(measurementKey{}).(MeasurementContext)
+	return m
+}
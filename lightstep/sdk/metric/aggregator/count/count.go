@@ -0,0 +1,149 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package count // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// Note: the count aggregator ignores the measured values, counting
+// only the number of measurements, for histogram instruments whose
+// distribution is not needed.  It reports a monotonic sum of the
+// count, expressed in the instrument's number kind.
+
+type (
+	Methods[N number.Any, Traits number.Traits[N]] struct{}
+
+	State[N number.Any, Traits number.Traits[N]] struct {
+		// count is the number of measurements.  This field
+		// uses atomic operations.
+		count uint64
+	}
+
+	Int64   = State[int64, number.Int64Traits]
+	Float64 = State[float64, number.Float64Traits]
+
+	Int64Methods   = Methods[int64, number.Int64Traits]
+	Float64Methods = Methods[float64, number.Float64Traits]
+)
+
+var (
+	_ aggregator.Methods[int64, Int64]     = Int64Methods{}
+	_ aggregator.Methods[float64, Float64] = Float64Methods{}
+
+	_ aggregation.Sum = &Int64{}
+	_ aggregation.Sum = &Float64{}
+)
+
+// NewInt64 returns an Int64 with the count.
+func NewInt64(count uint64) *Int64 {
+	return &Int64{count: count}
+}
+
+// NewFloat64 returns a Float64 with the count.
+func NewFloat64(count uint64) *Float64 {
+	return &Float64{count: count}
+}
+
+// Count returns the number of measurements.
+func (s *State[N, Traits]) Count() uint64 {
+	return atomic.LoadUint64(&s.count)
+}
+
+// Sum returns the count in the instrument's number kind.
+func (s *State[N, Traits]) Sum() number.Number {
+	var t Traits
+	return t.ToNumber(N(s.Count()))
+}
+
+func (s *State[N, Traits]) IsMonotonic() bool {
+	return true
+}
+
+func (s *State[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.CountKind
+}
+
+func (Methods[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.CountKind
+}
+
+func (Methods[N, Traits]) Init(state *State[N, Traits], _ aggregator.Config) {
+	// Note: storage is zero to start
+}
+
+func (Methods[N, Traits]) HasChange(ptr *State[N, Traits]) bool {
+	return ptr.Count() != 0
+}
+
+func (Methods[N, Traits]) SizeOf(ptr *State[N, Traits]) int {
+	return int(unsafe.Sizeof(*ptr))
+}
+
+// Move resets the source to zero, for delta collection.
+func (Methods[N, Traits]) Move(from, to *State[N, Traits]) {
+	to.count = atomic.SwapUint64(&from.count, 0)
+}
+
+func (Methods[N, Traits]) Copy(from, to *State[N, Traits]) {
+	to.count = atomic.LoadUint64(&from.count)
+}
+
+func (Methods[N, Traits]) Update(state *State[N, Traits], _ N, _ aggregator.ExemplarBits) {
+	atomic.AddUint64(&state.count, 1)
+}
+
+func (Methods[N, Traits]) UpdateBatch(state *State[N, Traits], _ N, count uint64, _ aggregator.ExemplarBits) {
+	if count != 0 {
+		atomic.AddUint64(&state.count, count)
+	}
+}
+
+func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
+	atomic.AddUint64(&to.count, atomic.LoadUint64(&from.count))
+}
+
+func (Methods[N, Traits]) ToAggregation(state *State[N, Traits]) aggregation.Aggregation {
+	return state
+}
+
+func (Methods[N, Traits]) ToStorage(aggr aggregation.Aggregation) (*State[N, Traits], bool) {
+	r, ok := aggr.(*State[N, Traits])
+	return r, ok
+}
+
+// SubtractSwap computes `*operand = *argument - *operand`, for
+// cumulative to delta translation.  A count that went backwards was
+// reset, in which case the difference is the later count.
+func (Methods[N, Traits]) SubtractSwap(operand, argument *State[N, Traits]) {
+	if argument.count >= operand.count {
+		operand.count = argument.count - operand.count
+	} else {
+		operand.count = argument.count
+	}
+}
+
+func (Methods[N, Traits]) Exemplars(ptr *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	return in
+}
+
+func (Methods[N, Traits]) Weight(n N) float64 {
+	return 1
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package count // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/stretchr/testify/require"
+)
+
+var nobits = aggregator.ExemplarBits{}
+
+func TestInt64Count(t *testing.T) {
+	test.GenericAggregatorTest[int64, Int64, Int64Methods](t, number.ToInt64)
+}
+
+func TestFloat64Count(t *testing.T) {
+	test.GenericAggregatorTest[float64, Float64, Float64Methods](t, number.ToFloat64)
+}
+
+func TestCountIgnoresValues(t *testing.T) {
+	var methods Float64Methods
+	var agg Float64
+	methods.Init(&agg, aggregator.Config{})
+
+	methods.Update(&agg, 1000, nobits)
+	methods.Update(&agg, -3, nobits)
+	methods.Update(&agg, math.NaN(), nobits)
+	methods.UpdateBatch(&agg, 0.5, 4, nobits)
+	methods.UpdateBatch(&agg, 7, 0, nobits)
+
+	require.Equal(t, uint64(7), agg.Count())
+	require.Equal(t, 7.0, number.ToFloat64(agg.Sum()))
+	require.True(t, agg.IsMonotonic())
+	require.True(t, methods.HasChange(&agg))
+}
+
+func TestMoveMergeSubtract(t *testing.T) {
+	var methods Int64Methods
+
+	// Move resets the source, for delta collection.
+	from := NewInt64(5)
+	var to Int64
+	methods.Move(from, &to)
+	require.Equal(t, uint64(0), from.Count())
+	require.Equal(t, uint64(5), to.Count())
+	require.False(t, methods.HasChange(from))
+
+	methods.Merge(NewInt64(3), &to)
+	require.Equal(t, int64(8), number.ToInt64(to.Sum()))
+
+	operand := NewInt64(3)
+	methods.SubtractSwap(operand, NewInt64(10))
+	require.Equal(t, uint64(7), operand.Count())
+
+	// A count that went backwards was reset.
+	operand = NewInt64(10)
+	methods.SubtractSwap(operand, NewInt64(4))
+	require.Equal(t, uint64(4), operand.Count())
+}
+
+func TestUpdateAllocs(t *testing.T) {
+	var methods Int64Methods
+	var agg Int64
+	methods.Init(&agg, aggregator.Config{})
+
+	require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		methods.Update(&agg, 1, nobits)
+		methods.UpdateBatch(&agg, 1, 2, nobits)
+	}))
+}
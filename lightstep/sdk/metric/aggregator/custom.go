@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// CustomAggregator is the state of one user-defined aggregation for
+// a single timeseries.  The SDK adapts CustomAggregator to the
+// Methods interface (see the aggregator/custom package), which means
+// the SDK is responsible for synchronization: none of these methods
+// are called concurrently on the same object, and implementations
+// need no locking of their own.
+//
+// The Methods contract translates as follows:
+//
+// Update: called once per measurement.  For asynchronous
+// instruments, called once per observation per collection with the
+// observed (cumulative) value.
+// Merge: adds the contents of `from` (an object returned by the same
+// factory) into the receiver.  Used to combine synchronous
+// accumulators into the output state, so it must be commutative and
+// associative.
+// Clone: returns a deep copy; the result must share no mutable state
+// with the receiver.
+// Reset: returns the receiver to the state of a newly-constructed
+// aggregator, which is how Move() empties its input.
+// HasChange: reports whether the aggregator differs from its newly
+// constructed state.  For asynchronous delta temporality, this is
+// called after Subtract and reports whether the difference is
+// non-empty; unchanged series are not exported.
+// Subtract: sets the receiver to the receiver minus `prior`, where
+// prior was cloned from the same series at the previous collection.
+// Subtract is only used for asynchronous instruments with delta
+// temporality (i.e., Methods.SubtractSwap); aggregations that cannot
+// be inverted, such as a HyperLogLog sketch, should be configured
+// with cumulative temporality or synchronous instruments and may
+// panic in Subtract.
+type CustomAggregator[N number.Any] interface {
+	Update(value N)
+	Merge(from CustomAggregator[N])
+	Clone() CustomAggregator[N]
+	Reset()
+	HasChange() bool
+	Subtract(prior CustomAggregator[N])
+}
+
+// CustomConfig supplies factories for a user-defined aggregation.
+// Only the factory corresponding to the instrument's number kind is
+// required.
+type CustomConfig struct {
+	// NewInt64 returns a new aggregator for int64 instruments.
+	NewInt64 func() CustomAggregator[int64]
+
+	// NewFloat64 returns a new aggregator for float64 instruments.
+	NewFloat64 func() CustomAggregator[float64]
+}
@@ -0,0 +1,198 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// The methods in this file adapt a user-defined
+// aggregator.CustomAggregator to the Methods interface pattern used
+// in this SDK.  The synchronization required by the Methods
+// interface is provided here, so CustomAggregator implementations
+// need not be safe for concurrent use.
+
+type (
+	Methods[N number.Any, Traits number.Traits[N]] struct{}
+
+	State[N number.Any, Traits number.Traits[N]] struct {
+		lock   sync.Mutex
+		agg    aggregator.CustomAggregator[N]
+		newAgg func() aggregator.CustomAggregator[N]
+	}
+
+	Int64   = State[int64, number.Int64Traits]
+	Float64 = State[float64, number.Float64Traits]
+
+	Int64Methods   = Methods[int64, number.Int64Traits]
+	Float64Methods = Methods[float64, number.Float64Traits]
+)
+
+var (
+	_ aggregator.Methods[int64, Int64]     = Int64Methods{}
+	_ aggregator.Methods[float64, Float64] = Float64Methods{}
+
+	_ aggregation.Aggregation = &Int64{}
+	_ aggregation.Aggregation = &Float64{}
+)
+
+// Kind returns aggregation.CustomKind.
+func (s *State[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.CustomKind
+}
+
+// Aggregator returns the user-defined aggregator, for use by
+// exporters.  This is nil if the configuration did not supply a
+// factory for this number kind.
+func (s *State[N, Traits]) Aggregator() aggregator.CustomAggregator[N] {
+	return s.agg
+}
+
+// factory returns the CustomConfig factory for number type N.
+func factory[N number.Any](cfg *aggregator.CustomConfig) func() aggregator.CustomAggregator[N] {
+	if cfg == nil {
+		return nil
+	}
+	var f any
+	var zero N
+	switch any(zero).(type) {
+	case int64:
+		f = cfg.NewInt64
+	case float64:
+		f = cfg.NewFloat64
+	}
+	if nf, ok := f.(func() aggregator.CustomAggregator[N]); ok {
+		return nf
+	}
+	return nil
+}
+
+func (Methods[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.CustomKind
+}
+
+func (Methods[N, Traits]) Init(ptr *State[N, Traits], cfg aggregator.Config) {
+	ptr.newAgg = factory[N](cfg.Custom)
+	if ptr.newAgg != nil {
+		ptr.agg = ptr.newAgg()
+	}
+}
+
+func (Methods[N, Traits]) HasChange(ptr *State[N, Traits]) bool {
+	return ptr.agg != nil && ptr.agg.HasChange()
+}
+
+// SizeOf does not include the memory of the custom aggregator, which
+// is not known.
+func (Methods[N, Traits]) SizeOf(ptr *State[N, Traits]) int {
+	return int(unsafe.Sizeof(*ptr))
+}
+
+func (Methods[N, Traits]) Update(ptr *State[N, Traits], number N, _ aggregator.ExemplarBits) {
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	if ptr.agg != nil {
+		ptr.agg.Update(number)
+	}
+}
+
+// UpdateBatch updates the custom aggregator count times, since it has
+// no batched update.
+func (Methods[N, Traits]) UpdateBatch(ptr *State[N, Traits], number N, count uint64, _ aggregator.ExemplarBits) {
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	if ptr.agg != nil {
+		for i := uint64(0); i < count; i++ {
+			ptr.agg.Update(number)
+		}
+	}
+}
+
+func (Methods[N, Traits]) Move(from, to *State[N, Traits]) {
+	from.lock.Lock()
+	defer from.lock.Unlock()
+
+	to.agg, from.agg = from.agg, to.agg
+	to.newAgg = from.newAgg
+
+	if from.agg != nil {
+		from.agg.Reset()
+	} else if from.newAgg != nil {
+		from.agg = from.newAgg()
+	}
+}
+
+func (Methods[N, Traits]) Copy(from, to *State[N, Traits]) {
+	from.lock.Lock()
+	defer from.lock.Unlock()
+
+	to.newAgg = from.newAgg
+	to.agg = nil
+	if from.agg != nil {
+		to.agg = from.agg.Clone()
+	}
+}
+
+func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+
+	if from.agg == nil {
+		return
+	}
+	if to.agg == nil {
+		to.newAgg = from.newAgg
+		to.agg = from.agg.Clone()
+		return
+	}
+	to.agg.Merge(from.agg)
+}
+
+func (Methods[N, Traits]) SubtractSwap(operand, argument *State[N, Traits]) {
+	// This is only used for asynchronous instruments with delta
+	// temporality.  Compute `argument - operand` in a copy of the
+	// argument, which remains unmodified, and store it in the operand.
+	if argument.agg == nil {
+		return
+	}
+	diff := argument.agg.Clone()
+	if operand.agg != nil {
+		diff.Subtract(operand.agg)
+	}
+	operand.agg = diff
+	operand.newAgg = argument.newAgg
+}
+
+func (Methods[N, Traits]) ToAggregation(ptr *State[N, Traits]) aggregation.Aggregation {
+	return ptr
+}
+
+func (Methods[N, Traits]) ToStorage(aggr aggregation.Aggregation) (*State[N, Traits], bool) {
+	r, ok := aggr.(*State[N, Traits])
+	return r, ok
+}
+
+func (Methods[N, Traits]) Exemplars(ptr *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	return in
+}
+
+func (Methods[N, Traits]) Weight(_ N) float64 {
+	return 1
+}
@@ -0,0 +1,168 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/stretchr/testify/require"
+)
+
+var nobits aggregator.ExemplarBits
+
+// bitsetUnion is a toy cardinality estimator that is exact for
+// values in [0, 64).
+type bitsetUnion struct {
+	set uint64
+}
+
+func newBitsetUnion() aggregator.CustomAggregator[int64] {
+	return &bitsetUnion{}
+}
+
+func (b *bitsetUnion) Update(value int64) {
+	b.set |= 1 << (uint64(value) % 64)
+}
+
+func (b *bitsetUnion) Merge(from aggregator.CustomAggregator[int64]) {
+	b.set |= from.(*bitsetUnion).set
+}
+
+func (b *bitsetUnion) Clone() aggregator.CustomAggregator[int64] {
+	cpy := *b
+	return &cpy
+}
+
+func (b *bitsetUnion) Reset() {
+	b.set = 0
+}
+
+func (b *bitsetUnion) HasChange() bool {
+	return b.set != 0
+}
+
+// Subtract leaves the members that are new since prior.
+func (b *bitsetUnion) Subtract(prior aggregator.CustomAggregator[int64]) {
+	b.set &^= prior.(*bitsetUnion).set
+}
+
+func (b *bitsetUnion) Estimate() int {
+	return bits.OnesCount64(b.set)
+}
+
+var testConfig = aggregator.Config{
+	Custom: &aggregator.CustomConfig{
+		NewInt64: newBitsetUnion,
+	},
+}
+
+func estimate(s *Int64) int {
+	return s.Aggregator().(*bitsetUnion).Estimate()
+}
+
+func TestCustomBitsetUnion(t *testing.T) {
+	var methods Int64Methods
+	init := func(vals ...int64) *Int64 {
+		var s Int64
+		methods.Init(&s, testConfig)
+		for _, val := range vals {
+			methods.Update(&s, val, nobits)
+		}
+		return &s
+	}
+
+	t.Run("update", func(t *testing.T) {
+		in := init(1, 2, 3, 2, 1)
+		require.Equal(t, 3, estimate(in))
+		require.True(t, methods.HasChange(in))
+		require.Equal(t, aggregation.CustomKind, methods.ToAggregation(in).Kind())
+	})
+
+	t.Run("move", func(t *testing.T) {
+		in := init(1, 2, 3)
+		out := init()
+		methods.Move(in, out)
+
+		require.Equal(t, 3, estimate(out))
+		require.Equal(t, 0, estimate(in))
+		require.False(t, methods.HasChange(in))
+
+		// The input remains usable after Move.
+		methods.Update(in, 10, nobits)
+		require.Equal(t, 1, estimate(in))
+		require.Equal(t, 3, estimate(out))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		in := init(1, 2, 3)
+		var out Int64
+		methods.Copy(in, &out)
+		methods.Update(in, 4, nobits)
+
+		require.Equal(t, 4, estimate(in))
+		require.Equal(t, 3, estimate(&out))
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		first := init(1, 2, 3)
+		second := init(3, 4, 5)
+
+		methods.Merge(first, second)
+		require.Equal(t, 3, estimate(first))
+		require.Equal(t, 5, estimate(second))
+
+		// Merge into an uninitialized output.
+		var out Int64
+		methods.Merge(first, &out)
+		require.Equal(t, 3, estimate(&out))
+	})
+
+	t.Run("subtract", func(t *testing.T) {
+		prior := init(1, 2)
+		current := init(1, 2, 3, 4)
+
+		methods.SubtractSwap(prior, current)
+		require.Equal(t, 2, estimate(prior))
+		require.Equal(t, 4, estimate(current))
+
+		unchanged := init(1, 2)
+		methods.SubtractSwap(unchanged, init(1, 2))
+		require.False(t, methods.HasChange(unchanged))
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		in := init(1)
+		s, ok := methods.ToStorage(methods.ToAggregation(in))
+		require.True(t, ok)
+		require.Equal(t, in, s)
+
+		_, ok = Float64Methods{}.ToStorage(methods.ToAggregation(in))
+		require.False(t, ok)
+	})
+}
+
+func TestCustomMissingFactory(t *testing.T) {
+	// The float64 factory is not configured.
+	var methods Float64Methods
+	var s Float64
+	methods.Init(&s, testConfig)
+	methods.Update(&s, 1, nobits)
+
+	require.Nil(t, s.Aggregator())
+	require.False(t, methods.HasChange(&s))
+}
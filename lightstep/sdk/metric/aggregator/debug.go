@@ -0,0 +1,49 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+
+import "fmt"
+
+// ErrInvariantViolated is the panic value, wrapped with details, when
+// an aggregator's storage fails validation in a debug build.
+var ErrInvariantViolated = fmt.Errorf("aggregator invariant violated")
+
+// Validator is implemented by the Methods of aggregators that define
+// invariants of their Storage, for example that a histogram's count
+// equals the sum of its bucket counts.
+type Validator[Storage any] interface {
+	// Validate returns an error describing a violated
+	// invariant, or nil.  It is called with the storage's lock
+	// held, if it has one, and must not lock it.
+	Validate(ptr *Storage) error
+}
+
+// CheckInvariants validates the storage following the operation
+// named, which is called by aggregators at the end of each Update,
+// Merge, and SubtractSwap.  In builds with the otelsdkdebug tag, a
+// violation panics with ErrInvariantViolated, to catch aggregator
+// bugs during development.  In other builds this does nothing and
+// costs nothing, since DebugInvariants is a false constant.
+func CheckInvariants[Storage any](v Validator[Storage], op string, ptr *Storage) {
+	if DebugInvariants {
+		checkInvariants(v, op, ptr)
+	}
+}
+
+func checkInvariants[Storage any](v Validator[Storage], op string, ptr *Storage) {
+	if err := v.Validate(ptr); err != nil {
+		panic(fmt.Errorf("%s: %w: %v", op, ErrInvariantViolated, err))
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type positive struct{}
+
+func (positive) Validate(ptr *int) error {
+	if *ptr <= 0 {
+		return errors.New("not positive")
+	}
+	return nil
+}
+
+func TestCheckInvariants(t *testing.T) {
+	good, bad := 1, -1
+	require.NotPanics(t, func() { CheckInvariants[int](positive{}, "test", &good) })
+
+	if !DebugInvariants {
+		// The check is skipped, without cost.
+		require.NotPanics(t, func() { CheckInvariants[int](positive{}, "test", &bad) })
+		require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+			CheckInvariants[int](positive{}, "test", &bad)
+		}))
+		return
+	}
+	require.PanicsWithError(t, "test: aggregator invariant violated: not positive", func() {
+		CheckInvariants[int](positive{}, "test", &bad)
+	})
+}
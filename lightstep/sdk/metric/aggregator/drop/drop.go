@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drop // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/drop"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// Note: the Drop aggregator discards every measurement.  Its state
+// is zero-sized, so that accumulators for a dropped view allocate
+// nothing, and it never has a change to report.
+
+type (
+	Methods[N number.Any, Traits number.Traits[N]] struct{}
+
+	State[N number.Any, Traits number.Traits[N]] struct{}
+
+	Int64   = State[int64, number.Int64Traits]
+	Float64 = State[float64, number.Float64Traits]
+
+	Int64Methods   = Methods[int64, number.Int64Traits]
+	Float64Methods = Methods[float64, number.Float64Traits]
+)
+
+var (
+	_ aggregator.Methods[int64, Int64]     = Int64Methods{}
+	_ aggregator.Methods[float64, Float64] = Float64Methods{}
+
+	_ aggregation.Aggregation = &Int64{}
+	_ aggregation.Aggregation = &Float64{}
+)
+
+func (*State[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.DropKind
+}
+
+func (Methods[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.DropKind
+}
+
+func (Methods[N, Traits]) Init(_ *State[N, Traits], _ aggregator.Config) {
+}
+
+func (Methods[N, Traits]) HasChange(_ *State[N, Traits]) bool {
+	return false
+}
+
+func (Methods[N, Traits]) SizeOf(_ *State[N, Traits]) int {
+	return 0
+}
+
+func (Methods[N, Traits]) Move(_, _ *State[N, Traits]) {
+}
+
+func (Methods[N, Traits]) Copy(_, _ *State[N, Traits]) {
+}
+
+func (Methods[N, Traits]) Update(_ *State[N, Traits], _ N, _ aggregator.ExemplarBits) {
+}
+
+func (Methods[N, Traits]) UpdateBatch(_ *State[N, Traits], _ N, _ uint64, _ aggregator.ExemplarBits) {
+}
+
+func (Methods[N, Traits]) Merge(_, _ *State[N, Traits]) {
+}
+
+func (Methods[N, Traits]) ToAggregation(state *State[N, Traits]) aggregation.Aggregation {
+	return state
+}
+
+func (Methods[N, Traits]) ToStorage(aggr aggregation.Aggregation) (*State[N, Traits], bool) {
+	r, ok := aggr.(*State[N, Traits])
+	return r, ok
+}
+
+func (Methods[N, Traits]) SubtractSwap(_, _ *State[N, Traits]) {
+}
+
+func (Methods[N, Traits]) Exemplars(_ *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	return in
+}
+
+func (Methods[N, Traits]) Weight(_ N) float64 {
+	return 1
+}
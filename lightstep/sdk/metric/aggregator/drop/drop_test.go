@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drop
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/stretchr/testify/require"
+)
+
+var nobits = aggregator.ExemplarBits{}
+
+func TestDiscards(t *testing.T) {
+	var methods Int64Methods
+	var state, other Int64
+
+	require.Equal(t, uintptr(0), unsafe.Sizeof(state))
+	require.Equal(t, aggregation.DropKind, methods.Kind())
+
+	methods.Init(&state, aggregator.Config{})
+	methods.Update(&state, 1, nobits)
+	methods.UpdateBatch(&state, 2, 10, nobits)
+	require.False(t, methods.HasChange(&state))
+	require.Equal(t, 0, methods.SizeOf(&state))
+
+	methods.Merge(&state, &other)
+	methods.Move(&state, &other)
+	require.False(t, methods.HasChange(&other))
+
+	ptr, ok := methods.ToStorage(methods.ToAggregation(&state))
+	require.True(t, ok)
+	require.Equal(t, &state, ptr)
+}
@@ -0,0 +1,349 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explicit // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// Note: the boundaries are shared by every State initialized with
+// the same aggregator.Config, and they are never modified.  Bucket i
+// counts the values in (boundaries[i-1], boundaries[i]], matching
+// the OTLP explicit bucket histogram, with a final bucket for the
+// values above every boundary.
+
+type (
+	Methods[N number.Any, Traits number.Traits[N]] struct{}
+
+	fields[N number.Any, Traits number.Traits[N]] struct {
+		min    N
+		max    N
+		sum    N
+		count  uint64
+		counts []uint64
+	}
+
+	State[N number.Any, Traits number.Traits[N]] struct {
+		lock   sync.Mutex
+		bounds []float64
+
+		// nonFinite is the policy for NaN and ±Inf updates,
+		// set by aggregator.Config.NonFinite.
+		nonFinite aggregator.NonFinitePolicy
+
+		// dropped counts the updates rejected because of
+		// NonFiniteDropAndCount.  This field uses atomic
+		// operations.
+		dropped uint64
+
+		fields[N, Traits]
+	}
+
+	Int64   = State[int64, number.Int64Traits]
+	Float64 = State[float64, number.Float64Traits]
+
+	Int64Methods   = Methods[int64, number.Int64Traits]
+	Float64Methods = Methods[float64, number.Float64Traits]
+)
+
+var (
+	_ aggregator.Methods[int64, Int64]     = Int64Methods{}
+	_ aggregator.Methods[float64, Float64] = Float64Methods{}
+
+	_ aggregation.ExplicitHistogram = &Int64{}
+	_ aggregation.ExplicitHistogram = &Float64{}
+
+	errBoundariesMismatch = errors.New("explicit histogram boundaries do not match")
+)
+
+// NewInt64 returns an Int64 with the boundaries and values.
+func NewInt64(bounds []float64, vals ...int64) *Int64 {
+	return newState[int64, number.Int64Traits](bounds, vals...)
+}
+
+// NewFloat64 returns a Float64 with the boundaries and values.
+func NewFloat64(bounds []float64, vals ...float64) *Float64 {
+	return newState[float64, number.Float64Traits](bounds, vals...)
+}
+
+func newState[N number.Any, Traits number.Traits[N]](bounds []float64, vals ...N) *State[N, Traits] {
+	var methods Methods[N, Traits]
+	s := &State[N, Traits]{}
+	methods.Init(s, aggregator.Config{
+		Explicit: &aggregator.ExplicitConfig{Boundaries: bounds},
+	})
+	for _, val := range vals {
+		methods.Update(s, val, aggregator.ExemplarBits{})
+	}
+	return s
+}
+
+func (s *State[N, Traits]) Sum() number.Number {
+	var t Traits
+	return t.ToNumber(s.sum)
+}
+
+func (s *State[N, Traits]) Count() uint64 {
+	return s.count
+}
+
+func (s *State[N, Traits]) Min() number.Number {
+	var t Traits
+	return t.ToNumber(s.min)
+}
+
+func (s *State[N, Traits]) Max() number.Number {
+	var t Traits
+	return t.ToNumber(s.max)
+}
+
+// Boundaries returns the upper-inclusive bucket boundaries, which
+// must not be modified.
+func (s *State[N, Traits]) Boundaries() []float64 {
+	return s.bounds
+}
+
+// BucketCounts returns the count of each bucket, one more than the
+// boundaries, which must not be modified.
+func (s *State[N, Traits]) BucketCounts() []uint64 {
+	return s.counts
+}
+
+// Dropped returns the number of updates rejected because of
+// aggregator.NonFiniteDropAndCount.
+func (s *State[N, Traits]) Dropped() uint64 {
+	return s.dropped
+}
+
+func (s *State[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.ExplicitHistogramKind
+}
+
+func (Methods[N, Traits]) Kind() aggregation.Kind {
+	return aggregation.ExplicitHistogramKind
+}
+
+func (Methods[N, Traits]) Init(state *State[N, Traits], cfg aggregator.Config) {
+	if cfg.Explicit != nil {
+		state.bounds = cfg.Explicit.Boundaries
+	}
+	state.nonFinite = cfg.NonFinite
+	state.counts = make([]uint64, len(state.bounds)+1)
+}
+
+func (Methods[N, Traits]) HasChange(ptr *State[N, Traits]) bool {
+	return ptr.count != 0
+}
+
+func (Methods[N, Traits]) SizeOf(ptr *State[N, Traits]) int {
+	return int(unsafe.Sizeof(*ptr)) + cap(ptr.counts)*int(unsafe.Sizeof(uint64(0)))
+}
+
+// Move moves the fields, exchanging the bucket counts so that
+// neither side allocates when their lengths match.
+func (Methods[N, Traits]) Move(from, to *State[N, Traits]) {
+	from.lock.Lock()
+	defer from.lock.Unlock()
+
+	counts := to.counts
+	if len(counts) != len(from.counts) {
+		counts = make([]uint64, len(from.counts))
+	} else {
+		clear(counts)
+	}
+	to.bounds = from.bounds
+	to.nonFinite = from.nonFinite
+	to.dropped = atomic.SwapUint64(&from.dropped, 0)
+	to.fields, from.fields = from.fields, fields[N, Traits]{counts: counts}
+}
+
+func (Methods[N, Traits]) Copy(from, to *State[N, Traits]) {
+	from.lock.Lock()
+	defer from.lock.Unlock()
+
+	counts := append(to.counts[:0], from.counts...)
+	to.bounds = from.bounds
+	to.nonFinite = from.nonFinite
+	to.dropped = atomic.LoadUint64(&from.dropped)
+	to.fields = from.fields
+	to.counts = counts
+}
+
+func (Methods[N, Traits]) Update(state *State[N, Traits], number N, _ aggregator.ExemplarBits) {
+	if aggregator.RejectNonFinite[N, Traits](state.nonFinite, number, &state.dropped) {
+		return
+	}
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	state.update(number, 1)
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "explicit Update", state)
+}
+
+func (Methods[N, Traits]) UpdateBatch(state *State[N, Traits], number N, count uint64, _ aggregator.ExemplarBits) {
+	if count == 0 || aggregator.RejectNonFiniteCount[N, Traits](state.nonFinite, number, count, &state.dropped) {
+		return
+	}
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	state.update(number, count)
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "explicit UpdateBatch", state)
+}
+
+// update adds count measurements of number, which must be locked.
+// The bucket is the first whose boundary is not less than number,
+// else the last.
+func (state *State[N, Traits]) update(number N, count uint64) {
+	if state.count == 0 {
+		state.min = number
+		state.max = number
+	} else {
+		if number < state.min {
+			state.min = number
+		}
+		if number > state.max {
+			state.max = number
+		}
+	}
+	state.counts[sort.SearchFloat64s(state.bounds, float64(number))] += count
+	state.sum += number * N(count)
+	state.count += count
+}
+
+// Merge panics with an error when the boundaries differ, since the
+// buckets cannot be combined.  Accumulators report this as
+// aggregator.ErrMergeFailed.  An empty destination takes the
+// boundaries of a non-empty source.
+func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+
+	atomic.AddUint64(&to.dropped, atomic.LoadUint64(&from.dropped))
+
+	if from.count == 0 {
+		return
+	}
+	if to.count == 0 && !slices.Equal(from.bounds, to.bounds) {
+		to.bounds = from.bounds
+		to.counts = make([]uint64, len(from.counts))
+	}
+	mustMatch(from, to)
+
+	if to.count == 0 {
+		to.min = from.min
+		to.max = from.max
+	} else {
+		if from.min < to.min {
+			to.min = from.min
+		}
+		if from.max > to.max {
+			to.max = from.max
+		}
+	}
+	for i, c := range from.counts {
+		to.counts[i] += c
+	}
+	to.sum += from.sum
+	to.count += from.count
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "explicit Merge", to)
+}
+
+// mustMatch panics unless a and b have the same boundaries.
+func mustMatch[N number.Any, Traits number.Traits[N]](a, b *State[N, Traits]) {
+	if !slices.Equal(a.bounds, b.bounds) || len(a.counts) != len(b.counts) {
+		panic(fmt.Errorf("%w: %v != %v", errBoundariesMismatch, a.bounds, b.bounds))
+	}
+}
+
+func (Methods[N, Traits]) ToAggregation(state *State[N, Traits]) aggregation.Aggregation {
+	return state
+}
+
+func (Methods[N, Traits]) ToStorage(aggr aggregation.Aggregation) (*State[N, Traits], bool) {
+	r, ok := aggr.(*State[N, Traits])
+	return r, ok
+}
+
+// SubtractSwap computes `*operand = *argument - *operand`, for
+// cumulative to delta translation.  The extremes of a difference are
+// not those of either side, so the difference reports the later
+// interval's.  Panics with an error when the boundaries differ.
+func (Methods[N, Traits]) SubtractSwap(operand, argument *State[N, Traits]) {
+	mustMatch(operand, argument)
+
+	for i, c := range argument.counts {
+		operand.counts[i] = c - operand.counts[i]
+	}
+	if argument.dropped >= operand.dropped {
+		operand.dropped = argument.dropped - operand.dropped
+	} else {
+		operand.dropped = argument.dropped
+	}
+	operand.sum = argument.sum - operand.sum
+	operand.count = argument.count - operand.count
+	operand.min = argument.min
+	operand.max = argument.max
+	if operand.count == 0 {
+		operand.min, operand.max, operand.sum = 0, 0, 0
+	}
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "explicit SubtractSwap", operand)
+}
+
+// Validate implements aggregator.Validator.  There is one more
+// bucket than boundaries and the bucket counts total the count.  An
+// empty state is zero; otherwise no field is NaN and the minimum
+// does not exceed the maximum.
+func (Methods[N, Traits]) Validate(state *State[N, Traits]) error {
+	f := state.fields
+	var total uint64
+	for _, c := range f.counts {
+		total += c
+	}
+	switch {
+	case len(f.counts) != len(state.bounds)+1:
+		return errors.New("bucket count does not match boundaries")
+	case total != f.count:
+		return errors.New("bucket counts do not total the count")
+	case f.count == 0:
+		if f.min != 0 || f.max != 0 || f.sum != 0 {
+			return errors.New("empty state is not zero")
+		}
+	case math.IsNaN(float64(f.min)) || math.IsNaN(float64(f.max)) || math.IsNaN(float64(f.sum)):
+		return errors.New("NaN field")
+	case f.min > f.max:
+		return errors.New("min exceeds max")
+	}
+	return nil
+}
+
+func (Methods[N, Traits]) Exemplars(ptr *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	return in
+}
+
+func (Methods[N, Traits]) Weight(n N) float64 {
+	return 1
+}
@@ -0,0 +1,169 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explicit // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nobits = aggregator.ExemplarBits{}
+
+	// promBounds are the default Prometheus client boundaries.
+	promBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+func TestInt64Explicit(t *testing.T) {
+	test.GenericAggregatorTest[int64, Int64, Int64Methods](t, number.ToInt64)
+}
+
+func TestFloat64Explicit(t *testing.T) {
+	test.GenericAggregatorTest[float64, Float64, Float64Methods](t, number.ToFloat64)
+}
+
+func TestBuckets(t *testing.T) {
+	bounds := []float64{1, 5, 10}
+
+	// Values equal to a boundary are counted in its bucket, and
+	// values above every boundary in the last bucket.
+	agg := NewFloat64(bounds, 0, 1, 1.5, 5, 10, 10.5, 1000, math.Inf(+1))
+	require.Equal(t, []uint64{2, 2, 1, 3}, agg.BucketCounts())
+	require.Equal(t, bounds, agg.Boundaries())
+	require.Equal(t, uint64(8), agg.Count())
+	require.Equal(t, 0.0, number.ToFloat64(agg.Min()))
+	require.Equal(t, math.Inf(+1), number.ToFloat64(agg.Max()))
+
+	ints := NewInt64(bounds, -3, 1, 2, 11)
+	require.Equal(t, []uint64{2, 1, 0, 1}, ints.BucketCounts())
+	require.Equal(t, int64(11), number.ToInt64(ints.Sum()))
+
+	// Without boundaries, there is one bucket.
+	empty := NewFloat64(nil, -1, 0, 1)
+	require.Equal(t, []uint64{3}, empty.BucketCounts())
+	require.Equal(t, 0, len(empty.Boundaries()))
+
+	prom := NewFloat64(promBounds, 0.005, 0.3, 7)
+	require.Equal(t, []uint64{1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0}, prom.BucketCounts())
+}
+
+func TestNonFinite(t *testing.T) {
+	var methods Float64Methods
+	var agg Float64
+	methods.Init(&agg, aggregator.Config{
+		Explicit:  &aggregator.ExplicitConfig{Boundaries: []float64{1}},
+		NonFinite: aggregator.NonFiniteDropAndCount,
+	})
+	methods.Update(&agg, math.NaN(), nobits)
+	methods.UpdateBatch(&agg, math.Inf(+1), 2, nobits)
+	methods.Update(&agg, 2, nobits)
+
+	require.Equal(t, []uint64{0, 1}, agg.BucketCounts())
+	require.Equal(t, uint64(3), agg.Dropped())
+}
+
+func TestUpdateBatch(t *testing.T) {
+	var methods Int64Methods
+	agg := NewInt64(promBounds)
+	methods.UpdateBatch(agg, 3, 4, nobits)
+	methods.UpdateBatch(agg, 100, 0, nobits)
+
+	expect := NewInt64(promBounds, 3, 3, 3, 3)
+	require.Equal(t, expect, agg)
+}
+
+func TestMoveCopyMerge(t *testing.T) {
+	var methods Float64Methods
+
+	first := NewFloat64(promBounds, 0.5, 3)
+	second := NewFloat64(promBounds, 0.25, 20)
+
+	moved := NewFloat64(promBounds)
+	methods.Move(first, moved)
+	require.Equal(t, NewFloat64(promBounds), first)
+	require.Equal(t, NewFloat64(promBounds, 0.5, 3), moved)
+
+	copied := NewFloat64(nil)
+	methods.Copy(second, copied)
+	require.Equal(t, second, copied)
+
+	methods.Merge(copied, moved)
+	require.Equal(t, NewFloat64(promBounds, 0.5, 3, 0.25, 20), moved)
+
+	// The copy does not share the source's buckets.
+	methods.Update(second, 1, nobits)
+	require.Equal(t, NewFloat64(promBounds, 0.25, 20), copied)
+}
+
+func TestMergeMismatch(t *testing.T) {
+	var methods Float64Methods
+
+	to := NewFloat64(promBounds, 1)
+
+	// An empty state merges regardless of the boundaries.
+	methods.Merge(NewFloat64([]float64{1, 2}), to)
+	require.Equal(t, NewFloat64(promBounds, 1), to)
+
+	// An empty destination takes the source's boundaries.
+	empty := NewFloat64(nil)
+	methods.Merge(NewFloat64([]float64{1, 2}, 1.5), empty)
+	require.Equal(t, NewFloat64([]float64{1, 2}, 1.5), empty)
+
+	require.PanicsWithError(t,
+		"explicit histogram boundaries do not match: [1 2] != [0.005 0.01 0.025 0.05 0.1 0.25 0.5 1 2.5 5 10]",
+		func() { methods.Merge(NewFloat64([]float64{1, 2}, 1), to) },
+	)
+	require.Panics(t, func() {
+		methods.SubtractSwap(NewFloat64(nil, 1), to)
+	})
+}
+
+func TestSubtractSwap(t *testing.T) {
+	var methods Int64Methods
+	bounds := []float64{1, 5, 10}
+
+	prior := NewInt64(bounds, 1, 6)
+	current := NewInt64(bounds, 1, 6, 2, 20, 4)
+
+	methods.SubtractSwap(prior, current)
+	require.Equal(t, []uint64{0, 2, 0, 1}, prior.BucketCounts())
+	require.Equal(t, uint64(3), prior.Count())
+	require.Equal(t, int64(26), number.ToInt64(prior.Sum()))
+
+	// The extremes are those of the later value.
+	require.Equal(t, int64(1), number.ToInt64(prior.Min()))
+	require.Equal(t, int64(20), number.ToInt64(prior.Max()))
+
+	// No change is an empty state.
+	unchanged := NewInt64(bounds, 1, 6)
+	methods.SubtractSwap(unchanged, NewInt64(bounds, 1, 6))
+	require.False(t, methods.HasChange(unchanged))
+	require.Equal(t, NewInt64(bounds), unchanged)
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid, err := aggregator.ExplicitConfig{Boundaries: promBounds}.Validate()
+	require.NoError(t, err)
+	require.Equal(t, promBounds, valid.Boundaries)
+
+	valid, err = aggregator.ExplicitConfig{Boundaries: []float64{5, 1, math.NaN(), 1, math.Inf(+1)}}.Validate()
+	require.Error(t, err)
+	require.Equal(t, []float64{1, 5}, valid.Boundaries)
+}
@@ -15,9 +15,13 @@
 package gauge // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -40,6 +44,16 @@ type (
 		lock  sync.Mutex
 		value N
 		seq   uint64
+
+		// time is the time of the value, when timed is set
+		// by aggregator.Config.GaugeTimestamps.
+		time  time.Time
+		timed bool
+
+		// count is the number of observations, when counted
+		// is set by aggregator.Config.GaugeCounts.
+		count   uint64
+		counted bool
 	}
 
 	Int64   = State[int64, number.Int64Traits]
@@ -61,8 +75,8 @@ var (
 	_ aggregator.Methods[int64, Int64]     = Int64Methods{}
 	_ aggregator.Methods[float64, Float64] = Float64Methods{}
 
-	_ aggregation.Gauge = &Int64{}
-	_ aggregation.Gauge = &Float64{}
+	_ aggregation.GaugeCount = &Int64{}
+	_ aggregation.GaugeCount = &Float64{}
 )
 
 func NewInt64(x int64) *Int64 {
@@ -97,6 +111,19 @@ func (g *State[N, Traits]) Gauge() number.Number {
 	return t.ToNumber(g.value)
 }
 
+// Time returns the time of the value when the gauge was configured
+// with aggregator.Config.GaugeTimestamps, otherwise the zero time.
+func (g *State[N, Traits]) Time() time.Time {
+	return g.time
+}
+
+// Count returns the number of observations of the value when the
+// gauge was configured with aggregator.Config.GaugeCounts, otherwise
+// zero.
+func (g *State[N, Traits]) Count() uint64 {
+	return g.count
+}
+
 func (g *State[N, Traits]) Kind() aggregation.Kind {
 	return aggregation.GaugeKind
 }
@@ -112,22 +139,33 @@ func (Methods[N, Traits]) Kind() aggregation.Kind {
 	return aggregation.GaugeKind
 }
 
-func (Methods[N, Traits]) Init(state *State[N, Traits], _ aggregator.Config) {
+func (Methods[N, Traits]) Init(state *State[N, Traits], cfg aggregator.Config) {
 	// Note: storage is zero to start
+	state.timed = cfg.GaugeTimestamps
+	state.counted = cfg.GaugeCounts
 }
 
 func (Methods[N, Traits]) HasChange(ptr *State[N, Traits]) bool {
 	return ptr.seq != 0
 }
 
+func (Methods[N, Traits]) SizeOf(ptr *State[N, Traits]) int {
+	return int(unsafe.Sizeof(*ptr))
+}
+
 func (Methods[N, Traits]) Move(from, to *State[N, Traits]) {
 	from.lock.Lock()
 	defer from.lock.Unlock()
 
 	to.value = from.value
 	to.seq = from.seq
+	to.time = from.time
+	to.timed = from.timed
+	to.count = from.count
 
 	from.seq = 0
+	from.time = time.Time{}
+	from.count = 0
 }
 
 func (Methods[N, Traits]) Copy(from, to *State[N, Traits]) {
@@ -135,14 +173,47 @@ func (Methods[N, Traits]) Copy(from, to *State[N, Traits]) {
 	defer from.lock.Unlock()
 	to.value = from.value
 	to.seq = from.seq
+	to.time = from.time
+	to.timed = from.timed
+	to.count = from.count
+}
+
+func (Methods[N, Traits]) Update(state *State[N, Traits], number N, ex aggregator.ExemplarBits) {
+	state.update(number, 1, ex, "gauge Update")
+}
+
+// UpdateBatch sets the value once, since repeating it has no effect,
+// and counts count observations.
+func (Methods[N, Traits]) UpdateBatch(state *State[N, Traits], number N, count uint64, ex aggregator.ExemplarBits) {
+	if count == 0 {
+		return
+	}
+	state.update(number, count, ex, "gauge UpdateBatch")
 }
 
-func (Methods[N, Traits]) Update(state *State[N, Traits], number N, _ aggregator.ExemplarBits) {
+// update sets the value for count observations, unless it is older
+// than the current value.  Observations are counted either way.  The
+// invariants are checked for op with the lock held.
+func (state *State[N, Traits]) update(number N, count uint64, ex aggregator.ExemplarBits, op string) {
 	newSeq := atomic.AddUint64(&sequenceVar, 1)
 
 	state.lock.Lock()
 	defer state.lock.Unlock()
+	defer aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, op, state)
 
+	if state.counted {
+		state.count += count
+	}
+	if state.timed {
+		when := ex.Time
+		if when.IsZero() {
+			when = time.Now()
+		}
+		if state.seq != 0 && when.Before(state.time) {
+			return
+		}
+		state.time = when
+	}
 	state.value = number
 	state.seq = newSeq
 }
@@ -151,10 +222,26 @@ func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
 	to.lock.Lock()
 	defer to.lock.Unlock()
 
-	if from.seq != 0 && from.seq > to.seq {
+	to.count += from.count
+	if from.seq != 0 && from.newerThan(to) {
 		to.value = from.value
 		to.seq = from.seq
+		to.time = from.time
 	}
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "gauge Merge", to)
+}
+
+// newerThan returns true when g should replace a set value of other.
+// When either is timed, the later time wins and the sequence number
+// breaks ties, otherwise the later update wins.
+func (g *State[N, Traits]) newerThan(other *State[N, Traits]) bool {
+	if other.seq == 0 {
+		return true
+	}
+	if (g.timed || other.timed) && !g.time.Equal(other.time) {
+		return g.time.After(other.time)
+	}
+	return g.seq > other.seq
 }
 
 func (Methods[N, Traits]) ToAggregation(state *State[N, Traits]) aggregation.Aggregation {
@@ -166,8 +253,24 @@ func (Methods[N, Traits]) ToStorage(aggr aggregation.Aggregation) (*State[N, Tra
 	return r, ok
 }
 
+// SubtractSwap computes `*operand = *argument - *operand`, used only
+// by the non-standard delta gauges of view.WithDeltaGauge.  The
+// difference takes the sequence, time, and count of the later
+// observation.
 func (Methods[N, Traits]) SubtractSwap(operand, argument *State[N, Traits]) {
-	panic("not used for non-temporal metrics")
+	operand.value = argument.value - operand.value
+	operand.seq = argument.seq
+	operand.time, operand.timed = argument.time, argument.timed
+	operand.count, operand.counted = argument.count, argument.counted
+}
+
+// Validate implements aggregator.Validator.  A set value is not NaN,
+// since NaN inputs are rejected.
+func (Methods[N, Traits]) Validate(state *State[N, Traits]) error {
+	if state.seq != 0 && math.IsNaN(float64(state.value)) {
+		return errors.New("gauge is NaN")
+	}
+	return nil
 }
 
 func (Methods[N, Traits]) Exemplars(ptr *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
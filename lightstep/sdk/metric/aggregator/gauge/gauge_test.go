@@ -15,8 +15,10 @@
 package gauge // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 
 import (
+	"math"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -118,3 +120,105 @@ func genericLastValueTest[N number.Any, Storage any, Methods aggregator.Methods[
 		require.Equal(t, N(17), nf(agg.(aggregation.Gauge).Gauge()))
 	})
 }
+
+func TestValidate(t *testing.T) {
+	var methods Float64Methods
+	var g Float64
+	methods.Update(&g, 1, aggregator.ExemplarBits{})
+	require.NoError(t, methods.Validate(&g))
+
+	g.value = math.NaN()
+	require.ErrorContains(t, methods.Validate(&g), "NaN")
+}
+
+func TestTimestampMerge(t *testing.T) {
+	var methods Float64Methods
+	cfg := aggregator.Config{GaugeTimestamps: true}
+	early := time.Unix(100, 0)
+	late := early.Add(time.Second)
+
+	for _, lateFirst := range []bool{true, false} {
+		var a, b, out Float64
+		methods.Init(&a, cfg)
+		methods.Init(&b, cfg)
+		methods.Init(&out, aggregator.Config{})
+
+		methods.Update(&a, 1, aggregator.ExemplarBits{Time: early})
+		methods.Update(&b, 2, aggregator.ExemplarBits{Time: late})
+
+		if lateFirst {
+			methods.Merge(&b, &out)
+			methods.Merge(&a, &out)
+		} else {
+			methods.Merge(&a, &out)
+			methods.Merge(&b, &out)
+		}
+		require.Equal(t, 2.0, number.ToFloat64(out.Gauge()))
+		require.True(t, late.Equal(out.Time()))
+	}
+
+	// An update older than the current value is ignored.
+	var s Float64
+	methods.Init(&s, cfg)
+	methods.Update(&s, 2, aggregator.ExemplarBits{Time: late})
+	methods.Update(&s, 1, aggregator.ExemplarBits{Time: early})
+	require.Equal(t, 2.0, number.ToFloat64(s.Gauge()))
+
+	// Without the option, no time is kept.
+	var u Float64
+	methods.Init(&u, aggregator.Config{})
+	methods.Update(&u, 1, aggregator.ExemplarBits{Time: late})
+	require.True(t, u.Time().IsZero())
+}
+
+func TestCountMerge(t *testing.T) {
+	var methods Int64Methods
+	cfg := aggregator.Config{GaugeCounts: true}
+
+	var a, b, out, moved Int64
+	methods.Init(&a, cfg)
+	methods.Init(&b, cfg)
+	methods.Init(&out, cfg)
+
+	methods.Update(&a, 1, aggregator.ExemplarBits{})
+	methods.Update(&a, 2, aggregator.ExemplarBits{})
+	methods.UpdateBatch(&b, 3, 5, aggregator.ExemplarBits{})
+	require.Equal(t, uint64(2), a.Count())
+	require.Equal(t, uint64(5), b.Count())
+
+	methods.Merge(&a, &out)
+	methods.Merge(&b, &out)
+	require.Equal(t, uint64(7), out.Count())
+	require.Equal(t, int64(3), number.ToInt64(out.Gauge()))
+
+	// Move resets the count.
+	methods.Move(&out, &moved)
+	require.Equal(t, uint64(7), moved.Count())
+	require.Equal(t, uint64(0), out.Count())
+
+	// Without the option, observations are not counted.
+	var u Int64
+	methods.Init(&u, aggregator.Config{})
+	methods.Update(&u, 1, aggregator.ExemplarBits{})
+	require.Equal(t, uint64(0), u.Count())
+}
+
+func TestSubtractSwap(t *testing.T) {
+	var methods Int64Methods
+	cfg := aggregator.Config{GaugeCounts: true}
+
+	var prior, current Int64
+	methods.Init(&prior, cfg)
+	methods.Init(&current, cfg)
+
+	methods.Update(&prior, 10, nobits)
+	methods.Update(&current, 7, nobits)
+	methods.Update(&current, 4, nobits)
+
+	// The difference has the count of the later value.
+	methods.SubtractSwap(&prior, &current)
+	require.True(t, methods.HasChange(&prior))
+	require.Equal(t, int64(-6), number.ToInt64(prior.Gauge()))
+	require.Equal(t, uint64(2), prior.Count())
+	require.Equal(t, int64(4), number.ToInt64(current.Gauge()))
+}
@@ -15,8 +15,13 @@
 package histogram // import "github.com/lightstep/go-expohisto"
 
 import (
+	"fmt"
+	"math"
 	"sync"
+	"time"
+	"unsafe"
 
+	"github.com/lightstep/go-expohisto/mapping/logarithm"
 	"github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -32,6 +37,41 @@ type (
 	Histogram[N number.Any, Traits number.Traits[N]] struct {
 		lock      sync.Mutex
 		Histogram structure.Histogram[N]
+
+		// extremes is set when aggregator.Config.HistogramExtremes
+		// is true, in which case minEx and maxEx describe the
+		// minimum and maximum observations.
+		extremes bool
+		minEx    aggregator.ExemplarBits
+		maxEx    aggregator.ExemplarBits
+
+		// weighted is set when aggregator.Config.HistogramWeighted
+		// is true, counting the secondary weight of each
+		// observation.  It is synchronized by lock.
+		weighted    *Histogram[N, Traits]
+		weightedCfg Config
+
+		// nonFinite is the policy for NaN and ±Inf updates,
+		// and dropped counts those rejected.  Both are
+		// synchronized by lock.
+		nonFinite aggregator.NonFinitePolicy
+		dropped   uint64
+
+		// sparse is set when aggregator.Config.HistogramSparse or
+		// HistogramLimitScale is true, in which case it is used
+		// instead of Histogram.
+		sparse *sparseHistogram[N]
+
+		// carryScale is aggregator.Config.HistogramCarryScale,
+		// set to start the sparse storage left empty by Move at
+		// the scale of the storage moved.
+		carryScale bool
+
+		// zeroThreshold is aggregator.Config.HistogramZeroThreshold,
+		// and zero holds the observations within it other than
+		// exact zeros, which are counted by the storage.
+		zeroThreshold float64
+		zero          zeroBucket[N]
 	}
 
 	Config = structure.Config
@@ -78,42 +118,138 @@ func WithMaxSize(sz int32) Option {
 	return structure.WithMaxSize(sz)
 }
 
+// Dropped returns the number of non-finite values rejected because of
+// aggregator.NonFiniteDropAndCount.
+func (h *Histogram[N, Traits]) Dropped() uint64 {
+	return h.dropped
+}
+
 func (h *Histogram[N, Traits]) Kind() aggregation.Kind {
 	return aggregation.HistogramKind
 }
 
 func (h *Histogram[N, Traits]) Max() number.Number {
 	var traits Traits
-	return traits.ToNumber(h.Histogram.Max())
+	return traits.ToNumber(h.maxValue())
 }
 
 func (h *Histogram[N, Traits]) Min() number.Number {
 	var traits Traits
-	return traits.ToNumber(h.Histogram.Min())
+	return traits.ToNumber(h.minValue())
 }
 
+// Sum returns the sum in the number type of the histogram, which
+// int64 histograms accumulate in int64 without rounding.
 func (h *Histogram[N, Traits]) Sum() number.Number {
 	var traits Traits
-	return traits.ToNumber(h.Histogram.Sum())
+	return traits.ToNumber(h.sumValue())
+}
+
+// ZeroThreshold returns the magnitude at or below which observations
+// are counted in the zero bucket.
+func (h *Histogram[N, Traits]) ZeroThreshold() float64 {
+	return h.zeroThreshold
+}
+
+func (h *Histogram[N, Traits]) sumValue() N {
+	if h.sparse != nil {
+		return h.sparse.sum + h.zero.sum
+	}
+	return h.Histogram.Sum() + h.zero.sum
+}
+
+func (h *Histogram[N, Traits]) maxValue() N {
+	switch {
+	case h.zero.count == 0:
+		return h.storageMax()
+	case h.storageCount() == 0:
+		return h.zero.max
+	}
+	return max(h.storageMax(), h.zero.max)
+}
+
+func (h *Histogram[N, Traits]) minValue() N {
+	switch {
+	case h.zero.count == 0:
+		return h.storageMin()
+	case h.storageCount() == 0:
+		return h.zero.min
+	}
+	return min(h.storageMin(), h.zero.min)
+}
+
+func (h *Histogram[N, Traits]) storageMax() N {
+	if h.sparse != nil {
+		return h.sparse.max
+	}
+	return h.Histogram.Max()
+}
+
+func (h *Histogram[N, Traits]) storageMin() N {
+	if h.sparse != nil {
+		return h.sparse.min
+	}
+	return h.Histogram.Min()
+}
+
+// MinTime returns the time of the minimum observation, if
+// HistogramExtremes was configured, otherwise the zero time.
+func (h *Histogram[N, Traits]) MinTime() time.Time {
+	return h.minEx.Time
+}
+
+// MaxTime returns the time of the maximum observation, if
+// HistogramExtremes was configured, otherwise the zero time.
+func (h *Histogram[N, Traits]) MaxTime() time.Time {
+	return h.maxEx.Time
+}
+
+// Weighted returns the histogram of observations counted by their
+// secondary weight, if HistogramWeighted was configured, otherwise
+// nil.  Its Count() is the total weight and its Sum() is the sum of
+// each value times its weight.  Observations with zero weight are
+// not included, so its range and scale may differ from the count
+// histogram.
+func (h *Histogram[N, Traits]) Weighted() *Histogram[N, Traits] {
+	return h.weighted
 }
 
 func (h *Histogram[N, Traits]) Count() uint64 {
+	return h.storageCount() + h.zero.count
+}
+
+func (h *Histogram[N, Traits]) storageCount() uint64 {
+	if h.sparse != nil {
+		return h.sparse.count
+	}
 	return h.Histogram.Count()
 }
 
 func (h *Histogram[N, Traits]) ZeroCount() uint64 {
-	return h.Histogram.ZeroCount()
+	if h.sparse != nil {
+		return h.sparse.zeroCount + h.zero.count
+	}
+	return h.Histogram.ZeroCount() + h.zero.count
 }
 
 func (h *Histogram[N, Traits]) Negative() aggregation.Buckets {
+	if h.sparse != nil {
+		return &h.sparse.negative
+	}
 	return h.Histogram.Negative()
 }
 
 func (h *Histogram[N, Traits]) Positive() aggregation.Buckets {
+	if h.sparse != nil {
+		return &h.sparse.positive
+	}
 	return h.Histogram.Positive()
 }
 
 func (h *Histogram[N, Traits]) Scale() int32 {
+	if h.sparse != nil {
+		return h.sparse.scale()
+	}
 	return h.Histogram.Scale()
 }
 
@@ -123,36 +259,296 @@ func (Methods[N, Traits]) Kind() aggregation.Kind {
 
 func (Methods[N, Traits]) Init(agg *Histogram[N, Traits], cfg aggregator.Config) {
 	agg.Histogram.Init(cfg.Histogram)
+	agg.extremes = cfg.HistogramExtremes
+	agg.nonFinite = cfg.NonFinite
+	agg.zeroThreshold = cfg.HistogramZeroThreshold
+	agg.zero = zeroBucket[N]{}
+	agg.carryScale = cfg.HistogramCarryScale
+	agg.sparse = nil
+	// Only the sparse storage can start below the maximum scale.
+	sparse := cfg.HistogramSparse || cfg.HistogramLimitScale
+	if sparse {
+		agg.sparse = newSparse[N](maxSizeOf(cfg.Histogram), maxScaleOf(cfg))
+	}
+	agg.weighted = nil
+	if cfg.HistogramWeighted {
+		agg.weighted = newWeighted[N, Traits](cfg.Histogram, sparse, maxScaleOf(cfg), cfg.HistogramZeroThreshold)
+	}
+}
+
+// newWeighted returns a new weighted histogram.  The maxScale applies
+// to sparse storage.
+func newWeighted[N number.Any, Traits number.Traits[N]](cfg Config, sparse bool, maxScale int32, zeroThreshold float64) *Histogram[N, Traits] {
+	w := &Histogram[N, Traits]{
+		weightedCfg:   cfg,
+		zeroThreshold: zeroThreshold,
+	}
+	w.Histogram.Init(cfg)
+	if sparse {
+		w.sparse = newSparse[N](maxSizeOf(cfg), maxScale)
+	}
+	return w
+}
+
+// updateByIncr adds incr observations of number to the storage in
+// use, or to the zero bucket when within the zero threshold.
+func (h *Histogram[N, Traits]) updateByIncr(number N, incr uint64) {
+	if within(number, h.zeroThreshold) {
+		h.zero.update(number, incr)
+		return
+	}
+	if h.sparse != nil {
+		h.sparse.updateByIncr(number, incr)
+		return
+	}
+	h.Histogram.UpdateByIncr(number, incr)
+}
+
+// maxScale returns the scale at which the storage starts.
+func (h *Histogram[N, Traits]) maxScale() int32 {
+	if h.sparse != nil {
+		return h.sparse.maxScale
+	}
+	return logarithm.MaxScale
+}
+
+// clearStorage resets the storage in use.
+func (h *Histogram[N, Traits]) clearStorage() {
+	h.zero = zeroBucket[N]{}
+	if h.sparse != nil {
+		h.sparse.clear()
+		return
+	}
+	h.Histogram.Clear()
+}
+
+// moveStorage exchanges the storage of h, which must be locked, with
+// the empty storage of to.  Sparse storage is exchanged by pointer,
+// leaving h with empty sparse storage of the same size.
+func (h *Histogram[N, Traits]) moveStorage(to *Histogram[N, Traits]) {
+	to.zeroThreshold = h.zeroThreshold
+	to.zero, h.zero = h.zero, zeroBucket[N]{}
+	if h.sparse == nil {
+		to.sparse = nil
+		h.Histogram.Swap(&to.Histogram)
+		return
+	}
+	h.sparse, to.sparse = to.sparse, h.sparse
+	if h.sparse == nil {
+		h.sparse = newSparse[N](to.sparse.maxSize, to.sparse.maxScale)
+	} else {
+		h.sparse.maxSize = to.sparse.maxSize
+		h.sparse.maxScale = to.sparse.maxScale
+		h.sparse.clear()
+	}
+	if h.carryScale {
+		// An empty histogram's mapping is the scale it was
+		// started at, which is carried again.
+		h.sparse.mapping = to.sparse.mapping
+	}
+}
+
+// copyStorage replaces the storage of to with a copy of the storage
+// in use by h, which must be locked.
+func (h *Histogram[N, Traits]) copyStorage(to *Histogram[N, Traits]) {
+	to.zeroThreshold = h.zeroThreshold
+	to.zero = h.zero
+	if h.sparse == nil {
+		to.sparse = nil
+		h.Histogram.CopyInto(&to.Histogram)
+		return
+	}
+	if to.sparse == nil {
+		to.sparse = &sparseHistogram[N]{}
+	}
+	h.sparse.copyInto(to.sparse)
+}
+
+// mergeStorage merges the storage of from into h, which must be
+// locked.  When either is sparse, the result is sparse.  The zero
+// buckets are added, and the larger zero threshold applies.
+func (h *Histogram[N, Traits]) mergeStorage(from *Histogram[N, Traits]) {
+	h.zeroThreshold = max(h.zeroThreshold, from.zeroThreshold)
+	h.zero.merge(from.zero)
+	if h.sparse == nil && from.sparse == nil {
+		h.Histogram.MergeFrom(&from.Histogram)
+		return
+	}
+	if h.sparse == nil {
+		h.sparse = newSparse[N](from.sparse.maxSize, from.sparse.maxScale)
+		h.sparse.mergeFrom(denseSource(&h.Histogram))
+		h.Histogram.Clear()
+	}
+	if from.sparse == nil {
+		h.sparse.mergeFrom(denseSource(&from.Histogram))
+		return
+	}
+	h.sparse.mergeFrom(from.sparse.source())
 }
 
 func (Methods[N, Traits]) HasChange(ptr *Histogram[N, Traits]) bool {
 	return ptr.Count() != 0
 }
 
-func (Methods[N, Traits]) Update(agg *Histogram[N, Traits], number N, _ aggregator.ExemplarBits) {
+// SizeOf estimates the histogram's bucket memory by its populated
+// range, which scales with the number of buckets in use.
+func (Methods[N, Traits]) SizeOf(ptr *Histogram[N, Traits]) int {
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	return ptr.sizeOf()
+}
+
+// sizeOf estimates the memory used by h, which must be locked.
+func (h *Histogram[N, Traits]) sizeOf() int {
+	size := int(unsafe.Sizeof(*h))
+	if h.sparse != nil {
+		size += int(unsafe.Sizeof(*h.sparse))
+		size += (cap(h.sparse.positive.buckets) + cap(h.sparse.negative.buckets)) * int(unsafe.Sizeof(sparseBucket{}))
+	} else {
+		size += int(h.Histogram.Positive().Len()+h.Histogram.Negative().Len()) * int(unsafe.Sizeof(uint64(0)))
+	}
+	if h.weighted != nil {
+		size += h.weighted.sizeOf()
+	}
+	return size
+}
+
+func (Methods[N, Traits]) Update(agg *Histogram[N, Traits], number N, ex aggregator.ExemplarBits) {
+	agg.lock.Lock()
+	defer agg.lock.Unlock()
+	if agg.update(number, 1, ex) {
+		aggregator.CheckInvariants[Histogram[N, Traits]](Methods[N, Traits]{}, "histogram Update", agg)
+	}
+}
+
+// UpdateBatch increments the bucket of number by count.
+func (Methods[N, Traits]) UpdateBatch(agg *Histogram[N, Traits], number N, count uint64, ex aggregator.ExemplarBits) {
+	if count == 0 {
+		return
+	}
 	agg.lock.Lock()
 	defer agg.lock.Unlock()
-	agg.Histogram.Update(number)
+	if agg.update(number, count, ex) {
+		aggregator.CheckInvariants[Histogram[N, Traits]](Methods[N, Traits]{}, "histogram UpdateBatch", agg)
+	}
+}
+
+// update adds count measurements of number to agg, which must be
+// locked, returning false when the value is rejected.
+func (agg *Histogram[N, Traits]) update(number N, count uint64, ex aggregator.ExemplarBits) bool {
+	// A non-finite value would break the scale computation.
+	if aggregator.RejectNonFiniteCount[N, Traits](agg.nonFinite, number, count, &agg.dropped) {
+		return false
+	}
+	if agg.extremes {
+		agg.updateExtremes(number, ex)
+	}
+	agg.updateByIncr(number, count)
+	if agg.weighted != nil {
+		weight := uint64(1)
+		if ex.HasSecondaryWeight {
+			weight = ex.SecondaryWeight
+		}
+		if weight != 0 {
+			agg.weighted.updateByIncr(number, weight*count)
+		}
+	}
+	return true
+}
+
+// weightedFor returns the weighted histogram of to, allocating it
+// like the one of from if necessary, or nil when from is not
+// weighted.
+func weightedFor[N number.Any, Traits number.Traits[N]](from, to *Histogram[N, Traits]) *Histogram[N, Traits] {
+	if from.weighted == nil {
+		to.weighted = nil
+		return nil
+	}
+	if to.weighted == nil {
+		to.weighted = newWeighted[N, Traits](from.weighted.weightedCfg, from.weighted.sparse != nil, from.weighted.maxScale(), from.weighted.zeroThreshold)
+	}
+	return to.weighted
+}
+
+// updateExtremes records the exemplar bits of a new minimum or
+// maximum value, called before the value is added to the histogram.
+func (agg *Histogram[N, Traits]) updateExtremes(number N, ex aggregator.ExemplarBits) {
+	first := agg.Count() == 0
+	newMin := first || number < agg.minValue()
+	newMax := first || number > agg.maxValue()
+	if !newMin && !newMax {
+		return
+	}
+	var traits Traits
+	if ex.Time.IsZero() {
+		ex.Time = time.Now()
+	}
+	ex.Number = traits.ToNumber(number)
+	if newMin {
+		agg.minEx = ex
+	}
+	if newMax {
+		agg.maxEx = ex
+	}
 }
 
 func (Methods[N, Traits]) Move(from, to *Histogram[N, Traits]) {
-	to.Histogram.Clear()
+	to.clearStorage()
 
 	from.lock.Lock()
 	defer from.lock.Unlock()
-	from.Histogram.Swap(&to.Histogram)
+	from.moveStorage(to)
+	if w := weightedFor(from, to); w != nil {
+		w.clearStorage()
+		from.weighted.moveStorage(w)
+	}
+
+	to.extremes = from.extremes
+	to.carryScale = from.carryScale
+	to.nonFinite = from.nonFinite
+	to.dropped, from.dropped = from.dropped, 0
+	to.minEx, from.minEx = from.minEx, aggregator.ExemplarBits{}
+	to.maxEx, from.maxEx = from.maxEx, aggregator.ExemplarBits{}
 }
 
 func (Methods[N, Traits]) Copy(from, to *Histogram[N, Traits]) {
 	from.lock.Lock()
 	defer from.lock.Unlock()
-	from.Histogram.CopyInto(&to.Histogram)
+	from.copyStorage(to)
+	if w := weightedFor(from, to); w != nil {
+		from.weighted.copyStorage(w)
+	}
+
+	to.extremes = from.extremes
+	to.carryScale = from.carryScale
+	to.nonFinite = from.nonFinite
+	to.dropped = from.dropped
+	to.minEx = from.minEx
+	to.maxEx = from.maxEx
 }
 
 func (Methods[N, Traits]) Merge(from, to *Histogram[N, Traits]) {
 	to.lock.Lock()
 	defer to.lock.Unlock()
-	to.Histogram.MergeFrom(&from.Histogram)
+
+	if from.extremes && from.Count() != 0 {
+		first := to.Count() == 0
+		if first || from.minValue() < to.minValue() {
+			to.minEx = from.minEx
+		}
+		if first || from.maxValue() > to.maxValue() {
+			to.maxEx = from.maxEx
+		}
+	}
+	to.mergeStorage(from)
+	to.dropped += from.dropped
+	if from.weighted != nil {
+		if to.weighted == nil {
+			to.weighted = newWeighted[N, Traits](from.weighted.weightedCfg, from.weighted.sparse != nil, from.weighted.maxScale(), from.weighted.zeroThreshold)
+		}
+		to.weighted.mergeStorage(from.weighted)
+	}
+	aggregator.CheckInvariants[Histogram[N, Traits]](Methods[N, Traits]{}, "histogram Merge", to)
 }
 
 func (Methods[N, Traits]) ToAggregation(histo *Histogram[N, Traits]) aggregation.Aggregation {
@@ -170,8 +566,46 @@ func (Methods[N, Traits]) SubtractSwap(operand, argument *Histogram[N, Traits])
 	panic("impossible call")
 }
 
+// Validate implements aggregator.Validator.  The count equals the
+// zero count plus the bucket counts, the sum is not NaN unless
+// non-finite values pass through, and the minimum does not exceed
+// the maximum.  The same holds for the weighted histogram, counting
+// weights.
+func (m Methods[N, Traits]) Validate(h *Histogram[N, Traits]) error {
+	buckets := h.ZeroCount() + bucketsTotal(h.Positive()) + bucketsTotal(h.Negative())
+	switch {
+	case h.Count() != buckets:
+		return fmt.Errorf("count %d differs from bucket total %d", h.Count(), buckets)
+	case h.nonFinite != aggregator.NonFinitePassThrough && math.IsNaN(float64(h.sumValue())):
+		return fmt.Errorf("sum is NaN")
+	case h.Count() != 0 && h.minValue() > h.maxValue():
+		return fmt.Errorf("min %v exceeds max %v", h.minValue(), h.maxValue())
+	}
+	if h.weighted != nil {
+		if err := m.Validate(h.weighted); err != nil {
+			return fmt.Errorf("weighted: %w", err)
+		}
+	}
+	return nil
+}
+
+// bucketsTotal returns the sum of the bucket counts.
+func bucketsTotal(b aggregation.Buckets) uint64 {
+	var total uint64
+	for i := uint32(0); i < b.Len(); i++ {
+		total += b.At(i)
+	}
+	return total
+}
+
 func (Methods[N, Traits]) Exemplars(ptr *Histogram[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
-	return in
+	if !ptr.extremes || ptr.Count() == 0 {
+		return in
+	}
+	return append(in,
+		aggregator.WeightedExemplarBits{ExemplarBits: ptr.minEx},
+		aggregator.WeightedExemplarBits{ExemplarBits: ptr.maxEx},
+	)
 }
 
 func (Methods[N, Traits]) Weight(_ N) float64 {
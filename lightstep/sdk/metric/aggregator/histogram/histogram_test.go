@@ -15,9 +15,14 @@
 package histogram // import "github.com/lightstep/go-expohisto"
 
 import (
+	"math"
+	"math/rand"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/lightstep/go-expohisto/structure"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
@@ -78,6 +83,309 @@ func TestMerge(t *testing.T) {
 	RequireEqualValues(t, h5, h4)
 }
 
+// Tests that merging histograms at different scales downscales to
+// the coarser scale, conserving the counts and the sum.
+func TestMergeDivergentScales(t *testing.T) {
+	var mf Float64Methods
+
+	// Values are exact, so that sums do not depend on the order.
+	var narrow, wide []float64
+	for i := 0; i < 50; i++ {
+		narrow = append(narrow, 1+float64(i)/1024)
+	}
+	for i := 0; i < 40; i++ {
+		wide = append(wide, math.Ldexp(1, i))
+	}
+	expect := NewFloat64(NewConfig(), append(append([]float64{}, wide...), narrow...)...)
+
+	for _, sparse := range []bool{false, true} {
+		cfg := aggregator.Config{
+			Histogram:       NewConfig(),
+			HistogramSparse: sparse,
+		}
+		newHisto := func(values []float64) *Float64 {
+			var h Float64
+			mf.Init(&h, cfg)
+			for _, v := range values {
+				mf.Update(&h, v, aggregator.ExemplarBits{})
+			}
+			return &h
+		}
+
+		// Merge in both directions.
+		for _, order := range [][2][]float64{{narrow, wide}, {wide, narrow}} {
+			from := newHisto(order[0])
+			to := newHisto(order[1])
+			require.NotEqual(t, from.Scale(), to.Scale())
+
+			minScale := min(from.Scale(), to.Scale())
+			mf.Merge(from, to)
+
+			require.LessOrEqual(t, to.Scale(), minScale)
+			require.Equal(t, uint64(len(narrow)+len(wide)), bucketsTotal(to.Positive()))
+			require.NoError(t, mf.Validate(to))
+			RequireEqualValues(t, expect, to)
+		}
+	}
+}
+
+// Tests that the extreme observations are recorded with their time.
+func TestExtremes(t *testing.T) {
+	var mf Float64Methods
+
+	start := time.Unix(1000, 0)
+	at := func(i int) aggregator.ExemplarBits {
+		return aggregator.ExemplarBits{Time: start.Add(time.Duration(i) * time.Second)}
+	}
+	cfg := aggregator.Config{
+		Histogram:         NewConfig(),
+		HistogramExtremes: true,
+	}
+
+	var h1, h2, h3 Float64
+	mf.Init(&h1, cfg)
+	mf.Init(&h2, cfg)
+	mf.Init(&h3, cfg)
+
+	for i, v := range []float64{5, 3, 7, 1, 9, 4} {
+		mf.Update(&h1, v, at(i))
+	}
+	require.Equal(t, at(3).Time, h1.MinTime())
+	require.Equal(t, at(4).Time, h1.MaxTime())
+
+	ex := mf.Exemplars(&h1, nil)
+	require.Equal(t, 2, len(ex))
+	require.Equal(t, 1.0, ex[0].Number.CoerceToFloat64(number.Float64Kind))
+	require.Equal(t, at(3).Time, ex[0].Time)
+	require.Equal(t, 9.0, ex[1].Number.CoerceToFloat64(number.Float64Kind))
+	require.Equal(t, at(4).Time, ex[1].Time)
+
+	for i, v := range []float64{2, 11} {
+		mf.Update(&h2, v, at(10+i))
+	}
+
+	// Merge keeps the smaller minimum and larger maximum.
+	mf.Merge(&h1, &h3)
+	mf.Merge(&h2, &h3)
+	require.Equal(t, at(3).Time, h3.MinTime())
+	require.Equal(t, at(11).Time, h3.MaxTime())
+
+	// Move resets the extremes of the input.
+	var h4 Float64
+	mf.Init(&h4, cfg)
+	mf.Move(&h3, &h4)
+	require.Equal(t, at(3).Time, h4.MinTime())
+	require.Equal(t, at(11).Time, h4.MaxTime())
+	require.True(t, h3.MinTime().IsZero())
+	require.Equal(t, 0, len(mf.Exemplars(&h3, nil)))
+
+	// Without the option, nothing is recorded.
+	h5 := NewFloat64(NewConfig(), 1, 2, 3)
+	require.True(t, h5.MinTime().IsZero())
+	require.Equal(t, 0, len(mf.Exemplars(h5, nil)))
+}
+
+func TestWeighted(t *testing.T) {
+	var mf Float64Methods
+
+	weight := func(w uint64) aggregator.ExemplarBits {
+		return aggregator.ExemplarBits{SecondaryWeight: w, HasSecondaryWeight: true}
+	}
+	cfg := aggregator.Config{
+		Histogram:         NewConfig(),
+		HistogramWeighted: true,
+	}
+
+	var h1, h2, h3 Float64
+	mf.Init(&h1, cfg)
+	mf.Init(&h2, cfg)
+	mf.Init(&h3, cfg)
+
+	mf.Update(&h1, 1, weight(10))
+	mf.Update(&h1, 2, weight(1))
+	mf.Update(&h1, 4, weight(0))
+	mf.Update(&h2, 8, weight(5))
+
+	require.Equal(t, uint64(3), h1.Count())
+	require.Equal(t, 7.0, number.ToFloat64(h1.Sum()))
+	require.Equal(t, uint64(11), h1.Weighted().Count())
+	require.Equal(t, 12.0, number.ToFloat64(h1.Weighted().Sum()))
+	require.Equal(t, 2.0, number.ToFloat64(h1.Weighted().Max()))
+
+	// Merge combines both histograms.
+	mf.Merge(&h1, &h3)
+	mf.Merge(&h2, &h3)
+	require.Equal(t, uint64(4), h3.Count())
+	require.Equal(t, uint64(16), h3.Weighted().Count())
+	require.Equal(t, 52.0, number.ToFloat64(h3.Weighted().Sum()))
+
+	// Copy and Move allocate the weighted histogram of the output.
+	var h4, h5 Float64
+	mf.Init(&h4, aggregator.Config{Histogram: NewConfig()})
+	mf.Init(&h5, aggregator.Config{Histogram: NewConfig()})
+	mf.Copy(&h3, &h4)
+	RequireEqualValues(t, h3.Weighted(), h4.Weighted())
+
+	mf.Move(&h4, &h5)
+	RequireEqualValues(t, h3.Weighted(), h5.Weighted())
+	require.Equal(t, uint64(0), h4.Weighted().Count())
+
+	// Without the option, there is no weighted histogram.
+	h6 := NewFloat64(NewConfig(), 1, 2, 3)
+	require.Nil(t, h6.Weighted())
+}
+
+// Tests that sparse and dense storage produce identical results for
+// every operation, including mixed merges.
+func TestSparseIdentical(t *testing.T) {
+	var mf Float64Methods
+
+	rnd := rand.New(rand.NewSource(77))
+	random := func() float64 {
+		v := math.Exp(rnd.NormFloat64() * 20)
+		switch rnd.Intn(4) {
+		case 0:
+			return -v
+		case 1:
+			return 0
+		}
+		return v
+	}
+
+	for _, size := range []int32{MinSize, 20, DefaultMaxSize} {
+		dcfg := aggregator.Config{
+			Histogram:         NewConfig(WithMaxSize(size)),
+			HistogramWeighted: true,
+		}
+		scfg := dcfg
+		scfg.HistogramSparse = true
+
+		require.Equal(t, size, maxSizeOf(dcfg.Histogram))
+
+		for trial := 0; trial < 20; trial++ {
+			var d1, d2, s1, s2 Float64
+			mf.Init(&d1, dcfg)
+			mf.Init(&d2, dcfg)
+			mf.Init(&s1, scfg)
+			mf.Init(&s2, scfg)
+
+			for i := rnd.Intn(100); i > 0; i-- {
+				v := random()
+				ex := aggregator.ExemplarBits{SecondaryWeight: uint64(rnd.Intn(3)), HasSecondaryWeight: true}
+				mf.Update(&d1, v, ex)
+				mf.Update(&s1, v, ex)
+			}
+			for i := rnd.Intn(100); i > 0; i-- {
+				v := random() * 1e3
+				mf.Update(&d2, v, aggregator.ExemplarBits{})
+				mf.Update(&s2, v, aggregator.ExemplarBits{})
+			}
+			requireIdentical(t, &d1, &s1)
+			requireIdentical(t, &d2, &s2)
+
+			// Merge into each kind of storage from each kind.
+			var dd, ds, sd, ss Float64
+			mf.Init(&dd, dcfg)
+			mf.Init(&ds, dcfg)
+			mf.Init(&sd, scfg)
+			mf.Init(&ss, scfg)
+			mf.Merge(&d1, &dd)
+			mf.Merge(&d2, &dd)
+			mf.Merge(&s1, &ds)
+			mf.Merge(&s2, &ds)
+			mf.Merge(&d1, &sd)
+			mf.Merge(&d2, &sd)
+			mf.Merge(&s1, &ss)
+			mf.Merge(&s2, &ss)
+			requireIdentical(t, &dd, &ds)
+			requireIdentical(t, &dd, &sd)
+			requireIdentical(t, &dd, &ss)
+
+			// Copy and Move.
+			var dc, sc, dm, sm Float64
+			mf.Init(&dc, dcfg)
+			mf.Init(&dm, dcfg)
+			mf.Init(&sc, scfg)
+			mf.Init(&sm, scfg)
+			mf.Copy(&dd, &dc)
+			mf.Copy(&ss, &sc)
+			requireIdentical(t, &dc, &sc)
+			mf.Move(&dc, &dm)
+			mf.Move(&sc, &sm)
+			requireIdentical(t, &dd, &sm)
+			requireIdentical(t, &dc, &sc)
+			require.Equal(t, uint64(0), sc.Count())
+
+			// The moved-from storage is ready to reuse.
+			mf.Update(&dc, 1, aggregator.ExemplarBits{})
+			mf.Update(&sc, 1, aggregator.ExemplarBits{})
+			requireIdentical(t, &dc, &sc)
+		}
+	}
+}
+
+func requireIdentical(t *testing.T, a, b *Float64) {
+	RequireEqualValues(t, a, b)
+	require.Equal(t, a.ZeroCount(), b.ZeroCount())
+	if a.Weighted() != nil {
+		RequireEqualValues(t, a.Weighted(), b.Weighted())
+	}
+}
+
+// Tests that sparse storage uses less memory for high-resolution
+// series with few, widely spread observations.
+func TestSparseMemory(t *testing.T) {
+	var mf Float64Methods
+	const series = 1000
+
+	heapFor := func(cfg aggregator.Config) uint64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		hs := make([]Float64, series)
+		for i := range hs {
+			mf.Init(&hs[i], cfg)
+			for _, v := range []float64{1e-3, 1, 1e3, 1e6} {
+				mf.Update(&hs[i], v*float64(i+1), aggregator.ExemplarBits{})
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(hs)
+		return after.HeapAlloc - before.HeapAlloc
+	}
+
+	hcfg := NewConfig(WithMaxSize(4096))
+	dense := heapFor(aggregator.Config{Histogram: hcfg})
+	sparse := heapFor(aggregator.Config{Histogram: hcfg, HistogramSparse: true})
+
+	require.Less(t, sparse, dense)
+}
+
+// Tests that the size estimate scales with the buckets in use and
+// reflects the savings of sparse storage.
+func TestSizeOf(t *testing.T) {
+	var mf Float64Methods
+
+	hcfg := NewConfig(WithMaxSize(4096))
+	sizes := map[bool][2]int{}
+	for _, sparse := range []bool{false, true} {
+		var h Float64
+		mf.Init(&h, aggregator.Config{Histogram: hcfg, HistogramSparse: sparse})
+		mf.Update(&h, 1, aggregator.ExemplarBits{})
+		one := mf.SizeOf(&h)
+
+		for _, v := range []float64{1e-3, 1e3, 1e6} {
+			mf.Update(&h, v, aggregator.ExemplarBits{})
+		}
+		sizes[sparse] = [2]int{one, mf.SizeOf(&h)}
+		require.Less(t, one, mf.SizeOf(&h))
+	}
+	require.Less(t, sizes[true][1], sizes[false][1])
+}
+
 func TestAggregatorToFrom(t *testing.T) {
 	var mi Int64Methods
 	var mf Float64Methods
@@ -104,3 +412,354 @@ func TestInt64Histogram(t *testing.T) {
 func TestFloat64Histogram(t *testing.T) {
 	test.GenericAggregatorTest[float64, Float64, Float64Methods](t, number.ToFloat64)
 }
+
+func TestValidate(t *testing.T) {
+	var methods Float64Methods
+	cfg := aggregator.Config{
+		Histogram:         NewConfig(),
+		HistogramSparse:   true,
+		HistogramWeighted: true,
+	}
+
+	var h Float64
+	methods.Init(&h, cfg)
+	for _, v := range []float64{-1, 0, 1, 2} {
+		methods.Update(&h, v, aggregator.ExemplarBits{})
+	}
+	require.NoError(t, methods.Validate(&h))
+
+	h.sparse.count++
+	require.ErrorContains(t, methods.Validate(&h), "count 5 differs from bucket total 4")
+	h.sparse.count--
+
+	h.weighted.sparse.max = -2
+	require.ErrorContains(t, methods.Validate(&h), "weighted: min -1 exceeds max -2")
+	h.weighted.sparse.max = 2
+
+	// Only a debug build checks after each operation.
+	h.sparse.count++
+	update := func() { methods.Update(&h, 3, aggregator.ExemplarBits{}) }
+	if aggregator.DebugInvariants {
+		require.Panics(t, update)
+	} else {
+		require.NotPanics(t, update)
+	}
+}
+
+// Tests that non-finite updates are dropped, and counted, according
+// to the policy.
+func TestNonFinitePolicy(t *testing.T) {
+	var mf Float64Methods
+	nonFinite := []float64{math.Inf(+1), math.Inf(-1), math.NaN()}
+
+	for _, policy := range []aggregator.NonFinitePolicy{
+		aggregator.NonFiniteDrop,
+		aggregator.NonFiniteDropAndCount,
+	} {
+		var h Float64
+		mf.Init(&h, aggregator.Config{NonFinite: policy})
+
+		mf.Update(&h, 2, aggregator.ExemplarBits{})
+		for _, x := range nonFinite {
+			mf.Update(&h, x, aggregator.ExemplarBits{})
+		}
+		mf.Update(&h, 4, aggregator.ExemplarBits{})
+
+		RequireEqualValues(t, NewFloat64(NewConfig(), 2, 4), &h)
+		require.NoError(t, mf.Validate(&h))
+
+		expect := uint64(0)
+		if policy == aggregator.NonFiniteDropAndCount {
+			expect = uint64(len(nonFinite))
+		}
+		require.Equal(t, expect, h.Dropped())
+
+		// The count follows merges and moves.
+		var merged, moved Float64
+		mf.Init(&merged, aggregator.Config{})
+		mf.Merge(&h, &merged)
+		mf.Merge(&h, &merged)
+		require.Equal(t, 2*expect, merged.Dropped())
+
+		mf.Move(&merged, &moved)
+		require.Equal(t, 2*expect, moved.Dropped())
+		require.Equal(t, uint64(0), merged.Dropped())
+	}
+
+	// Passed through, the values reach the histogram.
+	var h Float64
+	mf.Init(&h, aggregator.Config{})
+	mf.Update(&h, math.Inf(+1), aggregator.ExemplarBits{})
+	require.Equal(t, uint64(1), h.Count())
+	require.Equal(t, uint64(0), h.Dropped())
+}
+
+// Tests that observations within the zero threshold, inclusive of
+// the threshold and of either sign, are counted in the zero bucket
+// while the sum and range remain exact.
+func TestZeroThreshold(t *testing.T) {
+	var mf Float64Methods
+	for _, sparse := range []bool{false, true} {
+		cfg := aggregator.Config{
+			Histogram:              NewConfig(),
+			HistogramSparse:        sparse,
+			HistogramZeroThreshold: 0.5,
+		}
+		var h Float64
+		mf.Init(&h, cfg)
+		for _, v := range []float64{0.5, -0.5, -0.25, 0, 3, -2} {
+			mf.Update(&h, v, aggregator.ExemplarBits{})
+		}
+		mf.UpdateBatch(&h, 0.125, 2, aggregator.ExemplarBits{})
+
+		require.Equal(t, 0.5, h.ZeroThreshold())
+		require.Equal(t, uint64(8), h.Count())
+		require.Equal(t, uint64(6), h.ZeroCount())
+		require.Equal(t, 1.0, number.ToFloat64(h.Sum()))
+		require.Equal(t, -2.0, number.ToFloat64(h.Min()))
+		require.Equal(t, 3.0, number.ToFloat64(h.Max()))
+		require.Equal(t, uint64(1), bucketsTotal(h.Positive()))
+		require.Equal(t, uint64(1), bucketsTotal(h.Negative()))
+		require.NoError(t, mf.Validate(&h))
+
+		// Values just outside the threshold are bucketed.
+		var edge Float64
+		mf.Init(&edge, cfg)
+		mf.Update(&edge, math.Nextafter(0.5, 1), aggregator.ExemplarBits{})
+		mf.Update(&edge, math.Nextafter(-0.5, -1), aggregator.ExemplarBits{})
+		require.Equal(t, uint64(0), edge.ZeroCount())
+
+		// The range of the zero bucket alone is the range.
+		var only Float64
+		mf.Init(&only, cfg)
+		mf.Update(&only, -0.25, aggregator.ExemplarBits{})
+		mf.Update(&only, 0.25, aggregator.ExemplarBits{})
+		require.Equal(t, -0.25, number.ToFloat64(only.Min()))
+		require.Equal(t, 0.25, number.ToFloat64(only.Max()))
+		require.Equal(t, uint64(2), only.ZeroCount())
+		require.Equal(t, int32(0), only.Scale())
+
+		// Merge adds the zero buckets, taking the larger
+		// threshold.
+		var merged Float64
+		mf.Init(&merged, aggregator.Config{Histogram: NewConfig()})
+		mf.Merge(&h, &merged)
+		mf.Merge(&only, &merged)
+		require.Equal(t, 0.5, merged.ZeroThreshold())
+		require.Equal(t, uint64(10), merged.Count())
+		require.Equal(t, uint64(8), merged.ZeroCount())
+		require.Equal(t, 1.0, number.ToFloat64(merged.Sum()))
+		require.NoError(t, mf.Validate(&merged))
+
+		// Copy and Move carry the zero bucket.
+		var copied, moved Float64
+		mf.Init(&copied, aggregator.Config{Histogram: NewConfig()})
+		mf.Init(&moved, aggregator.Config{Histogram: NewConfig()})
+		mf.Copy(&merged, &copied)
+		require.Equal(t, uint64(8), copied.ZeroCount())
+		require.Equal(t, 0.5, copied.ZeroThreshold())
+
+		mf.Move(&copied, &moved)
+		require.Equal(t, uint64(8), moved.ZeroCount())
+		require.Equal(t, 1.0, number.ToFloat64(moved.Sum()))
+		require.Equal(t, uint64(0), copied.Count())
+		require.Equal(t, uint64(0), copied.ZeroCount())
+		require.Equal(t, 0.0, number.ToFloat64(copied.Sum()))
+	}
+
+	// Integer thresholds count the integers within them.
+	var mi Int64Methods
+	var hi Int64
+	mi.Init(&hi, aggregator.Config{Histogram: NewConfig(), HistogramZeroThreshold: 1})
+	for _, v := range []int64{-1, 0, 1, 2, -2} {
+		mi.Update(&hi, v, aggregator.ExemplarBits{})
+	}
+	require.Equal(t, uint64(3), hi.ZeroCount())
+	require.Equal(t, int64(0), number.ToInt64(hi.Sum()))
+	require.Equal(t, int64(-2), number.ToInt64(hi.Min()))
+}
+
+// Tests that sparse storage carries its scale to the next interval
+// when configured.
+func TestCarryScale(t *testing.T) {
+	var mf Float64Methods
+
+	for _, carry := range []bool{false, true} {
+		cfg := aggregator.Config{
+			Histogram:           NewConfig(WithMaxSize(MinSize)),
+			HistogramSparse:     true,
+			HistogramCarryScale: carry,
+		}
+		var current, moved, output Float64
+		mf.Init(&current, cfg)
+		mf.Init(&moved, cfg)
+		mf.Init(&output, cfg)
+
+		for v := 1.0; v <= 1000; v *= 2 {
+			mf.Update(&current, v, aggregator.ExemplarBits{})
+		}
+		scale := current.Scale()
+		mf.Move(&current, &moved)
+
+		// The first value of the next interval is placed at
+		// the carried scale, else at the maximum scale.
+		mf.Update(&current, 3, aggregator.ExemplarBits{})
+		if carry {
+			require.Equal(t, scale, current.Scale())
+		} else {
+			require.Equal(t, int32(20), current.Scale())
+		}
+
+		// A merge into an empty destination starts from the
+		// carried scale, too.
+		mf.Merge(&current, &output)
+		mf.Move(&output, &moved)
+		mf.Update(&output, 5, aggregator.ExemplarBits{})
+		require.Equal(t, current.Scale(), output.Scale())
+
+		// Observations spanning the scale produce the same
+		// result as in a new histogram.
+		var fresh Float64
+		mf.Init(&fresh, cfg)
+		mf.Move(&current, &moved)
+		for v := 1.0; v <= 1000; v *= 2 {
+			mf.Update(&current, v, aggregator.ExemplarBits{})
+			mf.Update(&fresh, v, aggregator.ExemplarBits{})
+		}
+		RequireEqualValues(t, &fresh, &current)
+	}
+}
+
+// BenchmarkCarryScale reports the changes of scale per delta
+// interval with and without carrying the scale.
+func BenchmarkCarryScale(b *testing.B) {
+	for _, carry := range []bool{false, true} {
+		name := "reset"
+		if carry {
+			name = "carry"
+		}
+		b.Run(name, func(b *testing.B) {
+			var mf Float64Methods
+			cfg := aggregator.Config{
+				Histogram:           NewConfig(),
+				HistogramSparse:     true,
+				HistogramCarryScale: carry,
+			}
+			var current, snapshot Float64
+			mf.Init(&current, cfg)
+			mf.Init(&snapshot, cfg)
+
+			rnd := rand.New(rand.NewSource(1))
+			var rescales int
+			scale := int32(20)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !carry {
+					scale = 20
+				}
+				for j := 0; j < 100; j++ {
+					mf.Update(&current, math.Exp(rnd.NormFloat64()*5), aggregator.ExemplarBits{})
+					if s := current.Scale(); s != scale {
+						scale = s
+						rescales++
+					}
+				}
+				mf.Move(&current, &snapshot)
+			}
+			b.ReportMetric(float64(rescales)/float64(b.N), "rescales/interval")
+		})
+	}
+}
+
+// Tests that int64 histograms sum large values exactly, beyond the
+// integers a float64 can represent.
+func TestInt64LargeSum(t *testing.T) {
+	var mi Int64Methods
+
+	const base = int64(1) << 60
+	for _, cfg := range []aggregator.Config{
+		{Histogram: NewConfig()},
+		{Histogram: NewConfig(), HistogramSparse: true},
+		{Histogram: NewConfig(), HistogramZeroThreshold: 10},
+	} {
+		var a, b, merged Int64
+		mi.Init(&a, cfg)
+		mi.Init(&b, cfg)
+		mi.Init(&merged, cfg)
+
+		var expect int64
+		for i := int64(1); i <= 7; i++ {
+			mi.Update(&a, base+i, aggregator.ExemplarBits{})
+			mi.UpdateBatch(&b, i, 3, aggregator.ExemplarBits{})
+			expect += base + 4*i
+		}
+		require.Equal(t, 7*base+28, number.ToInt64(a.Sum()))
+		require.Equal(t, base+1, number.ToInt64(a.Min()))
+		require.Equal(t, base+7, number.ToInt64(a.Max()))
+
+		// A float64 cannot distinguish these values.
+		require.Equal(t, float64(base), float64(base+7))
+
+		mi.Merge(&a, &merged)
+		mi.Merge(&b, &merged)
+		require.Equal(t, expect, number.ToInt64(merged.Sum()))
+		require.Equal(t, uint64(28), merged.Count())
+	}
+}
+
+// Tests that a limited scale is where the histogram starts, that it
+// is kept by Move and Merge, and that the size still reduces it.
+func TestMaxScale(t *testing.T) {
+	var mf Float64Methods
+
+	cfg := aggregator.Config{
+		Histogram:           NewConfig(WithMaxSize(20)),
+		HistogramWeighted:   true,
+		HistogramLimitScale: true,
+		HistogramMaxScale:   3,
+	}
+	var h, moved, merged, dense Float64
+	mf.Init(&h, cfg)
+	mf.Init(&moved, cfg)
+	mf.Init(&merged, cfg)
+	mf.Init(&dense, aggregator.Config{Histogram: cfg.Histogram})
+
+	mf.Update(&h, 1.5, aggregator.ExemplarBits{})
+	mf.Update(&h, 1.6, aggregator.ExemplarBits{})
+	require.Equal(t, int32(3), h.Scale())
+	require.Equal(t, int32(3), h.Weighted().Scale())
+
+	mf.Move(&h, &moved)
+	mf.Update(&h, 1.5, aggregator.ExemplarBits{})
+	require.Equal(t, int32(3), h.Scale())
+
+	// A merge from the dense storage at the maximum scale is
+	// reduced to the limit.
+	mf.Update(&dense, 1.5, aggregator.ExemplarBits{})
+	require.Equal(t, int32(20), dense.Scale())
+	mf.Merge(&dense, &merged)
+	require.Equal(t, int32(3), merged.Scale())
+	require.Equal(t, uint64(1), merged.Count())
+
+	// A wide range of values is held in at most the maximum
+	// size, at a lower scale.
+	for v := 1e-10; v < 1e10; v *= 1.1 {
+		mf.Update(&h, v, aggregator.ExemplarBits{})
+		mf.Update(&h, -v, aggregator.ExemplarBits{})
+	}
+	require.Less(t, h.Scale(), int32(3))
+	require.LessOrEqual(t, h.Positive().Len(), uint32(20))
+	require.LessOrEqual(t, h.Negative().Len(), uint32(20))
+	require.NoError(t, mf.Validate(&h))
+
+	// A limit of the maximum scale matches the default.
+	cfg.HistogramMaxScale = 20
+	var limited, unlimited Float64
+	mf.Init(&limited, cfg)
+	mf.Init(&unlimited, aggregator.Config{Histogram: cfg.Histogram})
+	for v := 1e-3; v < 1e3; v *= 1.7 {
+		mf.Update(&limited, v, aggregator.ExemplarBits{})
+		mf.Update(&unlimited, v, aggregator.ExemplarBits{})
+	}
+	RequireEqualValues(t, &unlimited, &limited)
+}
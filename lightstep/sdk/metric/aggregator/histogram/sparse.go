@@ -0,0 +1,469 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogram // import "github.com/lightstep/go-expohisto"
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/lightstep/go-expohisto/mapping"
+	"github.com/lightstep/go-expohisto/mapping/exponent"
+	"github.com/lightstep/go-expohisto/mapping/logarithm"
+	"github.com/lightstep/go-expohisto/structure"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+)
+
+// The code in this file implements the exponential histogram of
+// ./structure with sparse bucket storage.  A dense histogram keeps a
+// counter for every bucket between its lowest and highest populated
+// index, which is wasteful when few of them are populated.  Here,
+// only populated buckets are stored, in order of index, so updates
+// cost a binary search and sometimes an insertion.  The scale and
+// bucket counts are computed exactly as in the dense form, so both
+// produce identical output for the same input.  Unlike the dense
+// form, the sparse form can start below the maximum scale, so it is
+// also used to limit the scale.
+
+type (
+	// sparseHistogram is an exponential histogram with sparse
+	// bucket storage.
+	sparseHistogram[N structure.ValueType] struct {
+		maxSize   int32
+		maxScale  int32
+		sum       N
+		count     uint64
+		zeroCount uint64
+		min       N
+		max       N
+		positive  sparseBuckets
+		negative  sparseBuckets
+		mapping   mapping.Mapping
+	}
+
+	// sparseBuckets holds the populated buckets of one range in
+	// increasing order of index.
+	sparseBuckets struct {
+		buckets []sparseBucket
+	}
+
+	// sparseBucket is one populated bucket.
+	sparseBucket struct {
+		index int32
+		count uint64
+	}
+
+	// highLow is the range of bucket indexes needed at a scale.
+	highLow struct {
+		low  int32
+		high int32
+	}
+
+	// bucketSource supplies the buckets of a histogram to merge.
+	bucketSource interface {
+		aggregation.Buckets
+
+		// each calls f for each populated bucket.
+		each(f func(index int32, count uint64))
+	}
+
+	// histogramSource is the content of a histogram, either
+	// dense or sparse, to merge into a sparse histogram.
+	histogramSource[N structure.ValueType] struct {
+		sum       N
+		count     uint64
+		zeroCount uint64
+		min       N
+		max       N
+		scale     int32
+		positive  bucketSource
+		negative  bucketSource
+	}
+
+	// denseBuckets adapts the buckets of a dense histogram.
+	denseBuckets struct {
+		*structure.Buckets
+	}
+)
+
+var _ aggregation.Buckets = &sparseBuckets{}
+
+// maxSizes caches the result of maxSizeOf.
+var maxSizes sync.Map // map[Config]int32
+
+// maxSizeOf returns the maximum size of a configuration.  The size
+// is not exposed by Config, so it is found by observing the index
+// span at which a dense histogram first changes scale.
+func maxSizeOf(cfg Config) int32 {
+	cfg, _ = cfg.Validate()
+	if sz, ok := maxSizes.Load(cfg); ok {
+		return sz.(int32)
+	}
+	m, _ := logarithm.NewMapping(logarithm.MaxScale)
+	valueAt := func(index int32) float64 {
+		lower, _ := m.LowerBoundary(index)
+		upper, _ := m.LowerBoundary(index + 1)
+		return (lower + upper) / 2
+	}
+	// The smallest span causing a change of scale is the size.
+	size := int32(structure.MinSize + sort.Search(structure.MaximumMaxSize-structure.MinSize, func(i int) bool {
+		var h structure.Float64
+		h.Init(cfg)
+		h.Update(valueAt(0))
+		h.Update(valueAt(int32(structure.MinSize + i)))
+		return h.Scale() != logarithm.MaxScale
+	}))
+	maxSizes.Store(cfg, size)
+	return size
+}
+
+// maxScaleOf returns the scale at which a histogram of the
+// configuration starts, which is logarithm.MaxScale unless
+// aggregator.Config.HistogramLimitScale is set.
+func maxScaleOf(cfg aggregator.Config) int32 {
+	if !cfg.HistogramLimitScale {
+		return logarithm.MaxScale
+	}
+	return min(max(cfg.HistogramMaxScale, exponent.MinScale), logarithm.MaxScale)
+}
+
+// newSparse returns an empty sparse histogram, which starts at
+// maxScale.
+func newSparse[N structure.ValueType](maxSize, maxScale int32) *sparseHistogram[N] {
+	s := &sparseHistogram[N]{
+		maxSize:  maxSize,
+		maxScale: maxScale,
+	}
+	s.clear()
+	return s
+}
+
+func newMapping(scale int32) mapping.Mapping {
+	var m mapping.Mapping
+	var err error
+	if scale <= 0 {
+		m, err = exponent.NewMapping(scale)
+	} else {
+		m, err = logarithm.NewMapping(scale)
+	}
+	if err != nil {
+		panic(fmt.Sprint("impossible scale ", scale))
+	}
+	return m
+}
+
+// clear resets the histogram, retaining its storage.
+func (s *sparseHistogram[N]) clear() {
+	s.sum = 0
+	s.count = 0
+	s.zeroCount = 0
+	s.min = 0
+	s.max = 0
+	s.positive.clear()
+	s.negative.clear()
+	s.mapping = newMapping(s.maxScale)
+}
+
+// copyInto replaces the contents of dest, re-using its storage.
+func (s *sparseHistogram[N]) copyInto(dest *sparseHistogram[N]) {
+	dest.maxSize = s.maxSize
+	dest.maxScale = s.maxScale
+	dest.sum = s.sum
+	dest.count = s.count
+	dest.zeroCount = s.zeroCount
+	dest.min = s.min
+	dest.max = s.max
+	s.positive.copyInto(&dest.positive)
+	s.negative.copyInto(&dest.negative)
+	dest.mapping = s.mapping
+}
+
+// scale returns the scale, which is zero when there are no buckets.
+func (s *sparseHistogram[N]) scale() int32 {
+	if s.count == s.zeroCount {
+		return 0
+	}
+	return s.mapping.Scale()
+}
+
+// source returns the contents of this histogram for merging.
+func (s *sparseHistogram[N]) source() histogramSource[N] {
+	return histogramSource[N]{
+		sum:       s.sum,
+		count:     s.count,
+		zeroCount: s.zeroCount,
+		min:       s.min,
+		max:       s.max,
+		scale:     s.scale(),
+		positive:  &s.positive,
+		negative:  &s.negative,
+	}
+}
+
+// denseSource returns the contents of a dense histogram for merging.
+func denseSource[N structure.ValueType](h *structure.Histogram[N]) histogramSource[N] {
+	return histogramSource[N]{
+		sum:       h.Sum(),
+		count:     h.Count(),
+		zeroCount: h.ZeroCount(),
+		min:       h.Min(),
+		max:       h.Max(),
+		scale:     h.Scale(),
+		positive:  denseBuckets{h.Positive()},
+		negative:  denseBuckets{h.Negative()},
+	}
+}
+
+// updateByIncr adds incr observations of number.
+func (s *sparseHistogram[N]) updateByIncr(number N, incr uint64) {
+	value := float64(number)
+
+	if s.count == 0 {
+		s.min = number
+		s.max = number
+	} else {
+		if number < s.min {
+			s.min = number
+		}
+		if number > s.max {
+			s.max = number
+		}
+	}
+
+	s.count += incr
+
+	if value == 0 {
+		s.zeroCount += incr
+		return
+	}
+
+	s.sum += number * N(incr)
+
+	b := &s.positive
+	if value < 0 {
+		value = -value
+		b = &s.negative
+	}
+	index := s.mapping.MapToIndex(value)
+	if hl, ok := s.incrementIndexBy(b, index, incr); !ok {
+		s.downscale(changeScale(hl, s.maxSize))
+
+		index = s.mapping.MapToIndex(value)
+		if _, ok := s.incrementIndexBy(b, index, incr); !ok {
+			panic("downscale logic error")
+		}
+	}
+}
+
+// incrementIndexBy increments a bucket, unless the index lies too far
+// outside the current range, in which case it returns the range
+// needed.
+func (s *sparseHistogram[N]) incrementIndexBy(b *sparseBuckets, index int32, incr uint64) (highLow, bool) {
+	if incr == 0 {
+		return highLow{}, true
+	}
+	if n := len(b.buckets); n != 0 {
+		low, high := b.buckets[0].index, b.buckets[n-1].index
+		if index < low && high-index >= s.maxSize {
+			return highLow{low: index, high: high}, false
+		}
+		if index > high && index-low >= s.maxSize {
+			return highLow{low: low, high: index}, false
+		}
+	}
+	b.increment(index, incr)
+	return highLow{}, true
+}
+
+// downscale subtracts change from the current scale.
+func (s *sparseHistogram[N]) downscale(change int32) {
+	if change == 0 {
+		return
+	}
+	if change < 0 {
+		panic(fmt.Sprint("impossible change of scale ", change))
+	}
+	s.positive.downscale(change)
+	s.negative.downscale(change)
+	s.mapping = newMapping(s.mapping.Scale() - change)
+}
+
+// mergeFrom combines the contents of another histogram into this one.
+func (s *sparseHistogram[N]) mergeFrom(o histogramSource[N]) {
+	if s.count == 0 {
+		s.min = o.min
+		s.max = o.max
+	} else if o.count != 0 {
+		if o.min < s.min {
+			s.min = o.min
+		}
+		if o.max > s.max {
+			s.max = o.max
+		}
+	}
+
+	s.sum += o.sum
+	s.count += o.count
+	s.zeroCount += o.zeroCount
+
+	// A histogram holding only zeros reports scale zero, which
+	// may exceed the limit.
+	minScale := min(s.scale(), o.scale, s.maxScale)
+
+	hlp := highLowAtScale(&s.positive, s.scale()-minScale).with(highLowAtScale(o.positive, o.scale-minScale))
+	hln := highLowAtScale(&s.negative, s.scale()-minScale).with(highLowAtScale(o.negative, o.scale-minScale))
+
+	minScale = min(
+		minScale-changeScale(hlp, s.maxSize),
+		minScale-changeScale(hln, s.maxSize),
+	)
+
+	s.downscale(s.scale() - minScale)
+
+	s.mergeBuckets(&s.positive, o.positive, o.scale-minScale)
+	s.mergeBuckets(&s.negative, o.negative, o.scale-minScale)
+}
+
+// mergeBuckets adds the buckets of another histogram, whose indexes
+// are reduced by change to match this histogram.
+func (s *sparseHistogram[N]) mergeBuckets(mine *sparseBuckets, theirs bucketSource, change int32) {
+	theirs.each(func(index int32, count uint64) {
+		if _, ok := s.incrementIndexBy(mine, index>>change, count); !ok {
+			panic("incorrect merge scale")
+		}
+	})
+}
+
+// changeScale computes how much downscaling is needed by shifting the
+// high and low values until they are separated by no more than size.
+func changeScale(hl highLow, size int32) int32 {
+	var change int32
+	for hl.high-hl.low >= size {
+		hl.high >>= 1
+		hl.low >>= 1
+		change++
+	}
+	return change
+}
+
+// highLowAtScale returns the range of a set of buckets after
+// reducing the scale by shift.
+func highLowAtScale(b aggregation.Buckets, shift int32) highLow {
+	if b.Len() == 0 {
+		return highLow{low: 0, high: -1}
+	}
+	return highLow{
+		low:  b.Offset() >> shift,
+		high: (b.Offset() + int32(b.Len()) - 1) >> shift,
+	}
+}
+
+// with returns the union of two ranges.
+func (h highLow) with(o highLow) highLow {
+	if o.empty() {
+		return h
+	}
+	if h.empty() {
+		return o
+	}
+	return highLow{
+		low:  min(h.low, o.low),
+		high: max(h.high, o.high),
+	}
+}
+
+// empty indicates whether there are any values in a highLow.
+func (h highLow) empty() bool {
+	return h.low > h.high
+}
+
+// Offset implements aggregation.Buckets.
+func (b *sparseBuckets) Offset() int32 {
+	if len(b.buckets) == 0 {
+		return 0
+	}
+	return b.buckets[0].index
+}
+
+// Len implements aggregation.Buckets.
+func (b *sparseBuckets) Len() uint32 {
+	if len(b.buckets) == 0 {
+		return 0
+	}
+	return uint32(b.buckets[len(b.buckets)-1].index - b.buckets[0].index + 1)
+}
+
+// At implements aggregation.Buckets.
+func (b *sparseBuckets) At(pos uint32) uint64 {
+	if i, found := b.search(b.Offset() + int32(pos)); found {
+		return b.buckets[i].count
+	}
+	return 0
+}
+
+func (b *sparseBuckets) each(f func(index int32, count uint64)) {
+	for _, bucket := range b.buckets {
+		f(bucket.index, bucket.count)
+	}
+}
+
+// search returns the position of index, or where it would be
+// inserted.
+func (b *sparseBuckets) search(index int32) (int, bool) {
+	return slices.BinarySearchFunc(b.buckets, index, func(bucket sparseBucket, index int32) int {
+		return int(bucket.index) - int(index)
+	})
+}
+
+func (b *sparseBuckets) increment(index int32, incr uint64) {
+	i, found := b.search(index)
+	if found {
+		b.buckets[i].count += incr
+		return
+	}
+	b.buckets = slices.Insert(b.buckets, i, sparseBucket{index: index, count: incr})
+}
+
+// downscale collapses 2**by-to-1 buckets.
+func (b *sparseBuckets) downscale(by int32) {
+	out := 0
+	for _, bucket := range b.buckets {
+		bucket.index >>= by
+		if out != 0 && b.buckets[out-1].index == bucket.index {
+			b.buckets[out-1].count += bucket.count
+			continue
+		}
+		b.buckets[out] = bucket
+		out++
+	}
+	b.buckets = b.buckets[:out]
+}
+
+func (b *sparseBuckets) clear() {
+	b.buckets = b.buckets[:0]
+}
+
+func (b *sparseBuckets) copyInto(dest *sparseBuckets) {
+	dest.buckets = append(dest.buckets[:0], b.buckets...)
+}
+
+func (b denseBuckets) each(f func(index int32, count uint64)) {
+	offset := b.Offset()
+	for i := uint32(0); i < b.Len(); i++ {
+		f(offset+int32(i), b.At(i))
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogram // import "github.com/lightstep/go-expohisto"
+
+import (
+	"context"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// ContextWithWeight returns a context carrying the secondary weight
+// of measurements made with the context, for example the cost of a
+// request whose latency is being measured.  Histograms configured
+// with aggregator.Config.HistogramWeighted count each measurement by
+// this weight in their Weighted() histogram.  Weights are integers,
+// so fractional quantities should be expressed in a smaller unit.
+func ContextWithWeight(ctx context.Context, weight uint64) context.Context {
+	m := aggregator.MeasurementFromContext(ctx)
+	m.Weight, m.HasWeight = weight, true
+	return aggregator.ContextWithMeasurement(ctx, m)
+}
+
+// WeightFromContext returns the weight set by ContextWithWeight and
+// true, or false when there is none.
+func WeightFromContext(ctx context.Context) (uint64, bool) {
+	m := aggregator.MeasurementFromContext(ctx)
+	return m.Weight, m.HasWeight
+}
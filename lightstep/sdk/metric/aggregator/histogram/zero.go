@@ -0,0 +1,64 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogram // import "github.com/lightstep/go-expohisto"
+
+import (
+	"math"
+
+	"github.com/lightstep/go-expohisto/structure"
+)
+
+// zeroBucket holds the non-zero observations within the zero
+// threshold configured by aggregator.Config.HistogramZeroThreshold.
+// The storage counts exact zeros in its own zero bucket, and these
+// are added to it.  Their sum and range are kept so that the
+// histogram's Sum, Min, and Max remain exact.
+type zeroBucket[N structure.ValueType] struct {
+	count uint64
+	sum   N
+	min   N
+	max   N
+}
+
+// within returns true when number is counted in the zero bucket of
+// a histogram with the threshold, but not in its storage.
+func within[N structure.ValueType](number N, threshold float64) bool {
+	return number != 0 && math.Abs(float64(number)) <= threshold
+}
+
+// update adds incr observations of number.
+func (z *zeroBucket[N]) update(number N, incr uint64) {
+	z.merge(zeroBucket[N]{
+		count: incr,
+		sum:   number * N(incr),
+		min:   number,
+		max:   number,
+	})
+}
+
+// merge adds the observations of o.
+func (z *zeroBucket[N]) merge(o zeroBucket[N]) {
+	if o.count == 0 {
+		return
+	}
+	if z.count == 0 {
+		*z = o
+		return
+	}
+	z.count += o.count
+	z.sum += o.sum
+	z.min = min(z.min, o.min)
+	z.max = max(z.max, o.max)
+}
@@ -15,7 +15,10 @@
 package minmaxsumcount // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
 
 import (
+	"errors"
+	"math"
 	"sync"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -111,6 +114,10 @@ func (Methods[N, Traits]) HasChange(ptr *State[N, Traits]) bool {
 	return ptr.count != 0
 }
 
+func (Methods[N, Traits]) SizeOf(ptr *State[N, Traits]) int {
+	return int(unsafe.Sizeof(*ptr))
+}
+
 func (Methods[N, Traits]) Move(from, to *State[N, Traits]) {
 	from.lock.Lock()
 	defer from.lock.Unlock()
@@ -129,6 +136,23 @@ func (Methods[N, Traits]) Update(state *State[N, Traits], number N, _ aggregator
 	state.lock.Lock()
 	defer state.lock.Unlock()
 
+	state.update(number, 1)
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "minmaxsumcount Update", state)
+}
+
+func (Methods[N, Traits]) UpdateBatch(state *State[N, Traits], number N, count uint64, _ aggregator.ExemplarBits) {
+	if count == 0 {
+		return
+	}
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	state.update(number, count)
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "minmaxsumcount UpdateBatch", state)
+}
+
+// update adds count measurements of number, which must be locked.
+func (state *State[N, Traits]) update(number N, count uint64) {
 	if state.count == 0 {
 		state.min = number
 		state.max = number
@@ -141,8 +165,8 @@ func (Methods[N, Traits]) Update(state *State[N, Traits], number N, _ aggregator
 		}
 	}
 
-	state.sum += number
-	state.count++
+	state.sum += number * N(count)
+	state.count += count
 }
 
 func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
@@ -165,6 +189,7 @@ func (Methods[N, Traits]) Merge(from, to *State[N, Traits]) {
 
 	to.fields.sum += from.fields.sum
 	to.fields.count += from.fields.count
+	aggregator.CheckInvariants[State[N, Traits]](Methods[N, Traits]{}, "minmaxsumcount Merge", to)
 }
 
 func (Methods[N, Traits]) ToAggregation(state *State[N, Traits]) aggregation.Aggregation {
@@ -182,6 +207,26 @@ func (Methods[N, Traits]) SubtractSwap(operand, argument *State[N, Traits]) {
 	panic("impossible call")
 }
 
+// Validate implements aggregator.Validator.  An empty state is zero;
+// otherwise no field is NaN and the minimum does not exceed the
+// maximum, which both equal the sum of a single value.
+func (Methods[N, Traits]) Validate(state *State[N, Traits]) error {
+	f := state.fields
+	switch {
+	case f.count == 0:
+		if f.min != 0 || f.max != 0 || f.sum != 0 {
+			return errors.New("empty state is not zero")
+		}
+	case math.IsNaN(float64(f.min)) || math.IsNaN(float64(f.max)) || math.IsNaN(float64(f.sum)):
+		return errors.New("NaN field")
+	case f.min > f.max:
+		return errors.New("min exceeds max")
+	case f.count == 1 && (f.min != f.sum || f.max != f.sum):
+		return errors.New("single value has unequal min, max, and sum")
+	}
+	return nil
+}
+
 func (Methods[N, Traits]) Exemplars(ptr *State[N, Traits], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
 	return in
 }
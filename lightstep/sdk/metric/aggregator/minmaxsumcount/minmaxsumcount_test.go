@@ -104,3 +104,22 @@ func genericMinMaxSumCountTest[N number.Any, Storage any, Methods aggregator.Met
 	})
 
 }
+
+func TestValidate(t *testing.T) {
+	var methods Float64Methods
+	var s Float64
+	require.NoError(t, methods.Validate(&s))
+
+	methods.Update(&s, 2, aggregator.ExemplarBits{})
+	require.NoError(t, methods.Validate(&s))
+
+	s.sum = 3
+	require.ErrorContains(t, methods.Validate(&s), "single value")
+
+	methods.Update(&s, 1, aggregator.ExemplarBits{})
+	s.min = 5
+	require.ErrorContains(t, methods.Validate(&s), "min exceeds max")
+
+	s.min, s.count = 1, 0
+	require.ErrorContains(t, methods.Validate(&s), "empty state is not zero")
+}
@@ -15,9 +15,18 @@
 package sum // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 
 import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"go.opentelemetry.io/otel"
 )
 
 type (
@@ -32,6 +41,45 @@ type (
 
 	State[N number.Any, Traits number.Traits[N], M Monotonicity] struct {
 		value N
+
+		// settings are the policies of the view, shared by
+		// its series, or nil for the defaults.
+		settings *settings
+
+		// ext is the state of the policies that need more
+		// than the value, allocated only when one is
+		// configured.
+		ext *extension[N]
+	}
+
+	// settings are the sum policies of an aggregator.Config.
+	settings struct {
+		overflow  aggregator.SumOverflowPolicy
+		nonFinite aggregator.NonFinitePolicy
+		minMax    bool
+	}
+
+	// extension is the state kept for aggregator.SumOverflowPromote,
+	// aggregator.NonFiniteDropAndCount, and
+	// aggregator.Config.SumMinMax.
+	extension[N number.Any] struct {
+		// promoted is the part of an Int64 sum beyond the
+		// int64 range.
+		promoted float64
+
+		// dropped counts the non-finite values rejected.
+		dropped uint64
+
+		minMax minMax[N]
+	}
+
+	// minMax is the minimum and maximum of the values added
+	// to a sum, set once a value has been added.
+	minMax[N number.Any] struct {
+		lock sync.Mutex
+		set  bool
+		min  N
+		max  N
 	}
 
 	MonotonicInt64    = State[int64, number.Int64Traits, Monotonic]
@@ -47,6 +95,52 @@ type (
 	NonMonotonicFloat64Methods = Methods[float64, number.Float64Traits, NonMonotonic]
 )
 
+// allSettings holds the settings of the known policies, so that the
+// series of a view share one.
+var allSettings = func() (all [3][3][2]settings) {
+	for o := range all {
+		for n := range all[o] {
+			for m := range all[o][n] {
+				all[o][n][m] = settings{
+					overflow:  aggregator.SumOverflowPolicy(o),
+					nonFinite: aggregator.NonFinitePolicy(n),
+					minMax:    m == 1,
+				}
+			}
+		}
+	}
+	return all
+}()
+
+// settingsOf returns the shared settings of a configuration, or nil
+// when it uses the defaults.
+func settingsOf(cfg aggregator.Config) *settings {
+	key := settings{
+		overflow:  cfg.SumOverflow,
+		nonFinite: cfg.NonFinite,
+		minMax:    cfg.SumMinMax,
+	}
+	if key == (settings{}) {
+		return nil
+	}
+	o, n := int(key.overflow), int(key.nonFinite)
+	if o < 0 || o >= len(allSettings) || n < 0 || n >= len(allSettings[o]) {
+		// Unknown policies are not shared.
+		unknown := key
+		return &unknown
+	}
+	m := 0
+	if key.minMax {
+		m = 1
+	}
+	return &allSettings[o][n][m]
+}
+
+// extended returns true when the settings need an extension.
+func (s *settings) extended() bool {
+	return s != nil && (s.overflow == aggregator.SumOverflowPromote || s.nonFinite == aggregator.NonFiniteDropAndCount || s.minMax)
+}
+
 func NewMonotonicInt64(x int64) *MonotonicInt64 {
 	return &MonotonicInt64{value: x}
 }
@@ -83,9 +177,108 @@ var (
 	_ aggregation.Sum = &NonMonotonicFloat64{}
 )
 
+// Sum returns the sum.  An Int64 sum that was promoted because of
+// aggregator.SumOverflowPromote returns the int64 limit in the
+// direction of the sum; see Promoted.
 func (s *State[N, Traits, M]) Sum() number.Number {
 	var t Traits
-	return t.ToNumber(s.value)
+	promoted := s.promoted()
+	if promoted == 0 {
+		return t.ToNumber(s.value)
+	}
+	if float64(s.value)+promoted > 0 {
+		return number.FromInt64(math.MaxInt64)
+	}
+	return number.FromInt64(math.MinInt64)
+}
+
+// Promoted returns the sum as a float64 and true when an Int64 sum
+// has left the int64 range because of aggregator.SumOverflowPromote,
+// otherwise false.
+func (s *State[N, Traits, M]) Promoted() (float64, bool) {
+	promoted := s.promoted()
+	if promoted == 0 {
+		return 0, false
+	}
+	return float64(s.value) + promoted, true
+}
+
+// promoted returns the promoted part of the sum.
+func (s *State[N, Traits, M]) promoted() float64 {
+	if s.ext == nil {
+		return 0
+	}
+	return number.Float64Traits{}.GetAtomic(&s.ext.promoted)
+}
+
+// overflow returns the overflow policy.
+func (s *State[N, Traits, M]) overflow() aggregator.SumOverflowPolicy {
+	if s.settings == nil {
+		return aggregator.SumOverflowWrap
+	}
+	return s.settings.overflow
+}
+
+// nonFinite returns the non-finite policy.
+func (s *State[N, Traits, M]) nonFinite() aggregator.NonFinitePolicy {
+	if s.settings == nil {
+		return aggregator.NonFinitePassThrough
+	}
+	return s.settings.nonFinite
+}
+
+// extend returns the extension, allocating it for the output of a
+// Merge, Copy, or SubtractSwap.
+func (s *State[N, Traits, M]) extend() *extension[N] {
+	if s.ext == nil {
+		s.ext = &extension[N]{}
+	}
+	return s.ext
+}
+
+// reject returns true when the non-finite policy rejects count
+// values, counting them for aggregator.NonFiniteDropAndCount.
+func (s *State[N, Traits, M]) reject(value N, count uint64) bool {
+	if s.settings == nil {
+		return false
+	}
+	var dropped *uint64
+	if s.ext != nil {
+		dropped = &s.ext.dropped
+	}
+	return aggregator.RejectNonFiniteCount[N, Traits](s.settings.nonFinite, value, count, dropped)
+}
+
+// updateMinMax records a value added by Update, when configured.
+func (s *State[N, Traits, M]) updateMinMax(value N) {
+	if s.settings != nil && s.settings.minMax {
+		s.ext.minMax.update(value, value)
+	}
+}
+
+// MinMax returns the minimum and maximum of the values added to the
+// sum and true, or false when aggregator.Config.SumMinMax was not set
+// or no value has been added.  For a sum computed by subtracting
+// cumulative values, these are the extremes of the later value.
+func (s *State[N, Traits, M]) MinMax() (min, max number.Number, ok bool) {
+	if s.ext == nil {
+		return 0, 0, false
+	}
+	var t Traits
+	mn, mx, ok := s.ext.minMax.get()
+	if !ok {
+		return 0, 0, false
+	}
+	return t.ToNumber(mn), t.ToNumber(mx), true
+}
+
+// Dropped returns the number of non-finite values rejected because of
+// aggregator.NonFiniteDropAndCount.
+func (s *State[N, Traits, M]) Dropped() uint64 {
+	if s.ext == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&s.ext.dropped)
 }
 
 func (s *State[N, Traits, M]) Kind() aggregation.Kind {
@@ -103,32 +296,225 @@ func (Methods[N, Traits, M]) Kind() aggregation.Kind {
 	return m.kind()
 }
 
-func (Methods[N, Traits, M]) Init(state *State[N, Traits, M], _ aggregator.Config) {
+func (Methods[N, Traits, M]) Init(state *State[N, Traits, M], cfg aggregator.Config) {
 	// Note: storage is zero to start
+	state.settings = settingsOf(cfg)
+	state.ext = nil
+	if state.settings.extended() {
+		state.ext = &extension[N]{}
+	}
 }
 
 func (Methods[N, Traits, M]) Move(from, to *State[N, Traits, M]) {
 	var t Traits
 	to.value = t.SwapAtomic(&from.value, 0)
+	to.settings = from.settings
+	to.ext = from.ext.take(to.ext, true)
 }
 
 func (Methods[N, Traits, M]) HasChange(ptr *State[N, Traits, M]) bool {
-	return ptr.value != 0
+	return ptr.value != 0 || ptr.promoted() != 0
+}
+
+func (Methods[N, Traits, M]) SizeOf(ptr *State[N, Traits, M]) int {
+	size := int(unsafe.Sizeof(*ptr))
+	if ptr.ext != nil {
+		size += int(unsafe.Sizeof(*ptr.ext))
+	}
+	return size
 }
 
 func (Methods[N, Traits, M]) Update(state *State[N, Traits, M], value N, _ aggregator.ExemplarBits) {
-	var t Traits
-	t.AddAtomic(&state.value, value)
+	if state.reject(value, 1) {
+		return
+	}
+	state.add(value, 0, state.overflow())
+	state.updateMinMax(value)
+	aggregator.CheckInvariants[State[N, Traits, M]](Methods[N, Traits, M]{}, "sum Update", state)
+}
+
+func (Methods[N, Traits, M]) UpdateBatch(state *State[N, Traits, M], value N, count uint64, _ aggregator.ExemplarBits) {
+	if count == 0 || state.reject(value, count) {
+		return
+	}
+	state.addBatch(value, count)
+	state.updateMinMax(value)
+	aggregator.CheckInvariants[State[N, Traits, M]](Methods[N, Traits, M]{}, "sum UpdateBatch", state)
 }
 
 func (Methods[N, Traits, M]) Copy(from, to *State[N, Traits, M]) {
 	var t Traits
 	to.value = t.GetAtomic(&from.value)
+	to.settings = from.settings
+	to.ext = from.ext.take(to.ext, false)
 }
 
 func (Methods[N, Traits, M]) Merge(from, to *State[N, Traits, M]) {
+	// The output of a conversion may not be configured, in
+	// which case the input policy applies.
+	policy := to.overflow()
+	if policy == aggregator.SumOverflowWrap {
+		policy = from.overflow()
+	}
+	to.add(from.value, from.promoted(), policy)
+	if from.ext != nil {
+		if dropped := atomic.LoadUint64(&from.ext.dropped); dropped != 0 {
+			atomic.AddUint64(&to.extend().dropped, dropped)
+		}
+		if min, max, ok := from.ext.minMax.get(); ok {
+			to.extend().minMax.update(min, max)
+		}
+	}
+	aggregator.CheckInvariants[State[N, Traits, M]](Methods[N, Traits, M]{}, "sum Merge", to)
+}
+
+// update widens the extremes to include min and max.
+func (mm *minMax[N]) update(min, max N) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if !mm.set || min < mm.min {
+		mm.min = min
+	}
+	if !mm.set || max > mm.max {
+		mm.max = max
+	}
+	mm.set = true
+}
+
+// get returns the extremes and whether any value was added.
+func (mm *minMax[N]) get() (min, max N, ok bool) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	return mm.min, mm.max, mm.set
+}
+
+// copyTo copies the extremes into the destination, and resets the
+// receiver when reset is true.
+func (mm *minMax[N]) copyTo(to *minMax[N], reset bool) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	to.lock.Lock()
+	defer to.lock.Unlock()
+	to.set, to.min, to.max = mm.set, mm.min, mm.max
+	if reset {
+		mm.set, mm.min, mm.max = false, 0, 0
+	}
+}
+
+// take copies the extension into the destination, allocating it if
+// necessary, and resets the receiver when reset is true.  The result
+// is nil when the receiver is nil.
+func (e *extension[N]) take(to *extension[N], reset bool) *extension[N] {
+	if e == nil {
+		return nil
+	}
+	if to == e {
+		return to
+	}
+	if to == nil {
+		to = &extension[N]{}
+	}
+	if reset {
+		to.promoted = number.Float64Traits{}.SwapAtomic(&e.promoted, 0)
+		to.dropped = atomic.SwapUint64(&e.dropped, 0)
+	} else {
+		to.promoted = number.Float64Traits{}.GetAtomic(&e.promoted)
+		to.dropped = atomic.LoadUint64(&e.dropped)
+	}
+	e.minMax.copyTo(&to.minMax, reset)
+	return to
+}
+
+// add adds a value and promoted excess to the state, detecting
+// overflow of Int64 sums unless the policy is to wrap.
+func (s *State[N, Traits, M]) add(value N, promoted float64, policy aggregator.SumOverflowPolicy) {
+	var t Traits
+	if promoted != 0 {
+		number.Float64Traits{}.AddAtomic(&s.extend().promoted, promoted)
+	}
+	if policy == aggregator.SumOverflowWrap || t.Kind() != number.Int64Kind {
+		t.AddAtomic(&s.value, value)
+		return
+	}
+	for {
+		old := t.GetAtomic(&s.value)
+		sum, excess, overflow := addChecked[N, Traits](old, value, policy)
+		if !t.CompareAndSwapAtomic(&s.value, old, sum) {
+			continue
+		}
+		if !overflow {
+			return
+		}
+		if policy == aggregator.SumOverflowPromote {
+			number.Float64Traits{}.AddAtomic(&s.extend().promoted, excess)
+			return
+		}
+		doevery.TimePeriod(30*time.Second, func() {
+			otel.Handle(aggregator.ErrSumOverflow)
+		})
+		return
+	}
+}
+
+// addBatch adds count times value.  Where an integer product could
+// overflow, it is added in chunks that do not, so that the overflow
+// policy applies as it would to count separate additions.
+func (s *State[N, Traits, M]) addBatch(value N, count uint64) {
+	var t Traits
+	policy := s.overflow()
+	if t.Kind() != number.Int64Kind || policy == aggregator.SumOverflowWrap {
+		// Wrapping products equal wrapping sums.
+		s.add(value*N(count), 0, policy)
+		return
+	}
+	chunk := uint64(1)
+	if v := int64(value); v != math.MinInt64 && v != 0 {
+		if v < 0 {
+			v = -v
+		}
+		chunk = uint64(math.MaxInt64 / v)
+	}
+	for count > 0 {
+		n := min(chunk, count)
+		s.add(value*N(n), 0, policy)
+		count -= n
+	}
+}
+
+// addChecked returns a+b and false, or when the sum overflows the
+// int64 range, the value to store, the excess to promote, and true.
+// With SumOverflowSaturate the stored value is the limit and nothing
+// is promoted; with SumOverflowPromote the stored value is zero and
+// the whole sum is promoted.
+func addChecked[N number.Any, Traits number.Traits[N]](a, b N, policy aggregator.SumOverflowPolicy) (N, float64, bool) {
+	sum := a + b
+	if (b > 0 && sum >= a) || (b < 0 && sum <= a) || b == 0 {
+		return sum, 0, false
+	}
+	return overflowed[N, Traits](float64(a)+float64(b), b > 0, policy)
+}
+
+// subtractChecked is addChecked for a-b, which would overflow
+// negating b = math.MinInt64.
+func subtractChecked[N number.Any, Traits number.Traits[N]](a, b N, policy aggregator.SumOverflowPolicy) (N, float64, bool) {
+	diff := a - b
+	if (b > 0 && diff <= a) || (b < 0 && diff >= a) || b == 0 {
+		return diff, 0, false
+	}
+	return overflowed[N, Traits](float64(a)-float64(b), b < 0, policy)
+}
+
+// overflowed returns the value to store and the excess to promote
+// following an overflow, which is upward when up is true.
+func overflowed[N number.Any, Traits number.Traits[N]](exact float64, up bool, policy aggregator.SumOverflowPolicy) (N, float64, bool) {
 	var t Traits
-	t.AddAtomic(&to.value, from.value)
+	if policy == aggregator.SumOverflowPromote {
+		return 0, exact, true
+	}
+	if up {
+		return t.FromNumber(number.FromInt64(math.MaxInt64)), 0, true
+	}
+	return t.FromNumber(number.FromInt64(math.MinInt64)), 0, true
 }
 
 func (Methods[N, Traits, M]) ToAggregation(state *State[N, Traits, M]) aggregation.Aggregation {
@@ -141,7 +527,74 @@ func (Methods[N, Traits, M]) ToStorage(aggr aggregation.Aggregation) (*State[N,
 }
 
 func (Methods[N, Traits, M]) SubtractSwap(operand, argument *State[N, Traits, M]) {
-	operand.value = argument.value - operand.value
+	subtractSwap(operand, argument)
+	aggregator.CheckInvariants[State[N, Traits, M]](Methods[N, Traits, M]{}, "sum SubtractSwap", operand)
+}
+
+func subtractSwap[N number.Any, Traits number.Traits[N], M Monotonicity](operand, argument *State[N, Traits, M]) {
+	var t Traits
+	opPromoted, argPromoted := operand.promoted(), argument.promoted()
+	if argument.ext != nil {
+		ext := operand.extend()
+		// The extremes of a difference are not those of
+		// either side, so the difference reports the later
+		// interval's.
+		argument.ext.minMax.copyTo(&ext.minMax, false)
+		// The dropped counts are cumulative like the sum.
+		if argument.ext.dropped >= ext.dropped {
+			ext.dropped = argument.ext.dropped - ext.dropped
+		} else {
+			ext.dropped = argument.ext.dropped
+		}
+	} else if operand.ext != nil {
+		operand.ext = nil
+	}
+	setPromoted := func(promoted float64) {
+		if promoted != 0 || operand.ext != nil {
+			operand.extend().promoted = promoted
+		}
+	}
+	policy := argument.overflow()
+	if t.Kind() != number.Int64Kind || (policy == aggregator.SumOverflowWrap && opPromoted == 0 && argPromoted == 0) {
+		operand.value = argument.value - operand.value
+		setPromoted(0)
+		return
+	}
+	// Subtract as float64 when either side was promoted, since
+	// the difference of the int64 parts may itself overflow.
+	if opPromoted != 0 || argPromoted != 0 {
+		diff := (float64(argument.value) + argPromoted) - (float64(operand.value) + opPromoted)
+		operand.value = 0
+		if diff >= math.MinInt64 && diff < math.MaxInt64 {
+			operand.value, diff = N(diff), 0
+		}
+		setPromoted(diff)
+		return
+	}
+	value, excess, _ := subtractChecked[N, Traits](argument.value, operand.value, policy)
+	operand.value = value
+	setPromoted(excess)
+}
+
+// Validate implements aggregator.Validator.  The sum is not NaN,
+// unless non-finite values pass through, and only an Int64 sum has a
+// promoted part.
+func (Methods[N, Traits, M]) Validate(state *State[N, Traits, M]) error {
+	var t Traits
+	value := t.GetAtomic(&state.value)
+	promoted := state.promoted()
+	switch {
+	case state.nonFinite() != aggregator.NonFinitePassThrough && (math.IsNaN(float64(value)) || math.IsNaN(promoted)):
+		return errors.New("sum is NaN")
+	case promoted != 0 && t.Kind() != number.Int64Kind:
+		return errors.New("promoted part of a Float64 sum")
+	}
+	if state.ext != nil {
+		if min, max, ok := state.ext.minMax.get(); ok && min > max {
+			return errors.New("sum minimum exceeds maximum")
+		}
+	}
+	return nil
 }
 
 func (Methods[N, Traits, M]) Exemplars(ptr *State[N, Traits, M], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
@@ -15,12 +15,15 @@
 package sum // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 
 import (
+	"math"
 	"testing"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 )
 
 var nobits aggregator.ExemplarBits
@@ -73,3 +76,308 @@ func TestSubtract(t *testing.T) {
 	genericSubtractTest[int64, NonMonotonicInt64, NonMonotonicInt64Methods](t)
 	genericSubtractTest[float64, NonMonotonicFloat64, NonMonotonicFloat64Methods](t)
 }
+
+func TestOverflowWrap(t *testing.T) {
+	var methods MonotonicInt64Methods
+	var s MonotonicInt64
+	methods.Init(&s, aggregator.Config{})
+
+	methods.Update(&s, math.MaxInt64-1, nobits)
+	methods.Update(&s, 2, nobits)
+
+	require.Equal(t, int64(math.MinInt64), number.ToInt64(s.Sum()))
+}
+
+func TestOverflowSaturate(t *testing.T) {
+	var methods NonMonotonicInt64Methods
+	var s1, s2, s3 NonMonotonicInt64
+	cfg := aggregator.Config{SumOverflow: aggregator.SumOverflowSaturate}
+	methods.Init(&s1, cfg)
+	methods.Init(&s2, cfg)
+	methods.Init(&s3, cfg)
+
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	methods.Update(&s1, math.MaxInt64-1, nobits)
+	methods.Update(&s1, 1, nobits)
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(s1.Sum()))
+	require.Empty(t, errs)
+
+	methods.Update(&s1, 1, nobits)
+	methods.Update(&s1, 1, nobits)
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(s1.Sum()))
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], aggregator.ErrSumOverflow)
+
+	// Merge saturates too, in both directions.
+	methods.Update(&s2, 10, nobits)
+	methods.Merge(&s1, &s2)
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(s2.Sum()))
+
+	methods.Update(&s3, math.MinInt64+1, nobits)
+	methods.Update(&s3, -2, nobits)
+	require.Equal(t, int64(math.MinInt64), number.ToInt64(s3.Sum()))
+
+	_, promoted := s2.Promoted()
+	require.False(t, promoted)
+}
+
+func TestOverflowPromote(t *testing.T) {
+	var methods MonotonicInt64Methods
+	var s1, s2, s3, s4 MonotonicInt64
+	cfg := aggregator.Config{SumOverflow: aggregator.SumOverflowPromote}
+	methods.Init(&s1, cfg)
+	methods.Init(&s2, cfg)
+	methods.Init(&s3, cfg)
+	methods.Init(&s4, aggregator.Config{})
+
+	methods.Update(&s1, math.MaxInt64-10, nobits)
+	_, promoted := s1.Promoted()
+	require.False(t, promoted)
+
+	methods.Update(&s1, 20, nobits)
+	value, promoted := s1.Promoted()
+	require.True(t, promoted)
+	require.Equal(t, float64(math.MaxInt64)+10, value)
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(s1.Sum()))
+
+	// Subsequent updates add to the promoted value.
+	methods.Update(&s1, 1<<40, nobits)
+	value, _ = s1.Promoted()
+	require.Equal(t, float64(math.MaxInt64)+10+(1<<40), value)
+
+	// Merge into an unconfigured output, as for cumulative
+	// conversion, promotes using the input policy.
+	methods.Update(&s2, math.MaxInt64, nobits)
+	methods.Merge(&s2, &s4)
+	methods.Merge(&s2, &s4)
+	value, promoted = s4.Promoted()
+	require.True(t, promoted)
+	require.Equal(t, 2*float64(math.MaxInt64), value)
+
+	// Copy and Move preserve the promoted value.
+	methods.Copy(&s4, &s3)
+	value, _ = s3.Promoted()
+	require.Equal(t, 2*float64(math.MaxInt64), value)
+
+	var s5 MonotonicInt64
+	methods.Move(&s3, &s5)
+	require.False(t, methods.HasChange(&s3))
+	value, _ = s5.Promoted()
+	require.Equal(t, 2*float64(math.MaxInt64), value)
+
+	// The difference of promoted sums fits in int64 again.
+	methods.SubtractSwap(&s1, &s5)
+	_, promoted = s1.Promoted()
+	require.False(t, promoted)
+	require.InEpsilon(t, float64(math.MaxInt64-10-(1<<40)), float64(number.ToInt64(s1.Sum())), 1e-15)
+}
+
+func TestSubtractOverflow(t *testing.T) {
+	var methods NonMonotonicInt64Methods
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	subtract := func(policy aggregator.SumOverflowPolicy, before, after int64) *NonMonotonicInt64 {
+		var operand, argument NonMonotonicInt64
+		cfg := aggregator.Config{SumOverflow: policy}
+		methods.Init(&operand, cfg)
+		methods.Init(&argument, cfg)
+		operand.value, argument.value = before, after
+		methods.SubtractSwap(&operand, &argument)
+		return &operand
+	}
+
+	// The difference fits, although the negated operand does not.
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(subtract(aggregator.SumOverflowSaturate, math.MinInt64, -1).Sum()))
+
+	require.Equal(t, int64(math.MaxInt64), number.ToInt64(subtract(aggregator.SumOverflowSaturate, math.MinInt64, 0).Sum()))
+	require.Equal(t, int64(math.MinInt64), number.ToInt64(subtract(aggregator.SumOverflowSaturate, 1, math.MinInt64).Sum()))
+
+	value, promoted := subtract(aggregator.SumOverflowPromote, math.MinInt64, 1).Promoted()
+	require.True(t, promoted)
+	require.Equal(t, 1-float64(math.MinInt64), value)
+}
+
+// Tests that the default configuration and the policies without
+// per-series state keep the value alone, without allocating.
+func TestStorageSize(t *testing.T) {
+	var methods MonotonicInt64Methods
+	var s MonotonicInt64
+	for _, cfg := range []aggregator.Config{
+		{},
+		{SumOverflow: aggregator.SumOverflowSaturate},
+		{NonFinite: aggregator.NonFiniteDrop},
+	} {
+		require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+			methods.Init(&s, cfg)
+			methods.Update(&s, 1, nobits)
+		}))
+		require.Equal(t, int(unsafe.Sizeof(s)), methods.SizeOf(&s))
+	}
+	methods.Init(&s, aggregator.Config{SumMinMax: true})
+	require.Less(t, int(unsafe.Sizeof(s)), methods.SizeOf(&s))
+}
+
+func TestUpdateBatchOverflow(t *testing.T) {
+	var methods NonMonotonicInt64Methods
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	for _, policy := range []aggregator.SumOverflowPolicy{
+		aggregator.SumOverflowWrap,
+		aggregator.SumOverflowSaturate,
+		aggregator.SumOverflowPromote,
+	} {
+		for _, value := range []int64{math.MaxInt64 / 3, math.MinInt64 / 5, math.MinInt64, 7} {
+			var batch, single NonMonotonicInt64
+			cfg := aggregator.Config{SumOverflow: policy}
+			methods.Init(&batch, cfg)
+			methods.Init(&single, cfg)
+
+			methods.UpdateBatch(&batch, value, 10, nobits)
+			for i := 0; i < 10; i++ {
+				methods.Update(&single, value, nobits)
+			}
+			require.Equal(t, number.ToInt64(single.Sum()), number.ToInt64(batch.Sum()), "policy %v value %d", policy, value)
+
+			sv, _ := single.Promoted()
+			bv, _ := batch.Promoted()
+			require.Equal(t, sv, bv, "policy %v value %d", policy, value)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var methods MonotonicFloat64Methods
+	var s MonotonicFloat64
+	methods.Update(&s, 1, aggregator.ExemplarBits{})
+	require.NoError(t, methods.Validate(&s))
+
+	s.ext = &extension[float64]{promoted: 1}
+	require.ErrorContains(t, methods.Validate(&s), "promoted part")
+
+	// A NaN sum is only invalid when non-finite values are
+	// not passed through.
+	s.ext = nil
+	s.value = math.NaN()
+	require.NoError(t, methods.Validate(&s))
+
+	methods.Init(&s, aggregator.Config{NonFinite: aggregator.NonFiniteDrop})
+	s.value = math.NaN()
+	require.ErrorContains(t, methods.Validate(&s), "NaN")
+}
+
+func TestMinMax(t *testing.T) {
+	var methods NonMonotonicInt64Methods
+	var s1, s2, s3 NonMonotonicInt64
+	cfg := aggregator.Config{SumMinMax: true}
+	methods.Init(&s1, cfg)
+	methods.Init(&s2, cfg)
+	methods.Init(&s3, aggregator.Config{})
+
+	// Not configured, or nothing added.
+	_, _, ok := s3.MinMax()
+	require.False(t, ok)
+	_, _, ok = s1.MinMax()
+	require.False(t, ok)
+
+	methods.Update(&s1, 5, nobits)
+	methods.Update(&s1, -3, nobits)
+	methods.Update(&s1, 2, nobits)
+	min, max, ok := s1.MinMax()
+	require.True(t, ok)
+	require.Equal(t, int64(-3), number.ToInt64(min))
+	require.Equal(t, int64(5), number.ToInt64(max))
+	require.NoError(t, methods.Validate(&s1))
+
+	// Merge combines the extremes, including into an
+	// unconfigured output.
+	methods.Update(&s2, 10, nobits)
+	methods.Merge(&s1, &s2)
+	min, max, _ = s2.MinMax()
+	require.Equal(t, int64(-3), number.ToInt64(min))
+	require.Equal(t, int64(10), number.ToInt64(max))
+
+	methods.Merge(&s2, &s3)
+	min, max, ok = s3.MinMax()
+	require.True(t, ok)
+	require.Equal(t, int64(-3), number.ToInt64(min))
+	require.Equal(t, int64(10), number.ToInt64(max))
+
+	// Copy keeps the input; Move resets it.
+	var s4, s5 NonMonotonicInt64
+	methods.Copy(&s3, &s4)
+	_, _, ok = s3.MinMax()
+	require.True(t, ok)
+	methods.Move(&s4, &s5)
+	_, _, ok = s4.MinMax()
+	require.False(t, ok)
+	min, max, _ = s5.MinMax()
+	require.Equal(t, int64(-3), number.ToInt64(min))
+	require.Equal(t, int64(10), number.ToInt64(max))
+
+	// A difference reports the extremes of the later value.
+	var prior, current NonMonotonicInt64
+	methods.Init(&prior, cfg)
+	methods.Init(&current, cfg)
+	methods.Update(&prior, 100, nobits)
+	methods.Update(&current, 130, nobits)
+	methods.SubtractSwap(&prior, &current)
+	require.Equal(t, int64(30), number.ToInt64(prior.Sum()))
+	min, max, _ = prior.MinMax()
+	require.Equal(t, int64(130), number.ToInt64(min))
+	require.Equal(t, int64(130), number.ToInt64(max))
+}
+
+func TestNonFinitePolicy(t *testing.T) {
+	var methods NonMonotonicFloat64Methods
+	nonFinite := []float64{math.Inf(+1), math.Inf(-1), math.NaN()}
+
+	for _, policy := range []aggregator.NonFinitePolicy{
+		aggregator.NonFiniteDrop,
+		aggregator.NonFiniteDropAndCount,
+	} {
+		var s NonMonotonicFloat64
+		methods.Init(&s, aggregator.Config{NonFinite: policy})
+
+		methods.Update(&s, 1.5, nobits)
+		for _, x := range nonFinite {
+			methods.Update(&s, x, nobits)
+		}
+		methods.Update(&s, 2, nobits)
+
+		require.Equal(t, 3.5, number.ToFloat64(s.Sum()))
+		require.NoError(t, methods.Validate(&s))
+
+		expect := uint64(0)
+		if policy == aggregator.NonFiniteDropAndCount {
+			expect = uint64(len(nonFinite))
+		}
+		require.Equal(t, expect, s.Dropped())
+
+		var merged, copied NonMonotonicFloat64
+		methods.Merge(&s, &merged)
+		methods.Merge(&s, &merged)
+		require.Equal(t, 2*expect, merged.Dropped())
+		methods.Copy(&merged, &copied)
+		require.Equal(t, 2*expect, copied.Dropped())
+
+		// The count of a difference is the difference.
+		methods.SubtractSwap(&s, &merged)
+		require.Equal(t, expect, s.Dropped())
+	}
+
+	// Passed through, each value reaches the sum.
+	for _, x := range nonFinite {
+		var s NonMonotonicFloat64
+		methods.Init(&s, aggregator.Config{})
+		methods.Update(&s, x, nobits)
+		got := number.ToFloat64(s.Sum())
+		require.True(t, math.IsInf(got, 0) || math.IsNaN(got))
+		require.Equal(t, uint64(0), s.Dropped())
+	}
+}
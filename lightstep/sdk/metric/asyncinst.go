@@ -17,6 +17,7 @@ package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/me
 import (
 	"context"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/asyncstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
@@ -63,6 +64,11 @@ type (
 	}
 )
 
+var (
+	_ bypass.Int64GaugeSetter   = int64ObservableGauge{}
+	_ bypass.Float64GaugeSetter = float64ObservableGauge{}
+)
+
 func (io intObserver) Observe(value int64, options ...metric.ObserveOption) {
 	io.observer.ObserveInt64(io.observable, value, options...)
 }
@@ -71,6 +77,16 @@ func (fo floatObserver) Observe(value float64, options ...metric.ObserveOption)
 	fo.observer.ObserveFloat64(fo.observable, value, options...)
 }
 
+// Set implements bypass.Int64GaugeSetter.
+func (g int64ObservableGauge) Set(ctx context.Context, value int64, options ...metric.RecordOption) {
+	asyncstate.Push[int64, number.Int64Traits](ctx, g.Observer, value, options)
+}
+
+// Set implements bypass.Float64GaugeSetter.
+func (g float64ObservableGauge) Set(ctx context.Context, value float64, options ...metric.RecordOption) {
+	asyncstate.Push[float64, number.Float64Traits](ctx, g.Observer, value, options)
+}
+
 func registerIntCallbacks[T metric.Int64Observable](m *meter, inst T, cbs []metric.Int64Callback) {
 	for _, cb := range cbs {
 		_, _ = m.RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
@@ -22,6 +22,9 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
@@ -219,3 +222,79 @@ func TestAsyncInstsSingleCallback(t *testing.T) {
 		),
 	)
 }
+
+// TestAsyncGaugeSet verifies that values set between callbacks and
+// the callbacks' observations take precedence by event time.
+func TestAsyncGaugeSet(t *testing.T) {
+	rdr := NewManualReader("test")
+	res := resource.Empty()
+	provider := NewMeterProvider(WithReader(rdr), WithResource(res), WithSortedOutput())
+	tm := provider.Meter("test")
+
+	observed := attribute.String("a", "observed")
+	pushed := attribute.String("a", "pushed")
+
+	callbackValue := int64(1)
+	g := must(tm.Int64ObservableGauge("gauge",
+		metric.WithInt64Callback(
+			func(ctx context.Context, obs metric.Int64Observer) error {
+				obs.Observe(callbackValue, metric.WithAttributes(observed))
+				return nil
+			},
+		),
+	))
+	setter := g.(bypass.Int64GaugeSetter)
+
+	ctx := context.Background()
+	past := exemplar.ContextWithTimestamp(ctx, time.Now().Add(-time.Hour))
+	future := exemplar.ContextWithTimestamp(ctx, time.Now().Add(time.Hour))
+
+	notime := time.Time{}
+	cumulative := aggregation.CumulativeTemporality
+	expect := func(output data.Metrics, points ...data.Point) {
+		test.RequireEqualResourceMetrics(
+			t, output, res,
+			test.Scope(
+				test.Library("test"),
+				test.Instrument(
+					test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Int64Kind),
+					points...,
+				),
+			),
+		)
+	}
+
+	// Sets before the callback are superseded by its observation,
+	// and a series set but not observed is reported.  The last
+	// set to a series by time is kept.
+	setter.Set(ctx, 10, metric.WithAttributes(observed))
+	setter.Set(future, 20, metric.WithAttributes(pushed))
+	setter.Set(ctx, 30, metric.WithAttributes(pushed))
+	expect(rdr.Produce(nil),
+		test.Point(notime, notime, gauge.NewInt64(1), cumulative, observed),
+		test.Point(notime, notime, gauge.NewInt64(20), cumulative, pushed),
+	)
+
+	// Sets are reported once.
+	callbackValue = 2
+	expect(rdr.Produce(nil),
+		test.Point(notime, notime, gauge.NewInt64(2), cumulative, observed),
+	)
+
+	// A set later than the callback takes precedence, an earlier
+	// one does not.  A peek leaves the set for the next
+	// collection.
+	setter.Set(future, 40, metric.WithAttributes(observed))
+	setter.Set(past, 50, metric.WithAttributes(observed))
+	expect(rdr.ProduceWithTemporality(nil, aggregation.CumulativeTemporality),
+		test.Point(notime, notime, gauge.NewInt64(40), cumulative, observed),
+	)
+	expect(rdr.Produce(nil),
+		test.Point(notime, notime, gauge.NewInt64(40), cumulative, observed),
+	)
+
+	setter.Set(past, 60, metric.WithAttributes(observed))
+	expect(rdr.Produce(nil),
+		test.Point(notime, notime, gauge.NewInt64(2), cumulative, observed),
+	)
+}
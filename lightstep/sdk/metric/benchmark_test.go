@@ -16,8 +16,10 @@ package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/me
 
 import (
 	"context"
+	"sync"
 	"testing"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
@@ -262,6 +264,24 @@ func BenchmarkCounterAddManyFilteredAttrs(b *testing.B) {
 	}
 }
 
+func BenchmarkCounterAddDropped(b *testing.B) {
+	ctx := context.Background()
+	rdr := NewManualReader("bench")
+	provider := NewMeterProvider(
+		WithReader(rdr,
+			view.WithClause(view.WithAggregation(aggregation.DropKind)),
+		),
+	)
+	b.ReportAllocs()
+
+	cntr, _ := provider.Meter("test").Int64Counter("hello")
+	opts := []metric.AddOption{metric.WithAttributes(attribute.String("K", "V"))}
+
+	for i := 0; i < b.N; i++ {
+		cntr.Add(ctx, 1, opts...)
+	}
+}
+
 func BenchmarkCounterCollectOneAttrNoReuse(b *testing.B) {
 	ctx := context.Background()
 	rdr := NewManualReader("bench")
@@ -331,3 +351,41 @@ func BenchmarkCounterCollectTenAttrsTenTimes(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkCounterAddHotSeries measures 32 goroutines updating a
+// single series, with and without sharded accumulators.
+func BenchmarkCounterAddHotSeries(b *testing.B) {
+	const goroutines = 32
+	for _, bm := range []struct {
+		name   string
+		shards uint32
+	}{
+		{"Unsharded", 0},
+		{"Sharded", 8},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			rdr := NewManualReader("bench")
+			provider := NewMeterProvider(WithReader(rdr), WithPerformance(sdkinstrument.Performance{
+				AccumulatorShards: bm.shards,
+			}))
+			b.ReportAllocs()
+
+			cntr, _ := provider.Meter("test").Int64Counter("hello")
+			attrs := metric.WithAttributes(attribute.String("K", "V"))
+
+			var wg sync.WaitGroup
+			b.ResetTimer()
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := g; i < b.N; i += goroutines {
+						cntr.Add(ctx, 1, attrs)
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
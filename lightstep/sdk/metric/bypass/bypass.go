@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // FastInt64Adder is implemented by int64 Counter and UpDownCounter
@@ -47,3 +48,18 @@ type FastInt64Recorder interface {
 type FastFloat64Recorder interface {
 	RecordWithKeyValues(ctx context.Context, value float64, attrs ...attribute.KeyValue)
 }
+
+// Int64GaugeSetter is implemented by int64 ObservableGauge
+// instruments returned by this SDK, allowing a value to be set
+// between callbacks, for example when a value that is expensive to
+// observe is known to have changed.  The later by event time of a set
+// value and a callback's observation of the same series is reported.
+type Int64GaugeSetter interface {
+	Set(ctx context.Context, value int64, options ...metric.RecordOption)
+}
+
+// Float64GaugeSetter is implemented by float64 ObservableGauge
+// instruments returned by this SDK.  See Int64GaugeSetter.
+type Float64GaugeSetter interface {
+	Set(ctx context.Context, value float64, options ...metric.RecordOption)
+}
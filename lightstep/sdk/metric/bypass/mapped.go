@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bypass // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Mapper constructs the attributes of a measurement from a value of
+// a domain type, for example a request context.
+type Mapper[T any] func(T) []attribute.KeyValue
+
+type (
+	int64AddAPI interface {
+		Add(ctx context.Context, value int64, options ...metric.AddOption)
+	}
+	float64AddAPI interface {
+		Add(ctx context.Context, value float64, options ...metric.AddOption)
+	}
+	int64RecordAPI interface {
+		Record(ctx context.Context, value int64, options ...metric.RecordOption)
+	}
+	float64RecordAPI interface {
+		Record(ctx context.Context, value float64, options ...metric.RecordOption)
+	}
+)
+
+// Int64Adder updates an int64 Counter or UpDownCounter with the
+// attributes mapped from a value of type T.
+type Int64Adder[T any] struct {
+	inst   int64AddAPI
+	fast   FastInt64Adder
+	mapper Mapper[T]
+}
+
+// Float64Adder updates a float64 Counter or UpDownCounter with the
+// attributes mapped from a value of type T.
+type Float64Adder[T any] struct {
+	inst   float64AddAPI
+	fast   FastFloat64Adder
+	mapper Mapper[T]
+}
+
+// Int64Recorder updates an int64 Histogram or Gauge with the
+// attributes mapped from a value of type T.
+type Int64Recorder[T any] struct {
+	inst   int64RecordAPI
+	fast   FastInt64Recorder
+	mapper Mapper[T]
+}
+
+// Float64Recorder updates a float64 Histogram or Gauge with the
+// attributes mapped from a value of type T.
+type Float64Recorder[T any] struct {
+	inst   float64RecordAPI
+	fast   FastFloat64Recorder
+	mapper Mapper[T]
+}
+
+// NewInt64Adder returns an Int64Adder.  When the instrument was
+// returned by this SDK, the fast-path is used and no attribute set
+// is constructed before the SDK's own attribute processing.
+func NewInt64Adder[T any](inst int64AddAPI, mapper Mapper[T]) Int64Adder[T] {
+	fast, _ := inst.(FastInt64Adder)
+	return Int64Adder[T]{inst: inst, fast: fast, mapper: mapper}
+}
+
+// NewFloat64Adder returns a Float64Adder.  See NewInt64Adder.
+func NewFloat64Adder[T any](inst float64AddAPI, mapper Mapper[T]) Float64Adder[T] {
+	fast, _ := inst.(FastFloat64Adder)
+	return Float64Adder[T]{inst: inst, fast: fast, mapper: mapper}
+}
+
+// NewInt64Recorder returns an Int64Recorder.  The fast-path is used
+// for Histogram instruments returned by this SDK.
+func NewInt64Recorder[T any](inst int64RecordAPI, mapper Mapper[T]) Int64Recorder[T] {
+	fast, _ := inst.(FastInt64Recorder)
+	return Int64Recorder[T]{inst: inst, fast: fast, mapper: mapper}
+}
+
+// NewFloat64Recorder returns a Float64Recorder.  See NewInt64Recorder.
+func NewFloat64Recorder[T any](inst float64RecordAPI, mapper Mapper[T]) Float64Recorder[T] {
+	fast, _ := inst.(FastFloat64Recorder)
+	return Float64Recorder[T]{inst: inst, fast: fast, mapper: mapper}
+}
+
+// Add maps v to attributes and adds value.
+func (a Int64Adder[T]) Add(ctx context.Context, value int64, v T) {
+	if a.fast != nil {
+		a.fast.AddWithKeyValues(ctx, value, a.mapper(v)...)
+		return
+	}
+	a.inst.Add(ctx, value, metric.WithAttributes(a.mapper(v)...))
+}
+
+// Add maps v to attributes and adds value.
+func (a Float64Adder[T]) Add(ctx context.Context, value float64, v T) {
+	if a.fast != nil {
+		a.fast.AddWithKeyValues(ctx, value, a.mapper(v)...)
+		return
+	}
+	a.inst.Add(ctx, value, metric.WithAttributes(a.mapper(v)...))
+}
+
+// Record maps v to attributes and records value.
+func (r Int64Recorder[T]) Record(ctx context.Context, value int64, v T) {
+	if r.fast != nil {
+		r.fast.RecordWithKeyValues(ctx, value, r.mapper(v)...)
+		return
+	}
+	r.inst.Record(ctx, value, metric.WithAttributes(r.mapper(v)...))
+}
+
+// Record maps v to attributes and records value.
+func (r Float64Recorder[T]) Record(ctx context.Context, value float64, v T) {
+	if r.fast != nil {
+		r.fast.RecordWithKeyValues(ctx, value, r.mapper(v)...)
+		return
+	}
+	r.inst.Record(ctx, value, metric.WithAttributes(r.mapper(v)...))
+}
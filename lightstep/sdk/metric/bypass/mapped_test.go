@@ -0,0 +1,118 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bypass_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkmetric "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type request struct {
+	Method string
+	Route  string
+	UserID string
+}
+
+func requestAttributes(r request) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("method", r.Method),
+		attribute.String("route", r.Route),
+		attribute.String("user", r.UserID),
+	}
+}
+
+func TestMapper(t *testing.T) {
+	rdr := sdkmetric.NewManualReader("test")
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(
+		rdr,
+		// The view removes the high-cardinality field.
+		view.WithClause(
+			view.MatchInstrumentName("requests"),
+			view.WithKeys([]attribute.Key{"method", "route"}),
+		),
+	))
+	meter := provider.Meter("test")
+
+	cntr, err := meter.Int64Counter("requests")
+	require.NoError(t, err)
+	hist, err := meter.Float64Histogram("latency")
+	require.NoError(t, err)
+
+	requests := bypass.NewInt64Adder(cntr, requestAttributes)
+	latency := bypass.NewFloat64Recorder(hist, requestAttributes)
+
+	ctx := context.Background()
+	for _, user := range []string{"a", "b", "c"} {
+		r := request{Method: "GET", Route: "/index", UserID: user}
+		requests.Add(ctx, 1, r)
+		latency.Record(ctx, 2, r)
+	}
+
+	notime := time.Time{}
+	cumulative := aggregation.CumulativeTemporality
+	test.RequireEqualMetrics(t,
+		rdr.Produce(nil).Scopes[0].Instruments,
+		test.Instrument(
+			test.Descriptor("requests", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(notime, notime, sum.NewMonotonicInt64(3), cumulative,
+				attribute.String("method", "GET"),
+				attribute.String("route", "/index"),
+			),
+		),
+		test.Instrument(
+			test.Descriptor("latency", sdkinstrument.SyncHistogram, number.Float64Kind),
+			test.Point(notime, notime, histogram.NewFloat64(histogram.NewConfig(), 2), cumulative,
+				attribute.String("method", "GET"),
+				attribute.String("route", "/index"),
+				attribute.String("user", "a"),
+			),
+			test.Point(notime, notime, histogram.NewFloat64(histogram.NewConfig(), 2), cumulative,
+				attribute.String("method", "GET"),
+				attribute.String("route", "/index"),
+				attribute.String("user", "b"),
+			),
+			test.Point(notime, notime, histogram.NewFloat64(histogram.NewConfig(), 2), cumulative,
+				attribute.String("method", "GET"),
+				attribute.String("route", "/index"),
+				attribute.String("user", "c"),
+			),
+		),
+	)
+}
+
+func TestMapperOtherSDK(t *testing.T) {
+	// Instruments from other SDKs use the API path.
+	cntr, err := noop.NewMeterProvider().Meter("test").Float64UpDownCounter("c")
+	require.NoError(t, err)
+	gauge, err := noop.NewMeterProvider().Meter("test").Int64Gauge("g")
+	require.NoError(t, err)
+
+	bypass.NewFloat64Adder(cntr, requestAttributes).Add(context.Background(), 1, request{})
+	bypass.NewInt64Recorder(gauge, requestAttributes).Record(context.Background(), 1, request{})
+}
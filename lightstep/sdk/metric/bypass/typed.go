@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bypass // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// structField is one attribute of a struct type.
+type structField struct {
+	key    attribute.Key
+	kind   reflect.Kind
+	offset uintptr
+}
+
+// NewStructMapper returns a Mapper for a struct type whose exported
+// fields are the attributes, so that the set of keys is declared once
+// and a misspelled field fails to compile.  The key of each field is
+// given by its `attribute` tag, or is the field name when there is no
+// tag; fields tagged `attribute:"-"` are skipped.  Fields must have a
+// boolean, signed integer, unsigned integer of up to 32 bits,
+// floating point, or string kind, including named types such as
+// `type Method string`.  For example,
+//
+//	type requestAttrs struct {
+//		Method string `attribute:"http.method"`
+//		Status int    `attribute:"http.status_code"`
+//	}
+//	requests := bypass.NewInt64Adder(counter, bypass.MustStructMapper[requestAttrs]())
+//	requests.Add(ctx, 1, requestAttrs{Method: "GET", Status: 200})
+//
+// The struct type is inspected once, here.  The Mapper reads the
+// fields without reflection, allocating only the returned slice.
+func NewStructMapper[T any]() (Mapper[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("attribute mapper: %v is not a struct type", typ)
+	}
+	var fields []structField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key, ok := f.Tag.Lookup("attribute")
+		if key == "-" {
+			continue
+		}
+		if !ok || key == "" {
+			key = f.Name
+		}
+		switch k := f.Type.Kind(); k {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Float32, reflect.Float64,
+			reflect.String:
+			fields = append(fields, structField{
+				key:    attribute.Key(key),
+				kind:   k,
+				offset: f.Offset,
+			})
+		default:
+			return nil, fmt.Errorf("attribute mapper: field %s of %v has unsupported type %v", f.Name, typ, f.Type)
+		}
+	}
+	return func(v T) []attribute.KeyValue {
+		base := unsafe.Pointer(&v)
+		kvs := make([]attribute.KeyValue, len(fields))
+		for i, f := range fields {
+			kvs[i] = attribute.KeyValue{
+				Key:   f.key,
+				Value: fieldValue(unsafe.Add(base, f.offset), f.kind),
+			}
+		}
+		return kvs
+	}, nil
+}
+
+// MustStructMapper is NewStructMapper for use in variable
+// initialization, panicking when the type is not supported.
+func MustStructMapper[T any]() Mapper[T] {
+	m, err := NewStructMapper[T]()
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// fieldValue returns the value of a field of the kind given.
+func fieldValue(p unsafe.Pointer, kind reflect.Kind) attribute.Value {
+	switch kind {
+	case reflect.Bool:
+		return attribute.BoolValue(*(*bool)(p))
+	case reflect.Int:
+		return attribute.IntValue(*(*int)(p))
+	case reflect.Int8:
+		return attribute.Int64Value(int64(*(*int8)(p)))
+	case reflect.Int16:
+		return attribute.Int64Value(int64(*(*int16)(p)))
+	case reflect.Int32:
+		return attribute.Int64Value(int64(*(*int32)(p)))
+	case reflect.Int64:
+		return attribute.Int64Value(*(*int64)(p))
+	case reflect.Uint8:
+		return attribute.Int64Value(int64(*(*uint8)(p)))
+	case reflect.Uint16:
+		return attribute.Int64Value(int64(*(*uint16)(p)))
+	case reflect.Uint32:
+		return attribute.Int64Value(int64(*(*uint32)(p)))
+	case reflect.Float32:
+		return attribute.Float64Value(float64(*(*float32)(p)))
+	case reflect.Float64:
+		return attribute.Float64Value(*(*float64)(p))
+	default:
+		return attribute.StringValue(*(*string)(p))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bypass_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkmetric "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type method string
+
+type typedAttrs struct {
+	Method  method `attribute:"http.method"`
+	Status  int    `attribute:"http.status_code"`
+	Retry   bool
+	Ratio   float32 `attribute:"ratio"`
+	Port    uint16  `attribute:"port"`
+	Comment string  `attribute:"-"`
+	private string
+}
+
+func TestStructMapper(t *testing.T) {
+	rdr := sdkmetric.NewManualReader("test")
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(rdr))
+
+	cntr, err := provider.Meter("test").Int64Counter("requests")
+	require.NoError(t, err)
+
+	requests := bypass.NewInt64Adder(cntr, bypass.MustStructMapper[typedAttrs]())
+
+	ctx := context.Background()
+	ok := typedAttrs{Method: "GET", Status: 200, Ratio: 0.5, Port: 80, Comment: "x", private: "y"}
+	requests.Add(ctx, 1, ok)
+	requests.Add(ctx, 1, ok)
+	requests.Add(ctx, 1, typedAttrs{Method: "POST", Status: 500, Retry: true, Ratio: 0.5, Port: 80})
+
+	notime := time.Time{}
+	cumulative := aggregation.CumulativeTemporality
+	test.RequireEqualMetrics(t,
+		rdr.Produce(nil).Scopes[0].Instruments,
+		test.Instrument(
+			test.Descriptor("requests", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(notime, notime, sum.NewMonotonicInt64(2), cumulative,
+				attribute.String("http.method", "GET"),
+				attribute.Int("http.status_code", 200),
+				attribute.Bool("Retry", false),
+				attribute.Float64("ratio", 0.5),
+				attribute.Int("port", 80),
+			),
+			test.Point(notime, notime, sum.NewMonotonicInt64(1), cumulative,
+				attribute.String("http.method", "POST"),
+				attribute.Int("http.status_code", 500),
+				attribute.Bool("Retry", true),
+				attribute.Float64("ratio", 0.5),
+				attribute.Int("port", 80),
+			),
+		),
+	)
+}
+
+func TestStructMapperAllocs(t *testing.T) {
+	mapper := bypass.MustStructMapper[typedAttrs]()
+	v := typedAttrs{Method: "GET", Status: 200}
+
+	// The only allocation is the returned slice.
+	require.Equal(t, 1.0, testing.AllocsPerRun(100, func() {
+		_ = mapper(v)
+	}))
+}
+
+func TestStructMapperUnsupported(t *testing.T) {
+	_, err := bypass.NewStructMapper[string]()
+	require.Error(t, err)
+
+	_, err = bypass.NewStructMapper[struct{ Big uint64 }]()
+	require.Error(t, err)
+
+	_, err = bypass.NewStructMapper[struct{ List []string }]()
+	require.Error(t, err)
+
+	require.Panics(t, func() {
+		bypass.MustStructMapper[struct{ Ptr *int }]()
+	})
+}
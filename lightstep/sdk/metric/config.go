@@ -15,8 +15,12 @@
 package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
 
 import (
+	"context"
+	"time"
+
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
@@ -35,6 +39,43 @@ type config struct {
 
 	// performance settings
 	performance sdkinstrument.Performance
+
+	// clock returns the current time.  This is time.Now unless
+	// configured using WithClock.
+	clock func() time.Time
+
+	// deltaWarmup suppresses delta temporality points in the
+	// first collection by each reader.
+	deltaWarmup bool
+
+	// sortOutput sorts the instruments and points of each
+	// collection.
+	sortOutput bool
+
+	// flags returns the feature flag of each synchronous
+	// instrument, configured using WithFeatureFlags.
+	flags func(sdkinstrument.Descriptor) FeatureFlag
+
+	// rateLimits returns the rate limit of each synchronous
+	// instrument, configured using WithRateLimits.
+	rateLimits func(sdkinstrument.Descriptor) RateLimit
+
+	// onOverflow is the callback configured using
+	// WithOverflowCallback.
+	onOverflow func(instrumentName string, set attribute.Set)
+}
+
+// FeatureFlag decides, for each measurement, whether it is recorded.
+type FeatureFlag = func(ctx context.Context) bool
+
+// RateLimit is a token bucket configuration.  A zero Rate means no
+// limit.
+type RateLimit struct {
+	// Rate is the average number of calls allowed per second.
+	Rate float64
+
+	// Burst is the number of calls allowed at once.
+	Burst int
 }
 
 // Option applies a configuration option value to a MeterProvider.
@@ -75,3 +116,81 @@ func WithPerformance(perf sdkinstrument.Performance) Option {
 		return cfg
 	})
 }
+
+// WithClock configures the source of timestamps used by the
+// MeterProvider, including its start time and the time of each
+// collection.  This is meant for deterministic testing.
+func WithClock(clock func() time.Time) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.clock = clock
+		return cfg
+	})
+}
+
+// WithDeltaWarmup causes each reader's first collection to omit
+// delta temporality points.  The first delta interval starts when
+// the MeterProvider is created, which may include a burst of
+// start-up activity.  With this option, the first interval is
+// collected and discarded, and the first exported delta interval
+// begins at the moment of the first collection.
+func WithDeltaWarmup() Option {
+	return optionFunction(func(cfg config) config {
+		cfg.deltaWarmup = true
+		return cfg
+	})
+}
+
+// WithSortedOutput sorts the output of each collection, within each
+// scope, so that instruments are ordered by name and the points of
+// each instrument are ordered by data.CanonicalEncode of their
+// attributes.  Otherwise, points are in no particular order.  This is
+// meant for tests and strict consumers, since sorting adds to the
+// cost of every collection.
+func WithSortedOutput() Option {
+	return optionFunction(func(cfg config) config {
+		cfg.sortOutput = true
+		return cfg
+	})
+}
+
+// WithFeatureFlags configures a feature flag for synchronous
+// instruments.  The lookup function is called once when each
+// instrument is created, and may return nil to record unconditionally.
+// The flag is evaluated on every call to the instrument before any
+// attribute processing, so when it returns false the measurement is
+// skipped without constructing an attribute set.  Asynchronous
+// instruments are not affected.
+func WithFeatureFlags(lookup func(sdkinstrument.Descriptor) FeatureFlag) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.flags = lookup
+		return cfg
+	})
+}
+
+// WithRateLimits configures a rate limit for synchronous
+// instruments.  The lookup function is called once when each
+// instrument is created, and each instrument has its own token
+// bucket.  Calls over the limit are still aggregated, so counts and
+// sums remain exact, but they are not considered for exemplars, which
+// saves the cost of inspecting the context and capturing the
+// measurement.  Throttled calls are reported periodically through
+// otel.Handle.  Asynchronous instruments are not affected.
+func WithRateLimits(lookup func(sdkinstrument.Descriptor) RateLimit) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.rateLimits = lookup
+		return cfg
+	})
+}
+
+// WithOverflowCallback configures a callback for cardinality
+// overflow, called the first time in each collection interval that an
+// instrument records measurements in the overflow attribute set,
+// with the instrument name and the attribute set that overflowed.
+// This sets sdkinstrument.Performance.OnOverflow regardless of the
+// order of any WithPerformance option.
+func WithOverflowCallback(callback func(instrumentName string, set attribute.Set)) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.onOverflow = callback
+		return cfg
+	})
+}
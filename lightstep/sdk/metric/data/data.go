@@ -39,7 +39,11 @@ type (
 		// Resource is the MeterProvider's configured Resource.
 		Resource *resource.Resource
 
-		// Scopes is a slice of metric data, one per Meter.
+		// Scopes is a slice of metric data, one per Meter, in
+		// the order the Meters were created.  Since the
+		// MeterProvider has one Meter per instrumentation
+		// scope, each scope appears at most once, so that each
+		// entry corresponds with one OTLP ScopeMetrics.
 		Scopes []Scope
 	}
 
@@ -65,6 +69,12 @@ type (
 
 		// Points is a slice of metric data, one per attribute.Set value.
 		Points []Point
+
+		// DroppedMeasurements is the number of measurements
+		// dropped since the instrument was created because
+		// the cardinality limit was reached with
+		// aggregator.Config.DisableOverflow or DropOverflow.
+		DroppedMeasurements uint64
 	}
 
 	// Point is a timeseries data point resulting from a single collection.
@@ -72,6 +82,11 @@ type (
 		// Attributes are the coordinates of this series.
 		Attributes attribute.Set
 
+		// Metadata are non-identifying attributes of this
+		// series, which are not part of its coordinates.  The
+		// OTLP exporters output them with the attributes.
+		Metadata attribute.Set
+
 		// Temporality applies to instruments that have it defined
 		// (which excludes the asynchronous gauge).
 		Temporality aggregation.Temporality
@@ -89,12 +104,37 @@ type (
 		// was performed.
 		End time.Time
 
+		// Observed is the time of a gauge's value, when
+		// configured by aggregator.Config.GaugeTimestamps,
+		// otherwise the zero time.
+		Observed time.Time
+
+		// Observations is the number of observations of a
+		// gauge's value, when configured by
+		// aggregator.Config.GaugeCounts, otherwise zero.
+		Observations uint64
+
+		// LastUpdate is the time of the latest measurement of
+		// the series, when the view records it with
+		// view.WithLastUpdateTime, otherwise the zero time.
+		LastUpdate time.Time
+
 		// Exemplars. See the comments on Metrics about re-use
 		// of slices in this struct.
 		Exemplars []aggregator.WeightedExemplarBits
 	}
 )
 
+// ByScope returns the instruments of each scope, keyed by scope.  The
+// slices are shared with m, not copied.
+func (m *Metrics) ByScope() map[instrumentation.Scope][]Instrument {
+	scopes := make(map[instrumentation.Scope][]Instrument, len(m.Scopes))
+	for _, scope := range m.Scopes {
+		scopes[scope.Library] = scope.Instruments
+	}
+	return scopes
+}
+
 // Reset sets the size of every slice to zero, while maintaining
 // capacity, to support re-use.
 func (m *Metrics) Reset() {
@@ -0,0 +1,108 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package data // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+
+import (
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CanonicalEncode returns a string that identifies the attribute set
+// the way the SDK identifies a series: two sets encode the same if
+// and only if they are equal as attribute.Set values, which is how
+// the SDK keys its series after attribute filtering.  Sets compare
+// floating point values by their bits, so -0 and +0 are different
+// sets, hence different series, and encode as "-0" and "0"; they are
+// not normalized.  NaN values with different bit patterns are the
+// exception, which are different sets that encode the same.  Use the
+// Attributes of an exported Point to obtain the key of its series.
+//
+// The encoding lists the attributes in the set's (sorted) order,
+// separated by commas, each as a quoted key, "=", a type code, ":",
+// and the value.  Strings are quoted, floating point values use the
+// shortest representation that round-trips, and slices are enclosed
+// in brackets.  The type codes are b, i, f, and s for BOOL, INT64,
+// FLOAT64, and STRING, with a "[]" suffix for slices.  For example,
+//
+//	"host"=s:"a","port"=i:8080
+//
+// This format is stable; a future change would be made through a new
+// function.
+func CanonicalEncode(set attribute.Set) string {
+	var b strings.Builder
+	iter := set.Iter()
+	for i := 0; iter.Next(); i++ {
+		kv := iter.Attribute()
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(string(kv.Key)))
+		b.WriteByte('=')
+		encodeValue(&b, kv.Value)
+	}
+	return b.String()
+}
+
+// encodeValue appends the type code and value.
+func encodeValue(b *strings.Builder, v attribute.Value) {
+	switch v.Type() {
+	case attribute.BOOL:
+		b.WriteString("b:")
+		b.WriteString(strconv.FormatBool(v.AsBool()))
+	case attribute.INT64:
+		b.WriteString("i:")
+		b.WriteString(strconv.FormatInt(v.AsInt64(), 10))
+	case attribute.FLOAT64:
+		b.WriteString("f:")
+		b.WriteString(formatFloat(v.AsFloat64()))
+	case attribute.STRING:
+		b.WriteString("s:")
+		b.WriteString(strconv.Quote(v.AsString()))
+	case attribute.BOOLSLICE:
+		b.WriteString("b[]:")
+		encodeSlice(b, v.AsBoolSlice(), strconv.FormatBool)
+	case attribute.INT64SLICE:
+		b.WriteString("i[]:")
+		encodeSlice(b, v.AsInt64Slice(), func(i int64) string {
+			return strconv.FormatInt(i, 10)
+		})
+	case attribute.FLOAT64SLICE:
+		b.WriteString("f[]:")
+		encodeSlice(b, v.AsFloat64Slice(), formatFloat)
+	case attribute.STRINGSLICE:
+		b.WriteString("s[]:")
+		encodeSlice(b, v.AsStringSlice(), strconv.Quote)
+	default:
+		b.WriteString("invalid:")
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeSlice appends the bracketed, comma-separated elements.
+func encodeSlice[T any](b *strings.Builder, s []T, format func(T) string) {
+	b.WriteByte('[')
+	for i, e := range s {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(format(e))
+	}
+	b.WriteByte(']')
+}
@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package data
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestCanonicalEncode(t *testing.T) {
+	for _, test := range []struct {
+		attrs  []attribute.KeyValue
+		expect string
+	}{
+		{nil, ""},
+		{
+			[]attribute.KeyValue{attribute.Int("port", 8080), attribute.String("host", "a")},
+			`"host"=s:"a","port"=i:8080`,
+		},
+		{
+			[]attribute.KeyValue{
+				attribute.Bool("b", true),
+				attribute.Float64("f", 0.25),
+				attribute.String("s", `x,"y"=z`),
+				attribute.BoolSlice("bs", []bool{true, false}),
+				attribute.Int64Slice("is", []int64{1, -2}),
+				attribute.Float64Slice("fs", []float64{1.5, math.Inf(-1)}),
+				attribute.StringSlice("ss", []string{"a", ""}),
+			},
+			`"b"=b:true,"bs"=b[]:[true,false],"f"=f:0.25,"fs"=f[]:[1.5,-Inf],` +
+				`"is"=i[]:[1,-2],"s"=s:"x,\"y\"=z","ss"=s[]:["a",""]`,
+		},
+	} {
+		require.Equal(t, test.expect, CanonicalEncode(attribute.NewSet(test.attrs...)))
+	}
+}
+
+// TestCanonicalEncodeIdentity tests that sets encode identically
+// exactly when they are the same attribute.Set, which the SDK uses
+// as its series key.
+func TestCanonicalEncodeIdentity(t *testing.T) {
+	sets := []attribute.Set{
+		attribute.NewSet(),
+		attribute.NewSet(attribute.String("a", "1")),
+		attribute.NewSet(attribute.Int("a", 1)),
+		attribute.NewSet(attribute.Float64("a", 1)),
+		attribute.NewSet(attribute.Bool("a", true)),
+		attribute.NewSet(attribute.String("a", "true")),
+		attribute.NewSet(attribute.StringSlice("a", []string{"1"})),
+		attribute.NewSet(attribute.Int64Slice("a", []int64{1})),
+		attribute.NewSet(attribute.String("a", "1,b=2")),
+		attribute.NewSet(attribute.String("a", "1"), attribute.String("b", "2")),
+		attribute.NewSet(attribute.Float64("a", 0)),
+		attribute.NewSet(attribute.Float64("a", math.Copysign(0, -1))),
+
+		// Equal to earlier sets: reordered, and with a
+		// duplicate key whose last value is kept.
+		attribute.NewSet(attribute.String("b", "2"), attribute.String("a", "1")),
+		attribute.NewSet(attribute.String("a", "0"), attribute.String("a", "1")),
+	}
+	for _, a := range sets {
+		for _, b := range sets {
+			require.Equal(t, a.Equals(&b), CanonicalEncode(a) == CanonicalEncode(b),
+				"%v vs %v", a.ToSlice(), b.ToSlice())
+		}
+	}
+}
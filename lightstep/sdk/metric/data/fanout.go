@@ -0,0 +1,50 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package data // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// FanOut describes a group of live series of one instrument that
+// have the same attributes apart from a set of volatile keys.  A
+// group with many series, most of them due to one key, indicates
+// that the key is the source of a cardinality leak.
+type FanOut struct {
+	// Reader is the name of the reader whose views produced
+	// the series.
+	Reader string
+
+	// Library is the instrumentation scope of the instrument.
+	Library instrumentation.Scope
+
+	// Descriptor describes the instrument, as modified by the
+	// view.
+	Descriptor sdkinstrument.Descriptor
+
+	// Attributes are the attributes of the group, excluding the
+	// volatile keys.
+	Attributes attribute.Set
+
+	// Series is the number of live series in the group.
+	Series int
+
+	// Values is the number of distinct values of each volatile
+	// key in the group.  Keys that are absent from every series
+	// of the group are not included.
+	Values map[attribute.Key]int
+}
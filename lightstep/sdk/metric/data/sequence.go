@@ -15,7 +15,12 @@
 package data // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
 
 import (
+	"context"
 	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Sequence provides the three relevant timestamps that are used by
@@ -39,7 +44,60 @@ type Collector interface {
 	// snapshots into output.
 	Collect(sequence Sequence, output *[]Instrument)
 
+	// CollectFunc gathers data points like Collect, passing each
+	// one to emit with the descriptor of the output instrument
+	// instead of appending it to an output slice, so
+	// that the points of a collection are not held in memory at
+	// once.  Points are emitted while the instrument is locked
+	// and the storage of each point is re-used for the next, so
+	// emit must finish with the point before it returns and must
+	// not retain it.
+	CollectFunc(sequence Sequence, emit func(desc sdkinstrument.Descriptor, point Point))
+
+	// CollectContext gathers data points like Collect, stopping
+	// early when ctx is done so that the instrument is not
+	// locked past a deadline.  The context is tested between
+	// groups of series.  When stopped, output holds the points
+	// gathered and the context's error is returned; the series
+	// not reached keep their state, so a delta instrument reports
+	// them with the next collection.  Asynchronous instruments
+	// always complete, since their observations do not carry
+	// over to the next collection.
+	CollectContext(ctx context.Context, sequence Sequence, output *[]Instrument) error
+
+	// CollectTemporality gathers data points like Collect, with
+	// the temporality given.  Synchronous instruments configured
+	// with view.WithEitherTemporality keep state for both
+	// temporalities, so that exporters with different
+	// preferences can share them; each point covers the interval
+	// since the last collection with the same temporality.  Other
+	// instruments report their configured temporality, as
+	// Collect.
+	CollectTemporality(sequence Sequence, tempo aggregation.Temporality, output *[]Instrument)
+
+	// Peek gathers data points like Collect, with the temporality
+	// given, without modifying the state kept for Collect.
+	// Cumulative points start at sequence.Start; synchronous
+	// instruments configured with delta temporality keep running
+	// totals of their intervals for this.  Delta points of
+	// instruments configured with delta temporality start at
+	// sequence.Last, otherwise at sequence.Start.
+	Peek(sequence Sequence, tempo aggregation.Temporality, output *[]Instrument)
+
 	// InMemorySize returns the number of entries held in memory.  InMemorySize()
 	// is meant to be called following Collect().
 	InMemorySize() int
+
+	// InMemoryBytes estimates the memory held by the entries of
+	// InMemorySize in bytes, including aggregator storage such as
+	// histogram buckets and exemplars, and any state kept in
+	// addition.  The estimate is not exact; it is meant for
+	// enforcing a memory budget.
+	InMemoryBytes() int
+
+	// FanOut groups the series held in memory by their
+	// attributes apart from the volatile keys, appending one
+	// FanOut per group to output, in decreasing order of Series.
+	// The Reader and Library fields are not set.
+	FanOut(volatile []attribute.Key, output *[]FanOut)
 }
@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errorcount supports counting errors with a category
+// attribute extracted from the error value.
+package errorcount // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/errorcount"
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultKey is the attribute key used for the error category.
+const DefaultKey = attribute.Key("error.type")
+
+// OtherCategory is the category of errors for which the extractor
+// returns no category.
+const OtherCategory = "_OTHER"
+
+// Categorized is implemented by errors that carry a category, such as
+// an error code.
+type Categorized interface {
+	error
+	ErrorCategory() string
+}
+
+// Extractor returns the category of a non-nil error, with false if
+// the error has no category.  Extractors are called concurrently and
+// should not retain the error.
+type Extractor func(err error) (string, bool)
+
+// CategorizedExtractor is the default Extractor.  It uses errors.As
+// to find the first Categorized error in the chain of wrapped errors.
+func CategorizedExtractor(err error) (string, bool) {
+	var ce Categorized
+	if errors.As(err, &ce) {
+		return ce.ErrorCategory(), true
+	}
+	return "", false
+}
+
+// config contains the configuration of a Counter.
+type config struct {
+	key     attribute.Key
+	extract Extractor
+}
+
+// Option applies a configuration option value to a Counter.
+type Option interface {
+	apply(config) config
+}
+
+// optionFunction makes a functional Option out of a function object.
+type optionFunction func(cfg config) config
+
+// apply implements Option.
+func (of optionFunction) apply(in config) config {
+	return of(in)
+}
+
+// WithKey sets the attribute key of the error category.
+func WithKey(key attribute.Key) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.key = key
+		return cfg
+	})
+}
+
+// WithExtractor sets the function that extracts the error category.
+func WithExtractor(extract Extractor) Option {
+	return optionFunction(func(cfg config) config {
+		cfg.extract = extract
+		return cfg
+	})
+}
+
+// Counter counts errors using an Int64Counter, with the error
+// category as an attribute.
+type Counter struct {
+	counter metric.Int64Counter
+	cfg     config
+}
+
+// New returns a Counter that records to the counter.
+func New(counter metric.Int64Counter, opts ...Option) Counter {
+	cfg := config{
+		key:     DefaultKey,
+		extract: CategorizedExtractor,
+	}
+	for _, opt := range opts {
+		cfg = opt.apply(cfg)
+	}
+	return Counter{
+		counter: counter,
+		cfg:     cfg,
+	}
+}
+
+// Category returns the category attribute of an error, which uses
+// OtherCategory when the extractor returns none.
+func (c Counter) Category(err error) attribute.KeyValue {
+	category, ok := c.cfg.extract(err)
+	if !ok {
+		category = OtherCategory
+	}
+	return c.cfg.key.String(category)
+}
+
+// Record adds 1 to the counter with the category of the error and the
+// additional attributes.  Nil errors are not counted.
+func (c Counter) Record(ctx context.Context, err error, attrs ...attribute.KeyValue) {
+	if err == nil {
+		return
+	}
+	kvs := make([]attribute.KeyValue, 0, len(attrs)+1)
+	kvs = append(kvs, attrs...)
+	kvs = append(kvs, c.Category(err))
+
+	if fast, ok := c.counter.(bypass.FastInt64Adder); ok {
+		fast.AddWithKeyValues(ctx, 1, kvs...)
+		return
+	}
+	c.counter.Add(ctx, 1, metric.WithAttributes(kvs...))
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorcount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sdkmetric "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type codedError struct {
+	code int
+}
+
+func (e codedError) Error() string         { return fmt.Sprint("code ", e.code) }
+func (e codedError) ErrorCategory() string { return fmt.Sprint(e.code) }
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	rdr := sdkmetric.NewManualReader("test")
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(rdr), sdkmetric.WithResource(resource.Empty()))
+
+	cntr, err := provider.Meter("test").Int64Counter("errors")
+	require.NoError(t, err)
+
+	// A custom extractor sees the plain error.
+	timeouts, err := provider.Meter("test").Int64Counter("timeouts")
+	require.NoError(t, err)
+
+	errs := New(cntr)
+	timeoutErrs := New(timeouts,
+		WithKey("reason"),
+		WithExtractor(func(err error) (string, bool) {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return "deadline", true
+			}
+			return "", false
+		}),
+	)
+
+	attr := attribute.String("op", "read")
+
+	errs.Record(ctx, codedError{404}, attr)
+	errs.Record(ctx, fmt.Errorf("wrapped: %w", codedError{404}), attr)
+	errs.Record(ctx, fmt.Errorf("twice: %w", fmt.Errorf("wrapped: %w", codedError{500})), attr)
+	errs.Record(ctx, errors.New("plain"), attr)
+	errs.Record(ctx, nil, attr)
+
+	timeoutErrs.Record(ctx, fmt.Errorf("call: %w", context.DeadlineExceeded))
+	timeoutErrs.Record(ctx, codedError{404})
+	timeoutErrs.Record(ctx, nil)
+
+	var notime time.Time
+	const cumulative = aggregation.CumulativeTemporality
+
+	test.RequireEqualResourceMetrics(t, rdr.Produce(nil), resource.Empty(),
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("errors", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(notime, notime, sum.NewMonotonicInt64(2), cumulative, attr, DefaultKey.String("404")),
+				test.Point(notime, notime, sum.NewMonotonicInt64(1), cumulative, attr, DefaultKey.String("500")),
+				test.Point(notime, notime, sum.NewMonotonicInt64(1), cumulative, attr, DefaultKey.String(OtherCategory)),
+			),
+			test.Instrument(
+				test.Descriptor("timeouts", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(notime, notime, sum.NewMonotonicInt64(1), cumulative, attribute.String("reason", "deadline")),
+				test.Point(notime, notime, sum.NewMonotonicInt64(1), cumulative, attribute.String("reason", OtherCategory)),
+			),
+		),
+	)
+}
@@ -0,0 +1,165 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// BucketReservoir keeps the most recent exemplar in each bucket of a
+// fixed base-2 exponential bucketing, which corresponds with scale 0
+// of the exponential histogram: bucket i holds the magnitudes in
+// (2^i, 2^(i+1)].  At finer histogram scales, each of these buckets
+// spans several histogram buckets; see ScaledBucketReservoir for a
+// reservoir that follows the histogram's scale.
+//
+// At most size buckets are kept; events in new buckets beyond the
+// limit are not selected.  Each exemplar has weight equal to the
+// number of events offered in its bucket.
+type BucketReservoir struct {
+	size    int
+	buckets map[bucketKey]*bucketSample
+}
+
+// bucketKey identifies a bucket.  Zero has its own bucket.
+type bucketKey struct {
+	negative bool
+	zero     bool
+	index    int
+}
+
+// bucketSample is the exemplar of one bucket.
+type bucketSample struct {
+	count uint64
+	ex    aggregator.ExemplarBits
+}
+
+var _ Reservoir = &BucketReservoir{}
+
+// NewBucketReservoir returns a reservoir keeping one exemplar in each
+// of up to size buckets.
+func NewBucketReservoir(size int) *BucketReservoir {
+	return &BucketReservoir{
+		size:    size,
+		buckets: map[bucketKey]*bucketSample{},
+	}
+}
+
+// bucketFor returns the bucket of a value.
+func bucketFor(value float64) bucketKey {
+	if value == 0 {
+		return bucketKey{zero: true}
+	}
+	frac, exp := math.Frexp(math.Abs(value))
+	// |value| = frac * 2^exp, with frac in [0.5, 1), so |value|
+	// is in [2^(exp-1), 2^exp).  Exact powers of two belong to
+	// the lower bucket, since buckets include their upper bound.
+	index := exp - 1
+	if frac == 0.5 {
+		index--
+	}
+	return bucketKey{negative: value < 0, index: index}
+}
+
+// Offer implements Reservoir.
+func (r *BucketReservoir) Offer(value float64, ex aggregator.ExemplarBits) {
+	r.add(bucketFor(value), 1, ex)
+}
+
+func (r *BucketReservoir) add(key bucketKey, count uint64, ex aggregator.ExemplarBits) {
+	if b, ok := r.buckets[key]; ok {
+		b.count += count
+		b.ex = ex
+		return
+	}
+	if len(r.buckets) >= r.size {
+		return
+	}
+	r.buckets[key] = &bucketSample{count: count, ex: ex}
+}
+
+// Collect implements Reservoir.  Exemplars are output in increasing
+// order of bucket.
+func (r *BucketReservoir) Collect(in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	keys := make([]bucketKey, 0, len(r.buckets))
+	for key := range r.buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bucketLess(keys[i], keys[j])
+	})
+	for _, key := range keys {
+		b := r.buckets[key]
+		in = append(in, aggregator.WeightedExemplarBits{
+			ExemplarBits: b.ex,
+			Weight:       float64(b.count),
+		})
+	}
+	return in
+}
+
+// bucketLess orders buckets by the values they contain.
+func bucketLess(a, b bucketKey) bool {
+	rank := func(k bucketKey) int {
+		switch {
+		case k.zero:
+			return 0
+		case k.negative:
+			return -1
+		default:
+			return 1
+		}
+	}
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra < rb
+	}
+	if a.negative {
+		return a.index > b.index
+	}
+	return a.index < b.index
+}
+
+// Reset implements Reservoir.
+func (r *BucketReservoir) Reset() {
+	for key := range r.buckets {
+		delete(r.buckets, key)
+	}
+}
+
+// Merge implements Reservoir.  The argument's exemplars are taken to
+// be more recent.
+func (r *BucketReservoir) Merge(from Reservoir) {
+	f, ok := from.(*BucketReservoir)
+	if !ok {
+		return
+	}
+	for key, b := range f.buckets {
+		r.add(key, b.count, b.ex)
+	}
+}
+
+// SizeOf counts each bucket as its key, its sample, and a pointer.
+func (r *BucketReservoir) SizeOf() int {
+	return int(unsafe.Sizeof(*r)) + len(r.buckets)*bucketEntrySize
+}
+
+// bucketEntrySize estimates the memory of one bucket in a map of
+// bucket samples.
+const bucketEntrySize = int(unsafe.Sizeof(bucketKey{}) + unsafe.Sizeof(bucketSample{}) + unsafe.Sizeof(uintptr(0)))
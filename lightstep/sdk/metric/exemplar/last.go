@@ -16,6 +16,7 @@ package exemplar
 
 import (
 	"sync"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -27,6 +28,7 @@ type LastStorage[N number.Any, Storage any, Methods aggregator.Methods[N, Storag
 
 	lock     sync.Mutex
 	exemplar aggregator.ExemplarBits
+	maxLinks int
 }
 
 type LastMethods[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct{}
@@ -44,6 +46,7 @@ func (s *LastStorage[N, Storage, Methods]) Unwrap() aggregation.Aggregation {
 func (m LastMethods[N, Storage, Methods]) Init(ptr *LastStorage[N, Storage, Methods], cfg aggregator.Config) {
 	var am Methods
 	am.Init(&ptr.aggregate, cfg)
+	ptr.maxLinks = maxLinks(cfg)
 }
 
 func (m LastMethods[N, Storage, Methods]) Update(ptr *LastStorage[N, Storage, Methods], number N, ex aggregator.ExemplarBits) {
@@ -53,12 +56,29 @@ func (m LastMethods[N, Storage, Methods]) Update(ptr *LastStorage[N, Storage, Me
 		return
 	}
 
+	limitLinks(&ex, ptr.maxLinks)
+
 	ptr.lock.Lock()
 	defer ptr.lock.Unlock()
 	ptr.exemplar = ex
 	am.Update(&ptr.aggregate, number, ex)
 }
 
+func (m LastMethods[N, Storage, Methods]) UpdateBatch(ptr *LastStorage[N, Storage, Methods], number N, count uint64, ex aggregator.ExemplarBits) {
+	var am Methods
+	if ex.Attributes == nil || count == 0 {
+		am.UpdateBatch(&ptr.aggregate, number, count, ex)
+		return
+	}
+
+	limitLinks(&ex, ptr.maxLinks)
+
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	ptr.exemplar = ex
+	am.UpdateBatch(&ptr.aggregate, number, count, ex)
+}
+
 func (m LastMethods[N, Storage, Methods]) Move(input, output *LastStorage[N, Storage, Methods]) {
 	var am Methods
 	input.lock.Lock()
@@ -108,8 +128,15 @@ func (m LastMethods[N, Storage, Methods]) HasChange(ptr *LastStorage[N, Storage,
 	return am.HasChange(&ptr.aggregate)
 }
 
+func (m LastMethods[N, Storage, Methods]) SizeOf(ptr *LastStorage[N, Storage, Methods]) int {
+	var am Methods
+	return int(unsafe.Sizeof(*ptr)-unsafe.Sizeof(ptr.aggregate)) + am.SizeOf(&ptr.aggregate)
+}
+
 func (m LastMethods[N, Storage, Methods]) Exemplars(ptr *LastStorage[N, Storage, Methods], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
 	// By the time exemplars are read, the object does not require locking.
+	var am Methods
+	in = am.Exemplars(&ptr.aggregate, in)
 	return append(in, aggregator.WeightedExemplarBits{
 		ExemplarBits: ptr.exemplar,
 	})
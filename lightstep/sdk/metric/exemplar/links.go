@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"context"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ContextWithLinks returns a context carrying references to traces
+// that contributed to measurements made with the context, in addition
+// to the span of the context itself.  This is meant for operations
+// that span several traces, such as a batch of requests.  Exemplars
+// selected from these measurements carry the links, up to the
+// ExemplarConfig.MaxLinks limit.
+func ContextWithLinks(ctx context.Context, links ...trace.SpanContext) context.Context {
+	m := aggregator.MeasurementFromContext(ctx)
+	m.Links = links
+	return aggregator.ContextWithMeasurement(ctx, m)
+}
+
+// LinksFromContext returns the links set by ContextWithLinks.
+func LinksFromContext(ctx context.Context) []trace.SpanContext {
+	return aggregator.MeasurementFromContext(ctx).Links
+}
+
+// maxLinks returns the configured limit on links per exemplar.
+func maxLinks(cfg aggregator.Config) int {
+	if cfg.Exemplar.MaxLinks == 0 {
+		return aggregator.DefaultExemplarMaxLinks
+	}
+	return int(cfg.Exemplar.MaxLinks)
+}
+
+// limitLinks copies the links of an exemplar, at most limit of them,
+// counting the remainder as dropped.  The copy ensures the exemplar
+// does not share memory with the caller.
+func limitLinks(ex *aggregator.ExemplarBits, limit int) {
+	if len(ex.Links) == 0 {
+		return
+	}
+	links := ex.Links
+	if len(links) > limit {
+		ex.DroppedLinks += uint32(len(links) - limit)
+		links = links[:limit]
+	}
+	ex.Links = append([]trace.SpanContext(nil), links...)
+}
@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"sort"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// MaxReservoir keeps the exemplars with the largest values, for
+// example latency outliers.  Each exemplar has weight 1.
+type MaxReservoir struct {
+	size    int
+	samples []valueSample
+}
+
+// valueSample is an exemplar with its value.
+type valueSample struct {
+	value float64
+	ex    aggregator.ExemplarBits
+}
+
+var _ Reservoir = &MaxReservoir{}
+
+// NewMaxReservoir returns a reservoir keeping the size largest
+// values.
+func NewMaxReservoir(size int) *MaxReservoir {
+	return &MaxReservoir{
+		size:    size,
+		samples: make([]valueSample, 0, size),
+	}
+}
+
+// Offer implements Reservoir.
+func (r *MaxReservoir) Offer(value float64, ex aggregator.ExemplarBits) {
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, valueSample{value: value, ex: ex})
+		return
+	}
+	if r.size == 0 {
+		return
+	}
+	// Replace the smallest value, if the new value is larger.
+	// Ties are resolved in favor of the newer event.
+	mi := 0
+	for i := range r.samples {
+		if r.samples[i].value < r.samples[mi].value {
+			mi = i
+		}
+	}
+	if value >= r.samples[mi].value {
+		r.samples[mi] = valueSample{value: value, ex: ex}
+	}
+}
+
+// Collect implements Reservoir.  Exemplars are output in decreasing
+// order of value.
+func (r *MaxReservoir) Collect(in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	sort.SliceStable(r.samples, func(i, j int) bool {
+		return r.samples[i].value > r.samples[j].value
+	})
+	for _, s := range r.samples {
+		in = append(in, aggregator.WeightedExemplarBits{
+			ExemplarBits: s.ex,
+			Weight:       1,
+		})
+	}
+	return in
+}
+
+// Reset implements Reservoir.
+func (r *MaxReservoir) Reset() {
+	r.samples = r.samples[:0]
+}
+
+// Merge implements Reservoir.
+func (r *MaxReservoir) Merge(from Reservoir) {
+	f, ok := from.(*MaxReservoir)
+	if !ok {
+		return
+	}
+	for _, s := range f.samples {
+		r.Offer(s.value, s.ex)
+	}
+}
+
+func (r *MaxReservoir) SizeOf() int {
+	return int(unsafe.Sizeof(*r)) + cap(r.samples)*int(unsafe.Sizeof(valueSample{}))
+}
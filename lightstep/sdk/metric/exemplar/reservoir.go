@@ -0,0 +1,221 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"math/rand"
+	"sync"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// Reservoir is an exemplar selection strategy.  Reservoirs are not
+// synchronized; the storage that contains one is responsible for
+// locking.
+type Reservoir interface {
+	// Offer considers one event for selection.
+	Offer(value float64, ex aggregator.ExemplarBits)
+
+	// Collect appends the selected exemplars to the input.
+	Collect(in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits
+
+	// Reset clears the selection.
+	Reset()
+
+	// Merge combines the selection of another reservoir of the
+	// same strategy into this one.  The argument is unmodified.
+	Merge(from Reservoir)
+
+	// SizeOf estimates the memory used by the selection in
+	// bytes.
+	SizeOf() int
+}
+
+// NewReservoir returns the Reservoir selected by the configuration.
+// A zero size uses aggregator.DefaultExemplarReservoirSize.  The
+// default kind uses a uniform reservoir.
+func NewReservoir(cfg aggregator.ExemplarConfig) Reservoir {
+	size := int(cfg.Size)
+	if size == 0 {
+		size = aggregator.DefaultExemplarReservoirSize
+	}
+	switch cfg.Reservoir {
+	case aggregator.MaxReservoirKind:
+		return NewMaxReservoir(size)
+	case aggregator.BucketReservoirKind:
+		return NewBucketReservoir(size)
+	case aggregator.ScaledBucketReservoirKind:
+		return NewScaledBucketReservoir(size)
+	default:
+		return NewUniformReservoir(size, rand.New(rand.NewSource(rand.Int63())))
+	}
+}
+
+// ReservoirStorage is an aggregator Storage with exemplars selected
+// by a Reservoir.
+type ReservoirStorage[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
+	aggregate Storage
+
+	lock     sync.Mutex
+	res      Reservoir
+	maxLinks int
+}
+
+type ReservoirMethods[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct{}
+
+func (s *ReservoirStorage[N, Storage, Methods]) Kind() aggregation.Kind {
+	var am Methods
+	return am.Kind()
+}
+
+func (s *ReservoirStorage[N, Storage, Methods]) Unwrap() aggregation.Aggregation {
+	var am Methods
+	return am.ToAggregation(&s.aggregate)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Init(ptr *ReservoirStorage[N, Storage, Methods], cfg aggregator.Config) {
+	var am Methods
+	am.Init(&ptr.aggregate, cfg)
+	ptr.res = NewReservoir(cfg.Exemplar)
+	ptr.maxLinks = maxLinks(cfg)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Update(ptr *ReservoirStorage[N, Storage, Methods], value N, ex aggregator.ExemplarBits) {
+	var am Methods
+
+	if ex.Span == nil {
+		// Avoid locking when the filter rejects sampling.
+		am.Update(&ptr.aggregate, value, ex)
+		return
+	}
+
+	limitLinks(&ex, ptr.maxLinks)
+
+	// Note: The lock protects the Update() call to ensure the
+	// aggregate and samples are consistent.
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+
+	am.Update(&ptr.aggregate, value, ex)
+	ptr.res.Offer(float64(value), ex)
+	followScale(ptr.res, &ptr.aggregate)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) UpdateBatch(ptr *ReservoirStorage[N, Storage, Methods], value N, count uint64, ex aggregator.ExemplarBits) {
+	var am Methods
+
+	if ex.Span == nil || count == 0 {
+		am.UpdateBatch(&ptr.aggregate, value, count, ex)
+		return
+	}
+
+	limitLinks(&ex, ptr.maxLinks)
+
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+
+	am.UpdateBatch(&ptr.aggregate, value, count, ex)
+	ptr.res.Offer(float64(value), ex)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Move(input, output *ReservoirStorage[N, Storage, Methods]) {
+	input.lock.Lock()
+	defer input.lock.Unlock()
+
+	var am Methods
+	am.Move(&input.aggregate, &output.aggregate)
+
+	output.res, input.res = input.res, output.res
+	input.res.Reset()
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Copy(input, output *ReservoirStorage[N, Storage, Methods]) {
+	input.lock.Lock()
+	defer input.lock.Unlock()
+
+	var am Methods
+	am.Copy(&input.aggregate, &output.aggregate)
+
+	output.res.Reset()
+	output.res.Merge(input.res)
+	followScale(output.res, &output.aggregate)
+}
+
+// Merge combines the aggregates and the reservoirs, so the output
+// selects from the exemplars of both sides, up to the reservoir size.
+func (m ReservoirMethods[N, Storage, Methods]) Merge(input, output *ReservoirStorage[N, Storage, Methods]) {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+
+	var am Methods
+	am.Merge(&input.aggregate, &output.aggregate)
+
+	output.res.Merge(input.res)
+	followScale(output.res, &output.aggregate)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) SubtractSwap(operand, argument *ReservoirStorage[N, Storage, Methods]) {
+	// impossible because exemplars are for synchronous
+	// instruments and subtract is only used with async
+	// instruments.
+	panic("impossible")
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) ToAggregation(ptr *ReservoirStorage[N, Storage, Methods]) aggregation.Aggregation {
+	return ptr
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) ToStorage(agg aggregation.Aggregation) (*ReservoirStorage[N, Storage, Methods], bool) {
+	r, ok := agg.(*ReservoirStorage[N, Storage, Methods])
+	return r, ok
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Kind() aggregation.Kind {
+	var am Methods
+	return am.Kind()
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) HasChange(ptr *ReservoirStorage[N, Storage, Methods]) bool {
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+
+	var am Methods
+	return am.HasChange(&ptr.aggregate)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) SizeOf(ptr *ReservoirStorage[N, Storage, Methods]) int {
+	var am Methods
+	size := int(unsafe.Sizeof(*ptr)-unsafe.Sizeof(ptr.aggregate)) + am.SizeOf(&ptr.aggregate)
+
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	return size + ptr.res.SizeOf()
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Exemplars(ptr *ReservoirStorage[N, Storage, Methods], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	// By the time exemplars are read, the object does not require locking.
+	var am Methods
+	in = am.Exemplars(&ptr.aggregate, in)
+	followScale(ptr.res, &ptr.aggregate)
+	return ptr.res.Collect(in)
+}
+
+func (m ReservoirMethods[N, Storage, Methods]) Weight(n N) float64 {
+	var am Methods
+	return am.Weight(n)
+}
@@ -0,0 +1,374 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/lightstep/go-expohisto/mapping/logarithm"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// offer offers each value with an exemplar identified by its value.
+func offer(r Reservoir, values ...float64) {
+	for _, v := range values {
+		r.Offer(v, aggregator.ExemplarBits{Number: number.FromFloat64(v)})
+	}
+}
+
+// collected returns the exemplar values and weights.
+func collected(r Reservoir) (values, weights []float64) {
+	for _, ex := range r.Collect(nil) {
+		values = append(values, number.ToFloat64(ex.Number))
+		weights = append(weights, ex.Weight)
+	}
+	return values, weights
+}
+
+func TestUniformReservoir(t *testing.T) {
+	r := NewUniformReservoir(3, rand.New(rand.NewSource(1)))
+
+	// Under the size, everything is kept.
+	offer(r, 1, 2)
+	values, weights := collected(r)
+	require.Equal(t, []float64{1, 2}, values)
+	require.Equal(t, []float64{1, 1}, weights)
+
+	// Over the size, distinct inputs are kept with weight n/k.
+	offer(r, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+	values, weights = collected(r)
+	require.Equal(t, 3, len(values))
+	require.Equal(t, []float64{4, 4, 4}, weights)
+	for _, v := range values {
+		require.True(t, v >= 1 && v <= 12)
+	}
+	require.NotEqual(t, values[0], values[1])
+	require.NotEqual(t, values[1], values[2])
+
+	r.Reset()
+	values, _ = collected(r)
+	require.Empty(t, values)
+
+	// Every position is selected with probability k/n.
+	const trials = 10000
+	counts := map[float64]int{}
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < trials; i++ {
+		r := NewUniformReservoir(2, rnd)
+		offer(r, 1, 2, 3, 4)
+		values, _ := collected(r)
+		for _, v := range values {
+			counts[v]++
+		}
+	}
+	for v := 1.0; v <= 4; v++ {
+		require.InEpsilon(t, trials/2, counts[v], 0.05, "value %v", v)
+	}
+}
+
+func TestUniformReservoirMerge(t *testing.T) {
+	r1 := NewUniformReservoir(4, rand.New(rand.NewSource(1)))
+	r2 := NewUniformReservoir(4, rand.New(rand.NewSource(2)))
+	offer(r1, 1, 2)
+	offer(r2, 3, 4, 5, 6, 7, 8)
+
+	r1.Merge(r2)
+	values, weights := collected(r1)
+	require.Equal(t, 4, len(values))
+	require.Equal(t, []float64{2, 2, 2, 2}, weights)
+
+	// Merging into an empty reservoir copies.
+	r3 := NewUniformReservoir(4, rand.New(rand.NewSource(3)))
+	r3.Merge(r1)
+	values3, weights3 := collected(r3)
+	require.Equal(t, values, values3)
+	require.Equal(t, weights, weights3)
+}
+
+func TestUniformReservoirMergeBalance(t *testing.T) {
+	// Two reservoirs of the same size represent 2 and 6 events
+	// per exemplar; the merged sample draws from each side in
+	// proportion to the events it represents.
+	const trials = 10000
+	rnd := rand.New(rand.NewSource(4))
+	var fromSmall, total int
+	for i := 0; i < trials; i++ {
+		r1 := NewUniformReservoir(2, rnd)
+		r2 := NewUniformReservoir(2, rnd)
+		offer(r1, 1, 2, 3, 4)
+		offer(r2, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22)
+
+		r1.Merge(r2)
+		values, weights := collected(r1)
+		require.Equal(t, []float64{8, 8}, weights)
+		for _, v := range values {
+			if v < 10 {
+				fromSmall++
+			}
+			total++
+		}
+	}
+	require.InEpsilon(t, total/4, fromSmall, 0.05)
+}
+
+func TestReservoirStorageMerge(t *testing.T) {
+	type storage = ReservoirStorage[float64, histogram.Float64, histogram.Float64Methods]
+	var methods ReservoirMethods[float64, histogram.Float64, histogram.Float64Methods]
+
+	cfg := aggregator.Config{
+		Histogram: histogram.NewConfig(),
+		Exemplar: aggregator.ExemplarConfig{
+			Size: 4,
+		},
+	}
+	span := trace.SpanFromContext(context.Background())
+
+	// Each side holds distinct exemplars, and the merged
+	// reservoir keeps exemplars of both.
+	const trials = 1000
+	var both int
+	for i := 0; i < trials; i++ {
+		var a, b storage
+		methods.Init(&a, cfg)
+		methods.Init(&b, cfg)
+		for v := 1.0; v <= 4; v++ {
+			methods.Update(&a, v, aggregator.ExemplarBits{Span: span, Number: number.FromFloat64(v)})
+			methods.Update(&b, v+10, aggregator.ExemplarBits{Span: span, Number: number.FromFloat64(v + 10)})
+		}
+		methods.Merge(&b, &a)
+
+		exs := methods.Exemplars(&a, nil)
+		require.Equal(t, 4, len(exs))
+		sides := map[bool]bool{}
+		for _, ex := range exs {
+			require.Equal(t, 2.0, ex.Weight)
+			sides[number.ToFloat64(ex.Number) > 10] = true
+		}
+		if len(sides) == 2 {
+			both++
+		}
+		require.Equal(t, uint64(8), a.aggregate.Count())
+	}
+	// Each slot is drawn from either side with equal
+	// probability, so all four come from one side in 1 of 8
+	// merges.
+	require.InEpsilon(t, trials*7/8, both, 0.05)
+}
+
+func TestMaxReservoir(t *testing.T) {
+	r := NewMaxReservoir(3)
+	offer(r, 5, 1, 9, 3, 7, 2, 10, 4, 8, 6)
+
+	values, weights := collected(r)
+	require.Equal(t, []float64{10, 9, 8}, values)
+	require.Equal(t, []float64{1, 1, 1}, weights)
+
+	r2 := NewMaxReservoir(3)
+	offer(r2, 8.5, 11)
+	r.Merge(r2)
+	values, _ = collected(r)
+	require.Equal(t, []float64{11, 10, 9}, values)
+
+	r.Reset()
+	values, _ = collected(r)
+	require.Empty(t, values)
+}
+
+func TestBucketReservoir(t *testing.T) {
+	r := NewBucketReservoir(5)
+
+	// Buckets are (0.5, 1], (1, 2], (2, 4], (4, 8], with zero
+	// and negative values in their own buckets.
+	offer(r, 1, 1.5, 2, 3, 4, 0, -3, 5, 6, 7)
+
+	values, weights := collected(r)
+	require.Equal(t, []float64{-3, 0, 1, 2, 4}, values)
+	require.Equal(t, []float64{1, 1, 1, 2, 2}, weights)
+
+	// The most recent in each bucket is kept when merging.
+	r2 := NewBucketReservoir(5)
+	offer(r2, 0.75, 3.5)
+	r.Merge(r2)
+	values, weights = collected(r)
+	require.Equal(t, []float64{-3, 0, 0.75, 2, 3.5}, values)
+	require.Equal(t, []float64{1, 1, 2, 2, 3}, weights)
+}
+
+func TestReservoirStorage(t *testing.T) {
+	type storage = ReservoirStorage[float64, histogram.Float64, histogram.Float64Methods]
+	var methods ReservoirMethods[float64, histogram.Float64, histogram.Float64Methods]
+
+	cfg := aggregator.Config{
+		Histogram: histogram.NewConfig(),
+		Exemplar: aggregator.ExemplarConfig{
+			Size:      2,
+			Reservoir: aggregator.MaxReservoirKind,
+		},
+	}
+	var current, snapshot, output storage
+	methods.Init(&current, cfg)
+	methods.Init(&snapshot, cfg)
+	methods.Init(&output, cfg)
+
+	span := trace.SpanFromContext(context.Background())
+	for _, v := range []float64{3, 1, 4, 1, 5} {
+		methods.Update(&current, v, aggregator.ExemplarBits{Span: span, Number: number.FromFloat64(v)})
+	}
+	// Unsampled updates are aggregated but not offered.
+	methods.Update(&current, 9, aggregator.ExemplarBits{})
+
+	methods.Move(&current, &snapshot)
+	methods.Merge(&snapshot, &output)
+
+	exs := methods.Exemplars(&output, nil)
+	require.Equal(t, 2, len(exs))
+	require.Equal(t, 5.0, number.ToFloat64(exs[0].Number))
+	require.Equal(t, 4.0, number.ToFloat64(exs[1].Number))
+	require.Equal(t, uint64(6), output.aggregate.Count())
+
+	require.Empty(t, methods.Exemplars(&current, nil))
+}
+
+func TestScaledBucketReservoir(t *testing.T) {
+	r := NewScaledBucketReservoir(5)
+	require.Equal(t, logarithm.MaxScale, r.Scale())
+
+	// At scale 0 the buckets are (0.5, 1], (1, 2], (2, 4], (4, 8],
+	// with zero and negative values in their own buckets.
+	r.Rescale(0)
+	offer(r, 1, 1.5, 2, 3, 0, -3, 5)
+
+	values, weights := collected(r)
+	require.Equal(t, []float64{-3, 0, 1, 2, 3}, values)
+	require.Equal(t, []float64{1, 1, 1, 2, 1}, weights)
+
+	// At scale -1 the buckets are (0.25, 1], (1, 4], (4, 16].
+	// Combined buckets keep the later exemplar.
+	r.Rescale(-1)
+	values, weights = collected(r)
+	require.Equal(t, []float64{-3, 0, 1, 3}, values)
+	require.Equal(t, []float64{1, 1, 1, 3}, weights)
+
+	// The scale does not increase.
+	r.Rescale(0)
+	require.Equal(t, int32(-1), r.Scale())
+
+	// Merging aligns to the lower scale, where the buckets are
+	// (0.0625, 1], (1, 16].
+	r2 := NewScaledBucketReservoir(5)
+	r2.Rescale(-2)
+	offer(r2, 0.75, 12)
+	r.Merge(r2)
+	require.Equal(t, int32(-2), r.Scale())
+	values, weights = collected(r)
+	require.Equal(t, []float64{-3, 0, 0.75, 12}, values)
+	require.Equal(t, []float64{1, 1, 2, 4}, weights)
+
+	r.Reset()
+	require.Equal(t, logarithm.MaxScale, r.Scale())
+	require.Empty(t, r.Collect(nil))
+}
+
+func TestScaledBucketReservoirStorage(t *testing.T) {
+	var methods ReservoirMethods[float64, histogram.Float64, histogram.Float64Methods]
+	var current, output ReservoirStorage[float64, histogram.Float64, histogram.Float64Methods]
+
+	cfg := aggregator.Config{
+		Histogram: histogram.NewConfig(histogram.WithMaxSize(histogram.MinSize)),
+		Exemplar: aggregator.ExemplarConfig{
+			Size:      10,
+			Reservoir: aggregator.ScaledBucketReservoirKind,
+		},
+	}
+	methods.Init(&current, cfg)
+	methods.Init(&output, cfg)
+
+	span := trace.SpanFromContext(context.Background())
+	values := []float64{1, 2, 3, 5, 100, 1000}
+	for _, v := range values {
+		methods.Update(&current, v, aggregator.ExemplarBits{Span: span, Number: number.FromFloat64(v)})
+	}
+	methods.Move(&current, &output)
+
+	// The reservoir has the histogram's scale, with one exemplar
+	// per histogram bucket weighted by the bucket's count.
+	scale := output.aggregate.Scale()
+	require.Equal(t, scale, output.res.(*ScaledBucketReservoir).Scale())
+
+	m := newMapping(scale)
+	pos := output.aggregate.Positive()
+	exs := methods.Exemplars(&output, nil)
+	var total float64
+	for _, ex := range exs {
+		idx := m.MapToIndex(number.ToFloat64(ex.Number))
+		require.Equal(t, float64(pos.At(uint32(idx-pos.Offset()))), ex.Weight)
+		total += ex.Weight
+	}
+	require.Equal(t, float64(len(values)), total)
+}
+
+// Tests that zeros, for which the histogram reports scale zero, do
+// not lower the reservoir's scale.
+func TestScaledBucketReservoirZeros(t *testing.T) {
+	var methods ReservoirMethods[float64, histogram.Float64, histogram.Float64Methods]
+	var current ReservoirStorage[float64, histogram.Float64, histogram.Float64Methods]
+
+	methods.Init(&current, aggregator.Config{
+		Exemplar: aggregator.ExemplarConfig{
+			Size:      10,
+			Reservoir: aggregator.ScaledBucketReservoirKind,
+		},
+	})
+
+	span := trace.SpanFromContext(context.Background())
+	for _, v := range []float64{0, 0, 3} {
+		methods.Update(&current, v, aggregator.ExemplarBits{Span: span, Number: number.FromFloat64(v)})
+	}
+	require.Equal(t, logarithm.MaxScale, current.aggregate.Scale())
+	require.Equal(t, logarithm.MaxScale, current.res.(*ScaledBucketReservoir).Scale())
+}
+
+// Tests that the values carried by a context for measurements are
+// combined.
+func TestMeasurementContext(t *testing.T) {
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	link := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}})
+
+	ctx := histogram.ContextWithWeight(context.Background(), 7)
+	ctx = ContextWithTimestamp(ctx, past)
+	ctx = ContextWithLinks(ctx, link)
+
+	require.Equal(t, aggregator.MeasurementContext{
+		Links:     []trace.SpanContext{link},
+		Time:      past,
+		Weight:    7,
+		HasWeight: true,
+	}, aggregator.MeasurementFromContext(ctx))
+	require.Equal(t, past, TimestampFromContext(ctx))
+	require.Equal(t, []trace.SpanContext{link}, LinksFromContext(ctx))
+	weight, ok := histogram.WeightFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, uint64(7), weight)
+
+	_, ok = histogram.WeightFromContext(context.Background())
+	require.False(t, ok)
+}
@@ -0,0 +1,213 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/lightstep/go-expohisto/mapping"
+	"github.com/lightstep/go-expohisto/mapping/exponent"
+	"github.com/lightstep/go-expohisto/mapping/logarithm"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// ScaledBucketReservoir keeps the most recent exemplar in each bucket
+// of an exponential histogram at the histogram's current scale.  It
+// starts at the maximum scale and follows the histogram as its scale
+// decreases: each bucket at the new scale combines the buckets that
+// it contains, keeping the most recent of their exemplars, so every
+// exemplar remains in the bucket holding its value.  Used with other
+// aggregations, it stays at the maximum scale.
+//
+// At most size buckets are kept; events in new buckets beyond the
+// limit are not selected.  Each exemplar has weight equal to the
+// number of events offered in its bucket.
+type ScaledBucketReservoir struct {
+	size    int
+	mapping mapping.Mapping
+	buckets map[bucketKey]*bucketSample
+}
+
+var _ Reservoir = &ScaledBucketReservoir{}
+
+// scaledReservoir is a Reservoir that follows the scale of an
+// exponential histogram.
+type scaledReservoir interface {
+	Reservoir
+
+	// Rescale lowers the scale of the reservoir to the scale
+	// given, if it is lower.
+	Rescale(scale int32)
+}
+
+// scaledAggregate is an aggregate with a scale, i.e., an exponential
+// histogram.
+type scaledAggregate interface {
+	Scale() int32
+	Count() uint64
+	ZeroCount() uint64
+}
+
+// NewScaledBucketReservoir returns a reservoir keeping one exemplar in
+// each of up to size buckets.
+func NewScaledBucketReservoir(size int) *ScaledBucketReservoir {
+	return &ScaledBucketReservoir{
+		size:    size,
+		mapping: newMapping(logarithm.MaxScale),
+		buckets: map[bucketKey]*bucketSample{},
+	}
+}
+
+func newMapping(scale int32) mapping.Mapping {
+	var m mapping.Mapping
+	var err error
+	if scale <= 0 {
+		m, err = exponent.NewMapping(scale)
+	} else {
+		m, err = logarithm.NewMapping(scale)
+	}
+	if err != nil {
+		panic(fmt.Sprint("impossible scale ", scale))
+	}
+	return m
+}
+
+// Scale returns the current scale.
+func (r *ScaledBucketReservoir) Scale() int32 {
+	return r.mapping.Scale()
+}
+
+// Offer implements Reservoir.
+func (r *ScaledBucketReservoir) Offer(value float64, ex aggregator.ExemplarBits) {
+	key := bucketKey{zero: true}
+	if value != 0 {
+		key = bucketKey{
+			negative: value < 0,
+			index:    int(r.mapping.MapToIndex(math.Abs(value))),
+		}
+	}
+	r.add(key, 1, ex)
+}
+
+// add combines the count, keeping the more recent exemplar.
+func (r *ScaledBucketReservoir) add(key bucketKey, count uint64, ex aggregator.ExemplarBits) {
+	if b, ok := r.buckets[key]; ok {
+		b.count += count
+		if !ex.Time.Before(b.ex.Time) {
+			b.ex = ex
+		}
+		return
+	}
+	if len(r.buckets) >= r.size {
+		return
+	}
+	r.buckets[key] = &bucketSample{count: count, ex: ex}
+}
+
+// Rescale implements scaledReservoir.
+func (r *ScaledBucketReservoir) Rescale(scale int32) {
+	change := r.Scale() - scale
+	if change <= 0 {
+		return
+	}
+	old := r.buckets
+	r.buckets = make(map[bucketKey]*bucketSample, len(old))
+	r.mapping = newMapping(scale)
+
+	// Combining buckets does not increase their number, so the
+	// size limit drops nothing.
+	for _, key := range sortedKeys(old) {
+		b := old[key]
+		r.add(downscaleKey(key, change), b.count, b.ex)
+	}
+}
+
+// downscaleKey returns the key at a scale lower by change.
+func downscaleKey(key bucketKey, change int32) bucketKey {
+	if !key.zero {
+		key.index >>= change
+	}
+	return key
+}
+
+// sortedKeys returns the keys in increasing order of bucket.
+func sortedKeys(buckets map[bucketKey]*bucketSample) []bucketKey {
+	keys := make([]bucketKey, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bucketLess(keys[i], keys[j])
+	})
+	return keys
+}
+
+// Collect implements Reservoir.  Exemplars are output in increasing
+// order of bucket.
+func (r *ScaledBucketReservoir) Collect(in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	for _, key := range sortedKeys(r.buckets) {
+		b := r.buckets[key]
+		in = append(in, aggregator.WeightedExemplarBits{
+			ExemplarBits: b.ex,
+			Weight:       float64(b.count),
+		})
+	}
+	return in
+}
+
+// Reset implements Reservoir.  The scale returns to the maximum, as
+// does the scale of a histogram when it is reset.
+func (r *ScaledBucketReservoir) Reset() {
+	for key := range r.buckets {
+		delete(r.buckets, key)
+	}
+	r.mapping = newMapping(logarithm.MaxScale)
+}
+
+// Merge implements Reservoir.  The result has the lower of the two
+// scales.
+func (r *ScaledBucketReservoir) Merge(from Reservoir) {
+	f, ok := from.(*ScaledBucketReservoir)
+	if !ok {
+		return
+	}
+	r.Rescale(f.Scale())
+	change := f.Scale() - r.Scale()
+	for _, key := range sortedKeys(f.buckets) {
+		b := f.buckets[key]
+		r.add(downscaleKey(key, change), b.count, b.ex)
+	}
+}
+
+// followScale lowers the scale of a scaledReservoir to the scale of
+// an exponential histogram aggregate.
+func followScale(res Reservoir, aggregate any) {
+	sr, ok := res.(scaledReservoir)
+	if !ok {
+		return
+	}
+	// A histogram of only zeros reports scale zero, which it
+	// leaves at the first other value.
+	if sa, ok := aggregate.(scaledAggregate); ok && sa.Count() != sa.ZeroCount() {
+		sr.Rescale(sa.Scale())
+	}
+}
+
+func (r *ScaledBucketReservoir) SizeOf() int {
+	return int(unsafe.Sizeof(*r)) + len(r.buckets)*bucketEntrySize
+}
@@ -0,0 +1,40 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+
+import (
+	"context"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// ContextWithTimestamp returns a context carrying the time at which
+// measurements made with the context happened, for replaying or
+// backfilling data.  Exemplars selected from these measurements carry
+// this timestamp instead of the time of the call, as do histogram
+// extremes and the deduplication window.  The measurements are still
+// aggregated into the current collection interval.
+func ContextWithTimestamp(ctx context.Context, t time.Time) context.Context {
+	m := aggregator.MeasurementFromContext(ctx)
+	m.Time = t
+	return aggregator.ContextWithMeasurement(ctx, m)
+}
+
+// TimestampFromContext returns the timestamp set by
+// ContextWithTimestamp, or the zero time.
+func TimestampFromContext(ctx context.Context) time.Time {
+	return aggregator.MeasurementFromContext(ctx).Time
+}
@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exemplar
+
+import (
+	"math/rand"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+)
+
+// UniformReservoir selects a uniform random sample of fixed size
+// using Algorithm R.  Each exemplar has weight equal to the number
+// of events offered divided by the number selected.
+type UniformReservoir struct {
+	rnd     *rand.Rand
+	size    int
+	count   uint64
+	samples []aggregator.ExemplarBits
+}
+
+var _ Reservoir = &UniformReservoir{}
+
+// NewUniformReservoir returns a uniform reservoir of the given size
+// using the random source, which is not shared.
+func NewUniformReservoir(size int, rnd *rand.Rand) *UniformReservoir {
+	return &UniformReservoir{
+		rnd:     rnd,
+		size:    size,
+		samples: make([]aggregator.ExemplarBits, 0, size),
+	}
+}
+
+// Offer implements Reservoir.
+func (r *UniformReservoir) Offer(_ float64, ex aggregator.ExemplarBits) {
+	r.count++
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, ex)
+		return
+	}
+	if j := r.rnd.Int63n(int64(r.count)); j < int64(r.size) {
+		r.samples[j] = ex
+	}
+}
+
+// Collect implements Reservoir.
+func (r *UniformReservoir) Collect(in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
+	if len(r.samples) == 0 {
+		return in
+	}
+	weight := float64(r.count) / float64(len(r.samples))
+	for _, ex := range r.samples {
+		in = append(in, aggregator.WeightedExemplarBits{
+			ExemplarBits: ex,
+			Weight:       weight,
+		})
+	}
+	return in
+}
+
+// Reset implements Reservoir.
+func (r *UniformReservoir) Reset() {
+	r.count = 0
+	r.samples = r.samples[:0]
+}
+
+// Merge implements Reservoir.  Each slot of the combined sample is
+// drawn from one of the two samples with probability proportional
+// to the number of events each represents.
+func (r *UniformReservoir) Merge(from Reservoir) {
+	f, ok := from.(*UniformReservoir)
+	if !ok || f.count == 0 {
+		return
+	}
+	if r.count == 0 {
+		r.count = f.count
+		r.samples = append(r.samples[:0], f.samples...)
+		return
+	}
+	mine := append([]aggregator.ExemplarBits(nil), r.samples...)
+	theirs := append([]aggregator.ExemplarBits(nil), f.samples...)
+	myCount, theirCount := r.count, f.count
+
+	r.count += f.count
+	r.samples = r.samples[:0]
+
+	for len(r.samples) < r.size && len(mine)+len(theirs) != 0 {
+		pool := &mine
+		if len(mine) == 0 || (len(theirs) != 0 && r.rnd.Int63n(int64(myCount+theirCount)) >= int64(myCount)) {
+			pool = &theirs
+		}
+		i := r.rnd.Intn(len(*pool))
+		r.samples = append(r.samples, (*pool)[i])
+		(*pool)[i] = (*pool)[len(*pool)-1]
+		*pool = (*pool)[:len(*pool)-1]
+	}
+}
+
+func (r *UniformReservoir) SizeOf() int {
+	return int(unsafe.Sizeof(*r)) + cap(r.samples)*int(unsafe.Sizeof(aggregator.ExemplarBits{}))
+}
@@ -18,6 +18,7 @@ import (
 	"math"
 	"math/rand"
 	"sync"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
@@ -28,8 +29,9 @@ import (
 type WeightedStorage[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
 	aggregate Storage
 
-	lock    sync.Mutex
-	samples varopt.Varopt[*aggregator.ExemplarBits]
+	lock     sync.Mutex
+	samples  varopt.Varopt[*aggregator.ExemplarBits]
+	maxLinks int
 }
 
 type WeightedMethods[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct{}
@@ -52,6 +54,7 @@ func (m WeightedMethods[N, Storage, Methods]) Init(ptr *WeightedStorage[N, Stora
 		sz = aggregator.DefaultExemplarReservoirSize
 	}
 	ptr.samples.Init(sz, rand.New(rand.NewSource(rand.Int63())))
+	ptr.maxLinks = maxLinks(cfg)
 }
 
 func (m WeightedMethods[N, Storage, Methods]) Update(ptr *WeightedStorage[N, Storage, Methods], value N, ex aggregator.ExemplarBits) {
@@ -63,6 +66,8 @@ func (m WeightedMethods[N, Storage, Methods]) Update(ptr *WeightedStorage[N, Sto
 		return
 	}
 
+	limitLinks(&ex, ptr.maxLinks)
+
 	// Note: The lock protects the Update() call to ensure the
 	// aggregate and samples are consistent.
 	ptr.lock.Lock()
@@ -85,6 +90,25 @@ func (m WeightedMethods[N, Storage, Methods]) Update(ptr *WeightedStorage[N, Sto
 	ptr.samples.Add(&ex, math.Abs(am.Weight(value)))
 }
 
+// UpdateBatch samples the batch as one event with count times the
+// weight of its value.
+func (m WeightedMethods[N, Storage, Methods]) UpdateBatch(ptr *WeightedStorage[N, Storage, Methods], value N, count uint64, ex aggregator.ExemplarBits) {
+	var am Methods
+
+	if ex.Span == nil || count == 0 {
+		am.UpdateBatch(&ptr.aggregate, value, count, ex)
+		return
+	}
+
+	limitLinks(&ex, ptr.maxLinks)
+
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+
+	am.UpdateBatch(&ptr.aggregate, value, count, ex)
+	ptr.samples.Add(&ex, math.Abs(am.Weight(value))*float64(count))
+}
+
 func (m WeightedMethods[N, Storage, Methods]) Move(input, output *WeightedStorage[N, Storage, Methods]) {
 	input.lock.Lock()
 	defer input.lock.Unlock()
@@ -148,8 +172,21 @@ func (m WeightedMethods[N, Storage, Methods]) HasChange(ptr *WeightedStorage[N,
 	return am.HasChange(&ptr.aggregate)
 }
 
+// SizeOf counts each sample as its exemplar plus a pointer and a
+// weight.
+func (m WeightedMethods[N, Storage, Methods]) SizeOf(ptr *WeightedStorage[N, Storage, Methods]) int {
+	var am Methods
+	size := int(unsafe.Sizeof(*ptr)-unsafe.Sizeof(ptr.aggregate)) + am.SizeOf(&ptr.aggregate)
+
+	ptr.lock.Lock()
+	defer ptr.lock.Unlock()
+	return size + ptr.samples.Size()*int(unsafe.Sizeof(aggregator.ExemplarBits{})+unsafe.Sizeof(uintptr(0))+unsafe.Sizeof(float64(0)))
+}
+
 func (m WeightedMethods[N, Storage, Methods]) Exemplars(ptr *WeightedStorage[N, Storage, Methods], in []aggregator.WeightedExemplarBits) []aggregator.WeightedExemplarBits {
 	// By the time exemplars are read, the object does not require locking.
+	var am Methods
+	in = am.Exemplars(&ptr.aggregate, in)
 	for i := 0; i < ptr.samples.Size(); i++ {
 		ex, weight := ptr.samples.Get(i)
 		in = append(in, aggregator.WeightedExemplarBits{
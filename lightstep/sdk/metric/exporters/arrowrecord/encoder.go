@@ -0,0 +1,398 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package arrowrecord materializes collected metric data into Apache
+// Arrow record batches for columnar transfer.  Each call to Encode
+// yields at most one record per aggregation type (sum, gauge,
+// histogram, minmaxsumcount), with one row per point.
+//
+// Every record begins with the same columns describing the scope,
+// the instrument, and the point's timestamps, followed by an
+// "attributes" struct column and the aggregation-specific value
+// columns.  The attributes struct has one nullable field per
+// attribute key present in the record, in sorted order; a series
+// without a given key has a null value in that field.  Keys that
+// appear with more than one value type in the same record, and
+// slice-valued attributes, are encoded as strings using
+// attribute.Value.Emit().
+package arrowrecord // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exporters/arrowrecord"
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/multierr"
+)
+
+// Record types, stored in each schema's metadata under AggregationKey.
+const (
+	AggregationKey = "otel.aggregation"
+
+	SumRecord            = "sum"
+	GaugeRecord          = "gauge"
+	HistogramRecord      = "histogram"
+	MinMaxSumCountRecord = "minmaxsumcount"
+)
+
+// Column names common to all records.
+const (
+	ScopeNameColumn    = "scope_name"
+	ScopeVersionColumn = "scope_version"
+	NameColumn         = "name"
+	UnitColumn         = "unit"
+	StartTimeColumn    = "start_time_unix_nano"
+	TimeColumn         = "time_unix_nano"
+	TemporalityColumn  = "temporality"
+	AttributesColumn   = "attributes"
+)
+
+// Column names for values.
+const (
+	MonotonicColumn            = "is_monotonic"
+	IntValueColumn             = "int_value"
+	DoubleValueColumn          = "double_value"
+	CountColumn                = "count"
+	SumColumn                  = "sum"
+	MinColumn                  = "min"
+	MaxColumn                  = "max"
+	ScaleColumn                = "scale"
+	ZeroCountColumn            = "zero_count"
+	PositiveOffsetColumn       = "positive_offset"
+	PositiveBucketCountsColumn = "positive_bucket_counts"
+	NegativeOffsetColumn       = "negative_offset"
+	NegativeBucketCountsColumn = "negative_bucket_counts"
+)
+
+// numCommonColumns is the number of columns preceding the value columns.
+const numCommonColumns = 8
+
+var recordTypes = []string{
+	SumRecord,
+	GaugeRecord,
+	HistogramRecord,
+	MinMaxSumCountRecord,
+}
+
+// Encoder builds Arrow records from collected metric data.
+type Encoder struct {
+	mem memory.Allocator
+}
+
+// pointRef refers to one point and its aggregation, with exemplar
+// wrappers removed.
+type pointRef struct {
+	scope *data.Scope
+	inst  *data.Instrument
+	point *data.Point
+	agg   aggregation.Aggregation
+}
+
+// NewEncoder returns an Encoder using the given allocator.  If mem is
+// nil, memory.DefaultAllocator is used.
+func NewEncoder(mem memory.Allocator) *Encoder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &Encoder{
+		mem: mem,
+	}
+}
+
+// Encode returns one record for each aggregation type present in
+// the input, in the order sum, gauge, histogram, minmaxsumcount.  The
+// caller is responsible for calling Release() on each record.
+// Points with an aggregation that has no Arrow encoding are skipped
+// and reported in the returned error, which does not prevent the
+// other points from being encoded.
+func (e *Encoder) Encode(in data.Metrics) ([]arrow.Record, error) {
+	var err error
+	groups := map[string][]pointRef{}
+
+	for si := range in.Scopes {
+		scope := &in.Scopes[si]
+		for ii := range scope.Instruments {
+			inst := &scope.Instruments[ii]
+			for pi := range inst.Points {
+				point := &inst.Points[pi]
+				agg := point.Aggregation
+				if unwr, ok := agg.(exemplar.Unwrapper); ok {
+					agg = unwr.Unwrap()
+				}
+				rt := recordType(agg)
+				if rt == "" {
+					err = multierr.Append(err, fmt.Errorf("%s: unsupported aggregation: %T", inst.Descriptor.Name, agg))
+					continue
+				}
+				groups[rt] = append(groups[rt], pointRef{
+					scope: scope,
+					inst:  inst,
+					point: point,
+					agg:   agg,
+				})
+			}
+		}
+	}
+
+	var records []arrow.Record
+	for _, rt := range recordTypes {
+		refs := groups[rt]
+		if len(refs) == 0 {
+			continue
+		}
+		records = append(records, e.encode(rt, refs))
+	}
+	return records, err
+}
+
+// recordType returns the record type for an aggregation, or the
+// empty string if the aggregation is not supported.
+func recordType(agg aggregation.Aggregation) string {
+	if agg == nil {
+		return ""
+	}
+	switch agg.Kind() {
+	case aggregation.MonotonicSumKind, aggregation.NonMonotonicSumKind, aggregation.CountKind:
+		if _, ok := agg.(aggregation.Sum); ok {
+			return SumRecord
+		}
+	case aggregation.GaugeKind:
+		if _, ok := agg.(aggregation.Gauge); ok {
+			return GaugeRecord
+		}
+	case aggregation.HistogramKind:
+		if _, ok := agg.(aggregation.Histogram); ok {
+			return HistogramRecord
+		}
+	case aggregation.MinMaxSumCountKind:
+		if _, ok := agg.(aggregation.MinMaxSumCount); ok {
+			return MinMaxSumCountRecord
+		}
+	}
+	return ""
+}
+
+// Schema returns the schema for a record type with the given
+// attributes struct type.
+func Schema(rt string, attrs *arrow.StructType) *arrow.Schema {
+	fields := []arrow.Field{
+		{Name: ScopeNameColumn, Type: arrow.BinaryTypes.String},
+		{Name: ScopeVersionColumn, Type: arrow.BinaryTypes.String},
+		{Name: NameColumn, Type: arrow.BinaryTypes.String},
+		{Name: UnitColumn, Type: arrow.BinaryTypes.String},
+		{Name: StartTimeColumn, Type: arrow.FixedWidthTypes.Timestamp_ns},
+		{Name: TimeColumn, Type: arrow.FixedWidthTypes.Timestamp_ns},
+		{Name: TemporalityColumn, Type: arrow.PrimitiveTypes.Uint8},
+		{Name: AttributesColumn, Type: attrs},
+	}
+	switch rt {
+	case SumRecord:
+		fields = append(fields,
+			arrow.Field{Name: MonotonicColumn, Type: arrow.FixedWidthTypes.Boolean},
+			arrow.Field{Name: IntValueColumn, Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+			arrow.Field{Name: DoubleValueColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		)
+	case GaugeRecord:
+		fields = append(fields,
+			arrow.Field{Name: IntValueColumn, Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+			arrow.Field{Name: DoubleValueColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		)
+	case HistogramRecord:
+		fields = append(fields,
+			arrow.Field{Name: CountColumn, Type: arrow.PrimitiveTypes.Uint64},
+			arrow.Field{Name: SumColumn, Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: MinColumn, Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: MaxColumn, Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: ScaleColumn, Type: arrow.PrimitiveTypes.Int32},
+			arrow.Field{Name: ZeroCountColumn, Type: arrow.PrimitiveTypes.Uint64},
+			arrow.Field{Name: PositiveOffsetColumn, Type: arrow.PrimitiveTypes.Int32},
+			arrow.Field{Name: PositiveBucketCountsColumn, Type: arrow.ListOf(arrow.PrimitiveTypes.Uint64)},
+			arrow.Field{Name: NegativeOffsetColumn, Type: arrow.PrimitiveTypes.Int32},
+			arrow.Field{Name: NegativeBucketCountsColumn, Type: arrow.ListOf(arrow.PrimitiveTypes.Uint64)},
+		)
+	case MinMaxSumCountRecord:
+		fields = append(fields,
+			arrow.Field{Name: CountColumn, Type: arrow.PrimitiveTypes.Uint64},
+			arrow.Field{Name: SumColumn, Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: MinColumn, Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: MaxColumn, Type: arrow.PrimitiveTypes.Float64},
+		)
+	}
+	md := arrow.NewMetadata([]string{AggregationKey}, []string{rt})
+	return arrow.NewSchema(fields, &md)
+}
+
+// attributesType computes the attributes struct type for a group of
+// points.
+func attributesType(refs []pointRef) *arrow.StructType {
+	types := map[attribute.Key]arrow.DataType{}
+	for _, ref := range refs {
+		for iter := ref.point.Attributes.Iter(); iter.Next(); {
+			kv := iter.Attribute()
+			dt := attributeType(kv.Value.Type())
+			if prev, has := types[kv.Key]; has && !arrow.TypeEqual(prev, dt) {
+				dt = arrow.BinaryTypes.String
+			}
+			types[kv.Key] = dt
+		}
+	}
+	keys := make([]string, 0, len(types))
+	for k := range types {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+
+	fields := make([]arrow.Field, len(keys))
+	for i, k := range keys {
+		fields[i] = arrow.Field{
+			Name:     k,
+			Type:     types[attribute.Key(k)],
+			Nullable: true,
+		}
+	}
+	return arrow.StructOf(fields...)
+}
+
+// attributeType returns the Arrow type used for an attribute value type.
+func attributeType(t attribute.Type) arrow.DataType {
+	switch t {
+	case attribute.BOOL:
+		return arrow.FixedWidthTypes.Boolean
+	case attribute.INT64:
+		return arrow.PrimitiveTypes.Int64
+	case attribute.FLOAT64:
+		return arrow.PrimitiveTypes.Float64
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// encode builds one record for a group of points of the same type.
+func (e *Encoder) encode(rt string, refs []pointRef) arrow.Record {
+	attrs := attributesType(refs)
+	schema := Schema(rt, attrs)
+
+	rb := array.NewRecordBuilder(e.mem, schema)
+	defer rb.Release()
+
+	fieldIndex := map[attribute.Key]int{}
+	for i, f := range attrs.Fields() {
+		fieldIndex[attribute.Key(f.Name)] = i
+	}
+
+	for _, ref := range refs {
+		desc := ref.inst.Descriptor
+
+		rb.Field(0).(*array.StringBuilder).Append(ref.scope.Library.Name)
+		rb.Field(1).(*array.StringBuilder).Append(ref.scope.Library.Version)
+		rb.Field(2).(*array.StringBuilder).Append(desc.Name)
+		rb.Field(3).(*array.StringBuilder).Append(string(desc.Unit))
+		rb.Field(4).(*array.TimestampBuilder).Append(arrow.Timestamp(ref.point.Start.UnixNano()))
+		rb.Field(5).(*array.TimestampBuilder).Append(arrow.Timestamp(ref.point.End.UnixNano()))
+		rb.Field(6).(*array.Uint8Builder).Append(uint8(ref.point.Temporality))
+
+		appendAttributes(rb.Field(7).(*array.StructBuilder), fieldIndex, ref.point.Attributes)
+
+		values := rb.Fields()[numCommonColumns:]
+		nk := desc.NumberKind
+
+		switch rt {
+		case SumRecord:
+			agg := ref.agg.(aggregation.Sum)
+			values[0].(*array.BooleanBuilder).Append(agg.IsMonotonic())
+			appendNumber(values[1].(*array.Int64Builder), values[2].(*array.Float64Builder), nk, agg.Sum())
+		case GaugeRecord:
+			agg := ref.agg.(aggregation.Gauge)
+			appendNumber(values[0].(*array.Int64Builder), values[1].(*array.Float64Builder), nk, agg.Gauge())
+		case HistogramRecord:
+			agg := ref.agg.(aggregation.Histogram)
+			values[0].(*array.Uint64Builder).Append(agg.Count())
+			values[1].(*array.Float64Builder).Append(agg.Sum().CoerceToFloat64(nk))
+			values[2].(*array.Float64Builder).Append(agg.Min().CoerceToFloat64(nk))
+			values[3].(*array.Float64Builder).Append(agg.Max().CoerceToFloat64(nk))
+			values[4].(*array.Int32Builder).Append(agg.Scale())
+			values[5].(*array.Uint64Builder).Append(agg.ZeroCount())
+			appendBuckets(values[6].(*array.Int32Builder), values[7].(*array.ListBuilder), agg.Positive())
+			appendBuckets(values[8].(*array.Int32Builder), values[9].(*array.ListBuilder), agg.Negative())
+		case MinMaxSumCountRecord:
+			agg := ref.agg.(aggregation.MinMaxSumCount)
+			values[0].(*array.Uint64Builder).Append(agg.Count())
+			values[1].(*array.Float64Builder).Append(agg.Sum().CoerceToFloat64(nk))
+			values[2].(*array.Float64Builder).Append(agg.Min().CoerceToFloat64(nk))
+			values[3].(*array.Float64Builder).Append(agg.Max().CoerceToFloat64(nk))
+		}
+	}
+	return rb.NewRecord()
+}
+
+// appendAttributes appends one row to the attributes struct column.
+func appendAttributes(sb *array.StructBuilder, fieldIndex map[attribute.Key]int, set attribute.Set) {
+	sb.Append(true)
+
+	present := make([]bool, sb.NumField())
+	for iter := set.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		idx := fieldIndex[kv.Key]
+		present[idx] = true
+
+		switch fb := sb.FieldBuilder(idx).(type) {
+		case *array.BooleanBuilder:
+			fb.Append(kv.Value.AsBool())
+		case *array.Int64Builder:
+			fb.Append(kv.Value.AsInt64())
+		case *array.Float64Builder:
+			fb.Append(kv.Value.AsFloat64())
+		case *array.StringBuilder:
+			if kv.Value.Type() == attribute.STRING {
+				fb.Append(kv.Value.AsString())
+			} else {
+				fb.Append(kv.Value.Emit())
+			}
+		}
+	}
+	for idx, ok := range present {
+		if !ok {
+			sb.FieldBuilder(idx).AppendNull()
+		}
+	}
+}
+
+// appendNumber appends to the int or double value column according
+// to the number kind, with a null in the other.
+func appendNumber(ib *array.Int64Builder, fb *array.Float64Builder, nk number.Kind, num number.Number) {
+	if nk == number.Int64Kind {
+		ib.Append(number.ToInt64(num))
+		fb.AppendNull()
+		return
+	}
+	ib.AppendNull()
+	fb.Append(number.ToFloat64(num))
+}
+
+// appendBuckets appends an exponential histogram bucket range.
+func appendBuckets(ob *array.Int32Builder, lb *array.ListBuilder, buckets aggregation.Buckets) {
+	ob.Append(buckets.Offset())
+	lb.Append(true)
+	vb := lb.ValueBuilder().(*array.Uint64Builder)
+	for i := uint32(0); i < buckets.Len(); i++ {
+		vb.Append(buckets.At(i))
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arrowrecord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+var (
+	endTime   = time.Unix(100, 0)
+	startTime = time.Unix(90, 0)
+
+	cumulative = aggregation.CumulativeTemporality
+	delta      = aggregation.DeltaTemporality
+)
+
+func column[T arrow.Array](t *testing.T, rec arrow.Record, name string) T {
+	idx := rec.Schema().FieldIndices(name)
+	require.Equal(t, 1, len(idx), "column %s", name)
+	col, ok := rec.Column(idx[0]).(T)
+	require.True(t, ok, "column %s has type %T", name, rec.Column(idx[0]))
+	return col
+}
+
+func schemaRecordType(t *testing.T, rec arrow.Record) string {
+	md := rec.Schema().Metadata()
+	idx := md.FindKey(AggregationKey)
+	require.GreaterOrEqual(t, idx, 0)
+	return md.Values()[idx]
+}
+
+func TestEncodeSchema(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	in := test.Metrics(
+		resource.Empty(),
+		test.Scope(
+			test.Library("lib", metric.WithInstrumentationVersion("v1")),
+			test.Instrument(
+				test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(10), cumulative, attribute.String("s", "x")),
+			),
+			test.Instrument(
+				test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Float64Kind),
+				test.Point(startTime, endTime, gauge.NewFloat64(1.5), cumulative, attribute.Int("i", 1)),
+			),
+			test.Instrument(
+				test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Float64Kind),
+				test.Point(startTime, endTime, histogram.NewFloat64(histogram.NewConfig(), 1, 2, 4), delta),
+			),
+			test.Instrument(
+				test.Descriptor("mmsc", sdkinstrument.SyncHistogram, number.Int64Kind),
+				test.Point(startTime, endTime, minmaxsumcount.NewInt64(1, 5), delta),
+			),
+		),
+	)
+
+	recs, err := NewEncoder(mem).Encode(in)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(recs))
+	defer func() {
+		for _, rec := range recs {
+			rec.Release()
+		}
+	}()
+
+	var types []string
+	for _, rec := range recs {
+		types = append(types, schemaRecordType(t, rec))
+		require.Equal(t, int64(1), rec.NumRows())
+	}
+	require.Equal(t, []string{SumRecord, GaugeRecord, HistogramRecord, MinMaxSumCountRecord}, types)
+
+	expectCommon := []string{
+		ScopeNameColumn,
+		ScopeVersionColumn,
+		NameColumn,
+		UnitColumn,
+		StartTimeColumn,
+		TimeColumn,
+		TemporalityColumn,
+		AttributesColumn,
+	}
+	names := func(rec arrow.Record) []string {
+		var r []string
+		for _, f := range rec.Schema().Fields() {
+			r = append(r, f.Name)
+		}
+		return r
+	}
+	require.Equal(t, append(expectCommon, MonotonicColumn, IntValueColumn, DoubleValueColumn), names(recs[0]))
+	require.Equal(t, append(expectCommon, IntValueColumn, DoubleValueColumn), names(recs[1]))
+	require.Equal(t, append(expectCommon,
+		CountColumn, SumColumn, MinColumn, MaxColumn, ScaleColumn, ZeroCountColumn,
+		PositiveOffsetColumn, PositiveBucketCountsColumn, NegativeOffsetColumn, NegativeBucketCountsColumn,
+	), names(recs[2]))
+	require.Equal(t, append(expectCommon, CountColumn, SumColumn, MinColumn, MaxColumn), names(recs[3]))
+
+	// Attribute structs are per-record.
+	require.Equal(t, arrow.StructOf(arrow.Field{Name: "s", Type: arrow.BinaryTypes.String, Nullable: true}),
+		recs[0].Schema().Field(7).Type)
+	require.Equal(t, arrow.StructOf(arrow.Field{Name: "i", Type: arrow.PrimitiveTypes.Int64, Nullable: true}),
+		recs[1].Schema().Field(7).Type)
+	require.Equal(t, arrow.StructOf(), recs[2].Schema().Field(7).Type)
+
+	// Scope and histogram values.
+	require.Equal(t, "lib", column[*array.String](t, recs[2], ScopeNameColumn).Value(0))
+	require.Equal(t, "v1", column[*array.String](t, recs[2], ScopeVersionColumn).Value(0))
+	require.Equal(t, uint64(3), column[*array.Uint64](t, recs[2], CountColumn).Value(0))
+	require.Equal(t, 7.0, column[*array.Float64](t, recs[2], SumColumn).Value(0))
+	require.Equal(t, 1.0, column[*array.Float64](t, recs[2], MinColumn).Value(0))
+	require.Equal(t, 4.0, column[*array.Float64](t, recs[2], MaxColumn).Value(0))
+
+	pos := column[*array.List](t, recs[2], PositiveBucketCountsColumn)
+	start, end := pos.ValueOffsets(0)
+	var total uint64
+	for i := start; i < end; i++ {
+		total += pos.ListValues().(*array.Uint64).Value(int(i))
+	}
+	require.Equal(t, uint64(3), total)
+
+	// MinMaxSumCount values.
+	require.Equal(t, uint64(2), column[*array.Uint64](t, recs[3], CountColumn).Value(0))
+	require.Equal(t, 6.0, column[*array.Float64](t, recs[3], SumColumn).Value(0))
+	require.Equal(t, 1.0, column[*array.Float64](t, recs[3], MinColumn).Value(0))
+	require.Equal(t, 5.0, column[*array.Float64](t, recs[3], MaxColumn).Value(0))
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	// The series have different attribute keys; "k" has
+	// conflicting types and is encoded as a string.
+	in := test.Metrics(
+		resource.Empty(),
+		test.Scope(
+			test.Library("lib"),
+			test.Instrument(
+				test.DescriptorDescUnit("ints", sdkinstrument.SyncCounter, number.Int64Kind, "", "By"),
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(10), cumulative,
+					attribute.String("a", "x"), attribute.Bool("b", true), attribute.Int("k", 1)),
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(20), cumulative,
+					attribute.Float64("c", 0.5), attribute.String("k", "two")),
+			),
+			test.Instrument(
+				test.Descriptor("floats", sdkinstrument.SyncUpDownCounter, number.Float64Kind),
+				test.Point(startTime, endTime, sum.NewNonMonotonicFloat64(-1.5), delta),
+			),
+		),
+	)
+
+	recs, err := NewEncoder(mem).Encode(in)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(recs))
+	rec := recs[0]
+	defer rec.Release()
+
+	require.Equal(t, SumRecord, schemaRecordType(t, rec))
+	require.Equal(t, int64(3), rec.NumRows())
+
+	names := column[*array.String](t, rec, NameColumn)
+	units := column[*array.String](t, rec, UnitColumn)
+	starts := column[*array.Timestamp](t, rec, StartTimeColumn)
+	ends := column[*array.Timestamp](t, rec, TimeColumn)
+	tempos := column[*array.Uint8](t, rec, TemporalityColumn)
+	monos := column[*array.Boolean](t, rec, MonotonicColumn)
+	ints := column[*array.Int64](t, rec, IntValueColumn)
+	floats := column[*array.Float64](t, rec, DoubleValueColumn)
+
+	require.Equal(t, []string{"ints", "ints", "floats"}, []string{names.Value(0), names.Value(1), names.Value(2)})
+	require.Equal(t, "By", units.Value(0))
+	require.Equal(t, "", units.Value(2))
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, startTime.UnixNano(), int64(starts.Value(i)))
+		require.Equal(t, endTime.UnixNano(), int64(ends.Value(i)))
+	}
+	require.Equal(t, uint8(cumulative), tempos.Value(0))
+	require.Equal(t, uint8(delta), tempos.Value(2))
+
+	require.True(t, monos.Value(0))
+	require.False(t, monos.Value(2))
+
+	require.Equal(t, int64(10), ints.Value(0))
+	require.Equal(t, int64(20), ints.Value(1))
+	require.True(t, ints.IsNull(2))
+	require.True(t, floats.IsNull(0))
+	require.True(t, floats.IsNull(1))
+	require.Equal(t, -1.5, floats.Value(2))
+
+	// Decode the attributes back into sets.
+	attrs := column[*array.Struct](t, rec, AttributesColumn)
+	st := attrs.DataType().(*arrow.StructType)
+	require.Equal(t, []arrow.Field{
+		{Name: "a", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "b", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+		{Name: "c", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "k", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, st.Fields())
+
+	decode := func(row int) attribute.Set {
+		var kvs []attribute.KeyValue
+		for i, f := range st.Fields() {
+			col := attrs.Field(i)
+			if col.IsNull(row) {
+				continue
+			}
+			key := attribute.Key(f.Name)
+			switch c := col.(type) {
+			case *array.String:
+				kvs = append(kvs, key.String(c.Value(row)))
+			case *array.Boolean:
+				kvs = append(kvs, key.Bool(c.Value(row)))
+			case *array.Int64:
+				kvs = append(kvs, key.Int64(c.Value(row)))
+			case *array.Float64:
+				kvs = append(kvs, key.Float64(c.Value(row)))
+			}
+		}
+		return attribute.NewSet(kvs...)
+	}
+
+	require.Equal(t,
+		attribute.NewSet(attribute.String("a", "x"), attribute.Bool("b", true), attribute.String("k", "1")),
+		decode(0))
+	require.Equal(t,
+		attribute.NewSet(attribute.Float64("c", 0.5), attribute.String("k", "two")),
+		decode(1))
+	require.Equal(t, attribute.NewSet(), decode(2))
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	var unsupported custom.Int64
+
+	in := test.Metrics(
+		resource.Empty(),
+		test.Scope(
+			test.Library("lib"),
+			test.Instrument(
+				test.Descriptor("custom", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(startTime, endTime, &unsupported, cumulative),
+			),
+			test.Instrument(
+				test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Int64Kind),
+				test.Point(startTime, endTime, gauge.NewInt64(3), cumulative),
+			),
+		),
+	)
+
+	recs, err := NewEncoder(nil).Encode(in)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "custom: unsupported aggregation")
+	require.Equal(t, 1, len(recs))
+	defer recs[0].Release()
+
+	require.Equal(t, GaugeRecord, schemaRecordType(t, recs[0]))
+	require.Equal(t, int64(3), column[*array.Int64](t, recs[0], IntValueColumn).Value(0))
+}
@@ -51,14 +51,32 @@ type Config struct {
 	SelfSpans   bool
 	Batcher     concurrentbatchprocessor.Config
 	Exporter    otelarrowexporter.Config
+
+	// AttributeLimits are enforced on the attributes of each
+	// exported point.
+	AttributeLimits AttributeLimits
+
+	// NonFinitePolicy is enforced on the values of each exported
+	// point.
+	NonFinitePolicy NonFinitePolicy
 }
 
+// AttributeLimits limits the count and length of exported point
+// attributes.  See WithAttributeLimits.
+type AttributeLimits = export.AttributeLimits
+
+// NonFinitePolicy replaces or drops NaN and Inf values of exported
+// points.  See WithNonFinitePolicy.
+type NonFinitePolicy = export.NonFinitePolicy
+
 type client struct {
 	internal.ResourceMap
 
 	exporter exporter.Metrics
 	batcher  processor.Metrics
 	settings exporter.Settings
+	limits   AttributeLimits
+	values   NonFinitePolicy
 
 	// self-observability
 	tracer  traceapi.Tracer
@@ -141,8 +159,34 @@ func WithTLSSetting(tlss configtls.ClientConfig) Option {
 	}
 }
 
+// WithAttributeLimits limits the number of attributes of each
+// exported point and the length of their keys and string values.
+// Attributes are kept in key order up to the count limit, and a key
+// that collides with another after truncation is dropped.  The limits
+// apply to the exported data, not to the SDK's aggregation state.
+// Changes are reported through otel.Handle.
+func WithAttributeLimits(limits AttributeLimits) Option {
+	return func(cfg *Config) {
+		cfg.AttributeLimits = limits
+	}
+}
+
+// WithNonFinitePolicy replaces NaN and Inf values of exported points
+// with the configured values, or drops the points holding them.  Like
+// the attribute limits, the policy applies to the exported data only.
+// The number of values replaced and points dropped is reported
+// through otel.Handle.
+func WithNonFinitePolicy(policy NonFinitePolicy) Option {
+	return func(cfg *Config) {
+		cfg.NonFinitePolicy = policy
+	}
+}
+
 func NewExporter(ctx context.Context, cfg Config) (metric.PushExporter, error) {
-	c := &client{}
+	c := &client{
+		limits: cfg.AttributeLimits,
+		values: cfg.NonFinitePolicy,
+	}
 
 	if !cfg.Exporter.Arrow.Disabled {
 		c.settings.ID = component.NewID(component.MustNewType("otel_sdk_metric_arrow"))
@@ -215,6 +259,8 @@ func (c *client) ExportMetrics(ctx context.Context, data data.Metrics) error {
 		&c.ResourceMap,
 		c.exporter,
 		true, // use exponential histograms
+		c.limits,
+		c.values,
 	)
 }
 
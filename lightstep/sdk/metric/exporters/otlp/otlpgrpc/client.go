@@ -0,0 +1,276 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlpgrpc is an OTLP/gRPC metrics exporter that sends each
+// collection directly to an OTLP endpoint, retrying transient errors
+// with exponential backoff.
+package otlpgrpc // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exporters/otlp/otlpgrpc"
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/internal"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/export"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/otel"
+	metricapi "go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	traceapi "go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type Option func(*Config)
+
+type Config struct {
+	// Endpoint is the gRPC target address.
+	Endpoint string
+
+	// Insecure disables transport security.
+	Insecure bool
+
+	// TLS is the client TLS configuration, used unless Insecure
+	// is set.  When nil, the system defaults are used.
+	TLS *tls.Config
+
+	// Headers are sent with every export request.
+	Headers map[string]string
+
+	// Timeout limits each export attempt.  Zero means that
+	// attempts are limited only by the export context.
+	Timeout time.Duration
+
+	// Retry configures retries of transient errors.
+	Retry RetryConfig
+
+	// AttributeLimits are enforced on the attributes of each
+	// exported point.
+	AttributeLimits AttributeLimits
+
+	// NonFinitePolicy is enforced on the values of each exported
+	// point.
+	NonFinitePolicy NonFinitePolicy
+
+	SelfMetrics bool
+	SelfSpans   bool
+}
+
+// AttributeLimits limits the count and length of exported point
+// attributes.  See WithAttributeLimits.
+type AttributeLimits = export.AttributeLimits
+
+// NonFinitePolicy replaces or drops NaN and Inf values of exported
+// points.  See WithNonFinitePolicy.
+type NonFinitePolicy = export.NonFinitePolicy
+
+type client struct {
+	internal.ResourceMap
+
+	cfg    Config
+	conn   *grpc.ClientConn
+	grpc   pmetricotlp.GRPCClient
+	header metadata.MD
+
+	// self-observability
+	tracer  traceapi.Tracer
+	counter metricapi.Int64Counter
+}
+
+var _ metric.PushExporter = &client{}
+
+func NewDefaultConfig() Config {
+	return Config{
+		Headers:     map[string]string{},
+		Timeout:     10 * time.Second,
+		Retry:       NewDefaultRetryConfig(),
+		SelfMetrics: true,
+		SelfSpans:   true,
+	}
+}
+
+func NewConfig(opts ...Option) Config {
+	cfg := NewDefaultConfig()
+	for _, option := range opts {
+		if option != nil {
+			option(&cfg)
+		}
+	}
+	return cfg
+}
+
+func WithEndpoint(addr string) Option {
+	return func(cfg *Config) {
+		cfg.Endpoint = addr
+	}
+}
+
+func WithHeaders(hdrs map[string]string) Option {
+	return func(cfg *Config) {
+		for key, val := range hdrs {
+			cfg.Headers[key] = val
+		}
+	}
+}
+
+func WithInsecure() Option {
+	return func(cfg *Config) {
+		cfg.Insecure = true
+	}
+}
+
+func WithTLSConfig(tlsCfg *tls.Config) Option {
+	return func(cfg *Config) {
+		cfg.TLS = tlsCfg
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Timeout = d
+	}
+}
+
+func WithRetry(rc RetryConfig) Option {
+	return func(cfg *Config) {
+		cfg.Retry = rc
+	}
+}
+
+// WithAttributeLimits trims the attributes of exported points to
+// conform with the limits of the receiver, which would otherwise
+// reject the whole request.
+func WithAttributeLimits(limits AttributeLimits) Option {
+	return func(cfg *Config) {
+		cfg.AttributeLimits = limits
+	}
+}
+
+// WithNonFinitePolicy replaces or drops the NaN and Inf values of
+// exported points, which the receiver would otherwise reject,
+// possibly with the whole request.
+func WithNonFinitePolicy(policy NonFinitePolicy) Option {
+	return func(cfg *Config) {
+		cfg.NonFinitePolicy = policy
+	}
+}
+
+// NewExporter returns an exporter for the configuration.  The
+// connection is established lazily, so this does not fail when the
+// endpoint is unavailable.
+func NewExporter(_ context.Context, cfg Config) (metric.PushExporter, error) {
+	c := &client{
+		cfg:    cfg,
+		header: metadata.New(cfg.Headers),
+	}
+
+	var mp metricapi.MeterProvider = metricnoop.NewMeterProvider()
+	var tp traceapi.TracerProvider = tracenoop.NewTracerProvider()
+
+	if cfg.SelfSpans {
+		tp = otel.GetTracerProvider()
+	}
+	if cfg.SelfMetrics {
+		mp = otel.GetMeterProvider()
+	}
+
+	if _, tracer, counter, err :=
+		internal.ConfigureSelfTelemetry("lightstep-go/sdk/metric", tp, mp); err != nil {
+		return nil, err
+	} else {
+		c.tracer = tracer
+		c.counter = counter
+	}
+
+	var creds credentials.TransportCredentials
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	} else {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("otlpgrpc: %w", err)
+	}
+	c.conn = conn
+	c.grpc = pmetricotlp.NewGRPCClient(conn)
+
+	return c, nil
+}
+
+func (c *client) String() string {
+	return "otlpgrpc/metricsexporter"
+}
+
+// ExportMetrics implements PushExporter.
+func (c *client) ExportMetrics(ctx context.Context, data data.Metrics) error {
+	return export.ExportMetrics(
+		ctx,
+		data,
+		c.tracer,
+		c.counter,
+		&c.ResourceMap,
+		c,
+		true, // use exponential histograms
+		c.cfg.AttributeLimits,
+		c.cfg.NonFinitePolicy,
+	)
+}
+
+// ShutdownMetrics implements PushExporter.
+func (c *client) ShutdownMetrics(ctx context.Context, data data.Metrics) error {
+	err := c.ForceFlushMetrics(ctx, data)
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ForceFlushMetrics implements PushExporter.
+func (c *client) ForceFlushMetrics(ctx context.Context, data data.Metrics) error {
+	return c.ExportMetrics(ctx, data)
+}
+
+// ConsumeMetrics sends one request, retrying transient errors.
+func (c *client) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	if len(c.header) != 0 {
+		ctx = metadata.NewOutgoingContext(ctx, c.header)
+	}
+	req := pmetricotlp.NewExportRequestFromMetrics(md)
+
+	return c.cfg.Retry.do(ctx, func(ctx context.Context) error {
+		if c.cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+			defer cancel()
+		}
+		resp, err := c.grpc.Export(ctx, req)
+		if err != nil {
+			return err
+		}
+		if ps := resp.PartialSuccess(); ps.RejectedDataPoints() != 0 {
+			otel.Handle(fmt.Errorf("otlpgrpc: %d points rejected: %s", ps.RejectedDataPoints(), ps.ErrorMessage()))
+		}
+		return nil
+	})
+}
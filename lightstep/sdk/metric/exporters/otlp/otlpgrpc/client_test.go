@@ -0,0 +1,185 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlpgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmetric "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockServer fails with the configured errors, in order, then
+// succeeds.
+type mockServer struct {
+	pmetricotlp.UnimplementedGRPCServer
+
+	lock     sync.Mutex
+	errs     []error
+	attempts int
+	received []pmetricotlp.ExportRequest
+	headers  []string
+}
+
+func (s *mockServer) Export(ctx context.Context, req pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attempts++
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.headers = md.Get("test-header")
+	}
+	if len(s.errs) != 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return pmetricotlp.NewExportResponse(), err
+	}
+	s.received = append(s.received, req)
+	return pmetricotlp.NewExportResponse(), nil
+}
+
+func startServer(t *testing.T, srv *mockServer) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	gs := grpc.NewServer()
+	pmetricotlp.RegisterGRPCServer(gs, srv)
+	go func() {
+		_ = gs.Serve(ln)
+	}()
+	t.Cleanup(gs.Stop)
+	return ln.Addr().String()
+}
+
+func testRetry() RetryConfig {
+	return RetryConfig{
+		Enabled:         true,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		Multiplier:      2,
+	}
+}
+
+func testData(t *testing.T) data.Metrics {
+	rdr := sdkmetric.NewManualReader("test")
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(rdr))
+	cntr, err := provider.Meter("test").Int64Counter("counter")
+	require.NoError(t, err)
+	cntr.Add(context.Background(), 1)
+	return rdr.Produce(nil)
+}
+
+func testExporter(t *testing.T, addr string, rc RetryConfig) sdkmetric.PushExporter {
+	exp, err := NewExporter(context.Background(), NewConfig(
+		WithEndpoint(addr),
+		WithInsecure(),
+		WithHeaders(map[string]string{"test-header": "value"}),
+		WithRetry(rc),
+	))
+	require.NoError(t, err)
+	return exp
+}
+
+func TestRetryTransient(t *testing.T) {
+	srv := &mockServer{
+		errs: []error{
+			status.Error(codes.Unavailable, "unavailable"),
+			status.Error(codes.ResourceExhausted, "busy"),
+		},
+	}
+	exp := testExporter(t, startServer(t, srv), testRetry())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, exp.ForceFlushMetrics(ctx, testData(t)))
+	require.NoError(t, exp.ShutdownMetrics(ctx, data.Metrics{}))
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	require.Equal(t, 4, srv.attempts)
+	require.Equal(t, []string{"value"}, srv.headers)
+	require.Len(t, srv.received, 2)
+
+	rm := srv.received[0].Metrics().ResourceMetrics()
+	require.Equal(t, 1, rm.Len())
+	require.Equal(t, "counter", rm.At(0).ScopeMetrics().At(0).Metrics().At(0).Name())
+}
+
+func TestRetryPermanent(t *testing.T) {
+	srv := &mockServer{
+		errs: []error{
+			status.Error(codes.InvalidArgument, "invalid"),
+		},
+	}
+	exp := testExporter(t, startServer(t, srv), testRetry())
+
+	err := exp.ExportMetrics(context.Background(), testData(t))
+	require.ErrorIs(t, err, ErrPermanent)
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	require.Equal(t, 1, srv.attempts)
+	require.Len(t, srv.received, 0)
+}
+
+func TestRetryBudget(t *testing.T) {
+	srv := &mockServer{}
+	for i := 0; i < 100; i++ {
+		srv.errs = append(srv.errs, status.Error(codes.Unavailable, "unavailable"))
+	}
+	rc := testRetry()
+	rc.InitialInterval = 20 * time.Millisecond
+	rc.MaxInterval = 20 * time.Millisecond
+	exp := testExporter(t, startServer(t, srv), rc)
+
+	// The budget allows a few attempts, then the export fails
+	// before the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := exp.ExportMetrics(ctx, testData(t))
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrPermanent))
+	require.NoError(t, ctx.Err())
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	require.Less(t, 1, srv.attempts)
+	require.Greater(t, 100, srv.attempts)
+}
+
+func TestRetryDisabled(t *testing.T) {
+	srv := &mockServer{
+		errs: []error{
+			status.Error(codes.Unavailable, "unavailable"),
+		},
+	}
+	rc := testRetry()
+	rc.Enabled = false
+	exp := testExporter(t, startServer(t, srv), rc)
+
+	err := exp.ExportMetrics(context.Background(), testData(t))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
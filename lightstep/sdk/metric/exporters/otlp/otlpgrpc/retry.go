@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlpgrpc // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exporters/otlp/otlpgrpc"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrPermanent is wrapped by export errors that will not succeed on
+// retry.  The data is dropped.
+var ErrPermanent = errors.New("permanent export failure")
+
+// RetryConfig configures retries of transient errors with
+// exponential backoff.  Retries stop when the export context would
+// expire before the next attempt, so that one export does not
+// outlast the collection interval of the reader, which sets the
+// context deadline.
+type RetryConfig struct {
+	// Enabled turns on retries.
+	Enabled bool
+
+	// InitialInterval is the wait before the first retry.
+	InitialInterval time.Duration
+
+	// MaxInterval limits the wait between retries.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor by which the wait increases after
+	// each retry.  Values less than 1 are treated as 1.
+	Multiplier float64
+
+	// MaxElapsedTime limits the total time spent on one export,
+	// for contexts without a deadline.  Zero means no limit.
+	MaxElapsedTime time.Duration
+}
+
+func NewDefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		Enabled:         true,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+		MaxElapsedTime:  time.Minute,
+	}
+}
+
+// isTransient returns true for gRPC status codes that may succeed on
+// retry.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable,
+		codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// do calls the function until it succeeds, returns a permanent
+// error, or the context does not allow another attempt.
+func (rc RetryConfig) do(ctx context.Context, attempt func(context.Context) error) error {
+	if rc.MaxElapsedTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.MaxElapsedTime)
+		defer cancel()
+	}
+	wait := max(rc.InitialInterval, time.Millisecond)
+	for {
+		err := attempt(ctx)
+		if err == nil {
+			return nil
+		}
+		if !isTransient(err) {
+			return fmt.Errorf("%w: %w", ErrPermanent, err)
+		}
+		if !rc.Enabled {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("retry budget exhausted: %w", err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		if rc.Multiplier > 1 {
+			wait = time.Duration(float64(wait) * rc.Multiplier)
+		}
+		if rc.MaxInterval > 0 && wait > rc.MaxInterval {
+			wait = rc.MaxInterval
+		}
+	}
+}
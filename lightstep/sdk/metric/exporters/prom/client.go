@@ -150,6 +150,8 @@ func (c *client) ExportMetrics(ctx context.Context, data data.Metrics) error {
 		c.exporter,
 		// don't use exponential histograms, since the prometheus exporter doesn't support them
 		false,
+		export.AttributeLimits{},
+		export.NonFinitePolicy{},
 	)
 }
 
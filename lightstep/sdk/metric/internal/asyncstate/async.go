@@ -15,10 +15,13 @@
 package asyncstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/asyncstate"
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
@@ -41,7 +44,25 @@ type (
 
 		// store is a map from instrument to set of values
 		// observed during one collection.
-		store map[*Observer]map[attribute.Set]viewstate.Accumulator
+		store map[*Observer]map[attribute.Set]*series
+
+		// peek is set for a collection that does not modify
+		// the state kept for the next, which leaves pushed
+		// values in place.
+		peek bool
+	}
+
+	// series is the accumulator of one observed series and the
+	// time of its latest observation, which is known for gauges.
+	series struct {
+		acc  viewstate.Accumulator
+		time time.Time
+	}
+
+	// pushed is a gauge value set between callbacks.
+	pushed struct {
+		value number.Number
+		time  time.Time
 	}
 
 	// Observer is the implementation object associated with one
@@ -69,6 +90,13 @@ type (
 		// performance has performance settings for the
 		// instrument.
 		performance sdkinstrument.Performance
+
+		// pushLock protects pushed.
+		pushLock sync.Mutex
+
+		// pushed holds the values set by Push since the last
+		// collection, per pipeline.
+		pushed []map[attribute.Set]pushed
 	}
 
 	implementation interface {
@@ -79,10 +107,18 @@ type (
 func NewState(pipe int) *State {
 	return &State{
 		pipe:  pipe,
-		store: map[*Observer]map[attribute.Set]viewstate.Accumulator{},
+		store: map[*Observer]map[attribute.Set]*series{},
 	}
 }
 
+// NewPeekState returns a State for a collection that does not modify
+// the state kept for the next collection.
+func NewPeekState(pipe int) *State {
+	s := NewState(pipe)
+	s.peek = true
+	return s
+}
+
 // New returns a new Observer; this compiles individual
 // instruments for each reader.
 func New(desc sdkinstrument.Descriptor, perf sdkinstrument.Performance, opaque interface{}, compiled pipeline.Register[viewstate.Instrument]) *Observer {
@@ -99,6 +135,23 @@ func New(desc sdkinstrument.Descriptor, perf sdkinstrument.Performance, opaque i
 		descriptor:  desc,
 		compiled:    compiled,
 		performance: perf,
+		pushed:      make([]map[attribute.Set]pushed, len(compiled)),
+	}
+}
+
+// SetEnabled enables or disables the instrument for every pipeline
+// at runtime.  The observations of a disabled instrument are
+// discarded.  When clear is set, disabling also discards the series
+// the views have accumulated.
+func (obs *Observer) SetEnabled(enabled, clear bool) {
+	for _, comp := range obs.compiled {
+		if comp == nil {
+			continue
+		}
+		comp.SetEnabled(enabled)
+		if clear && !enabled {
+			comp.Reset()
+		}
 	}
 }
 
@@ -108,43 +161,133 @@ func (obs *Observer) get() *Observer {
 }
 
 // SnapshotAndProcess calls SnapshotAndProcess() on each of the pending
-// aggregations for a given reader.
-func (obs *Observer) SnapshotAndProcess(state *State) {
+// aggregations for a given reader, after applying the values pushed
+// since the last collection that are more recent than the callbacks'
+// observations.  The first error is returned, after the remaining
+// aggregations are processed.
+func (obs *Observer) SnapshotAndProcess(state *State) error {
+	pushes := obs.takePushed(state)
+
 	state.lock.Lock()
 	defer state.lock.Unlock()
 
-	for _, acc := range state.store[obs] {
+	for aset, p := range pushes {
+		se := obs.getOrCreateLocked(state, aset)
+		if !se.time.IsZero() && !p.time.After(se.time) {
+			continue
+		}
+		se.time = p.time
+		obs.update(se.acc, p.value, p.time)
+	}
+
+	var first error
+	for _, se := range state.store[obs] {
 		// SnapshotAndProcess is always final for asynchronous state, since
 		// the map is built anew for each collection.
-		acc.SnapshotAndProcess(true)
+		if err := se.acc.SnapshotAndProcess(true); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", obs.descriptor.Name, err)
+		}
 	}
+	return first
 }
 
-func (obs *Observer) getOrCreate(cs *callbackState, options []metric.ObserveOption) viewstate.Accumulator {
-	comp := obs.compiled[cs.state.pipe]
+// takePushed returns the values pushed for the state's pipeline,
+// which are removed unless the state is for a peek.
+func (obs *Observer) takePushed(state *State) map[attribute.Set]pushed {
+	obs.pushLock.Lock()
+	defer obs.pushLock.Unlock()
 
-	if comp == nil {
-		// The view disabled the instrument.
-		return nil
+	pushes := obs.pushed[state.pipe]
+	if state.peek {
+		return pushes
 	}
+	obs.pushed[state.pipe] = nil
+	return pushes
+}
 
-	cs.state.lock.Lock()
-	defer cs.state.lock.Unlock()
+// Push sets the value of a gauge between callbacks, for each pipeline
+// that enables the instrument.  The value is reported by the next
+// collection of each pipeline, unless the callbacks observe the same
+// series at a later time; of several pushes to a series, the latest
+// is kept.  The event time is taken from the context, see
+// exemplar.ContextWithTimestamp, or else is the time of the call.
+func Push[N number.Any, Traits number.Traits[N]](ctx context.Context, obs *Observer, value N, options []metric.RecordOption) {
+	if obs == nil {
+		return
+	}
+	if !aggregator.RangeTest[N, Traits](value, obs.descriptor) {
+		return
+	}
+	when := exemplar.TimestampFromContext(ctx)
+	if when.IsZero() {
+		when = time.Now()
+	}
+	rcfg := metric.NewRecordConfig(options)
+	aset := rcfg.Attributes()
+	aset = attribute.NewSet(obs.performance.TruncateAttributes(aset.ToSlice())...)
 
-	imap, has := cs.state.store[obs]
+	var traits Traits
+	p := pushed{value: traits.ToNumber(value), time: when}
 
-	if !has {
-		imap = map[attribute.Set]viewstate.Accumulator{}
-		cs.state.store[obs] = imap
+	obs.pushLock.Lock()
+	defer obs.pushLock.Unlock()
+
+	for pipe, comp := range obs.compiled {
+		if comp == nil {
+			continue
+		}
+		if obs.pushed[pipe] == nil {
+			obs.pushed[pipe] = map[attribute.Set]pushed{}
+		}
+		if prev, ok := obs.pushed[pipe][aset]; ok && prev.time.After(when) {
+			continue
+		}
+		obs.pushed[pipe][aset] = p
 	}
+}
 
+// update applies a value to an accumulator, with the time of the
+// observation, which is set for gauges.
+func (obs *Observer) update(acc viewstate.Accumulator, value number.Number, when time.Time) {
+	ex := aggregator.ExemplarBits{Time: when}
+	if obs.descriptor.NumberKind == number.Int64Kind {
+		acc.(viewstate.Updater[int64]).Update(number.ToInt64(value), ex)
+	} else {
+		acc.(viewstate.Updater[float64]).Update(number.ToFloat64(value), ex)
+	}
+}
+
+// attributesFor returns the attribute set of an observation, or false
+// if the view disabled the instrument for this pipeline.
+func (obs *Observer) attributesFor(state *State, options []metric.ObserveOption) (attribute.Set, bool) {
+	if obs.compiled[state.pipe] == nil {
+		// The view disabled the instrument.
+		return attribute.Set{}, false
+	}
 	ocfg := metric.NewObserveConfig(options)
 	aset := ocfg.Attributes()
-	aset = attribute.NewSet(obs.performance.TruncateAttributes(aset.ToSlice())...)
+	return attribute.NewSet(obs.performance.TruncateAttributes(aset.ToSlice())...), true
+}
+
+func (obs *Observer) getOrCreate(state *State, aset attribute.Set) *series {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	return obs.getOrCreateLocked(state, aset)
+}
+
+// getOrCreateLocked is getOrCreate with the state lock held.
+func (obs *Observer) getOrCreateLocked(state *State, aset attribute.Set) *series {
+	imap, has := state.store[obs]
+
+	if !has {
+		imap = map[attribute.Set]*series{}
+		state.store[obs] = imap
+	}
 
 	se, has := imap[aset]
 	if !has {
-		se = comp.NewAccumulator(aset)
+		se = &series{acc: obs.compiled[state.pipe].NewAccumulator(aset)}
 		imap[aset] = se
 	}
 	return se
@@ -176,7 +319,8 @@ func Observe[N number.Any, Traits number.Traits[N]](inst metric.Observable, cs *
 		return
 	}
 
-	if acc := obs.getOrCreate(cs, options); acc != nil {
-		acc.(viewstate.Updater[N]).Update(value, aggregator.ExemplarBits{})
+	if aset, ok := obs.attributesFor(cs.state, options); ok {
+		var traits Traits
+		cs.stage(obs, aset, traits.ToNumber(value))
 	}
 }
@@ -455,3 +455,94 @@ func TestAttributeSizeLimit(t *testing.T) {
 		),
 	)
 }
+
+func TestCallbackPanic(t *testing.T) {
+	errs := test.OTelErrors()
+
+	tsdk := testAsync("test")
+
+	good := testIntObserver(tsdk, "good", sdkinstrument.AsyncCounter)
+	bad := testIntObserver(tsdk, "bad", sdkinstrument.AsyncCounter)
+
+	var value int64
+	var panics bool
+	goodCB, err := NewCallback([]metric.Observable{good}, tsdk, func(ctx context.Context, obs metric.Observer) error {
+		obs.ObserveInt64(good, value)
+		return nil
+	})
+	require.NoError(t, err)
+	badCB, err := NewCallback([]metric.Observable{bad}, tsdk, func(ctx context.Context, obs metric.Observer) error {
+		obs.ObserveInt64(bad, value)
+		if panics {
+			panic("buggy callback")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	collect := func() []data.Instrument {
+		state := testState(0)
+
+		// The panicking callback is run first.
+		require.NotPanics(t, func() {
+			badCB.Run(context.Background(), state)
+		})
+		goodCB.Run(context.Background(), state)
+
+		// The state is not left locked.
+		require.True(t, state.lock.TryLock())
+		state.lock.Unlock()
+
+		good.SnapshotAndProcess(state)
+		bad.SnapshotAndProcess(state)
+
+		return test.CollectScope(t, tsdk.compilers[0].Collectors(), testSequence)
+	}
+
+	value = 10
+	test.RequireEqualMetrics(t,
+		collect(),
+		test.Instrument(
+			good.descriptor,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(10), aggregation.CumulativeTemporality),
+		),
+		test.Instrument(
+			bad.descriptor,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(10), aggregation.CumulativeTemporality),
+		),
+	)
+	require.Equal(t, 0, len(*errs))
+
+	// The observation made before the panic is discarded.
+	value = 20
+	panics = true
+	test.RequireEqualMetrics(t,
+		collect(),
+		test.Instrument(
+			good.descriptor,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(20), aggregation.CumulativeTemporality),
+		),
+		test.Instrument(
+			bad.descriptor,
+		),
+	)
+	require.Equal(t, 1, len(*errs))
+	require.ErrorIs(t, (*errs)[0], ErrCallbackPanic)
+	require.Contains(t, (*errs)[0].Error(), "buggy callback")
+	require.Contains(t, (*errs)[0].Error(), "bad")
+
+	// The next interval is consistent.
+	value = 30
+	panics = false
+	test.RequireEqualMetrics(t,
+		collect(),
+		test.Instrument(
+			good.descriptor,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(30), aggregation.CumulativeTemporality),
+		),
+		test.Instrument(
+			bad.descriptor,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(30), aggregation.CumulativeTemporality),
+		),
+	)
+}
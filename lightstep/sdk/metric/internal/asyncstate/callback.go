@@ -16,13 +16,23 @@ package asyncstate // import "github.com/lightstep/otel-launcher-go/lightstep/sd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
+// ErrCallbackPanic is reported when an asynchronous callback panics.
+var ErrCallbackPanic = errors.New("asynchronous callback panicked")
+
 // Callback is the implementation object associated with one
 // asynchronous callback.
 type Callback struct {
@@ -71,14 +81,45 @@ func NewCallback(instruments []metric.Observable, opaque interface{}, function m
 }
 
 // Run executes the callback after setting up the appropriate context
-// for a specific reader.
+// for a specific reader.  Observations are staged until the callback
+// returns.  If the callback panics, the panic is reported and its
+// observations are discarded, so that the affected instruments keep
+// their prior state and collection continues.
 func (c *Callback) Run(ctx context.Context, state *State) {
 	cp := &callbackState{
 		callback: c,
 		state:    state,
 	}
-	c.function(ctx, cp)
-	cp.invalidate()
+	if c.run(ctx, cp) {
+		cp.commit()
+	}
+}
+
+// run calls the callback function, returning false if it panicked.
+func (c *Callback) run(ctx context.Context, cp *callbackState) (ok bool) {
+	defer func() {
+		cp.invalidate()
+
+		if r := recover(); r != nil {
+			err := fmt.Errorf("%w: %v: observing %v", ErrCallbackPanic, r, c.instrumentNames())
+			doevery.TimePeriod(time.Minute, func() {
+				otel.Handle(err)
+			})
+		}
+	}()
+	_ = c.function(ctx, cp)
+	return true
+}
+
+// instrumentNames returns the sorted names of the callback's
+// instruments, for diagnostics.
+func (c *Callback) instrumentNames() []string {
+	names := make([]string, 0, len(c.instruments))
+	for inst := range c.instruments {
+		names = append(names, inst.descriptor.Name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // callbackState is used to lookup the current callback and
@@ -86,7 +127,8 @@ func (c *Callback) Run(ctx context.Context, state *State) {
 type callbackState struct {
 	metric.Observer
 
-	// lock protects callback, see invalidate() and getCallback()
+	// lock protects callback and staged, see invalidate() and
+	// getCallback()
 	lock sync.Mutex
 
 	// callback is the currently running callback; this is set to nil
@@ -95,6 +137,49 @@ type callbackState struct {
 
 	// state is a single collection of data.
 	state *State
+
+	// staged are the observations made by the callback, in order,
+	// applied to state when the callback returns normally.
+	staged []observation
+}
+
+// observation is one staged observation.  The time is set for
+// gauges, to give precedence to the later of a callback's
+// observation and a pushed value.
+type observation struct {
+	obs   *Observer
+	attrs attribute.Set
+	value number.Number
+	time  time.Time
+}
+
+// stage records an observation, unless the callback has returned.
+func (cs *callbackState) stage(obs *Observer, attrs attribute.Set, value number.Number) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.callback == nil {
+		return
+	}
+	o := observation{
+		obs:   obs,
+		attrs: attrs,
+		value: value,
+	}
+	if obs.descriptor.Kind == sdkinstrument.AsyncGauge {
+		o.time = time.Now()
+	}
+	cs.staged = append(cs.staged, o)
+}
+
+// commit applies the staged observations.  The last observation of
+// each series takes effect, as if it had been applied directly.
+func (cs *callbackState) commit() {
+	for _, o := range cs.staged {
+		se := o.obs.getOrCreate(cs.state, o.attrs)
+		se.time = o.time
+		o.obs.update(se.acc, o.value, o.time)
+	}
+	cs.staged = nil
 }
 
 func (cs *callbackState) invalidate() {
@@ -18,20 +18,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lightstep/go-expohisto/mapping/logarithm"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/internal"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 
-	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/otel"
@@ -51,6 +54,20 @@ func toTemporality(t aggregation.Temporality) pmetric.AggregationTemporality {
 	return pmetric.AggregationTemporalityUnspecified
 }
 
+// copyPointAttributes copies the attributes of the point followed by
+// its metadata, which is exported with the attributes.  A metadata
+// key that is also an attribute is skipped.
+func copyPointAttributes(dest pcommon.Map, inP data.Point) {
+	internal.CopyAttributes(dest, inP.Attributes)
+	for iter := inP.Metadata.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		if inP.Attributes.HasValue(kv.Key) {
+			continue
+		}
+		internal.CopyAttribute(dest, kv)
+	}
+}
+
 func copySumPoints(m pmetric.Metric, inM data.Instrument, mono bool) {
 	s := m.SetEmptySum()
 	s.SetIsMonotonic(mono)
@@ -62,17 +79,21 @@ func copySumPoints(m pmetric.Metric, inM data.Instrument, mono bool) {
 		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(inP.Start))
 		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
 
-		internal.CopyAttributes(dp.Attributes(), inP.Attributes)
+		copyPointAttributes(dp.Attributes(), inP)
 
 		switch t := unwrapExemplars(inP.Aggregation).(type) {
 		case *sum.MonotonicInt64:
-			dp.SetIntValue(number.ToInt64(t.Sum()))
+			copyInt64Sum(dp, t)
 		case *sum.NonMonotonicInt64:
-			dp.SetIntValue(number.ToInt64(t.Sum()))
+			copyInt64Sum(dp, t)
 		case *sum.MonotonicFloat64:
 			dp.SetDoubleValue(number.ToFloat64(t.Sum()))
 		case *sum.NonMonotonicFloat64:
 			dp.SetDoubleValue(number.ToFloat64(t.Sum()))
+		case *count.Int64:
+			dp.SetIntValue(number.ToInt64(t.Sum()))
+		case *count.Float64:
+			dp.SetDoubleValue(number.ToFloat64(t.Sum()))
 		default:
 			panic("unhandled case")
 		}
@@ -81,6 +102,19 @@ func copySumPoints(m pmetric.Metric, inM data.Instrument, mono bool) {
 	}
 }
 
+// copyInt64Sum sets an integer value, or a double value if the sum
+// was promoted because of aggregator.SumOverflowPromote.
+func copyInt64Sum(dp pmetric.NumberDataPoint, t interface {
+	Sum() number.Number
+	Promoted() (float64, bool)
+}) {
+	if f, ok := t.Promoted(); ok {
+		dp.SetDoubleValue(f)
+		return
+	}
+	dp.SetIntValue(number.ToInt64(t.Sum()))
+}
+
 func copyGaugePoints(m pmetric.Metric, inM data.Instrument) {
 	s := m.SetEmptyGauge()
 
@@ -90,7 +124,7 @@ func copyGaugePoints(m pmetric.Metric, inM data.Instrument) {
 		// Note: no start timestamp
 		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
 
-		internal.CopyAttributes(dp.Attributes(), inP.Attributes)
+		copyPointAttributes(dp.Attributes(), inP)
 
 		switch t := unwrapExemplars(inP.Aggregation).(type) {
 		case *gauge.Int64:
@@ -114,9 +148,9 @@ func copyExplicitHistogramPoints(m pmetric.Metric, inM data.Instrument) {
 
 		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(inP.Start))
 		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
-		internal.CopyAttributes(dp.Attributes(), inP.Attributes)
+		copyPointAttributes(dp.Attributes(), inP)
 
-		switch t := inP.Aggregation.(type) {
+		switch t := unwrapExemplars(inP.Aggregation).(type) {
 		case *histogram.Int64:
 			dp.SetSum(t.Sum().CoerceToFloat64(number.Int64Kind))
 			dp.SetCount(t.Count())
@@ -140,6 +174,8 @@ func copyExplicitHistogramPoints(m pmetric.Metric, inM data.Instrument) {
 		default:
 			panic("unhandled case")
 		}
+
+		CopyExemplars(dp.Exemplars(), inP.Attributes, inM.Descriptor.NumberKind, inP.Exemplars)
 	}
 }
 
@@ -194,13 +230,14 @@ func copyExponentialHistogramPoints(m pmetric.Metric, inM data.Instrument) {
 		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(inP.Start))
 		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
 
-		internal.CopyAttributes(dp.Attributes(), inP.Attributes)
+		copyPointAttributes(dp.Attributes(), inP)
 
 		switch t := unwrapExemplars(inP.Aggregation).(type) {
 		case *histogram.Int64:
 			dp.SetSum(t.Sum().CoerceToFloat64(number.Int64Kind))
 			dp.SetCount(t.Count())
 			dp.SetZeroCount(t.ZeroCount())
+			dp.SetZeroThreshold(t.ZeroThreshold())
 			dp.SetScale(t.Scale())
 			if t.Count() != 0 {
 				dp.SetMax(t.Max().CoerceToFloat64(number.Int64Kind))
@@ -216,6 +253,7 @@ func copyExponentialHistogramPoints(m pmetric.Metric, inM data.Instrument) {
 			dp.SetSum(number.ToFloat64(t.Sum()))
 			dp.SetCount(t.Count())
 			dp.SetZeroCount(t.ZeroCount())
+			dp.SetZeroThreshold(t.ZeroThreshold())
 			dp.SetScale(t.Scale())
 			if t.Count() != 0 {
 				dp.SetMax(number.ToFloat64(t.Max()))
@@ -256,7 +294,7 @@ func copyMMSCPoints(m pmetric.Metric, inM data.Instrument) {
 		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(inP.Start))
 		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
 
-		internal.CopyAttributes(dp.Attributes(), inP.Attributes)
+		copyPointAttributes(dp.Attributes(), inP)
 
 		switch t := unwrapExemplars(inP.Aggregation).(type) {
 		case *minmaxsumcount.Int64:
@@ -281,6 +319,90 @@ func copyMMSCPoints(m pmetric.Metric, inM data.Instrument) {
 	}
 }
 
+// copyExplicitBucketPoints copies explicit-bucket histograms, whose
+// boundaries and bucket counts map directly to the OTLP histogram.
+func copyExplicitBucketPoints(m pmetric.Metric, inM data.Instrument) {
+	s := m.SetEmptyHistogram()
+	s.SetAggregationTemporality(toTemporality(inM.Points[0].Temporality))
+
+	for _, inP := range inM.Points {
+		dp := s.DataPoints().AppendEmpty()
+
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(inP.Start))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(inP.End))
+
+		copyPointAttributes(dp.Attributes(), inP)
+
+		switch t := unwrapExemplars(inP.Aggregation).(type) {
+		case *explicit.Int64:
+			copyExplicitBuckets(dp, t, number.Int64Kind)
+		case *explicit.Float64:
+			copyExplicitBuckets(dp, t, number.Float64Kind)
+		default:
+			panic("unhandled case")
+		}
+
+		CopyExemplars(dp.Exemplars(), inP.Attributes, inM.Descriptor.NumberKind, inP.Exemplars)
+	}
+}
+
+func copyExplicitBuckets(dp pmetric.HistogramDataPoint, t aggregation.ExplicitHistogram, nk number.Kind) {
+	dp.SetSum(t.Sum().CoerceToFloat64(nk))
+	dp.SetCount(t.Count())
+	if t.Count() != 0 {
+		dp.SetMax(t.Max().CoerceToFloat64(nk))
+		dp.SetMin(t.Min().CoerceToFloat64(nk))
+	}
+	dp.ExplicitBounds().FromRaw(t.Boundaries())
+	dp.BucketCounts().FromRaw(t.BucketCounts())
+}
+
+// WeightedSuffix is appended to the name of a histogram instrument
+// for the metric holding its secondary-weight histogram.
+const WeightedSuffix = ".weighted"
+
+func copyHistogramPoints(m pmetric.Metric, inM data.Instrument, useExponentialHistogram bool) {
+	if useExponentialHistogram {
+		copyExponentialHistogramPoints(m, inM)
+	} else {
+		copyExplicitHistogramPoints(m, inM)
+	}
+}
+
+// weightedHistograms returns an instrument with the secondary-weight
+// histogram of each point, if the histograms are weighted.
+func weightedHistograms(inM data.Instrument) (data.Instrument, bool) {
+	out := data.Instrument{
+		Descriptor: inM.Descriptor,
+		Points:     make([]data.Point, 0, len(inM.Points)),
+	}
+	for _, inP := range inM.Points {
+		var wagg aggregation.Aggregation
+		switch t := unwrapExemplars(inP.Aggregation).(type) {
+		case *histogram.Int64:
+			if w := t.Weighted(); w != nil {
+				wagg = w
+			}
+		case *histogram.Float64:
+			if w := t.Weighted(); w != nil {
+				wagg = w
+			}
+		}
+		if wagg == nil {
+			return data.Instrument{}, false
+		}
+		out.Points = append(out.Points, data.Point{
+			Attributes:  inP.Attributes,
+			Metadata:    inP.Metadata,
+			Aggregation: wagg,
+			Temporality: inP.Temporality,
+			Start:       inP.Start,
+			End:         inP.End,
+		})
+	}
+	return out, true
+}
+
 func unwrapExemplars(agg aggregation.Aggregation) aggregation.Aggregation {
 	if unwr, ok := agg.(exemplar.Unwrapper); ok {
 		agg = unwr.Unwrap()
@@ -317,20 +439,26 @@ func d2pd(
 			agg := unwrapExemplars(inM.Points[0].Aggregation)
 
 			switch agg.(type) {
-			case *sum.MonotonicInt64, *sum.MonotonicFloat64:
+			case *sum.MonotonicInt64, *sum.MonotonicFloat64, *count.Int64, *count.Float64:
 				copySumPoints(m, inM, true)
 			case *sum.NonMonotonicInt64, *sum.NonMonotonicFloat64:
 				copySumPoints(m, inM, false)
 			case *gauge.Int64, *gauge.Float64:
 				copyGaugePoints(m, inM)
 			case *histogram.Int64, *histogram.Float64:
-				if useExponentialHistogram {
-					copyExponentialHistogramPoints(m, inM)
-				} else {
-					copyExplicitHistogramPoints(m, inM)
+				copyHistogramPoints(m, inM, useExponentialHistogram)
+
+				if wM, ok := weightedHistograms(inM); ok {
+					wm := sm.Metrics().AppendEmpty()
+					wm.SetName(inM.Descriptor.Name + WeightedSuffix)
+					wm.SetUnit(string(inM.Descriptor.Unit))
+					wm.SetDescription(inM.Descriptor.Description)
+					copyHistogramPoints(wm, wM, useExponentialHistogram)
 				}
 			case *minmaxsumcount.Int64, *minmaxsumcount.Float64:
 				copyMMSCPoints(m, inM)
+			case *explicit.Int64, *explicit.Float64:
+				copyExplicitBucketPoints(m, inM)
 			default:
 				otel.Handle(fmt.Errorf("unknown concrete aggregator type: %T", agg))
 			}
@@ -400,7 +528,34 @@ func CopyExemplars(dest pmetric.ExemplarSlice, attrs attribute.Set, nk number.Ki
 		if wex.Weight != 0 {
 			ex.FilteredAttributes().PutDouble("sample.weight", wex.Weight)
 		}
+		copyLinks(ex.FilteredAttributes(), wex.Links, wex.DroppedLinks)
+	}
+}
+
+// copyLinks adds the links of an exemplar as filtered attributes,
+// since OTLP exemplars reference a single span.  The trace and span
+// IDs of each link are at the same position of two lists, followed by
+// the number of links dropped, if any.
+func copyLinks(dest pcommon.Map, links []trace.SpanContext, dropped uint32) {
+	if len(links) != 0 {
+		traceIDs := dest.PutEmptySlice("link.trace_id")
+		spanIDs := dest.PutEmptySlice("link.span_id")
+		traceIDs.EnsureCapacity(len(links))
+		spanIDs.EnsureCapacity(len(links))
+		for _, link := range links {
+			traceIDs.AppendEmpty().SetStr(link.TraceID().String())
+			spanIDs.AppendEmpty().SetStr(link.SpanID().String())
+		}
 	}
+	if dropped != 0 {
+		dest.PutInt("link.dropped_count", int64(dropped))
+	}
+}
+
+// Consumer is the part of a collector exporter.Metrics used by
+// ExportMetrics.
+type Consumer interface {
+	ConsumeMetrics(context.Context, pmetric.Metrics) error
 }
 
 func ExportMetrics(
@@ -409,8 +564,10 @@ func ExportMetrics(
 	tracer trace.Tracer,
 	telemetryItemsCounter metricapi.Int64Counter,
 	resourceMap *internal.ResourceMap,
-	exporter exporter.Metrics,
+	exporter Consumer,
 	useExponentialHistogram bool,
+	limits AttributeLimits,
+	values NonFinitePolicy,
 ) error {
 	ctx, span := tracer.Start(
 		ctx,
@@ -419,6 +576,27 @@ func ExportMetrics(
 	defer span.End()
 
 	converted := d2pd(resourceMap, data, useExponentialHistogram)
+
+	if stats := limits.apply(converted); stats.any() {
+		span.SetAttributes(
+			attribute.Int("attributes_dropped", stats.dropped),
+			attribute.Int("attribute_keys_truncated", stats.truncatedKeys),
+			attribute.Int("attribute_values_truncated", stats.truncatedValues),
+		)
+		doevery.TimePeriod(time.Minute, func() {
+			otel.Handle(stats.err())
+		})
+	}
+	if stats := values.apply(converted); stats.any() {
+		span.SetAttributes(
+			attribute.Int("nan_values_replaced", stats.replacedNaN),
+			attribute.Int("inf_values_replaced", stats.replacedInf),
+			attribute.Int("non_finite_points_dropped", stats.dropped),
+		)
+		doevery.TimePeriod(time.Minute, func() {
+			otel.Handle(stats.err())
+		})
+	}
 	points := int64(converted.DataPointCount())
 
 	err := exporter.ConsumeMetrics(ctx, converted)
@@ -15,13 +15,31 @@
 package export
 
 import (
+	"context"
 	"fmt"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/internal"
+	sdkmetric "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	metricapi "go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"math"
 	"testing"
 )
 
@@ -97,6 +115,174 @@ func Test_d2pd(t *testing.T) {
 	}
 }
 
+func TestWeightedHistogram(t *testing.T) {
+	var methods histogram.Float64Methods
+	var h histogram.Float64
+	methods.Init(&h, aggregator.Config{
+		Histogram:         histogram.NewConfig(),
+		HistogramWeighted: true,
+	})
+	methods.Update(&h, 1, aggregator.ExemplarBits{SecondaryWeight: 3, HasSecondaryWeight: true})
+	methods.Update(&h, 2, aggregator.ExemplarBits{SecondaryWeight: 1, HasSecondaryWeight: true})
+
+	in := pointToMetric(&h)
+	in.Scopes[0].Instruments[0].Descriptor.Name = "latency"
+
+	out := d2pd(&internal.ResourceMap{}, in, true)
+	metrics := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 2, metrics.Len())
+
+	require.Equal(t, "latency", metrics.At(0).Name())
+	require.Equal(t, uint64(2), metrics.At(0).ExponentialHistogram().DataPoints().At(0).Count())
+
+	require.Equal(t, "latency"+WeightedSuffix, metrics.At(1).Name())
+	wpt := metrics.At(1).ExponentialHistogram().DataPoints().At(0)
+	require.Equal(t, uint64(4), wpt.Count())
+	require.Equal(t, 5.0, wpt.Sum())
+}
+
+func TestHistogramZeroThreshold(t *testing.T) {
+	var methods histogram.Float64Methods
+	var h histogram.Float64
+	methods.Init(&h, aggregator.Config{
+		Histogram:              histogram.NewConfig(),
+		HistogramZeroThreshold: 0.01,
+	})
+	methods.Update(&h, -0.01, aggregator.ExemplarBits{})
+	methods.Update(&h, 0.005, aggregator.ExemplarBits{})
+	methods.Update(&h, 2, aggregator.ExemplarBits{})
+
+	out := d2pd(&internal.ResourceMap{}, pointToMetric(&h), true)
+	dp := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).ExponentialHistogram().DataPoints().At(0)
+	require.Equal(t, 0.01, dp.ZeroThreshold())
+	require.Equal(t, uint64(2), dp.ZeroCount())
+	require.Equal(t, uint64(3), dp.Count())
+	require.Equal(t, -0.01, dp.Min())
+	require.Equal(t, uint64(1), dp.Positive().BucketCounts().At(0))
+	require.Equal(t, 0, dp.Negative().BucketCounts().Len())
+}
+
+func TestPromotedSum(t *testing.T) {
+	var methods sum.MonotonicInt64Methods
+	var s sum.MonotonicInt64
+	methods.Init(&s, aggregator.Config{SumOverflow: aggregator.SumOverflowPromote})
+	methods.Update(&s, math.MaxInt64, aggregator.ExemplarBits{})
+
+	out := d2pd(&internal.ResourceMap{}, pointToMetric(&s), true)
+	dp := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	require.Equal(t, pmetric.NumberDataPointValueTypeInt, dp.ValueType())
+	require.Equal(t, int64(math.MaxInt64), dp.IntValue())
+
+	methods.Update(&s, math.MaxInt64, aggregator.ExemplarBits{})
+
+	out = d2pd(&internal.ResourceMap{}, pointToMetric(&s), true)
+	dp = out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	require.Equal(t, pmetric.NumberDataPointValueTypeDouble, dp.ValueType())
+	require.Equal(t, 2*float64(math.MaxInt64), dp.DoubleValue())
+}
+
+func TestCountSum(t *testing.T) {
+	out := d2pd(&internal.ResourceMap{}, pointToMetric(count.NewInt64(5)), true)
+	m := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.True(t, m.Sum().IsMonotonic())
+	dp := m.Sum().DataPoints().At(0)
+	require.Equal(t, pmetric.NumberDataPointValueTypeInt, dp.ValueType())
+	require.Equal(t, int64(5), dp.IntValue())
+
+	out = d2pd(&internal.ResourceMap{}, pointToMetric(count.NewFloat64(3)), true)
+	dp = out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	require.Equal(t, pmetric.NumberDataPointValueTypeDouble, dp.ValueType())
+	require.Equal(t, 3.0, dp.DoubleValue())
+}
+
+func TestExemplarLinks(t *testing.T) {
+	link := func(n byte) trace.SpanContext {
+		return trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{n},
+			SpanID:  trace.SpanID{n},
+		})
+	}
+	exs := pmetric.NewExemplarSlice()
+	CopyExemplars(exs, *attribute.EmptySet(), number.Float64Kind, []aggregator.WeightedExemplarBits{
+		{
+			ExemplarBits: aggregator.ExemplarBits{
+				Number:       number.FromFloat64(1),
+				Links:        []trace.SpanContext{link(1), link(2)},
+				DroppedLinks: 3,
+			},
+		},
+		{
+			ExemplarBits: aggregator.ExemplarBits{
+				Number: number.FromFloat64(2),
+			},
+		},
+	})
+	require.Equal(t, 2, exs.Len())
+	require.Equal(t, map[string]any{
+		"link.trace_id":      []any{link(1).TraceID().String(), link(2).TraceID().String()},
+		"link.span_id":       []any{link(1).SpanID().String(), link(2).SpanID().String()},
+		"link.dropped_count": int64(3),
+	}, exs.At(0).FilteredAttributes().AsRaw())
+	require.Equal(t, 0, exs.At(1).FilteredAttributes().Len())
+}
+
+func TestSeriesMetadata(t *testing.T) {
+	in := pointToMetric(count.NewInt64(5))
+	pt := &in.Scopes[0].Instruments[0].Points[0]
+	pt.Attributes = attribute.NewSet(attribute.String("host", "a"))
+	pt.Metadata = attribute.NewSet(
+		attribute.String("host", "b"),
+		attribute.String("label", "primary"),
+	)
+
+	// Metadata is output with the attributes, which win over
+	// metadata of the same key.
+	out := d2pd(&internal.ResourceMap{}, in, true)
+	dp := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	require.Equal(t, map[string]any{
+		"host":  "a",
+		"label": "primary",
+	}, dp.Attributes().AsRaw())
+}
+
+func TestExplicitBucketHistogram(t *testing.T) {
+	bounds := []float64{1, 5, 10}
+	values := []float64{0.5, 1, 5, 7, 10, 11, 1000}
+
+	out := d2pd(&internal.ResourceMap{}, pointToMetric(explicit.NewFloat64(bounds, values...)), true)
+	dp := getSingleHistPoint(t, out)
+
+	// The boundaries and buckets are copied unchanged, regardless
+	// of the exponential histogram setting.
+	require.Equal(t, bounds, dp.ExplicitBounds().AsRaw())
+	require.Equal(t, []uint64{2, 1, 2, 2}, dp.BucketCounts().AsRaw())
+	require.Equal(t, uint64(7), dp.Count())
+	require.Equal(t, 1034.5, dp.Sum())
+	require.Equal(t, 0.5, dp.Min())
+	require.Equal(t, 1000.0, dp.Max())
+
+	// Each value falls in the OTLP bucket that counts it.
+	buckets := populateBuckets(dp)
+	for _, v := range values {
+		found := false
+		for i := range buckets {
+			if buckets[i].contains(v) && buckets[i].value != 0 {
+				buckets[i].value--
+				found = true
+				break
+			}
+		}
+		require.True(t, found, "could not find %f in histogram", v)
+	}
+
+	ints := d2pd(&internal.ResourceMap{}, pointToMetric(explicit.NewInt64(nil, -2, 3)), false)
+	idp := getSingleHistPoint(t, ints)
+	require.Equal(t, 0, idp.ExplicitBounds().Len())
+	require.Equal(t, []uint64{2}, idp.BucketCounts().AsRaw())
+	require.Equal(t, 1.0, idp.Sum())
+	require.Equal(t, -2.0, idp.Min())
+}
+
 type bucket struct {
 	start *float64
 	end   *float64 // inclusive
@@ -207,3 +393,240 @@ func getSingleHistPoint(t *testing.T, m pmetric.Metrics) pmetric.HistogramDataPo
 
 	return dataPoints.At(0)
 }
+
+type testConsumer struct {
+	received []pmetric.Metrics
+}
+
+func (c *testConsumer) ConsumeMetrics(_ context.Context, md pmetric.Metrics) error {
+	c.received = append(c.received, md)
+	return nil
+}
+
+func TestAttributeLimits(t *testing.T) {
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	in := pointToMetric(sum.NewMonotonicInt64(1))
+	in.Scopes[0].Instruments[0].Points[0].Attributes = attribute.NewSet(
+		attribute.String("a", "short"),
+		attribute.String("b", "a long value ☃☃☃"),
+		attribute.StringSlice("c", []string{"ok", "too long"}),
+		attribute.Int("long_key_1", 1),
+		attribute.Int("long_key_2", 2),
+		attribute.Int("z", 3),
+	)
+
+	var consumer testConsumer
+	err := ExportMetrics(
+		context.Background(),
+		in,
+		tracenoop.NewTracerProvider().Tracer("test"),
+		counter(t),
+		&internal.ResourceMap{},
+		&consumer,
+		true,
+		AttributeLimits{
+			MaxCount:       4,
+			MaxKeyLength:   8,
+			MaxValueLength: 7,
+		},
+		NonFinitePolicy{},
+	)
+	require.NoError(t, err)
+	require.Len(t, consumer.received, 1)
+
+	// "long_key_2" collides with "long_key_1" after truncation,
+	// and "z" is beyond the count limit.
+	attrs := consumer.received[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0).Attributes()
+	require.Equal(t, map[string]any{
+		"a":        "short",
+		"b":        "a long ",
+		"c":        []any{"ok", "too lon"},
+		"long_key": int64(1),
+	}, attrs.AsRaw())
+
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], ErrAttributesLimited)
+	require.Contains(t, errs[0].Error(), "2 dropped, 1 keys truncated, 2 values truncated")
+}
+
+// nonFiniteMetrics returns a float sum with non-finite and finite
+// points, a NaN gauge, and an MMSC histogram with an infinite value.
+func nonFiniteMetrics() data.Metrics {
+	var sums []data.Point
+	for i, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 2.5} {
+		sums = append(sums, data.Point{
+			Attributes:  attribute.NewSet(attribute.Int("i", i)),
+			Temporality: aggregation.DeltaTemporality,
+			Aggregation: sum.NewNonMonotonicFloat64(v),
+		})
+	}
+	in := pointToMetric(gauge.NewFloat64(math.NaN()))
+	in.Scopes[0].Instruments = append(in.Scopes[0].Instruments,
+		data.Instrument{Points: sums},
+		pointToMetric(minmaxsumcount.NewFloat64(1, math.Inf(1))).Scopes[0].Instruments[0],
+	)
+	return in
+}
+
+func TestNonFinitePolicy(t *testing.T) {
+	// The zero policy exports values unchanged.
+	out := d2pd(&internal.ResourceMap{}, nonFiniteMetrics(), true)
+	require.False(t, NonFinitePolicy{}.apply(out).any())
+	ms := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.True(t, math.IsNaN(ms.At(0).Gauge().DataPoints().At(0).DoubleValue()))
+
+	out = d2pd(&internal.ResourceMap{}, nonFiniteMetrics(), true)
+	stats := NonFinitePolicy{
+		NaN:      ReplaceNonFinite,
+		NaNValue: -1,
+		Inf:      ReplaceNonFinite,
+		InfValue: math.MaxFloat64,
+	}.apply(out)
+	require.Equal(t, valueStats{replacedNaN: 2, replacedInf: 4}, stats)
+
+	ms = out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, -1.0, ms.At(0).Gauge().DataPoints().At(0).DoubleValue())
+
+	var values []float64
+	dps := ms.At(1).Sum().DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		values = append(values, dps.At(i).DoubleValue())
+	}
+	require.Equal(t, []float64{-1, math.MaxFloat64, -math.MaxFloat64, 2.5}, values)
+
+	hist := ms.At(2).Histogram().DataPoints().At(0)
+	require.Equal(t, math.MaxFloat64, hist.Sum())
+	require.Equal(t, 1.0, hist.Min())
+	require.Equal(t, math.MaxFloat64, hist.Max())
+
+	// Dropping removes every point holding a non-finite value.
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	var consumer testConsumer
+	err := ExportMetrics(
+		context.Background(),
+		nonFiniteMetrics(),
+		tracenoop.NewTracerProvider().Tracer("test"),
+		counter(t),
+		&internal.ResourceMap{},
+		&consumer,
+		true,
+		AttributeLimits{},
+		NonFinitePolicy{
+			NaN: DropNonFinite,
+			Inf: DropNonFinite,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, consumer.received, 1)
+
+	ms = consumer.received[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 0, ms.At(0).Gauge().DataPoints().Len())
+	require.Equal(t, 1, ms.At(1).Sum().DataPoints().Len())
+	require.Equal(t, 2.5, ms.At(1).Sum().DataPoints().At(0).DoubleValue())
+	require.Equal(t, 0, ms.At(2).Histogram().DataPoints().Len())
+
+	for _, err := range errs {
+		require.ErrorIs(t, err, ErrNonFiniteValues)
+		require.Contains(t, err.Error(), "0 NaN replaced, 0 Inf replaced, 5 points dropped")
+	}
+}
+
+// Tests that histogram exemplars are exported in the datapoint's
+// exemplar list, once per collection.
+func TestHistogramExemplars(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	for _, tempo := range []aggregation.Temporality{aggregation.CumulativeTemporality, aggregation.DeltaTemporality} {
+		t.Run(tempo.String(), func(t *testing.T) {
+			reader := sdkmetric.NewManualReader("test")
+			provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader,
+				view.WithDefaultAggregationTemporalitySelector(func(sdkinstrument.Kind) aggregation.Temporality {
+					return tempo
+				}),
+				view.WithClause(
+					view.MatchInstrumentName("latency"),
+					view.WithKeys([]attribute.Key{"path"}),
+					view.WithAggregatorConfig(aggregator.Config{
+						Exemplar: aggregator.ExemplarConfig{
+							Filter: aggregator.AlwaysOnKind,
+							Size:   4,
+						},
+					}),
+				),
+			))
+			histo, err := provider.Meter("test").Float64Histogram("latency")
+			require.NoError(t, err)
+
+			var md data.Metrics
+			for collect := 0; collect < 3; collect++ {
+				value := float64(collect + 1)
+				if collect == 0 || tempo == aggregation.DeltaTemporality {
+					histo.Record(ctx, value, metricapi.WithAttributes(
+						attribute.String("path", "/a"),
+						attribute.String("host", "h1"),
+					))
+				} else {
+					// Cumulative collects repeat the first interval.
+					value = 1
+				}
+				md = reader.Produce(&md)
+
+				for _, exponential := range []bool{true, false} {
+					out := d2pd(&internal.ResourceMap{}, md, exponential)
+					m := out.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+
+					var exs pmetric.ExemplarSlice
+					if exponential {
+						require.Equal(t, pmetric.MetricTypeExponentialHistogram, m.Type())
+						exs = m.ExponentialHistogram().DataPoints().At(0).Exemplars()
+					} else {
+						require.Equal(t, pmetric.MetricTypeHistogram, m.Type())
+						exs = m.Histogram().DataPoints().At(0).Exemplars()
+					}
+
+					require.Equal(t, 1, exs.Len())
+					ex := exs.At(0)
+					require.Equal(t, value, ex.DoubleValue())
+					require.NotZero(t, ex.Timestamp())
+					require.Equal(t, [16]byte(sc.TraceID()), [16]byte(ex.TraceID()))
+					require.Equal(t, [8]byte(sc.SpanID()), [8]byte(ex.SpanID()))
+					require.Equal(t, map[string]any{"host": "h1", "sample.weight": 1.0}, ex.FilteredAttributes().AsRaw())
+				}
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s, trunc := truncate("☃☃☃", 2)
+	require.True(t, trunc)
+	require.Equal(t, "☃☃", s)
+
+	s, trunc = truncate("☃☃", 2)
+	require.False(t, trunc)
+	require.Equal(t, "☃☃", s)
+
+	_, trunc = truncate("unlimited", 0)
+	require.False(t, trunc)
+}
+
+func counter(t *testing.T) metricapi.Int64Counter {
+	cntr, err := metricnoop.NewMeterProvider().Meter("test").Int64Counter("test")
+	require.NoError(t, err)
+	return cntr
+}
@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// ErrAttributesLimited is reported when exported point attributes
+// were dropped or truncated because of AttributeLimits.
+var ErrAttributesLimited = errors.New("exported attributes exceeded limits")
+
+// AttributeLimits limits the attributes of each exported point, so
+// that the export is not rejected by a receiver that enforces
+// limits.  Lengths are counted in characters.  Zero means unlimited.
+type AttributeLimits struct {
+	// MaxCount limits the number of attributes of each point.
+	MaxCount int
+
+	// MaxKeyLength limits the length of each attribute key.
+	MaxKeyLength int
+
+	// MaxValueLength limits the length of string attribute
+	// values, including each element of a string slice.
+	MaxValueLength int
+}
+
+// limitStats counts the changes made by AttributeLimits.
+type limitStats struct {
+	dropped         int
+	truncatedKeys   int
+	truncatedValues int
+}
+
+func (s limitStats) any() bool {
+	return s != limitStats{}
+}
+
+func (s limitStats) err() error {
+	return fmt.Errorf("%w: %d dropped, %d keys truncated, %d values truncated",
+		ErrAttributesLimited, s.dropped, s.truncatedKeys, s.truncatedValues)
+}
+
+func (l AttributeLimits) enabled() bool {
+	return l.MaxCount > 0 || l.MaxKeyLength > 0 || l.MaxValueLength > 0
+}
+
+// apply enforces the limits on every point of the converted data.
+func (l AttributeLimits) apply(md pmetric.Metrics) limitStats {
+	var stats limitStats
+	if !l.enabled() {
+		return stats
+	}
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				l.applyMetric(ms.At(k), &stats)
+			}
+		}
+	}
+	return stats
+}
+
+func (l AttributeLimits) applyMetric(m pmetric.Metric, stats *limitStats) {
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		dps := m.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			l.applyMap(dps.At(i).Attributes(), stats)
+		}
+	case pmetric.MetricTypeGauge:
+		dps := m.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			l.applyMap(dps.At(i).Attributes(), stats)
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			l.applyMap(dps.At(i).Attributes(), stats)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			l.applyMap(dps.At(i).Attributes(), stats)
+		}
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			l.applyMap(dps.At(i).Attributes(), stats)
+		}
+	}
+}
+
+// applyMap enforces the limits on one attribute map.  Attributes are
+// considered in key order, so the result is deterministic: keys are
+// truncated, a truncated key that collides with an earlier key is
+// dropped, and attributes beyond MaxCount are dropped.
+func (l AttributeLimits) applyMap(attrs pcommon.Map, stats *limitStats) {
+	if l.conforms(attrs) {
+		return
+	}
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+
+	out := pcommon.NewMap()
+	out.EnsureCapacity(min(len(keys), max(l.MaxCount, 0)))
+	for _, key := range keys {
+		if l.MaxCount > 0 && out.Len() == l.MaxCount {
+			stats.dropped++
+			continue
+		}
+		nkey, truncated := truncate(key, l.MaxKeyLength)
+		if _, exists := out.Get(nkey); exists {
+			stats.dropped++
+			continue
+		}
+		if truncated {
+			stats.truncatedKeys++
+		}
+		value, _ := attrs.Get(key)
+		nvalue := out.PutEmpty(nkey)
+		value.CopyTo(nvalue)
+		stats.truncatedValues += l.truncateValue(nvalue)
+	}
+	out.MoveTo(attrs)
+}
+
+// conforms returns true when no change is needed.
+func (l AttributeLimits) conforms(attrs pcommon.Map) bool {
+	if l.MaxCount > 0 && attrs.Len() > l.MaxCount {
+		return false
+	}
+	ok := true
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		if _, trunc := truncate(k, l.MaxKeyLength); trunc {
+			ok = false
+		} else if l.MaxValueLength > 0 && l.valueExceeds(v) {
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+func (l AttributeLimits) valueExceeds(v pcommon.Value) bool {
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		return utf8.RuneCountInString(v.Str()) > l.MaxValueLength
+	case pcommon.ValueTypeSlice:
+		sl := v.Slice()
+		for i := 0; i < sl.Len(); i++ {
+			if l.valueExceeds(sl.At(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// truncateValue truncates string values in place, returning the
+// number of strings truncated.
+func (l AttributeLimits) truncateValue(v pcommon.Value) int {
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		if s, trunc := truncate(v.Str(), l.MaxValueLength); trunc {
+			v.SetStr(s)
+			return 1
+		}
+	case pcommon.ValueTypeSlice:
+		n := 0
+		sl := v.Slice()
+		for i := 0; i < sl.Len(); i++ {
+			n += l.truncateValue(sl.At(i))
+		}
+		return n
+	}
+	return 0
+}
+
+// truncate returns s limited to n characters, and whether it was
+// truncated.  Zero means unlimited.
+func truncate(s string, n int) (string, bool) {
+	if n <= 0 || len(s) <= n {
+		return s, false
+	}
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i], true
+		}
+		count++
+	}
+	return s, false
+}
@@ -0,0 +1,224 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// ErrNonFiniteValues is reported when exported points contained NaN
+// or Inf values that were replaced or dropped because of a
+// NonFinitePolicy.
+var ErrNonFiniteValues = errors.New("exported points contained non-finite values")
+
+// NonFiniteAction selects the handling of one kind of non-finite
+// value.
+type NonFiniteAction int
+
+const (
+	// KeepNonFinite exports the value unchanged.
+	KeepNonFinite NonFiniteAction = iota
+
+	// ReplaceNonFinite exports the configured replacement value.
+	ReplaceNonFinite
+
+	// DropNonFinite removes the point, or the exemplar, holding
+	// the value.
+	DropNonFinite
+)
+
+// NonFinitePolicy replaces or drops the NaN and Inf values of each
+// exported point, since some receivers reject a request containing
+// them.  It applies to float sums and gauges, histogram sum, min and
+// max, and exemplar values.  The zero value exports values
+// unchanged.
+type NonFinitePolicy struct {
+	// NaN selects the handling of NaN values.
+	NaN NonFiniteAction
+
+	// NaNValue replaces NaN values when NaN is ReplaceNonFinite.
+	NaNValue float64
+
+	// Inf selects the handling of infinite values.
+	Inf NonFiniteAction
+
+	// InfValue replaces +Inf values when Inf is ReplaceNonFinite,
+	// and its negation replaces -Inf values.
+	InfValue float64
+}
+
+// valueStats counts the changes made by NonFinitePolicy.
+type valueStats struct {
+	replacedNaN int
+	replacedInf int
+	dropped     int
+}
+
+func (s valueStats) any() bool {
+	return s != valueStats{}
+}
+
+func (s valueStats) err() error {
+	return fmt.Errorf("%w: %d NaN replaced, %d Inf replaced, %d points dropped",
+		ErrNonFiniteValues, s.replacedNaN, s.replacedInf, s.dropped)
+}
+
+func (p NonFinitePolicy) enabled() bool {
+	return p.NaN != KeepNonFinite || p.Inf != KeepNonFinite
+}
+
+// apply enforces the policy on every point of the converted data.
+func (p NonFinitePolicy) apply(md pmetric.Metrics) valueStats {
+	var stats valueStats
+	if !p.enabled() {
+		return stats
+	}
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				p.applyMetric(ms.At(k), &stats)
+			}
+		}
+	}
+	return stats
+}
+
+func (p NonFinitePolicy) applyMetric(m pmetric.Metric, stats *valueStats) {
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		m.Sum().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return !p.applyNumber(dp, stats)
+		})
+	case pmetric.MetricTypeGauge:
+		m.Gauge().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return !p.applyNumber(dp, stats)
+		})
+	case pmetric.MetricTypeHistogram:
+		m.Histogram().DataPoints().RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return !p.applyHistogram(dp, stats)
+		})
+	case pmetric.MetricTypeExponentialHistogram:
+		m.ExponentialHistogram().DataPoints().RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return !p.applyHistogram(dp, stats)
+		})
+	}
+}
+
+// applyNumber returns false when the point should be dropped.
+func (p NonFinitePolicy) applyNumber(dp pmetric.NumberDataPoint, stats *valueStats) bool {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeDouble {
+		value, ok := p.replace(dp.DoubleValue(), stats)
+		if !ok {
+			stats.dropped++
+			return false
+		}
+		dp.SetDoubleValue(value)
+	}
+	p.applyExemplars(dp.Exemplars(), stats)
+	return true
+}
+
+// histogramPoint is the part of the explicit and exponential
+// histogram points that may hold non-finite values.
+type histogramPoint interface {
+	Sum() float64
+	SetSum(float64)
+	HasMin() bool
+	Min() float64
+	SetMin(float64)
+	HasMax() bool
+	Max() float64
+	SetMax(float64)
+	Exemplars() pmetric.ExemplarSlice
+}
+
+// applyHistogram returns false when the point should be dropped.
+// The point is checked before it is modified, so that a dropped point
+// is not counted as replaced.
+func (p NonFinitePolicy) applyHistogram(dp histogramPoint, stats *valueStats) bool {
+	values := []float64{dp.Sum()}
+	if dp.HasMin() {
+		values = append(values, dp.Min())
+	}
+	if dp.HasMax() {
+		values = append(values, dp.Max())
+	}
+	for _, v := range values {
+		if p.drops(v) {
+			stats.dropped++
+			return false
+		}
+	}
+	sum, _ := p.replace(dp.Sum(), stats)
+	dp.SetSum(sum)
+	if dp.HasMin() {
+		v, _ := p.replace(dp.Min(), stats)
+		dp.SetMin(v)
+	}
+	if dp.HasMax() {
+		v, _ := p.replace(dp.Max(), stats)
+		dp.SetMax(v)
+	}
+	p.applyExemplars(dp.Exemplars(), stats)
+	return true
+}
+
+// applyExemplars replaces or drops non-finite exemplar values.
+// Dropped exemplars are not counted, since the point is exported.
+func (p NonFinitePolicy) applyExemplars(exs pmetric.ExemplarSlice, stats *valueStats) {
+	exs.RemoveIf(func(ex pmetric.Exemplar) bool {
+		if ex.ValueType() != pmetric.ExemplarValueTypeDouble {
+			return false
+		}
+		value, ok := p.replace(ex.DoubleValue(), stats)
+		if ok {
+			ex.SetDoubleValue(value)
+		}
+		return !ok
+	})
+}
+
+// drops returns true when the policy drops the value.
+func (p NonFinitePolicy) drops(value float64) bool {
+	return (math.IsNaN(value) && p.NaN == DropNonFinite) ||
+		(math.IsInf(value, 0) && p.Inf == DropNonFinite)
+}
+
+// replace returns the value to export, or false when the policy drops
+// the value.
+func (p NonFinitePolicy) replace(value float64, stats *valueStats) (float64, bool) {
+	if p.drops(value) {
+		return 0, false
+	}
+	switch {
+	case math.IsNaN(value) && p.NaN == ReplaceNonFinite:
+		stats.replacedNaN++
+		return p.NaNValue, true
+	case math.IsInf(value, 1) && p.Inf == ReplaceNonFinite:
+		stats.replacedInf++
+		return p.InfValue, true
+	case math.IsInf(value, -1) && p.Inf == ReplaceNonFinite:
+		stats.replacedInf++
+		return -p.InfValue, true
+	}
+	return value, true
+}
@@ -24,3 +24,12 @@ var OverflowAttributes = []attribute.KeyValue{
 
 // OverflowAttributeSet is the set corresponding with OverflowAttributes.
 var OverflowAttributeSet = attribute.NewSet(OverflowAttributes...)
+
+// OverflowSet returns the set corresponding with configured overflow
+// attributes, or OverflowAttributeSet when none are configured.
+func OverflowSet(attrs []attribute.KeyValue) attribute.Set {
+	if len(attrs) == 0 {
+		return OverflowAttributeSet
+	}
+	return attribute.NewSet(attrs...)
+}
@@ -18,7 +18,6 @@ import (
 	"runtime"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/fprint"
-	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -164,8 +163,8 @@ func acquireReadLockedKV(inst *Observer, fp uint64, attrs []attribute.KeyValue,
 	// one lookup if the attribute set was preexisting.
 	if !*overflow && uint32(len(inst.currentFP)) >= inst.performance.InstrumentCardinalityLimit-1 {
 		// Use the overflow attributes, repeat.
-		attrs = pipeline.OverflowAttributes
-		fp = overflowAttributesFingerprint
+		attrs = inst.performance.OverflowAttributes
+		fp = inst.overflowFP
 		*overflow = true
 	}
 
@@ -177,10 +176,14 @@ func acquireReadLockedKV(inst *Observer, fp uint64, attrs []attribute.KeyValue,
 // possibly not initialized.
 func acquireUninitializedKV[N number.Any](inst *Observer, attrs []attribute.KeyValue) *recordKV {
 	fp := fingerprintAttributes(attrs)
+	input, inputFP := attrs, fp
 
 	// acquireRead may replace fp and attrs when there is overflow.
 	var rec *recordKV
 	fp, attrs, rec = acquireReadKV(inst, fp, attrs)
+	if fp != inputFP {
+		inst.notifyOverflow(input)
+	}
 	if rec != nil {
 		return rec
 	}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncstate
+
+import "sync"
+
+// snapshotPool is shared by the parallel SnapshotAndProcess of every
+// instrument.  Its goroutines are started as needed, up to the
+// largest Performance.SnapshotParallelism in use, and are reused by
+// later collections.
+var snapshotPool workerPool
+
+// workerPool is a bounded set of goroutines waiting for tasks.
+type workerPool struct {
+	lock    sync.Mutex
+	started int
+	tasks   chan func()
+}
+
+// run calls do for each part from 0 to parts-1, using parts-1
+// goroutines of the pool and the calling goroutine, and returns once
+// every call has returned.
+func (p *workerPool) run(parts int, do func(part int)) {
+	p.grow(parts - 1)
+
+	var wg sync.WaitGroup
+	wg.Add(parts - 1)
+	for part := 1; part < parts; part++ {
+		part := part
+		p.tasks <- func() {
+			defer wg.Done()
+			do(part)
+		}
+	}
+	do(0)
+	wg.Wait()
+}
+
+// grow starts goroutines until the pool has at least size.
+func (p *workerPool) grow(size int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.tasks == nil {
+		p.tasks = make(chan func())
+	}
+	for ; p.started < size; p.started++ {
+		go func(tasks <-chan func()) {
+			for task := range tasks {
+				task()
+			}
+		}(p.tasks)
+	}
+}
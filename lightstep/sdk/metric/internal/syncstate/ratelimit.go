@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncstate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrRateLimited is reported when measurements exceed the rate limit
+// of their instrument.
+var ErrRateLimited = errors.New("instrument rate limit exceeded, exemplars skipped")
+
+// RateLimiter is a token bucket shared by the calls to one
+// instrument.  It is implemented as the equivalent generic cell rate
+// algorithm, which tracks the theoretical arrival time of the next
+// call in a single atomic value, so that calls never take a lock.
+type RateLimiter struct {
+	// interval is the time, in nanoseconds, to earn one token.
+	interval int64
+
+	// tolerance is the time, in nanoseconds, to fill the bucket.
+	tolerance int64
+
+	// clock returns the current time.
+	clock func() time.Time
+
+	// tat is the theoretical arrival time, in Unix nanoseconds,
+	// at which the bucket is full.  It is zero initially.
+	tat atomic.Int64
+
+	// throttled counts the calls over the limit since the last
+	// diagnostic.
+	throttled atomic.Uint64
+}
+
+// NewRateLimiter returns a limiter allowing rate calls per second on
+// average, with bursts of up to burst calls.  A burst less than one
+// is treated as one.
+func NewRateLimiter(rate float64, burst int, clock func() time.Time) *RateLimiter {
+	interval := int64(math.Ceil(float64(time.Second) / rate))
+	return &RateLimiter{
+		interval:  interval,
+		tolerance: int64(max(burst, 1)) * interval,
+		clock:     clock,
+	}
+}
+
+// Allow takes a token, returning false when none are available.  A
+// nil limiter allows every call.  Throttled calls are reported
+// periodically, using the instrument name given.
+func (r *RateLimiter) Allow(name string) bool {
+	if r == nil {
+		return true
+	}
+	now := r.clock().UnixNano()
+	for {
+		tat := r.tat.Load()
+		next := max(tat, now) + r.interval
+		if next-now > r.tolerance {
+			r.throttle(name)
+			return false
+		}
+		if r.tat.CompareAndSwap(tat, next) {
+			return true
+		}
+	}
+}
+
+// throttle counts a throttled call and reports the count.
+func (r *RateLimiter) throttle(name string) {
+	r.throttled.Add(1)
+
+	doevery.TimePeriod(30*time.Second, func() {
+		otel.Handle(fmt.Errorf("%s: %w: %d calls", name, ErrRateLimited, r.throttled.Swap(0)))
+	})
+}
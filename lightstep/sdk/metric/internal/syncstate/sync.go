@@ -16,6 +16,8 @@ package syncstate
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,8 +31,6 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-var overflowAttributesFingerprint = fingerprintAttributes(pipeline.OverflowAttributes)
-
 // Instrument maintains a mapping from attribute.Set to an internal
 // record type for a single API-level instrument.  This type is
 // organized so that a single attribute.Set lookup is performed
@@ -46,6 +46,9 @@ type Observer struct {
 	// performance settings for the instrument.
 	performance sdkinstrument.Performance
 
+	// overflowFP is the fingerprint of the overflow attributes.
+	overflowFP uint64
+
 	// compiled will be a single compiled instrument or a
 	// multi-instrument in case of multiple view behaviors
 	// and/or readers; these distinctions do not matter
@@ -57,6 +60,30 @@ type Observer struct {
 
 	// currentFP is protected by lock.
 	currentFP map[uint64]*recordKV
+
+	// lists is reused by parallelSnapshotAndProcess, protected
+	// by lock.
+	lists []snapshotList
+
+	// closed is set by Close, after which measurements are
+	// ignored.
+	closed atomic.Bool
+
+	// disabled is set by SetEnabled(false, ...), after which
+	// measurements are skipped.
+	disabled atomic.Bool
+
+	// flag, if set, is evaluated for each measurement, which is
+	// skipped when it returns false.
+	flag func(context.Context) bool
+
+	// limiter, if set, limits the rate of measurements that are
+	// considered for exemplars.
+	limiter *RateLimiter
+
+	// overflowed is set when Performance.OnOverflow is called,
+	// until the next SnapshotAndProcess.
+	overflowed atomic.Bool
 }
 
 // New builds a new synchronous instrument *Observer given the
@@ -80,6 +107,7 @@ func New(desc sdkinstrument.Descriptor, performance sdkinstrument.Performance, _
 		descriptor:  desc,
 		currentFP:   map[uint64]*recordKV{},
 		performance: performance,
+		overflowFP:  fingerprintAttributes(performance.OverflowAttributes),
 
 		// Note that viewstate.Combine is used to eliminate
 		// the per-pipeline distinction that is useful in the
@@ -93,63 +121,209 @@ func New(desc sdkinstrument.Descriptor, performance sdkinstrument.Performance, _
 	}
 }
 
+// SetFlag configures a callback that decides, for each measurement,
+// whether it is recorded.  SetFlag must be called before the
+// instrument is used.
+func (inst *Observer) SetFlag(flag func(context.Context) bool) {
+	inst.flag = flag
+}
+
+// SetRateLimiter configures a limiter.  Measurements over the limit
+// are aggregated, but they skip exemplar consideration, including
+// inspection of the context for spans, links, and timestamps.
+// Attribute processing is not skipped, since it determines the
+// series that is updated.  SetRateLimiter must be called before the
+// instrument is used.
+func (inst *Observer) SetRateLimiter(limiter *RateLimiter) {
+	inst.limiter = limiter
+}
+
+// SetEnabled enables or disables the instrument at runtime.  The
+// flag is read without locking before any attribute processing, and
+// the compiled views discard measurements of accumulators already in
+// use.  When clear is set, disabling also discards the series the
+// views have accumulated.
+func (inst *Observer) SetEnabled(enabled, clear bool) {
+	inst.disabled.Store(!enabled)
+	inst.compiled.SetEnabled(enabled)
+	if clear && !enabled {
+		inst.compiled.Reset()
+	}
+}
+
+// Enabled returns false when the measurement should be skipped
+// because the instrument is disabled by views or SetEnabled, or its
+// flag is off.  This is checked before any attribute processing.
+func (inst *Observer) Enabled(ctx context.Context) bool {
+	return inst != nil && !inst.disabled.Load() && (inst.flag == nil || inst.flag(ctx))
+}
+
 // SnapshotAndProcess calls SnapshotAndProcess() for all live
 // accumulators of this instrument.  Inactive accumulators will be
-// subsequently removed from the map.
-func (inst *Observer) SnapshotAndProcess() {
+// subsequently removed from the map.  The first error is returned,
+// after the remaining accumulators are processed.  With
+// Performance.SnapshotParallelism greater than 1, the fingerprints
+// are divided among that many goroutines of a shared pool.
+func (inst *Observer) SnapshotAndProcess() error {
 	inst.lock.Lock()
 	defer inst.lock.Unlock()
 
+	inst.overflowed.Store(false)
+
+	workers := min(int(inst.performance.SnapshotParallelism), len(inst.currentFP))
+	if workers > 1 {
+		return inst.parallelSnapshotAndProcess(workers)
+	}
+
+	var first error
+
 	for key, reclist := range inst.currentFP {
-		// reclist is a list of records for this fingerprint.
-		var head *recordKV
-		var tail *recordKV
-
-		// Scan reclist and modify the list. We're holding the
-		// lock giving exclusive access to the head-of-list
-		// and each next field, so the process here builds a new
-		// linked list after filtering records that are no longer
-		// in use.
-		for rec := reclist; rec != nil; rec = rec.next {
-			if inst.collect(key, rec) {
-				if head == nil {
-					// The first time a record will be kept,
-					// it becomes the head and tail.
-					head = rec
-					tail = rec
-				} else {
-					// Subsequently, update the tail of the
-					// list.  Note that this creates a
-					// temporarily invalid list will be
-					// repaired outside the loop, below.
-					tail.next = rec
-					tail = rec
-				}
-			}
+		head, err := inst.collectList(key, reclist)
+		if err != nil && first == nil {
+			first = err
 		}
+		inst.replaceList(key, reclist, head)
+	}
+	return first
+}
+
+// snapshotList is one fingerprint's list of records, before and
+// after a parallel collection.
+type snapshotList struct {
+	key     uint64
+	reclist *recordKV
+	head    *recordKV
+	err     error
+}
 
-		// When no records are kept, delete the map entry.
-		if head == nil {
-			delete(inst.currentFP, key)
-			continue
+// parallelSnapshotAndProcess is SnapshotAndProcess for more than one
+// worker.  Each worker of snapshotPool collects a contiguous share of
+// the lists, which are replaced in the map afterward.  Records of one
+// series of the compiled views may be collected by different
+// workers; the accumulators synchronize the state they share in the
+// series, and the views modify the rest of it during collection with
+// the instrument lock held.  Called with the lock held.
+func (inst *Observer) parallelSnapshotAndProcess(workers int) error {
+	lists := inst.lists[:0]
+	for key, reclist := range inst.currentFP {
+		lists = append(lists, snapshotList{key: key, reclist: reclist})
+	}
+
+	share := (len(lists) + workers - 1) / workers
+	snapshotPool.run((len(lists)+share-1)/share, func(part int) {
+		for i := part * share; i < min((part+1)*share, len(lists)); i++ {
+			lists[i].head, lists[i].err = inst.collectList(lists[i].key, lists[i].reclist)
 		}
+	})
 
-		// Otherwise, terminate the list that was built.
+	var first error
+	for _, l := range lists {
+		if l.err != nil && first == nil {
+			first = l.err
+		}
+		inst.replaceList(l.key, l.reclist, l.head)
+	}
+
+	// The lists are reused without holding on to the records.
+	clear(lists)
+	inst.lists = lists[:0]
+	return first
+}
+
+// collectList collects the records of reclist, returning the first
+// error and a new list of the records kept, which is nil when none
+// are kept.  The callers hold the lock, and only one calls
+// collectList for a fingerprint at a time.
+func (inst *Observer) collectList(key uint64, reclist *recordKV) (*recordKV, error) {
+	var first error
+	var head *recordKV
+	var tail *recordKV
+
+	// Scan reclist and modify the list. We're holding the
+	// lock giving exclusive access to the head-of-list
+	// and each next field, so the process here builds a new
+	// linked list after filtering records that are no longer
+	// in use.
+	for rec := reclist; rec != nil; rec = rec.next {
+		keep, err := inst.collect(key, rec)
+		if err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", inst.descriptor.Name, err)
+		}
+		if keep {
+			if head == nil {
+				// The first time a record will be kept,
+				// it becomes the head and tail.
+				head = rec
+				tail = rec
+			} else {
+				// Subsequently, update the tail of the
+				// list.  Note that this creates a
+				// temporarily invalid list will be
+				// repaired outside the loop, below.
+				tail.next = rec
+				tail = rec
+			}
+		}
+	}
+
+	// Terminate the list that was built.
+	if tail != nil {
 		tail.next = nil
+	}
+	return head, first
+}
+
+// replaceList updates the map entry for a list collected by
+// collectList.  Called with the lock held.
+func (inst *Observer) replaceList(key uint64, reclist, head *recordKV) {
+	// When no records are kept, delete the map entry.
+	if head == nil {
+		delete(inst.currentFP, key)
+		return
+	}
+	if head != reclist {
+		// If the head changes, update the map.
+		inst.currentFP[key] = head
+	}
+}
 
-		if head != reclist {
-			// If the head changes, update the map.
-			inst.currentFP[key] = head
+// notifyOverflow calls Performance.OnOverflow for attributes recorded
+// in the overflow attribute set, unless it was called since the last
+// SnapshotAndProcess.  Called without the lock.
+func (inst *Observer) notifyOverflow(attrs []attribute.KeyValue) {
+	if inst.performance.OnOverflow == nil || inst.overflowed.Load() || inst.overflowed.Swap(true) {
+		return
+	}
+	inst.performance.OnOverflow(inst.descriptor.Name, attribute.NewSet(slices.Clone(attrs)...))
+}
+
+// Close stops recording, so that a subsequent collection is the
+// final one.  Measurements made after Close are ignored.
+func (inst *Observer) Close() {
+	inst.closed.Store(true)
+}
+
+// Release releases every accumulator of this instrument, after the
+// final collection.  Records are removed from memory regardless of
+// their references, since Close has stopped further measurements.
+func (inst *Observer) Release() {
+	inst.lock.Lock()
+	defer inst.lock.Unlock()
+
+	for _, reclist := range inst.currentFP {
+		for rec := reclist; rec != nil; rec = rec.next {
+			_, _ = rec.scavengeCollect(true)
 		}
 	}
+	clear(inst.currentFP)
 }
 
 // collect collects the record.  When the record has been inactive for
 // the configured number of periods, it is removed from memory.
-func (inst *Observer) collect(fp uint64, rec *recordKV) bool {
-	if rec.normalCollect() {
+func (inst *Observer) collect(fp uint64, rec *recordKV) (bool, error) {
+	if active, err := rec.normalCollect(); active {
 		rec.inactiveCount = 0
-		return true
+		return true, err
 	}
 
 	// Allow the record to remain in an inactive state for a number
@@ -157,7 +331,7 @@ func (inst *Observer) collect(fp uint64, rec *recordKV) bool {
 	rec.inactiveCount++
 
 	if rec.inactiveCount < inst.performance.InactiveCollectionPeriods {
-		return true
+		return true, nil
 	}
 
 	// Having no updates since last collection, try to unmap:
@@ -166,7 +340,7 @@ func (inst *Observer) collect(fp uint64, rec *recordKV) bool {
 	// normalCollect returned false indicating no change, except:
 	// (a) it's now possible there was a race, the collector needs to see it.
 	// (b) if this is indeed the last reference, the collector needs the release signal.
-	_ = rec.scavengeCollect(unmapped)
+	_, err := rec.scavengeCollect(unmapped)
 
 	// reset inactivity in case of !unmapped.
 	rec.inactiveCount = 0
@@ -174,7 +348,7 @@ func (inst *Observer) collect(fp uint64, rec *recordKV) bool {
 	// When `unmapped` is true, any other goroutines are now
 	// trying to re-insert this entry in the map, they are busy
 	// calling Gosched() waiting for this record to disappear.
-	return !unmapped
+	return !unmapped, err
 }
 
 // record consists of an accumulator, a reference count, the number of
@@ -240,36 +414,36 @@ type recordKV struct {
 
 // normalCollect equals conditionalCollect(false), is named
 // differently from scavengeCollect for profiling.
-func (rec *recordKV) normalCollect() bool {
+func (rec *recordKV) normalCollect() (bool, error) {
 	return rec.conditionalCollect(false)
 }
 
 // scavengeCollect equals conditionalCollect(false), is named
 // differently from normalCollect for profiling.
-func (rec *recordKV) scavengeCollect(release bool) bool {
+func (rec *recordKV) scavengeCollect(release bool) (bool, error) {
 	return rec.conditionalCollect(release)
 }
 
 // conditionalCollect checks whether the accumulator has been modified
 // since the last collection (by any reader), returns a boolean
 // indicating whether the record is active.  If modified, calls
-// SnapshotAndProcess on the associated accumulator and returns true.
-// If updates happened since the last collection (by any reader),
-// returns false.
-func (rec *recordKV) conditionalCollect(release bool) bool {
+// SnapshotAndProcess on the associated accumulator and returns true,
+// with its error.  If updates happened since the last collection (by
+// any reader), returns false.
+func (rec *recordKV) conditionalCollect(release bool) (bool, error) {
 	mods := atomic.LoadUint32(&rec.updateCount)
 
 	if !release {
 		if mods == rec.collectedCount {
-			return false
+			return false, nil
 		}
 	}
 
-	rec.readAccumulator().SnapshotAndProcess(release)
+	err := rec.readAccumulator().SnapshotAndProcess(release)
 
 	// Updates happened in this interval, collect and continue.
 	rec.collectedCount = mods
-	return true
+	return true, err
 }
 
 // readAccumulator gets the accumulator for this record after once.Do(initialize).
@@ -278,10 +452,27 @@ func (rec *recordKV) readAccumulator() viewstate.Accumulator {
 	return rec.accumulatorUnsafe
 }
 
+// readAccumulatorWithMetadata is readAccumulator for a measurement
+// with series metadata, which is given to the accumulator when this
+// measurement initializes the record.
+func (rec *recordKV) readAccumulatorWithMetadata(metadata attribute.Set) viewstate.Accumulator {
+	if metadata.Len() == 0 {
+		return rec.readAccumulator()
+	}
+	rec.once.Do(func() { rec.initializeWithMetadata(metadata) })
+	return rec.accumulatorUnsafe
+}
+
 // initialize ensures that accumulatorUnsafe and attrsUnsafe are correctly initialized.
 //
 // readAccumulator() calls this inside a sync.Once.Do().
 func (rec *recordKV) initialize() {
+	rec.initializeWithMetadata(*attribute.EmptySet())
+}
+
+// initializeWithMetadata is initialize with the metadata of the
+// series.
+func (rec *recordKV) initializeWithMetadata(metadata attribute.Set) {
 	// We need another copy of the attribute list because NewSet()
 	// will sort it in place.
 	acpy := make([]attribute.KeyValue, len(rec.attrsList))
@@ -293,7 +484,7 @@ func (rec *recordKV) initialize() {
 	}
 
 	aset := attribute.NewSet(acpy...)
-	rec.accumulatorUnsafe = rec.inst.compiled.NewAccumulator(aset)
+	rec.accumulatorUnsafe = rec.inst.compiled.NewAccumulatorWithMetadata(aset, metadata)
 }
 
 // computeAttrsUnderLock sets the attribute.Set that will be used to
@@ -334,6 +525,10 @@ func Observe[N number.Any, Traits number.Traits[N]](ctx context.Context, inst *O
 		// Instrument was completely disabled by the view.
 		return
 	}
+	if inst.closed.Load() {
+		// The provider was shut down.
+		return
+	}
 
 	if !aggregator.RangeTest[N, Traits](num, inst.descriptor) {
 		return
@@ -358,9 +553,22 @@ func Observe[N number.Any, Traits number.Traits[N]](ctx context.Context, inst *O
 
 	defer rec.refMapped.unref()
 
+	// The weight and the timestamp apply to the aggregate, so
+	// they are used even when the measurement is throttled.
+	mc := aggregator.MeasurementFromContext(ctx)
+
 	var tr Traits
 	var exBits aggregator.ExemplarBits
-	updater := rec.readAccumulator().(viewstate.Updater[N])
+	updater := rec.readAccumulatorWithMetadata(mc.Metadata).(viewstate.Updater[N])
+
+	exBits.SecondaryWeight, exBits.HasSecondaryWeight = mc.Weight, mc.HasWeight
+	exBits.Time = mc.Time
+
+	if !inst.limiter.Allow(inst.descriptor.Name) {
+		updater.Update(num, exBits)
+		atomic.AddUint32(&rec.updateCount, 1)
+		return
+	}
 
 	// TODO: Note the isTraced() calculation here is difficult to
 	// place.  It can be deferred until the filter is known, but
@@ -370,13 +578,17 @@ func Observe[N number.Any, Traits number.Traits[N]](ctx context.Context, inst *O
 	// been probed.  Assuming the context has already been probed
 	// once, we should know by now whether the context is sampled.
 	span := trace.SpanFromContext(ctx)
-	isTraced := span.SpanContext().IsSampled()
+	links := mc.Links
+	isTraced := span.SpanContext().IsSampled() || anySampled(links)
 
 	if updater.MaySample(isTraced) {
-		exBits.Time = time.Now()
+		if exBits.Time.IsZero() {
+			exBits.Time = time.Now()
+		}
 		exBits.Attributes = keyValues
 		exBits.Span = span
 		exBits.Number = tr.ToNumber(num)
+		exBits.Links = links
 	}
 
 	updater.Update(num, exBits)
@@ -384,3 +596,13 @@ func Observe[N number.Any, Traits number.Traits[N]](ctx context.Context, inst *O
 	// Record was modified.
 	atomic.AddUint32(&rec.updateCount, 1)
 }
+
+// anySampled returns true if any of the links is sampled.
+func anySampled(links []trace.SpanContext) bool {
+	for _, link := range links {
+		if link.IsSampled() {
+			return true
+		}
+	}
+	return false
+}
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,6 +34,7 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
@@ -39,6 +42,7 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
@@ -146,6 +150,11 @@ func TestSyncStateCumulativeConcurrencyFloatFiltered(t *testing.T) {
 func testSyncStateConcurrency[N number.Any, Traits number.Traits[N]](t *testing.T, update func(old, new N) N, vopts ...view.Option) {
 	t.Run("unsafe_collisions", func(t *testing.T) { testSyncStateConcurrencyWithPerf[N, Traits](t, unsafePerf, update, vopts...) })
 	t.Run("safe_collisions", func(t *testing.T) { testSyncStateConcurrencyWithPerf[N, Traits](t, safePerf, update, vopts...) })
+	t.Run("parallel_snapshot", func(t *testing.T) {
+		perf := safePerf
+		perf.SnapshotParallelism = 4
+		testSyncStateConcurrencyWithPerf[N, Traits](t, perf, update, vopts...)
+	})
 }
 
 func testSyncStateConcurrencyWithPerf[N number.Any, Traits number.Traits[N]](t *testing.T, perf sdkinstrument.Performance, update func(old, new N) N, vopts ...view.Option) {
@@ -1611,3 +1620,501 @@ func TestSyncExemplars(t *testing.T) {
 		),
 	)
 }
+
+func TestSyncExemplarLinks(t *testing.T) {
+	lib := instrumentation.Scope{
+		Name: "testlib",
+	}
+	perf := sdkinstrument.Performance{}
+	vcs := viewstate.New(lib, view.New(
+		"test",
+		perf,
+		deltaSelector,
+		view.WithClause(
+			view.WithAggregatorConfig(aggregator.Config{
+				Exemplar: aggregator.ExemplarConfig{
+					Filter:   aggregator.WhenTracedKind,
+					Size:     2,
+					MaxLinks: 2,
+				},
+			}),
+		),
+	))
+	desc := test.Descriptor(
+		"batch",
+		sdkinstrument.SyncCounter,
+		number.Int64Kind)
+
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vcs.Compile(desc)
+
+	require.NotNil(t, pipes[0])
+	inst := New(desc, perf, nil, pipes)
+	require.NotNil(t, inst)
+
+	link := func(t byte) trace.SpanContext {
+		return test.FakeSpan(t, t).SpanContext()
+	}
+	attrs := []attribute.KeyValue{attribute.String("a", "1")}
+
+	// A traced batch with more links than the limit.
+	ctx1 := exemplar.ContextWithLinks(
+		trace.ContextWithSpan(context.Background(), test.FakeSpan(1, 1)),
+		link(2), link(3), link(4),
+	)
+	inst.ObserveInt64(ctx1, 3, attrsConfig(attrs...))
+
+	// An untraced batch is sampled because its links are.
+	ctx2 := exemplar.ContextWithLinks(context.Background(), link(5))
+	inst.ObserveInt64(ctx2, 1, attrsConfig(attrs...))
+
+	// An untraced batch without links is not sampled.
+	inst.ObserveInt64(context.Background(), 1, attrsConfig(attrs...))
+
+	inst.SnapshotAndProcess()
+	test.RequireEqualMetrics(
+		t,
+		test.CollectScope(
+			t,
+			vcs.Collectors(),
+			testSequence,
+		),
+		test.Instrument(
+			desc,
+			test.PointEx(
+				middleTime, endTime,
+				sum.NewMonotonicInt64(5),
+				aggregation.DeltaTemporality,
+				attrs,
+				// Note: the sampler outputs lower weights first.
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: aggregator.ExemplarBits{
+						Number:     number.FromInt64(1),
+						Attributes: attrs,
+						Span:       trace.SpanFromContext(context.Background()),
+						Links:      []trace.SpanContext{link(5)},
+					},
+					Weight: 1,
+				},
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: aggregator.ExemplarBits{
+						Number:       number.FromInt64(3),
+						Attributes:   attrs,
+						Span:         test.FakeSpan(1, 1),
+						Links:        []trace.SpanContext{link(2), link(3)},
+						DroppedLinks: 1,
+					},
+					Weight: 3,
+				},
+			),
+		),
+	)
+}
+
+func TestSyncExemplarTimestamp(t *testing.T) {
+	lib := instrumentation.Scope{
+		Name: "testlib",
+	}
+	perf := sdkinstrument.Performance{}
+	vcs := viewstate.New(lib, view.New(
+		"test",
+		perf,
+		deltaSelector,
+		view.WithClause(
+			view.WithAggregatorConfig(aggregator.Config{
+				Exemplar: aggregator.ExemplarConfig{
+					Filter: aggregator.AlwaysOnKind,
+					Size:   1,
+				},
+			}),
+		),
+	))
+	desc := test.Descriptor(
+		"backfill",
+		sdkinstrument.SyncCounter,
+		number.Int64Kind)
+
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vcs.Compile(desc)
+
+	inst := New(desc, perf, nil, pipes)
+	require.NotNil(t, inst)
+
+	attrs := []attribute.KeyValue{attribute.String("a", "1")}
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	inst.ObserveInt64(exemplar.ContextWithTimestamp(context.Background(), past), 3, attrsConfig(attrs...))
+
+	inst.SnapshotAndProcess()
+	output := test.CollectScope(t, vcs.Collectors(), testSequence)
+	test.RequireEqualMetrics(
+		t,
+		output,
+		test.Instrument(
+			desc,
+			test.PointEx(
+				middleTime, endTime,
+				sum.NewMonotonicInt64(3),
+				aggregation.DeltaTemporality,
+				attrs,
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: aggregator.ExemplarBits{
+						Time:       past,
+						Number:     number.FromInt64(3),
+						Attributes: attrs,
+						Span:       trace.SpanFromContext(context.Background()),
+					},
+				},
+			),
+		),
+	)
+
+	// Without a timestamp, the exemplar has the time of the call.
+	before := time.Now()
+	inst.ObserveInt64(context.Background(), 4, attrsConfig(attrs...))
+	inst.SnapshotAndProcess()
+
+	output = test.CollectScope(t, vcs.Collectors(), testSequence)
+	require.Equal(t, 1, len(output[0].Points))
+	exs := output[0].Points[0].Exemplars
+	require.Equal(t, 1, len(exs))
+	require.False(t, exs[0].Time.Before(before))
+}
+
+// TestRateLimiter verifies that a flood of measurements is counted
+// exactly while only the calls within the limit are offered to the
+// exemplar reservoir.
+func TestRateLimiter(t *testing.T) {
+	var errsLock sync.Mutex
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errsLock.Lock()
+		defer errsLock.Unlock()
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	lib := instrumentation.Scope{
+		Name: "testlib",
+	}
+	perf := sdkinstrument.Performance{}
+	vcs := viewstate.New(lib, view.New(
+		"test",
+		perf,
+		deltaSelector,
+		view.WithClause(
+			view.WithAggregatorConfig(aggregator.Config{
+				Exemplar: aggregator.ExemplarConfig{
+					Filter:    aggregator.AlwaysOnKind,
+					Size:      1,
+					Reservoir: aggregator.BucketReservoirKind,
+				},
+			}),
+		),
+	))
+	desc := test.Descriptor(
+		"flood",
+		sdkinstrument.SyncCounter,
+		number.Int64Kind)
+
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vcs.Compile(desc)
+
+	inst := New(desc, perf, nil, pipes)
+	require.NotNil(t, inst)
+
+	var now atomic.Int64
+	now.Store(endTime.UnixNano())
+	clock := func() time.Time { return time.Unix(0, now.Load()) }
+
+	// 10 calls per second, in bursts of up to 20.
+	inst.SetRateLimiter(NewRateLimiter(10, 20, clock))
+
+	attrs := []attribute.KeyValue{attribute.String("a", "1")}
+
+	flood := func() {
+		const workers = 10
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					inst.ObserveInt64(context.Background(), 1, attrsConfig(attrs...))
+				}
+			}()
+		}
+		wg.Wait()
+		inst.SnapshotAndProcess()
+	}
+	// check returns the sum and the number of calls offered to
+	// the reservoir, which is the weight of its one exemplar.
+	check := func(total int64, offered float64) {
+		output := test.CollectScope(t, vcs.Collectors(), testSequence)
+		require.Equal(t, 1, len(output))
+		require.Equal(t, 1, len(output[0].Points))
+
+		point := output[0].Points[0]
+		require.Equal(t, total, number.ToInt64(point.Aggregation.(*exemplar.ReservoirStorage[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods]).Unwrap().(aggregation.Sum).Sum()))
+		require.Equal(t, 1, len(point.Exemplars))
+		require.Equal(t, offered, point.Exemplars[0].Weight)
+	}
+
+	// The clock is stopped, so the burst is allowed.
+	flood()
+	check(1000, 20)
+
+	// After a second, 10 more are allowed.
+	now.Add(int64(time.Second))
+	flood()
+	check(1000, 10)
+
+	// Diagnostics are rate-limited per call site, so there may be
+	// none when the test repeats.
+	for _, err := range errs {
+		require.ErrorIs(t, err, ErrRateLimited)
+	}
+}
+
+// TestRateLimiterTimestamp verifies that throttled measurements keep
+// the timestamp of their context, which applies to the aggregate.
+func TestRateLimiterTimestamp(t *testing.T) {
+	lib := instrumentation.Scope{
+		Name: "testlib",
+	}
+	perf := sdkinstrument.Performance{}
+	vcs := viewstate.New(lib, view.New(
+		"test",
+		perf,
+		deltaSelector,
+		view.WithClause(
+			view.WithAggregatorConfig(aggregator.Config{
+				HistogramExtremes: true,
+			}),
+		),
+	))
+	desc := test.Descriptor(
+		"throttled",
+		sdkinstrument.SyncHistogram,
+		number.Float64Kind)
+
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vcs.Compile(desc)
+
+	inst := New(desc, perf, nil, pipes)
+	require.NotNil(t, inst)
+
+	// The clock is stopped, so only the first call is allowed.
+	inst.SetRateLimiter(NewRateLimiter(1, 1, func() time.Time { return endTime }))
+
+	attrs := []attribute.KeyValue{attribute.String("a", "1")}
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	inst.ObserveFloat64(context.Background(), 5, attrsConfig(attrs...))
+	inst.ObserveFloat64(exemplar.ContextWithTimestamp(context.Background(), past), 1, attrsConfig(attrs...))
+	inst.SnapshotAndProcess()
+
+	output := test.CollectScope(t, vcs.Collectors(), testSequence)
+	require.Equal(t, 1, len(output))
+	require.Equal(t, 1, len(output[0].Points))
+
+	h := output[0].Points[0].Aggregation.(*histogram.Float64)
+	require.Equal(t, uint64(2), h.Count())
+	require.Equal(t, past, h.MinTime())
+}
+
+// TestCloseRelease verifies that measurements after Close are
+// ignored and that Release removes every record.
+func TestCloseRelease(t *testing.T) {
+	ctx := context.Background()
+	vc := viewstate.New(instrumentation.Scope{Name: "testlib"}, view.New("test", safePerf, deltaSelector))
+
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vc.Compile(desc)
+
+	inst := New(desc, safePerf, nil, pipes)
+	require.NotNil(t, inst)
+
+	inst.ObserveInt64(ctx, 1, attrsConfig(attribute.String("A", "a")))
+	inst.ObserveInt64(ctx, 2, attrsConfig(attribute.String("A", "b")))
+	inst.Close()
+	inst.ObserveInt64(ctx, 4, attrsConfig(attribute.String("A", "a")))
+	inst.ObserveInt64(ctx, 8, attrsConfig(attribute.String("A", "c")))
+	require.Len(t, inst.currentFP, 2)
+
+	inst.SnapshotAndProcess()
+	test.RequireEqualMetrics(
+		t,
+		test.CollectScope(t, vc.Collectors(), testSequence),
+		test.Instrument(
+			desc,
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(1), aggregation.DeltaTemporality, attribute.String("A", "a"), testKeyVal),
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(2), aggregation.DeltaTemporality, attribute.String("A", "b"), testKeyVal),
+		),
+	)
+
+	inst.Release()
+	require.Len(t, inst.currentFP, 0)
+}
+
+// TestParallelSnapshotOverflow verifies that a parallel snapshot
+// merges every accumulator of the aggregator overflow series.
+func TestParallelSnapshotOverflow(t *testing.T) {
+	const numAttrs = 1000
+	ctx := context.Background()
+	vperf := safePerf
+	vperf.AggregatorCardinalityLimit = 10
+	vc := viewstate.New(instrumentation.Scope{Name: "testlib"}, view.New("test", vperf, deltaSelector))
+
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	pipes := make(pipeline.Register[viewstate.Instrument], 1)
+	pipes[0], _ = vc.Compile(desc)
+
+	perf := safePerf
+	perf.SnapshotParallelism = 8
+	inst := New(desc, perf, nil, pipes)
+	require.NotNil(t, inst)
+
+	for i := 0; i < numAttrs; i++ {
+		inst.ObserveInt64(ctx, 1, attrsConfig(testAttr.Int(i)))
+	}
+	require.NoError(t, inst.SnapshotAndProcess())
+
+	output := test.CollectScope(t, vc.Collectors(), testSequence)
+	require.Equal(t, 1, len(output))
+	require.Equal(t, 10, len(output[0].Points))
+
+	var total int64
+	for _, point := range output[0].Points {
+		total += number.ToInt64(point.Aggregation.(aggregation.Sum).Sum())
+	}
+	require.Equal(t, int64(numAttrs), total)
+}
+
+// BenchmarkSnapshotAndProcess compares serial and parallel snapshots
+// of an instrument with 100k updated attribute sets.
+func BenchmarkSnapshotAndProcess(b *testing.B) {
+	const numAttrs = 100000
+	cfgs := make([]OpConfig, numAttrs)
+	for i := range cfgs {
+		cfgs[i] = attrsConfig(testAttr.Int(i))
+	}
+	for _, bm := range []struct {
+		name        string
+		parallelism uint32
+	}{
+		{"Serial", 0},
+		{"Parallel", uint32(runtime.GOMAXPROCS(0))},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			perf := sdkinstrument.Performance{
+				IgnoreCollisions:           true,
+				InstrumentCardinalityLimit: 2 * numAttrs,
+				AggregatorCardinalityLimit: 2 * numAttrs,
+				SnapshotParallelism:        bm.parallelism,
+			}
+			vc := viewstate.New(instrumentation.Scope{Name: "testlib"}, view.New("test", perf, cumulativeSelector))
+
+			desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+			pipes := make(pipeline.Register[viewstate.Instrument], 1)
+			pipes[0], _ = vc.Compile(desc)
+
+			inst := New(desc, perf, nil, pipes)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for _, cfg := range cfgs {
+					inst.ObserveInt64(ctx, 1, cfg)
+				}
+				b.StartTimer()
+				_ = inst.SnapshotAndProcess()
+			}
+		})
+	}
+}
+
+// TestParallelSnapshotRace runs parallel snapshots concurrently with
+// updates, with several attribute sets sharing each series, the
+// overflow series, and series kept for eviction and leak detection.
+// This is meant for the race detector.
+func TestParallelSnapshotRace(t *testing.T) {
+	const (
+		numAttrs   = 200
+		numWriters = 4
+		numRounds  = 20
+	)
+	for _, tc := range []struct {
+		name  string
+		tempo view.Option
+		opts  []view.ClauseOption
+	}{
+		{"delta", deltaSelector, nil},
+		{"cumulative", cumulativeSelector, []view.ClauseOption{view.WithSeriesBudget(20)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			vperf := safePerf
+			vperf.AggregatorCardinalityLimit = 50
+			vperf.LeakCollectionPeriods = 1
+			vperf.OnLeak = func(string, int) {}
+			vc := viewstate.New(instrumentation.Scope{Name: "testlib"}, view.New(
+				"test",
+				vperf,
+				tc.tempo,
+				view.WithClause(append([]view.ClauseOption{
+					view.WithKeys([]attribute.Key{"i"}),
+					view.WithLastUpdateTime(),
+					view.WithOverflowSeriesCount(),
+				}, tc.opts...)...),
+			))
+
+			desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+			pipes := make(pipeline.Register[viewstate.Instrument], 1)
+			pipes[0], _ = vc.Compile(desc)
+
+			perf := safePerf
+			perf.SnapshotParallelism = 8
+			inst := New(desc, perf, nil, pipes)
+			require.NotNil(t, inst)
+
+			var total atomic.Int64
+			var wg sync.WaitGroup
+			for w := 0; w < numWriters; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for r := 0; r < numRounds; r++ {
+						for i := 0; i < numAttrs; i++ {
+							// Each writer's sets share the
+							// series of key "i".
+							inst.ObserveInt64(ctx, 1, attrsConfig(attribute.Int("i", i), attribute.Int("w", w)))
+							total.Add(1)
+						}
+					}
+				}(w)
+			}
+
+			var sum int64
+			collect := func() {
+				require.NoError(t, inst.SnapshotAndProcess())
+				output := test.CollectScope(t, vc.Collectors(), testSequence)
+				for _, point := range output[0].Points {
+					sum += number.ToInt64(point.Aggregation.(aggregation.Sum).Sum())
+				}
+			}
+			for r := 0; r < numRounds; r++ {
+				collect()
+			}
+			wg.Wait()
+			collect()
+
+			if tc.name == "delta" {
+				require.Equal(t, total.Load(), sum)
+			}
+		})
+	}
+}
@@ -15,76 +15,455 @@
 package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 
 import (
+	"fmt"
+	"math/rand/v2"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/multierr"
 )
 
 // compiledSyncBase is any synchronous instrument view.
 type compiledSyncBase[N number.Any, Storage any, Methods aggregator.Methods[N, Storage], Samp SampleFilter] struct {
 	instrumentBase[N, Storage, int64, Methods]
+
+	// shards is the number of storage shards in each accumulator.
+	shards uint32
+
+	// rollup is set to record each measurement in the empty
+	// attribute set as well.
+	rollup bool
+
+	// dedup is the window in which repeated measurements are
+	// discarded, or zero.
+	dedup time.Duration
+
+	// emitEmpty is set to report series without measurements
+	// in delta temporality.
+	emitEmpty bool
+
+	// overflowSeries counts the attribute sets recorded in the
+	// overflow attribute set, when configured.
+	overflowSeries *overflowSeries
+
+	// primed holds the entries created by PreRegister, each of
+	// which holds one reference until it is taken by the first
+	// accumulator of the series.  Synchronized by instLock.
+	primed map[*storageHolder[Storage, int64]]struct{}
+
+	// leakPeriods and onLeak are Performance.LeakCollectionPeriods
+	// and Performance.OnLeak.  leaked is the number of possibly
+	// leaked series last reported, synchronized by instLock.
+	leakPeriods uint32
+	onLeak      func(instrumentName string, series int)
+	leaked      int
+
+	// eviction removes the least recently used series, when
+	// configured by view.WithSeriesBudget.
+	eviction *seriesEviction[Storage]
+}
+
+// init configures the instrument in place, see instrumentBase.init.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) init(behavior singleBehavior) {
+	c.instrumentBase.init(behavior)
+	c.shards = behavior.shards
+	c.rollup = behavior.rollup
+	c.dedup = behavior.dedup
+	c.emitEmpty = behavior.emitEmpty
+	c.leakPeriods = behavior.leakPeriods
+	c.onLeak = behavior.onLeak
+	if behavior.overflowCount {
+		c.overflowSeries = newOverflowSeries(behavior)
+	}
 }
 
 // NewAccumulator returns a Accumulator for a synchronous instrument view.
 func (c *compiledSyncBase[N, Storage, Methods, Samp]) NewAccumulator(kvs attribute.Set) Accumulator {
-	sc := &syncAccumulator[N, Storage, Methods, Samp]{}
+	return c.NewAccumulatorWithMetadata(kvs, *attribute.EmptySet())
+}
+
+// NewAccumulatorWithMetadata returns a Accumulator for a synchronous
+// instrument view, with metadata for the series.  A disabled view
+// does not locate the series until it is enabled.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) NewAccumulatorWithMetadata(kvs, metadata attribute.Set) Accumulator {
+	if c.disabled.Load() {
+		return &enablingAccumulator[N]{
+			disabled: &c.disabled,
+			create: func() Accumulator {
+				return c.enabledAccumulator(kvs, metadata)
+			},
+		}
+	}
+	return c.enabledAccumulator(kvs, metadata)
+}
+
+// enabledAccumulator returns the Accumulator of NewAccumulatorWithMetadata
+// for an enabled view.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) enabledAccumulator(kvs, metadata attribute.Set) Accumulator {
+	acc := c.newAccumulator(kvs, metadata)
+	if c.rollup {
+		if filtered := c.applyTransform(c.applyKeysFilter(kvs)); filtered.Len() != 0 {
+			// The total shares no state with the series,
+			// so each is updated exactly once per
+			// measurement.
+			acc = multiAccumulator[N]{acc, c.newAccumulator(*attribute.EmptySet(), *attribute.EmptySet())}
+		}
+	}
+	return quantizeAccumulator[N](dedupAccumulator[N](acc, c.dedup), c.quantum)
+}
+
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) newAccumulator(kvs, metadata attribute.Set) Accumulator {
+	holder, routed := c.findStorage(kvs, metadata)
+	if holder == nil {
+		return droppedAccumulator[N]{dropped: &c.dropped}
+	}
+	acc := c.newHolderAccumulator(holder)
+	if debugAccumulators {
+		debugRegistry.register(holder, acc, c.desc.Name, kvs, 1)
+	}
+	if routed.Len() == 0 || (c.overflowSeries == nil && c.onOverflow == nil) {
+		return acc
+	}
+	oa := &overflowAccumulator[N]{
+		Accumulator: acc,
+	}
+	if c.overflowSeries != nil {
+		oa.series = c.overflowSeries
+		oa.key = c.overflowSeries.key(routed)
+	}
+	if c.onOverflow != nil {
+		oa.notify = func() { c.notifyOverflow(routed) }
+	}
+	return oa
+}
+
+// newHolderAccumulator returns a syncAccumulator, or a
+// shardedSyncAccumulator when configured, that merges into holder.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) newHolderAccumulator(holder *storageHolder[Storage, int64]) Accumulator {
+	if c.shards > 1 {
+		sc := &shardedSyncAccumulator[N, Storage, Methods, Samp]{
+			shards:   make([]accumulatorShard[Storage], c.shards),
+			disabled: &c.disabled,
+		}
+		for i := range sc.shards {
+			c.initStorage(&sc.shards[i].current)
+		}
+		c.initStorage(&sc.snapshot)
+
+		sc.holder = holder
+		return sc
+	}
+	sc := &syncAccumulator[N, Storage, Methods, Samp]{
+		disabled: &c.disabled,
+	}
 	c.initStorage(&sc.current)
 	c.initStorage(&sc.snapshot)
 
-	sc.holder = c.findStorage(kvs)
+	sc.holder = holder
 	return sc
 }
 
 // findStorage locates the output Storage and adds to the auxiliary
-// reference count for synchronous instruments.
+// reference count for synchronous instruments.  When the attribute
+// set is recorded in the overflow attribute set instead, it is
+// returned as routed, otherwise routed is empty.
 func (c *compiledSyncBase[N, Storage, Methods, Samp]) findStorage(
-	kvs attribute.Set,
-) *storageHolder[Storage, int64] {
-	kvs = c.applyKeysFilter(kvs)
+	kvs, metadata attribute.Set,
+) (_ *storageHolder[Storage, int64], routed attribute.Set) {
+	kvs = c.normalize.Normalize(c.applyTransform(c.applyKeysFilter(kvs)))
 
 	c.instLock.Lock()
 	defer c.instLock.Unlock()
 
+	kvs = c.values.apply(kvs)
+	created := c.eviction != nil && c.eviction.makeRoom(c.data, kvs)
 	entry := c.getOrCreateEntry(kvs)
+	c.mergeMetadata(kvs, entry, metadata)
+	if entry == nil {
+		return nil, routed
+	}
+	if created && c.data[kvs] == entry {
+		c.eviction.created(kvs)
+	}
+	if kvs != c.overflow && c.data[kvs] != entry {
+		routed = kvs
+	}
+	if _, has := c.primed[entry]; has {
+		// Take the reference of PreRegister.
+		delete(c.primed, entry)
+	} else {
+		atomic.AddInt64(&entry.auxiliary, 1)
+	}
+	return entry, routed
+}
+
+// PreRegister creates the storage for each attribute set, and for
+// the total when rolling up, before the first accumulator.  Each
+// entry holds a reference, so it is not removed by delta
+// temporality before it is used.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) PreRegister(sets ...attribute.Set) {
+	for _, kvs := range sets {
+		filtered := c.applyTransform(c.applyKeysFilter(kvs))
+		c.preRegister(c.normalize.Normalize(filtered))
+		if c.rollup && filtered.Len() != 0 {
+			c.preRegister(*attribute.EmptySet())
+		}
+	}
+}
+
+// preRegister adds the reference of PreRegister to the entry for a
+// filtered attribute set, unless it already has one.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) preRegister(kvs attribute.Set) {
+	c.instLock.Lock()
+	defer c.instLock.Unlock()
+
+	entry := c.getOrCreateEntry(c.values.apply(kvs))
+	if entry == nil {
+		return
+	}
+	if _, has := c.primed[entry]; has {
+		return
+	}
+	if c.primed == nil {
+		c.primed = map[*storageHolder[Storage, int64]]struct{}{}
+	}
+	c.primed[entry] = struct{}{}
 	atomic.AddInt64(&entry.auxiliary, 1)
-	return entry
+}
+
+// Reset removes the series that have no accumulator references and
+// empties the others, since their accumulators will merge into the
+// same storage.  The reference counts are not modified, so the
+// accumulators release their references as usual.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) Reset() {
+	var methods Methods
+
+	c.instLock.Lock()
+	defer c.instLock.Unlock()
+
+	// References are only added with the lock held, so an entry
+	// without references cannot gain one before it is removed.
+	var discard *Storage
+	for set, entry := range c.data {
+		if atomic.LoadInt64(&entry.auxiliary) == 0 {
+			delete(c.data, set)
+			continue
+		}
+		if discard == nil {
+			discard = c.newStorage()
+		}
+		methods.Move(&entry.storage, discard)
+	}
+	c.dropped.Store(0)
+	if c.overflowSeries != nil {
+		c.overflowSeries.reset()
+	}
+}
+
+// ResetSeries has no effect on synchronous views without cumulative
+// state, see statefulSyncInstrument.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) ResetSeries(_ attribute.Set, _ time.Time) {
 }
 
 // compiledAsyncBase is any asynchronous instrument view.
 type compiledAsyncBase[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
 	instrumentBase[N, Storage, notUsed, Methods]
+
+	// generation counts the replacements of the data map by
+	// collection and Reset, synchronized by the instrument lock.
+	// Accumulators holding a series of an earlier generation
+	// find it again in the current map when they snapshot.
+	generation uint64
+}
+
+// init configures the instrument in place, see instrumentBase.init.
+func (c *compiledAsyncBase[N, Storage, Methods]) init(behavior singleBehavior) {
+	c.instrumentBase.init(behavior)
 }
 
 // NewAccumulator returns a Accumulator for an asynchronous instrument view.
 func (c *compiledAsyncBase[N, Storage, Methods]) NewAccumulator(kvs attribute.Set) Accumulator {
-	ac := &asyncAccumulator[N, Storage, Methods]{}
+	return c.NewAccumulatorWithMetadata(kvs, *attribute.EmptySet())
+}
 
-	ac.holder = c.findStorage(kvs)
-	return ac
+// NewAccumulatorWithMetadata returns a Accumulator for an
+// asynchronous instrument view, with metadata for the series.
+func (c *compiledAsyncBase[N, Storage, Methods]) NewAccumulatorWithMetadata(kvs, metadata attribute.Set) Accumulator {
+	if c.disabled.Load() {
+		return disabledAccumulator[N]{}
+	}
+	ac := &asyncAccumulator[N, Storage, Methods]{
+		inst:     c,
+		metadata: metadata,
+	}
+	if !c.findStorage(ac, kvs) {
+		return droppedAccumulator[N]{dropped: &c.dropped}
+	}
+	return quantizeAccumulator[N](ac, c.quantum)
 }
 
-// findStorage locates the output Storage for asynchronous instruments.
+// findStorage locates the output Storage for asynchronous
+// instruments, setting the holder, attribute set, and generation of
+// the accumulator.  Returns false when the attribute set is dropped.
 func (c *compiledAsyncBase[N, Storage, Methods]) findStorage(
-	kvs attribute.Set,
-) *storageHolder[Storage, notUsed] {
-	kvs = c.applyKeysFilter(kvs)
+	ac *asyncAccumulator[N, Storage, Methods], kvs attribute.Set,
+) bool {
+	kvs = c.normalize.Normalize(c.applyTransform(c.applyKeysFilter(kvs)))
 
+	c.instLock.Lock()
+	ac.kvs = c.values.apply(kvs)
+	routed := ac.resolve()
+	c.instLock.Unlock()
+
+	if routed {
+		c.notifyOverflow(ac.kvs)
+	}
+	return ac.holder != nil
+}
+
+// replaceData starts a new data map and generation.  The caller
+// holds the instrument lock.
+func (c *compiledAsyncBase[N, Storage, Methods]) replaceData() {
+	c.data = map[attribute.Set]*storageHolder[Storage, notUsed]{}
+	c.generation++
+}
+
+// Reset removes every series.  Asynchronous accumulators are created
+// for each collection; those in progress find their series again in
+// the new data map.
+func (c *compiledAsyncBase[N, Storage, Methods]) Reset() {
 	c.instLock.Lock()
 	defer c.instLock.Unlock()
 
-	return c.getOrCreateEntry(kvs)
+	c.replaceData()
+	c.dropped.Store(0)
+}
+
+// ResetSeries has no effect on asynchronous views, which observe
+// cumulative values directly.
+func (c *compiledAsyncBase[N, Storage, Methods]) ResetSeries(_ attribute.Set, _ time.Time) {
+}
+
+// PreRegister has no effect on asynchronous views, since every
+// series observed in a collection is new to it.
+func (c *compiledAsyncBase[N, Storage, Methods]) PreRegister(_ ...attribute.Set) {
+}
+
+// droppedAccumulator is returned for attribute sets that are dropped
+// because the cardinality limit was reached with overflow disabled.
+// It counts the measurements dropped in the instrument.
+type droppedAccumulator[N number.Any] struct {
+	dropped *atomic.Uint64
+}
+
+func (droppedAccumulator[N]) SnapshotAndProcess(_ bool) error {
+	return nil
+}
+
+func (d droppedAccumulator[N]) Update(_ N, _ aggregator.ExemplarBits) {
+	d.dropped.Add(1)
+}
+
+func (d droppedAccumulator[N]) UpdateBatch(_ N, count uint64, _ aggregator.ExemplarBits) {
+	d.dropped.Add(count)
+}
+
+func (droppedAccumulator[N]) MaySample(_ bool) bool {
+	return false
+}
+
+// disabledAccumulator is returned by asynchronous instruments
+// disabled by SetEnabled.  Unlike droppedAccumulator, it does not
+// count the measurements it discards.  Synchronous accumulators
+// outlive a collection, so they test the flag on each measurement
+// instead.
+type disabledAccumulator[N number.Any] struct{}
+
+func (disabledAccumulator[N]) SnapshotAndProcess(_ bool) error {
+	return nil
+}
+
+func (disabledAccumulator[N]) Update(_ N, _ aggregator.ExemplarBits) {
+}
+
+func (disabledAccumulator[N]) UpdateBatch(_ N, _ uint64, _ aggregator.ExemplarBits) {
+}
+
+func (disabledAccumulator[N]) MaySample(_ bool) bool {
+	return false
+}
+
+// enablingAccumulator is returned by synchronous views while they
+// are disabled.  Synchronous accumulators are kept for as long as
+// their series is in use, so this one creates the accumulator of the
+// series on the first measurement after the view is enabled.
+type enablingAccumulator[N number.Any] struct {
+	disabled *atomic.Bool
+	create   func() Accumulator
+
+	lock sync.Mutex
+	acc  atomic.Pointer[Accumulator]
+}
+
+// live returns the accumulator of the series, or nil while the view
+// is disabled and it has not been created.
+func (a *enablingAccumulator[N]) live() Accumulator {
+	if acc := a.acc.Load(); acc != nil {
+		return *acc
+	}
+	if a.disabled.Load() {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if acc := a.acc.Load(); acc != nil {
+		return *acc
+	}
+	acc := a.create()
+	a.acc.Store(&acc)
+	return acc
+}
+
+func (a *enablingAccumulator[N]) SnapshotAndProcess(release bool) error {
+	if acc := a.acc.Load(); acc != nil {
+		return (*acc).SnapshotAndProcess(release)
+	}
+	return nil
+}
+
+func (a *enablingAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
+	if acc := a.live(); acc != nil {
+		acc.(Updater[N]).Update(value, ex)
+	}
+}
+
+func (a *enablingAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	if acc := a.live(); acc != nil {
+		acc.(Updater[N]).UpdateBatch(value, count, ex)
+	}
+}
+
+func (a *enablingAccumulator[N]) MaySample(isTraced bool) bool {
+	acc := a.live()
+	return acc != nil && acc.(Updater[N]).MaySample(isTraced)
 }
 
 // multiAccumulator
 type multiAccumulator[N number.Any] []Accumulator
 
-func (a multiAccumulator[N]) SnapshotAndProcess(release bool) {
+// SnapshotAndProcess processes every accumulator, returning the
+// combined errors.
+func (a multiAccumulator[N]) SnapshotAndProcess(release bool) error {
+	var err error
 	for _, coll := range a {
-		coll.SnapshotAndProcess(release)
+		err = multierr.Append(err, coll.SnapshotAndProcess(release))
 	}
+	return err
 }
 
 func (a multiAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
@@ -93,6 +472,12 @@ func (a multiAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
 	}
 }
 
+func (a multiAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	for _, coll := range a {
+		coll.(Updater[N]).UpdateBatch(value, count, ex)
+	}
+}
+
 func (a multiAccumulator[N]) MaySample(isTraced bool) bool {
 	for _, coll := range a {
 		if coll.(Updater[N]).MaySample(isTraced) {
@@ -110,51 +495,237 @@ type syncAccumulator[N number.Any, Storage any, Methods aggregator.Methods[N, St
 	current  Storage
 	snapshot Storage
 	holder   *storageHolder[Storage, int64]
+
+	// disabled is the instrument's flag set by SetEnabled.
+	disabled *atomic.Bool
 }
 
 func (a *syncAccumulator[N, Storage, Methods, Samp]) Update(number N, ex aggregator.ExemplarBits) {
+	if a.disabled.Load() {
+		return
+	}
 	var methods Methods
 	methods.Update(&a.current, number, ex)
+	a.holder.touch(ex.Time)
+}
+
+func (a *syncAccumulator[N, Storage, Methods, Samp]) UpdateBatch(number N, count uint64, ex aggregator.ExemplarBits) {
+	if a.disabled.Load() {
+		return
+	}
+	var methods Methods
+	methods.UpdateBatch(&a.current, number, count, ex)
+	a.holder.touch(ex.Time)
 }
 
 func (a *syncAccumulator[N, Storage, Methods, Samp]) MaySample(isTraced bool) bool {
 	var samp Samp
-	return samp.MaySample(isTraced)
+	return !a.disabled.Load() && samp.MaySample(isTraced)
 }
 
-func (a *syncAccumulator[N, Storage, Methods, Samp]) SnapshotAndProcess(release bool) {
+func (a *syncAccumulator[N, Storage, Methods, Samp]) SnapshotAndProcess(release bool) (err error) {
 	var methods Methods
 	a.syncLock.Lock()
 	defer a.syncLock.Unlock()
+	if release {
+		// On the final snapshot-and-process, decrement the auxiliary reference count.
+		defer atomic.AddInt64(&a.holder.auxiliary, -1)
+		if debugAccumulators {
+			debugRegistry.release(a.holder, a)
+		}
+	}
+	defer recoverMerge(&err)
 	methods.Move(&a.current, &a.snapshot)
-	methods.Merge(&a.snapshot, &a.holder.storage)
+	a.holder.merge(&a.snapshot, methods.Merge)
+	return nil
+}
+
+// shardedSyncAccumulator is a syncAccumulator with multiple current
+// Storage shards, to reduce contention between concurrent updates to
+// the same series.  Each Update lands in one shard; the shards are
+// merged into the holder in SnapshotAndProcess.
+type shardedSyncAccumulator[N number.Any, Storage any, Methods aggregator.Methods[N, Storage], Samp SampleFilter] struct {
+	// syncLock prevents two readers from calling
+	// SnapshotAndProcess at the same moment.
+	syncLock sync.Mutex
+	shards   []accumulatorShard[Storage]
+	snapshot Storage
+	holder   *storageHolder[Storage, int64]
+	disabled *atomic.Bool
+}
+
+// accumulatorShard is one current Storage, padded to avoid false
+// sharing with adjacent shards.
+type accumulatorShard[Storage any] struct {
+	current Storage
+	_       [64]byte
+}
+
+func (a *shardedSyncAccumulator[N, Storage, Methods, Samp]) Update(number N, ex aggregator.ExemplarBits) {
+	if a.disabled.Load() {
+		return
+	}
+	var methods Methods
+	methods.Update(&a.shards[rand.IntN(len(a.shards))].current, number, ex)
+	a.holder.touch(ex.Time)
+}
+
+func (a *shardedSyncAccumulator[N, Storage, Methods, Samp]) UpdateBatch(number N, count uint64, ex aggregator.ExemplarBits) {
+	if a.disabled.Load() {
+		return
+	}
+	var methods Methods
+	methods.UpdateBatch(&a.shards[rand.IntN(len(a.shards))].current, number, count, ex)
+	a.holder.touch(ex.Time)
+}
+
+func (a *shardedSyncAccumulator[N, Storage, Methods, Samp]) MaySample(isTraced bool) bool {
+	var samp Samp
+	return !a.disabled.Load() && samp.MaySample(isTraced)
+}
+
+func (a *shardedSyncAccumulator[N, Storage, Methods, Samp]) SnapshotAndProcess(release bool) (err error) {
+	a.syncLock.Lock()
+	defer a.syncLock.Unlock()
 	if release {
 		// On the final snapshot-and-process, decrement the auxiliary reference count.
-		atomic.AddInt64(&a.holder.auxiliary, -1)
+		defer atomic.AddInt64(&a.holder.auxiliary, -1)
+		if debugAccumulators {
+			debugRegistry.release(a.holder, a)
+		}
+	}
+	// A failed shard does not prevent merging the others.
+	for i := range a.shards {
+		err = multierr.Append(err, a.mergeShard(&a.shards[i].current))
 	}
+	return err
+}
+
+// mergeShard moves one shard into the snapshot and merges it into the
+// holder.  Called with syncLock held.
+func (a *shardedSyncAccumulator[N, Storage, Methods, Samp]) mergeShard(current *Storage) (err error) {
+	var methods Methods
+	defer recoverMerge(&err)
+	methods.Move(current, &a.snapshot)
+	a.holder.merge(&a.snapshot, methods.Merge)
+	return nil
 }
 
 // asyncAccumulator
 type asyncAccumulator[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
 	asyncLock sync.Mutex
 	current   N
-	holder    *storageHolder[Storage, notUsed]
+
+	// inst, kvs, and metadata locate the series, which is
+	// holder unless the generation of inst has changed.  The
+	// holder and generation are synchronized by the instrument
+	// lock.
+	inst       *compiledAsyncBase[N, Storage, Methods]
+	kvs        attribute.Set
+	metadata   attribute.Set
+	holder     *storageHolder[Storage, notUsed]
+	generation uint64
+
+	// time is the event time of the current value, which
+	// orders the values of gauges configured with
+	// aggregator.Config.GaugeTimestamps.
+	time time.Time
+
+	// count is the number of observations since the last
+	// snapshot, which gauges count.
+	count uint64
+}
+
+// resolve finds the holder of the series in the current data map,
+// returning true when the attribute set was routed to the overflow
+// set.  The caller holds the instrument lock.
+func (a *asyncAccumulator[N, Storage, Methods]) resolve() bool {
+	c := a.inst
+	entry := c.getOrCreateEntry(a.kvs)
+	c.mergeMetadata(a.kvs, entry, a.metadata)
+	a.holder = entry
+	a.generation = c.generation
+	return entry != nil && a.kvs != c.overflow && c.data[a.kvs] != entry
 }
 
 func (a *asyncAccumulator[N, Storage, Methods]) Update(number N, ex aggregator.ExemplarBits) {
 	a.asyncLock.Lock()
 	defer a.asyncLock.Unlock()
 	a.current = number
+	a.time = ex.Time
+	a.count++
+	a.holder.touch(ex.Time)
+}
+
+// UpdateBatch captures the value once, since the last value is
+// captured, and counts count observations.
+func (a *asyncAccumulator[N, Storage, Methods]) UpdateBatch(number N, count uint64, ex aggregator.ExemplarBits) {
+	if count == 0 {
+		return
+	}
+	a.asyncLock.Lock()
+	defer a.asyncLock.Unlock()
+	a.current = number
+	a.time = ex.Time
+	a.count += count
+	a.holder.touch(ex.Time)
 }
 
 func (a *asyncAccumulator[N, Storage, Methods]) MaySample(isTraced bool) bool {
 	return false
 }
 
-func (a *asyncAccumulator[N, Storage, Methods]) SnapshotAndProcess(_ bool) {
+// SnapshotAndProcess updates the series with the current value.  A
+// collection or Reset that replaced the data map since the series
+// was found would drop the value in a holder no longer reachable, so
+// the series is found again in the current map.  The update holds
+// the instrument lock, so that it completes before the next
+// collection or after it, in which case the value belongs to the
+// following collection.
+func (a *asyncAccumulator[N, Storage, Methods]) SnapshotAndProcess(_ bool) (err error) {
 	a.asyncLock.Lock()
 	defer a.asyncLock.Unlock()
+	defer recoverMerge(&err)
+
+	if a.process() {
+		a.inst.notifyOverflow(a.kvs)
+	}
+	a.count = 0
+	return nil
+}
+
+// process updates the holder of the series, returning true when it
+// was found again and routed to the overflow set.
+func (a *asyncAccumulator[N, Storage, Methods]) process() (routed bool) {
+	a.inst.instLock.Lock()
+	defer a.inst.instLock.Unlock()
+
+	if a.generation != a.inst.generation {
+		if routed = a.resolve(); a.holder == nil {
+			// The cardinality limit was reached in
+			// the new data map with overflow disabled.
+			a.inst.dropped.Add(max(a.count, 1))
+			return false
+		}
+		a.holder.touch(a.time)
+	}
 
 	var methods Methods
-	methods.Update(&a.holder.storage, a.current, aggregator.ExemplarBits{})
+	ex := aggregator.ExemplarBits{Time: a.time}
+	if methods.Kind() == aggregation.GaugeKind {
+		// Gauges count the observations since the last
+		// snapshot, which are at least one.
+		methods.UpdateBatch(&a.holder.storage, a.current, max(a.count, 1), ex)
+	} else {
+		methods.Update(&a.holder.storage, a.current, ex)
+	}
+	return routed
+}
+
+// recoverMerge is deferred by SnapshotAndProcess to return an error
+// wrapping aggregator.ErrMergeFailed when the aggregator panics.
+func recoverMerge(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", aggregator.ErrMergeFailed, r)
+	}
 }
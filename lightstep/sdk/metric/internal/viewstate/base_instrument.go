@@ -15,23 +15,25 @@
 package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
-	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 )
 
-var overflowAttributeSet = pipeline.OverflowAttributeSet
-
 var errInternalOverflowError = fmt.Errorf("internal overflow error condition")
 
 // storageHolder is a generic struct for holding one storage and one
@@ -43,6 +45,60 @@ var errInternalOverflowError = fmt.Errorf("internal overflow error condition")
 type storageHolder[Storage, Auxiliary any] struct {
 	auxiliary Auxiliary
 	storage   Storage
+
+	// mergeLock serializes the accumulators merging into storage,
+	// which may snapshot concurrently.
+	mergeLock sync.Mutex
+
+	// metadata is the non-identifying metadata of the series,
+	// synchronized by the instrument lock.
+	metadata attribute.Set
+
+	// lastUpdate is the time of the latest measurement in Unix
+	// nanoseconds, allocated only when the view records it.
+	lastUpdate *int64
+
+	// periods counts consecutive collections of synchronous
+	// entries: for delta temporality, those in which the entry
+	// had references and no change, for
+	// Performance.LeakCollectionPeriods; for cumulative
+	// temporality, those in which it had no references, for
+	// view.WithSeriesBudget.  For asynchronous delta
+	// temporality, it counts the collections without an
+	// observation, for Performance.InactiveCollectionPeriods.
+	// Synchronized by the instrument lock.
+	periods uint32
+}
+
+// touch records the time of a measurement, when configured.  A zero
+// time is taken to mean now.
+func (h *storageHolder[Storage, Auxiliary]) touch(when time.Time) {
+	if h.lastUpdate == nil {
+		return
+	}
+	if when.IsZero() {
+		when = time.Now()
+	}
+	atomic.StoreInt64(h.lastUpdate, when.UnixNano())
+}
+
+// updated returns the time of the latest measurement, or the zero
+// time when not configured or without measurements.
+func (h *storageHolder[Storage, Auxiliary]) updated() time.Time {
+	if h.lastUpdate == nil {
+		return time.Time{}
+	}
+	if ns := atomic.LoadInt64(h.lastUpdate); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// merge merges from into the storage with mergeLock held.
+func (h *storageHolder[Storage, Auxiliary]) merge(from *Storage, merge func(from, to *Storage)) {
+	h.mergeLock.Lock()
+	defer h.mergeLock.Unlock()
+	merge(from, &h.storage)
 }
 
 // notUsed is the Auxiliary type for asynchronous instruments.
@@ -58,6 +114,50 @@ type instrumentBase[N number.Any, Storage, Auxiliary any, Methods aggregator.Met
 
 	keysSet    *attribute.Set
 	keysFilter *attribute.Filter
+	overflow   attribute.Set
+	transform  view.AttributeTransformFunction
+	normalize  view.NormalizationRules
+	values     *valueLimiter
+	quantum    float64
+
+	// dropped counts the measurements of attribute sets dropped
+	// because overflow is disabled.
+	dropped atomic.Uint64
+
+	// lastUpdate is set to record the time of each series'
+	// latest measurement.
+	lastUpdate bool
+
+	// onOverflow is Performance.OnOverflow, called when
+	// overflowed is first set in a collection interval.
+	onOverflow func(instrumentName string, set attribute.Set)
+	overflowed atomic.Bool
+
+	// interner is Performance.AttributeInterner, which
+	// canonicalizes the attribute set of each new entry.
+	interner *sdkinstrument.AttributeInterner
+
+	// disabled is set by SetEnabled(false), after which
+	// measurements are discarded.
+	disabled atomic.Bool
+}
+
+// init configures the instrument in place, since it contains a lock.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) init(behavior singleBehavior) {
+	metric.fromName = behavior.fromName
+	metric.desc = behavior.desc
+	metric.acfg = behavior.acfg
+	metric.data = map[attribute.Set]*storageHolder[Storage, Auxiliary]{}
+	metric.keysSet = behavior.keysSet
+	metric.keysFilter = behavior.keysFilter
+	metric.overflow = behavior.overflow
+	metric.transform = behavior.transform
+	metric.normalize = behavior.normalize
+	metric.values = newValueLimiter(behavior.valueLimit)
+	metric.quantum = validQuantum(behavior.quantum)
+	metric.lastUpdate = behavior.lastUpdate
+	metric.onOverflow = behavior.onOverflow
+	metric.interner = behavior.interner
 }
 
 // InMemorySize reports the size of the data map.
@@ -67,6 +167,44 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) InMemorySize() int
 	return len(metric.data)
 }
 
+// InMemoryBytes estimates the memory of the data map.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) InMemoryBytes() int {
+	metric.instLock.Lock()
+	defer metric.instLock.Unlock()
+	return metric.sizeOf(metric.data)
+}
+
+// SetEnabled enables or disables the instrument.  The flag is read
+// without locking by each measurement.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) SetEnabled(enabled bool) {
+	metric.disabled.Store(!enabled)
+}
+
+// seriesBytes is the memory of a map entry apart from its holder, and
+// attributeBytes is the memory of one attribute.
+const (
+	seriesBytes    = int(unsafe.Sizeof(attribute.Set{}) + unsafe.Sizeof(uintptr(0)))
+	attributeBytes = int(unsafe.Sizeof(attribute.KeyValue{}))
+)
+
+// sizeOf estimates the memory of a map of series in bytes, counting
+// the holder, the aggregator storage, and the attributes of each.
+// The caller holds the instrument lock.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) sizeOf(series map[attribute.Set]*storageHolder[Storage, Auxiliary]) int {
+	var methods Methods
+
+	size := 0
+	for set, entry := range series {
+		size += seriesBytes + int(unsafe.Sizeof(*entry)-unsafe.Sizeof(entry.storage))
+		size += methods.SizeOf(&entry.storage)
+		size += (set.Len() + entry.metadata.Len()) * attributeBytes
+		if entry.lastUpdate != nil {
+			size += int(unsafe.Sizeof(*entry.lastUpdate))
+		}
+	}
+	return size
+}
+
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) Aggregation() aggregation.Kind {
 	var methods Methods
 	return methods.Kind()
@@ -115,6 +253,12 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) invalidAttributeFi
 }
 
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) applyKeysFilter(kvs attribute.Set) attribute.Set {
+	// Normalized keys are filtered, so that the filter names the
+	// canonical keys.
+	if metric.normalize&view.NormalizeKeys != 0 {
+		kvs = metric.normalize.Normalize(kvs)
+	}
+
 	invalidFilter := false
 	for iter := kvs.Iter(); iter.Next(); {
 		kv := iter.Attribute()
@@ -139,6 +283,18 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) applyKeysFilter(kv
 	return res
 }
 
+// applyTransform applies the configured attribute transformation, if
+// any, to a filtered attribute set.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) applyTransform(kvs attribute.Set) attribute.Set {
+	if metric.transform == nil {
+		return kvs
+	}
+	return metric.transform(kvs)
+}
+
+// getOrCreateEntry returns the entry for an attribute set, creating
+// it if necessary.  Returns nil when the attribute set is dropped
+// because the cardinality limit was reached with overflow disabled.
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) getOrCreateEntry(kvs attribute.Set) *storageHolder[Storage, Auxiliary] {
 	entry, has := metric.data[kvs]
 	if has {
@@ -149,10 +305,19 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) getOrCreateEntry(k
 	sz := len(metric.data)
 	lim := int(metric.acfg.CardinalityLimit)
 
-	if sz == lim {
+	if metric.acfg.DisableOverflow || metric.acfg.DropOverflow {
+		if sz >= lim {
+			if metric.acfg.DisableOverflow {
+				doevery.TimePeriod(time.Minute, func() {
+					otel.Handle(fmt.Errorf("%s: limit %d: %w", metric.desc.Name, lim, aggregator.ErrCardinalityLimitExceeded))
+				})
+			}
+			return nil
+		}
+	} else if sz == lim {
 		// Second lookup is required and it *must* succeed or
 		// there is an internal error condition.
-		if entry, has = metric.data[overflowAttributeSet]; has {
+		if entry, has = metric.data[metric.overflow]; has {
 			return entry
 		}
 		// The boundary condtions in the branch below ensures
@@ -167,10 +332,10 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) getOrCreateEntry(k
 		// exists, allow this attribute set to be created,
 		// otherwise force creation of the overflow attribute
 		// set.
-		if kvs != overflowAttributeSet {
-			if _, overflowed := metric.data[overflowAttributeSet]; !overflowed {
+		if kvs != metric.overflow {
+			if _, overflowed := metric.data[metric.overflow]; !overflowed {
 				// First overflow event.
-				kvs = overflowAttributeSet
+				kvs = metric.overflow
 			}
 			// If not overflowed, the overflow aggregator
 			// already exists, means we're creating the
@@ -182,10 +347,42 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) getOrCreateEntry(k
 	var methods Methods
 	entry = &storageHolder[Storage, Auxiliary]{}
 	methods.Init(&entry.storage, metric.acfg)
-	metric.data[kvs] = entry
+	if metric.lastUpdate {
+		entry.lastUpdate = new(int64)
+	}
+	metric.data[metric.interner.Intern(kvs)] = entry
 	return entry
 }
 
+// notifyOverflow calls onOverflow for an attribute set recorded in
+// the overflow attribute set, unless it was called in this collection
+// interval.  Called without the instrument lock.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) notifyOverflow(kvs attribute.Set) {
+	if metric.onOverflow != nil && !metric.overflowed.Load() && !metric.overflowed.Swap(true) {
+		metric.onOverflow(metric.desc.Name, kvs)
+	}
+}
+
+// mergeMetadata adds metadata to the series entry found for kvs,
+// unless the entry is for a different attribute set (i.e., the
+// overflow set).  Where the series already has metadata for a key,
+// the existing value is kept, so the first accumulator to provide a
+// key determines its value.  Called with the instrument lock held.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) mergeMetadata(kvs attribute.Set, entry *storageHolder[Storage, Auxiliary], metadata attribute.Set) {
+	if metadata.Len() == 0 || entry == nil || metric.data[kvs] != entry {
+		return
+	}
+	if entry.metadata.Len() == 0 {
+		entry.metadata = metadata
+		return
+	}
+	merged := make([]attribute.KeyValue, 0, entry.metadata.Len()+metadata.Len())
+	for iter := attribute.NewMergeIterator(&entry.metadata, &metadata); iter.Next(); {
+		merged = append(merged, iter.Attribute())
+	}
+	entry.metadata = attribute.NewSet(merged...)
+}
+
 // newStorage allocates and initializes a new Storage.
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) newStorage() *Storage {
 	ns := new(Storage)
@@ -202,6 +399,7 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) newStorage() *Stor
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendInstrument(output *[]data.Instrument) *data.Instrument {
 	inst := data.ReallocateFrom(output)
 	inst.Descriptor = metric.desc
+	inst.DroppedMeasurements = metric.dropped.Load()
 	return inst
 }
 
@@ -211,7 +409,7 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendInstrument(o
 // Move() or Copy() is used.  Note that both Move and Copy are
 // synchronized with respect to Update() and Merge(), necessary for the
 // synchronous code path which may see concurrent collection.
-func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendPoint(inst *data.Instrument, set attribute.Set, storage *Storage, tempo aggregation.Temporality, start, end time.Time, reset bool) {
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendPoint(inst *data.Instrument, set, metadata attribute.Set, updated time.Time, storage *Storage, tempo aggregation.Temporality, start, end time.Time, reset bool) {
 	var methods Methods
 
 	// Possibly re-use the underlying storage.
@@ -229,13 +427,128 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendPoint(inst *
 	}
 
 	point.Attributes = set
+	point.Metadata = metadata
 	point.Aggregation = methods.ToAggregation(out)
 	point.Temporality = tempo
 	point.Start = start
 	point.End = end
+	point.Observed = observedTime(point.Aggregation)
+	point.Observations = observationCount(point.Aggregation)
+	point.LastUpdate = updated
 	point.Exemplars = methods.Exemplars(out, point.Exemplars)
 }
 
+// emitPoints passes the points in inst to emit, when set, then
+// truncates them so that the next appendPoint re-uses their storage.
+func emitPoints(inst *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point)) {
+	if emit == nil {
+		return
+	}
+	for _, pt := range inst.Points {
+		emit(inst.Descriptor, pt)
+	}
+	inst.Points = inst.Points[:0]
+}
+
+// deadlineSeries is the number of series collected between tests of
+// the context passed to CollectContext.
+const deadlineSeries = 256
+
+// collectDeadline stops a collection by CollectContext once its
+// context is done.  The context is tested before the first series
+// and then once every deadlineSeries series.  A nil collectDeadline
+// never stops.
+type collectDeadline struct {
+	ctx    context.Context
+	series int
+	err    error
+}
+
+// newCollectDeadline returns a collectDeadline for the context.
+func newCollectDeadline(ctx context.Context) *collectDeadline {
+	return &collectDeadline{ctx: ctx}
+}
+
+// stop returns true when the collection should stop before the next
+// series.
+func (d *collectDeadline) stop() bool {
+	if d == nil {
+		return false
+	}
+	if d.err == nil && d.series%deadlineSeries == 0 {
+		d.err = d.ctx.Err()
+	}
+	d.series++
+	return d.err != nil
+}
+
+// error returns the error of a stopped collection, or nil.
+func (d *collectDeadline) error() error {
+	if d == nil {
+		return nil
+	}
+	return d.err
+}
+
+// carriedStarts is the start of each delta series not reached by a
+// stopped collection, which the next collection reports in place of
+// the last collection time.  It is allocated on first use and
+// synchronized by the instrument lock.
+type carriedStarts map[attribute.Set]time.Time
+
+// carry records start for the series, unless a start was already
+// carried by an earlier stopped collection.
+func (c *carriedStarts) carry(set attribute.Set, start time.Time) {
+	if *c == nil {
+		*c = carriedStarts{}
+	}
+	if _, has := (*c)[set]; !has {
+		(*c)[set] = start
+	}
+}
+
+// start returns the carried start of the series, else def.
+func (c carriedStarts) start(set attribute.Set, def time.Time) time.Time {
+	if start, has := c[set]; has {
+		return start
+	}
+	return def
+}
+
+// take returns the carried start of the series, else def, and
+// forgets it.
+func (c carriedStarts) take(set attribute.Set, def time.Time) time.Time {
+	if start, has := c[set]; has {
+		delete(c, set)
+		return start
+	}
+	return def
+}
+
+// observedTime returns the time of a gauge configured with
+// aggregator.Config.GaugeTimestamps, otherwise the zero time.
+func observedTime(agg aggregation.Aggregation) time.Time {
+	if unwr, ok := agg.(exemplar.Unwrapper); ok {
+		agg = unwr.Unwrap()
+	}
+	if timed, ok := agg.(interface{ Time() time.Time }); ok {
+		return timed.Time()
+	}
+	return time.Time{}
+}
+
+// observationCount returns the count of a gauge configured with
+// aggregator.Config.GaugeCounts, otherwise zero.
+func observationCount(agg aggregation.Aggregation) uint64 {
+	if unwr, ok := agg.(exemplar.Unwrapper); ok {
+		agg = unwr.Unwrap()
+	}
+	if counted, ok := agg.(aggregation.GaugeCount); ok {
+		return counted.Count()
+	}
+	return 0
+}
+
 // appendOrReusePoint is an alternate to appendPoint; this form is used when
 // the storage will be reset on collection.
 func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendOrReusePoint(inst *data.Instrument) (*data.Point, *Storage) {
@@ -247,3 +560,93 @@ func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) appendOrReusePoint
 	}
 	return point, nil
 }
+
+// storageOf returns the storage of a point output by this
+// instrument, or an error wrapping aggregator.ErrAggregationKindMismatch.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) storageOf(point *data.Point) (*Storage, error) {
+	var methods Methods
+	if s, ok := methods.ToStorage(point.Aggregation); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s: %w: %T", metric.desc.Name, aggregator.ErrAggregationKindMismatch, point.Aggregation)
+}
+
+// Fold reduces the points of inst, which were output by this
+// instrument, to at most limit (and at least one) by merging all but
+// the first limit-1 into the point with the overflow attribute set,
+// which is added if necessary.  The folded points keep their sum or
+// count in the overflow point, so no data is lost.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) Fold(inst *data.Instrument, limit int) {
+	var methods Methods
+
+	points := inst.Points
+	if limit < 1 {
+		limit = 1
+	}
+	if len(points) <= limit {
+		return
+	}
+
+	var target data.Point
+	var storage *Storage
+	for _, pt := range points {
+		if pt.Attributes.Equals(&metric.overflow) {
+			target = pt
+			storage, _ = methods.ToStorage(pt.Aggregation)
+			break
+		}
+	}
+	if storage == nil {
+		storage = metric.newStorage()
+		target = data.Point{
+			Attributes:  metric.overflow,
+			Aggregation: methods.ToAggregation(storage),
+			Temporality: points[0].Temporality,
+			Start:       points[0].Start,
+			End:         points[0].End,
+		}
+	}
+
+	// Keep the first limit-1 points apart from the overflow
+	// point, merge the rest into it, then compact.
+	kept := 0
+	for _, pt := range points {
+		if pt.Attributes.Equals(&metric.overflow) {
+			continue
+		}
+		if kept < limit-1 {
+			kept++
+			continue
+		}
+		if from, err := metric.storageOf(&pt); err != nil {
+			otel.Handle(err)
+		} else {
+			methods.Merge(from, storage)
+		}
+		if pt.LastUpdate.After(target.LastUpdate) {
+			target.LastUpdate = pt.LastUpdate
+		}
+	}
+	target.Exemplars = methods.Exemplars(storage, target.Exemplars[:0])
+
+	j := 0
+	for _, pt := range points {
+		if j == kept {
+			break
+		}
+		if pt.Attributes.Equals(&metric.overflow) {
+			continue
+		}
+		points[j] = pt
+		j++
+	}
+	points[j] = target
+	j++
+
+	// The tail holds the points merged into the overflow point,
+	// and possibly the overflow point at its previous index.
+	for i := j; i < len(points); i++ {
+		points[i] = data.Point{}
+	}
+	inst.Points = points[:j]
+}
@@ -15,19 +15,112 @@
 package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
-	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 )
 
 // statefulSyncInstrument is a synchronous instrument that maintains cumulative state.
 type statefulSyncInstrument[N number.Any, Storage any, Methods aggregator.Methods[N, Storage], Samp SampleFilter] struct {
 	compiledSyncBase[N, Storage, Methods, Samp]
+
+	// starts is the declared reset time of each series passed
+	// to ResetSeries, or its creation time after an eviction,
+	// allocated on first use and synchronized by the instrument
+	// lock.
+	starts map[attribute.Set]time.Time
+
+	// evicted are the series evicted since the last collection,
+	// which report their final values, synchronized by the
+	// instrument lock.
+	evicted []evictedSeries[Storage]
+}
+
+// evictedSeries is a series removed by view.WithSeriesBudget, with
+// its start time, or zero for the start of the sequence.
+type evictedSeries[Storage any] struct {
+	seriesEntry[Storage]
+	start time.Time
+}
+
+// evict keeps the final value of an evicted series for the next
+// collection.  The caller holds the instrument lock.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) evict(set attribute.Set, entry *storageHolder[Storage, int64]) {
+	p.evicted = append(p.evicted, evictedSeries[Storage]{
+		seriesEntry: seriesEntry[Storage]{set: set, entry: entry},
+		start:       p.starts[set],
+	})
+	delete(p.starts, set)
+}
+
+// created records the creation time of a series created after an
+// eviction as its start, since the attribute set may have been
+// evicted.  The caller holds the instrument lock.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) created(set attribute.Set) {
+	if p.starts == nil {
+		p.starts = map[attribute.Set]time.Time{}
+	}
+	p.starts[set] = time.Now()
+}
+
+// ResetSeries empties the series and records the time as the start
+// of its subsequent points.  The attributes are filtered and
+// normalized to locate the series as measurements are.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) ResetSeries(kvs attribute.Set, when time.Time) {
+	var methods Methods
+
+	kvs = p.normalize.Normalize(p.applyTransform(p.applyKeysFilter(kvs)))
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	kvs = p.values.apply(kvs)
+	if entry, has := p.data[kvs]; has {
+		methods.Move(&entry.storage, p.newStorage())
+	}
+	if p.starts == nil {
+		p.starts = map[attribute.Set]time.Time{}
+	}
+	p.starts[kvs] = when
+}
+
+// Reset removes the series as for every synchronous view and
+// discards the declared reset times and evicted series.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) Reset() {
+	p.compiledSyncBase.Reset()
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	p.starts = nil
+	p.evicted = nil
+	if p.eviction != nil {
+		p.eviction.reset()
+	}
+}
+
+// InMemoryBytes (special case) includes the declared reset times.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) InMemoryBytes() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return p.sizeOf(p.data) + len(p.starts)*int(unsafe.Sizeof(attribute.Set{})+unsafe.Sizeof(time.Time{}))
+}
+
+// seriesStart returns the start time of a series, which is its
+// declared reset time if any, else the start of the sequence.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) seriesStart(set attribute.Set, seq data.Sequence) time.Time {
+	if start, has := p.starts[set]; has {
+		return start
+	}
+	return seq.Start
 }
 
 // Collect for synchronous cumulative temporality.
@@ -35,35 +128,208 @@ func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) Collect(seq data.Seq
 	p.instLock.Lock()
 	defer p.instLock.Unlock()
 
+	p.collect(seq, p.appendInstrument(output), nil, nil)
+}
+
+// CollectContext for synchronous cumulative temporality.  The series
+// not reached are reported by the next collection.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) CollectContext(ctx context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	deadline := newCollectDeadline(ctx)
+	p.collect(seq, p.appendInstrument(output), nil, deadline)
+	return deadline.error()
+}
+
+// CollectTemporality reports the configured temporality, as Collect.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) CollectTemporality(seq data.Sequence, _ aggregation.Temporality, output *[]data.Instrument) {
+	p.Collect(seq, output)
+}
+
+// CollectFunc for synchronous cumulative temporality.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, &scratch, emit, nil)
+}
+
+// collect appends the points of Collect to ioutput, passing each to
+// emit when set, until the deadline stops it.  The caller holds the
+// instrument lock.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) collect(seq data.Sequence, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point), deadline *collectDeadline) {
+	p.overflowed.Store(false)
+
+	p.appendEvicted(seq, aggregation.CumulativeTemporality, ioutput, emit)
+	clear(p.evicted)
+	p.evicted = p.evicted[:0]
+
+	if p.eviction != nil {
+		p.eviction.reset()
+	}
+	for set, entry := range p.data {
+		// The series not reached are still observed, so that
+		// eviction ranks every series.
+		if !deadline.stop() {
+			p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, aggregation.CumulativeTemporality, p.seriesStart(set, seq), seq.Now, false)
+			emitPoints(ioutput, emit)
+		}
+		if p.eviction != nil {
+			p.eviction.observe(set, p.overflow, entry)
+		}
+	}
+	if p.eviction != nil {
+		p.eviction.rank()
+	}
+}
+
+// appendEvicted appends the final points of the evicted series to
+// ioutput with the temporality, passing each to emit when set.  The
+// caller holds the instrument lock.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) appendEvicted(seq data.Sequence, tempo aggregation.Temporality, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point)) {
+	for _, ev := range p.evicted {
+		start := ev.start
+		if start.IsZero() {
+			start = seq.Start
+		}
+		p.appendPoint(ioutput, ev.set, ev.entry.metadata, ev.entry.updated(), &ev.entry.storage, tempo, start, seq.Now, false)
+		emitPoints(ioutput, emit)
+	}
+}
+
+// Peek for synchronous cumulative temporality.
+func (p *statefulSyncInstrument[N, Storage, Methods, Samp]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
 	ioutput := p.appendInstrument(output)
+	p.appendEvicted(seq, tempo, ioutput, nil)
 
 	for set, entry := range p.data {
-		p.appendPoint(ioutput, set, &entry.storage, aggregation.CumulativeTemporality, seq.Start, seq.Now, false)
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, tempo, p.seriesStart(set, seq), seq.Now, false)
 	}
 }
 
-// lowmemorySyncInstrument is a synchronous instrument that maintains no state.
+// lowmemorySyncInstrument is a synchronous instrument that maintains
+// no interval state.
 type lowmemorySyncInstrument[N number.Any, Storage any, Methods aggregator.Methods[N, Storage], Samp SampleFilter] struct {
 	compiledSyncBase[N, Storage, Methods, Samp]
+
+	// carried are the starts of the series not reached by a
+	// stopped collection.
+	carried carriedStarts
+
+	// totals are the cumulative values of the collected
+	// intervals, reported by Peek for cumulative temporality.
+	totals runningTotals[Storage]
+
+	// held are the points returned by Carry, which the next
+	// collection reports with the interval of each series.
+	held map[attribute.Set]*storageHolder[Storage, int64]
+}
+
+// Reset removes the series as for every synchronous view and
+// discards the carried starts, running totals, and held points.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) Reset() {
+	p.compiledSyncBase.Reset()
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	p.carried = nil
+	p.totals.reset()
+	p.held = nil
+}
+
+// InMemoryBytes (special case) includes the running totals and the
+// held points.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) InMemoryBytes() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return p.sizeOf(p.data) + p.totalsBytes(&p.totals) + p.sizeOf(p.held)
+}
+
+// Carry holds the points of the last Collect for the next one, which
+// reports them with the interval of each series from their start.
+// The running totals already include them.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) Carry(inst *data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	if p.held == nil {
+		p.held = map[attribute.Set]*storageHolder[Storage, int64]{}
+	}
+	p.carryPoints(p.held, &p.carried, inst)
 }
 
 // Collect for synchronous delta temporality.
+//
+// Accumulators merge into entry.storage without the instrument lock,
+// concurrently with this method.  An update is attributed to exactly
+// one interval because Merge and the Move below are each atomic with
+// respect to the storage: a merge lands either before the Move, in
+// this interval, or after it, in the next.  Updates not yet
+// snapshotted remain in the accumulator for a later interval.  An
+// entry is removed only when it had no references before the Move,
+// and an accumulator releases its reference after its final merge, so
+// no merge can follow the removal.
 func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) Collect(seq data.Sequence, output *[]data.Instrument) {
-	var methods Methods
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
 
+	p.collect(seq, p.appendInstrument(output), nil, nil)
+}
+
+// CollectContext for synchronous delta temporality.  The series not
+// reached keep their interval and its start, which the next
+// collection reports.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) CollectContext(ctx context.Context, seq data.Sequence, output *[]data.Instrument) error {
 	p.instLock.Lock()
 	defer p.instLock.Unlock()
 
-	ioutput := p.appendInstrument(output)
+	deadline := newCollectDeadline(ctx)
+	p.collect(seq, p.appendInstrument(output), nil, deadline)
+	return deadline.error()
+}
+
+// CollectTemporality reports the configured temporality, as Collect.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) CollectTemporality(seq data.Sequence, _ aggregation.Temporality, output *[]data.Instrument) {
+	p.Collect(seq, output)
+}
+
+// CollectFunc for synchronous delta temporality.  Each point is
+// emitted before the storage moved into it is re-used for the next.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, &scratch, emit, nil)
+}
+
+// collect appends the points of Collect to ioutput, passing each to
+// emit when set, until the deadline stops it.  The caller holds the
+// instrument lock.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) collect(seq data.Sequence, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point), deadline *collectDeadline) {
+	p.overflowed.Store(false)
+
+	var methods Methods
+	var leaked int
 
 	for set, entry := range p.data {
+		if deadline.stop() {
+			p.carried.carry(set, seq.Last)
+			continue
+		}
 		// capture the number of references before the Move() call
 		// below.  we're holding the lock that prevents new refs, so
 		// the value before Move() indicates when it's safe to remove
 		// this entry from the map.
 		numRefs := atomic.LoadInt64(&entry.auxiliary)
 
-		p.appendPoint(ioutput, set, &entry.storage, aggregation.DeltaTemporality, seq.Last, seq.Now, true)
+		start := p.carried.take(set, seq.Last)
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, aggregation.DeltaTemporality, start, seq.Now, true)
 
 		// By passing reset=true above, the aggregator data in
 		// entry.storage has been moved into the last index of
@@ -71,23 +337,509 @@ func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) Collect(seq data.Se
 		ptsArr := ioutput.Points
 		point := &ptsArr[len(ptsArr)-1]
 
-		cpy, _ := methods.ToStorage(point.Aggregation)
+		cpy, err := p.storageOf(point)
+		if err == nil && methods.HasChange(cpy) {
+			p.addTotal(&p.totals, set, entry, cpy, start)
+		}
+		if held, has := p.held[set]; has && err == nil {
+			methods.Merge(&held.storage, cpy)
+			delete(p.held, set)
+		}
+		unchanged := err == nil && !methods.HasChange(cpy)
+		if err != nil {
+			// The point is reported, since it cannot
+			// be tested for change.
+			otel.Handle(err)
+		} else if unchanged {
+			// We allowed the array to grow before the above
+			// test speculatively, since when it succeeds
+			// we are able to re-use the underlying
+			// aggregator.  Here, undo the new element, unless
+			// empty points are reported.
+			if !p.emitEmpty {
+				ioutput.Points = ptsArr[0 : len(ptsArr)-1 : cap(ptsArr)]
+			}
+
+			// If there are no more accumulator references to the
+			// entry, remove from the map.
+			if numRefs == 0 {
+				delete(p.data, set)
+			}
+		}
+		if p.leakPeriods != 0 && p.heldWithoutUpdate(entry, numRefs != 0 && unchanged) {
+			leaked++
+		}
+		emitPoints(ioutput, emit)
+	}
+	// The held points of series without an interval are
+	// reported alone.
+	for set, held := range p.held {
+		if deadline.stop() {
+			break
+		}
+		p.appendPoint(ioutput, set, held.metadata, held.updated(), &held.storage, aggregation.DeltaTemporality, p.carried.take(set, seq.Last), seq.Now, true)
+		emitPoints(ioutput, emit)
+		delete(p.held, set)
+	}
+	// A stopped collection did not reach every series, so the
+	// totals are not aged by it.
+	if deadline.error() == nil {
+		p.totals.expire()
+	}
+	// A stopped collection did not count every series.
+	if p.onLeak != nil && p.leakPeriods != 0 && leaked != p.leaked && deadline.error() == nil {
+		p.leaked = leaked
+		p.onLeak(p.desc.Name, leaked)
+	}
+}
+
+// heldWithoutUpdate counts the consecutive collections in which the
+// entry was held without a change, returning true when they exceed
+// Performance.LeakCollectionPeriods.  The caller holds the
+// instrument lock.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) heldWithoutUpdate(entry *storageHolder[Storage, int64], held bool) bool {
+	if !held {
+		entry.periods = 0
+		return false
+	}
+	entry.periods++
+	return entry.periods > p.leakPeriods
+}
+
+// Peek for synchronous delta temporality.  The current interval is
+// copied, not moved, so it remains for the next Collect.  For
+// cumulative temporality, the interval is combined with a copy of the
+// running total.
+func (p *lowmemorySyncInstrument[N, Storage, Methods, Samp]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	var methods Methods
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	ioutput := p.appendInstrument(output)
+
+	if tempo == aggregation.CumulativeTemporality {
+		p.peekTotals(&p.totals, seq, ioutput, func(set attribute.Set) time.Time {
+			return p.carried.start(set, seq.Last)
+		})
+		return
+	}
+	for set, entry := range p.data {
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, tempo, p.carried.start(set, seq.Last), seq.Now, false)
+
+		ptsArr := ioutput.Points
+		point := &ptsArr[len(ptsArr)-1]
+
+		cpy, err := p.storageOf(point)
+		if held, has := p.held[set]; has && err == nil {
+			methods.Merge(&held.storage, cpy)
+		}
+		if err != nil {
+			otel.Handle(err)
+		} else if !methods.HasChange(cpy) && !p.emitEmpty {
+			ioutput.Points = ptsArr[0 : len(ptsArr)-1 : cap(ptsArr)]
+		}
+	}
+	for set, held := range p.held {
+		if _, has := p.data[set]; has {
+			continue
+		}
+		p.appendPoint(ioutput, set, held.metadata, held.updated(), &held.storage, tempo, p.carried.start(set, seq.Last), seq.Now, false)
+	}
+}
+
+// carryPoints merges the points of inst, output by this instrument's
+// last Collect with delta temporality, into their series in state,
+// carrying the start of each.  The caller holds the instrument lock.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) carryPoints(state map[attribute.Set]*storageHolder[Storage, int64], carried *carriedStarts, inst *data.Instrument) {
+	var methods Methods
 
-		if methods.HasChange(cpy) {
+	for i := range inst.Points {
+		pt := &inst.Points[i]
+		from, err := c.storageOf(pt)
+		if err != nil {
+			otel.Handle(err)
 			continue
 		}
+		holder, has := state[pt.Attributes]
+		if !has {
+			holder = &storageHolder[Storage, int64]{}
+			c.initStorage(&holder.storage)
+			state[pt.Attributes] = holder
+		}
+		methods.Merge(from, &holder.storage)
+
+		if pt.Metadata.Len() != 0 {
+			holder.metadata = pt.Metadata
+		}
+		if !pt.LastUpdate.IsZero() {
+			when := pt.LastUpdate.UnixNano()
+			holder.lastUpdate = &when
+		}
+		carried.carry(pt.Attributes, pt.Start)
+	}
+}
 
-		// We allowed the array to grow before the above
-		// test speculatively, since when it succeeds
-		// we are able to re-use the underlying
-		// aggregator.  Here, undo the new element.
-		ioutput.Points = ptsArr[0 : len(ptsArr)-1 : cap(ptsArr)]
+// runningTotals are the cumulative values of a synchronous instrument
+// whose intervals are moved out by delta collections, synchronized by
+// the instrument lock.  A total without change for
+// Performance.InactiveCollectionPeriods collections is removed, as
+// an asynchronous prior value is.
+type runningTotals[Storage any] struct {
+	series map[attribute.Set]*storageHolder[Storage, int64]
 
-		// If there are no more accumulator references to the
-		// entry, remove from the map.
-		if numRefs == 0 {
-			delete(p.data, set)
+	// starts are the starts of the totals created after a total
+	// was removed, since the series may have been removed.
+	// Other totals start with the sequence.  Allocated on the
+	// first removal.
+	starts map[attribute.Set]time.Time
+
+	// inactive is Performance.InactiveCollectionPeriods.
+	inactive uint32
+}
+
+// start returns the start of the total of a series.
+func (t *runningTotals[Storage]) start(set attribute.Set, seq data.Sequence) time.Time {
+	if start, has := t.starts[set]; has {
+		return start
+	}
+	return seq.Start
+}
+
+// expire ages the totals by one collection, removing those not
+// changed for the inactive number of collections.
+func (t *runningTotals[Storage]) expire() {
+	for set, total := range t.series {
+		if total.periods > t.inactive {
+			delete(t.series, set)
+			delete(t.starts, set)
+			if t.starts == nil {
+				t.starts = map[attribute.Set]time.Time{}
+			}
+			continue
 		}
+		total.periods++
+	}
+}
+
+// reset removes every total.
+func (t *runningTotals[Storage]) reset() {
+	t.series = nil
+	t.starts = nil
+}
+
+// addTotal merges an interval of a series, which started at start,
+// into its running total.  The caller holds the instrument lock.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) addTotal(t *runningTotals[Storage], set attribute.Set, entry *storageHolder[Storage, int64], interval *Storage, start time.Time) {
+	var methods Methods
+
+	if t.series == nil {
+		t.series = map[attribute.Set]*storageHolder[Storage, int64]{}
+	}
+	total, has := t.series[set]
+	if !has {
+		total = &storageHolder[Storage, int64]{}
+		c.initStorage(&total.storage)
+		t.series[set] = total
+		if t.starts != nil {
+			t.starts[set] = start
+		}
+	}
+	methods.Merge(interval, &total.storage)
+
+	if entry.metadata.Len() != 0 {
+		total.metadata = entry.metadata
+	}
+	total.lastUpdate = entry.lastUpdate
+	total.periods = 0
+}
+
+// peekTotals appends the cumulative points of the running totals
+// combined with the current intervals, without modifying either.  A
+// series without a total after a total was removed starts with its
+// current interval, given by intervalStart.  The caller holds the
+// instrument lock.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) peekTotals(t *runningTotals[Storage], seq data.Sequence, ioutput *data.Instrument, intervalStart func(attribute.Set) time.Time) {
+	var methods Methods
+
+	for set, entry := range c.data {
+		total, has := t.series[set]
+		start := t.start(set, seq)
+		if !has {
+			if !methods.HasChange(&entry.storage) {
+				continue
+			}
+			if t.starts != nil {
+				start = intervalStart(set)
+			}
+		}
+		cpy := c.newStorage()
+		methods.Copy(&entry.storage, cpy)
+		if has {
+			methods.Merge(&total.storage, cpy)
+		}
+		c.appendPoint(ioutput, set, entry.metadata, entry.updated(), cpy, aggregation.CumulativeTemporality, start, seq.Now, false)
+	}
+	for set, total := range t.series {
+		if _, has := c.data[set]; has {
+			continue
+		}
+		c.appendPoint(ioutput, set, total.metadata, total.updated(), &total.storage, aggregation.CumulativeTemporality, t.start(set, seq), seq.Now, false)
+	}
+}
+
+// totalsBytes estimates the memory of the running totals.  The caller
+// holds the instrument lock.
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) totalsBytes(t *runningTotals[Storage]) int {
+	return c.sizeOf(t.series) + len(t.starts)*int(unsafe.Sizeof(attribute.Set{})+unsafe.Sizeof(time.Time{}))
+}
+
+// eitherSyncInstrument is a synchronous instrument that maintains
+// both cumulative and delta state, so that it can be collected with
+// either temporality.
+type eitherSyncInstrument[N number.Any, Storage any, Methods aggregator.Methods[N, Storage], Samp SampleFilter] struct {
+	compiledSyncBase[N, Storage, Methods, Samp]
+
+	// tempo is the configured temporality, reported by Collect.
+	tempo aggregation.Temporality
+
+	// totals and pending are the cumulative and delta state,
+	// into which every collection moves the current interval.
+	// Delta collections empty the pending state, and totals
+	// without change are removed as for lowmemory instruments.
+	totals  runningTotals[Storage]
+	pending map[attribute.Set]*storageHolder[Storage, int64]
+
+	// lastDelta is the time of the last delta collection, or
+	// zero before the first.
+	lastDelta time.Time
+
+	// carried are the starts of the pending series not reached
+	// by a stopped delta collection.
+	carried carriedStarts
+}
+
+// InMemorySize (special case) reports the size of the totals map,
+// since series are removed from data when they are unchanged.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) InMemorySize() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return len(p.totals.series)
+}
+
+// InMemoryBytes (special case) includes both states.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) InMemoryBytes() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return p.sizeOf(p.data) + p.totalsBytes(&p.totals) + p.sizeOf(p.pending)
+}
+
+// Collect for synchronous instruments with the configured temporality.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) Collect(seq data.Sequence, output *[]data.Instrument) {
+	p.CollectTemporality(seq, p.tempo, output)
+}
+
+// CollectTemporality for synchronous instruments with either
+// temporality.  An undefined temporality is taken as the configured
+// temporality.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) CollectTemporality(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	if tempo == aggregation.UndefinedTemporality {
+		tempo = p.tempo
+	}
+	p.collect(seq, tempo, p.appendInstrument(output), nil, nil)
+}
+
+// CollectContext for synchronous instruments with the configured
+// temporality.  The current interval is always moved into both
+// states.  With delta temporality, the series not reached remain in
+// the pending state with their start, which the next collection
+// reports.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) CollectContext(ctx context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	deadline := newCollectDeadline(ctx)
+	p.collect(seq, p.tempo, p.appendInstrument(output), nil, deadline)
+	return deadline.error()
+}
+
+// CollectFunc for synchronous instruments with the configured temporality.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, p.tempo, &scratch, emit, nil)
+}
+
+// collect moves the current interval into both states, then appends
+// the points of the state for tempo to ioutput, passing each to emit
+// when set, until the deadline stops it.  The caller holds the
+// instrument lock.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) collect(seq data.Sequence, tempo aggregation.Temporality, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point), deadline *collectDeadline) {
+	p.overflowed.Store(false)
+
+	p.drain(seq)
+
+	if tempo != aggregation.DeltaTemporality {
+		for set, holder := range p.totals.series {
+			if deadline.stop() {
+				return
+			}
+			p.appendPoint(ioutput, set, holder.metadata, holder.updated(), &holder.storage, aggregation.CumulativeTemporality, p.totals.start(set, seq), seq.Now, false)
+			emitPoints(ioutput, emit)
+		}
+		return
+	}
+
+	start := p.deltaStart(seq)
+	for set, holder := range p.pending {
+		if deadline.stop() {
+			// The pending series not reached remain
+			// for the next delta collection.
+			p.carried.carry(set, start)
+			continue
+		}
+		p.appendPoint(ioutput, set, holder.metadata, holder.updated(), &holder.storage, aggregation.DeltaTemporality, p.carried.take(set, start), seq.Now, true)
+		emitPoints(ioutput, emit)
+		delete(p.pending, set)
+	}
+	if len(p.pending) == 0 {
+		p.pending = nil
+	}
+	p.lastDelta = seq.Now
+}
+
+// drain moves the current interval of each series, which started
+// with the last collection, into the cumulative and delta states,
+// then ages the totals.  Entries without change and without
+// references are removed from the map, as for delta temporality.
+// The caller holds the instrument lock.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) drain(seq data.Sequence) {
+	var methods Methods
+
+	if p.pending == nil {
+		p.pending = map[attribute.Set]*storageHolder[Storage, int64]{}
+	}
+	// Move() resets its output, so the interval is reused
+	// for each series.
+	interval := p.newStorage()
+	for set, entry := range p.data {
+		// See lowmemorySyncInstrument.Collect, the number of
+		// references before the Move() call indicates when it
+		// is safe to remove this entry from the map.
+		numRefs := atomic.LoadInt64(&entry.auxiliary)
+
+		methods.Move(&entry.storage, interval)
+
+		if !methods.HasChange(interval) {
+			if p.emitEmpty {
+				p.mergeInto(p.pending, set, entry, interval)
+			}
+			if numRefs == 0 {
+				delete(p.data, set)
+			}
+			continue
+		}
+		p.mergeInto(p.pending, set, entry, interval)
+		p.addTotal(&p.totals, set, entry, interval, seq.Last)
+	}
+	p.totals.expire()
+}
+
+// mergeInto merges the interval of a series into its holder in
+// state, which is created on first use.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) mergeInto(state map[attribute.Set]*storageHolder[Storage, int64], set attribute.Set, entry *storageHolder[Storage, int64], interval *Storage) {
+	var methods Methods
+
+	holder, has := state[set]
+	if !has {
+		holder = &storageHolder[Storage, int64]{}
+		p.initStorage(&holder.storage)
+		state[set] = holder
+	}
+	methods.Merge(interval, &holder.storage)
+
+	if entry.metadata.Len() != 0 {
+		holder.metadata = entry.metadata
+	}
+	holder.lastUpdate = entry.lastUpdate
+}
+
+// deltaStart returns the start time of delta points, which is the
+// time of the last delta collection, else the start of the sequence.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) deltaStart(seq data.Sequence) time.Time {
+	if p.lastDelta.IsZero() {
+		return seq.Start
+	}
+	return p.lastDelta
+}
+
+// Carry returns the points of the last delta collection to the
+// pending state, which the next delta collection reports from their
+// start.  The cumulative state already includes them.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) Carry(inst *data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	if p.pending == nil {
+		p.pending = map[attribute.Set]*storageHolder[Storage, int64]{}
+	}
+	p.carryPoints(p.pending, &p.carried, inst)
+}
+
+// Reset removes every series, including both states.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) Reset() {
+	p.compiledSyncBase.Reset()
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	p.totals.reset()
+	p.pending = nil
+	p.lastDelta = time.Time{}
+	p.carried = nil
+}
+
+// Peek for synchronous instruments with either temporality.  The
+// current interval is combined with a copy of the state for tempo, so
+// neither is modified.
+func (p *eitherSyncInstrument[N, Storage, Methods, Samp]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	var methods Methods
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	ioutput := p.appendInstrument(output)
+
+	if tempo != aggregation.DeltaTemporality {
+		// A series without a total started its interval
+		// with the last collection.
+		p.peekTotals(&p.totals, seq, ioutput, func(attribute.Set) time.Time {
+			return seq.Last
+		})
+		return
+	}
+	start := p.deltaStart(seq)
+	for set, entry := range p.data {
+		cpy := p.newStorage()
+		methods.Copy(&entry.storage, cpy)
+
+		if holder, has := p.pending[set]; has {
+			methods.Merge(&holder.storage, cpy)
+		}
+		if !methods.HasChange(cpy) && !p.emitEmpty {
+			continue
+		}
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), cpy, tempo, p.carried.start(set, start), seq.Now, false)
+	}
+	for set, holder := range p.pending {
+		if _, has := p.data[set]; has {
+			continue
+		}
+		p.appendPoint(ioutput, set, holder.metadata, holder.updated(), &holder.storage, tempo, p.carried.start(set, start), seq.Now, false)
 	}
 }
 
@@ -102,14 +854,173 @@ func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) Collect(seq data.Sequenc
 	p.instLock.Lock()
 	defer p.instLock.Unlock()
 
-	ioutput := p.appendInstrument(output)
+	p.collect(seq, p.appendInstrument(output), nil)
+}
+
+// CollectContext collects every series, as Collect, since the
+// observations do not carry over to the next collection.
+func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) CollectContext(_ context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	p.Collect(seq, output)
+	return nil
+}
+
+// CollectTemporality reports the configured temporality, as Collect.
+func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) CollectTemporality(seq data.Sequence, _ aggregation.Temporality, output *[]data.Instrument) {
+	p.Collect(seq, output)
+}
+
+// CollectFunc for asynchronous cumulative temporality.
+func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, &scratch, emit)
+}
+
+// collect appends the points of Collect to ioutput, passing each to
+// emit when set.  The caller holds the instrument lock.
+func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) collect(seq data.Sequence, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.overflowed.Store(false)
 
 	for set, entry := range p.data {
-		p.appendPoint(ioutput, set, &entry.storage, aggregation.CumulativeTemporality, seq.Start, seq.Now, false)
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, aggregation.CumulativeTemporality, seq.Start, seq.Now, false)
+		emitPoints(ioutput, emit)
 	}
 
 	// Reset the entire map.
-	p.data = map[attribute.Set]*storageHolder[Storage, notUsed]{}
+	p.replaceData()
+}
+
+// Peek for asynchronous cumulative temporality.  The observations
+// belong to this collection, so they are reset as in Collect.
+func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	ioutput := p.appendInstrument(output)
+
+	for set, entry := range p.data {
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, tempo, seq.Start, seq.Now, false)
+	}
+
+	p.replaceData()
+}
+
+// changedAsyncInstrument is an asynchronous instrument that keeps
+// the prior value of each series in order to omit unchanged series
+// with cumulative temporality.
+type changedAsyncInstrument[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
+	compiledAsyncBase[N, Storage, Methods]
+	prior map[attribute.Set]*storageHolder[Storage, notUsed]
+}
+
+// InMemorySize (special case) reports the size of the prior map,
+// since data is emptied on Collect().
+func (p *changedAsyncInstrument[N, Storage, Methods]) InMemorySize() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return len(p.prior)
+}
+
+// InMemoryBytes (special case) includes the prior map.
+func (p *changedAsyncInstrument[N, Storage, Methods]) InMemoryBytes() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return p.sizeOf(p.data) + p.sizeOf(p.prior)
+}
+
+// Collect for asynchronous cumulative temporality, omitting
+// unchanged series.
+func (p *changedAsyncInstrument[N, Storage, Methods]) Collect(seq data.Sequence, output *[]data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	p.collect(seq, p.appendInstrument(output), nil)
+}
+
+// CollectContext collects every series, as Collect, since the
+// observations do not carry over to the next collection.
+func (p *changedAsyncInstrument[N, Storage, Methods]) CollectContext(_ context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	p.Collect(seq, output)
+	return nil
+}
+
+// CollectTemporality reports the configured temporality, as Collect.
+func (p *changedAsyncInstrument[N, Storage, Methods]) CollectTemporality(seq data.Sequence, _ aggregation.Temporality, output *[]data.Instrument) {
+	p.Collect(seq, output)
+}
+
+// CollectFunc for asynchronous cumulative temporality, omitting
+// unchanged series.
+func (p *changedAsyncInstrument[N, Storage, Methods]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, &scratch, emit)
+}
+
+// collect appends the points of Collect to ioutput, passing each to
+// emit when set.  The caller holds the instrument lock.
+func (p *changedAsyncInstrument[N, Storage, Methods]) collect(seq data.Sequence, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point)) {
+	p.overflowed.Store(false)
+
+	for set, entry := range p.data {
+		if pval, has := p.prior[set]; has && p.unchanged(&pval.storage, &entry.storage) {
+			continue
+		}
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, aggregation.CumulativeTemporality, seq.Start, seq.Now, false)
+		emitPoints(ioutput, emit)
+	}
+
+	// Series that were not observed are forgotten, so they are
+	// reported when they reappear.
+	p.prior = p.data
+	p.replaceData()
+}
+
+// unchanged tests whether the current value equals the prior value.
+// Gauges compare their values, since they do not subtract; other
+// aggregations test the difference with HasChange.
+func (p *changedAsyncInstrument[N, Storage, Methods]) unchanged(prior, current *Storage) bool {
+	var methods Methods
+
+	if methods.Kind() == aggregation.GaugeKind {
+		pg, pok := methods.ToAggregation(prior).(aggregation.Gauge)
+		cg, cok := methods.ToAggregation(current).(aggregation.Gauge)
+		return pok && cok && pg.Gauge() == cg.Gauge()
+	}
+	diff := p.newStorage()
+	methods.Copy(prior, diff)
+	methods.SubtractSwap(diff, current)
+	return !methods.HasChange(diff)
+}
+
+// Reset removes every series, including the prior values, so the
+// next collection reports each series.
+func (p *changedAsyncInstrument[N, Storage, Methods]) Reset() {
+	p.compiledAsyncBase.Reset()
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	p.prior = nil
+}
+
+// Peek for asynchronous cumulative temporality.  Every series is
+// reported and the prior values are not modified.  The observations
+// belong to this collection, so they are reset as in Collect.
+func (p *changedAsyncInstrument[N, Storage, Methods]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	ioutput := p.appendInstrument(output)
+
+	for set, entry := range p.data {
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, tempo, seq.Start, seq.Now, false)
+	}
+
+	p.replaceData()
 }
 
 // statefulAsyncInstrument is an instrument that keeps asynchronous instrument state
@@ -117,6 +1028,19 @@ func (p *lowmemoryAsyncInstrument[N, Storage, Methods]) Collect(seq data.Sequenc
 type statefulAsyncInstrument[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct {
 	compiledAsyncBase[N, Storage, Methods]
 	prior map[attribute.Set]*storageHolder[Storage, notUsed]
+
+	// inactive is Performance.InactiveCollectionPeriods, the
+	// number of collections a prior value is kept without an
+	// observation.
+	inactive uint32
+
+	// carried is the overflow value carried into the current
+	// data set by the last Collect, restored after Peek.
+	carried *storageHolder[Storage, notUsed]
+
+	// starts are the starts of the points returned by Carry,
+	// which the next reported difference of each series keeps.
+	starts carriedStarts
 }
 
 // Size (special case) reports the size of the prior map, since
@@ -127,53 +1051,219 @@ func (p *statefulAsyncInstrument[N, Storage, Methods]) InMemorySize() int {
 	return len(p.prior)
 }
 
+// InMemoryBytes (special case) includes the prior map.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) InMemoryBytes() int {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+	return p.sizeOf(p.data) + p.sizeOf(p.prior)
+}
+
 // Collect for asynchronous delta temporality.  Note this code path is
-// not used for Gauge instruments.
+// not used for Gauge instruments, except with view.WithDeltaGauge.
 func (p *statefulAsyncInstrument[N, Storage, Methods]) Collect(seq data.Sequence, output *[]data.Instrument) {
-	var methods Methods
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
 
+	p.collect(seq, p.appendInstrument(output), nil)
+}
+
+// CollectContext collects every series, as Collect, since the
+// observations do not carry over to the next collection.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) CollectContext(_ context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	p.Collect(seq, output)
+	return nil
+}
+
+// CollectTemporality reports the configured temporality, as Collect.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) CollectTemporality(seq data.Sequence, _ aggregation.Temporality, output *[]data.Instrument) {
+	p.Collect(seq, output)
+}
+
+// CollectFunc for asynchronous delta temporality.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
 	p.instLock.Lock()
 	defer p.instLock.Unlock()
 
-	ioutput := p.appendInstrument(output)
+	scratch := data.Instrument{Descriptor: p.desc}
+	p.collect(seq, &scratch, emit)
+}
+
+// collect appends the points of Collect to ioutput, passing each to
+// emit when set.  The caller holds the instrument lock.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) collect(seq data.Sequence, ioutput *data.Instrument, emit func(sdkinstrument.Descriptor, data.Point)) {
+	var methods Methods
 
 	var ofe *storageHolder[Storage, notUsed]
 	for set, entry := range p.data {
 		// Compute the difference.
 		pval, has := p.prior[set]
+		updated := entry.updated()
 
-		if set == pipeline.OverflowAttributeSet {
+		if set == p.overflow {
 			ofe = entry
 		}
 		if has {
 			// This does `*pval := *storage - *pval`
-			methods.SubtractSwap(&pval.storage, &entry.storage)
+			p.subtract(&pval.storage, &entry.storage)
 
 			// Skip the series if it has not changed.
 			if !methods.HasChange(&pval.storage) {
+				delete(p.starts, set)
 				continue
 			}
+			pval.metadata = entry.metadata
 			entry = pval
 		}
-		p.appendPoint(ioutput, set, &entry.storage, aggregation.DeltaTemporality, seq.Last, seq.Now, false)
+		p.appendPoint(ioutput, set, entry.metadata, updated, &entry.storage, aggregation.DeltaTemporality, p.starts.take(set, seq.Last), seq.Now, false)
+		emitPoints(ioutput, emit)
+	}
+	// Values that are contained in prior but not in data are
+	// copied so they are not forgotten and do not output
+	// spurious counts in the future when they reappear.  These
+	// are retained for the inactive number of collections, after
+	// which a series that reappears is reported as new.
+	for set, pval := range p.prior {
+		if _, has := p.data[set]; has || pval.periods >= p.inactive {
+			if !has {
+				delete(p.starts, set)
+			}
+			continue
+		}
+		pval.periods++
+		p.data[set] = pval
 	}
-	// TODO: Values that are contained in prior but not in data
-	// should be copied so they are not forgotten and do not
-	// output spurious counts in the future when they reappear.
-	// This is only an issue for asynchronous instruments with
-	// delta temporality.
 
 	// Copy the current to the prior and reset.
 	p.prior = p.data
-	p.data = map[attribute.Set]*storageHolder[Storage, notUsed]{}
 
 	// Note: the overflow attribute set is synthesized from a
 	// number of inputs which are presumed cumulative.  To maintain this
 	// illusion, copy its current cumulative value into the next data set.
+	p.carried = nil
 	if ofe != nil {
+		p.carried = &storageHolder[Storage, notUsed]{}
+		methods.Copy(&ofe.storage, &p.carried.storage)
+	}
+	p.resetData()
+}
+
+// Reset removes every series, including the prior values used to
+// compute differences and the carried overflow value, so the next
+// collection reports each series as new.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) Reset() {
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	p.prior = nil
+	p.carried = nil
+	p.starts = nil
+	p.resetData()
+	p.dropped.Store(0)
+}
+
+// Carry returns the points of the last Collect by subtracting them
+// from the prior values, so that the next differences include them.
+// The next point of each series keeps its start.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) Carry(inst *data.Instrument) {
+	var methods Methods
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
+
+	for i := range inst.Points {
+		pt := &inst.Points[i]
+		from, err := p.storageOf(pt)
+		if err != nil {
+			otel.Handle(err)
+			continue
+		}
+		pval, has := p.prior[pt.Attributes]
+		if !has {
+			continue
+		}
+		// This does `*pval := *pval - *from`.
+		diff := p.newStorage()
+		methods.Copy(from, diff)
+		methods.SubtractSwap(diff, &pval.storage)
+		methods.Move(diff, &pval.storage)
+
+		p.starts.carry(pt.Attributes, pt.Start)
+	}
+}
+
+// resetData starts a new data set, including the carried overflow
+// value.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) resetData() {
+	var methods Methods
+
+	p.replaceData()
+
+	if p.carried != nil {
 		cpy := &storageHolder[Storage, notUsed]{}
-		methods.Copy(&ofe.storage, &cpy.storage)
+		methods.Copy(&p.carried.storage, &cpy.storage)
+		if p.lastUpdate {
+			cpy.lastUpdate = new(int64)
+		}
+
+		p.data[p.overflow] = cpy
+	}
+}
+
+// Peek for asynchronous delta temporality.  The prior values are not
+// modified, and the observations, which belong to this collection,
+// are reset as in Collect.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	var methods Methods
+
+	p.instLock.Lock()
+	defer p.instLock.Unlock()
 
-		p.data[pipeline.OverflowAttributeSet] = cpy
+	ioutput := p.appendInstrument(output)
+
+	for set, entry := range p.data {
+		pval, has := p.prior[set]
+
+		if tempo == aggregation.CumulativeTemporality || !has {
+			start := seq.Last
+			if tempo == aggregation.CumulativeTemporality {
+				start = seq.Start
+			}
+			p.appendPoint(ioutput, set, entry.metadata, entry.updated(), &entry.storage, tempo, start, seq.Now, false)
+			continue
+		}
+
+		diff := p.newStorage()
+		methods.Copy(&pval.storage, diff)
+		p.subtract(diff, &entry.storage)
+
+		if !methods.HasChange(diff) {
+			continue
+		}
+		p.appendPoint(ioutput, set, entry.metadata, entry.updated(), diff, tempo, p.starts.start(set, seq.Last), seq.Now, false)
+	}
+
+	p.resetData()
+}
+
+// subtract computes `*prior := *current - *prior`.  A monotonic sum
+// whose cumulative value decreased was reset by its source, in which
+// case the difference is the current value, counted from the reset.
+// The same applies to a delta gauge whose value decreased.  The
+// reset happened after the last collection, which remains the
+// start of the point.
+func (p *statefulAsyncInstrument[N, Storage, Methods]) subtract(prior, current *Storage) {
+	var methods Methods
+
+	methods.SubtractSwap(prior, current)
+
+	var decreased bool
+	switch agg := methods.ToAggregation(prior).(type) {
+	case aggregation.Sum:
+		decreased = methods.Kind() == aggregation.MonotonicSumKind && agg.Sum().CoerceToFloat64(p.desc.NumberKind) < 0
+	case aggregation.Gauge:
+		decreased = methods.HasChange(prior) && agg.Gauge().CoerceToFloat64(p.desc.NumberKind) < 0
+	}
+	if decreased {
+		methods.Copy(current, prior)
 	}
 }
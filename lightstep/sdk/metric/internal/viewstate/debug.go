@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// accumulatorRegistry records the synchronous accumulators holding a
+// reference to each storageHolder, with the stack that created each,
+// to diagnose references that are never released and so keep delta
+// series from being removed.  It is used only in builds with the
+// otelsdkdebug tag, since it costs a lock and an allocation per
+// accumulator.
+type accumulatorRegistry struct {
+	lock sync.Mutex
+	held map[any]map[Accumulator]accumulatorRecord
+}
+
+// accumulatorRecord describes one accumulator in the registry.
+type accumulatorRecord struct {
+	instrument string
+	kvs        attribute.Set
+	stack      []uintptr
+}
+
+// debugRegistry is the registry of debug builds.
+var debugRegistry accumulatorRegistry
+
+// register records acc as holding a reference to holder.  The stack
+// omits skip callers, not counting register itself.
+func (r *accumulatorRegistry) register(holder any, acc Accumulator, instrument string, kvs attribute.Set, skip int) {
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.held == nil {
+		r.held = map[any]map[Accumulator]accumulatorRecord{}
+	}
+	accs := r.held[holder]
+	if accs == nil {
+		accs = map[Accumulator]accumulatorRecord{}
+		r.held[holder] = accs
+	}
+	accs[acc] = accumulatorRecord{
+		instrument: instrument,
+		kvs:        kvs,
+		stack:      append([]uintptr(nil), pcs[:n]...),
+	}
+}
+
+// release removes acc after it released its reference to holder.
+func (r *accumulatorRegistry) release(holder any, acc Accumulator) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	accs := r.held[holder]
+	delete(accs, acc)
+	if len(accs) == 0 {
+		delete(r.held, holder)
+	}
+}
+
+// count returns the number of accumulators holding holder.
+func (r *accumulatorRegistry) count(holder any) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.held[holder])
+}
+
+// dump writes each accumulator in the registry, ordered by
+// instrument name and attributes, with the stack that created it.
+func (r *accumulatorRegistry) dump(w io.Writer) error {
+	r.lock.Lock()
+	var recs []accumulatorRecord
+	for _, accs := range r.held {
+		for _, rec := range accs {
+			recs = append(recs, rec)
+		}
+	}
+	r.lock.Unlock()
+
+	enc := attribute.DefaultEncoder()
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].instrument != recs[j].instrument {
+			return recs[i].instrument < recs[j].instrument
+		}
+		return recs[i].kvs.Encoded(enc) < recs[j].kvs.Encoded(enc)
+	})
+	for _, rec := range recs {
+		if _, err := fmt.Fprintf(w, "%s {%s}\n", rec.instrument, rec.kvs.Encoded(enc)); err != nil {
+			return err
+		}
+		frames := runtime.CallersFrames(rec.stack)
+		for {
+			frame, more := frames.Next()
+			if _, err := fmt.Fprintf(w, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line); err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// DumpAccumulators writes the synchronous accumulators that hold a
+// reference to a series and have not released it, with the stack
+// that created each, e.g., to find why a delta series is never
+// removed.  The registry is kept only in builds with the otelsdkdebug
+// tag; otherwise this writes nothing.
+func DumpAccumulators(w io.Writer) error {
+	if !debugAccumulators {
+		return nil
+	}
+	return debugRegistry.dump(w)
+}
@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAccumulatorRegistry(t *testing.T) {
+	var r accumulatorRegistry
+	holder := &storageHolder[int64, int64]{}
+	acc1 := &droppedAccumulator[int64]{}
+	acc2 := &droppedAccumulator[int64]{}
+	set := attribute.NewSet(attribute.String("k", "v"))
+
+	r.register(holder, acc1, "leaky", set, 0)
+	r.register(holder, acc2, "leaky", set, 0)
+	require.Equal(t, 2, r.count(holder))
+
+	var out strings.Builder
+	require.NoError(t, r.dump(&out))
+	require.Equal(t, 2, strings.Count(out.String(), "leaky {k=v}\n"))
+	require.Contains(t, out.String(), "TestAccumulatorRegistry")
+
+	r.release(holder, acc1)
+	r.release(holder, acc2)
+	require.Equal(t, 0, r.count(holder))
+
+	out.Reset()
+	require.NoError(t, r.dump(&out))
+	require.Empty(t, out.String())
+}
+
+func TestDumpAccumulators(t *testing.T) {
+	vc := New(testLib, view.New("test", safePerf))
+	inst, err := testCompile(vc, "dumped", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet(attribute.String("k", "v")))
+
+	var out strings.Builder
+	require.NoError(t, DumpAccumulators(&out))
+	if !debugAccumulators {
+		// The registry is not kept.
+		require.Empty(t, out.String())
+		return
+	}
+	require.Contains(t, out.String(), "dumped {k=v}\n")
+	require.Contains(t, out.String(), "TestDumpAccumulators")
+
+	// A released accumulator is removed.
+	require.NoError(t, acc.SnapshotAndProcess(true))
+	out.Reset()
+	require.NoError(t, DumpAccumulators(&out))
+	require.NotContains(t, out.String(), "dumped {k=v}")
+}
@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// dedupCacheSize is the number of recent values remembered by each
+// deduplicating accumulator.
+const dedupCacheSize = 4
+
+// dedupAccumulator returns the accumulator, wrapped to discard
+// repeated values within the window when window is positive.
+func dedupAccumulator[N number.Any](acc Accumulator, window time.Duration) Accumulator {
+	if window <= 0 {
+		return acc
+	}
+	if _, ok := acc.(droppedAccumulator[N]); ok {
+		return acc
+	}
+	return &dedupedAccumulator[N]{
+		Accumulator: acc,
+		window:      window,
+	}
+}
+
+// dedupedAccumulator discards a measurement equal to one counted
+// within the window, remembering the most recent distinct values.
+type dedupedAccumulator[N number.Any] struct {
+	Accumulator
+	window time.Duration
+
+	lock   sync.Mutex
+	recent [dedupCacheSize]dedupEntry[N]
+	next   int
+}
+
+// dedupEntry is a value and the time it was counted.
+type dedupEntry[N number.Any] struct {
+	value N
+	at    time.Time
+}
+
+var _ Updater[float64] = &dedupedAccumulator[float64]{}
+
+func (a *dedupedAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
+	if a.duplicate(value, ex.Time) {
+		return
+	}
+	a.Accumulator.(Updater[N]).Update(value, ex)
+}
+
+// UpdateBatch counts the batch once, since its measurements repeat
+// the same value at the same time.
+func (a *dedupedAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	if count == 0 || a.duplicate(value, ex.Time) {
+		return
+	}
+	a.Accumulator.(Updater[N]).UpdateBatch(value, 1, ex)
+}
+
+// duplicate returns true when the value was counted within the
+// window, otherwise it remembers the value in place of the oldest.
+// The window starts when a value is counted, so a value repeated
+// indefinitely is counted once per window.
+func (a *dedupedAccumulator[N]) duplicate(value N, at time.Time) bool {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for i := range a.recent {
+		e := &a.recent[i]
+		if !e.at.IsZero() && e.value == value && at.Sub(e.at) < a.window {
+			return true
+		}
+	}
+	a.recent[a.next] = dedupEntry[N]{value: value, at: at}
+	a.next = (a.next + 1) % dedupCacheSize
+	return false
+}
+
+func (a *dedupedAccumulator[N]) MaySample(isTraced bool) bool {
+	return a.Accumulator.(Updater[N]).MaySample(isTraced)
+}
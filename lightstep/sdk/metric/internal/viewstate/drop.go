@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/drop"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// dropInstrument is the Instrument of a view with the Drop
+// aggregation where one is required, e.g., as a fallback.  It has no
+// storage and no Collector, its accumulators allocate nothing.
+type dropInstrument[N number.Any, Traits number.Traits[N]] struct{}
+
+// dropAccumulator updates the zero-sized Drop storage, discarding
+// every measurement.
+type dropAccumulator[N number.Any, Traits number.Traits[N]] struct {
+	state drop.State[N, Traits]
+}
+
+var (
+	_ Instrument       = dropInstrument[int64, number.Int64Traits]{}
+	_ Updater[float64] = dropAccumulator[float64, number.Float64Traits]{}
+)
+
+// newDropInstrument returns a dropInstrument for the number kind.
+func newDropInstrument(desc sdkinstrument.Descriptor) Instrument {
+	if desc.NumberKind == number.Float64Kind {
+		return dropInstrument[float64, number.Float64Traits]{}
+	}
+	return dropInstrument[int64, number.Int64Traits]{}
+}
+
+func (dropInstrument[N, Traits]) NewAccumulator(_ attribute.Set) Accumulator {
+	return dropAccumulator[N, Traits]{}
+}
+
+func (dropInstrument[N, Traits]) NewAccumulatorWithMetadata(_, _ attribute.Set) Accumulator {
+	return dropAccumulator[N, Traits]{}
+}
+
+func (dropInstrument[N, Traits]) Reset() {
+}
+
+func (dropInstrument[N, Traits]) ResetSeries(_ attribute.Set, _ time.Time) {
+}
+
+func (dropInstrument[N, Traits]) PreRegister(_ ...attribute.Set) {
+}
+
+func (dropInstrument[N, Traits]) SetEnabled(_ bool) {
+}
+
+func (a dropAccumulator[N, Traits]) Update(value N, ex aggregator.ExemplarBits) {
+	drop.Methods[N, Traits]{}.Update(&a.state, value, ex)
+}
+
+func (a dropAccumulator[N, Traits]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	drop.Methods[N, Traits]{}.UpdateBatch(&a.state, value, count, ex)
+}
+
+func (dropAccumulator[N, Traits]) MaySample(_ bool) bool {
+	return false
+}
+
+func (dropAccumulator[N, Traits]) SnapshotAndProcess(_ bool) error {
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// seriesEviction removes the least recently used series of a
+// synchronous instrument with cumulative temporality once it holds
+// the number of series configured by view.WithSeriesBudget.  Only
+// series without accumulator references are evicted, since no
+// reference can be added without the instrument lock, so nothing
+// merges into a series after its eviction.  Each collection ranks
+// these candidates by the number of collections since they had
+// references.  Synchronized by the instrument lock.
+type seriesEviction[Storage any] struct {
+	limit int
+
+	// candidates are the series without references at the last
+	// collection, the least recently used last.
+	candidates []seriesEntry[Storage]
+
+	// evicting is set after the first eviction, after which
+	// new series are passed to onCreate.
+	evicting bool
+
+	// onEvict receives each evicted series after it is removed
+	// from the data map.  onCreate receives each series created
+	// after the first eviction.
+	onEvict  func(set attribute.Set, entry *storageHolder[Storage, int64])
+	onCreate func(set attribute.Set)
+}
+
+// seriesEntry is a series of the data map.
+type seriesEntry[Storage any] struct {
+	set   attribute.Set
+	entry *storageHolder[Storage, int64]
+}
+
+// newSeriesEviction returns a seriesEviction for limit series.
+func newSeriesEviction[Storage any](
+	limit int,
+	onEvict func(attribute.Set, *storageHolder[Storage, int64]),
+	onCreate func(attribute.Set),
+) *seriesEviction[Storage] {
+	return &seriesEviction[Storage]{
+		limit:    limit,
+		onEvict:  onEvict,
+		onCreate: onCreate,
+	}
+}
+
+// makeRoom evicts candidates until data holds fewer than the limit,
+// or none remain, when kvs is a new series.  Candidates that were
+// removed or gained references since the collection are skipped.
+// Returns true when kvs is a new series.
+func (e *seriesEviction[Storage]) makeRoom(data map[attribute.Set]*storageHolder[Storage, int64], kvs attribute.Set) bool {
+	if _, has := data[kvs]; has {
+		return false
+	}
+	for len(data) >= e.limit && len(e.candidates) != 0 {
+		last := e.candidates[len(e.candidates)-1]
+		e.candidates[len(e.candidates)-1] = seriesEntry[Storage]{}
+		e.candidates = e.candidates[:len(e.candidates)-1]
+
+		if data[last.set] != last.entry || atomic.LoadInt64(&last.entry.auxiliary) != 0 {
+			continue
+		}
+		delete(data, last.set)
+		e.evicting = true
+		e.onEvict(last.set, last.entry)
+	}
+	return true
+}
+
+// created passes a new series to onCreate after the first eviction.
+func (e *seriesEviction[Storage]) created(kvs attribute.Set) {
+	if e.evicting {
+		e.onCreate(kvs)
+	}
+}
+
+// observe counts the collections without references of a series,
+// adding it to the candidates unless it is the overflow set.  Called
+// for each series of a collection, before rank.
+func (e *seriesEviction[Storage]) observe(set, overflow attribute.Set, entry *storageHolder[Storage, int64]) {
+	if atomic.LoadInt64(&entry.auxiliary) != 0 {
+		entry.periods = 0
+		return
+	}
+	entry.periods++
+	if set != overflow {
+		e.candidates = append(e.candidates, seriesEntry[Storage]{set: set, entry: entry})
+	}
+}
+
+// reset discards the candidates, before observing a collection or
+// for Reset.
+func (e *seriesEviction[Storage]) reset() {
+	clear(e.candidates)
+	e.candidates = e.candidates[:0]
+}
+
+// rank orders the candidates observed by a collection, the least
+// recently used last.
+func (e *seriesEviction[Storage]) rank() {
+	slices.SortFunc(e.candidates, func(a, b seriesEntry[Storage]) int {
+		return cmp.Compare(a.entry.periods, b.entry.periods)
+	})
+}
@@ -0,0 +1,270 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/multierr"
+)
+
+// compileFallback returns the fallback instrument for the leaf,
+// compiling it on first use.  The fallback is named for the primary
+// with view.FallbackSuffix and entered in the names map, so that
+// instruments with the same name are reported as conflicts.  With
+// view.FallbackForInterval, it has delta temporality.  The caller
+// holds the compiler lock.
+func (v *Compiler) compileFallback(ik sdkinstrument.Kind, primary leafInstrument, behavior singleBehavior, conflicts *ViewConflictsBuilder) (leafInstrument, error) {
+	name := primary.Descriptor().Name + view.FallbackSuffix
+	if fallback, ok := v.fallbacks[primary]; ok {
+		v.addNameConflict(name, conflicts)
+		return fallback, nil
+	}
+	behavior.desc.Name = name
+	behavior.kind = behavior.fallback
+	behavior.fallback = aggregation.UndefinedKind
+	behavior.overflowCount = false
+	if behavior.fallbackPolicy == view.FallbackForInterval {
+		behavior.tempo = aggregation.DeltaTemporality
+		behavior.eitherTempo = false
+	}
+
+	if err := checkSemanticCompatibility(ik, &behavior); err != nil {
+		return nil, fmt.Errorf("fallback: %w", err)
+	}
+
+	var fallback leafInstrument
+	switch behavior.desc.NumberKind {
+	case number.Int64Kind:
+		fallback = buildView[int64, number.Int64Traits](behavior)
+	case number.Float64Kind:
+		fallback = buildView[float64, number.Float64Traits](behavior)
+	}
+	v.collectors = append(v.collectors, fallback)
+	v.fallbacks[primary] = fallback
+	v.names[name] = append(v.names[name], fallback)
+	v.addNameConflict(name, conflicts)
+	return fallback, nil
+}
+
+// addNameConflict adds a conflict when more than one instrument has
+// the name.  The caller holds the compiler lock.
+func (v *Compiler) addNameConflict(name string, conflicts *ViewConflictsBuilder) {
+	existing := v.names[name]
+	if len(existing) < 2 {
+		return
+	}
+	c := Conflict{
+		Duplicates: make([]Duplicate, len(existing)),
+	}
+	for i := range existing {
+		c.Duplicates[i] = existing[i]
+	}
+	conflicts.Add(v.views.Name, c)
+}
+
+// newFallbackInstrument returns an Instrument whose accumulators
+// switch to the fallback instrument when the primary fails.
+func newFallbackInstrument(desc sdkinstrument.Descriptor, primary, fallback Instrument, policy view.FallbackPolicy) Instrument {
+	if desc.NumberKind == number.Float64Kind {
+		return fallbackInstrument[float64]{
+			name:     desc.Name,
+			primary:  primary,
+			fallback: fallback,
+			policy:   policy,
+		}
+	}
+	return fallbackInstrument[int64]{
+		name:     desc.Name,
+		primary:  primary,
+		fallback: fallback,
+		policy:   policy,
+	}
+}
+
+// fallbackInstrument is a primary instrument with a fallback.
+type fallbackInstrument[N number.Any] struct {
+	name     string
+	primary  Instrument
+	fallback Instrument
+	policy   view.FallbackPolicy
+}
+
+func (fi fallbackInstrument[N]) NewAccumulator(kvs attribute.Set) Accumulator {
+	return fi.NewAccumulatorWithMetadata(kvs, *attribute.EmptySet())
+}
+
+// NewAccumulatorWithMetadata returns the primary accumulator,
+// wrapped to create a fallback accumulator for the same series when
+// the primary fails.  The fallback accumulator is not created until
+// then, so that the fallback instrument has no series otherwise.
+func (fi fallbackInstrument[N]) NewAccumulatorWithMetadata(kvs, metadata attribute.Set) Accumulator {
+	acc := fi.primary.NewAccumulatorWithMetadata(kvs, metadata)
+	if _, ok := acc.(droppedAccumulator[N]); ok {
+		return acc
+	}
+	return &fallbackAccumulator[N]{
+		inst:     fi,
+		primary:  acc,
+		kvs:      kvs,
+		metadata: metadata,
+	}
+}
+
+// Reset resets the primary and the fallback instrument.
+func (fi fallbackInstrument[N]) Reset() {
+	fi.primary.Reset()
+	fi.fallback.Reset()
+}
+
+// ResetSeries resets the series in the primary and the fallback
+// instrument.
+func (fi fallbackInstrument[N]) ResetSeries(kvs attribute.Set, when time.Time) {
+	fi.primary.ResetSeries(kvs, when)
+	fi.fallback.ResetSeries(kvs, when)
+}
+
+// PreRegister registers the attribute sets in the primary and the
+// fallback instrument.
+func (fi fallbackInstrument[N]) PreRegister(sets ...attribute.Set) {
+	fi.primary.PreRegister(sets...)
+	fi.fallback.PreRegister(sets...)
+}
+
+// SetEnabled enables or disables the primary and the fallback
+// instrument.
+func (fi fallbackInstrument[N]) SetEnabled(enabled bool) {
+	fi.primary.SetEnabled(enabled)
+	fi.fallback.SetEnabled(enabled)
+}
+
+// fallbackAccumulator passes measurements to the primary
+// accumulator until it panics, then to the fallback accumulator.
+type fallbackAccumulator[N number.Any] struct {
+	inst     fallbackInstrument[N]
+	primary  Accumulator
+	kvs      attribute.Set
+	metadata attribute.Set
+
+	// failed is set while measurements go to the fallback.
+	failed atomic.Bool
+
+	// lock protects fallback, which is created on the first
+	// failure and kept until release, or until the end of the
+	// interval with view.FallbackForInterval.  Measurements are
+	// recorded in the fallback with the lock held, so that none
+	// follow its release.
+	lock     sync.Mutex
+	fallback Accumulator
+}
+
+var _ Updater[float64] = &fallbackAccumulator[float64]{}
+
+func (a *fallbackAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
+	if !a.failed.Load() && a.tryPrimary(func() { a.primary.(Updater[N]).Update(value, ex) }) {
+		return
+	}
+	a.withFallback(func(fallback Updater[N]) {
+		fallback.Update(value, ex)
+	})
+}
+
+func (a *fallbackAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	if !a.failed.Load() && a.tryPrimary(func() { a.primary.(Updater[N]).UpdateBatch(value, count, ex) }) {
+		return
+	}
+	a.withFallback(func(fallback Updater[N]) {
+		fallback.UpdateBatch(value, count, ex)
+	})
+}
+
+func (a *fallbackAccumulator[N]) MaySample(isTraced bool) (may bool) {
+	if a.failed.Load() {
+		a.withFallback(func(fallback Updater[N]) {
+			may = fallback.MaySample(isTraced)
+		})
+		return may
+	}
+	return a.primary.(Updater[N]).MaySample(isTraced)
+}
+
+// SnapshotAndProcess processes both accumulators.  A merge failure of
+// the primary switches the series to the fallback, like a panic.
+// With the FallbackForInterval policy, the primary is used again
+// afterward.
+func (a *fallbackAccumulator[N]) SnapshotAndProcess(release bool) error {
+	var err error
+	a.tryPrimary(func() { err = a.primary.SnapshotAndProcess(release) })
+	if err != nil {
+		a.failed.Store(true)
+	}
+
+	// With FallbackForInterval, the fallback series ends with
+	// the interval, so the series returns to the primary alone.
+	forInterval := a.inst.policy == view.FallbackForInterval
+
+	a.lock.Lock()
+	fallback := a.fallback
+	if forInterval || release {
+		a.fallback = nil
+	}
+	a.lock.Unlock()
+
+	if fallback != nil {
+		err = multierr.Append(err, fallback.SnapshotAndProcess(forInterval || release))
+	}
+	if forInterval {
+		a.failed.Store(false)
+	}
+	return err
+}
+
+// tryPrimary calls f, returning false after recovering from a panic,
+// which is reported and switches the series to the fallback.
+func (a *fallbackAccumulator[N]) tryPrimary(f func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.failed.Store(true)
+			doevery.TimePeriod(30*time.Second, func() {
+				otel.Handle(fmt.Errorf("%s: %w: %v", a.inst.name, aggregator.ErrAggregationFailed, r))
+			})
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
+
+// withFallback calls f with the fallback accumulator, creating it if
+// necessary, with the lock held.
+func (a *fallbackAccumulator[N]) withFallback(f func(Updater[N])) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.fallback == nil {
+		a.fallback = a.inst.fallback.NewAccumulatorWithMetadata(a.kvs, a.metadata)
+	}
+	f(a.fallback.(Updater[N]))
+}
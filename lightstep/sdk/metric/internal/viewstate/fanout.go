@@ -0,0 +1,123 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"sort"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FanOut implements data.Collector.
+func (metric *instrumentBase[N, Storage, Auxiliary, Methods]) FanOut(volatile []attribute.Key, output *[]data.FanOut) {
+	metric.instLock.Lock()
+	sets := make([]attribute.Set, 0, len(metric.data))
+	for set := range metric.data {
+		sets = append(sets, set)
+	}
+	metric.instLock.Unlock()
+
+	appendFanOut(metric.desc, volatile, sets, output)
+}
+
+// FanOut (special case) considers the prior map as well as the
+// current data, since data is emptied on Collect().
+func (p *statefulAsyncInstrument[N, Storage, Methods]) FanOut(volatile []attribute.Key, output *[]data.FanOut) {
+	p.instLock.Lock()
+	sets := make([]attribute.Set, 0, len(p.prior)+len(p.data))
+	for set := range p.prior {
+		sets = append(sets, set)
+	}
+	for set := range p.data {
+		if _, has := p.prior[set]; !has {
+			sets = append(sets, set)
+		}
+	}
+	p.instLock.Unlock()
+
+	appendFanOut(p.desc, volatile, sets, output)
+}
+
+// fanOutGroup accumulates one data.FanOut.
+type fanOutGroup struct {
+	series int
+	values map[attribute.Key]map[attribute.Value]struct{}
+}
+
+// appendFanOut groups the series by their attributes apart from the
+// volatile keys, appending the groups to output in decreasing order
+// of series count.  The overflow series is grouped like any other.
+func appendFanOut(desc sdkinstrument.Descriptor, volatile []attribute.Key, sets []attribute.Set, output *[]data.FanOut) {
+	if len(sets) == 0 {
+		return
+	}
+	isVolatile := keyFilter{}
+	for _, k := range volatile {
+		isVolatile[k] = struct{}{}
+	}
+	stable := func(kv attribute.KeyValue) bool {
+		return !isVolatile.filter(kv)
+	}
+
+	groups := map[attribute.Set]*fanOutGroup{}
+	for _, set := range sets {
+		group, _ := set.Filter(stable)
+		g := groups[group]
+		if g == nil {
+			g = &fanOutGroup{
+				values: map[attribute.Key]map[attribute.Value]struct{}{},
+			}
+			groups[group] = g
+		}
+		g.series++
+
+		for _, k := range volatile {
+			v, ok := set.Value(k)
+			if !ok {
+				continue
+			}
+			vs := g.values[k]
+			if vs == nil {
+				vs = map[attribute.Value]struct{}{}
+				g.values[k] = vs
+			}
+			vs[v] = struct{}{}
+		}
+	}
+
+	start := len(*output)
+	for group, g := range groups {
+		values := make(map[attribute.Key]int, len(g.values))
+		for k, vs := range g.values {
+			values[k] = len(vs)
+		}
+		*output = append(*output, data.FanOut{
+			Descriptor: desc,
+			Attributes: group,
+			Series:     g.series,
+			Values:     values,
+		})
+	}
+	added := (*output)[start:]
+	sort.Slice(added, func(i, j int) bool {
+		if added[i].Series != added[j].Series {
+			return added[i].Series > added[j].Series
+		}
+		return added[i].Attributes.Encoded(attribute.DefaultEncoder()) <
+			added[j].Attributes.Encoded(attribute.DefaultEncoder())
+	})
+}
@@ -0,0 +1,281 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// overflowSeriesLimit is the most attribute sets counted by an
+// overflowSeries for each temporality.
+const overflowSeriesLimit = 1 << 14
+
+// overflowSeries counts the distinct attribute sets with
+// measurements recorded in the overflow attribute set, by the hash
+// of their encoding, configured by view.WithOverflowSeriesCount.
+// The interval counts are emptied by each delta collection; the
+// total counts are kept for cumulative collection.  Either is nil
+// when the instrument does not collect with its temporality.  Each
+// count stops growing at limit.
+//
+// overflowSeries is the Collector of the instrument's count, which
+// follows the instrument in the Compiler's collectors.
+type overflowSeries struct {
+	desc  sdkinstrument.Descriptor
+	tempo aggregation.Temporality
+	limit int
+
+	// either is set when the instrument reports the
+	// temporality given to CollectTemporality.
+	either bool
+
+	lock     sync.Mutex
+	seed     maphash.Seed
+	interval map[uint64]struct{}
+	total    map[uint64]struct{}
+}
+
+var _ data.Collector = &overflowSeries{}
+
+// newOverflowSeries returns an overflowSeries for an instrument with
+// the behavior.
+func newOverflowSeries(behavior singleBehavior) *overflowSeries {
+	o := &overflowSeries{
+		desc: sdkinstrument.NewDescriptor(
+			behavior.desc.Name+view.OverflowSeriesSuffix,
+			sdkinstrument.AsyncGauge,
+			number.Int64Kind,
+			"distinct attribute sets recorded in the overflow series of "+behavior.desc.Name,
+			"{series}",
+		),
+		tempo:  behavior.tempo,
+		limit:  overflowSeriesLimit,
+		either: behavior.eitherTempo,
+		seed:   maphash.MakeSeed(),
+	}
+	if o.either || o.tempo != aggregation.DeltaTemporality {
+		o.total = map[uint64]struct{}{}
+	}
+	if o.either || o.tempo == aggregation.DeltaTemporality {
+		o.interval = map[uint64]struct{}{}
+	}
+	return o
+}
+
+// key returns the hash of an attribute set.
+func (o *overflowSeries) key(kvs attribute.Set) uint64 {
+	return maphash.String(o.seed, kvs.Encoded(attribute.DefaultEncoder()))
+}
+
+// note counts the attribute set with the key.
+func (o *overflowSeries) note(key uint64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	noteKey(o.interval, key, o.limit)
+	noteKey(o.total, key, o.limit)
+}
+
+// noteKey adds key to counts unless it is nil or full.
+func noteKey(counts map[uint64]struct{}, key uint64, limit int) {
+	if counts == nil || len(counts) >= limit {
+		return
+	}
+	counts[key] = struct{}{}
+}
+
+// count returns the number of attribute sets counted for the
+// temporality, emptying the interval counts when reset is set.
+func (o *overflowSeries) count(delta, reset bool) int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if !delta {
+		return len(o.total)
+	}
+	n := len(o.interval)
+	if reset {
+		clear(o.interval)
+	}
+	return n
+}
+
+// reset empties both counts, for Reset.
+func (o *overflowSeries) reset() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	clear(o.interval)
+	clear(o.total)
+}
+
+// Collect appends the count when it is not zero.
+func (o *overflowSeries) Collect(seq data.Sequence, output *[]data.Instrument) {
+	o.CollectTemporality(seq, o.tempo, output)
+}
+
+// CollectContext appends the count like Collect, since it is
+// collected without holding the instrument lock.
+func (o *overflowSeries) CollectContext(_ context.Context, seq data.Sequence, output *[]data.Instrument) error {
+	o.Collect(seq, output)
+	return nil
+}
+
+// CollectTemporality appends the count with the temporality given
+// when the instrument reports it, otherwise as Collect.
+func (o *overflowSeries) CollectTemporality(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	o.appendCount(seq, o.temporality(tempo), true, output)
+}
+
+// Peek appends the count like CollectTemporality, without emptying
+// the interval counts.
+func (o *overflowSeries) Peek(seq data.Sequence, tempo aggregation.Temporality, output *[]data.Instrument) {
+	o.appendCount(seq, o.temporality(tempo), false, output)
+}
+
+// CollectFunc passes the count to emit like Collect.
+func (o *overflowSeries) CollectFunc(seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) {
+	if pt, ok := o.point(seq, o.tempo, true); ok {
+		emit(o.desc, pt)
+	}
+}
+
+// temporality returns the temporality of a collection requesting
+// tempo.
+func (o *overflowSeries) temporality(tempo aggregation.Temporality) aggregation.Temporality {
+	if o.either && tempo != aggregation.UndefinedTemporality {
+		return tempo
+	}
+	return o.tempo
+}
+
+// appendCount appends an instrument with the count to output when it
+// is not zero.
+func (o *overflowSeries) appendCount(seq data.Sequence, tempo aggregation.Temporality, reset bool, output *[]data.Instrument) {
+	pt, ok := o.point(seq, tempo, reset)
+	if !ok {
+		return
+	}
+	ioutput := data.ReallocateFrom(output)
+	ioutput.Descriptor = o.desc
+	ioutput.Points = append(ioutput.Points[:0], pt)
+}
+
+// point returns the point of the count for the temporality, and
+// false when the count is zero.
+func (o *overflowSeries) point(seq data.Sequence, tempo aggregation.Temporality, reset bool) (data.Point, bool) {
+	delta := tempo == aggregation.DeltaTemporality
+	n := o.count(delta, reset)
+	if n == 0 {
+		return data.Point{}, false
+	}
+	start := seq.Start
+	if delta {
+		start = seq.Last
+	}
+	return data.Point{
+		Start:       start,
+		End:         seq.Now,
+		Aggregation: gauge.NewInt64(int64(n)),
+		Temporality: tempo,
+	}, true
+}
+
+// InMemorySize returns the number of attribute sets counted.
+func (o *overflowSeries) InMemorySize() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return len(o.interval) + len(o.total)
+}
+
+// InMemoryBytes estimates the memory of the counts.
+func (o *overflowSeries) InMemoryBytes() int {
+	return o.InMemorySize() * int(unsafe.Sizeof(uint64(0)))
+}
+
+// FanOut has no series to group.
+func (o *overflowSeries) FanOut([]attribute.Key, *[]data.FanOut) {}
+
+// overflowCounted is implemented by synchronous instruments, whose
+// overflowCounter is not nil when configured.
+type overflowCounted interface {
+	overflowCounter() *overflowSeries
+}
+
+func (c *compiledSyncBase[N, Storage, Methods, Samp]) overflowCounter() *overflowSeries {
+	return c.overflowSeries
+}
+
+// overflowAccumulator is the accumulator of an attribute set whose
+// measurements are recorded in the overflow attribute set.  With
+// series, an interval with measurements counts the attribute set
+// once.  With notify, each measurement calls it, for
+// Performance.OnOverflow.
+type overflowAccumulator[N number.Any] struct {
+	Accumulator
+	series  *overflowSeries
+	key     uint64
+	updated atomic.Bool
+	notify  func()
+}
+
+var _ Updater[float64] = &overflowAccumulator[float64]{}
+
+func (a *overflowAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
+	a.noteUpdate()
+	a.Accumulator.(Updater[N]).Update(value, ex)
+}
+
+func (a *overflowAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	if count != 0 {
+		a.noteUpdate()
+	}
+	a.Accumulator.(Updater[N]).UpdateBatch(value, count, ex)
+}
+
+// noteUpdate records a measurement of the attribute set.
+func (a *overflowAccumulator[N]) noteUpdate() {
+	if a.series != nil {
+		a.updated.Store(true)
+	}
+	if a.notify != nil {
+		a.notify()
+	}
+}
+
+func (a *overflowAccumulator[N]) MaySample(isTraced bool) bool {
+	return a.Accumulator.(Updater[N]).MaySample(isTraced)
+}
+
+func (a *overflowAccumulator[N]) SnapshotAndProcess(release bool) error {
+	if a.series != nil && a.updated.Swap(false) {
+		a.series.note(a.key)
+	}
+	return a.Accumulator.SnapshotAndProcess(release)
+}
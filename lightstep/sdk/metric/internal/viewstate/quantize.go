@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"math"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+)
+
+// validQuantum returns the quantum, or zero when it does not enable
+// quantization.
+func validQuantum(quantum float64) float64 {
+	if quantum > 0 && !math.IsInf(quantum, 0) {
+		return quantum
+	}
+	return 0
+}
+
+// quantize rounds the value to the nearest multiple of quantum, with
+// ties rounded to the even multiple.
+func quantize[N number.Any](value N, quantum float64) N {
+	f := float64(value)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return value
+	}
+	return N(math.RoundToEven(f/quantum) * quantum)
+}
+
+// quantizeAccumulator returns the accumulator, wrapped to quantize
+// measurements when quantum is non-zero.
+func quantizeAccumulator[N number.Any](acc Accumulator, quantum float64) Accumulator {
+	if quantum == 0 {
+		return acc
+	}
+	if _, ok := acc.(droppedAccumulator[N]); ok {
+		return acc
+	}
+	return quantizedAccumulator[N]{
+		Accumulator: acc,
+		quantum:     quantum,
+	}
+}
+
+// quantizedAccumulator quantizes measurements before they are passed
+// to the underlying accumulator.
+type quantizedAccumulator[N number.Any] struct {
+	Accumulator
+	quantum float64
+}
+
+var _ Updater[float64] = quantizedAccumulator[float64]{}
+
+func (a quantizedAccumulator[N]) Update(value N, ex aggregator.ExemplarBits) {
+	a.Accumulator.(Updater[N]).Update(quantize(value, a.quantum), ex)
+}
+
+func (a quantizedAccumulator[N]) UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits) {
+	a.Accumulator.(Updater[N]).UpdateBatch(quantize(value, a.quantum), count, ex)
+}
+
+func (a quantizedAccumulator[N]) MaySample(isTraced bool) bool {
+	return a.Accumulator.(Updater[N]).MaySample(isTraced)
+}
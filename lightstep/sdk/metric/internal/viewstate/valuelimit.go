@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxValueLimitKeys is the number of distinct keys a valueLimiter
+// tracks.  Values of keys beyond this are always replaced by
+// overflowValue, so the tracking memory is bounded even when the
+// number of keys is not.
+const maxValueLimitKeys = 128
+
+// overflowValue replaces attribute values that exceed the per-key
+// value limit.
+var overflowValue = attribute.StringValue("_OTHER")
+
+// valueLimiter limits the number of distinct values of each
+// attribute key.  Values are tracked exactly, up to the limit; once
+// a key has reached its limit, new values are not tracked and are
+// replaced by overflowValue.  The memory used is therefore bounded
+// by maxValueLimitKeys times the limit.  Tracking is not reset when
+// series are removed, so the values admitted are the first ones seen
+// for the lifetime of the instrument.
+//
+// valueLimiter is not synchronized; callers hold the instrument lock.
+type valueLimiter struct {
+	limit  int
+	values map[attribute.Key]map[attribute.Value]struct{}
+}
+
+// newValueLimiter returns a valueLimiter, or nil when the limit is
+// zero, meaning unlimited.
+func newValueLimiter(limit uint32) *valueLimiter {
+	if limit == 0 {
+		return nil
+	}
+	return &valueLimiter{
+		limit:  int(limit),
+		values: map[attribute.Key]map[attribute.Value]struct{}{},
+	}
+}
+
+// apply returns the set with values that exceed the limit replaced.
+func (vl *valueLimiter) apply(kvs attribute.Set) attribute.Set {
+	if vl == nil {
+		return kvs
+	}
+	var out []attribute.KeyValue
+	for iter := kvs.Iter(); iter.Next(); {
+		idx, kv := iter.IndexedAttribute()
+		if vl.admit(kv) {
+			if out != nil {
+				out = append(out, kv)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]attribute.KeyValue, idx, kvs.Len())
+			for i := 0; i < idx; i++ {
+				out[i], _ = kvs.Get(i)
+			}
+		}
+		out = append(out, attribute.KeyValue{
+			Key:   kv.Key,
+			Value: overflowValue,
+		})
+	}
+	if out == nil {
+		return kvs
+	}
+	return attribute.NewSet(out...)
+}
+
+// admit returns true if the value is, or can become, one of the
+// tracked values of its key.
+func (vl *valueLimiter) admit(kv attribute.KeyValue) bool {
+	seen, ok := vl.values[kv.Key]
+	if !ok {
+		if len(vl.values) >= maxValueLimitKeys {
+			return false
+		}
+		seen = map[attribute.Value]struct{}{}
+		vl.values[kv.Key] = seen
+	}
+	if _, ok := seen[kv.Value]; ok {
+		return true
+	}
+	if len(seen) >= vl.limit {
+		return false
+	}
+	seen[kv.Value] = struct{}{}
+	return true
+}
+
+// size returns the number of tracked values.
+func (vl *valueLimiter) size() int {
+	if vl == nil {
+		return 0
+	}
+	var sz int
+	for _, seen := range vl.values {
+		sz += len(seen)
+	}
+	return sz
+}
@@ -19,16 +19,21 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	histostruct "github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
@@ -76,6 +81,10 @@ type Compiler struct {
 	// names is the map of output names for metrics
 	// produced by this compiler.
 	names map[string][]leafInstrument
+
+	// fallbacks is the fallback instrument of each leaf
+	// instrument compiled with a fallback aggregation.
+	fallbacks map[leafInstrument]leafInstrument
 }
 
 // Instrument is a compiled implementation of an instrument
@@ -92,6 +101,45 @@ type Instrument interface {
 	// called since the last collection and to ensure that each
 	// of them has SnapshotAndProcess() called.
 	NewAccumulator(kvs attribute.Set) Accumulator
+
+	// NewAccumulatorWithMetadata is NewAccumulator with
+	// non-identifying metadata for the series, which is output
+	// in the Metadata field of its points.  When accumulators
+	// for the same series provide different metadata, the keys
+	// are combined and the first value provided for each key is
+	// kept.  Metadata is not kept for the overflow series.
+	NewAccumulatorWithMetadata(kvs, metadata attribute.Set) Accumulator
+
+	// Reset discards the series held in memory without
+	// producing output, for example to start a new measurement
+	// window.  Accumulators in use remain valid: their series
+	// are emptied instead of removed, and measurements they have
+	// not yet processed are kept for the next collection.
+	Reset()
+
+	// ResetSeries declares that the source of the measurements
+	// for one attribute set was reset at the given time, e.g.,
+	// when delta measurements come from a process that
+	// restarted.  Cumulative synchronous views empty the series
+	// and report the time as the start of its points; other
+	// views are not affected.
+	ResetSeries(kvs attribute.Set, when time.Time)
+
+	// PreRegister creates the series for each attribute set
+	// ahead of its first accumulator, so that the first
+	// measurement does not pay for its creation.  Synchronous
+	// views keep a pre-registered series until it has been
+	// used; asynchronous views are not affected.
+	PreRegister(sets ...attribute.Set)
+
+	// SetEnabled enables or disables the instrument at runtime;
+	// compiled instruments are enabled.  A disabled instrument
+	// discards measurements, including those of accumulators
+	// already in use, at the cost of an atomic load, and the
+	// accumulators it returns create no series until it is
+	// enabled.  The series it has accumulated are kept and
+	// collected as usual; call Reset as well to discard them.
+	SetEnabled(enabled bool)
 }
 
 // SampleFilter's indicates when exemplars may be sampled.
@@ -111,6 +159,12 @@ type Updater[N number.Any] interface {
 	// is captured by the accumulator snapshot.
 	Update(value N, ex aggregator.ExemplarBits)
 
+	// UpdateBatch captures count measurements of the same value,
+	// pre-aggregated by the caller, with the effect of count
+	// calls to Update.  An exemplar considers the batch as one
+	// event.  Asynchronous instruments capture the value once.
+	UpdateBatch(value N, count uint64, ex aggregator.ExemplarBits)
+
 	SampleFilter
 }
 
@@ -125,14 +179,35 @@ type Accumulator interface {
 	// aggregator.  The attribute.Set is possibly filtered, after
 	// which the snapshot is merged into the output.
 	//
-	// There is no return value from this method; the caller can
-	// safely forget an Accumulator after this method is called,
-	// provided Update is not used again.
+	// The caller can safely forget an Accumulator after this
+	// method is called, provided Update is not used again.  An
+	// error wrapping aggregator.ErrMergeFailed is returned when
+	// the snapshot could not be merged, in which case its data
+	// is lost; the Accumulator remains usable.
 	//
 	// When `release` is true, this is the last time the Accumulator
 	// will be snapshot/processed (according to the caller's
 	// reference counting) and it can be forgotten.
-	SnapshotAndProcess(release bool)
+	SnapshotAndProcess(release bool) error
+}
+
+// Folder is implemented by the Collectors of a Compiler.
+type Folder interface {
+	// Fold reduces the points of an instrument output by the
+	// same Collector's last Collect to at most limit, merging
+	// the excess into the overflow attribute set.
+	Fold(inst *data.Instrument, limit int)
+}
+
+// Carrier is implemented by the Collectors of a Compiler that output
+// delta temporality, apart from the series counts of
+// view.WithOverflowSeriesCount.
+type Carrier interface {
+	// Carry returns the points of an instrument output by the
+	// same Collector's last Collect, with delta temporality, to
+	// its state, so that the next Collect reports them.  The
+	// points are not modified.
+	Carry(inst *data.Instrument)
 }
 
 // leafInstrument is one of the (synchronous or asynchronous),
@@ -145,12 +220,18 @@ type leafInstrument interface {
 	data.Collector
 	// Duplicate is how other instruments this in a conflict.
 	Duplicate
+	// Folder reduces the output for a point limit.
+	Folder
 
 	// mergeDescription handles the special case allowing
 	// descriptions to be merged instead of conflict.
 	mergeDescription(string)
 }
 
+// shadowSuffix names the output of a shadow aggregation.  Compile
+// uses "view" as a variable name.
+const shadowSuffix = view.ShadowSuffix
+
 // singleBehavior is one instrument-view behavior, including the
 // original instrument details, the aggregation kind and temporality,
 // aggregator configuration, and optional keys to filter.
@@ -181,6 +262,85 @@ type singleBehavior struct {
 	// keysFilter (if non-nil) is the constructed keys filter.
 	keysFilter *attribute.Filter
 
+	// overflow is the attribute set used once the cardinality
+	// limit is reached, from the Performance settings.
+	overflow attribute.Set
+
+	// transform (if non-nil) is the configured attribute set
+	// transformation.
+	transform view.AttributeTransformFunction
+
+	// normalize is the configured attribute value normalization.
+	normalize view.NormalizationRules
+
+	// valueLimit is the configured per-key attribute value limit.
+	valueLimit uint32
+
+	// quantum is the configured measurement quantization.
+	quantum float64
+
+	// rollup is set when synchronous measurements are also
+	// recorded in the empty attribute set.
+	rollup bool
+
+	// dedup is the window for discarding repeated synchronous
+	// measurements.
+	dedup time.Duration
+
+	// emitEmpty is set when synchronous delta series without
+	// measurements are reported.
+	emitEmpty bool
+
+	// lastUpdate is set when the time of each series' latest
+	// measurement is recorded.
+	lastUpdate bool
+
+	// onOverflow is Performance.OnOverflow.
+	onOverflow func(instrumentName string, set attribute.Set)
+
+	// leakPeriods and onLeak are Performance.LeakCollectionPeriods
+	// and Performance.OnLeak.
+	leakPeriods uint32
+	onLeak      func(instrumentName string, series int)
+
+	// inactivePeriods is Performance.InactiveCollectionPeriods.
+	inactivePeriods uint32
+
+	// interner is Performance.AttributeInterner.
+	interner *sdkinstrument.AttributeInterner
+
+	// overflowCount is set when synchronous instruments count
+	// the attribute sets recorded in the overflow set.
+	overflowCount bool
+
+	// eitherTempo is set when synchronous instruments keep state
+	// for collection with either temporality.
+	eitherTempo bool
+
+	// suppress is set when unchanged asynchronous cumulative
+	// series are omitted.
+	suppress bool
+
+	// deltaGauge is set when asynchronous gauges with delta
+	// temporality report successive differences.
+	deltaGauge bool
+
+	// seriesBudget is the number of series of synchronous
+	// cumulative instruments before the least recently used are
+	// evicted, or zero.
+	seriesBudget uint32
+
+	// fallback is the aggregation used by synchronous series
+	// after the primary aggregation fails, or UndefinedKind.
+	fallback aggregation.Kind
+
+	// fallbackPolicy is how long a series keeps the fallback.
+	fallbackPolicy view.FallbackPolicy
+
+	// shards is the number of storage shards used by
+	// synchronous accumulators, from the Performance settings.
+	shards uint32
+
 	// hinted is true when the aggregation was set
 	// programmatically via a hint. this bypasses semantic
 	// compatibility checking and allows hints to create a
@@ -192,9 +352,10 @@ type singleBehavior struct {
 func New(library instrumentation.Scope, views *view.Views) *Compiler {
 	views, _ = view.Validate(views)
 	return &Compiler{
-		library: library,
-		views:   views,
-		names:   map[string][]leafInstrument{},
+		library:   library,
+		views:     views,
+		names:     map[string][]leafInstrument{},
+		fallbacks: map[leafInstrument]leafInstrument{},
 	}
 }
 
@@ -215,7 +376,7 @@ func (v *Compiler) tryToApplyHint(instrument sdkinstrument.Descriptor) (_ sdkins
 		instrument.Kind,
 		instrument.NumberKind,
 	)
-	acfg = defCfg
+	acfg = v.unitHistogramConfig(instrument, defCfg)
 
 	// Check for required JSON symbols, empty strings, ...
 	if !strings.Contains(instrument.Description, "{") {
@@ -280,6 +441,23 @@ func (v *Compiler) tryToApplyHint(instrument sdkinstrument.Descriptor) (_ sdkins
 	if hint.Config.Exemplar.Size != 0 {
 		acfg.Exemplar.Size = hint.Config.Exemplar.Size
 	}
+	if hint.Config.Exemplar.MaxLinks != 0 {
+		acfg.Exemplar.MaxLinks = hint.Config.Exemplar.MaxLinks
+	}
+	if hint.Config.Exemplar.Reservoir != "" {
+		switch strings.ToLower(hint.Config.Exemplar.Reservoir) {
+		case "uniform":
+			acfg.Exemplar.Reservoir = aggregator.UniformReservoirKind
+		case "max":
+			acfg.Exemplar.Reservoir = aggregator.MaxReservoirKind
+		case "bucket":
+			acfg.Exemplar.Reservoir = aggregator.BucketReservoirKind
+		case "scaled_bucket":
+			acfg.Exemplar.Reservoir = aggregator.ScaledBucketReservoirKind
+		default:
+			otel.Handle(fmt.Errorf("unrecognized exemplar reservoir: %s", hint.Config.Exemplar.Reservoir))
+		}
+	}
 	return instrument, akind, tempo, acfg, defCfg, hinted
 }
 
@@ -309,15 +487,39 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 		if akind == aggregation.UndefinedKind {
 			akind = hintAkind
 		}
+		if akind == aggregation.DropKind {
+			// The hint drops the instrument.
+			continue
+		}
 
 		cf := singleBehavior{
-			fromName: instrument.Name,
-			desc:     viewDescriptor(instrument, view),
-			kind:     akind,
-			acfg:     pickAggConfig(hintAcfg, defCfg, view.AggregatorConfig()),
-			tempo:    tempo,
-			hinted:   hinted,
+			fromName:   instrument.Name,
+			desc:       viewDescriptor(instrument, view),
+			kind:       akind,
+			acfg:       v.unitHistogramConfig(instrument, pickAggConfig(hintAcfg, defCfg, view.AggregatorConfig())),
+			tempo:      tempo,
+			shards:     v.views.AccumulatorShards,
+			overflow:   pipeline.OverflowSet(v.views.OverflowAttributes),
+			transform:  view.AttributeTransform(),
+			normalize:  view.AttributeNormalization(),
+			valueLimit: view.AttributeValueLimit(),
+			quantum:    view.ValueQuantum(),
+			rollup:     view.RollupTotal(),
+			dedup:      view.DeduplicationWindow(),
+			emitEmpty:  view.EmptyDeltaPoints(),
+			lastUpdate: view.LastUpdateTime(),
+			onOverflow: v.views.OnOverflow,
+			hinted:     hinted,
 		}
+		cf.leakPeriods, cf.onLeak = v.views.LeakCollectionPeriods, v.views.OnLeak
+		cf.inactivePeriods = v.views.InactiveCollectionPeriods
+		cf.interner = v.views.AttributeInterner
+		cf.overflowCount = view.OverflowSeriesCount()
+		cf.eitherTempo = view.EitherTemporality()
+		cf.suppress = view.SuppressUnchanged()
+		cf.deltaGauge = view.DeltaGauge()
+		cf.seriesBudget = view.SeriesBudget()
+		cf.fallback, cf.fallbackPolicy = view.FallbackAggregation()
 
 		keys := view.Keys()
 		if keys != nil {
@@ -325,6 +527,15 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 			cf.keysFilter = keysToFilter(view.Keys())
 		}
 		behaviors = append(behaviors, cf)
+
+		if skind, sacfg := view.ShadowAggregation(); skind != aggregation.UndefinedKind && skind != aggregation.DropKind {
+			shadow := cf
+			shadow.desc.Name += shadowSuffix
+			shadow.kind = skind
+			shadow.acfg = sacfg
+			shadow.fallback = aggregation.UndefinedKind
+			behaviors = append(behaviors, shadow)
+		}
 	}
 
 	// If there were no matching views, set the default aggregation.
@@ -334,12 +545,20 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 
 		if akind != aggregation.DropKind {
 			behaviors = append(behaviors, singleBehavior{
-				fromName: instrument.Name,
-				desc:     instrument,
-				kind:     akind,
-				acfg:     acfg,
-				tempo:    tempo,
-				hinted:   hinted,
+				fromName:    instrument.Name,
+				desc:        instrument,
+				kind:        akind,
+				acfg:        acfg,
+				tempo:       tempo,
+				shards:      v.views.AccumulatorShards,
+				overflow:    pipeline.OverflowSet(v.views.OverflowAttributes),
+				onOverflow:  v.views.OnOverflow,
+				leakPeriods: v.views.LeakCollectionPeriods,
+				onLeak:      v.views.OnLeak,
+				interner:    v.views.AttributeInterner,
+				hinted:      hinted,
+
+				inactivePeriods: v.views.InactiveCollectionPeriods,
 			})
 		}
 	}
@@ -357,49 +576,27 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 		semanticErr := checkSemanticCompatibility(instrument.Kind, &behavior)
 
 		existingInsts := v.names[behavior.desc.Name]
-		var leaf leafInstrument
-
-		// Scan the existing instruments for a match.
-		for _, inst := range existingInsts {
-			// Test for equivalence among the fields that we
-			// cannot merge or will not convert, means the
-			// testing everything except the description for
-			// equality.
-
-			if inst.Aggregation() != behavior.kind {
-				continue
-			}
-			if inst.Descriptor().Kind.Synchronous() != behavior.desc.Kind.Synchronous() {
-				continue
-			}
-
-			if inst.Descriptor().Unit != behavior.desc.Unit {
-				continue
-			}
-			if inst.Descriptor().NumberKind != behavior.desc.NumberKind {
-				continue
-			}
-			if !equalConfigs(inst.Config(), behavior.acfg) {
-				continue
-			}
-
-			// For attribute keys, test for equal nil-ness or equal value.
-			instKeys := inst.Keys()
-			confKeys := behavior.keysSet
-			if (instKeys == nil) != (confKeys == nil) {
-				continue
-			}
-			if instKeys != nil && *instKeys != *confKeys {
-				continue
+		leaf := findLeaf(existingInsts, behavior, true)
+
+		if leaf == nil && len(existingInsts) != 0 {
+			switch v.views.CollisionPolicy {
+			case view.CollisionMerge:
+				leaf = findLeaf(existingInsts, behavior, false)
+			case view.CollisionSuffix:
+				name := behavior.desc.Name
+				for i := 1; leaf == nil && len(existingInsts) != 0; i++ {
+					behavior.desc.Name = fmt.Sprintf("%s.%d", name, i)
+					existingInsts = v.names[behavior.desc.Name]
+					leaf = findLeaf(existingInsts, behavior, true)
+				}
 			}
+		}
+		if leaf != nil {
 			// We can return the previously-compiled instrument,
 			// we may have different descriptions and that is
 			// specified to choose the longer one.
-			inst.mergeDescription(behavior.desc.Description)
-			leaf = inst
-			break
-		}
-		if leaf == nil {
+			leaf.mergeDescription(behavior.desc.Description)
+		} else {
 			switch behavior.desc.NumberKind {
 			case number.Int64Kind:
 				leaf = buildView[int64, number.Int64Traits](behavior)
@@ -408,6 +605,9 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 			}
 
 			v.collectors = append(v.collectors, leaf)
+			if oc, ok := leaf.(overflowCounted); ok && oc.overflowCounter() != nil {
+				v.collectors = append(v.collectors, oc.overflowCounter())
+			}
 			existingInsts = append(existingInsts, leaf)
 			v.names[behavior.desc.Name] = existingInsts
 		}
@@ -421,11 +621,72 @@ func (v *Compiler) Compile(instrument sdkinstrument.Descriptor) (Instrument, Vie
 			}
 			conflicts.Add(v.views.Name, c)
 		}
+		if behavior.fallback == aggregation.DropKind && behavior.desc.Kind.Synchronous() {
+			// Measurements after a failure are discarded
+			// without storage.
+			compiled = append(compiled, newFallbackInstrument(behavior.desc, leaf, newDropInstrument(behavior.desc), behavior.fallbackPolicy))
+			continue
+		}
+		if behavior.fallback != aggregation.UndefinedKind && behavior.desc.Kind.Synchronous() {
+			fallback, fallbackErr := v.compileFallback(instrument.Kind, leaf, behavior, &conflicts)
+			if fallbackErr != nil {
+				conflicts.Add(v.views.Name, Conflict{Semantic: fallbackErr})
+			}
+			if fallback != nil {
+				compiled = append(compiled, newFallbackInstrument(behavior.desc, leaf, fallback, behavior.fallbackPolicy))
+				continue
+			}
+		}
 		compiled = append(compiled, leaf)
 	}
 	return Combine(instrument, compiled...), conflicts
 }
 
+// findLeaf returns the instrument among existing that behavior's
+// output can share, or nil.  When exact is false, the aggregator
+// configuration and the attribute keys are not compared, so that the
+// first instrument's apply.
+func findLeaf(existing []leafInstrument, behavior singleBehavior, exact bool) leafInstrument {
+	for _, inst := range existing {
+		// Test for equivalence among the fields that we
+		// cannot merge or will not convert, means the
+		// testing everything except the description for
+		// equality.
+
+		if inst.Aggregation() != behavior.kind {
+			continue
+		}
+		if inst.Descriptor().Kind.Synchronous() != behavior.desc.Kind.Synchronous() {
+			continue
+		}
+
+		if inst.Descriptor().Unit != behavior.desc.Unit {
+			continue
+		}
+		if inst.Descriptor().NumberKind != behavior.desc.NumberKind {
+			continue
+		}
+		if !exact {
+			return inst
+		}
+		if !equalConfigs(inst.Config(), behavior.acfg) {
+			continue
+		}
+
+		// For attribute keys, test for equal nil-ness or equal value.
+		instKeys := inst.Keys()
+		confKeys := behavior.keysSet
+		if (instKeys == nil) != (confKeys == nil) {
+			continue
+		}
+		if instKeys != nil && *instKeys != *confKeys {
+			continue
+		}
+		return inst
+	}
+	return nil
+}
+
 // buildView compiles either a synchronous or asynchronous instrument
 // given its behavior and generic number type/traits.
 func buildView[N number.Any, Traits number.Traits[N]](behavior singleBehavior) leafInstrument {
@@ -457,6 +718,12 @@ func newSyncViewWithEx[
 	if behavior.acfg.Exemplar.Filter == aggregator.AlwaysOffKind {
 		return newSyncView[N, Storage, Methods, alwaysOffSampleFilter](behavior)
 	}
+	if behavior.acfg.Exemplar.Reservoir != aggregator.DefaultReservoirKind {
+		return newSyncViewWithF[N,
+			exemplar.ReservoirStorage[N, Storage, Methods],
+			exemplar.ReservoirMethods[N, Storage, Methods],
+		](behavior)
+	}
 	if behavior.acfg.Exemplar.Size == 1 {
 		return newSyncViewWithF[N,
 			exemplar.LastStorage[N, Storage, Methods],
@@ -472,38 +739,36 @@ func newSyncViewWithEx[
 // newSyncView returns a compiled synchronous instrument.  If the view
 // calls for delta temporality, a lowmemory instrument is returned,
 // otherwise for cumulative temporality a stateful instrument will be
-// used.  I.e., Delta->Lowmemory, Cumulative->Stateful.
+// used.  I.e., Delta->Lowmemory, Cumulative->Stateful.  Views that
+// keep state for either temporality use an either instrument.
 func newSyncView[
 	N number.Any,
 	Storage any,
 	Methods aggregator.Methods[N, Storage],
 	Samp SampleFilter,
 ](behavior singleBehavior) leafInstrument {
-	// Note: nolint:govet below is to avoid copylocks.  The lock
-	// is being copied before the new object is returned to the
-	// user, and the extra allocation cost here would be
-	// noticeable.
-
-	metric := instrumentBase[N, Storage, int64, Methods]{
-		fromName:   behavior.fromName,
-		desc:       behavior.desc,
-		acfg:       behavior.acfg,
-		data:       map[attribute.Set]*storageHolder[Storage, int64]{},
-		keysSet:    behavior.keysSet,
-		keysFilter: behavior.keysFilter,
-	}
-	instrument := compiledSyncBase[N, Storage, Methods, Samp]{
-		instrumentBase: metric, //nolint:govet
+	if behavior.eitherTempo {
+		either := &eitherSyncInstrument[N, Storage, Methods, Samp]{
+			tempo:  behavior.tempo,
+			totals: runningTotals[Storage]{inactive: behavior.inactivePeriods},
+		}
+		either.compiledSyncBase.init(behavior)
+		return either
 	}
 	if behavior.tempo == aggregation.DeltaTemporality {
-		return &lowmemorySyncInstrument[N, Storage, Methods, Samp]{
-			compiledSyncBase: instrument, //nolint:govet
+		lowmemory := &lowmemorySyncInstrument[N, Storage, Methods, Samp]{
+			totals: runningTotals[Storage]{inactive: behavior.inactivePeriods},
 		}
+		lowmemory.compiledSyncBase.init(behavior)
+		return lowmemory
 	}
 
-	return &statefulSyncInstrument[N, Storage, Methods, Samp]{
-		compiledSyncBase: instrument, //nolint:govet
+	stateful := &statefulSyncInstrument[N, Storage, Methods, Samp]{}
+	stateful.compiledSyncBase.init(behavior)
+	if behavior.seriesBudget != 0 {
+		stateful.eviction = newSeriesEviction(int(behavior.seriesBudget), stateful.evict, stateful.created)
 	}
+	return stateful
 }
 
 // compileSync calls newSyncViewWithEx to compile a synchronous
@@ -524,6 +789,27 @@ func compileSync[N number.Any, Traits number.Traits[N]](behavior singleBehavior)
 			minmaxsumcount.State[N, Traits],
 			minmaxsumcount.Methods[N, Traits],
 		](behavior)
+	case aggregation.ExplicitHistogramKind:
+		return newSyncViewWithEx[
+			N,
+			Traits,
+			explicit.State[N, Traits],
+			explicit.Methods[N, Traits],
+		](behavior)
+	case aggregation.CountKind:
+		return newSyncViewWithEx[
+			N,
+			Traits,
+			count.State[N, Traits],
+			count.Methods[N, Traits],
+		](behavior)
+	case aggregation.CustomKind:
+		return newSyncViewWithEx[
+			N,
+			Traits,
+			custom.State[N, Traits],
+			custom.Methods[N, Traits],
+		](behavior)
 	case aggregation.NonMonotonicSumKind:
 		return newSyncViewWithEx[
 			N,
@@ -554,42 +840,36 @@ func compileSync[N number.Any, Traits number.Traits[N]](behavior singleBehavior)
 // view calls for delta temporality, a stateful instrument is
 // returned, otherwise for cumulative temporality a lowmemory
 // instrument will be used.  I.e., Cumulative->Lowmemory,
-// Delta->Stateful.
+// Delta->Stateful.  Gauges use the stateful instrument only for
+// views with view.WithDeltaGauge.  Views that suppress unchanged
+// series use a changed instrument in place of the lowmemory
+// instrument.
 func newAsyncView[
 	N number.Any,
 	Storage any,
 	Methods aggregator.Methods[N, Storage],
 ](behavior singleBehavior) leafInstrument {
-	// Note: nolint:govet below is to avoid copylocks.  The lock
-	// is being copied before the new object is returned to the
-	// user, and the extra allocation cost here would be
-	// noticeable.
-	metric := instrumentBase[N, Storage, notUsed, Methods]{
-		fromName:   behavior.fromName,
-		desc:       behavior.desc,
-		acfg:       behavior.acfg,
-		data:       map[attribute.Set]*storageHolder[Storage, notUsed]{},
-		keysSet:    behavior.keysSet,
-		keysFilter: behavior.keysFilter,
-	}
-	instrument := compiledAsyncBase[N, Storage, Methods]{
-		instrumentBase: metric, //nolint:govet
-	}
-
 	if behavior.tempo == aggregation.DeltaTemporality {
 		var methods Methods
-		if methods.Kind() != aggregation.GaugeKind {
-			return &statefulAsyncInstrument[N, Storage, Methods]{
-				compiledAsyncBase: instrument, //nolint:govet
+		if methods.Kind() != aggregation.GaugeKind || behavior.deltaGauge {
+			stateful := &statefulAsyncInstrument[N, Storage, Methods]{
+				inactive: behavior.inactivePeriods,
 			}
+			stateful.compiledAsyncBase.init(behavior)
+			return stateful
 		}
-		// Gauges fall through to the lowmemory behavior
-		// regardless of delta temporality.
+		// Other gauges fall through to the lowmemory
+		// behavior regardless of delta temporality.
 	}
-
-	return &lowmemoryAsyncInstrument[N, Storage, Methods]{
-		compiledAsyncBase: instrument, //nolint:govet
+	if behavior.suppress {
+		changed := &changedAsyncInstrument[N, Storage, Methods]{}
+		changed.compiledAsyncBase.init(behavior)
+		return changed
 	}
+
+	lowmemory := &lowmemoryAsyncInstrument[N, Storage, Methods]{}
+	lowmemory.compiledAsyncBase.init(behavior)
+	return lowmemory
 }
 
 // compileAsync calls newAsyncView to compile an asynchronous
@@ -608,6 +888,12 @@ func compileAsync[N number.Any, Traits number.Traits[N]](behavior singleBehavior
 			sum.State[N, Traits, sum.NonMonotonic],
 			sum.Methods[N, Traits, sum.NonMonotonic],
 		](behavior)
+	case aggregation.CustomKind:
+		return newAsyncView[
+			N,
+			custom.State[N, Traits],
+			custom.Methods[N, Traits],
+		](behavior)
 	default:
 		fallthrough
 	case aggregation.GaugeKind:
@@ -642,14 +928,48 @@ type multiInstrument[N number.Any] []Instrument
 
 // NewAccumulator returns a Accumulator for multiple views of the same instrument.
 func (mi multiInstrument[N]) NewAccumulator(kvs attribute.Set) Accumulator {
+	return mi.NewAccumulatorWithMetadata(kvs, *attribute.EmptySet())
+}
+
+// NewAccumulatorWithMetadata returns a Accumulator for multiple views of the same instrument.
+func (mi multiInstrument[N]) NewAccumulatorWithMetadata(kvs, metadata attribute.Set) Accumulator {
 	accs := make([]Accumulator, 0, len(mi))
 
 	for _, inst := range mi {
-		accs = append(accs, inst.NewAccumulator(kvs))
+		accs = append(accs, inst.NewAccumulatorWithMetadata(kvs, metadata))
 	}
 	return multiAccumulator[N](accs)
 }
 
+// Reset resets each of the views of the instrument.
+func (mi multiInstrument[N]) Reset() {
+	for _, inst := range mi {
+		inst.Reset()
+	}
+}
+
+// ResetSeries resets the series in each of the views of the instrument.
+func (mi multiInstrument[N]) ResetSeries(kvs attribute.Set, when time.Time) {
+	for _, inst := range mi {
+		inst.ResetSeries(kvs, when)
+	}
+}
+
+// PreRegister registers the attribute sets in each of the views of
+// the instrument.
+func (mi multiInstrument[N]) PreRegister(sets ...attribute.Set) {
+	for _, inst := range mi {
+		inst.PreRegister(sets...)
+	}
+}
+
+// SetEnabled enables or disables each of the views of the instrument.
+func (mi multiInstrument[N]) SetEnabled(enabled bool) {
+	for _, inst := range mi {
+		inst.SetEnabled(enabled)
+	}
+}
+
 // Uses a int(0)-value attribute to identify distinct key sets.
 func keysToSet(keys []attribute.Key) *attribute.Set {
 	attrs := make([]attribute.KeyValue, len(keys))
@@ -685,6 +1005,25 @@ func equalConfigs(a, b aggregator.Config) bool {
 	return a == b
 }
 
+// defaultHistogramConfig is the histogram configuration used when
+// none is given.
+var defaultHistogramConfig, _ = histostruct.NewConfig().Validate()
+
+// unitHistogramConfig returns acfg with the default histogram
+// configuration for the instrument's unit, if the unit is recognized
+// and acfg does not already configure the histogram size.
+func (v *Compiler) unitHistogramConfig(instrument sdkinstrument.Descriptor, acfg aggregator.Config) aggregator.Config {
+	ucfg, ok := v.views.Defaults.HistogramConfig(instrument.Unit)
+	if !ok {
+		return acfg
+	}
+	if cfg, _ := acfg.Histogram.Validate(); cfg != defaultHistogramConfig {
+		return acfg
+	}
+	acfg.Histogram = ucfg
+	return acfg
+}
+
 // pickAggConfig returns the aggregator configuration prescribed by a
 // view clause when it not the default value, otherwise the hinted config.
 func pickAggConfig(hintCfg, defCfg, viewCfg aggregator.Config) aggregator.Config {
@@ -703,6 +1042,10 @@ func checkSemanticCompatibility(ik sdkinstrument.Kind, behavior *singleBehavior)
 	}
 
 	agg := behavior.kind
+	if agg == aggregation.CustomKind {
+		// User-defined aggregations are the user's responsibility.
+		return nil
+	}
 	cat := agg.Category(ik)
 
 	if agg == aggregation.AnySumKind {
@@ -15,17 +15,26 @@
 package viewstate // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"math/bits"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/compare"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
@@ -39,6 +48,8 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
 )
 
 var (
@@ -135,6 +146,31 @@ func testCompileDescUnit(vc *Compiler, name string, ik sdkinstrument.Kind, nk nu
 	return inst, conflicts.AsError()
 }
 
+// testInstrument builds the instrument of one view directly, without
+// a Compiler, for testing collection in isolation.  The kinds of
+// instrument and aggregation and the temporality select the
+// implementation, e.g., a SyncCounter with CumulativeTemporality is
+// a statefulSyncInstrument and an AsyncGauge is a
+// lowmemoryAsyncInstrument.  The aggregator.Config is validated, so
+// the zero value has the default cardinality limit.
+func testInstrument(t testing.TB, desc sdkinstrument.Descriptor, akind aggregation.Kind, tempo aggregation.Temporality, acfg aggregator.Config) leafInstrument {
+	acfg, err := acfg.Validate()
+	require.NoError(t, err)
+
+	behavior := singleBehavior{
+		fromName: desc.Name,
+		desc:     desc,
+		kind:     akind,
+		acfg:     acfg,
+		tempo:    tempo,
+		overflow: pipeline.OverflowSet(pipeline.OverflowAttributes),
+	}
+	if desc.NumberKind == number.Int64Kind {
+		return buildView[int64, number.Int64Traits](behavior)
+	}
+	return buildView[float64, number.Float64Traits](behavior)
+}
+
 func testCollect(t *testing.T, vc *Compiler) []data.Instrument {
 	return test.CollectScope(t, vc.Collectors(), testSequence)
 }
@@ -403,6 +439,86 @@ func TestDuplicateFilterConflicts(t *testing.T) {
 	}
 }
 
+// TestDuplicateCollisionPolicy tests each policy for two views that
+// output the same name with different attribute filters.
+func TestDuplicateCollisionPolicy(t *testing.T) {
+	compile := func(t *testing.T, policy view.CollisionPolicy) (*Compiler, Instrument, Instrument, error) {
+		vc := New(testLib, view.New("test", safePerf, fooToBarFilteredView, view.WithCollisionPolicy(policy)))
+
+		inst1, err1 := testCompile(vc, "foo", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NoError(t, err1)
+		require.NotNil(t, inst1)
+
+		inst2, err2 := testCompile(vc, "bar", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NotNil(t, inst2)
+
+		for _, inst := range []Instrument{inst1, inst2} {
+			acc := inst.NewAccumulator(attribute.NewSet(attribute.String("a", "1"), attribute.String("c", "2")))
+			acc.(Updater[int64]).Update(1, nobits)
+			acc.SnapshotAndProcess(false)
+		}
+		return vc, inst1, inst2, err2
+	}
+	desc := test.Descriptor("bar", sdkinstrument.SyncCounter, number.Int64Kind)
+
+	t.Run("error", func(t *testing.T) {
+		vc, inst1, inst2, err := compile(t, view.CollisionError)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ViewConflictsError{}))
+		require.NotEqual(t, inst1, inst2)
+
+		output := testCollect(t, vc)
+		require.Equal(t, 2, len(output))
+		require.Equal(t, "bar", output[0].Descriptor.Name)
+		require.Equal(t, "bar", output[1].Descriptor.Name)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		vc, inst1, inst2, err := compile(t, view.CollisionMerge)
+		require.NoError(t, err)
+		require.Equal(t, inst1, inst2)
+
+		// The first view's attribute filter applies to both.
+		test.RequireEqualMetrics(t, testCollect(t, vc),
+			test.Instrument(desc,
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative, attribute.String("a", "1")),
+			),
+		)
+	})
+
+	t.Run("suffix", func(t *testing.T) {
+		vc, inst1, inst2, err := compile(t, view.CollisionSuffix)
+		require.NoError(t, err)
+		require.NotEqual(t, inst1, inst2)
+
+		// The suffixed output is shared by a later duplicate.
+		inst3, err3 := testCompile(vc, "bar", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NoError(t, err3)
+		require.Equal(t, inst2, inst3)
+
+		test.RequireEqualMetrics(t, testCollect(t, vc),
+			test.Instrument(desc,
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attribute.String("a", "1")),
+			),
+			test.Instrument(test.Descriptor("bar.1", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attribute.String("a", "1"), attribute.String("c", "2")),
+			),
+		)
+	})
+
+	t.Run("merge incompatible", func(t *testing.T) {
+		vc := New(testLib, view.New("test", safePerf, fooToBarView, view.WithCollisionPolicy(view.CollisionMerge)))
+
+		_, err1 := testCompile(vc, "foo", sdkinstrument.SyncHistogram, number.Int64Kind)
+		require.NoError(t, err1)
+
+		// Different aggregations cannot merge.
+		_, err2 := testCompile(vc, "bar", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.Error(t, err2)
+		require.True(t, errors.Is(err2, ViewConflictsError{}))
+	})
+}
+
 // TestDeduplicateSameFilters thests that when one instrument is
 // renamed to match another exactly, including filters, they are not
 // in conflict.
@@ -976,16 +1092,17 @@ func TestDeltaTemporalityAsyncCounter(t *testing.T) {
 	tick()
 	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
+	// The last value is kept without observations.
 	expectNone(seq)
 	tick()
-	require.Equal(t, 0, instF.(data.Collector).InMemorySize())
+	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
 	expectNone(seq)
 	tick()
-	require.Equal(t, 0, instF.(data.Collector).InMemorySize())
+	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
 	observe(11)
-	expectValues(11, seq)
+	expectValues(1, seq)
 	tick()
 	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
@@ -997,27 +1114,142 @@ func TestDeltaTemporalityAsyncCounter(t *testing.T) {
 	// HasChange() is false.
 	expectNone(seq)
 	tick()
-	// There is still a point mapped, even though it didn't output
-	// a value.
 	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
 	expectNone(seq)
 	tick()
-	// TODO: This is ill-specified and probably not right, but
-	// putting this test here to document it.  Async-delta case
-	// after multiple periods of no observation should probably
-	// keep track of the last value, otherwise it will reappear as
-	// new (and impact any rate calculations on the data).  Same
-	// for the two cases above.
-	require.Equal(t, 0, instF.(data.Collector).InMemorySize())
+	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 
 	observe(11)
-	expectValues(11, seq)
+	expectNone(seq)
 	tick()
 
 	require.Equal(t, 1, instF.(data.Collector).InMemorySize())
 }
 
+// TestDeltaTemporalityAsyncCounterSkipped ensures that a series
+// skipped by a callback keeps its prior value, so the next
+// observation reports the correct difference.
+func TestDeltaTemporalityAsyncCounterSkipped(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	setA := attribute.NewSet(attribute.String("a", "1"))
+	setB := attribute.NewSet(attribute.String("b", "2"))
+
+	observe := func(set attribute.Set, x int64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	seq := testSequence
+	tick := func() {
+		seq.Last = seq.Now
+		seq.Now = time.Now()
+	}
+
+	observe(setA, 100)
+	observe(setB, 10)
+	test.RequireEqualMetrics(t,
+		testCollectSequence(t, vc, seq),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(100), delta, setA.ToSlice()...),
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(10), delta, setB.ToSlice()...),
+		),
+	)
+	tick()
+
+	// Series A is skipped.
+	observe(setB, 15)
+	test.RequireEqualMetrics(t,
+		testCollectSequence(t, vc, seq),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(5), delta, setB.ToSlice()...),
+		),
+	)
+	tick()
+
+	observe(setA, 130)
+	observe(setB, 20)
+	test.RequireEqualMetrics(t,
+		testCollectSequence(t, vc, seq),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(30), delta, setA.ToSlice()...),
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(5), delta, setB.ToSlice()...),
+		),
+	)
+}
+
+// TestDeltaTemporalityAsyncCounterInactive ensures that the prior
+// value of a series that is not observed is kept for
+// InactiveCollectionPeriods collections.
+func TestDeltaTemporalityAsyncCounterInactive(t *testing.T) {
+	const inactive = 2
+
+	for _, skipped := range []int{inactive, inactive + 1} {
+		t.Run(fmt.Sprint(skipped), func(t *testing.T) {
+			views := view.New(
+				"test",
+				sdkinstrument.Performance{InactiveCollectionPeriods: inactive},
+				view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+			)
+
+			vc := New(testLib, views)
+
+			inst, err := testCompile(vc, "counter", sdkinstrument.AsyncCounter, number.Int64Kind)
+			require.NoError(t, err)
+
+			observe := func(x int64) {
+				acc := inst.NewAccumulator(*attribute.EmptySet())
+				acc.(Updater[int64]).Update(x, nobits)
+				require.NoError(t, acc.SnapshotAndProcess(true))
+			}
+			seq := testSequence
+			tick := func() {
+				seq.Last = seq.Now
+				seq.Now = time.Now()
+			}
+
+			observe(100)
+			testCollectSequence(t, vc, seq)
+			tick()
+
+			for i := 0; i < skipped; i++ {
+				test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq), test.Instrument(
+					test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+				))
+				tick()
+			}
+			expect := int64(30)
+			if skipped > inactive {
+				// The prior value was forgotten.
+				require.Equal(t, 0, inst.(data.Collector).InMemorySize())
+				expect = 130
+			}
+
+			observe(130)
+			test.RequireEqualMetrics(t,
+				testCollectSequence(t, vc, seq),
+				test.Instrument(
+					test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+					test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(expect), delta),
+				),
+			)
+		})
+	}
+}
+
 // TestDeltaTemporalityAsyncGauge ensures that the asynchronous gauge
 // disregards delta temporalty.
 func TestDeltaTemporalityAsyncGauge(t *testing.T) {
@@ -1452,6 +1684,90 @@ func TestRegexpMatch(t *testing.T) {
 	require.NotNil(t, inst2)
 }
 
+// TestExplicitHistogramView tests a histogram with configured
+// boundaries selected by a view.
+func TestExplicitHistogramView(t *testing.T) {
+	bounds := []float64{1, 5, 10}
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("latency"),
+			view.WithExplicitHistogram(bounds...),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "latency", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	for _, v := range []int64{1, 2, 13} {
+		acc.(Updater[int64]).Update(v, nobits)
+	}
+	acc.SnapshotAndProcess(false)
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("latency", sdkinstrument.SyncHistogram, number.Int64Kind),
+			test.Point(startTime, endTime, explicit.NewInt64(bounds, 1, 2, 13), cumulative),
+		),
+	)
+
+	acc.(Updater[int64]).Update(5, nobits)
+	acc.SnapshotAndProcess(false)
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("latency", sdkinstrument.SyncHistogram, number.Int64Kind),
+			test.Point(startTime, endTime, explicit.NewInt64(bounds, 1, 2, 13, 5), cumulative),
+		),
+	)
+}
+
+// TestCountView tests a histogram that counts its measurements,
+// with delta temporality.
+func TestCountView(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("requests"),
+			view.WithAggregation(aggregation.CountKind),
+		),
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "requests", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	for _, v := range []float64{0.5, 20, 3} {
+		acc.(Updater[float64]).Update(v, nobits)
+	}
+	acc.SnapshotAndProcess(false)
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("requests", sdkinstrument.SyncHistogram, number.Float64Kind),
+			test.Point(middleTime, endTime, count.NewFloat64(3), delta),
+		),
+	)
+
+	acc.(Updater[float64]).UpdateBatch(7, 2, nobits)
+	acc.SnapshotAndProcess(false)
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("requests", sdkinstrument.SyncHistogram, number.Float64Kind),
+			test.Point(middleTime, endTime, count.NewFloat64(2), delta),
+		),
+	)
+}
+
 func TestDeltaTemporalityMinMaxSumCount(t *testing.T) {
 	views := view.New(
 		"test",
@@ -2008,6 +2324,75 @@ func TestOverflowAsyncCumulative(t *testing.T) {
 	}
 }
 
+// TestOverflowCustomAttributes tests that the overflow attribute set
+// is configured by the Performance settings.
+func TestOverflowCustomAttributes(t *testing.T) {
+	const limit = 5
+	const count = 20
+	custom := attribute.NewSet(
+		attribute.Bool("otel.metric.overflow", true),
+		attribute.String("service.name", "custom"),
+	)
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{
+			AggregatorCardinalityLimit: limit,
+			OverflowAttributes:         custom.ToSlice(),
+		},
+		view.WithDefaultAggregationTemporalitySelector(func(ik sdkinstrument.Kind) aggregation.Temporality {
+			if ik == sdkinstrument.AsyncCounter {
+				return aggregation.DeltaTemporality
+			}
+			return aggregation.CumulativeTemporality
+		}),
+	)
+	views, err := view.Validate(views)
+	require.NoError(t, err)
+
+	vc := New(testLib, views)
+
+	syncInst, err := testCompile(vc, "sync", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	asyncInst, err := testCompile(vc, "async", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	for reps := 1; reps <= 2; reps++ {
+		for i := 0; i < count; i++ {
+			attrs := attribute.NewSet(attribute.Int("i", i))
+
+			acc1 := syncInst.NewAccumulator(attrs)
+			acc1.(Updater[int64]).Update(1, nobits)
+			acc1.SnapshotAndProcess(true)
+
+			acc2 := asyncInst.NewAccumulator(attrs)
+			acc2.(Updater[int64]).Update(int64(reps), nobits)
+			acc2.SnapshotAndProcess(true)
+		}
+
+		collected := testCollect(t, vc)
+		require.Equal(t, 2, len(collected))
+
+		for _, inst := range collected {
+			require.Equal(t, limit, len(inst.Points))
+
+			oflow := 0
+			total := int64(0)
+			for _, pt := range inst.Points {
+				require.NotEqual(t, pipeline.OverflowAttributeSet, pt.Attributes)
+				if pt.Attributes == custom {
+					oflow++
+				}
+				total += number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+			}
+			require.Equal(t, 1, oflow)
+			if inst.Descriptor.Name == "sync" {
+				require.Equal(t, int64(count*reps), total)
+			}
+		}
+	}
+}
+
 // TestOneViewOverflowsOneDoesNot tests that views can independently
 // repair an overflow problem.
 func TestOneViewOverflowsOneDoesNot(t *testing.T) {
@@ -2076,184 +2461,3638 @@ func TestOneViewOverflowsOneDoesNot(t *testing.T) {
 	)
 }
 
-// TestInstrumentOverflowCombined tests that the aggregator limit is a
-// hard limit even when the instrument-level limit was reached early.
-func TestInstrumentOverflowCombined(t *testing.T) {
-	const aggLimit = 10
-	const instLimit = 2 * aggLimit
-	const count = 5 * instLimit
+// TestCardinalityLimitPerView tests that instruments matched by views
+// with different cardinality limits overflow independently.
+func TestCardinalityLimitPerView(t *testing.T) {
+	const small = 3
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("small"),
+			view.WithCardinalityLimit(small),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("large"),
+			view.WithCardinalityLimit(100),
+		),
+	)
+	views, err := view.Validate(views)
+	require.NoError(t, err)
+
+	vc := New(testLib, views)
+
+	smallInst, err := testCompile(vc, "small", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+	largeInst, err := testCompile(vc, "large", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	var expSmall, expLarge []data.Point
+	for i := 0; i < small+1; i++ {
+		attrs := attribute.NewSet(attribute.Int("i", i))
+		for _, inst := range []Instrument{smallInst, largeInst} {
+			acc := inst.NewAccumulator(attrs)
+			acc.(Updater[int64]).Update(1, nobits)
+			acc.SnapshotAndProcess(true)
+		}
+		if i < small-1 {
+			expSmall = append(expSmall,
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attrs.ToSlice()...))
+		}
+		expLarge = append(expLarge,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attrs.ToSlice()...))
+	}
+	expSmall = append(expSmall,
+		test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative, attribute.Bool("otel.metric.overflow", true)))
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("small", sdkinstrument.SyncCounter, number.Int64Kind),
+			expSmall...,
+		),
+		test.Instrument(
+			test.Descriptor("large", sdkinstrument.SyncCounter, number.Int64Kind),
+			expLarge...,
+		),
+	)
+}
+
+// TestOverflowDisabled tests that a view with overflow disabled
+// drops new attribute sets at the limit and reports an error, while
+// a view using the default behavior still overflows.
+func TestOverflowDisabled(t *testing.T) {
+	const limit = 5
+	const count = 20
 	views := view.New(
 		"test",
 		sdkinstrument.Performance{
-			InactiveCollectionPeriods:  1,
-			AggregatorCardinalityLimit: aggLimit,
-			InstrumentCardinalityLimit: instLimit,
+			AggregatorCardinalityLimit: limit,
 		},
-		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+		view.WithClause(
+			view.MatchInstrumentNameRegexp(regexp.MustCompile("^strict")),
+			view.WithAggregatorConfig(aggregator.Config{
+				CardinalityLimit: limit,
+				DisableOverflow:  true,
+			}),
+		),
 	)
 	views, err := view.Validate(views)
 	require.NoError(t, err)
 
 	vc := New(testLib, views)
 
-	instS, err := testCompile(vc, "S", sdkinstrument.SyncCounter, number.Float64Kind)
+	otelErrs := test.OTelErrors()
+
+	strict, err := testCompile(vc, "strict", sdkinstrument.SyncCounter, number.Float64Kind)
 	require.NoError(t, err)
 
-	instA, err := testCompile(vc, "A", sdkinstrument.AsyncCounter, number.Int64Kind)
+	strictAsync, err := testCompile(vc, "strict_async", sdkinstrument.AsyncCounter, number.Int64Kind)
 	require.NoError(t, err)
 
-	totalS := float64(0)
-	totalA := int64(0)
+	graceful, err := testCompile(vc, "graceful", sdkinstrument.SyncCounter, number.Float64Kind)
+	require.NoError(t, err)
 
-	for reps := 0; reps < 10; reps++ {
-		for i := 0; i < count; i++ {
-			attr := attribute.Int("RCi", reps*count+i)
-			aset := attribute.NewSet(attr)
+	var expStrict, expStrictAsync, expGraceful []data.Point
 
-			accS := instS.NewAccumulator(aset)
-			accS.(Updater[float64]).Update(1, nobits)
-			accS.SnapshotAndProcess(true)
+	for i := 0; i < count; i++ {
+		attrs := attribute.NewSet(attribute.Int("i", i))
 
-			accA := instA.NewAccumulator(aset)
-			accA.(Updater[int64]).Update(1, nobits)
-			accA.SnapshotAndProcess(true)
-		}
+		acc1 := strict.NewAccumulator(attrs)
+		acc1.(Updater[float64]).Update(1, nobits)
+		acc1.SnapshotAndProcess(true)
 
-		// Both experience overflow; neither exceeds its limit
-		data := testCollect(t, vc)
-		require.Equal(t, test.Descriptor("S", sdkinstrument.SyncCounter, number.Float64Kind), data[0].Descriptor)
-		require.Equal(t, test.Descriptor("A", sdkinstrument.AsyncCounter, number.Int64Kind), data[1].Descriptor)
+		acc2 := strictAsync.NewAccumulator(attrs)
+		acc2.(Updater[int64]).Update(1, nobits)
+		acc2.SnapshotAndProcess(true)
 
-		// As tested in syncstate, there is an oscillation that
-		// develops when a delta temporality experiences major
-		// overflow.  We're not testing the form of the overflow here,
-		// just that the sum is correct.
-		oflowSCnt := 0
-		sumS := 0.0
-		for _, pt := range data[0].Points {
-			if pt.Attributes == pipeline.OverflowAttributeSet {
-				oflowSCnt++
-			}
-			sumS += number.ToFloat64(pt.Aggregation.(aggregation.Sum).Sum())
-		}
-		if reps == 0 {
-			require.Equal(t, 1, oflowSCnt)
-			require.Equal(t, float64(count), sumS)
-		}
-		totalS += sumS
-		require.Equal(t, float64((reps+1)*count), totalS, "rep %d", reps)
+		acc3 := graceful.NewAccumulator(attrs)
+		acc3.(Updater[float64]).Update(1, nobits)
+		acc3.SnapshotAndProcess(true)
 
-		// Note that because the attribute set is new every
-		// time, we expect a new sum in every round equal to count.
-		// See the special case treatment of overflow in the async
-		// delta-temporality aggregator.
-		oflowACnt := 0
-		sumA := int64(0)
-		for _, pt := range data[1].Points {
-			if pt.Attributes == pipeline.OverflowAttributeSet {
-				oflowACnt++
-			}
-			sumA += number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+		if i < limit {
+			expStrict = append(expStrict,
+				test.Point(startTime, endTime, sum.NewMonotonicFloat64(1), cumulative, attrs.ToSlice()...))
+			expStrictAsync = append(expStrictAsync,
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attrs.ToSlice()...))
 		}
-		if reps == 0 {
-			require.Equal(t, 1, oflowACnt)
-			require.Equal(t, int64(count), sumA)
+		if i < limit-1 {
+			expGraceful = append(expGraceful,
+				test.Point(startTime, endTime, sum.NewMonotonicFloat64(1), cumulative, attrs.ToSlice()...))
 		}
-		totalA += sumA
-		require.Equal(t, int64((reps+1)*count), totalA, "rep %d", reps)
+	}
+	expGraceful = append(expGraceful,
+		test.Point(
+			startTime, endTime, sum.NewMonotonicFloat64(count-limit+1), cumulative, attribute.Bool("otel.metric.overflow", true),
+		))
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("strict", sdkinstrument.SyncCounter, number.Float64Kind),
+			expStrict...,
+		),
+		test.Instrument(
+			test.Descriptor("strict_async", sdkinstrument.AsyncCounter, number.Int64Kind),
+			expStrictAsync...,
+		),
+		test.Instrument(
+			test.Descriptor("graceful", sdkinstrument.SyncCounter, number.Float64Kind),
+			expGraceful...,
+		),
+	)
+
+	require.Less(t, 0, len(*otelErrs))
+	for _, err := range *otelErrs {
+		require.ErrorIs(t, err, aggregator.ErrCardinalityLimitExceeded)
+		require.Contains(t, err.Error(), "strict")
 	}
 }
 
-// TestExemplars is the most-basic test for exemplars there could be.
-// It creates three series, distinguished by b=1,2,3 then filters b
-// leaving three points.
-func TestExemplars(t *testing.T) {
+// TestAttributeNormalization tests that values differing only in
+// encoding are aggregated into one series.
+func TestAttributeNormalization(t *testing.T) {
 	views := view.New(
 		"test",
 		safePerf,
 		view.WithClause(
-			view.WithKeys([]attribute.Key{"a"}),
-			view.WithAggregatorConfig(
-				aggregator.Config{
-					Exemplar: aggregator.ExemplarConfig{
-						Filter: aggregator.AlwaysOnKind,
-						Size:   3,
-					},
-				},
+			view.MatchInstrumentName("normal"),
+			view.WithAttributeNormalization(
+				view.NormalizeTrimSpace|view.NormalizeNFC|view.NormalizeCaseFold|view.NormalizeBool,
 			),
 		),
 	)
 
 	vc := New(testLib, views)
 
-	inst, err := testCompile(vc, "foo", sdkinstrument.SyncCounter, number.Float64Kind)
+	normal, err := testCompile(vc, "normal", sdkinstrument.SyncCounter, number.Int64Kind)
 	require.NoError(t, err)
 
-	all1 := []attribute.KeyValue{
-		attribute.Int("b", 1),
-		attribute.Int("a", 1),
+	raw, err := testCompile(vc, "raw", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	inputs := []attribute.Set{
+		attribute.NewSet(attribute.String("city", "caf\u00e9"), attribute.String("ok", "true")),
+		attribute.NewSet(attribute.String("city", "cafe\u0301"), attribute.String("ok", "True")),
+		attribute.NewSet(attribute.String("city", " CAF\u00c9 "), attribute.String("ok", "1")),
+		attribute.NewSet(attribute.String("city", "Caf\u00e9"), attribute.Bool("ok", true)),
 	}
-	all2 := []attribute.KeyValue{
-		attribute.Int("b", 2),
-		attribute.Int("a", 1),
+	for _, attrs := range inputs {
+		acc := normal.NewAccumulator(attrs)
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
+
+		acc = raw.NewAccumulator(attrs)
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
 	}
-	all3 := []attribute.KeyValue{
-		attribute.Int("b", 3),
-		attribute.Int("a", 1),
+
+	var expRaw []data.Point
+	for _, attrs := range inputs {
+		expRaw = append(expRaw, test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attrs.ToSlice()...))
 	}
-	acc1 := inst.NewAccumulator(attribute.NewSet(all1...))
-	eb1 := aggregator.ExemplarBits{
-		Time:       middleTime,
-		Number:     number.FromInt64(1),
-		Attributes: all1,
-		Span:       test.FakeSpan(1, 1),
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("normal", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(4), cumulative,
+				attribute.String("city", "caf\u00e9"), attribute.Bool("ok", true)),
+		),
+		test.Instrument(
+			test.Descriptor("raw", sdkinstrument.AsyncCounter, number.Int64Kind),
+			expRaw...,
+		),
+	)
+}
+
+// TestAttributeValueLimit tests that values of a key beyond the
+// per-key limit are replaced by the overflow value.
+func TestAttributeValueLimit(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("limited"),
+			view.WithAttributeValueLimit(2),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	limited, err := testCompile(vc, "limited", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	for _, user := range []string{"a", "b", "c", "a", "d"} {
+		acc := limited.NewAccumulator(attribute.NewSet(
+			attribute.String("user", user),
+			attribute.String("method", "get"),
+		))
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
 	}
-	acc1.(Updater[float64]).Update(1, eb1)
-	acc1.SnapshotAndProcess(false)
 
-	acc2 := inst.NewAccumulator(attribute.NewSet(all2...))
-	eb2 := aggregator.ExemplarBits{
-		Time:       middleTime,
-		Number:     number.FromInt64(2),
-		Attributes: all2,
-		Span:       test.FakeSpan(2, 2),
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("limited", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative,
+				attribute.String("user", "a"), attribute.String("method", "get")),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative,
+				attribute.String("user", "b"), attribute.String("method", "get")),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative,
+				attribute.String("user", "_OTHER"), attribute.String("method", "get")),
+		),
+	)
+}
+
+// TestAttributeTransform tests that the attribute transform is
+// applied to the filtered attribute set.
+func TestAttributeTransform(t *testing.T) {
+	dropIDs := func(kvs attribute.Set) attribute.Set {
+		if route, ok := kvs.Value("route"); !ok || !strings.HasPrefix(route.AsString(), "/user/") {
+			return kvs
+		}
+		res, _ := kvs.Filter(func(kv attribute.KeyValue) bool {
+			return kv.Key != "route"
+		})
+		return res
 	}
-	acc2.(Updater[float64]).Update(2, eb2)
-	acc2.SnapshotAndProcess(false)
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("transformed"),
+			view.WithKeys([]attribute.Key{"route", "method"}),
+			view.WithAttributeTransform(dropIDs),
+		),
+	)
 
-	acc3 := inst.NewAccumulator(attribute.NewSet(all3...))
-	eb3 := aggregator.ExemplarBits{
-		Time:       middleTime,
-		Number:     number.FromInt64(3),
-		Attributes: all3,
-		Span:       test.FakeSpan(3, 3),
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "transformed", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	for _, route := range []string{"/user/1", "/user/2", "/home", "/user/3"} {
+		acc := inst.NewAccumulator(attribute.NewSet(
+			attribute.String("route", route),
+			attribute.String("method", "get"),
+			attribute.String("host", "h1"),
+		))
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
 	}
-	acc3.(Updater[float64]).Update(3, eb3)
-	acc3.SnapshotAndProcess(false)
 
-	output := testCollect(t, vc)
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("transformed", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(3), cumulative,
+				attribute.String("method", "get")),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative,
+				attribute.String("route", "/home"), attribute.String("method", "get")),
+		),
+	)
+}
 
-	// In this test, the number of examples equals the reservoir size.
-	// Weight == point value.
-	test.RequireEqualMetrics(t, output,
+// TestAttributeValueLimitMemory tests that value tracking memory is
+// bounded for an unbounded stream of distinct values.
+func TestAttributeValueLimitMemory(t *testing.T) {
+	const limit = 10
+	vl := newValueLimiter(limit)
+
+	for i := 0; i < 100000; i++ {
+		out := vl.apply(attribute.NewSet(attribute.Int("id", i)))
+		val, _ := out.Value("id")
+		if i < limit {
+			require.Equal(t, attribute.IntValue(i), val)
+		} else {
+			require.Equal(t, overflowValue, val)
+		}
+	}
+	require.Equal(t, limit, vl.size())
+
+	// Distinct keys are bounded as well.
+	for i := 0; i < 1000; i++ {
+		vl.apply(attribute.NewSet(attribute.Int(fmt.Sprint("key", i), 0)))
+	}
+	require.Equal(t, maxValueLimitKeys, len(vl.values))
+	require.LessOrEqual(t, vl.size(), limit+maxValueLimitKeys)
+}
+
+// TestValueQuantization tests that measurements that differ by less
+// than the quantum are aggregated as the same value, so that delta
+// temporality reports no change.
+func TestValueQuantization(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(func(sdkinstrument.Kind) aggregation.Temporality {
+			return delta
+		}),
+		view.WithClause(
+			view.MatchInstrumentName("quantized"),
+			view.WithValueQuantization(0.01),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "quantized", sdkinstrument.AsyncUpDownCounter, number.Float64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	observe := func(x float64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[float64]).Update(x, nobits)
+		acc.SnapshotAndProcess(true)
+	}
+
+	seq := testSequence
+	tick := func() {
+		seq.Last = seq.Now
+		seq.Now = time.Now()
+	}
+
+	observe(1.2301)
+	test.RequireEqualMetrics(t,
+		testCollectSequence(t, vc, seq),
 		test.Instrument(
-			test.Descriptor("foo", sdkinstrument.SyncCounter, number.Float64Kind),
-			test.PointEx(
-				startTime, endTime, sum.NewMonotonicFloat64(1+2+3), cumulative,
-				[]attribute.KeyValue{attribute.Int("a", 1)},
-				aggregator.WeightedExemplarBits{
-					ExemplarBits: eb1,
-					Weight:       1,
-				},
-				aggregator.WeightedExemplarBits{
-					ExemplarBits: eb2,
-					Weight:       2,
-				},
-				aggregator.WeightedExemplarBits{
-					ExemplarBits: eb3,
-					Weight:       3,
-				},
+			test.Descriptor("quantized", sdkinstrument.AsyncUpDownCounter, number.Float64Kind),
+			test.Point(seq.Last, seq.Now, sum.NewNonMonotonicFloat64(quantize(1.23, 0.01)), delta),
+		),
+	)
+
+	for _, x := range []float64{1.2299, 1.2304, 1.2297} {
+		tick()
+		observe(x)
+		test.RequireEqualMetrics(t,
+			testCollectSequence(t, vc, seq),
+			test.Instrument(
+				test.Descriptor("quantized", sdkinstrument.AsyncUpDownCounter, number.Float64Kind),
 			),
+		)
+	}
+
+	tick()
+	observe(1.2401)
+	test.RequireEqualMetrics(t,
+		testCollectSequence(t, vc, seq),
+		test.Instrument(
+			test.Descriptor("quantized", sdkinstrument.AsyncUpDownCounter, number.Float64Kind),
+			test.Point(seq.Last, seq.Now, sum.NewNonMonotonicFloat64(quantize(1.24, 0.01)-quantize(1.23, 0.01)), delta),
+		),
+	)
+}
+
+// TestQuantize tests the rounding of ties to even multiples.
+func TestQuantize(t *testing.T) {
+	require.Equal(t, 2.0, quantize(2.5, 1))
+	require.Equal(t, 4.0, quantize(3.5, 1))
+	require.Equal(t, -2.0, quantize(-2.5, 1))
+	require.Equal(t, int64(5), quantize(int64(7), 5))
+	require.Equal(t, int64(10), quantize(int64(8), 5))
+	require.Equal(t, int64(7), quantize(int64(7), 0.5))
+	require.True(t, math.IsNaN(quantize(math.NaN(), 1)))
+
+	require.Equal(t, 0.0, validQuantum(-1))
+	require.Equal(t, 0.0, validQuantum(math.Inf(1)))
+	require.Equal(t, 0.0, validQuantum(math.NaN()))
+	require.Equal(t, 0.25, validQuantum(0.25))
+}
+
+// TestSeriesMetadata tests that series metadata is output without
+// changing the identity of the series.
+func TestSeriesMetadata(t *testing.T) {
+	views := view.New("test", safePerf)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "annotated", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	series := attribute.NewSet(attribute.String("host", "a"))
+	other := attribute.NewSet(attribute.String("host", "b"))
+
+	for _, md := range []attribute.Set{
+		attribute.NewSet(attribute.String("label", "first"), attribute.String("source", "x")),
+		attribute.NewSet(attribute.String("label", "second"), attribute.String("owner", "y")),
+		*attribute.EmptySet(),
+	} {
+		acc := inst.NewAccumulatorWithMetadata(series, md)
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
+	}
+	acc := inst.NewAccumulator(other)
+	acc.(Updater[int64]).Update(1, nobits)
+	acc.SnapshotAndProcess(true)
+
+	require.Equal(t, 2, inst.(data.Collector).InMemorySize())
+
+	annotated := test.Point(startTime, endTime, sum.NewMonotonicInt64(3), cumulative, series.ToSlice()...)
+	annotated.Metadata = attribute.NewSet(
+		attribute.String("label", "first"),
+		attribute.String("owner", "y"),
+		attribute.String("source", "x"),
+	)
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("annotated", sdkinstrument.SyncCounter, number.Int64Kind),
+			annotated,
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, other.ToSlice()...),
 		),
 	)
 }
+
+// TestOverflowDropped tests that a view with DropOverflow drops and
+// counts measurements of new attribute sets at the limit, without an
+// overflow point or an error.
+func TestOverflowDropped(t *testing.T) {
+	const limit = 5
+	const count = 20
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{
+			AggregatorCardinalityLimit: limit,
+		},
+		view.WithClause(
+			view.WithAggregatorConfig(aggregator.Config{
+				CardinalityLimit: limit,
+				DropOverflow:     true,
+			}),
+		),
+	)
+	views, err := view.Validate(views)
+	require.NoError(t, err)
+
+	vc := New(testLib, views)
+
+	otelErrs := test.OTelErrors()
+
+	quiet, err := testCompile(vc, "quiet", sdkinstrument.SyncCounter, number.Float64Kind)
+	require.NoError(t, err)
+
+	quietAsync, err := testCompile(vc, "quiet_async", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	var expQuiet, expQuietAsync []data.Point
+
+	for i := 0; i < count; i++ {
+		attrs := attribute.NewSet(attribute.Int("i", i))
+
+		acc1 := quiet.NewAccumulator(attrs)
+		acc1.(Updater[float64]).Update(1, nobits)
+		acc1.(Updater[float64]).Update(1, nobits)
+		acc1.SnapshotAndProcess(true)
+
+		acc2 := quietAsync.NewAccumulator(attrs)
+		acc2.(Updater[int64]).Update(1, nobits)
+		acc2.SnapshotAndProcess(true)
+
+		if i < limit {
+			expQuiet = append(expQuiet,
+				test.Point(startTime, endTime, sum.NewMonotonicFloat64(2), cumulative, attrs.ToSlice()...))
+			expQuietAsync = append(expQuietAsync,
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attrs.ToSlice()...))
+		}
+	}
+
+	output := testCollect(t, vc)
+	test.RequireEqualMetrics(
+		t,
+		output,
+		test.Instrument(
+			test.Descriptor("quiet", sdkinstrument.SyncCounter, number.Float64Kind),
+			expQuiet...,
+		),
+		test.Instrument(
+			test.Descriptor("quiet_async", sdkinstrument.AsyncCounter, number.Int64Kind),
+			expQuietAsync...,
+		),
+	)
+	require.Equal(t, uint64(2*(count-limit)), output[0].DroppedMeasurements)
+	require.Equal(t, uint64(count-limit), output[1].DroppedMeasurements)
+	require.Empty(t, *otelErrs)
+}
+
+// TestOverflowSeriesCount tests the count of distinct attribute
+// sets recorded in the overflow set, for each temporality.
+func TestOverflowSeriesCount(t *testing.T) {
+	const limit = 5
+	overflowSet := attribute.NewSet(attribute.Bool("otel.metric.overflow", true))
+
+	// overflowCount returns the count following the instrument,
+	// or zero when it is not reported.
+	overflowCount := func(t *testing.T, output []data.Instrument) int64 {
+		for _, pt := range output[0].Points {
+			if pt.Attributes.Equals(&overflowSet) {
+				// The count does not replace the metadata.
+				require.Equal(t, 0, pt.Metadata.Len())
+			}
+		}
+		if len(output) == 1 {
+			return 0
+		}
+		require.Equal(t, 2, len(output))
+		require.Equal(t, test.DescriptorDescUnit("counter"+view.OverflowSeriesSuffix, sdkinstrument.AsyncGauge, number.Int64Kind, "distinct attribute sets recorded in the overflow series of counter", "{series}"), output[1].Descriptor)
+		require.Equal(t, 1, len(output[1].Points))
+		require.Equal(t, output[0].Points[0].Temporality, output[1].Points[0].Temporality)
+		return number.ToInt64(output[1].Points[0].Aggregation.(aggregation.Gauge).Gauge())
+	}
+
+	for _, tc := range []struct {
+		name          string
+		tempo         aggregation.TemporalitySelector
+		second, third int64
+		bounded       int64
+	}{
+		// The later interval repeats 3 and adds 2 attribute
+		// sets, the last adds none.
+		{"cumulative", view.StandardTemporality, 18, 18, 3},
+		{"delta", view.DeltaPreferredTemporality, 5, 0, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			views := view.New(
+				"test",
+				safePerf,
+				view.WithClause(
+					view.WithCardinalityLimit(limit),
+					view.WithOverflowSeriesCount(),
+				),
+				view.WithDefaultAggregationTemporalitySelector(tc.tempo),
+			)
+			vc := New(testLib, views)
+
+			inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+			require.NoError(t, err)
+			require.Equal(t, 2, len(vc.Collectors()))
+
+			update := func(i int) {
+				acc := inst.NewAccumulator(attribute.NewSet(attribute.Int("i", i)))
+				acc.(Updater[int64]).Update(1, nobits)
+				acc.(Updater[int64]).Update(1, nobits)
+				require.NoError(t, acc.SnapshotAndProcess(true))
+			}
+
+			// The first limit-1 sets have their own series.
+			for i := 0; i < 20; i++ {
+				update(i)
+			}
+			update(10)
+			require.Equal(t, int64(20-limit+1), overflowCount(t, testCollect(t, vc)))
+
+			for _, i := range []int{10, 11, 12, 20, 21} {
+				update(i)
+			}
+			require.Equal(t, tc.second, overflowCount(t, testCollect(t, vc)))
+
+			update(0)
+			require.Equal(t, tc.third, overflowCount(t, testCollect(t, vc)))
+
+			// The count stops at the limit.
+			inst.Reset()
+			vc.Collectors()[1].(*overflowSeries).limit = 3
+			for i := 0; i < 20; i++ {
+				update(i)
+			}
+			require.Equal(t, tc.bounded, overflowCount(t, testCollect(t, vc)))
+		})
+	}
+}
+
+// TestInstrumentOverflowCombined tests that the aggregator limit is a
+// hard limit even when the instrument-level limit was reached early.
+func TestInstrumentOverflowCombined(t *testing.T) {
+	const aggLimit = 10
+	const instLimit = 2 * aggLimit
+	const count = 5 * instLimit
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{
+			InactiveCollectionPeriods:  1,
+			AggregatorCardinalityLimit: aggLimit,
+			InstrumentCardinalityLimit: instLimit,
+		},
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	views, err := view.Validate(views)
+	require.NoError(t, err)
+
+	vc := New(testLib, views)
+
+	instS, err := testCompile(vc, "S", sdkinstrument.SyncCounter, number.Float64Kind)
+	require.NoError(t, err)
+
+	instA, err := testCompile(vc, "A", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	totalS := float64(0)
+	totalA := int64(0)
+
+	for reps := 0; reps < 10; reps++ {
+		for i := 0; i < count; i++ {
+			attr := attribute.Int("RCi", reps*count+i)
+			aset := attribute.NewSet(attr)
+
+			accS := instS.NewAccumulator(aset)
+			accS.(Updater[float64]).Update(1, nobits)
+			accS.SnapshotAndProcess(true)
+
+			accA := instA.NewAccumulator(aset)
+			accA.(Updater[int64]).Update(1, nobits)
+			accA.SnapshotAndProcess(true)
+		}
+
+		// Both experience overflow; neither exceeds its limit
+		data := testCollect(t, vc)
+		require.Equal(t, test.Descriptor("S", sdkinstrument.SyncCounter, number.Float64Kind), data[0].Descriptor)
+		require.Equal(t, test.Descriptor("A", sdkinstrument.AsyncCounter, number.Int64Kind), data[1].Descriptor)
+
+		// As tested in syncstate, there is an oscillation that
+		// develops when a delta temporality experiences major
+		// overflow.  We're not testing the form of the overflow here,
+		// just that the sum is correct.
+		oflowSCnt := 0
+		sumS := 0.0
+		for _, pt := range data[0].Points {
+			if pt.Attributes == pipeline.OverflowAttributeSet {
+				oflowSCnt++
+			}
+			sumS += number.ToFloat64(pt.Aggregation.(aggregation.Sum).Sum())
+		}
+		if reps == 0 {
+			require.Equal(t, 1, oflowSCnt)
+			require.Equal(t, float64(count), sumS)
+		}
+		totalS += sumS
+		require.Equal(t, float64((reps+1)*count), totalS, "rep %d", reps)
+
+		// Note that because the attribute set is new every
+		// time, we expect a new sum in every round equal to count.
+		// See the special case treatment of overflow in the async
+		// delta-temporality aggregator.
+		oflowACnt := 0
+		sumA := int64(0)
+		for _, pt := range data[1].Points {
+			if pt.Attributes == pipeline.OverflowAttributeSet {
+				oflowACnt++
+			}
+			sumA += number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+		}
+		if reps == 0 {
+			require.Equal(t, 1, oflowACnt)
+			require.Equal(t, int64(count), sumA)
+		}
+		totalA += sumA
+		require.Equal(t, int64((reps+1)*count), totalA, "rep %d", reps)
+	}
+}
+
+// TestExemplars is the most-basic test for exemplars there could be.
+// It creates three series, distinguished by b=1,2,3 then filters b
+// leaving three points.
+func TestExemplars(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.WithKeys([]attribute.Key{"a"}),
+			view.WithAggregatorConfig(
+				aggregator.Config{
+					Exemplar: aggregator.ExemplarConfig{
+						Filter: aggregator.AlwaysOnKind,
+						Size:   3,
+					},
+				},
+			),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "foo", sdkinstrument.SyncCounter, number.Float64Kind)
+	require.NoError(t, err)
+
+	all1 := []attribute.KeyValue{
+		attribute.Int("b", 1),
+		attribute.Int("a", 1),
+	}
+	all2 := []attribute.KeyValue{
+		attribute.Int("b", 2),
+		attribute.Int("a", 1),
+	}
+	all3 := []attribute.KeyValue{
+		attribute.Int("b", 3),
+		attribute.Int("a", 1),
+	}
+	acc1 := inst.NewAccumulator(attribute.NewSet(all1...))
+	eb1 := aggregator.ExemplarBits{
+		Time:       middleTime,
+		Number:     number.FromInt64(1),
+		Attributes: all1,
+		Span:       test.FakeSpan(1, 1),
+	}
+	acc1.(Updater[float64]).Update(1, eb1)
+	acc1.SnapshotAndProcess(false)
+
+	acc2 := inst.NewAccumulator(attribute.NewSet(all2...))
+	eb2 := aggregator.ExemplarBits{
+		Time:       middleTime,
+		Number:     number.FromInt64(2),
+		Attributes: all2,
+		Span:       test.FakeSpan(2, 2),
+	}
+	acc2.(Updater[float64]).Update(2, eb2)
+	acc2.SnapshotAndProcess(false)
+
+	acc3 := inst.NewAccumulator(attribute.NewSet(all3...))
+	eb3 := aggregator.ExemplarBits{
+		Time:       middleTime,
+		Number:     number.FromInt64(3),
+		Attributes: all3,
+		Span:       test.FakeSpan(3, 3),
+	}
+	acc3.(Updater[float64]).Update(3, eb3)
+	acc3.SnapshotAndProcess(false)
+
+	output := testCollect(t, vc)
+
+	// In this test, the number of examples equals the reservoir size.
+	// Weight == point value.
+	test.RequireEqualMetrics(t, output,
+		test.Instrument(
+			test.Descriptor("foo", sdkinstrument.SyncCounter, number.Float64Kind),
+			test.PointEx(
+				startTime, endTime, sum.NewMonotonicFloat64(1+2+3), cumulative,
+				[]attribute.KeyValue{attribute.Int("a", 1)},
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: eb1,
+					Weight:       1,
+				},
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: eb2,
+					Weight:       2,
+				},
+				aggregator.WeightedExemplarBits{
+					ExemplarBits: eb3,
+					Weight:       3,
+				},
+			),
+		),
+	)
+}
+
+// TestExemplarReservoirPerView tests that the reservoir algorithm is
+// selected per view.
+func TestExemplarReservoirPerView(t *testing.T) {
+	exemplarConfig := func(kind aggregator.ReservoirKind) aggregator.Config {
+		return aggregator.Config{
+			Histogram: histogram.NewConfig(),
+			Exemplar: aggregator.ExemplarConfig{
+				Filter:    aggregator.AlwaysOnKind,
+				Size:      2,
+				Reservoir: kind,
+			},
+		}
+	}
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("max"),
+			view.WithAggregatorConfig(exemplarConfig(aggregator.MaxReservoirKind)),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("bucket"),
+			view.WithAggregatorConfig(exemplarConfig(aggregator.BucketReservoirKind)),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	maxInst, err := testCompile(vc, "max", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+	bucketInst, err := testCompile(vc, "bucket", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+
+	exbits := func(value float64) aggregator.ExemplarBits {
+		return aggregator.ExemplarBits{
+			Time:   middleTime,
+			Number: number.FromFloat64(value),
+			Span:   test.FakeSpan(1, 1),
+		}
+	}
+	values := []float64{3, 9, 1, 5, 0.5, 7}
+	for _, inst := range []Instrument{maxInst, bucketInst} {
+		acc := inst.NewAccumulator(*attribute.EmptySet())
+		for _, v := range values {
+			acc.(Updater[float64]).Update(v, exbits(v))
+		}
+		acc.SnapshotAndProcess(false)
+	}
+
+	output := testCollect(t, vc)
+	require.Equal(t, 2, len(output))
+
+	exemplarValues := func(inst data.Instrument) []float64 {
+		require.Equal(t, 1, len(inst.Points))
+		var values []float64
+		for _, ex := range inst.Points[0].Exemplars {
+			values = append(values, number.ToFloat64(ex.Number))
+		}
+		return values
+	}
+
+	require.Equal(t, "max", output[0].Descriptor.Name)
+	require.Equal(t, []float64{9, 7}, exemplarValues(output[0]))
+
+	// (2, 4] and (8, 16] are the first two buckets seen.
+	require.Equal(t, "bucket", output[1].Descriptor.Name)
+	require.Equal(t, []float64{3, 9}, exemplarValues(output[1]))
+}
+
+// TestExemplarReservoirSize tests that the number of exemplars is
+// configured per view.
+func TestExemplarReservoirSize(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("counter"),
+			view.WithExemplarReservoirSize(2),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("histogram"),
+			view.WithExemplarReservoirSize(5),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	counter, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Float64Kind)
+	require.NoError(t, err)
+	histo, err := testCompile(vc, "histogram", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+	plain, err := testCompile(vc, "plain", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+
+	for _, inst := range []Instrument{counter, histo, plain} {
+		acc := inst.NewAccumulator(*attribute.EmptySet())
+		for i := 0; i < 1000; i++ {
+			span := test.FakeSpan(byte(i), byte(i))
+			if !acc.(Updater[float64]).MaySample(true) {
+				continue
+			}
+			acc.(Updater[float64]).Update(float64(i), aggregator.ExemplarBits{
+				Time:   middleTime,
+				Number: number.FromFloat64(float64(i)),
+				Span:   span,
+			})
+		}
+		require.NoError(t, acc.SnapshotAndProcess(false))
+	}
+
+	output := testCollect(t, vc)
+	require.Equal(t, 3, len(output))
+
+	sizes := map[string]int{}
+	for _, inst := range output {
+		require.Equal(t, 1, len(inst.Points))
+		sizes[inst.Descriptor.Name] = len(inst.Points[0].Exemplars)
+	}
+	require.Equal(t, map[string]int{
+		"counter":   2,
+		"histogram": 5,
+		"plain":     0,
+	}, sizes)
+}
+
+// TestExemplarSampleFilter tests that the exemplar filter is
+// selected per view and that sampled exemplars keep their span.
+func TestExemplarSampleFilter(t *testing.T) {
+	filterConfig := func(kind aggregator.ExemplarFilterKind) aggregator.Config {
+		return aggregator.Config{
+			Exemplar: aggregator.ExemplarConfig{
+				Filter: kind,
+				Size:   2,
+			},
+		}
+	}
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("off"),
+			view.WithAggregatorConfig(filterConfig(aggregator.AlwaysOffKind)),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("on"),
+			view.WithAggregatorConfig(filterConfig(aggregator.AlwaysOnKind)),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("traced"),
+			view.WithAggregatorConfig(filterConfig(aggregator.WhenTracedKind)),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	insts := map[string]Instrument{}
+	for _, tc := range []struct {
+		name             string
+		traced, untraced bool
+	}{
+		{"off", false, false},
+		{"on", true, true},
+		{"traced", true, false},
+	} {
+		inst, err := testCompile(vc, tc.name, sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NoError(t, err)
+		insts[tc.name] = inst
+
+		acc := inst.NewAccumulator(*attribute.EmptySet())
+		require.Equal(t, tc.traced, acc.(Updater[int64]).MaySample(true), tc.name)
+		require.Equal(t, tc.untraced, acc.(Updater[int64]).MaySample(false), tc.name)
+	}
+
+	// A traced update is sampled, an untraced update is not.
+	acc := insts["traced"].NewAccumulator(*attribute.EmptySet())
+	updater := acc.(Updater[int64])
+	for _, span := range []trace.Span{test.FakeSpan(1, 2), nil} {
+		var ex aggregator.ExemplarBits
+		if updater.MaySample(span != nil) {
+			ex = aggregator.ExemplarBits{
+				Time:   middleTime,
+				Number: number.FromInt64(1),
+				Span:   span,
+			}
+		}
+		updater.Update(1, ex)
+	}
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	var exemplars []aggregator.WeightedExemplarBits
+	for _, out := range testCollect(t, vc) {
+		if out.Descriptor.Name == "traced" {
+			require.Equal(t, 1, len(out.Points))
+			exemplars = out.Points[0].Exemplars
+		}
+	}
+	require.Equal(t, 1, len(exemplars))
+	require.Equal(t, test.FakeSpan(1, 2).SpanContext(), exemplars[0].Span.SpanContext())
+}
+
+// bitsetUnion is a custom aggregator that estimates the number of
+// distinct values in [0, 64).  When unlucky is set, it panics on 13.
+// When unluckyMerge is set, it panics merging a set including 13.
+type bitsetUnion struct {
+	set          uint64
+	unlucky      bool
+	unluckyMerge bool
+}
+
+func (b *bitsetUnion) Update(value int64) {
+	if b.unlucky && value == 13 {
+		panic("unlucky")
+	}
+	b.set |= 1 << (uint64(value) % 64)
+}
+func (b *bitsetUnion) Merge(from aggregator.CustomAggregator[int64]) {
+	if b.unluckyMerge && from.(*bitsetUnion).set&(1<<13) != 0 {
+		panic("unlucky merge")
+	}
+	b.set |= from.(*bitsetUnion).set
+}
+func (b *bitsetUnion) Clone() aggregator.CustomAggregator[int64] { cpy := *b; return &cpy }
+func (b *bitsetUnion) Reset()                                    { b.set = 0 }
+func (b *bitsetUnion) HasChange() bool                           { return b.set != 0 }
+func (b *bitsetUnion) Subtract(prior aggregator.CustomAggregator[int64]) {
+	b.set &^= prior.(*bitsetUnion).set
+}
+
+var bitsetConfig = &aggregator.CustomConfig{
+	NewInt64: func() aggregator.CustomAggregator[int64] {
+		return &bitsetUnion{}
+	},
+}
+
+// requireCardinality tests the estimate of a single-point bitsetUnion instrument.
+func requireCardinality(t *testing.T, expect int, tempo aggregation.Temporality, inst data.Instrument) {
+	require.Equal(t, 1, len(inst.Points))
+	require.Equal(t, tempo, inst.Points[0].Temporality)
+	require.Equal(t, aggregation.CustomKind, inst.Points[0].Aggregation.Kind())
+
+	state := inst.Points[0].Aggregation.(*custom.Int64)
+	require.Equal(t, expect, bits.OnesCount64(state.Aggregator().(*bitsetUnion).set))
+}
+
+func TestCustomAggregationSync(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithCustomAggregation(bitsetConfig),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	acc1 := inst.NewAccumulator(set)
+	acc2 := inst.NewAccumulator(set)
+
+	for _, v := range []int64{1, 2, 3} {
+		acc1.(Updater[int64]).Update(v, nobits)
+	}
+	for _, v := range []int64{3, 4} {
+		acc2.(Updater[int64]).Update(v, nobits)
+	}
+	acc1.SnapshotAndProcess(false)
+	acc2.SnapshotAndProcess(false)
+
+	output := testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	requireCardinality(t, 4, cumulative, output[0])
+
+	// Repeat values do not change the estimate; new ones do.
+	acc1.(Updater[int64]).Update(1, nobits)
+	acc2.(Updater[int64]).Update(5, nobits)
+	acc1.SnapshotAndProcess(true)
+	acc2.SnapshotAndProcess(true)
+
+	output = testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	requireCardinality(t, 5, cumulative, output[0])
+}
+
+func TestFallbackAggregation(t *testing.T) {
+	for name, policy := range map[string]view.FallbackPolicy{
+		"interval":  view.FallbackForInterval,
+		"permanent": view.FallbackPermanently,
+	} {
+		t.Run(name, func(t *testing.T) {
+			views := view.New(
+				"test",
+				safePerf,
+				view.WithClause(
+					view.MatchInstrumentName("distinct"),
+					view.WithCustomAggregation(&aggregator.CustomConfig{
+						NewInt64: func() aggregator.CustomAggregator[int64] {
+							return &bitsetUnion{unlucky: true}
+						},
+					}),
+					view.WithFallbackAggregation(aggregation.MinMaxSumCountKind, policy),
+				),
+				view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+			)
+
+			vc := New(testLib, views)
+
+			inst, err := testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+			require.NoError(t, err)
+
+			desc := test.Descriptor("distinct"+view.FallbackSuffix, sdkinstrument.SyncHistogram, number.Int64Kind)
+			acc := inst.NewAccumulator(attribute.NewSet())
+
+			// The fallback captures the failing update and
+			// those after it.
+			for _, v := range []int64{1, 2, 13, 3} {
+				acc.(Updater[int64]).Update(v, nobits)
+			}
+			acc.SnapshotAndProcess(false)
+
+			output := testCollect(t, vc)
+			require.Equal(t, 2, len(output))
+			requireCardinality(t, 2, delta, output[0])
+			test.RequireEqualMetrics(t, output[1:],
+				test.Instrument(desc,
+					test.Point(middleTime, endTime, minmaxsumcount.NewInt64(13, 3), delta),
+				),
+			)
+
+			acc.(Updater[int64]).Update(4, nobits)
+			acc.SnapshotAndProcess(true)
+
+			output = testCollect(t, vc)
+			require.Equal(t, 2, len(output))
+
+			if policy == view.FallbackForInterval {
+				requireCardinality(t, 1, delta, output[0])
+				test.RequireEqualMetrics(t, output[1:], test.Instrument(desc))
+				return
+			}
+			require.Equal(t, 0, len(output[0].Points))
+			test.RequireEqualMetrics(t, output[1:],
+				test.Instrument(desc,
+					test.Point(middleTime, endTime, minmaxsumcount.NewInt64(4), delta),
+				),
+			)
+		})
+	}
+}
+
+// TestFallbackForIntervalCumulative ensures that the fallback of a
+// cumulative instrument reports only the intervals in which the
+// primary failed, so that the series is reported by the primary
+// alone once it recovers.
+func TestFallbackForIntervalCumulative(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithCustomAggregation(&aggregator.CustomConfig{
+				NewInt64: func() aggregator.CustomAggregator[int64] {
+					return &bitsetUnion{unlucky: true}
+				},
+			}),
+			view.WithFallbackAggregation(aggregation.MinMaxSumCountKind, view.FallbackForInterval),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.NoError(t, err)
+
+	desc := test.Descriptor("distinct"+view.FallbackSuffix, sdkinstrument.SyncHistogram, number.Int64Kind)
+	acc := inst.NewAccumulator(attribute.NewSet())
+
+	for _, v := range []int64{1, 13, 3} {
+		acc.(Updater[int64]).Update(v, nobits)
+	}
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	output := testCollect(t, vc)
+	require.Equal(t, 2, len(output))
+	requireCardinality(t, 1, cumulative, output[0])
+	test.RequireEqualMetrics(t, output[1:],
+		test.Instrument(desc,
+			test.Point(middleTime, endTime, minmaxsumcount.NewInt64(13, 3), delta),
+		),
+	)
+
+	acc.(Updater[int64]).Update(4, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	output = testCollect(t, vc)
+	require.Equal(t, 2, len(output))
+	requireCardinality(t, 2, cumulative, output[0])
+	test.RequireEqualMetrics(t, output[1:], test.Instrument(desc))
+}
+
+// TestFallbackNameConflict ensures that the name of a fallback is
+// checked for conflicts with other instruments.
+func TestFallbackNameConflict(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithFallbackAggregation(aggregation.MinMaxSumCountKind, view.FallbackPermanently),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	_, err := testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.NoError(t, err)
+
+	_, err = testCompile(vc, "distinct"+view.FallbackSuffix, sdkinstrument.SyncCounter, number.Int64Kind)
+	require.Error(t, err)
+	require.ErrorIs(t, err, ViewConflictsError{})
+
+	// Compiling the primary again reports the conflict of its
+	// fallback.
+	_, err = testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.ErrorIs(t, err, ViewConflictsError{})
+}
+
+// TestFallbackDrop ensures that a Drop fallback discards the
+// measurements of a failed series without storage or output.
+func TestFallbackDrop(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithCustomAggregation(&aggregator.CustomConfig{
+				NewInt64: func() aggregator.CustomAggregator[int64] {
+					return &bitsetUnion{unlucky: true}
+				},
+			}),
+			view.WithFallbackAggregation(aggregation.DropKind, view.FallbackPermanently),
+		),
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "distinct", sdkinstrument.SyncHistogram, number.Int64Kind)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(vc.Collectors()))
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	for _, v := range []int64{1, 2, 13, 3} {
+		acc.(Updater[int64]).Update(v, nobits)
+	}
+	require.NoError(t, acc.SnapshotAndProcess(true))
+
+	output := testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	requireCardinality(t, 2, delta, output[0])
+
+	drop := newDropInstrument(test.Descriptor("distinct", sdkinstrument.SyncHistogram, number.Int64Kind))
+	dacc := drop.NewAccumulator(attribute.NewSet())
+	require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		dacc.(Updater[int64]).Update(1, nobits)
+	}))
+	require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		drop.NewAccumulator(attribute.NewSet()).(Updater[int64]).Update(1, nobits)
+	}))
+}
+
+func TestFallbackAggregationSemanticError(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("updown"),
+			view.WithFallbackAggregation(aggregation.MinMaxSumCountKind, view.FallbackPermanently),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "updown", sdkinstrument.SyncUpDownCounter, number.Int64Kind)
+	require.Error(t, err)
+	require.ErrorIs(t, err, ViewConflictsError{})
+	require.Contains(t, err.Error(), "fallback: SyncUpDownCounter instrument incompatible with MinMaxSumCount aggregation")
+
+	// The primary aggregation is used without a fallback.
+	require.Equal(t, 1, len(vc.Collectors()))
+	acc := inst.NewAccumulator(attribute.NewSet())
+	acc.(Updater[int64]).Update(5, nobits)
+	acc.SnapshotAndProcess(true)
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("updown", sdkinstrument.SyncUpDownCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewNonMonotonicInt64(5), cumulative),
+		),
+	)
+}
+
+func TestCustomAggregationAsyncDelta(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithCustomAggregation(bitsetConfig),
+		),
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "distinct", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	observe := func(x int64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		acc.SnapshotAndProcess(true)
+	}
+
+	observe(10)
+	output := testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	requireCardinality(t, 1, delta, output[0])
+
+	// An unchanged observation is not reported.
+	observe(10)
+	output = testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	require.Equal(t, 0, len(output[0].Points))
+
+	observe(11)
+	output = testCollect(t, vc)
+	require.Equal(t, 1, len(output))
+	requireCardinality(t, 1, delta, output[0])
+}
+
+// TestShardedAccumulator tests that sharded synchronous accumulators
+// preserve exact aggregation semantics under concurrent updates.
+func TestShardedAccumulator(t *testing.T) {
+	const goroutines = 8
+	const updates = 1000
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{
+			AccumulatorShards: 4,
+		},
+		view.WithClause(
+			view.MatchInstrumentKind(sdkinstrument.SyncHistogram),
+			view.WithAggregation(aggregation.MinMaxSumCountKind),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	counter, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	histo, err := testCompile(vc, "histo", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+
+	gaugeInst, err := testCompile(vc, "gauge", sdkinstrument.SyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet(attribute.String("hot", "series"))
+	cacc := counter.NewAccumulator(set)
+	hacc := histo.NewAccumulator(set)
+	gacc := gaugeInst.NewAccumulator(set)
+
+	require.IsType(t, &shardedSyncAccumulator[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods, alwaysOffSampleFilter]{}, cacc)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= updates; i++ {
+				cacc.(Updater[int64]).Update(1, nobits)
+				hacc.(Updater[float64]).Update(float64(i), nobits)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		gacc.(Updater[int64]).Update(int64(i), nobits)
+	}
+
+	cacc.SnapshotAndProcess(true)
+	hacc.SnapshotAndProcess(true)
+	gacc.SnapshotAndProcess(true)
+
+	var expectHisto []float64
+	for g := 0; g < goroutines; g++ {
+		for i := 1; i <= updates; i++ {
+			expectHisto = append(expectHisto, float64(i))
+		}
+	}
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(goroutines*updates), cumulative, set.ToSlice()...),
+		),
+		test.Instrument(
+			test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Float64Kind),
+			test.Point(startTime, endTime, minmaxsumcount.NewFloat64(expectHisto...), cumulative, set.ToSlice()...),
+		),
+		test.Instrument(
+			test.Descriptor("gauge", sdkinstrument.SyncGauge, number.Int64Kind),
+			test.Point(startTime, endTime, gauge.NewInt64(9), cumulative, set.ToSlice()...),
+		),
+	)
+}
+
+// TestRollupTotal verifies that the empty attribute set of a view
+// with a rollup total equals the sum of its other series.
+func TestRollupTotal(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("requests"),
+			view.WithKeys([]attribute.Key{"path"}),
+			view.WithRollupTotal(),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "requests", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	record := func(x int64, kvs ...attribute.KeyValue) {
+		acc := inst.NewAccumulator(attribute.NewSet(kvs...))
+		acc.(Updater[int64]).Update(x, nobits)
+		acc.SnapshotAndProcess(true)
+	}
+
+	record(1, attribute.String("path", "/a"))
+	record(2, attribute.String("path", "/a"), attribute.String("host", "h1"))
+	record(4, attribute.String("path", "/b"))
+	record(8, attribute.String("path", "/c"))
+
+	output := testCollect(t, vc)
+	require.Len(t, output, 1)
+
+	series := map[attribute.Distinct]int64{}
+	var total, dimensioned int64
+	for _, pt := range output[0].Points {
+		value := number.ToInt64(pt.Aggregation.(*sum.MonotonicInt64).Sum())
+		series[pt.Attributes.Equivalent()] = value
+		if pt.Attributes.Len() == 0 {
+			total = value
+		} else {
+			dimensioned += value
+		}
+	}
+	pathA := attribute.NewSet(attribute.String("path", "/a"))
+	require.Len(t, series, 4)
+	require.Equal(t, int64(3), series[pathA.Equivalent()])
+	require.Equal(t, int64(15), total)
+	require.Equal(t, dimensioned, total)
+
+	// Measurements in the empty set after filtering are recorded
+	// in the total once.
+	record(16, attribute.String("host", "h2"))
+	record(32)
+
+	output = testCollect(t, vc)
+	for _, pt := range output[0].Points {
+		if pt.Attributes.Len() == 0 {
+			require.Equal(t, int64(63), number.ToInt64(pt.Aggregation.(*sum.MonotonicInt64).Sum()))
+		}
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("deliveries"),
+			view.WithDeduplication(time.Second),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "deliveries", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	start := time.Unix(1000, 0)
+	acc := inst.NewAccumulator(attribute.NewSet(attribute.String("queue", "a")))
+	record := func(x int64, after time.Duration) {
+		acc.(Updater[int64]).Update(x, aggregator.ExemplarBits{Time: start.Add(after)})
+	}
+	total := func() int64 {
+		acc.SnapshotAndProcess(false)
+		output := testCollect(t, vc)
+		require.Len(t, output, 1)
+		require.Len(t, output[0].Points, 1)
+		return number.ToInt64(output[0].Points[0].Aggregation.(*sum.MonotonicInt64).Sum())
+	}
+
+	// Repeats within the window are discarded.
+	record(5, 0)
+	record(5, time.Microsecond)
+	record(5, 2*time.Microsecond)
+	require.Equal(t, int64(5), total())
+
+	// Beyond the window, the value counts again.
+	record(5, time.Second)
+	record(5, time.Second+time.Microsecond)
+	require.Equal(t, int64(10), total())
+
+	// Distinct values always count.
+	for x := int64(1); x <= 10; x++ {
+		record(x*100, time.Second)
+	}
+	require.Equal(t, int64(5510), total())
+
+	// Other series are independent.
+	other := inst.NewAccumulator(attribute.NewSet(attribute.String("queue", "b")))
+	other.(Updater[int64]).Update(5, aggregator.ExemplarBits{Time: start})
+	other.SnapshotAndProcess(false)
+	output := testCollect(t, vc)
+	require.Len(t, output[0].Points, 2)
+}
+
+func TestEmptyDeltaPoints(t *testing.T) {
+	for _, emit := range []bool{false, true} {
+		t.Run(fmt.Sprint("emit=", emit), func(t *testing.T) {
+			opts := []view.ClauseOption{view.MatchInstrumentName("latency")}
+			if emit {
+				opts = append(opts, view.WithEmptyDeltaPoints())
+			}
+			views := view.New(
+				"test",
+				safePerf,
+				view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+				view.WithClause(opts...),
+			)
+
+			vc := New(testLib, views)
+
+			inst, err := testCompile(vc, "latency", sdkinstrument.SyncHistogram, number.Float64Kind)
+			require.NoError(t, err)
+
+			seq := testSequence
+			acc := inst.NewAccumulator(attribute.NewSet())
+			acc.(Updater[float64]).Update(1, nobits)
+			acc.(Updater[float64]).Update(2, nobits)
+			acc.SnapshotAndProcess(false)
+
+			desc := test.Descriptor("latency", sdkinstrument.SyncHistogram, number.Float64Kind)
+			test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq),
+				test.Instrument(desc,
+					test.Point(seq.Last, seq.Now, histogram.NewFloat64(histogram.NewConfig(), 1, 2), delta),
+				),
+			)
+
+			// An idle interval reports an empty histogram
+			// or nothing.
+			idle := test.Instrument(desc)
+			if emit {
+				idle = test.Instrument(desc,
+					test.Point(seq.Last, seq.Now, histogram.NewFloat64(histogram.NewConfig()), delta),
+				)
+			}
+			acc.SnapshotAndProcess(false)
+			test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq), idle)
+
+			// Once released, the series is reported for the
+			// last time.
+			acc.SnapshotAndProcess(true)
+			test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq), idle)
+			test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq), test.Instrument(desc))
+		})
+	}
+}
+
+func TestFanOutAsyncDelta(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		acc := inst.NewAccumulator(attribute.NewSet(
+			attribute.String("host", "a"),
+			attribute.Int("pid", i),
+		))
+		acc.(Updater[int64]).Update(1, nobits)
+		acc.SnapshotAndProcess(true)
+	}
+	acc := inst.NewAccumulator(attribute.NewSet(attribute.String("host", "b")))
+	acc.(Updater[int64]).Update(1, nobits)
+	acc.SnapshotAndProcess(true)
+
+	// The series remain live after the delta collection.
+	_ = testCollectSequence(t, vc, testSequence)
+
+	var fo []data.FanOut
+	for _, coll := range vc.Collectors() {
+		coll.FanOut([]attribute.Key{"pid"}, &fo)
+	}
+	require.Equal(t, []data.FanOut{
+		{
+			Descriptor: test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+			Attributes: attribute.NewSet(attribute.String("host", "a")),
+			Series:     10,
+			Values:     map[attribute.Key]int{"pid": 10},
+		},
+		{
+			Descriptor: test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+			Attributes: attribute.NewSet(attribute.String("host", "b")),
+			Series:     1,
+			Values:     map[attribute.Key]int{},
+		},
+	}, fo)
+}
+
+// TestDeltaConcurrentCollect verifies that an update concurrent with
+// a delta collection is attributed to exactly one interval, including
+// when accumulators are released and their series are removed.
+func TestDeltaConcurrentCollect(t *testing.T) {
+	for _, shards := range []uint32{1, 4} {
+		t.Run(fmt.Sprint("shards=", shards), func(t *testing.T) {
+			perf := safePerf
+			perf.AccumulatorShards = shards
+			vc := New(testLib, view.New(
+				"test",
+				perf,
+				view.WithDefaultAggregationTemporalitySelector(aggregation.LowMemoryTemporality),
+			))
+
+			cntr, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+			require.NoError(t, err)
+			hist, err := testCompile(vc, "histogram", sdkinstrument.SyncHistogram, number.Float64Kind)
+			require.NoError(t, err)
+
+			const (
+				workers = 8
+				rounds  = 200
+				updates = 10
+			)
+			attrs := attribute.NewSet(attribute.String("k", "v"))
+
+			var total int64
+			var count uint64
+			collect := func() {
+				for _, inst := range testCollect(t, vc) {
+					for _, pt := range inst.Points {
+						require.Equal(t, aggregation.DeltaTemporality, pt.Temporality)
+						switch agg := pt.Aggregation.(type) {
+						case aggregation.Histogram:
+							count += agg.Count()
+						case aggregation.Sum:
+							total += number.ToInt64(agg.Sum())
+						}
+					}
+				}
+			}
+
+			var wg sync.WaitGroup
+			stop := make(chan struct{})
+			collected := make(chan struct{})
+			go func() {
+				defer close(collected)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						collect()
+					}
+				}
+			}()
+
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for r := 0; r < rounds; r++ {
+						accC := cntr.NewAccumulator(attrs)
+						accH := hist.NewAccumulator(attrs)
+						for u := 0; u < updates; u++ {
+							accC.(Updater[int64]).Update(1, nobits)
+							accH.(Updater[float64]).Update(1, nobits)
+							if u == updates/2 {
+								accC.SnapshotAndProcess(false)
+								accH.SnapshotAndProcess(false)
+							}
+						}
+						// Releasing allows Collect to remove
+						// the series while others are updated.
+						accC.SnapshotAndProcess(true)
+						accH.SnapshotAndProcess(true)
+					}
+				}()
+			}
+			wg.Wait()
+			close(stop)
+			<-collected
+
+			// The final collection includes the remainder.
+			collect()
+
+			require.Equal(t, int64(workers*rounds*updates), total)
+			require.Equal(t, uint64(workers*rounds*updates), count)
+		})
+	}
+}
+
+func TestSparseHistogramPerView(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("latency"),
+			view.WithAggregatorConfig(altHistogramConfig),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("latency"),
+			view.WithName("latency.sparse"),
+			view.WithAggregatorConfig(altHistogramConfig),
+			view.WithSparseHistogram(),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "latency", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	for _, v := range []float64{1e-6, 0.5, 3, 3, 1e3, 1e9, -2, 0} {
+		acc.(Updater[float64]).Update(v, nobits)
+	}
+	acc.SnapshotAndProcess(true)
+
+	type buckets struct {
+		Offset int32
+		Counts []uint64
+	}
+	expand := func(b aggregation.Buckets) buckets {
+		r := buckets{Offset: b.Offset()}
+		for i := uint32(0); i < b.Len(); i++ {
+			r.Counts = append(r.Counts, b.At(i))
+		}
+		return r
+	}
+	type result struct {
+		Scale              int32
+		Count, ZeroCount   uint64
+		Sum, Min, Max      float64
+		Positive, Negative buckets
+	}
+	resultOf := func(inst data.Instrument) result {
+		require.Len(t, inst.Points, 1)
+		h := inst.Points[0].Aggregation.(aggregation.Histogram)
+		return result{
+			Scale:     h.Scale(),
+			Count:     h.Count(),
+			ZeroCount: h.ZeroCount(),
+			Sum:       number.ToFloat64(h.Sum()),
+			Min:       number.ToFloat64(h.Min()),
+			Max:       number.ToFloat64(h.Max()),
+			Positive:  expand(h.Positive()),
+			Negative:  expand(h.Negative()),
+		}
+	}
+
+	output := testCollect(t, vc)
+	require.Len(t, output, 2)
+	require.Equal(t, "latency", output[0].Descriptor.Name)
+	require.Equal(t, "latency.sparse", output[1].Descriptor.Name)
+
+	dense := resultOf(output[0])
+	require.Equal(t, uint64(8), dense.Count)
+	require.Equal(t, dense, resultOf(output[1]))
+}
+
+func TestHistogramUnitDefaults(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultHistogramUnitSelector(view.StandardHistogramUnit),
+		view.WithClause(
+			view.MatchInstrumentName("configured"),
+			view.WithAggregatorConfig(altHistogramConfig),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	for _, tc := range []struct {
+		name    string
+		unit    string
+		maxSize int32
+	}{
+		{"duration", "s", 319},
+		{"size", "By", 479},
+		{"distance", "furlong", histogram.DefaultMaxSize},
+		{"configured", "s", 15},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inst, err := testCompileDescUnit(vc, tc.name, sdkinstrument.SyncHistogram, number.Float64Kind, "", tc.unit)
+			require.NoError(t, err)
+
+			acc := inst.NewAccumulator(attribute.NewSet())
+			acc.(Updater[float64]).Update(1, nobits)
+			acc.SnapshotAndProcess(true)
+
+			var output []data.Instrument
+			inst.(data.Collector).Collect(testSequence, &output)
+
+			require.Len(t, output, 1)
+			require.Equal(t,
+				histogram.NewFloat64(histogram.NewConfig(histogram.WithMaxSize(tc.maxSize)), 1),
+				output[0].Points[0].Aggregation,
+			)
+		})
+	}
+}
+
+func TestShadowAggregation(t *testing.T) {
+	shadowCfg := aggregator.Config{
+		Histogram: histogram.NewConfig(histogram.WithMaxSize(8)),
+	}
+	primaryViews := view.New("test", safePerf)
+	shadowViews := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("lat"),
+			view.WithShadowAggregation(aggregation.HistogramKind, shadowCfg),
+		),
+	)
+
+	var outputs [][]data.Instrument
+	for _, views := range []*view.Views{primaryViews, shadowViews} {
+		vc := New(testLib, views)
+
+		inst, err := testCompile(vc, "lat", sdkinstrument.SyncHistogram, number.Float64Kind)
+		require.NoError(t, err)
+
+		// A known exponential distribution from 1 to 20959.
+		acc := inst.NewAccumulator(attribute.NewSet())
+		for i := 0; i < 1000; i++ {
+			acc.(Updater[float64]).Update(math.Pow(1.01, float64(i)), nobits)
+		}
+		acc.SnapshotAndProcess(false)
+
+		outputs = append(outputs, testCollect(t, vc))
+	}
+	require.Equal(t, 1, len(outputs[0]))
+	require.Equal(t, 2, len(outputs[1]))
+
+	// The shadow does not change the primary output.
+	require.Equal(t, outputs[0][0], outputs[1][0])
+
+	shadow := outputs[1][1]
+	require.Equal(t, "lat"+view.ShadowSuffix, shadow.Descriptor.Name)
+	require.LessOrEqual(t, shadow.Points[0].Aggregation.(*histogram.Float64).Positive().Len(), uint32(8))
+
+	divs, err := compare.Instruments(outputs[1][0], shadow, 0.5, 0.9, 0.99)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(divs))
+
+	div := divs[attribute.NewSet()]
+	require.Equal(t, int64(0), div.Count)
+	require.Equal(t, 0.0, div.Sum)
+	require.Equal(t, 0.0, div.Min)
+	require.Equal(t, 0.0, div.Max)
+	require.Equal(t, 3, len(div.Quantiles))
+
+	// The primary has scale 3, the shadow scale -1, which range
+	// in error up to 4.4% and 100% respectively.
+	require.Greater(t, div.MaxQuantileError(), 0.05)
+	require.Less(t, div.MaxQuantileError(), 2.0)
+}
+
+// TestSnapshotAndProcessMergeError tests that a failed merge is
+// returned by SnapshotAndProcess, with the errors of each view
+// combined, and that the accumulator remains usable.
+func TestSnapshotAndProcessMergeError(t *testing.T) {
+	unlucky := &aggregator.CustomConfig{
+		NewInt64: func() aggregator.CustomAggregator[int64] {
+			return &bitsetUnion{unluckyMerge: true}
+		},
+	}
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithName("first"),
+			view.WithCustomAggregation(unlucky),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithName("second"),
+			view.WithCustomAggregation(unlucky),
+		),
+		view.WithClause(
+			view.MatchInstrumentName("distinct"),
+			view.WithName("total"),
+			view.WithAggregation(aggregation.MonotonicSumKind),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "distinct", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	acc.(Updater[int64]).Update(13, nobits)
+
+	err = acc.SnapshotAndProcess(false)
+	require.ErrorIs(t, err, aggregator.ErrMergeFailed)
+	require.Equal(t, 2, len(multierr.Errors(err)))
+	require.Contains(t, err.Error(), "unlucky merge")
+
+	acc.(Updater[int64]).Update(1, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(true))
+
+	// The failed snapshot is lost, the others are not.
+	output := testCollect(t, vc)
+	require.Equal(t, 3, len(output))
+	for _, inst := range output[:2] {
+		require.Equal(t, 1, len(inst.Points))
+		require.Equal(t, uint64(1<<1), inst.Points[0].Aggregation.(*custom.Int64).Aggregator().(*bitsetUnion).set)
+	}
+	test.RequireEqualMetrics(t, output[2:],
+		test.Instrument(
+			test.Descriptor("total", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(14), cumulative),
+		),
+	)
+}
+
+// TestGaugeTimestamps ensures that the gauge value with the latest
+// time wins regardless of the order accumulators are processed.
+func TestGaugeTimestamps(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("gauge"),
+			view.WithAggregatorConfig(aggregator.Config{GaugeTimestamps: true}),
+		),
+	)
+
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "gauge", sdkinstrument.AsyncGauge, number.Float64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	base := time.Unix(1000, 0)
+
+	for round, lateFirst := range []bool{true, false} {
+		early := base.Add(time.Duration(2*round) * time.Second)
+		late := early.Add(time.Second)
+
+		accEarly := inst.NewAccumulator(set)
+		accEarly.(Updater[float64]).Update(1, aggregator.ExemplarBits{Time: early})
+		accLate := inst.NewAccumulator(set)
+		accLate.(Updater[float64]).Update(2, aggregator.ExemplarBits{Time: late})
+
+		order := []Accumulator{accEarly, accLate}
+		if lateFirst {
+			order = []Accumulator{accLate, accEarly}
+		}
+		for _, acc := range order {
+			require.NoError(t, acc.SnapshotAndProcess(true))
+		}
+
+		output := testCollect(t, vc)
+		require.Equal(t, 1, len(output))
+		require.Equal(t, 1, len(output[0].Points))
+
+		pt := output[0].Points[0]
+		require.Equal(t, 2.0, number.ToFloat64(pt.Aggregation.(aggregation.Gauge).Gauge()))
+		require.True(t, late.Equal(pt.Observed))
+	}
+}
+
+// TestReset ensures that Reset discards series without output,
+// leaving the accumulators in use valid.
+func TestReset(t *testing.T) {
+	vc := New(testLib, view.New("test", safePerf))
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	setA := attribute.NewSet(attribute.String("a", "1"))
+	setB := attribute.NewSet(attribute.String("b", "2"))
+
+	// accA remains in use, accB releases its reference.
+	accA := inst.NewAccumulator(setA)
+	accA.(Updater[int64]).Update(1, nobits)
+	require.NoError(t, accA.SnapshotAndProcess(false))
+
+	accB := inst.NewAccumulator(setB)
+	accB.(Updater[int64]).Update(2, nobits)
+	require.NoError(t, accB.SnapshotAndProcess(true))
+
+	// Pending measurements are kept.
+	accA.(Updater[int64]).Update(3, nobits)
+
+	inst.Reset()
+
+	require.NoError(t, accA.SnapshotAndProcess(true))
+
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(3), cumulative, setA.ToSlice()...),
+		),
+	)
+
+	// Once its accumulator is released, the series is removed.
+	inst.Reset()
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+		),
+	)
+}
+
+// TestResetAsyncDelta ensures that Reset clears the prior values of
+// an asynchronous instrument with delta temporality.
+func TestResetAsyncDelta(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "async", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	observe := func(x int64) {
+		acc := inst.NewAccumulator(attribute.NewSet())
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	expect := func(x int64) {
+		test.RequireEqualMetrics(t,
+			testCollect(t, vc),
+			test.Instrument(
+				test.Descriptor("async", sdkinstrument.AsyncCounter, number.Int64Kind),
+				test.Point(middleTime, endTime, sum.NewMonotonicInt64(x), delta),
+			),
+		)
+	}
+
+	observe(10)
+	expect(10)
+
+	observe(15)
+	inst.Reset()
+
+	// Without a prior value, the observation is reported whole.
+	observe(20)
+	expect(20)
+
+	observe(25)
+	expect(5)
+}
+
+// TestAsyncDeltaSourceReset ensures that a decrease in the cumulative
+// value of an asynchronous counter is treated as a reset of its
+// source.
+func TestAsyncDeltaSourceReset(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	vc := New(testLib, views)
+
+	ints, err := testCompile(vc, "ints", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+	floats, err := testCompile(vc, "floats", sdkinstrument.AsyncCounter, number.Float64Kind)
+	require.NoError(t, err)
+
+	observe := func(x int64) {
+		acc := ints.NewAccumulator(attribute.NewSet())
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+
+		acc = floats.NewAccumulator(attribute.NewSet())
+		acc.(Updater[float64]).Update(float64(x), nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	expect := func(x int64) {
+		test.RequireEqualMetrics(t,
+			testCollect(t, vc),
+			test.Instrument(
+				test.Descriptor("ints", sdkinstrument.AsyncCounter, number.Int64Kind),
+				test.Point(middleTime, endTime, sum.NewMonotonicInt64(x), delta),
+			),
+			test.Instrument(
+				test.Descriptor("floats", sdkinstrument.AsyncCounter, number.Float64Kind),
+				test.Point(middleTime, endTime, sum.NewMonotonicFloat64(float64(x)), delta),
+			),
+		)
+	}
+
+	observe(10)
+	expect(10)
+
+	// The source restarted and counted 4 since.
+	observe(4)
+	expect(4)
+
+	// Differences continue from the new baseline.
+	observe(6)
+	expect(2)
+}
+
+// TestDeltaGauge ensures that asynchronous gauges configured with
+// view.WithDeltaGauge report successive differences, treating a
+// decrease as a reset, while other gauges report the last value.
+func TestDeltaGauge(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+		view.WithClause(
+			view.MatchInstrumentNameRegexp(regexp.MustCompile("^delta")),
+			view.WithDeltaGauge(),
+		),
+	)
+	vc := New(testLib, views)
+
+	ints, err := testCompile(vc, "delta.ints", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+	floats, err := testCompile(vc, "delta.floats", sdkinstrument.AsyncGauge, number.Float64Kind)
+	require.NoError(t, err)
+	plain, err := testCompile(vc, "plain", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+
+	observe := func(x int64) {
+		for _, inst := range []Instrument{ints, plain} {
+			acc := inst.NewAccumulator(attribute.NewSet())
+			acc.(Updater[int64]).Update(x, nobits)
+			require.NoError(t, acc.SnapshotAndProcess(true))
+		}
+		acc := floats.NewAccumulator(attribute.NewSet())
+		acc.(Updater[float64]).Update(float64(x), nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	expect := func(x, last int64) {
+		test.RequireEqualMetrics(t,
+			testCollect(t, vc),
+			test.Instrument(
+				test.Descriptor("delta.ints", sdkinstrument.AsyncGauge, number.Int64Kind),
+				test.Point(middleTime, endTime, gauge.NewInt64(x), delta),
+			),
+			test.Instrument(
+				test.Descriptor("delta.floats", sdkinstrument.AsyncGauge, number.Float64Kind),
+				test.Point(middleTime, endTime, gauge.NewFloat64(float64(x)), delta),
+			),
+			test.Instrument(
+				test.Descriptor("plain", sdkinstrument.AsyncGauge, number.Int64Kind),
+				test.Point(startTime, endTime, gauge.NewInt64(last), cumulative),
+			),
+		)
+	}
+
+	// The first observation is its difference from zero.
+	observe(10)
+	expect(10, 10)
+
+	observe(15)
+	expect(5, 15)
+
+	// An unchanged value has a zero difference.
+	observe(15)
+	expect(0, 15)
+
+	// The decrease is a reset of the source.
+	observe(3)
+	expect(3, 3)
+
+	observe(7)
+	expect(4, 7)
+
+	// Unobserved series are not reported.
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("delta.ints", sdkinstrument.AsyncGauge, number.Int64Kind),
+		),
+		test.Instrument(
+			test.Descriptor("delta.floats", sdkinstrument.AsyncGauge, number.Float64Kind),
+		),
+		test.Instrument(
+			test.Descriptor("plain", sdkinstrument.AsyncGauge, number.Int64Kind),
+		),
+	)
+
+	observe(9)
+	expect(2, 9)
+}
+
+// TestSetEnabled ensures that disabled instruments discard
+// measurements, including those of accumulators already in use, and
+// keep their state until Reset.
+func TestSetEnabled(t *testing.T) {
+	vc := New(testLib, view.New("test", safePerf))
+
+	counter, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+	lastValue, err := testCompile(vc, "gauge", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	acc := counter.NewAccumulator(set)
+	add := func(x int64) {
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(false))
+	}
+	observe := func(x int64) {
+		acc := lastValue.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	expect := func(total int64, last ...int64) {
+		var points []data.Point
+		for _, x := range last {
+			points = append(points, test.Point(startTime, endTime, gauge.NewInt64(x), cumulative))
+		}
+		test.RequireEqualMetrics(t,
+			testCollect(t, vc),
+			test.Instrument(
+				test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(startTime, endTime, sum.NewMonotonicInt64(total), cumulative),
+			),
+			test.Instrument(
+				test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Int64Kind),
+				points...,
+			),
+		)
+	}
+
+	add(1)
+	observe(10)
+	expect(1, 10)
+
+	// Disabled instruments keep their state, while the
+	// asynchronous observations are discarded.
+	counter.SetEnabled(false)
+	lastValue.SetEnabled(false)
+	add(2)
+	observe(20)
+	require.False(t, acc.(Updater[int64]).MaySample(true))
+	expect(1)
+
+	// The accumulator in use records again once enabled.
+	counter.SetEnabled(true)
+	lastValue.SetEnabled(true)
+	add(3)
+	observe(30)
+	expect(4, 30)
+
+	// Reset after disabling discards the state.
+	counter.SetEnabled(false)
+	lastValue.SetEnabled(false)
+	counter.Reset()
+	lastValue.Reset()
+	add(4)
+	observe(40)
+	expect(0)
+
+	// An accumulator created while disabled creates no series
+	// until the instrument is enabled.
+	other := counter.NewAccumulator(attribute.NewSet(attribute.String("k", "v")))
+	other.(Updater[int64]).Update(5, nobits)
+	require.NoError(t, other.SnapshotAndProcess(false))
+	require.Equal(t, 1, len(testCollect(t, vc)[0].Points))
+
+	counter.SetEnabled(true)
+	other.(Updater[int64]).Update(6, nobits)
+	require.NoError(t, other.SnapshotAndProcess(false))
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc)[:1],
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(0), cumulative),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(6), cumulative, attribute.String("k", "v")),
+		),
+	)
+}
+
+// TestAggregationKindMismatch ensures that an aggregation not of the
+// instrument's storage type is reported, not mistaken for storage.
+func TestAggregationKindMismatch(t *testing.T) {
+	base := instrumentBase[int64, sum.MonotonicInt64, int64, sum.MonotonicInt64Methods]{
+		desc: test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+	}
+	_, err := base.storageOf(&data.Point{Aggregation: gauge.NewInt64(1)})
+	require.ErrorIs(t, err, aggregator.ErrAggregationKindMismatch)
+	require.Contains(t, err.Error(), "counter")
+
+	storage, err := base.storageOf(&data.Point{Aggregation: sum.NewMonotonicInt64(1)})
+	require.NoError(t, err)
+	require.Equal(t, sum.NewMonotonicInt64(1), storage)
+
+	// Folding a point of the wrong type reports the error.
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	vc := New(testLib, view.New("test", safePerf))
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	points := data.Instrument{
+		Points: []data.Point{
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(1), cumulative, attribute.String("a", "1")),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative, attribute.String("a", "2")),
+			test.Point(startTime, endTime, gauge.NewInt64(3), cumulative, attribute.String("a", "3")),
+		},
+	}
+	inst.(Folder).Fold(&points, 1)
+	require.Equal(t, 1, len(errs))
+	require.ErrorIs(t, errs[0], aggregator.ErrAggregationKindMismatch)
+	require.Equal(t, 1, len(points.Points))
+	require.Equal(t, sum.NewMonotonicInt64(3), points.Points[0].Aggregation)
+}
+
+// TestResetSeries ensures that a declared reset empties one
+// cumulative series and sets the start time of its points.
+func TestResetSeries(t *testing.T) {
+	vc := New(testLib, view.New("test", safePerf))
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	setA := attribute.NewSet(attribute.String("a", "1"))
+	setB := attribute.NewSet(attribute.String("b", "2"))
+
+	add := func(set attribute.Set, x int64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+
+	add(setA, 5)
+	add(setB, 2)
+
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(5), cumulative, setA.ToSlice()...),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative, setB.ToSlice()...),
+		),
+	)
+
+	inst.ResetSeries(setA, middleTime)
+	add(setA, 3)
+
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(3), cumulative, setA.ToSlice()...),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(2), cumulative, setB.ToSlice()...),
+		),
+	)
+}
+
+// TestPreRegister ensures that a pre-registered series is kept by
+// delta temporality until its first accumulator is released, and
+// that asynchronous views are not affected.
+func TestPreRegister(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	vc := New(testLib, views)
+
+	syncInst, err := testCompile(vc, "sync", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+	asyncInst, err := testCompile(vc, "async", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	setA := attribute.NewSet(attribute.String("a", "1"))
+	setB := attribute.NewSet(attribute.String("b", "2"))
+
+	// Registering twice holds one reference.
+	syncInst.PreRegister(setA, setB)
+	syncInst.PreRegister(setA)
+	asyncInst.PreRegister(setA, setB)
+
+	require.Equal(t, 2, syncInst.(data.Collector).InMemorySize())
+	require.Equal(t, 0, asyncInst.(data.Collector).InMemorySize())
+
+	// Unused series are not reported or removed.
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(test.Descriptor("sync", sdkinstrument.SyncCounter, number.Int64Kind)),
+		test.Instrument(test.Descriptor("async", sdkinstrument.AsyncCounter, number.Int64Kind)),
+	)
+	require.Equal(t, 2, syncInst.(data.Collector).InMemorySize())
+
+	acc := syncInst.NewAccumulator(setA)
+	acc.(Updater[int64]).Update(1, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(true))
+
+	test.RequireEqualMetrics(t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("sync", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(1), delta, setA.ToSlice()...),
+		),
+		test.Instrument(test.Descriptor("async", sdkinstrument.AsyncCounter, number.Int64Kind)),
+	)
+
+	// The used series is removed once its accumulator is
+	// released, the unused series remains.
+	testCollect(t, vc)
+	require.Equal(t, 1, syncInst.(data.Collector).InMemorySize())
+}
+
+// TestPeekSyncDelta ensures that Peek copies the current interval
+// of a synchronous delta instrument without disturbing the next
+// Collect.
+func TestPeekSyncDelta(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	set := attribute.NewSet(attribute.String("a", "1"))
+
+	acc := inst.NewAccumulator(set)
+	acc.(Updater[int64]).Update(1, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	var peeked []data.Instrument
+	inst.(data.Collector).Peek(testSequence, delta, &peeked)
+	test.RequireEqualMetrics(t, peeked,
+		test.Instrument(desc,
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(1), delta, set.ToSlice()...),
+		),
+	)
+
+	acc.(Updater[int64]).Update(2, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	// The peeked point does not share the instrument's storage,
+	// and the interval is reported in full.
+	require.Equal(t, int64(1), number.ToInt64(peeked[0].Points[0].Aggregation.(*sum.MonotonicInt64).Sum()))
+
+	test.RequireEqualMetrics(t, testCollect(t, vc),
+		test.Instrument(desc,
+			test.Point(middleTime, endTime, sum.NewMonotonicInt64(3), delta, set.ToSlice()...),
+		),
+	)
+}
+
+// TestPeekSyncDeltaCumulative ensures that Peek reports the running
+// total of a synchronous delta instrument for cumulative
+// temporality, and that totals without change are removed after the
+// inactive number of collections.
+func TestPeekSyncDeltaCumulative(t *testing.T) {
+	const inactive = 2
+
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{InactiveCollectionPeriods: inactive},
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	set := attribute.NewSet(attribute.String("a", "1"))
+
+	update := func(x int64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	seq := testSequence
+	tick := func() {
+		seq.Last = seq.Now
+		seq.Now = seq.Now.Add(time.Second)
+	}
+	peek := func() []data.Instrument {
+		var peeked []data.Instrument
+		inst.(data.Collector).Peek(seq, cumulative, &peeked)
+		return peeked
+	}
+
+	update(10)
+	testCollectSequence(t, vc, seq)
+	tick()
+	update(5)
+
+	test.RequireEqualMetrics(t, peek(),
+		test.Instrument(desc,
+			test.Point(startTime, seq.Now, sum.NewMonotonicInt64(15), cumulative, set.ToSlice()...),
+		),
+	)
+
+	// The peek leaves the delta interval intact.
+	test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq),
+		test.Instrument(desc,
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(5), delta, set.ToSlice()...),
+		),
+	)
+	tick()
+
+	// The total is kept for the inactive number of collections
+	// without change.
+	for i := 0; i < inactive; i++ {
+		testCollectSequence(t, vc, seq)
+		tick()
+		test.RequireEqualMetrics(t, peek(),
+			test.Instrument(desc,
+				test.Point(startTime, seq.Now, sum.NewMonotonicInt64(15), cumulative, set.ToSlice()...),
+			),
+		)
+	}
+	testCollectSequence(t, vc, seq)
+	tick()
+	test.RequireEqualMetrics(t, peek(), test.Instrument(desc))
+
+	// A series that returns starts with its interval.
+	update(3)
+	test.RequireEqualMetrics(t, peek(),
+		test.Instrument(desc,
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(3), cumulative, set.ToSlice()...),
+		),
+	)
+	testCollectSequence(t, vc, seq)
+	intervalStart := seq.Last
+	tick()
+	test.RequireEqualMetrics(t, peek(),
+		test.Instrument(desc,
+			test.Point(intervalStart, seq.Now, sum.NewMonotonicInt64(3), cumulative, set.ToSlice()...),
+		),
+	)
+}
+
+// TestLastUpdateTime ensures that the time of each series' latest
+// measurement is reported when configured.
+func TestLastUpdateTime(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentNameRegexp(regexp.MustCompile("^tracked")),
+			view.WithLastUpdateTime(),
+		),
+	)
+	vc := New(testLib, views)
+
+	syncInst, err := testCompile(vc, "tracked.sync", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+	asyncInst, err := testCompile(vc, "tracked.async", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+	plainInst, err := testCompile(vc, "plain", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	first := time.Unix(1000, 0)
+	second := first.Add(time.Minute)
+
+	acc := syncInst.NewAccumulator(attribute.NewSet())
+	acc.(Updater[int64]).Update(1, aggregator.ExemplarBits{Time: first})
+	acc.(Updater[int64]).Update(1, aggregator.ExemplarBits{Time: second})
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	asyncAcc := asyncInst.NewAccumulator(attribute.NewSet())
+	asyncAcc.(Updater[int64]).Update(1, aggregator.ExemplarBits{Time: first})
+	require.NoError(t, asyncAcc.SnapshotAndProcess(true))
+
+	plainAcc := plainInst.NewAccumulator(attribute.NewSet())
+	plainAcc.(Updater[int64]).Update(1, aggregator.ExemplarBits{Time: first})
+	require.NoError(t, plainAcc.SnapshotAndProcess(true))
+
+	lastUpdates := func() map[string]time.Time {
+		m := map[string]time.Time{}
+		for _, inst := range testCollect(t, vc) {
+			for _, pt := range inst.Points {
+				m[inst.Descriptor.Name] = pt.LastUpdate
+			}
+		}
+		return m
+	}
+
+	got := lastUpdates()
+	require.True(t, second.Equal(got["tracked.sync"]))
+	require.True(t, first.Equal(got["tracked.async"]))
+	require.True(t, got["plain"].IsZero())
+
+	// Without measurements, the cumulative series keeps its time.
+	got = lastUpdates()
+	require.True(t, second.Equal(got["tracked.sync"]))
+}
+
+// TestCollectFunc tests that CollectFunc emits the points of Collect
+// for each kind of collector.
+func TestCollectFunc(t *testing.T) {
+	kinds := []sdkinstrument.Kind{
+		sdkinstrument.SyncCounter,        // delta
+		sdkinstrument.SyncUpDownCounter,  // cumulative
+		sdkinstrument.AsyncCounter,       // delta
+		sdkinstrument.AsyncUpDownCounter, // cumulative
+	}
+	newCompiler := func() (*Compiler, []Instrument) {
+		vc := New(testLib, view.New(
+			"test",
+			safePerf,
+			view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+		))
+		var insts []Instrument
+		for _, ik := range kinds {
+			inst, err := testCompile(vc, ik.String(), ik, number.Int64Kind)
+			require.NoError(t, err)
+			insts = append(insts, inst)
+		}
+		return vc, insts
+	}
+	vcCollect, instsCollect := newCompiler()
+	vcFunc, instsFunc := newCompiler()
+
+	type values map[attribute.Set]int64
+
+	for round := int64(1); round <= 3; round++ {
+		for _, insts := range [][]Instrument{instsCollect, instsFunc} {
+			for _, inst := range insts {
+				for i := int64(0); i < 3; i++ {
+					acc := inst.NewAccumulator(attribute.NewSet(attribute.Int64("i", i)))
+					acc.(Updater[int64]).Update(round*(i+1), nobits)
+					require.NoError(t, acc.SnapshotAndProcess(true))
+				}
+			}
+		}
+
+		var expect []values
+		for _, inst := range testCollect(t, vcCollect) {
+			vals := values{}
+			for _, pt := range inst.Points {
+				vals[pt.Attributes] = number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+			}
+			expect = append(expect, vals)
+		}
+
+		var actual []values
+		for _, coll := range vcFunc.Collectors() {
+			vals := values{}
+			coll.CollectFunc(testSequence, func(desc sdkinstrument.Descriptor, pt data.Point) {
+				require.Equal(t, kinds[len(actual)], desc.Kind)
+				vals[pt.Attributes] = number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+			})
+			actual = append(actual, vals)
+		}
+
+		require.Equal(t, len(kinds), len(actual))
+		require.Equal(t, expect, actual)
+		for _, vals := range actual {
+			require.Equal(t, 3, len(vals))
+		}
+	}
+}
+
+// TestEitherTemporality collects one synchronous instrument with
+// both temporalities and checks that the deltas are consistent with
+// the cumulative values.
+func TestEitherTemporality(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(view.WithEitherTemporality()),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "either", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	coll := vc.Collectors()[0]
+
+	sums := func(insts []data.Instrument, tempo aggregation.Temporality, start, end time.Time) map[attribute.Set]int64 {
+		require.Equal(t, 1, len(insts))
+		vals := map[attribute.Set]int64{}
+		for _, pt := range insts[0].Points {
+			require.Equal(t, tempo, pt.Temporality)
+			require.Equal(t, start, pt.Start)
+			require.Equal(t, end, pt.End)
+			vals[pt.Attributes] = number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+		}
+		return vals
+	}
+
+	totals := map[attribute.Set]int64{}
+	deltas := map[attribute.Set]int64{}
+	lastDelta := testSequence.Start
+
+	for round := int64(1); round <= 4; round++ {
+		// Series 1 is updated in odd rounds only.
+		for i := int64(0); i <= round%2; i++ {
+			set := attribute.NewSet(attribute.Int64("i", i))
+			acc := inst.NewAccumulator(set)
+			acc.(Updater[int64]).Update(round*(i+1), nobits)
+			require.NoError(t, acc.SnapshotAndProcess(true))
+
+			totals[set] += round * (i + 1)
+			deltas[set] += round * (i + 1)
+		}
+
+		seq := testSequence
+		seq.Now = seq.Start.Add(time.Duration(round) * time.Second)
+
+		var output []data.Instrument
+		coll.CollectTemporality(seq, aggregation.CumulativeTemporality, &output)
+		require.Equal(t, totals, sums(output, aggregation.CumulativeTemporality, seq.Start, seq.Now))
+
+		// Deltas are collected in even rounds and span the
+		// interval since the last delta collection.
+		if round%2 == 0 {
+			output = nil
+			coll.CollectTemporality(seq, aggregation.DeltaTemporality, &output)
+			require.Equal(t, deltas, sums(output, aggregation.DeltaTemporality, lastDelta, seq.Now))
+
+			deltas = map[attribute.Set]int64{}
+			lastDelta = seq.Now
+		}
+	}
+
+	// Collect uses the configured (cumulative) temporality.
+	var output []data.Instrument
+	coll.Collect(testSequence, &output)
+	require.Equal(t, totals, sums(output, aggregation.CumulativeTemporality, testSequence.Start, testSequence.Now))
+	require.Equal(t, 2, coll.InMemorySize())
+}
+
+// TestEitherTemporalityInactive ensures that the cumulative totals
+// of an instrument with either temporality are removed after the
+// inactive number of collections without change.
+func TestEitherTemporalityInactive(t *testing.T) {
+	const inactive = 2
+
+	views := view.New(
+		"test",
+		sdkinstrument.Performance{InactiveCollectionPeriods: inactive},
+		view.WithClause(view.WithEitherTemporality()),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "either", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	desc := test.Descriptor("either", sdkinstrument.SyncCounter, number.Int64Kind)
+	set := attribute.NewSet(attribute.String("a", "1"))
+
+	update := func(x int64) {
+		acc := inst.NewAccumulator(set)
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	seq := testSequence
+	tick := func() {
+		seq.Last = seq.Now
+		seq.Now = seq.Now.Add(time.Second)
+	}
+
+	update(10)
+	test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq),
+		test.Instrument(desc,
+			test.Point(startTime, seq.Now, sum.NewMonotonicInt64(10), cumulative, set.ToSlice()...),
+		),
+	)
+	tick()
+
+	// The total is kept for the inactive number of collections
+	// without change.
+	for i := 0; i < inactive; i++ {
+		test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq),
+			test.Instrument(desc,
+				test.Point(startTime, seq.Now, sum.NewMonotonicInt64(10), cumulative, set.ToSlice()...),
+			),
+		)
+		tick()
+	}
+	test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq), test.Instrument(desc))
+	require.Equal(t, 0, vc.Collectors()[0].InMemorySize())
+	tick()
+
+	// A series that returns starts with its interval.
+	update(3)
+	test.RequireEqualMetrics(t, testCollectSequence(t, vc, seq),
+		test.Instrument(desc,
+			test.Point(seq.Last, seq.Now, sum.NewMonotonicInt64(3), cumulative, set.ToSlice()...),
+		),
+	)
+}
+
+// TestSuppressUnchanged ensures that unchanged asynchronous series are
+// omitted when configured and reported otherwise.
+func TestSuppressUnchanged(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentNameRegexp(regexp.MustCompile("^changed")),
+			view.WithSuppressUnchanged(),
+		),
+	)
+	vc := New(testLib, views)
+
+	var insts []Instrument
+	for _, name := range []string{"changed.gauge", "changed.updown", "plain.gauge"} {
+		ik := sdkinstrument.AsyncGauge
+		if name == "changed.updown" {
+			ik = sdkinstrument.AsyncUpDownCounter
+		}
+		inst, err := testCompile(vc, name, ik, number.Int64Kind)
+		require.NoError(t, err)
+		insts = append(insts, inst)
+	}
+
+	observe := func(values ...int64) map[string]int {
+		for _, inst := range insts {
+			for i, value := range values {
+				acc := inst.NewAccumulator(attribute.NewSet(attribute.Int("i", i)))
+				acc.(Updater[int64]).Update(value, nobits)
+				require.NoError(t, acc.SnapshotAndProcess(true))
+			}
+		}
+		points := map[string]int{}
+		for _, inst := range testCollect(t, vc) {
+			points[inst.Descriptor.Name] = len(inst.Points)
+		}
+		return points
+	}
+
+	require.Equal(t, map[string]int{
+		"changed.gauge":  2,
+		"changed.updown": 2,
+		"plain.gauge":    2,
+	}, observe(1, 2))
+
+	// Only the second series changes.
+	require.Equal(t, map[string]int{
+		"changed.gauge":  1,
+		"changed.updown": 1,
+		"plain.gauge":    2,
+	}, observe(1, 3))
+
+	// A series that is not observed is reported when it reappears.
+	observe(1)
+	require.Equal(t, map[string]int{
+		"changed.gauge":  1,
+		"changed.updown": 1,
+		"plain.gauge":    2,
+	}, observe(1, 3))
+}
+
+// TestInMemoryBytes ensures that the memory estimate scales with the
+// number of series and the histogram buckets in use, and includes the
+// prior values kept for delta temporality.
+func TestInMemoryBytes(t *testing.T) {
+	vc := New(testLib, view.New("test", safePerf, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)))
+
+	counter, err := testCompile(vc, "counter", sdkinstrument.SyncUpDownCounter, number.Int64Kind)
+	require.NoError(t, err)
+	histo, err := testCompile(vc, "histo", sdkinstrument.SyncHistogram, number.Float64Kind)
+	require.NoError(t, err)
+	async, err := testCompile(vc, "async", sdkinstrument.AsyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	colls := vc.Collectors()
+	require.Equal(t, 3, len(colls))
+	counterColl, histoColl, asyncColl := colls[0], colls[1], colls[2]
+
+	for _, coll := range colls {
+		require.Equal(t, 0, coll.InMemoryBytes())
+	}
+
+	update := func(inst Instrument, i int, value float64) {
+		acc := inst.NewAccumulator(attribute.NewSet(attribute.Int("i", i)))
+		if updater, ok := acc.(Updater[int64]); ok {
+			updater.Update(int64(value), nobits)
+		} else {
+			acc.(Updater[float64]).Update(value, nobits)
+		}
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+
+	update(counter, 0, 1)
+	one := counterColl.InMemoryBytes()
+	require.Less(t, 0, one)
+	update(counter, 1, 1)
+	update(counter, 2, 1)
+	require.Equal(t, 3*one, counterColl.InMemoryBytes())
+
+	update(histo, 0, 1)
+	narrow := histoColl.InMemoryBytes()
+	for _, v := range []float64{1e-3, 1e3, 1e6} {
+		update(histo, 0, v)
+	}
+	require.Less(t, narrow, histoColl.InMemoryBytes())
+
+	update(async, 0, 10)
+	_ = testCollect(t, vc)
+	require.Less(t, 0, asyncColl.InMemoryBytes())
+}
+
+// TestUpdateBatch ensures that a batch of measurements produces the
+// same aggregate as the equivalent singleton updates.
+func TestUpdateBatch(t *testing.T) {
+	const count = 1000
+
+	type spec struct {
+		name string
+		ik   sdkinstrument.Kind
+	}
+	specs := []spec{
+		{"counter", sdkinstrument.SyncCounter},
+		{"updown", sdkinstrument.SyncUpDownCounter},
+		{"gauge", sdkinstrument.SyncGauge},
+		{"histogram", sdkinstrument.SyncHistogram},
+		{"mmsc", sdkinstrument.SyncHistogram},
+		{"async", sdkinstrument.AsyncCounter},
+	}
+
+	for _, nk := range []number.Kind{number.Int64Kind, number.Float64Kind} {
+		t.Run(nk.String(), func(t *testing.T) {
+			newCompiler := func() (*Compiler, []Instrument) {
+				vc := New(testLib, view.New(
+					"test",
+					safePerf,
+					view.WithClause(
+						view.MatchInstrumentName("mmsc"),
+						view.WithAggregation(aggregation.MinMaxSumCountKind),
+					),
+				))
+				var insts []Instrument
+				for _, s := range specs {
+					inst, err := testCompile(vc, s.name, s.ik, nk)
+					require.NoError(t, err)
+					insts = append(insts, inst)
+				}
+				return vc, insts
+			}
+			vcBatch, batchInsts := newCompiler()
+			vcSingle, singleInsts := newCompiler()
+
+			for round := 1; round <= 2; round++ {
+				for i, inst := range batchInsts {
+					acc := inst.NewAccumulator(attribute.NewSet())
+					if nk == number.Int64Kind {
+						acc.(Updater[int64]).UpdateBatch(int64(round*3), count, nobits)
+					} else {
+						acc.(Updater[float64]).UpdateBatch(float64(round)*0.5, count, nobits)
+					}
+					require.NoError(t, acc.SnapshotAndProcess(true))
+
+					acc = singleInsts[i].NewAccumulator(attribute.NewSet())
+					for j := 0; j < count; j++ {
+						if nk == number.Int64Kind {
+							acc.(Updater[int64]).Update(int64(round*3), nobits)
+						} else {
+							acc.(Updater[float64]).Update(float64(round)*0.5, nobits)
+						}
+					}
+					require.NoError(t, acc.SnapshotAndProcess(true))
+				}
+
+				batch := testCollect(t, vcBatch)
+				single := testCollect(t, vcSingle)
+				require.Equal(t, len(specs), len(batch))
+				for i := range batch {
+					require.Equal(t, 1, len(batch[i].Points))
+					expect := single[i].Points[0]
+					expect.Start, expect.End = time.Time{}, time.Time{}
+					if g, ok := expect.Aggregation.(interface{ SetSequenceForTesting() }); ok {
+						g.SetSequenceForTesting()
+					}
+					test.RequireEqualPoints(t, batch[i].Points, expect)
+				}
+			}
+		})
+	}
+}
+
+// TestAttributeKeyNormalization tests that keys differing only in case
+// are aggregated into one series and filtered by their normalized
+// form.
+func TestAttributeKeyNormalization(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.WithKeys([]attribute.Key{"content-type"}),
+			view.WithAttributeNormalization(view.NormalizeCaseFold|view.NormalizeKeys),
+		),
+	)
+	vc := New(testLib, views)
+
+	inst, err := testCompile(vc, "headers", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	for _, attrs := range []attribute.Set{
+		attribute.NewSet(attribute.String("Content-Type", "text/html"), attribute.String("Other", "x")),
+		attribute.NewSet(attribute.String("content-type", "Text/HTML")),
+		attribute.NewSet(attribute.String("CONTENT-TYPE", "text/html")),
+	} {
+		acc := inst.NewAccumulator(attrs)
+		acc.(Updater[int64]).Update(1, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+
+	test.RequireEqualMetrics(
+		t,
+		testCollect(t, vc),
+		test.Instrument(
+			test.Descriptor("headers", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(3), cumulative,
+				attribute.String("content-type", "text/html"),
+			),
+		),
+	)
+}
+
+// TestGaugeCounts ensures that gauges count the observations of each
+// collection interval when configured.
+func TestGaugeCounts(t *testing.T) {
+	views := view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("counted"),
+			view.WithAggregatorConfig(aggregator.Config{GaugeCounts: true}),
+		),
+	)
+	vc := New(testLib, views)
+
+	counted, err := testCompile(vc, "counted", sdkinstrument.AsyncGauge, number.Float64Kind)
+	require.NoError(t, err)
+	plain, err := testCompile(vc, "plain", sdkinstrument.AsyncGauge, number.Float64Kind)
+	require.NoError(t, err)
+
+	set := attribute.NewSet()
+	for _, updates := range [][]int{{3}, {1, 4}, {2}} {
+		// Each accumulator is updated n times.
+		total := 0
+		for _, n := range updates {
+			for _, inst := range []Instrument{counted, plain} {
+				acc := inst.NewAccumulator(set)
+				for i := 0; i < n; i++ {
+					acc.(Updater[float64]).Update(float64(i), nobits)
+				}
+				require.NoError(t, acc.SnapshotAndProcess(true))
+			}
+			total += n
+		}
+
+		output := testCollect(t, vc)
+		require.Equal(t, 2, len(output))
+		require.Equal(t, 1, len(output[0].Points))
+		require.Equal(t, uint64(total), output[0].Points[0].Observations)
+		require.Equal(t, uint64(total), output[0].Points[0].Aggregation.(aggregation.GaugeCount).Count())
+		require.Equal(t, uint64(0), output[1].Points[0].Observations)
+	}
+}
+
+// TestAsyncSnapshotAfterCollect ensures that an asynchronous
+// accumulator created before a collection or Reset replaced the data
+// map does not lose its value when it snapshots afterward.  The
+// value is reported by the following collection.
+func TestAsyncSnapshotAfterCollect(t *testing.T) {
+	for _, tempo := range []aggregation.Temporality{aggregation.CumulativeTemporality, aggregation.DeltaTemporality} {
+		t.Run(tempo.String(), func(t *testing.T) {
+			vc := New(testLib, view.New(
+				"test",
+				safePerf,
+				view.WithDefaultAggregationTemporalitySelector(func(sdkinstrument.Kind) aggregation.Temporality {
+					return tempo
+				}),
+			))
+
+			inst, err := testCompile(vc, "counter", sdkinstrument.AsyncCounter, number.Int64Kind)
+			require.NoError(t, err)
+
+			set := attribute.NewSet()
+			begin := testSequence.Start
+			if tempo == aggregation.DeltaTemporality {
+				begin = testSequence.Last
+			}
+			start := func(x int64) Accumulator {
+				acc := inst.NewAccumulator(set)
+				acc.(Updater[int64]).Update(x, nobits)
+				return acc
+			}
+			expect := func(x ...int64) {
+				var points []data.Point
+				for _, v := range x {
+					points = append(points, test.Point(begin, testSequence.Now, sum.NewMonotonicInt64(v), tempo))
+				}
+				test.RequireEqualMetrics(t,
+					testCollect(t, vc),
+					test.Instrument(
+						test.Descriptor("counter", sdkinstrument.AsyncCounter, number.Int64Kind),
+						points...,
+					),
+				)
+			}
+
+			// The collection reports the series before
+			// its value, which is not lost.
+			acc := start(10)
+			expect(0)
+			require.NoError(t, acc.SnapshotAndProcess(true))
+			expect(10)
+
+			// Likewise across a Reset.
+			acc = start(15)
+			inst.Reset()
+			require.NoError(t, acc.SnapshotAndProcess(true))
+			expect(15)
+		})
+	}
+}
+
+// TestAsyncConcurrentCollect ensures that every observation of
+// asynchronous accumulators racing with collection is counted in
+// exactly one collection.
+func TestAsyncConcurrentCollect(t *testing.T) {
+	vc := New(testLib, view.New(
+		"test",
+		safePerf,
+		view.WithClause(
+			view.MatchInstrumentName("gauge"),
+			view.WithAggregatorConfig(aggregator.Config{GaugeCounts: true}),
+		),
+	))
+
+	inst, err := testCompile(vc, "gauge", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+
+	const (
+		workers = 8
+		rounds  = 200
+		updates = 10
+	)
+	attrs := attribute.NewSet(attribute.String("k", "v"))
+
+	var count uint64
+	collect := func() {
+		for _, out := range testCollect(t, vc) {
+			for _, pt := range out.Points {
+				count += pt.Aggregation.(aggregation.GaugeCount).Count()
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				collect()
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				acc := inst.NewAccumulator(attrs)
+				for u := 0; u < updates; u++ {
+					acc.(Updater[int64]).Update(int64(u), nobits)
+				}
+				require.NoError(t, acc.SnapshotAndProcess(true))
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-collected
+
+	// The final collection includes the remainder.
+	collect()
+
+	require.Equal(t, uint64(workers*rounds*updates), count)
+}
+
+// TestLeakCollectionPeriods ensures that series held by an
+// accumulator without updates for more than LeakCollectionPeriods
+// are reported as possible leaks, until the accumulator is released.
+func TestLeakCollectionPeriods(t *testing.T) {
+	var reports []int
+	perf := safePerf
+	perf.LeakCollectionPeriods = 2
+	perf.OnLeak = func(name string, series int) {
+		require.Equal(t, "counter", name)
+		reports = append(reports, series)
+	}
+	vc := New(testLib, view.New(
+		"test",
+		perf,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	))
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	held := inst.NewAccumulator(attribute.NewSet(attribute.String("k", "held")))
+	active := inst.NewAccumulator(attribute.NewSet(attribute.String("k", "active")))
+
+	collect := func() {
+		active.(Updater[int64]).Update(1, nobits)
+		require.NoError(t, active.SnapshotAndProcess(false))
+		require.NoError(t, held.SnapshotAndProcess(false))
+		testCollect(t, vc)
+	}
+
+	held.(Updater[int64]).Update(1, nobits)
+	collect()
+
+	// The held series is unchanged for two periods, which are
+	// allowed; the active series is never counted.
+	collect()
+	collect()
+	require.Empty(t, reports)
+
+	collect()
+	require.Equal(t, []int{1}, reports)
+
+	// The count is reported only when it changes.
+	collect()
+	require.Equal(t, []int{1}, reports)
+
+	// An update restarts the count.
+	held.(Updater[int64]).Update(1, nobits)
+	collect()
+	require.Equal(t, []int{1, 0}, reports)
+
+	// Releasing the accumulator removes the series.
+	collect()
+	collect()
+	collect()
+	require.Equal(t, []int{1, 0, 1}, reports)
+	require.NoError(t, held.SnapshotAndProcess(true))
+	testCollect(t, vc)
+	require.Equal(t, []int{1, 0, 1, 0}, reports)
+	require.Equal(t, 1, inst.(data.Collector).InMemorySize())
+}
+
+// TestSeriesBudget ensures that the least recently used series are
+// evicted once the budget is reached, reporting their final values,
+// and that a series created after an eviction starts when created.
+func TestSeriesBudget(t *testing.T) {
+	vc := New(testLib, view.New(
+		"test",
+		safePerf,
+		view.WithClause(view.WithSeriesBudget(2)),
+	))
+
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	attrs := func(v string) attribute.Set {
+		return attribute.NewSet(attribute.String("k", v))
+	}
+	add := func(v string, x int64, release bool) Accumulator {
+		acc := inst.NewAccumulator(attrs(v))
+		acc.(Updater[int64]).Update(x, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(release))
+		return acc
+	}
+	type series struct {
+		value int64
+		start time.Time
+	}
+	collect := func() map[string][]series {
+		out := map[string][]series{}
+		for _, pt := range testCollect(t, vc)[0].Points {
+			v, _ := pt.Attributes.Value("k")
+			out[v.AsString()] = append(out[v.AsString()], series{
+				value: number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum()),
+				start: pt.Start,
+			})
+		}
+		return out
+	}
+
+	add("a", 1, true)
+	add("b", 2, true)
+	require.Equal(t, map[string][]series{
+		"a": {{1, startTime}},
+		"b": {{2, startTime}},
+	}, collect())
+
+	// The series "a" is in use, so "b" is the least recently
+	// used.
+	held := add("a", 10, false)
+	require.Equal(t, map[string][]series{
+		"a": {{11, startTime}},
+		"b": {{2, startTime}},
+	}, collect())
+
+	before := time.Now()
+	add("c", 3, true)
+	out := collect()
+	require.Equal(t, []series{{11, startTime}}, out["a"])
+	require.Equal(t, []series{{2, startTime}}, out["b"])
+	require.Equal(t, int64(3), out["c"][0].value)
+	require.False(t, out["c"][0].start.Before(before))
+	require.Equal(t, 2, inst.(data.Collector).InMemorySize())
+
+	// The series "b" restarts, evicting "c", which reports its
+	// final value with its own start.
+	cstart := out["c"][0].start
+	before = time.Now()
+	add("b", 5, true)
+	out = collect()
+	require.Equal(t, []series{{3, cstart}}, out["c"])
+	require.Equal(t, int64(5), out["b"][0].value)
+	require.False(t, out["b"][0].start.Before(before))
+
+	// The series "d" evicts "b", then with every series in use,
+	// "e" exceeds the budget.
+	bstart := out["b"][0].start
+	add("d", 1, false)
+	add("e", 1, false)
+	require.NoError(t, held.SnapshotAndProcess(true))
+	out = collect()
+	require.Equal(t, []series{{5, bstart}}, out["b"])
+	require.Equal(t, []series{{11, startTime}}, out["a"])
+	require.Contains(t, out, "d")
+	require.Contains(t, out, "e")
+	require.Equal(t, 3, inst.(data.Collector).InMemorySize())
+}
+
+// BenchmarkSeriesBudget measures the collection and eviction overhead
+// of a churning set of attribute sets, with a budget smaller than the
+// series created in each interval.
+func BenchmarkSeriesBudget(b *testing.B) {
+	const series = 1000
+	for _, budget := range []uint32{0, series / 2} {
+		b.Run(fmt.Sprint("budget=", budget), func(b *testing.B) {
+			vc := New(testLib, view.New(
+				"test",
+				safePerf,
+				view.WithClause(view.WithSeriesBudget(budget)),
+			))
+			inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+			require.NoError(b, err)
+
+			sets := make([]attribute.Set, 4*series)
+			for i := range sets {
+				sets[i] = attribute.NewSet(attribute.Int("k", i))
+			}
+			var output []data.Instrument
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < series; j++ {
+					acc := inst.NewAccumulator(sets[(i*series+j)%len(sets)])
+					acc.(Updater[int64]).Update(1, nobits)
+					_ = acc.SnapshotAndProcess(true)
+				}
+				output = output[:0]
+				inst.(data.Collector).Collect(testSequence, &output)
+			}
+		})
+	}
+}
+
+// TestInstrumentDirect tests collection of instruments built by
+// testInstrument, without a Compiler.
+func TestInstrumentDirect(t *testing.T) {
+	counter := testInstrument(t,
+		test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+		aggregation.MonotonicSumKind, cumulative, aggregator.Config{},
+	)
+	require.IsType(t, &statefulSyncInstrument[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods, alwaysOffSampleFilter]{}, counter)
+
+	lastValue := testInstrument(t,
+		test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Float64Kind),
+		aggregation.GaugeKind, cumulative, aggregator.Config{},
+	)
+	require.IsType(t, &lowmemoryAsyncInstrument[float64, gauge.Float64, gauge.Float64Methods]{}, lastValue)
+
+	set := attribute.NewSet(attribute.String("k", "v"))
+	for i := 1; i <= 2; i++ {
+		acc := counter.NewAccumulator(set)
+		acc.(Updater[int64]).Update(int64(i), nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+
+		obs := lastValue.NewAccumulator(set)
+		obs.(Updater[float64]).Update(float64(i), nobits)
+		require.NoError(t, obs.SnapshotAndProcess(true))
+	}
+
+	var output []data.Instrument
+	counter.Collect(testSequence, &output)
+	lastValue.Collect(testSequence, &output)
+	test.RequireEqualMetrics(t, output,
+		test.Instrument(
+			test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(startTime, endTime, sum.NewMonotonicInt64(3), cumulative, attribute.String("k", "v")),
+		),
+		test.Instrument(
+			test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Float64Kind),
+			test.Point(startTime, endTime, gauge.NewFloat64(2), cumulative, attribute.String("k", "v")),
+		),
+	)
+}
+
+// TestAttributeInterner tests that instruments sharing an interner
+// key their series by one instance of equal attribute sets,
+// including a set computed by a keys filter.
+func TestAttributeInterner(t *testing.T) {
+	// backing is the address of a set's attributes, the data
+	// word of the interface in its attribute.Distinct.
+	backing := func(set attribute.Set) unsafe.Pointer {
+		d := set.Equivalent()
+		return (*[2]unsafe.Pointer)(unsafe.Pointer(&d))[1]
+	}
+
+	for _, interned := range []bool{false, true} {
+		perf := safePerf
+		if interned {
+			perf.AttributeInterner = sdkinstrument.NewAttributeInterner(0)
+		}
+		vc := New(testLib, view.New(
+			"test",
+			perf,
+			view.WithClause(
+				view.MatchInstrumentName("filtered"),
+				view.WithKeys([]attribute.Key{"k"}),
+			),
+		))
+
+		counter, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NoError(t, err)
+		filtered, err := testCompile(vc, "filtered", sdkinstrument.SyncUpDownCounter, number.Int64Kind)
+		require.NoError(t, err)
+
+		acc1 := counter.NewAccumulator(attribute.NewSet(attribute.String("k", "v")))
+		acc2 := filtered.NewAccumulator(attribute.NewSet(attribute.String("k", "v"), attribute.Int("x", 1)))
+		acc1.(Updater[int64]).Update(1, nobits)
+		acc2.(Updater[int64]).Update(1, nobits)
+		require.NoError(t, acc1.SnapshotAndProcess(false))
+		require.NoError(t, acc2.SnapshotAndProcess(false))
+
+		out := testCollect(t, vc)
+		require.Equal(t, 2, len(out))
+
+		set1, set2 := out[0].Points[0].Attributes, out[1].Points[0].Attributes
+		require.Equal(t, set1, set2)
+		require.Equal(t, interned, backing(set1) == backing(set2))
+	}
+}
+
+// expiringContext is done after the number of Err calls in checks.
+type expiringContext struct {
+	context.Context
+	checks int
+}
+
+func (c *expiringContext) Err() error {
+	if c.checks == 0 {
+		return context.DeadlineExceeded
+	}
+	c.checks--
+	return nil
+}
+
+// TestCollectContext tests that synchronous collections stop between
+// groups of series once the context is done, keeping the series not
+// reached with their start time, and that asynchronous collections
+// complete.
+func TestCollectContext(t *testing.T) {
+	const series = 3 * deadlineSeries
+
+	for _, test := range []struct {
+		tempo  aggregation.Temporality
+		either bool
+	}{
+		{cumulative, false},
+		{delta, false},
+		{delta, true},
+	} {
+		tempo := test.tempo
+		selector := view.StandardTemporality
+		if tempo == delta {
+			selector = view.DeltaPreferredTemporality
+		}
+		opts := []view.Option{view.WithDefaultAggregationTemporalitySelector(selector)}
+		if test.either {
+			opts = append(opts, view.WithClause(view.WithEitherTemporality()))
+		}
+		vc := New(testLib, view.New("test", safePerf, opts...))
+
+		inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+		require.NoError(t, err)
+
+		for i := 0; i < series; i++ {
+			acc := inst.NewAccumulator(attribute.NewSet(attribute.Int("i", i)))
+			acc.(Updater[int64]).Update(1, nobits)
+			require.NoError(t, acc.SnapshotAndProcess(false))
+		}
+		coll := vc.Collectors()[0]
+
+		// A context that is already done gathers nothing.
+		var output []data.Instrument
+		require.ErrorIs(t, coll.CollectContext(&expiringContext{Context: context.Background()}, testSequence, &output), context.DeadlineExceeded)
+		require.Equal(t, 0, len(output[0].Points))
+
+		// The deadline passes after the first group.
+		output = nil
+		require.ErrorIs(t, coll.CollectContext(&expiringContext{Context: context.Background(), checks: 1}, testSequence, &output), context.DeadlineExceeded)
+		require.Equal(t, deadlineSeries, len(output[0].Points))
+
+		seen := map[attribute.Set]bool{}
+		for _, pt := range output[0].Points {
+			seen[pt.Attributes] = true
+		}
+
+		// Every series remains for the next collection, where
+		// delta temporality reports only those not reached,
+		// starting from the interval of the stopped collection.
+		next := data.Sequence{
+			Start: testSequence.Start,
+			Last:  testSequence.Now,
+			Now:   testSequence.Now.Add(time.Minute),
+		}
+		output = nil
+		require.NoError(t, coll.CollectContext(context.Background(), next, &output))
+		if tempo == cumulative {
+			require.Equal(t, series, len(output[0].Points))
+			continue
+		}
+		// The first delta interval of either temporality
+		// starts with the sequence.
+		stoppedStart := testSequence.Last
+		if test.either {
+			stoppedStart = testSequence.Start
+		}
+		require.Equal(t, series-deadlineSeries, len(output[0].Points))
+		for _, pt := range output[0].Points {
+			require.False(t, seen[pt.Attributes])
+			require.Equal(t, int64(1), number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum()))
+			require.Equal(t, stoppedStart, pt.Start)
+		}
+
+		// The carried starts are used once.
+		acc := inst.NewAccumulator(attribute.NewSet(attribute.Int("i", 0)))
+		acc.(Updater[int64]).Update(1, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(false))
+
+		final := data.Sequence{
+			Start: next.Start,
+			Last:  next.Now,
+			Now:   next.Now.Add(time.Minute),
+		}
+		output = nil
+		require.NoError(t, coll.CollectContext(context.Background(), final, &output))
+		require.Equal(t, 1, len(output[0].Points))
+		require.Equal(t, next.Now, output[0].Points[0].Start)
+	}
+
+	// A stopped cumulative collection still ranks every series
+	// for eviction.
+	vc := New(testLib, view.New("test", safePerf, view.WithClause(view.WithSeriesBudget(2))))
+	inst, err := testCompile(vc, "counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	require.NoError(t, err)
+
+	add := func(v string) {
+		acc := inst.NewAccumulator(attribute.NewSet(attribute.String("k", v)))
+		acc.(Updater[int64]).Update(1, nobits)
+		require.NoError(t, acc.SnapshotAndProcess(true))
+	}
+	add("a")
+	add("b")
+
+	var output []data.Instrument
+	require.ErrorIs(t, vc.Collectors()[0].CollectContext(&expiringContext{Context: context.Background()}, testSequence, &output), context.DeadlineExceeded)
+
+	add("c")
+	require.Equal(t, 3, len(testCollect(t, vc)[0].Points))
+	require.Equal(t, 2, len(testCollect(t, vc)[0].Points))
+
+	vc = New(testLib, view.New("test", safePerf))
+	inst, err = testCompile(vc, "gauge", sdkinstrument.AsyncGauge, number.Int64Kind)
+	require.NoError(t, err)
+
+	acc := inst.NewAccumulator(attribute.NewSet())
+	acc.(Updater[int64]).Update(1, nobits)
+	require.NoError(t, acc.SnapshotAndProcess(false))
+
+	output = nil
+	require.NoError(t, vc.Collectors()[0].CollectContext(&expiringContext{Context: context.Background()}, testSequence, &output))
+	require.Equal(t, 1, len(output[0].Points))
+}
@@ -0,0 +1,353 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// ErrPointLimitExceeded is reported when a collection exceeds the
+// limit configured by view.WithMaxPointsPerCollection.
+var ErrPointLimitExceeded = fmt.Errorf("collection point limit exceeded")
+
+// pointLimiter enforces the point limit of one reader.  See
+// view.WithMaxPointsPerCollection for the policy.
+type pointLimiter struct {
+	limit int
+
+	// lock protects the state below.
+	lock sync.Mutex
+
+	// deferred counts the consecutive collections that deferred
+	// each cumulative series, as of the last collection.
+	deferred map[seriesKey]int
+
+	// carried counts the consecutive collections that carried
+	// each delta instrument, as of the last collection.
+	carried map[instKey]int
+
+	// reported is the value of each cumulative series when it
+	// was last reported.
+	reported map[seriesKey]pointValue
+}
+
+// instKey identifies an instrument in the output.
+type instKey struct {
+	scope instrumentation.Scope
+	desc  sdkinstrument.Descriptor
+}
+
+// seriesKey identifies a series in the output.
+type seriesKey struct {
+	instKey
+	attrs attribute.Distinct
+}
+
+// pointValue summarizes a point's aggregation by its count and its
+// sum or gauge value, which change when it is updated.
+type pointValue struct {
+	count uint64
+	value number.Number
+}
+
+// valueOf returns the pointValue of an aggregation.
+func valueOf(agg aggregation.Aggregation) pointValue {
+	if unwr, ok := agg.(exemplar.Unwrapper); ok {
+		agg = unwr.Unwrap()
+	}
+	var pv pointValue
+	if counted, ok := agg.(interface{ Count() uint64 }); ok {
+		pv.count = counted.Count()
+	}
+	switch agg := agg.(type) {
+	case aggregation.HasASum:
+		pv.value = agg.Sum()
+	case aggregation.Gauge:
+		pv.value = agg.Gauge()
+	}
+	return pv
+}
+
+// limitedInst is a cumulative instrument in the output.
+type limitedInst struct {
+	inst *data.Instrument
+	keys []seriesKey
+	keep []bool
+}
+
+// candidate is a cumulative point, whether its value changed since
+// it was last reported, and the number of consecutive collections
+// that deferred it.
+type candidate struct {
+	inst    int
+	point   int
+	changed bool
+	age     int
+}
+
+// deltaInst is a delta instrument in the output and its collector.
+type deltaInst struct {
+	key     instKey
+	inst    *data.Instrument
+	carrier viewstate.Carrier
+	folder  viewstate.Folder
+	age     int
+}
+
+// apply limits the output, in which the instruments of each scope
+// were output by the collectors of the corresponding meter.
+func (pl *pointLimiter) apply(output *data.Metrics, collectors [][]data.Collector) {
+	total := 0
+	forEachInst(output, func(_ instrumentation.Scope, inst *data.Instrument) {
+		total += len(inst.Points)
+	})
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	if total <= pl.limit {
+		pl.deferred, pl.carried = nil, nil
+		pl.reported = map[seriesKey]pointValue{}
+		forEachInst(output, func(scope instrumentation.Scope, inst *data.Instrument) {
+			if isDelta(inst) {
+				return
+			}
+			for _, pt := range inst.Points {
+				pl.reported[pointKey(scope, inst, &pt)] = valueOf(pt.Aggregation)
+			}
+		})
+		return
+	}
+	byDesc := collectorsByDesc(output, collectors)
+
+	// Delta instruments come first: those that cannot be
+	// carried, then those carried by the most consecutive
+	// collections, then the others in order.
+	var deltas []deltaInst
+	forEachInst(output, func(scope instrumentation.Scope, inst *data.Instrument) {
+		if !isDelta(inst) {
+			return
+		}
+		key := instKey{scope: scope, desc: inst.Descriptor}
+		coll := byDesc[key]
+		carrier, _ := coll.(viewstate.Carrier)
+		folder, _ := coll.(viewstate.Folder)
+		deltas = append(deltas, deltaInst{
+			key:     key,
+			inst:    inst,
+			carrier: carrier,
+			folder:  folder,
+			age:     pl.carried[key],
+		})
+	})
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if (deltas[i].carrier == nil) != (deltas[j].carrier == nil) {
+			return deltas[i].carrier == nil
+		}
+		return deltas[i].age > deltas[j].age
+	})
+
+	// Each delta instrument reported keeps at least one point,
+	// so those beyond the limit are carried into the next
+	// collection.
+	kept := len(deltas)
+	if kept > pl.limit {
+		kept = pl.limit
+		for kept < len(deltas) && deltas[kept].carrier == nil {
+			kept++
+		}
+	}
+	carried := map[instKey]int{}
+	carriedPoints := 0
+	for _, d := range deltas[kept:] {
+		d.carrier.Carry(d.inst)
+		carriedPoints += len(d.inst.Points)
+		carried[d.key] = d.age + 1
+		d.inst.Points = d.inst.Points[:0]
+	}
+	pl.carried = carried
+
+	var deltaCounts []int
+	deltaTotal := 0
+	for _, d := range deltas[:kept] {
+		deltaCounts = append(deltaCounts, len(d.inst.Points))
+		deltaTotal += len(d.inst.Points)
+	}
+	folded := 0
+	if deltaTotal > pl.limit {
+		share := equalShare(deltaCounts, pl.limit)
+		deltaTotal = 0
+		for _, d := range deltas[:kept] {
+			if d.folder != nil && len(d.inst.Points) > share {
+				before := len(d.inst.Points)
+				d.folder.Fold(d.inst, share)
+				folded += before - len(d.inst.Points)
+			}
+			deltaTotal += len(d.inst.Points)
+		}
+	}
+	budget := pl.limit - deltaTotal
+
+	// Cumulative points come next: those changed since they were
+	// last reported, then the unchanged, each deferred the
+	// longest first, then in order.
+	var cumulative []limitedInst
+	var candidates []candidate
+	forEachInst(output, func(scope instrumentation.Scope, inst *data.Instrument) {
+		if len(inst.Points) == 0 || isDelta(inst) {
+			return
+		}
+		li := limitedInst{
+			inst: inst,
+			keys: make([]seriesKey, len(inst.Points)),
+			keep: make([]bool, len(inst.Points)),
+		}
+		for pi := range inst.Points {
+			key := pointKey(scope, inst, &inst.Points[pi])
+			last, has := pl.reported[key]
+			li.keys[pi] = key
+			candidates = append(candidates, candidate{
+				inst:    len(cumulative),
+				point:   pi,
+				changed: !has || last != valueOf(inst.Points[pi].Aggregation),
+				age:     pl.deferred[key],
+			})
+		}
+		cumulative = append(cumulative, li)
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].changed != candidates[j].changed {
+			return candidates[i].changed
+		}
+		return candidates[i].age > candidates[j].age
+	})
+	for _, c := range candidates[:min(max(budget, 0), len(candidates))] {
+		cumulative[c.inst].keep[c.point] = true
+	}
+
+	deferred := map[seriesKey]int{}
+	reported := map[seriesKey]pointValue{}
+	for _, li := range cumulative {
+		points := li.inst.Points[:0]
+		for pi, pt := range li.inst.Points {
+			key := li.keys[pi]
+			if li.keep[pi] {
+				reported[key] = valueOf(pt.Aggregation)
+				points = append(points, pt)
+				continue
+			}
+			if last, has := pl.reported[key]; has {
+				reported[key] = last
+			}
+			deferred[key] = pl.deferred[key] + 1
+		}
+		// Deferred points are output again by a later
+		// collection, not from this slice; drop the tail
+		// so kept points are not referenced from two slots.
+		for i := len(points); i < len(li.inst.Points); i++ {
+			li.inst.Points[i] = data.Point{}
+		}
+		li.inst.Points = points
+	}
+	pl.deferred = deferred
+	pl.reported = reported
+
+	doevery.TimePeriod(30*time.Second, func() {
+		otel.Handle(fmt.Errorf("%w: %d points deferred, %d points carried, %d points folded", ErrPointLimitExceeded, len(deferred), carriedPoints, folded))
+	})
+}
+
+// pointKey returns the seriesKey of a point in the output.
+func pointKey(scope instrumentation.Scope, inst *data.Instrument, pt *data.Point) seriesKey {
+	return seriesKey{
+		instKey: instKey{scope: scope, desc: inst.Descriptor},
+		attrs:   pt.Attributes.Equivalent(),
+	}
+}
+
+// collectorsByDesc returns the collector of each instrument in the
+// output by its descriptor, since a meter's collectors need not
+// correspond to its instruments in order.
+func collectorsByDesc(output *data.Metrics, collectors [][]data.Collector) map[instKey]data.Collector {
+	byDesc := map[instKey]data.Collector{}
+	for si := range output.Scopes {
+		if si >= len(collectors) {
+			break
+		}
+		for _, coll := range collectors[si] {
+			described, ok := coll.(interface {
+				Descriptor() sdkinstrument.Descriptor
+			})
+			if !ok {
+				continue
+			}
+			key := instKey{scope: output.Scopes[si].Library, desc: described.Descriptor()}
+			if _, has := byDesc[key]; !has {
+				byDesc[key] = coll
+			}
+		}
+	}
+	return byDesc
+}
+
+// forEachInst calls f for every instrument in the output.
+func forEachInst(output *data.Metrics, f func(scope instrumentation.Scope, inst *data.Instrument)) {
+	for si := range output.Scopes {
+		insts := output.Scopes[si].Instruments
+		for ii := range insts {
+			f(output.Scopes[si].Library, &insts[ii])
+		}
+	}
+}
+
+// isDelta returns true for an instrument with delta points.
+func isDelta(inst *data.Instrument) bool {
+	return len(inst.Points) != 0 && inst.Points[0].Temporality == aggregation.DeltaTemporality
+}
+
+// equalShare returns the largest share, at least one, such that the
+// counts limited to the share total no more than the limit.
+func equalShare(counts []int, limit int) int {
+	largest := 0
+	for _, c := range counts {
+		largest = max(largest, c)
+	}
+	total := func(share int) int {
+		sum := 0
+		for _, c := range counts {
+			sum += min(c, share)
+		}
+		return sum
+	}
+	// The first share that exceeds the limit, less one.
+	share := sort.Search(largest, func(share int) bool {
+		return total(share+1) > limit
+	})
+	return max(share, 1)
+}
@@ -18,11 +18,17 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
 )
 
 var ErrMultipleReaderRegistration = fmt.Errorf("reader has multiple registrations")
 
+var ErrTemporalityUnsupported = fmt.Errorf("producer does not support a temporality override")
+
 // ManualReader is a a simple Reader that allows an application to
 // read metrics on demand.  It simply stores the Producer interface
 // provided through registration.  Flush and Shutdown are no-ops.
@@ -56,6 +62,61 @@ func (mr *ManualReader) Register(p Producer) {
 	mr.Producer = p
 }
 
+// ProduceWithTemporality collects metrics on demand with every point
+// reported with the temporality given, without affecting subsequent
+// calls to Produce.  See TemporalityProducer.
+func (mr *ManualReader) ProduceWithTemporality(in *data.Metrics, tempo aggregation.Temporality) data.Metrics {
+	tp, ok := mr.Producer.(TemporalityProducer)
+	if !ok {
+		otel.Handle(fmt.Errorf("%v: %w", mr.Name, ErrTemporalityUnsupported))
+		return data.Metrics{}
+	}
+	return tp.ProduceWithTemporality(in, tempo)
+}
+
+// ProduceTemporality collects metrics on demand with instruments
+// that keep either temporality reporting the one given.  See
+// TemporalityProducer.
+func (mr *ManualReader) ProduceTemporality(in *data.Metrics, tempo aggregation.Temporality) data.Metrics {
+	tp, ok := mr.Producer.(TemporalityProducer)
+	if !ok {
+		otel.Handle(fmt.Errorf("%v: %w", mr.Name, ErrTemporalityUnsupported))
+		return data.Metrics{}
+	}
+	return tp.ProduceTemporality(in, tempo)
+}
+
+// ProduceContext collects metrics on demand, stopping the collection
+// of synchronous instruments once ctx is done.  See ContextProducer.
+// A Producer that does not support a context collects every
+// instrument.
+func (mr *ManualReader) ProduceContext(ctx context.Context, in *data.Metrics) (data.Metrics, error) {
+	cp, ok := mr.Producer.(ContextProducer)
+	if !ok {
+		return mr.Producer.Produce(in), nil
+	}
+	return cp.ProduceContext(ctx, in)
+}
+
+// ProduceFunc collects metrics on demand, passing each point to emit.
+// See FuncProducer.  A Producer that does not support it produces the
+// metrics, which are then passed to emit.
+func (mr *ManualReader) ProduceFunc(ctx context.Context, emit func(scope instrumentation.Scope, desc sdkinstrument.Descriptor, point data.Point)) error {
+	fp, ok := mr.Producer.(FuncProducer)
+	if ok {
+		return fp.ProduceFunc(ctx, emit)
+	}
+	output, err := mr.ProduceContext(ctx, nil)
+	for _, scope := range output.Scopes {
+		for _, inst := range scope.Instruments {
+			for _, pt := range inst.Points {
+				emit(scope.Library, inst.Descriptor, pt)
+			}
+		}
+	}
+	return err
+}
+
 // ForceFlush is a no-op, always returns nil.
 func (mr *ManualReader) ForceFlush(context.Context) error {
 	return nil
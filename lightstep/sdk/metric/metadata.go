@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+
+import (
+	"context"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ContextWithSeriesMetadata returns a context carrying
+// non-identifying attributes, for example a human-readable label, of
+// the series of synchronous measurements made with the context.  The
+// metadata is attached when a measurement creates the series, or
+// brings it back into memory, and is reported in data.Point Metadata
+// without changing the series identity.  When measurements of one
+// series carry different metadata, the keys are combined and the
+// first value of each key is kept.  The OTLP exporters output the
+// metadata with the point attributes.
+func ContextWithSeriesMetadata(ctx context.Context, kvs ...attribute.KeyValue) context.Context {
+	m := aggregator.MeasurementFromContext(ctx)
+	m.Metadata = attribute.NewSet(kvs...)
+	return aggregator.ContextWithMeasurement(ctx, m)
+}
+
+// SeriesMetadataFromContext returns the metadata set by
+// ContextWithSeriesMetadata, which is empty when there is none.
+func SeriesMetadataFromContext(ctx context.Context) attribute.Set {
+	md := aggregator.MeasurementFromContext(ctx).Metadata
+	if md.Len() == 0 {
+		return *attribute.EmptySet()
+	}
+	return md
+}
@@ -185,10 +185,42 @@ func configureInstrument[T any](
 
 // synchronousInstrument configures a synchronous instrument.
 func (m *meter) synchronousInstrument(name string, cfg instConfig, nk number.Kind, ik sdkinstrument.Kind) (*syncstate.Observer, error) {
-	return configureInstrument(m, name, cfg, nk, ik, &m.syncInsts, syncstate.New)
+	pcfg := m.provider.cfg
+	if pcfg.flags == nil && pcfg.rateLimits == nil {
+		return configureInstrument(m, name, cfg, nk, ik, &m.syncInsts, syncstate.New)
+	}
+	ctor := func(desc sdkinstrument.Descriptor, perf sdkinstrument.Performance, opaque interface{}, compiled pipeline.Register[viewstate.Instrument]) *syncstate.Observer {
+		inst := syncstate.New(desc, perf, opaque, compiled)
+		if inst == nil {
+			return nil
+		}
+		if pcfg.flags != nil {
+			inst.SetFlag(pcfg.flags(desc))
+		}
+		if pcfg.rateLimits != nil {
+			if rl := pcfg.rateLimits(desc); rl.Rate > 0 {
+				inst.SetRateLimiter(syncstate.NewRateLimiter(rl.Rate, rl.Burst, pcfg.clock))
+			}
+		}
+		return inst
+	}
+	return configureInstrument(m, name, cfg, nk, ik, &m.syncInsts, ctor)
 }
 
 // synchronousInstrument configures an asynchronous instrument.
 func (m *meter) asynchronousInstrument(name string, cfg instConfig, nk number.Kind, ik sdkinstrument.Kind) (*asyncstate.Observer, error) {
 	return configureInstrument(m, name, cfg, nk, ik, &m.asyncInsts, asyncstate.New)
 }
+
+// forEachSync calls f for each synchronous instrument.
+func (m *meter) forEachSync(f func(*syncstate.Observer)) {
+	m.lock.Lock()
+	syncInsts := m.syncInsts
+	m.lock.Unlock()
+
+	for _, inst := range syncInsts {
+		if inst != nil {
+			f(inst)
+		}
+	}
+}
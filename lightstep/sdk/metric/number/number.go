@@ -14,7 +14,12 @@
 
 package number
 
-import "math"
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strings"
+)
 
 //go:generate stringer -type=Kind
 
@@ -29,6 +34,19 @@ const (
 	Float64Kind
 )
 
+// ParseKind returns the Kind named by str, ignoring case, which is
+// the String() of the Kind with or without its "Kind" suffix, e.g.,
+// "int64" or "Float64Kind".  An unknown name is an error.
+func ParseKind(str string) (Kind, error) {
+	for k := Kind(0); k < Kind(len(_Kind_index)-1); k++ {
+		name := k.String()
+		if strings.EqualFold(str, name) || strings.EqualFold(str, strings.TrimSuffix(name, "Kind")) {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown number kind: %q", str)
+}
+
 // Number is a 64bit numeric value, one of the Any interface types.
 type Number uint64
 
@@ -40,7 +58,7 @@ type Any interface {
 // CoerceToFloat64 converts Number to float64 according to Kind.
 func (n Number) CoerceToFloat64(k Kind) float64 {
 	if k == Int64Kind {
-		return float64(n)
+		return float64(int64(n))
 	}
 	return math.Float64frombits(uint64(n))
 }
@@ -64,3 +82,14 @@ func FromFloat64(n float64) Number {
 func FromInt64(n int64) Number {
 	return Number(n)
 }
+
+// Compare returns -1, 0, or +1 as a is less than, equal to, or
+// greater than b, both of Kind k.  Int64 values compare as signed
+// integers.  Float64 values follow cmp.Compare: a NaN is less than
+// every other value and equal to any NaN, and -0 equals 0.
+func Compare(a, b Number, k Kind) int {
+	if k == Int64Kind {
+		return cmp.Compare(ToInt64(a), ToInt64(b))
+	}
+	return cmp.Compare(ToFloat64(a), ToFloat64(b))
+}
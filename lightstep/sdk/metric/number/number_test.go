@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package number
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKind(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		kind  Kind
+		ok    bool
+	}{
+		{"int64", Int64Kind, true},
+		{"Int64Kind", Int64Kind, true},
+		{"FLOAT64", Float64Kind, true},
+		{"float64kind", Float64Kind, true},
+		{"uint64", 0, false},
+		{"Kind", 0, false},
+		{"", 0, false},
+	} {
+		kind, err := ParseKind(test.input)
+		require.Equal(t, test.kind, kind)
+		require.Equal(t, test.ok, err == nil, "%q: %v", test.input, err)
+	}
+}
+
+func TestParseKindRoundTrip(t *testing.T) {
+	for k := Kind(0); k < Kind(len(_Kind_index)-1); k++ {
+		for _, name := range []string{k.String(), strings.TrimSuffix(k.String(), "Kind")} {
+			kind, err := ParseKind(name)
+			require.NoError(t, err)
+			require.Equal(t, k, kind)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(+1)
+
+	for _, test := range []struct {
+		a, b   Number
+		kind   Kind
+		expect int
+	}{
+		{FromInt64(1), FromInt64(2), Int64Kind, -1},
+		{FromInt64(2), FromInt64(2), Int64Kind, 0},
+		{FromInt64(-1), FromInt64(1), Int64Kind, -1},
+		{FromInt64(math.MinInt64), FromInt64(math.MaxInt64), Int64Kind, -1},
+		{FromInt64(math.MaxInt64), FromInt64(-1), Int64Kind, +1},
+
+		{FromFloat64(1.5), FromFloat64(2), Float64Kind, -1},
+		{FromFloat64(-1), FromFloat64(-2), Float64Kind, +1},
+		{FromFloat64(math.Copysign(0, -1)), FromFloat64(0), Float64Kind, 0},
+		{FromFloat64(inf), FromFloat64(math.MaxFloat64), Float64Kind, +1},
+		{FromFloat64(-inf), FromFloat64(-math.MaxFloat64), Float64Kind, -1},
+		{FromFloat64(inf), FromFloat64(inf), Float64Kind, 0},
+
+		// NaN is less than every other value, including -Inf.
+		{FromFloat64(nan), FromFloat64(-inf), Float64Kind, -1},
+		{FromFloat64(0), FromFloat64(nan), Float64Kind, +1},
+		{FromFloat64(nan), FromFloat64(nan), Float64Kind, 0},
+	} {
+		require.Equal(t, test.expect, Compare(test.a, test.b, test.kind), "%v %v %v", test.a, test.b, test.kind)
+		require.Equal(t, -test.expect, Compare(test.b, test.a, test.kind), "%v %v %v", test.b, test.a, test.kind)
+	}
+
+	// The kind decides the interpretation: as int64, the bits of
+	// -1.0 are a negative integer, less than those of 1.0.
+	require.Equal(t, -1, Compare(FromFloat64(-1), FromFloat64(1), Int64Kind))
+}
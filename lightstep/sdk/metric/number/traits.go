@@ -40,6 +40,10 @@ type Traits[N Any] interface {
 	// SwapAtomic sets `ptr` to `value` and returns the former value.
 	SwapAtomic(ptr *N, value N) N
 
+	// CompareAndSwapAtomic sets `ptr` to `new` if it equals `old`,
+	// returning true if it was set.
+	CompareAndSwapAtomic(ptr *N, old, new N) bool
+
 	// IsNaN indicates whether `math.IsNaN()` is true (impossible for int64).
 	IsNaN(value N) bool
 
@@ -75,6 +79,10 @@ func (Int64Traits) SwapAtomic(ptr *int64, value int64) int64 {
 	return atomic.SwapInt64(ptr, value)
 }
 
+func (Int64Traits) CompareAndSwapAtomic(ptr *int64, old, new int64) bool {
+	return atomic.CompareAndSwapInt64(ptr, old, new)
+}
+
 func (Int64Traits) AddAtomic(ptr *int64, value int64) {
 	atomic.AddInt64(ptr, value)
 }
@@ -116,6 +124,10 @@ func (Float64Traits) SwapAtomic(ptr *float64, value float64) float64 {
 	return math.Float64frombits(atomic.SwapUint64((*uint64)(unsafe.Pointer(ptr)), math.Float64bits(value)))
 }
 
+func (Float64Traits) CompareAndSwapAtomic(ptr *float64, old, new float64) bool {
+	return atomic.CompareAndSwapUint64((*uint64)(unsafe.Pointer(ptr)), math.Float64bits(old), math.Float64bits(new))
+}
+
 func (Float64Traits) AddAtomic(ptr *float64, value float64) {
 	for {
 		oldBits := atomic.LoadUint64((*uint64)(unsafe.Pointer(ptr)))
@@ -16,12 +16,19 @@ package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/me
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/stage"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.uber.org/multierr"
 )
 
 const DefaultInterval = 30 * time.Second
@@ -55,18 +62,89 @@ type PeriodicReader struct {
 	timeout  time.Duration
 	exporter PushExporter
 	producer Producer
+	pipeline stage.Pipeline
+	filtered []*filteredExporter
+	jitter   float64
+	policy   JitterPolicy
 	stop     context.CancelFunc
 	wait     sync.WaitGroup
+	shutdown atomic.Bool
 }
 
+// ErrReaderShutdown is returned by ForceFlush and Shutdown after
+// the reader was shut down.
+var ErrReaderShutdown = fmt.Errorf("reader was already shut down")
+
 type PeriodicReaderOption func(*PeriodicReader)
 
+// JitterPolicy determines how often the collection jitter is chosen.
+type JitterPolicy int
+
+const (
+	// StableJitter chooses one offset when the reader starts, so
+	// collections remain exactly one interval apart.
+	StableJitter JitterPolicy = iota
+
+	// PerIntervalJitter chooses a new offset for each interval.
+	PerIntervalJitter
+)
+
+// ExportFilter selects the instruments that are exported to one
+// exporter.
+type ExportFilter func(lib instrumentation.Scope, desc sdkinstrument.Descriptor) bool
+
+// filteredExporter is an additional exporter with its own filter.
+type filteredExporter struct {
+	exporter PushExporter
+	filter   ExportFilter
+	data     data.Metrics
+}
+
 func WithTimeout(d time.Duration) PeriodicReaderOption {
 	return func(pr *PeriodicReader) {
 		pr.timeout = d
 	}
 }
 
+// WithJitter delays each periodic collection by a random offset of
+// less than fraction times the interval, so that many instances that
+// started together do not export at the same moment.  The offset is
+// added to the regular schedule, the n-th collection occurring n
+// intervals after the reader starts, so jitter does not accumulate.
+// Fractions are limited to [0, 1]; zero disables jitter.  There is no
+// alignment of the schedule to wall-clock time, so the offset is
+// relative to when the reader started.
+func WithJitter(fraction float64, policy JitterPolicy) PeriodicReaderOption {
+	return func(pr *PeriodicReader) {
+		pr.jitter = min(max(fraction, 0), 1)
+		pr.policy = policy
+	}
+}
+
+// WithStages appends stages to the reader's pipeline, which is
+// applied to the collected data, in order, before each export.
+func WithStages(stages ...stage.Stage) PeriodicReaderOption {
+	return func(pr *PeriodicReader) {
+		pr.pipeline = append(pr.pipeline, stages...)
+	}
+}
+
+// WithExporter adds an exporter to the reader, which receives the
+// instruments of each collection selected by the filter.  A nil
+// filter selects every instrument.  The exporters share a single
+// collection, so instruments with delta temporality report the same
+// interval to every exporter.  The collected points are shared by all
+// exporters and must not be modified.  Exporters are called in
+// sequence, starting with the exporter passed to NewPeriodicReader.
+func WithExporter(exporter PushExporter, filter ExportFilter) PeriodicReaderOption {
+	return func(pr *PeriodicReader) {
+		pr.filtered = append(pr.filtered, &filteredExporter{
+			exporter: exporter,
+			filter:   filter,
+		})
+	}
+}
+
 // NewPeriodicReader constructs a PeriodicReader from a push-based
 // exporter given an interval.
 func NewPeriodicReader(exporter PushExporter, interval time.Duration, opts ...PeriodicReaderOption) *PeriodicReader {
@@ -111,34 +189,90 @@ func (pr *PeriodicReader) Register(producer Producer) {
 // start runs the export loop.
 func (pr *PeriodicReader) start(ctx context.Context) {
 	defer pr.wait.Done()
-	ticker := time.NewTicker(pr.interval)
+	sched := newSchedule(time.Now(), pr.interval, pr.jitter, pr.policy, rand.New(rand.NewSource(time.Now().UnixNano())))
+	timer := time.NewTimer(time.Until(sched.next(time.Now())))
+	defer timer.Stop()
 	for {
 
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			if err := pr.collectWithTimeout(ctx, pr.exporter.ExportMetrics); err != nil {
+		case <-timer.C:
+			if err := pr.collectWithTimeout(ctx, PushExporter.ExportMetrics); err != nil && !errors.Is(err, ErrReaderShutdown) {
 				otel.Handle(err)
 			}
+			timer.Reset(time.Until(sched.next(time.Now())))
+		}
+	}
+}
+
+// schedule computes the time of each periodic collection.
+type schedule struct {
+	start    time.Time
+	interval time.Duration
+	jitter   float64
+	policy   JitterPolicy
+	rand     *rand.Rand
+	offset   time.Duration
+	count    int64
+}
+
+func newSchedule(start time.Time, interval time.Duration, jitter float64, policy JitterPolicy, rnd *rand.Rand) *schedule {
+	s := &schedule{
+		start:    start,
+		interval: interval,
+		jitter:   jitter,
+		policy:   policy,
+		rand:     rnd,
+	}
+	s.offset = s.randomOffset()
+	return s
+}
+
+// randomOffset returns a jitter offset in [0, jitter*interval).
+func (s *schedule) randomOffset() time.Duration {
+	return time.Duration(s.rand.Float64() * s.jitter * float64(s.interval))
+}
+
+// next returns the time of the next collection after now.  Like a
+// time.Ticker, intervals that have already passed are skipped.
+func (s *schedule) next(now time.Time) time.Time {
+	for {
+		s.count++
+		if s.policy == PerIntervalJitter {
+			s.offset = s.randomOffset()
+		}
+		at := s.start.Add(time.Duration(s.count)*s.interval + s.offset)
+		if at.After(now) {
+			return at
 		}
 	}
 }
 
-func (pr *PeriodicReader) collectWithTimeout(ctx context.Context, method func(context.Context, data.Metrics) error) error {
+func (pr *PeriodicReader) collectWithTimeout(ctx context.Context, method func(PushExporter, context.Context, data.Metrics) error) error {
 	ctx, cancel := context.WithTimeout(ctx, pr.timeout)
 	defer cancel()
-	return pr.collect(ctx, method)
+	return pr.collect(ctx, method, false)
 }
 
 // Shutdown stops the export loop, canceling its Context, and waits
-// for it to return.  Then it issues a ShutdownMetrics with final
-// data.  There is no automatic timeout; to apply one, use
-// context.WithTimeout.
+// for it to return.  Then it performs a final collection, which
+// includes the delta state accumulated since the last collection, and
+// issues a ShutdownMetrics with the final data.  Subsequent calls to Shutdown
+// and ForceFlush return ErrReaderShutdown.  There is no automatic
+// timeout; to apply one, use context.WithTimeout.
 func (pr *PeriodicReader) Shutdown(ctx context.Context) error {
+	// Note: pr.lock is held by an export in progress, which is
+	// canceled below.
+	if !pr.shutdown.CompareAndSwap(false, true) {
+		return ErrReaderShutdown
+	}
+	if pr.producer == nil {
+		return nil
+	}
 	pr.stop()
 	pr.wait.Wait()
-	return pr.collect(ctx, pr.exporter.ShutdownMetrics)
+	return pr.collect(ctx, PushExporter.ShutdownMetrics, true)
 }
 
 // ForceFlush immediately waits for an existing collection, otherwise
@@ -146,22 +280,61 @@ func (pr *PeriodicReader) Shutdown(ctx context.Context) error {
 // ForceFlush with current data.  There is no automatic timeout; to
 // apply one, use context.WithTimeout.
 func (pr *PeriodicReader) ForceFlush(ctx context.Context) error {
-	return pr.collect(ctx, pr.exporter.ForceFlushMetrics)
+	return pr.collect(ctx, PushExporter.ForceFlushMetrics, false)
 }
 
 // collect serializes access to re-usable metrics data, in each case
-// calling through to an underlying PushExporter method with current
+// calling through to the underlying PushExporter methods with current
 // data.
-func (pr *PeriodicReader) collect(ctx context.Context, method func(context.Context, data.Metrics) error) error {
+func (pr *PeriodicReader) collect(ctx context.Context, method func(PushExporter, context.Context, data.Metrics) error, final bool) error {
 	pr.lock.Lock()
 	defer pr.lock.Unlock()
 
+	// After Shutdown begins, only its own final collection
+	// proceeds, so that no data is collected after the final
+	// export.
+	if pr.shutdown.Load() && !final {
+		return ErrReaderShutdown
+	}
+
 	// The lock ensures that re-use of `pr.data` is successful, it
 	// means that shutdown, flush, and ordinary collection are
 	// exclusive.  Note that shutdown will cancel a concurrent
 	// (ordinary) export, while flush will wait for a concurrent
 	// export.
 	pr.data = pr.producer.Produce(&pr.data)
+	pr.pipeline.Process(&pr.data)
+
+	err := method(pr.exporter, ctx, pr.data)
+
+	for _, fe := range pr.filtered {
+		fe.apply(&pr.data)
+		err = multierr.Append(err, method(fe.exporter, ctx, fe.data))
+	}
+	return err
+}
 
-	return method(ctx, pr.data)
+// apply selects the filtered instruments of the collection.  The
+// scope and instrument slices are re-used from one collection to the
+// next, while the points are shared with the collection.
+func (fe *filteredExporter) apply(in *data.Metrics) {
+	out := &fe.data
+	out.Resource = in.Resource
+	out.Scopes = out.Scopes[:0]
+
+	for si := range in.Scopes {
+		scope := &in.Scopes[si]
+		ptr := data.ReallocateFrom(&out.Scopes)
+		ptr.Library = scope.Library
+		ptr.Instruments = ptr.Instruments[:0]
+
+		for _, inst := range scope.Instruments {
+			if fe.filter == nil || fe.filter(scope.Library, inst.Descriptor) {
+				ptr.Instruments = append(ptr.Instruments, inst)
+			}
+		}
+		if len(ptr.Instruments) == 0 {
+			out.Scopes = out.Scopes[:len(out.Scopes)-1]
+		}
+	}
 }
@@ -17,6 +17,7 @@ package metric
 import (
 	"context"
 	"errors"
+	"math/rand"
 	"sync"
 	"testing"
 	"time"
@@ -28,7 +29,9 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
@@ -215,3 +218,147 @@ func TestPeriodicRepeats(t *testing.T) {
 		require.Equal(t, DefaultTimeout, periodic.timeout)
 	})
 }
+
+func TestPeriodicJitter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	const interval = 10 * time.Second
+	const bound = interval / 4
+
+	offsets := func(policy JitterPolicy) []time.Duration {
+		sched := newSchedule(start, interval, 0.25, policy, rand.New(rand.NewSource(1)))
+		var result []time.Duration
+		now := start
+		for n := 1; n <= 20; n++ {
+			at := sched.next(now)
+			offset := at.Sub(start.Add(time.Duration(n) * interval))
+			require.GreaterOrEqual(t, offset, time.Duration(0))
+			require.Less(t, offset, bound)
+			result = append(result, offset)
+			now = at
+		}
+		return result
+	}
+
+	// A stable offset is the same in every interval.
+	stable := offsets(StableJitter)
+	require.NotEqual(t, time.Duration(0), stable[0])
+	for _, offset := range stable {
+		require.Equal(t, stable[0], offset)
+	}
+
+	// A per-interval offset varies.
+	varied := offsets(PerIntervalJitter)
+	require.NotEqual(t, varied[0], varied[1])
+
+	// Missed intervals are skipped.
+	sched := newSchedule(start, interval, 0, StableJitter, rand.New(rand.NewSource(1)))
+	require.Equal(t, start.Add(4*interval), sched.next(start.Add(3*interval+time.Second)))
+
+	// The fraction is limited.
+	exporter := NewMockPushExporter(gomock.NewController(t))
+	require.Equal(t, 1.0, NewPeriodicReader(exporter, interval, WithJitter(2, StableJitter)).jitter)
+	require.Equal(t, 0.0, NewPeriodicReader(exporter, interval, WithJitter(-1, StableJitter)).jitter)
+}
+
+// sumsExporter records the sum of each instrument in each export.
+type sumsExporter struct {
+	exports []map[string]int64
+}
+
+func (e *sumsExporter) String() string {
+	return "sums"
+}
+
+func (e *sumsExporter) ExportMetrics(_ context.Context, m data.Metrics) error {
+	sums := map[string]int64{}
+	for _, scope := range m.Scopes {
+		for _, inst := range scope.Instruments {
+			for _, pt := range inst.Points {
+				sums[inst.Descriptor.Name] += number.ToInt64(pt.Aggregation.(*sum.MonotonicInt64).Sum())
+			}
+		}
+	}
+	e.exports = append(e.exports, sums)
+	return nil
+}
+
+func (e *sumsExporter) ShutdownMetrics(ctx context.Context, m data.Metrics) error {
+	return e.ExportMetrics(ctx, m)
+}
+
+func (e *sumsExporter) ForceFlushMetrics(ctx context.Context, m data.Metrics) error {
+	return e.ExportMetrics(ctx, m)
+}
+
+func TestPeriodicFilteredExporters(t *testing.T) {
+	ctx := context.Background()
+
+	full := &sumsExporter{}
+	subset := &sumsExporter{}
+	periodic := NewPeriodicReader(full, time.Hour,
+		WithExporter(subset, func(_ instrumentation.Scope, desc sdkinstrument.Descriptor) bool {
+			return desc.Name == "a"
+		}),
+	)
+	provider := NewMeterProvider(
+		WithReader(periodic, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+	)
+
+	meter := provider.Meter("test")
+	a := must(meter.Int64Counter("a"))
+	b := must(meter.Int64Counter("b"))
+
+	a.Add(ctx, 5)
+	b.Add(ctx, 7)
+	require.NoError(t, provider.ForceFlush(ctx))
+
+	a.Add(ctx, 1)
+	b.Add(ctx, 2)
+	require.NoError(t, provider.ForceFlush(ctx))
+
+	// Both exporters see the same delta intervals.
+	require.Equal(t, []map[string]int64{
+		{"a": 5, "b": 7},
+		{"a": 1, "b": 2},
+	}, full.exports)
+	require.Equal(t, []map[string]int64{
+		{"a": 5},
+		{"a": 1},
+	}, subset.exports)
+
+	require.NoError(t, provider.Shutdown(ctx))
+}
+
+// TestPeriodicShutdownDrain verifies that a short-lived process with
+// sparse updates exports its last deltas on Shutdown, before any
+// periodic export, and records nothing afterward.
+func TestPeriodicShutdownDrain(t *testing.T) {
+	ctx := context.Background()
+
+	exp := &sumsExporter{}
+	periodic := NewPeriodicReader(exp, time.Hour)
+	provider := NewMeterProvider(
+		WithReader(periodic, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+	)
+
+	meter := provider.Meter("test")
+	a := must(meter.Int64Counter("a"))
+	b := must(meter.Int64Counter("b"))
+
+	a.Add(ctx, 3)
+	b.Add(ctx, 4)
+	a.Add(ctx, 5)
+
+	require.NoError(t, provider.Shutdown(ctx))
+	require.Equal(t, []map[string]int64{
+		{"a": 8, "b": 4},
+	}, exp.exports)
+
+	// Measurements after Shutdown are ignored, and the reader does
+	// not collect again.
+	a.Add(ctx, 1)
+	require.ErrorIs(t, periodic.ForceFlush(ctx), ErrReaderShutdown)
+	require.ErrorIs(t, periodic.Shutdown(ctx), ErrReaderShutdown)
+	require.ErrorIs(t, provider.Shutdown(ctx), ErrAlreadyShutdown)
+	require.Len(t, exp.exports, 1)
+}
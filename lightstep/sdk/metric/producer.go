@@ -16,11 +16,18 @@ package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/me
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/asyncstate"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/doevery"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 // providerProducer is the binding between the MeterProvider and the
@@ -31,21 +38,124 @@ type providerProducer struct {
 	provider    *MeterProvider
 	pipe        int
 	lastCollect time.Time
+	collected   bool
+
+	// limiter is set when the reader's views limit the number
+	// of points per collection.
+	limiter *pointLimiter
 }
 
+var (
+	_ TemporalityProducer = &providerProducer{}
+	_ ContextProducer     = &providerProducer{}
+	_ FuncProducer        = &providerProducer{}
+)
+
 // producerFor returns the new Producer for calling Register.
 func (mp *MeterProvider) producerFor(pipe int) Producer {
-	return &providerProducer{
+	pp := &providerProducer{
 		provider:    mp,
 		pipe:        pipe,
 		lastCollect: mp.startTime,
 	}
+	if limit := mp.views[pipe].MaxPointsPerCollection; limit > 0 {
+		pp.limiter = &pointLimiter{limit: limit}
+	}
+	return pp
 }
 
 // Produce runs collection and produces a new metrics data object.
 func (pp *providerProducer) Produce(inout *data.Metrics) data.Metrics {
+	output, _ := pp.ProduceContext(context.Background(), inout)
+	return output
+}
+
+// ProduceContext runs collection like Produce, stopping the
+// collection of synchronous instruments once ctx is done.
+func (pp *providerProducer) ProduceContext(ctx context.Context, inout *data.Metrics) (data.Metrics, error) {
+	return pp.produce(ctx, inout, aggregation.UndefinedTemporality)
+}
+
+// ProduceTemporality runs collection like Produce, with instruments
+// that keep either temporality reporting tempo.
+func (pp *providerProducer) ProduceTemporality(inout *data.Metrics, tempo aggregation.Temporality) data.Metrics {
+	output, _ := pp.produce(context.Background(), inout, tempo)
+	return output
+}
+
+// produce runs a collection for Produce, ProduceContext, and
+// ProduceTemporality.  When tempo is not UndefinedTemporality, it is
+// passed to the collectors' CollectTemporality.
+func (pp *providerProducer) produce(ctx context.Context, inout *data.Metrics, tempo aggregation.Temporality) (data.Metrics, error) {
+	ordered := pp.provider.getOrdered()
+
+	sequence, warmup := pp.advance()
+
+	var output data.Metrics
+	if inout != nil {
+		inout.Reset()
+		output = *inout
+	}
+
+	output.Resource = pp.provider.cfg.res
+
+	var err error
+	for _, meter := range ordered {
+		if merr := meter.collectFor(
+			ctx,
+			pp.pipe,
+			sequence,
+			tempo,
+			false,
+			&output,
+		); merr != nil && err == nil {
+			err = merr
+		}
+	}
+
+	if warmup {
+		suppressDeltaPoints(&output)
+	}
+	if pp.limiter != nil {
+		pp.limiter.apply(&output, pp.collectors(ordered))
+	}
+	if pp.provider.cfg.sortOutput {
+		sortOutput(&output)
+	}
+
+	return output, err
+}
+
+// ProduceFunc runs collection like ProduceContext, passing each point
+// to emit instead of building the output.
+func (pp *providerProducer) ProduceFunc(ctx context.Context, emit func(scope instrumentation.Scope, desc sdkinstrument.Descriptor, point data.Point)) error {
 	ordered := pp.provider.getOrdered()
 
+	sequence, warmup := pp.advance()
+
+	var err error
+	for _, meter := range ordered {
+		lib := meter.library
+		if merr := meter.collectFuncFor(ctx, pp.pipe, sequence, func(desc sdkinstrument.Descriptor, point data.Point) {
+			if warmup && point.Temporality == aggregation.DeltaTemporality {
+				return
+			}
+			emit(lib, desc, point)
+		}); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	return err
+}
+
+// Resource returns the resource of the metrics produced.
+func (pp *providerProducer) Resource() *resource.Resource {
+	return pp.provider.cfg.res
+}
+
+// advance returns the sequence of a new collection, advancing the
+// last collection time, and whether it is the warm-up collection.
+func (pp *providerProducer) advance() (data.Sequence, bool) {
 	// Note: the Last time is only used in delta-temporality
 	// scenarios.  This lock protects the only stateful change in
 	// `pp` but does not prevent concurrent collection.  If a
@@ -53,10 +163,38 @@ func (pp *providerProducer) Produce(inout *data.Metrics) data.Metrics {
 	// concurrently, the results would be be recorded with
 	// non-overlapping timestamps but would have been collected in
 	// an overlapping way.
+	//
+	// The first collection's Last time is the MeterProvider start
+	// time, so the first delta interval covers the period since
+	// startup.
 	pp.lock.Lock()
+	defer pp.lock.Unlock()
+
 	lastTime := pp.lastCollect
-	nowTime := time.Now()
+	nowTime := pp.provider.cfg.clock()
 	pp.lastCollect = nowTime
+	warmup := !pp.collected && pp.provider.cfg.deltaWarmup
+	pp.collected = true
+
+	return pp.sequence(lastTime, nowTime), warmup
+}
+
+// ProduceWithTemporality runs a collection that reports every
+// instrument with the temporality given, without modifying the state
+// kept for Produce.  The next call to Produce reports the same delta
+// intervals as it would have without this call.
+//
+// Cumulative points cover the time since the MeterProvider started,
+// including for synchronous instruments configured with delta
+// temporality, which keep running totals of the intervals reported
+// by Produce.  Delta points of instruments configured with delta
+// temporality cover the time since the last call to Produce,
+// otherwise the time since the MeterProvider started.
+func (pp *providerProducer) ProduceWithTemporality(inout *data.Metrics, tempo aggregation.Temporality) data.Metrics {
+	ordered := pp.provider.getOrdered()
+
+	pp.lock.Lock()
+	lastTime := pp.lastCollect
 	pp.lock.Unlock()
 
 	var output data.Metrics
@@ -67,28 +205,157 @@ func (pp *providerProducer) Produce(inout *data.Metrics) data.Metrics {
 
 	output.Resource = pp.provider.cfg.res
 
-	sequence := data.Sequence{
-		Start: pp.provider.startTime,
-		Last:  lastTime,
-		Now:   nowTime,
-	}
+	sequence := pp.sequence(lastTime, pp.provider.cfg.clock())
 
 	ctx := context.Background()
 
 	for _, meter := range ordered {
-		meter.collectFor(
+		_ = meter.collectFor(
 			ctx,
 			pp.pipe,
 			sequence,
+			tempo,
+			true,
 			&output,
 		)
 	}
+	if pp.provider.cfg.sortOutput {
+		sortOutput(&output)
+	}
 
 	return output
 }
 
-// collectFor collects from a single meter.
-func (m *meter) collectFor(ctx context.Context, pipe int, seq data.Sequence, output *data.Metrics) {
+// sequence returns the timestamps of a collection, rounded as
+// configured by the reader's views.
+func (pp *providerProducer) sequence(lastTime, nowTime time.Time) data.Sequence {
+	seq := data.Sequence{
+		Start: pp.provider.startTime,
+		Last:  lastTime,
+		Now:   nowTime,
+	}
+	if d := pp.provider.views[pp.pipe].TimestampRounding; d > 0 {
+		seq.Start = seq.Start.Round(d)
+		seq.Last = seq.Last.Round(d)
+		seq.Now = seq.Now.Round(d)
+	}
+	return seq
+}
+
+// collectors returns the collectors of each meter for this reader,
+// which correspond with the instruments of each output scope.
+func (pp *providerProducer) collectors(ordered []*meter) [][]data.Collector {
+	colls := make([][]data.Collector, len(ordered))
+	for i, m := range ordered {
+		colls[i] = m.compilers[pp.pipe].Collectors()
+	}
+	return colls
+}
+
+// suppressDeltaPoints removes delta temporality points from the
+// output, used to discard the warm-up interval.
+func suppressDeltaPoints(output *data.Metrics) {
+	for si := range output.Scopes {
+		insts := output.Scopes[si].Instruments
+		for ii := range insts {
+			all := insts[ii].Points
+			points := all[:0]
+			for _, pt := range all {
+				if pt.Temporality == aggregation.DeltaTemporality {
+					continue
+				}
+				points = append(points, pt)
+			}
+			// A cumulative point shifted down over a warm-up
+			// delta point is also left at its old index; the
+			// next collection would reuse its aggregation twice.
+			for i := len(points); i < len(all); i++ {
+				all[i] = data.Point{}
+			}
+			insts[ii].Points = points
+		}
+	}
+}
+
+// sortOutput orders the instruments of each scope by name and the
+// points of each instrument by their encoded attributes.  The sorts
+// are stable, so instruments with the same name remain in the order
+// they were compiled.
+func sortOutput(output *data.Metrics) {
+	var keys []string
+	for si := range output.Scopes {
+		insts := output.Scopes[si].Instruments
+		sort.SliceStable(insts, func(i, j int) bool {
+			return insts[i].Descriptor.Name < insts[j].Descriptor.Name
+		})
+		for ii := range insts {
+			points := insts[ii].Points
+			keys = keys[:0]
+			for _, pt := range points {
+				keys = append(keys, data.CanonicalEncode(pt.Attributes))
+			}
+			sort.Sort(pointsByKey{points: points, keys: keys})
+		}
+	}
+}
+
+// pointsByKey sorts points and their keys together.
+type pointsByKey struct {
+	points []data.Point
+	keys   []string
+}
+
+func (p pointsByKey) Len() int           { return len(p.points) }
+func (p pointsByKey) Less(i, j int) bool { return p.keys[i] < p.keys[j] }
+func (p pointsByKey) Swap(i, j int) {
+	p.points[i], p.points[j] = p.points[j], p.points[i]
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+}
+
+// collectFor collects from a single meter.  When peek is set,
+// instruments are peeked with the temporality instead of collected.
+// Otherwise, a temporality other than UndefinedTemporality is passed
+// to CollectTemporality.  Returns the error of the first instrument
+// whose collection was stopped by ctx.
+func (m *meter) collectFor(ctx context.Context, pipe int, seq data.Sequence, tempo aggregation.Temporality, peek bool, output *data.Metrics) error {
+	m.snapshotFor(ctx, pipe, peek)
+
+	scope := data.ReallocateFrom(&output.Scopes)
+	scope.Library = m.library
+
+	// Every instrument is called after one is stopped, so that
+	// asynchronous instruments complete.
+	var collErr error
+	for _, coll := range m.compilers[pipe].Collectors() {
+		switch {
+		case peek:
+			coll.Peek(seq, tempo, &scope.Instruments)
+		case tempo != aggregation.UndefinedTemporality:
+			coll.CollectTemporality(seq, tempo, &scope.Instruments)
+		default:
+			if cerr := coll.CollectContext(ctx, seq, &scope.Instruments); cerr != nil && collErr == nil {
+				collErr = cerr
+			}
+		}
+	}
+	return collErr
+}
+
+// collectFuncFor collects from a single meter like collectFor,
+// passing each point to emit.  Returns ctx's error if it is done
+// after the callbacks, since the collections do not stop.
+func (m *meter) collectFuncFor(ctx context.Context, pipe int, seq data.Sequence, emit func(sdkinstrument.Descriptor, data.Point)) error {
+	m.snapshotFor(ctx, pipe, false)
+
+	for _, coll := range m.compilers[pipe].Collectors() {
+		coll.CollectFunc(seq, emit)
+	}
+	return ctx.Err()
+}
+
+// snapshotFor runs the callbacks of a single meter and processes its
+// accumulators for the pipeline, before its collectors are called.
+func (m *meter) snapshotFor(ctx context.Context, pipe int, peek bool) {
 	// Use m.lock to briefly access the current lists: syncInsts,
 	// asyncInsts, callbacks.  By releasing these locks, we allow
 	// new instruments and callbacks to be registered while
@@ -105,23 +372,31 @@ func (m *meter) collectFor(ctx context.Context, pipe int, seq data.Sequence, out
 	m.lock.Unlock()
 
 	asyncState := asyncstate.NewState(pipe)
+	if peek {
+		asyncState = asyncstate.NewPeekState(pipe)
+	}
 
 	for _, cb := range callbacks {
 		cb.Run(ctx, asyncState)
 	}
 
+	// A failed instrument does not stop the others from being
+	// processed.  The first failure is reported.
+	var err error
 	for _, inst := range syncInsts {
-		inst.SnapshotAndProcess()
+		if ierr := inst.SnapshotAndProcess(); ierr != nil && err == nil {
+			err = ierr
+		}
 	}
 
 	for _, inst := range asyncInsts {
-		inst.SnapshotAndProcess(asyncState)
+		if ierr := inst.SnapshotAndProcess(asyncState); ierr != nil && err == nil {
+			err = ierr
+		}
 	}
-
-	scope := data.ReallocateFrom(&output.Scopes)
-	scope.Library = m.library
-
-	for _, coll := range m.compilers[pipe].Collectors() {
-		coll.Collect(seq, &scope.Instruments)
+	if err != nil {
+		doevery.TimePeriod(30*time.Second, func() {
+			otel.Handle(err)
+		})
 	}
 }
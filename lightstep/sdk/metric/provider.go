@@ -20,11 +20,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/asyncstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/syncstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/embedded"
 	"go.opentelemetry.io/otel/metric/noop"
@@ -66,11 +70,17 @@ func NewMeterProvider(options ...Option) *MeterProvider {
 	for _, option := range options {
 		cfg = option.apply(cfg)
 	}
+	if cfg.onOverflow != nil {
+		cfg.performance.OnOverflow = cfg.onOverflow
+	}
 	cfg.performance = cfg.performance.Validate()
+	if cfg.clock == nil {
+		cfg.clock = time.Now
+	}
 
 	p := &MeterProvider{
 		cfg:       cfg,
-		startTime: time.Now(),
+		startTime: cfg.clock(),
 		meters:    map[instrumentation.Scope]*meter{},
 	}
 	for pipe := 0; pipe < len(cfg.readers); pipe++ {
@@ -179,13 +189,75 @@ func (mp *MeterProvider) Shutdown(ctx context.Context) error {
 		return ErrAlreadyShutdown
 	}
 
+	// Stop recording so that each reader's final collection
+	// includes every measurement, then release the accumulators
+	// once the final data has been exported.
+	ordered := mp.getOrdered()
+	for _, m := range ordered {
+		m.forEachSync((*syncstate.Observer).Close)
+	}
+
 	for _, r := range mp.cfg.readers {
 		err = multierr.Append(err, r.Shutdown(ctx))
 	}
 
+	for _, m := range ordered {
+		m.forEachSync((*syncstate.Observer).Release)
+	}
 	return err
 }
 
+// FanOut reports the live series of every instrument grouped by their
+// attributes apart from the volatile keys, for each reader, to help
+// locate the attribute responsible for unexpected cardinality.  Within
+// each instrument, groups are ordered by decreasing series count.
+//
+// This method is safe to call concurrently with collection.
+func (mp *MeterProvider) FanOut(volatile ...attribute.Key) []data.FanOut {
+	var output []data.FanOut
+	for _, m := range mp.getOrdered() {
+		for pipe := range m.compilers {
+			for _, coll := range m.compilers[pipe].Collectors() {
+				start := len(output)
+				coll.FanOut(volatile, &output)
+				for i := start; i < len(output); i++ {
+					output[i].Reader = mp.cfg.readers[pipe].String()
+					output[i].Library = m.library
+				}
+			}
+		}
+	}
+	return output
+}
+
+// SetInstrumentEnabled enables or disables, at runtime, the
+// instruments with the name given in every meter, for every reader.
+// Instruments are enabled when created.  A disabled synchronous
+// instrument skips measurements at the cost of an atomic load, and
+// the observations of a disabled asynchronous instrument are
+// discarded.  The series accumulated before disabling continue to be
+// collected, unless clear is set, in which case they are discarded.
+//
+// This method is safe to call concurrently with measurements and
+// collection.
+func (mp *MeterProvider) SetInstrumentEnabled(name string, enabled, clear bool) {
+	for _, m := range mp.getOrdered() {
+		m.lock.Lock()
+		for desc, inst := range m.byDesc {
+			if desc.Name != name {
+				continue
+			}
+			switch inst := inst.(type) {
+			case *syncstate.Observer:
+				inst.SetEnabled(enabled, clear)
+			case *asyncstate.Observer:
+				inst.SetEnabled(enabled, clear)
+			}
+		}
+		m.lock.Unlock()
+	}
+}
+
 // getOrdered returns meters in the order they were registered.
 func (mp *MeterProvider) getOrdered() []*meter {
 	mp.lock.Lock()
@@ -18,9 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
@@ -28,9 +30,12 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/viewstate"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
@@ -302,3 +307,829 @@ func TestDuplicateInstrumentConflict(t *testing.T) {
 	require.Equal(t, 1, len(*errs))
 	require.True(t, errors.Is((*errs)[0], viewstate.ViewConflictsError{}))
 }
+
+// testClock returns a clock that advances one second per call,
+// starting at the returned time.
+func testClock() (time.Time, func() time.Time) {
+	start := time.Unix(1000, 0)
+	now := start.Add(-time.Second)
+	return start, func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+// TestTimestampRounding verifies that point timestamps are rounded
+// and that delta intervals remain contiguous.
+func TestTimestampRounding(t *testing.T) {
+	ctx := context.Background()
+
+	// Collections every 10s, with jitter.
+	start := time.Unix(1000, 400*int64(time.Millisecond))
+	jitter := []time.Duration{0, 10300, 19800, 30600, 40499}
+	var calls int
+	clock := func() time.Time {
+		now := start.Add(jitter[calls] * time.Millisecond)
+		calls++
+		return now
+	}
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr,
+			view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+			view.WithTimestampRounding(time.Second),
+		),
+		WithResource(resource.Empty()),
+		WithClock(clock),
+	)
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+
+	var ends []time.Time
+	for i := 0; i < 3; i++ {
+		cntr.Add(ctx, 1)
+		pt := rdr.Produce(nil).Scopes[0].Instruments[0].Points[0]
+		require.Equal(t, time.Duration(0), pt.Start.Sub(time.Unix(0, 0))%time.Second)
+		require.Equal(t, time.Duration(0), pt.End.Sub(time.Unix(0, 0))%time.Second)
+		require.True(t, pt.Start.Before(pt.End))
+		ends = append(ends, pt.End)
+
+		// Each interval starts where the last ended.
+		if i == 0 {
+			require.Equal(t, time.Unix(1000, 0), pt.Start)
+		} else {
+			require.Equal(t, ends[i-1], pt.Start)
+		}
+	}
+	require.Equal(t, []time.Time{time.Unix(1011, 0), time.Unix(1020, 0), time.Unix(1031, 0)}, ends)
+
+	// ProduceWithTemporality rounds the same way.
+	cntr.Add(ctx, 1)
+	pt := rdr.ProduceWithTemporality(nil, aggregation.CumulativeTemporality).Scopes[0].Instruments[0].Points[0]
+	require.Equal(t, time.Unix(1000, 0), pt.Start)
+	require.Equal(t, time.Unix(1041, 0), pt.End)
+	require.Equal(t, int64(4), number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum()))
+}
+
+// failingMerge is a custom aggregator that panics in Merge.
+type failingMerge struct{}
+
+func (failingMerge) Update(int64)                                {}
+func (failingMerge) Merge(aggregator.CustomAggregator[int64])    { panic("merge") }
+func (failingMerge) Clone() aggregator.CustomAggregator[int64]   { return failingMerge{} }
+func (failingMerge) Reset()                                      {}
+func (failingMerge) HasChange() bool                             { return false }
+func (failingMerge) Subtract(aggregator.CustomAggregator[int64]) {}
+
+// TestCollectMergeError tests that a failed merge is reported by
+// collection, which continues with the other instruments.
+func TestCollectMergeError(t *testing.T) {
+	ctx := context.Background()
+
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr,
+			view.WithClause(
+				view.MatchInstrumentName("broken"),
+				view.WithCustomAggregation(&aggregator.CustomConfig{
+					NewInt64: func() aggregator.CustomAggregator[int64] {
+						return failingMerge{}
+					},
+				}),
+			),
+		),
+		WithResource(resource.Empty()),
+	)
+	broken := must(provider.Meter("test").Int64Counter("broken"))
+	working := must(provider.Meter("test").Int64Counter("working"))
+
+	broken.Add(ctx, 1)
+	working.Add(ctx, 2)
+
+	output := rdr.Produce(nil)
+	insts := output.Scopes[0].Instruments
+	require.Equal(t, 2, len(insts))
+	require.Equal(t, "working", insts[1].Descriptor.Name)
+	require.Equal(t, 1, len(insts[1].Points))
+	require.Equal(t, int64(2), number.ToInt64(insts[1].Points[0].Aggregation.(aggregation.Sum).Sum()))
+
+	// The failed instrument remains usable.
+	broken.Add(ctx, 1)
+	working.Add(ctx, 2)
+
+	output = rdr.Produce(nil)
+	insts = output.Scopes[0].Instruments
+	require.Equal(t, int64(4), number.ToInt64(insts[1].Points[0].Aggregation.(aggregation.Sum).Sum()))
+
+	for _, err := range errs {
+		require.ErrorIs(t, err, aggregator.ErrMergeFailed)
+		require.Contains(t, err.Error(), "broken")
+	}
+}
+
+func TestFirstDeltaInterval(t *testing.T) {
+	ctx := context.Background()
+
+	start, clock := testClock()
+	rdr := NewManualReader("test")
+	res := resource.Empty()
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+		WithResource(res),
+		WithClock(clock),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	cntr.Add(ctx, 10)
+
+	// The first interval starts at the provider start time.
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start, start.Add(time.Second), sum.NewMonotonicInt64(10), aggregation.DeltaTemporality),
+			),
+		),
+	)
+
+	cntr.Add(ctx, 1)
+
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start.Add(time.Second), start.Add(2*time.Second), sum.NewMonotonicInt64(1), aggregation.DeltaTemporality),
+			),
+		),
+	)
+}
+
+func TestDeltaWarmup(t *testing.T) {
+	ctx := context.Background()
+
+	start, clock := testClock()
+	rdr := NewManualReader("test")
+	res := resource.Empty()
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+		WithResource(res),
+		WithClock(clock),
+		WithDeltaWarmup(),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	updown := must(provider.Meter("test").Int64UpDownCounter("updown"))
+	cntr.Add(ctx, 1000)
+	updown.Add(ctx, 5)
+
+	// The warm-up collection has no delta points; the
+	// cumulative UpDownCounter is unaffected.
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+			),
+			test.Instrument(
+				test.Descriptor("updown", sdkinstrument.SyncUpDownCounter, number.Int64Kind),
+				test.Point(start, start.Add(time.Second), sum.NewNonMonotonicInt64(5), aggregation.CumulativeTemporality),
+			),
+		),
+	)
+
+	cntr.Add(ctx, 1)
+
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start.Add(time.Second), start.Add(2*time.Second), sum.NewMonotonicInt64(1), aggregation.DeltaTemporality),
+			),
+			test.Instrument(
+				test.Descriptor("updown", sdkinstrument.SyncUpDownCounter, number.Int64Kind),
+				test.Point(start, start.Add(2*time.Second), sum.NewNonMonotonicInt64(5), aggregation.CumulativeTemporality),
+			),
+		),
+	)
+}
+
+func TestSortedOutput(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr),
+		WithResource(resource.Empty()),
+		WithSortedOutput(),
+	)
+
+	// Registered out of order.
+	zeta := must(provider.Meter("test").Int64Counter("zeta"))
+	alpha := must(provider.Meter("test").Int64Counter("alpha"))
+
+	for i := 0; i < 100; i++ {
+		zeta.Add(ctx, 1, metric.WithAttributes(attribute.Int("i", i)))
+		alpha.Add(ctx, 1, metric.WithAttributes(attribute.String("s", fmt.Sprint(i))))
+	}
+
+	var names []string
+	var keys [][]string
+	var reuse data.Metrics
+	for round := 0; round < 5; round++ {
+		// Re-use does not affect the order.
+		var output data.Metrics
+		if round%2 == 1 {
+			reuse = rdr.Produce(&reuse)
+			output = reuse
+		} else {
+			output = rdr.Produce(nil)
+		}
+		insts := output.Scopes[0].Instruments
+
+		var roundNames []string
+		var roundKeys [][]string
+		for _, inst := range insts {
+			roundNames = append(roundNames, inst.Descriptor.Name)
+
+			var instKeys []string
+			for _, pt := range inst.Points {
+				instKeys = append(instKeys, data.CanonicalEncode(pt.Attributes))
+			}
+			require.True(t, sort.StringsAreSorted(instKeys))
+			require.Equal(t, 100, len(instKeys))
+			roundKeys = append(roundKeys, instKeys)
+		}
+		require.Equal(t, []string{"alpha", "zeta"}, roundNames)
+
+		if round == 0 {
+			names, keys = roundNames, roundKeys
+			continue
+		}
+		require.Equal(t, names, roundNames)
+		require.Equal(t, keys, roundKeys)
+	}
+}
+
+// TestScopeGrouping verifies that the output has one entry per
+// instrumentation scope, however the instruments were registered.
+func TestScopeGrouping(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr),
+		WithResource(resource.Empty()),
+		WithSortedOutput(),
+	)
+
+	// Instruments of the two scopes are interleaved, and the
+	// second Meter of scope "a" is the same as the first.
+	a1 := must(provider.Meter("a").Int64Counter("a1"))
+	b1 := must(provider.Meter("b", metric.WithInstrumentationVersion("v1")).Int64Counter("b1"))
+	a2 := must(provider.Meter("a").Int64Counter("a2"))
+
+	a1.Add(ctx, 1)
+	b1.Add(ctx, 2)
+	a2.Add(ctx, 3)
+
+	names := func(insts []data.Instrument) (r []string) {
+		for _, inst := range insts {
+			r = append(r, inst.Descriptor.Name)
+		}
+		return r
+	}
+
+	output := rdr.Produce(nil)
+	require.Equal(t, 2, len(output.Scopes))
+	require.Equal(t, instrumentation.Scope{Name: "a"}, output.Scopes[0].Library)
+	require.Equal(t, []string{"a1", "a2"}, names(output.Scopes[0].Instruments))
+	require.Equal(t, instrumentation.Scope{Name: "b", Version: "v1"}, output.Scopes[1].Library)
+	require.Equal(t, []string{"b1"}, names(output.Scopes[1].Instruments))
+
+	byScope := output.ByScope()
+	require.Equal(t, 2, len(byScope))
+	require.Equal(t, []string{"a1", "a2"}, names(byScope[instrumentation.Scope{Name: "a"}]))
+	require.Equal(t, []string{"b1"}, names(byScope[instrumentation.Scope{Name: "b", Version: "v1"}]))
+
+	// A single scope is unaffected.
+	rdr = NewManualReader("single")
+	provider = NewMeterProvider(
+		WithReader(rdr),
+		WithResource(resource.Empty()),
+	)
+	must(provider.Meter("a").Int64Counter("a1")).Add(ctx, 1)
+
+	output = rdr.Produce(nil)
+	require.Equal(t, 1, len(output.Scopes))
+	require.Equal(t, []string{"a1"}, names(output.Scopes[0].Instruments))
+	require.Equal(t, map[instrumentation.Scope][]data.Instrument{
+		{Name: "a"}: output.Scopes[0].Instruments,
+	}, output.ByScope())
+}
+
+func TestMaxPointsPerCollection(t *testing.T) {
+	ctx := context.Background()
+
+	deltaRdr := NewManualReader("delta")
+	cumRdr := NewManualReader("cumulative")
+	provider := NewMeterProvider(
+		WithReader(deltaRdr,
+			view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+			view.WithMaxPointsPerCollection(5),
+		),
+		WithReader(cumRdr, view.WithMaxPointsPerCollection(4)),
+		WithResource(resource.Empty()),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+
+	sums := func(output data.Metrics) map[string]int64 {
+		points := output.Scopes[0].Instruments[0].Points
+		values := map[string]int64{}
+		for _, pt := range points {
+			values[data.CanonicalEncode(pt.Attributes)] = int64(pt.Aggregation.(aggregation.Sum).Sum().CoerceToFloat64(number.Int64Kind))
+		}
+		return values
+	}
+	total := func(values map[string]int64) (sum int64) {
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	}
+
+	seen := map[string]int64{}
+	for round := 1; round <= 3; round++ {
+		for i := 0; i < 10; i++ {
+			cntr.Add(ctx, 1, metric.WithAttributes(attribute.Int("i", i)))
+		}
+
+		// The delta points are folded into the overflow
+		// series without loss.
+		delta := sums(deltaRdr.Produce(nil))
+		require.Equal(t, 5, len(delta))
+		require.Equal(t, int64(10), total(delta))
+		require.Equal(t, int64(6), delta[data.CanonicalEncode(attribute.NewSet(attribute.Bool("otel.metric.overflow", true)))])
+
+		// The cumulative points are deferred, those
+		// deferred first.
+		cum := sums(cumRdr.Produce(nil))
+		require.Equal(t, 4, len(cum))
+		for key, value := range cum {
+			require.Equal(t, int64(round), value)
+			seen[key] = value
+		}
+	}
+	// Every cumulative series was reported by the third collection.
+	require.Equal(t, 10, len(seen))
+
+	// Within the limit, nothing is deferred or folded.
+	for i := 0; i < 3; i++ {
+		cntr.Add(ctx, 1, metric.WithAttributes(attribute.Int("i", i)))
+	}
+	require.Equal(t, 3, len(sums(deltaRdr.Produce(nil))))
+}
+
+func TestMaxPointsManyDeltaInstruments(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("delta")
+	provider := NewMeterProvider(
+		WithReader(rdr,
+			view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+			view.WithMaxPointsPerCollection(3),
+		),
+		WithResource(resource.Empty()),
+	)
+
+	meter := provider.Meter("test")
+	var cntrs []metric.Int64Counter
+	for i := 0; i < 5; i++ {
+		cntrs = append(cntrs, must(meter.Int64Counter(fmt.Sprint("counter", i))))
+	}
+	var observed int64
+	var obss []metric.Int64Observable
+	for i := 0; i < 2; i++ {
+		obs := must(meter.Int64ObservableCounter(fmt.Sprint("observed", i)))
+		_, err := meter.RegisterCallback(func(_ context.Context, obsrv metric.Observer) error {
+			obsrv.ObserveInt64(obs, observed)
+			return nil
+		}, obs)
+		require.NoError(t, err)
+		obss = append(obss, obs)
+	}
+
+	totals := map[string]int64{}
+	produce := func() {
+		output := rdr.Produce(nil)
+		points := 0
+		for _, inst := range output.Scopes[0].Instruments {
+			for _, pt := range inst.Points {
+				points++
+				totals[inst.Descriptor.Name] += int64(pt.Aggregation.(aggregation.Sum).Sum().CoerceToFloat64(number.Int64Kind))
+			}
+		}
+		require.LessOrEqual(t, points, 3)
+	}
+
+	for round := 0; round < 2; round++ {
+		for _, cntr := range cntrs {
+			cntr.Add(ctx, 1)
+		}
+		observed++
+		produce()
+	}
+	// The instruments carried by the last collections are
+	// reported by the next.
+	for round := 0; round < 3; round++ {
+		produce()
+	}
+
+	for i := range cntrs {
+		require.Equal(t, int64(2), totals[fmt.Sprint("counter", i)], "counter%d", i)
+	}
+	for i := range obss {
+		require.Equal(t, int64(2), totals[fmt.Sprint("observed", i)], "observed%d", i)
+	}
+}
+
+func TestMaxPointsChangedFirst(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("cumulative")
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithMaxPointsPerCollection(2)),
+		WithResource(resource.Empty()),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+
+	produce := func() map[int64]int64 {
+		output := rdr.Produce(nil)
+		values := map[int64]int64{}
+		for _, pt := range output.Scopes[0].Instruments[0].Points {
+			i, _ := pt.Attributes.Value("i")
+			values[i.AsInt64()] = int64(pt.Aggregation.(aggregation.Sum).Sum().CoerceToFloat64(number.Int64Kind))
+		}
+		return values
+	}
+
+	for i := 0; i < 4; i++ {
+		cntr.Add(ctx, 1, metric.WithAttributes(attribute.Int("i", i)))
+	}
+	first := produce()
+	second := produce()
+	require.Equal(t, 2, len(first))
+	require.Equal(t, 2, len(second))
+
+	// The changed series are reported ahead of those deferred
+	// longer, whose values are unchanged.
+	for i := range second {
+		cntr.Add(ctx, 1, metric.WithAttributes(attribute.Int("i", int(i))))
+		second[i]++
+	}
+	require.Equal(t, second, produce())
+	require.Equal(t, first, produce())
+}
+
+func TestProduceWithTemporality(t *testing.T) {
+	ctx := context.Background()
+
+	start, clock := testClock()
+	rdr := NewManualReader("test")
+	res := resource.Empty()
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+		WithResource(res),
+		WithClock(clock),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	obs := must(provider.Meter("test").Int64ObservableCounter("observed"))
+	observed := int64(100)
+	_, err := provider.Meter("test").RegisterCallback(func(_ context.Context, obsrv metric.Observer) error {
+		obsrv.ObserveInt64(obs, observed)
+		return nil
+	}, obs)
+	require.NoError(t, err)
+
+	cntr.Add(ctx, 10)
+
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start, start.Add(time.Second), sum.NewMonotonicInt64(10), aggregation.DeltaTemporality),
+			),
+			test.Instrument(
+				test.Descriptor("observed", sdkinstrument.AsyncCounter, number.Int64Kind),
+				test.Point(start, start.Add(time.Second), sum.NewMonotonicInt64(100), aggregation.DeltaTemporality),
+			),
+		),
+	)
+
+	cntr.Add(ctx, 5)
+	observed = 150
+
+	// Cumulative points since the start, the running total
+	// with the current interval (synchronous) and the observed
+	// value (asynchronous).
+	test.RequireEqualResourceMetrics(
+		t, rdr.ProduceWithTemporality(nil, aggregation.CumulativeTemporality), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start, start.Add(2*time.Second), sum.NewMonotonicInt64(15), aggregation.CumulativeTemporality),
+			),
+			test.Instrument(
+				test.Descriptor("observed", sdkinstrument.AsyncCounter, number.Int64Kind),
+				test.Point(start, start.Add(2*time.Second), sum.NewMonotonicInt64(150), aggregation.CumulativeTemporality),
+			),
+		),
+	)
+
+	// The delta state is intact: the next ordinary collection
+	// reports the same interval and values as without the peek.
+	test.RequireEqualResourceMetrics(
+		t, rdr.Produce(nil), res,
+		test.Scope(
+			test.Library("test"),
+			test.Instrument(
+				test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind),
+				test.Point(start.Add(time.Second), start.Add(3*time.Second), sum.NewMonotonicInt64(5), aggregation.DeltaTemporality),
+			),
+			test.Instrument(
+				test.Descriptor("observed", sdkinstrument.AsyncCounter, number.Int64Kind),
+				test.Point(start.Add(time.Second), start.Add(3*time.Second), sum.NewMonotonicInt64(50), aggregation.DeltaTemporality),
+			),
+		),
+	)
+
+	// The running total includes the intervals collected since.
+	cntr.Add(ctx, 1)
+	pt := rdr.ProduceWithTemporality(nil, aggregation.CumulativeTemporality).Scopes[0].Instruments[0].Points[0]
+	require.Equal(t, start, pt.Start)
+	require.Equal(t, int64(16), number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum()))
+}
+
+func TestProduceTemporality(t *testing.T) {
+	ctx := context.Background()
+
+	start, clock := testClock()
+	rdr := NewManualReader("test")
+	res := resource.Empty()
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithClause(view.WithEitherTemporality())),
+		WithResource(res),
+		WithClock(clock),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	desc := test.Descriptor("hello", sdkinstrument.SyncCounter, number.Int64Kind)
+
+	expect := func(out data.Metrics, pt data.Point) {
+		test.RequireEqualResourceMetrics(
+			t, out, res,
+			test.Scope(test.Library("test"), test.Instrument(desc, pt)),
+		)
+	}
+
+	// One instrument serves a delta and a cumulative exporter,
+	// each delta covering the time since the last delta.
+	cntr.Add(ctx, 10)
+	expect(rdr.ProduceTemporality(nil, aggregation.DeltaTemporality),
+		test.Point(start, start.Add(time.Second), sum.NewMonotonicInt64(10), aggregation.DeltaTemporality))
+
+	cntr.Add(ctx, 5)
+	expect(rdr.ProduceTemporality(nil, aggregation.CumulativeTemporality),
+		test.Point(start, start.Add(2*time.Second), sum.NewMonotonicInt64(15), aggregation.CumulativeTemporality))
+
+	cntr.Add(ctx, 1)
+	expect(rdr.ProduceTemporality(nil, aggregation.DeltaTemporality),
+		test.Point(start.Add(time.Second), start.Add(3*time.Second), sum.NewMonotonicInt64(6), aggregation.DeltaTemporality))
+
+	// Produce reports the configured temporality.
+	expect(rdr.Produce(nil),
+		test.Point(start, start.Add(4*time.Second), sum.NewMonotonicInt64(16), aggregation.CumulativeTemporality))
+}
+
+func TestSetInstrumentEnabled(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(WithReader(rdr))
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	obs := must(provider.Meter("test").Int64ObservableGauge("observed"))
+	_, err := provider.Meter("test").RegisterCallback(func(_ context.Context, obsrv metric.Observer) error {
+		obsrv.ObserveInt64(obs, 100)
+		return nil
+	}, obs)
+	require.NoError(t, err)
+
+	// sums returns the counter's value and the number of gauge
+	// points of a collection.
+	sums := func() (int64, int) {
+		var total int64
+		var gauges int
+		for _, inst := range rdr.Produce(nil).Scopes[0].Instruments {
+			switch inst.Descriptor.Name {
+			case "hello":
+				for _, pt := range inst.Points {
+					total += number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum())
+				}
+			case "observed":
+				gauges += len(inst.Points)
+			}
+		}
+		return total, gauges
+	}
+
+	cntr.Add(ctx, 1)
+	total, gauges := sums()
+	require.Equal(t, int64(1), total)
+	require.Equal(t, 1, gauges)
+
+	// Disabling keeps the counter's state.
+	provider.SetInstrumentEnabled("hello", false, false)
+	provider.SetInstrumentEnabled("observed", false, false)
+	cntr.Add(ctx, 10)
+	total, gauges = sums()
+	require.Equal(t, int64(1), total)
+	require.Equal(t, 0, gauges)
+
+	provider.SetInstrumentEnabled("hello", true, false)
+	provider.SetInstrumentEnabled("observed", true, false)
+	cntr.Add(ctx, 2)
+	total, gauges = sums()
+	require.Equal(t, int64(3), total)
+	require.Equal(t, 1, gauges)
+
+	// Disabling with clear discards the state.
+	provider.SetInstrumentEnabled("hello", false, true)
+	cntr.Add(ctx, 10)
+	total, _ = sums()
+	require.Equal(t, int64(0), total)
+
+	provider.SetInstrumentEnabled("hello", true, false)
+	cntr.Add(ctx, 4)
+	total, _ = sums()
+	require.Equal(t, int64(4), total)
+}
+
+func TestProduceContext(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+	)
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	cntr.Add(ctx, 10)
+
+	// A done context stops the collection, and the delta is
+	// reported by the next one.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	out, err := rdr.ProduceContext(canceled, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 0, len(out.Scopes[0].Instruments[0].Points))
+
+	out, err = rdr.ProduceContext(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(out.Scopes[0].Instruments[0].Points))
+	require.Equal(t, int64(10), number.ToInt64(out.Scopes[0].Instruments[0].Points[0].Aggregation.(aggregation.Sum).Sum()))
+}
+
+func TestFanOut(t *testing.T) {
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(WithReader(rdr))
+	cntr := must(provider.Meter("test").Int64Counter("requests"))
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		cntr.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("route", "/leaky"),
+			attribute.Int("request_id", i),
+		))
+	}
+	for _, code := range []int{200, 404} {
+		cntr.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("route", "/fine"),
+			attribute.Int("status", code),
+		))
+	}
+
+	fo := provider.FanOut("request_id", "status")
+	require.Len(t, fo, 2)
+
+	require.Equal(t, "test", fo[0].Reader)
+	require.Equal(t, "test", fo[0].Library.Name)
+	require.Equal(t, "requests", fo[0].Descriptor.Name)
+	require.Equal(t, attribute.NewSet(attribute.String("route", "/leaky")), fo[0].Attributes)
+	require.Equal(t, 100, fo[0].Series)
+	require.Equal(t, map[attribute.Key]int{"request_id": 100}, fo[0].Values)
+
+	require.Equal(t, attribute.NewSet(attribute.String("route", "/fine")), fo[1].Attributes)
+	require.Equal(t, 2, fo[1].Series)
+	require.Equal(t, map[attribute.Key]int{"status": 2}, fo[1].Values)
+}
+
+func TestProduceFunc(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithReader(rdr, view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality)),
+	)
+
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+	obs := must(provider.Meter("other").Int64ObservableGauge("observed"))
+	_, err := provider.Meter("other").RegisterCallback(func(_ context.Context, obsrv metric.Observer) error {
+		obsrv.ObserveInt64(obs, 100)
+		return nil
+	}, obs)
+	require.NoError(t, err)
+
+	// produce streams a collection, returning each value by
+	// scope and instrument name.
+	produce := func() map[string]int64 {
+		values := map[string]int64{}
+		require.NoError(t, rdr.ProduceFunc(ctx, func(scope instrumentation.Scope, desc sdkinstrument.Descriptor, pt data.Point) {
+			var value int64
+			switch agg := pt.Aggregation.(type) {
+			case aggregation.Sum:
+				value = number.ToInt64(agg.Sum())
+			case aggregation.Gauge:
+				value = number.ToInt64(agg.Gauge())
+			}
+			values[scope.Name+"/"+desc.Name] += value
+		}))
+		return values
+	}
+
+	cntr.Add(ctx, 10, metric.WithAttributes(attribute.String("a", "1")))
+	cntr.Add(ctx, 5, metric.WithAttributes(attribute.String("a", "2")))
+	require.Equal(t, map[string]int64{"test/hello": 15, "other/observed": 100}, produce())
+
+	// Streaming resets delta series like Produce.
+	cntr.Add(ctx, 1, metric.WithAttributes(attribute.String("a", "1")))
+	require.Equal(t, map[string]int64{"test/hello": 1, "other/observed": 100}, produce())
+
+	cntr.Add(ctx, 2, metric.WithAttributes(attribute.String("a", "2")))
+	out := rdr.Produce(nil)
+	require.Equal(t, 2, len(out.Scopes))
+	require.Equal(t, int64(2), number.ToInt64(out.Scopes[0].Instruments[0].Points[0].Aggregation.(aggregation.Sum).Sum()))
+
+	// A canceled context is reported after the callbacks.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, rdr.ProduceFunc(cctx, func(instrumentation.Scope, sdkinstrument.Descriptor, data.Point) {}), context.Canceled)
+}
+
+func TestContextWithSeriesMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(WithReader(rdr))
+	cntr := must(provider.Meter("test").Int64Counter("hello"))
+
+	mctx := ContextWithSeriesMetadata(ctx, attribute.String("label", "first"))
+	require.Equal(t, attribute.NewSet(attribute.String("label", "first")), SeriesMetadataFromContext(mctx))
+	require.Equal(t, *attribute.EmptySet(), SeriesMetadataFromContext(ctx))
+
+	// The metadata does not change the series, and the first
+	// value of each key is kept.
+	cntr.Add(mctx, 1, metric.WithAttributes(attribute.String("a", "1")))
+	cntr.Add(ContextWithSeriesMetadata(ctx, attribute.String("label", "second")), 1, metric.WithAttributes(attribute.String("a", "1")))
+	cntr.Add(ctx, 1, metric.WithAttributes(attribute.String("a", "2")))
+
+	out := rdr.Produce(nil)
+	points := out.Scopes[0].Instruments[0].Points
+	require.Equal(t, 2, len(points))
+	for _, pt := range points {
+		a, _ := pt.Attributes.Value("a")
+		switch a.AsString() {
+		case "1":
+			require.Equal(t, attribute.NewSet(attribute.String("label", "first")), pt.Metadata)
+			require.Equal(t, int64(2), number.ToInt64(pt.Aggregation.(aggregation.Sum).Sum()))
+		default:
+			require.Equal(t, 0, pt.Metadata.Len())
+		}
+	}
+}
@@ -17,7 +17,11 @@ package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/me
 import (
 	"context"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 // Reader is the interface used between the SDK and an
@@ -63,3 +67,60 @@ type Producer interface {
 	// When `in` is nil, a new Metrics object is returned.
 	Produce(in *data.Metrics) data.Metrics
 }
+
+// TemporalityProducer is implemented by the Producer passed to
+// Register, supporting collection with a temporality override.
+type TemporalityProducer interface {
+	Producer
+
+	// ProduceWithTemporality returns metrics from a collection
+	// in which every point has the temporality given.  This does
+	// not modify the state kept for Produce, so it does not
+	// affect the delta intervals reported by Produce.
+	ProduceWithTemporality(in *data.Metrics, tempo aggregation.Temporality) data.Metrics
+
+	// ProduceTemporality returns metrics from a collection like
+	// Produce, in which synchronous instruments configured with
+	// view.WithEitherTemporality report the temporality given.
+	// Each of their points covers the interval since the last
+	// collection with the same temporality, so exporters with
+	// different preferences can share one reader.  Other
+	// instruments report their configured temporality, as
+	// Produce.
+	ProduceTemporality(in *data.Metrics, tempo aggregation.Temporality) data.Metrics
+}
+
+// ContextProducer is implemented by the Producer passed to Register,
+// supporting collection bounded by a context.
+type ContextProducer interface {
+	Producer
+
+	// ProduceContext returns metrics from a collection like
+	// Produce, passing ctx to the callbacks.  Synchronous
+	// instruments stop collecting once ctx is done, so that
+	// they are not locked past its deadline; the partial
+	// metrics are returned with the context's error.  The
+	// series not reached keep their state for the next
+	// collection.  Points of delta instruments that were
+	// gathered are not reported again, so the context should
+	// bound the collection and not the export that follows.
+	ProduceContext(ctx context.Context, in *data.Metrics) (data.Metrics, error)
+}
+
+// FuncProducer is implemented by the Producer passed to Register,
+// supporting collection without building the output.
+type FuncProducer interface {
+	Producer
+
+	// Resource returns the resource of the metrics produced.
+	Resource() *resource.Resource
+
+	// ProduceFunc runs a collection like ProduceContext,
+	// passing each point to emit with its scope and
+	// instrument.  The point is only valid during the call to
+	// emit.  Points are not sorted and MaxPointsPerCollection
+	// does not apply.  Synchronous instruments collect every
+	// series; ctx is passed to the callbacks, and its error is
+	// returned once it is done.
+	ProduceFunc(ctx context.Context, emit func(scope instrumentation.Scope, desc sdkinstrument.Descriptor, point data.Point)) error
+}
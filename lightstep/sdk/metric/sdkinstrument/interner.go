@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdkinstrument
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultAttributeInternerLimit is the number of distinct attribute
+// sets an AttributeInterner holds before it is emptied.
+const DefaultAttributeInternerLimit = 16384
+
+// AttributeInterner canonicalizes attribute sets, so that the series
+// of every instrument sharing it use one copy of each distinct set.
+// Equal sets constructed separately, e.g., by each measurement or by
+// filtering, otherwise hold separate copies of their attributes.
+// Interning does not change which sets are equal.
+//
+// The interner does not know when sets fall out of use, so once it
+// holds its limit it is emptied, after which sets are shared again
+// from the next use.  Sets interned before remain valid.
+type AttributeInterner struct {
+	lock  sync.Mutex
+	limit int
+	sets  map[attribute.Set]attribute.Set
+}
+
+// NewAttributeInterner returns an AttributeInterner that holds up to
+// limit distinct sets.  A limit of 0 is
+// DefaultAttributeInternerLimit.
+func NewAttributeInterner(limit int) *AttributeInterner {
+	if limit <= 0 {
+		limit = DefaultAttributeInternerLimit
+	}
+	return &AttributeInterner{
+		limit: limit,
+		sets:  map[attribute.Set]attribute.Set{},
+	}
+}
+
+// Intern returns the shared instance of a set equal to set.  A nil
+// interner returns set.
+func (i *AttributeInterner) Intern(set attribute.Set) attribute.Set {
+	if i == nil || set.Len() == 0 {
+		return set
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if shared, has := i.sets[set]; has {
+		return shared
+	}
+	if len(i.sets) >= i.limit {
+		clear(i.sets)
+	}
+	i.sets[set] = set
+	return set
+}
+
+// Len returns the number of sets held.
+func (i *AttributeInterner) Len() int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return len(i.sets)
+}
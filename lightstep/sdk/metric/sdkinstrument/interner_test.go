@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdkinstrument
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// backing returns the address of a set's attributes, which is the
+// data word of the interface in its attribute.Distinct.
+func backing(set attribute.Set) unsafe.Pointer {
+	d := set.Equivalent()
+	return (*[2]unsafe.Pointer)(unsafe.Pointer(&d))[1]
+}
+
+func TestAttributeInterner(t *testing.T) {
+	in := NewAttributeInterner(0)
+
+	a := attribute.NewSet(attribute.String("K", "V"), attribute.Int("N", 1))
+	b := attribute.NewSet(attribute.Int("N", 1), attribute.String("K", "V"))
+	require.Equal(t, a, b)
+	require.NotEqual(t, backing(a), backing(b))
+
+	// Equal sets share the first instance.
+	require.Equal(t, backing(a), backing(in.Intern(a)))
+	require.Equal(t, backing(a), backing(in.Intern(b)))
+	require.Equal(t, 1, in.Len())
+
+	// Different sets are not combined, and empty sets are not held.
+	c := attribute.NewSet(attribute.String("K", "W"))
+	require.Equal(t, c, in.Intern(c))
+	require.Equal(t, *attribute.EmptySet(), in.Intern(*attribute.EmptySet()))
+	require.Equal(t, 2, in.Len())
+
+	// A nil interner is disabled.
+	var none *AttributeInterner
+	require.Equal(t, backing(b), backing(none.Intern(b)))
+}
+
+func TestAttributeInternerLimit(t *testing.T) {
+	in := NewAttributeInterner(2)
+
+	a := attribute.NewSet(attribute.Int("N", 1))
+	in.Intern(a)
+	in.Intern(attribute.NewSet(attribute.Int("N", 2)))
+	require.Equal(t, 2, in.Len())
+
+	// The third set empties the table, after which an equal set
+	// becomes the shared instance.
+	in.Intern(attribute.NewSet(attribute.Int("N", 3)))
+	require.Equal(t, 1, in.Len())
+
+	again := attribute.NewSet(attribute.Int("N", 1))
+	require.Equal(t, a, in.Intern(again))
+	require.Equal(t, backing(again), backing(in.Intern(a)))
+}
@@ -17,6 +17,7 @@ package sdkinstrument
 import (
 	"context"
 
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/pipeline"
 	"go.opentelemetry.io/otel/attribute"
 )
 
@@ -70,6 +71,67 @@ type Performance struct {
 	// ExemplarsEnabled is the number of exemplars that will be
 	// collected per timeseries, in the standard configuration.
 	ExemplarsEnabled uint32
+
+	// AccumulatorShards is the number of independent storage
+	// shards in each synchronous accumulator.  Updates land in a
+	// randomly chosen shard, and the shards are merged into the
+	// output at collection time.  This reduces contention for
+	// series that are updated concurrently from many goroutines,
+	// at the cost of memory and collection time proportional to
+	// the number of shards for every series.  Values of 0 and 1
+	// disable sharding; runtime.GOMAXPROCS(0) is a good choice
+	// for hot series.
+	AccumulatorShards uint32
+
+	// SnapshotParallelism is the number of goroutines that call
+	// SnapshotAndProcess on the accumulators of a synchronous
+	// instrument during collection, each for a share of its
+	// attribute sets.  Accumulators that share an output series
+	// merge into it one at a time.  Values of 0 and 1 snapshot
+	// serially; runtime.GOMAXPROCS(0) is a good choice for
+	// instruments with many attribute sets.
+	SnapshotParallelism uint32
+
+	// OverflowAttributes is the attribute set that replaces the
+	// attributes of new series once a cardinality limit is
+	// reached.  When empty, the specified attribute
+	// otel.metric.overflow=true is used.
+	OverflowAttributes []attribute.KeyValue
+
+	// OnOverflow, if set, is called the first time in each
+	// collection interval that an instrument records an
+	// attribute set in the overflow attribute set, either because
+	// of InstrumentCardinalityLimit or an aggregator cardinality
+	// limit.  It receives the instrument name and the attribute
+	// set that overflowed.  It is called without SDK locks held,
+	// from the goroutine making the measurement, so it should
+	// return quickly.
+	OnOverflow func(instrumentName string, set attribute.Set)
+
+	// LeakCollectionPeriods, when nonzero, is the number of
+	// consecutive collection periods that a series of a
+	// synchronous instrument with delta temporality may be held
+	// by accumulators without an update before it is counted as
+	// a possible leak, that is, by an accumulator that was never
+	// released.  Series held by the SDK are released after
+	// InactiveCollectionPeriods, so a larger value should be
+	// used.
+	LeakCollectionPeriods uint32
+
+	// OnLeak, if set with LeakCollectionPeriods, is called after
+	// a collection changes the number of possibly leaked series
+	// of an instrument, including to zero.  It receives the
+	// instrument name and the number of series.  It is called
+	// with the instrument's lock held, so it should return
+	// quickly and must not use the instrument.
+	OnLeak func(instrumentName string, series int)
+
+	// AttributeInterner, if set, canonicalizes the attribute
+	// set of each new series, so that instruments using the
+	// same interner share one copy of equal sets.  This saves
+	// memory when many instruments see the same sets, at the
+	// cost of a lock when each series is created.
+	AttributeInterner *AttributeInterner
 }
 
 // MeasurementProcessor allows applications to extend metric events
@@ -96,6 +158,13 @@ func (p Performance) Validate() Performance {
 	if p.AttributeSizeLimit == 0 {
 		p.AttributeSizeLimit = DefaultAttributeSizeLimit
 	}
+	if len(p.OverflowAttributes) == 0 {
+		p.OverflowAttributes = pipeline.OverflowAttributes
+	} else {
+		// Sorted and de-duplicated, as for any attribute set.
+		set := attribute.NewSet(p.OverflowAttributes...)
+		p.OverflowAttributes = set.ToSlice()
+	}
 
 	return p
 }
@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric"
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+)
+
+// SnapshotCache serves pull scrapes from a ManualReader, bounding
+// the collection frequency regardless of the number of scrapers.  The
+// first scrape after the freshness window has elapsed triggers a
+// collection and caches the encoded result; concurrent scrapes wait
+// for that collection, and subsequent scrapes within the window are
+// served from the cache.
+//
+// Because scrapers share one collection, every point is collected
+// with cumulative temporality, which is consistent for all of them.
+// This does not affect the delta intervals of the reader's Produce.
+type SnapshotCache struct {
+	reader    *ManualReader
+	freshness time.Duration
+	encode    func(data.Metrics) ([]byte, error)
+	clock     func() time.Time
+
+	// lock is held during collection, so that concurrent
+	// scrapes wait for the result.
+	lock    sync.Mutex
+	metrics data.Metrics
+	encoded []byte
+	err     error
+	expires time.Time
+}
+
+// NewSnapshotCache returns a SnapshotCache that collects from reader
+// at most once per freshness window, encoding each collection using
+// the function given.  The reader must be registered with a
+// MeterProvider.
+func NewSnapshotCache(reader *ManualReader, freshness time.Duration, encode func(data.Metrics) ([]byte, error)) *SnapshotCache {
+	return &SnapshotCache{
+		reader:    reader,
+		freshness: freshness,
+		encode:    encode,
+		clock:     time.Now,
+	}
+}
+
+// Snapshot returns the encoded result of the most recent collection,
+// collecting first if the cached result is older than the freshness
+// window.  An encoding error is cached like a result.  The returned
+// slice must not be modified.
+func (c *SnapshotCache) Snapshot() ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.expires.IsZero() || !c.clock().Before(c.expires) {
+		// Re-use the memory of the prior collection, which is
+		// no longer referenced once encoded.
+		c.metrics = c.reader.ProduceWithTemporality(&c.metrics, aggregation.CumulativeTemporality)
+		c.encoded, c.err = c.encode(c.metrics)
+		c.expires = c.clock().Add(c.freshness)
+	}
+	return c.encoded, c.err
+}
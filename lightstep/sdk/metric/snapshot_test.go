@@ -0,0 +1,101 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestSnapshotCache(t *testing.T) {
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(WithReader(
+		rdr,
+		view.WithDefaultAggregationTemporalitySelector(view.DeltaPreferredTemporality),
+	))
+	cntr := must(provider.Meter("test").Int64Counter("counter"))
+
+	var collections atomic.Int64
+	_ = must(provider.Meter("test").Int64ObservableGauge("collections",
+		metric.WithInt64Callback(func(_ context.Context, obs metric.Int64Observer) error {
+			obs.Observe(collections.Add(1))
+			return nil
+		}),
+	))
+
+	encode := func(md data.Metrics) ([]byte, error) {
+		for _, inst := range md.Scopes[0].Instruments {
+			if inst.Descriptor.Name != "counter" {
+				continue
+			}
+			pt := inst.Points[0]
+			value := pt.Aggregation.(aggregation.Sum).Sum().CoerceToFloat64(number.Int64Kind)
+			return []byte(fmt.Sprint(pt.Temporality, " ", value)), nil
+		}
+		return nil, fmt.Errorf("no counter")
+	}
+
+	now := time.Unix(1000, 0)
+	cache := NewSnapshotCache(rdr, time.Minute, encode)
+	cache.clock = func() time.Time { return now }
+
+	cntr.Add(context.Background(), 1)
+
+	const scrapers = 10
+	var wg sync.WaitGroup
+	results := make([]string, scrapers)
+	for i := 0; i < scrapers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b, err := cache.Snapshot()
+			require.NoError(t, err)
+			results[i] = string(b)
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(1), collections.Load())
+	expect := fmt.Sprint(aggregation.CumulativeTemporality, " ", 1)
+	for _, r := range results {
+		require.Equal(t, expect, r)
+	}
+
+	// Within the window, the cache is served.
+	cntr.Add(context.Background(), 1)
+	now = now.Add(30 * time.Second)
+	b, err := cache.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, expect, string(b))
+	require.Equal(t, int64(1), collections.Load())
+
+	// After the window, the cumulative value is collected again.
+	now = now.Add(30 * time.Second)
+	b, err = cache.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprint(aggregation.CumulativeTemporality, " ", 2), string(b))
+	require.Equal(t, int64(2), collections.Load())
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stage // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/stage"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// attributeOverlay is a Stage that adds static attributes to every
+// point.
+type attributeOverlay struct {
+	overlay attribute.Set
+}
+
+var _ Stage = attributeOverlay{}
+
+// NewAttributeOverlay returns a Stage that adds the static attributes
+// to every point.  Where a point already has one of the keys, the
+// static value replaces it.  Note that replacing a value can cause
+// two points of the same instrument to have identical attributes;
+// callers should use keys that are not otherwise recorded.
+func NewAttributeOverlay(kvs ...attribute.KeyValue) Stage {
+	return attributeOverlay{
+		overlay: attribute.NewSet(kvs...),
+	}
+}
+
+// Process implements Stage.
+func (a attributeOverlay) Process(_ instrumentation.Scope, insts []data.Instrument) []data.Instrument {
+	if a.overlay.Len() == 0 {
+		return insts
+	}
+	for ii := range insts {
+		points := insts[ii].Points
+		for pi := range points {
+			points[pi].Attributes = overlaySet(points[pi].Attributes, a.overlay)
+		}
+	}
+	return insts
+}
+
+// overlaySet returns the union of two sets, preferring values from
+// the overlay.
+func overlaySet(base, overlay attribute.Set) attribute.Set {
+	// The merge iterator yields the first argument's value when
+	// both sets have the same key.
+	merged := make([]attribute.KeyValue, 0, base.Len()+overlay.Len())
+	for iter := attribute.NewMergeIterator(&overlay, &base); iter.Next(); {
+		merged = append(merged, iter.Attribute())
+	}
+	return attribute.NewSet(merged...)
+}
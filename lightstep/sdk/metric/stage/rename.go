@@ -0,0 +1,46 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stage // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/stage"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// nameTransform is a Stage that renames instruments.
+type nameTransform struct {
+	rename view.RenameInstrumentFunction
+}
+
+var _ Stage = nameTransform{}
+
+// NewNameTransform returns a Stage that renames every instrument
+// using the function.  Unlike view.WithRenameFunction, this applies
+// after collection, so instruments that are renamed to the same name
+// are not checked for conflicts.
+func NewNameTransform(rename view.RenameInstrumentFunction) Stage {
+	return nameTransform{
+		rename: rename,
+	}
+}
+
+// Process implements Stage.
+func (n nameTransform) Process(_ instrumentation.Scope, insts []data.Instrument) []data.Instrument {
+	for ii := range insts {
+		insts[ii].Descriptor.Name = n.rename(insts[ii].Descriptor.Name)
+	}
+	return insts
+}
@@ -0,0 +1,49 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stage defines transformations applied to collected metric
+// data between collection and export.  A Pipeline is an ordered list
+// of Stage values, assembled by the reader.
+package stage // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/stage"
+
+import (
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// Stage is one transformation of the instruments collected for a
+// single instrumentation scope.
+//
+// Process may modify the instruments and points passed in, including
+// their Aggregation values, and returns the resulting slice, which
+// may be the input slice with a different length.  The memory
+// belongs to the caller, which may re-use it in the next collection,
+// so a Stage must not retain references to its input or output.
+type Stage interface {
+	Process(lib instrumentation.Scope, insts []data.Instrument) []data.Instrument
+}
+
+// Pipeline is an ordered list of stages.
+type Pipeline []Stage
+
+// Process applies each stage, in order, to every scope of the
+// collected metrics.
+func (p Pipeline) Process(metrics *data.Metrics) {
+	for si := range metrics.Scopes {
+		scope := &metrics.Scopes[si]
+		for _, s := range p {
+			scope.Instruments = s.Process(scope.Library, scope.Instruments)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	cumulative = aggregation.CumulativeTemporality
+	delta      = aggregation.DeltaTemporality
+)
+
+var (
+	testLib = test.Library("test")
+
+	time0 = time.Unix(1000, 0)
+	time1 = time0.Add(time.Second)
+	time2 = time1.Add(time.Second)
+)
+
+func TestAttributeOverlay(t *testing.T) {
+	in := []data.Instrument{
+		test.Instrument(
+			test.Descriptor("a", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(time0, time1, sum.NewMonotonicInt64(1), delta, attribute.String("k", "v"), attribute.String("env", "dev")),
+			test.Point(time0, time1, sum.NewMonotonicInt64(2), delta),
+		),
+	}
+	out := NewAttributeOverlay(attribute.String("env", "prod"), attribute.Int("shard", 3)).Process(testLib, in)
+
+	test.RequireEqualMetrics(t, out,
+		test.Instrument(
+			test.Descriptor("a", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(time0, time1, sum.NewMonotonicInt64(1), delta,
+				attribute.String("k", "v"), attribute.String("env", "prod"), attribute.Int("shard", 3)),
+			test.Point(time0, time1, sum.NewMonotonicInt64(2), delta,
+				attribute.String("env", "prod"), attribute.Int("shard", 3)),
+		),
+	)
+}
+
+func TestNameTransform(t *testing.T) {
+	in := []data.Instrument{
+		test.Instrument(test.Descriptor("a", sdkinstrument.SyncCounter, number.Int64Kind)),
+		test.Instrument(test.Descriptor("b", sdkinstrument.SyncHistogram, number.Float64Kind)),
+	}
+	out := NewNameTransform(func(name string) string { return "app." + name }).Process(testLib, in)
+
+	test.RequireEqualMetrics(t, out,
+		test.Instrument(test.Descriptor("app.a", sdkinstrument.SyncCounter, number.Int64Kind)),
+		test.Instrument(test.Descriptor("app.b", sdkinstrument.SyncHistogram, number.Float64Kind)),
+	)
+}
+
+// TestPipeline tests a pipeline of three stages over two collections
+// of delta temporality input.
+func TestPipeline(t *testing.T) {
+	pipe := Pipeline{
+		NewTemporality(cumulative),
+		NewAttributeOverlay(attribute.String("env", "prod")),
+		NewNameTransform(strings.ToUpper),
+	}
+	desc := test.Descriptor("requests", sdkinstrument.SyncCounter, number.Int64Kind)
+	other := test.Library("other")
+
+	first := test.Metrics(resource.Empty(),
+		test.Scope(testLib,
+			test.Instrument(desc,
+				test.Point(time0, time1, sum.NewMonotonicInt64(1), delta, attribute.String("code", "200")),
+				test.Point(time0, time1, sum.NewMonotonicInt64(2), delta, attribute.String("code", "500")),
+			),
+		),
+		// A same-named instrument in another scope is independent.
+		test.Scope(other,
+			test.Instrument(desc,
+				test.Point(time0, time1, sum.NewMonotonicInt64(10), delta, attribute.String("code", "200")),
+			),
+		),
+	)
+	pipe.Process(&first)
+
+	outDesc := test.Descriptor("REQUESTS", sdkinstrument.SyncCounter, number.Int64Kind)
+	test.RequireEqualResourceMetrics(t, first, resource.Empty(),
+		test.Scope(testLib,
+			test.Instrument(outDesc,
+				test.Point(time0, time1, sum.NewMonotonicInt64(1), cumulative, attribute.String("code", "200"), attribute.String("env", "prod")),
+				test.Point(time0, time1, sum.NewMonotonicInt64(2), cumulative, attribute.String("code", "500"), attribute.String("env", "prod")),
+			),
+		),
+		test.Scope(other,
+			test.Instrument(outDesc,
+				test.Point(time0, time1, sum.NewMonotonicInt64(10), cumulative, attribute.String("code", "200"), attribute.String("env", "prod")),
+			),
+		),
+	)
+
+	// In the second interval, "500" is unchanged and omitted
+	// from the delta input, but repeated in the output.
+	second := test.Metrics(resource.Empty(),
+		test.Scope(testLib,
+			test.Instrument(desc,
+				test.Point(time1, time2, sum.NewMonotonicInt64(5), delta, attribute.String("code", "200")),
+			),
+		),
+		test.Scope(other,
+			test.Instrument(desc,
+				test.Point(time1, time2, sum.NewMonotonicInt64(1), delta, attribute.String("code", "200")),
+			),
+		),
+	)
+	pipe.Process(&second)
+
+	test.RequireEqualResourceMetrics(t, second, resource.Empty(),
+		test.Scope(testLib,
+			test.Instrument(outDesc,
+				test.Point(time0, time2, sum.NewMonotonicInt64(6), cumulative, attribute.String("code", "200"), attribute.String("env", "prod")),
+				test.Point(time0, time2, sum.NewMonotonicInt64(2), cumulative, attribute.String("code", "500"), attribute.String("env", "prod")),
+			),
+		),
+		test.Scope(other,
+			test.Instrument(outDesc,
+				test.Point(time0, time2, sum.NewMonotonicInt64(11), cumulative, attribute.String("code", "200"), attribute.String("env", "prod")),
+			),
+		),
+	)
+}
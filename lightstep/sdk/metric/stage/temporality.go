@@ -0,0 +1,425 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stage // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/stage"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/count"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/custom"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/minmaxsumcount"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// temporality is a Stage that converts points to one temporality.
+type temporality struct {
+	lock  sync.Mutex
+	tempo aggregation.Temporality
+	acfg  aggregator.Config
+	// inactive is the number of collections a series may be
+	// absent from the input before its state is removed.
+	inactive uint64
+	scopes   map[instrumentation.Scope]*scopeState
+}
+
+var _ Stage = &temporality{}
+
+// scopeState is the state kept for one instrumentation scope.
+type scopeState struct {
+	// round counts the collections of this scope.
+	round  uint64
+	series map[instrumentKey]map[attribute.Distinct]*series
+}
+
+// instrumentKey identifies one instrument across collections.
+type instrumentKey struct {
+	name  string
+	nkind number.Kind
+}
+
+// series is the state kept for one series across collections.
+type series struct {
+	// conv converts this series, of one aggregation kind.
+	conv converter
+	// attrs are the series attributes.
+	attrs attribute.Set
+	// metadata is the series metadata of the last input point.
+	metadata attribute.Set
+	// storage is the *Storage type of the converter, holding
+	// the cumulative value.
+	storage any
+	// start is the start time of the cumulative series.
+	start time.Time
+	// end is the end time of the last output point.
+	end time.Time
+	// round is the last collection including this series.
+	round uint64
+	// seen is the last collection whose input included this
+	// series.
+	seen uint64
+}
+
+// NewTemporality returns a Stage that converts points to the
+// temporality given, which must be CumulativeTemporality or
+// DeltaTemporality.  The stage keeps the cumulative value of each
+// series, so it should be used by one reader only.
+//
+// Conversion to cumulative temporality applies to sum, histogram,
+// min-max-sum-count, and custom aggregations.  Because delta
+// temporality omits series that did not change, series that were
+// previously output are repeated with the end time of the current
+// collection.  Histogram state is kept using the default histogram
+// configuration.
+//
+// Conversion to delta temporality applies to sum and custom
+// aggregations, because other aggregations do not support
+// subtraction; those points are output unchanged.  Series that did
+// not change are omitted, and a change of start time is considered a
+// reset of the cumulative series.
+//
+// Points with exemplars are converted in place of the aggregation
+// they wrap.  A point whose aggregation does not match its kind and
+// number kind is output unchanged, and the failure is reported
+// through otel.Handle.
+//
+// The state of a series that is absent from the input for
+// sdkinstrument.DefaultInactiveCollectionPeriods collections is
+// removed, after which it is no longer repeated.
+func NewTemporality(tempo aggregation.Temporality) Stage {
+	acfg, _ := aggregator.Config{}.Validate()
+	return &temporality{
+		tempo:    tempo,
+		acfg:     acfg,
+		inactive: sdkinstrument.DefaultInactiveCollectionPeriods,
+		scopes:   map[instrumentation.Scope]*scopeState{},
+	}
+}
+
+// Process implements Stage.
+func (t *temporality) Process(lib instrumentation.Scope, insts []data.Instrument) []data.Instrument {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ss := t.scopes[lib]
+	if ss == nil {
+		ss = &scopeState{
+			series: map[instrumentKey]map[attribute.Distinct]*series{},
+		}
+		t.scopes[lib] = ss
+	}
+	ss.round++
+
+	// Every point in one collection has the same end time, which
+	// is used to repeat series that are not present.
+	var now time.Time
+	for ii := range insts {
+		for _, pt := range insts[ii].Points {
+			if pt.End.After(now) {
+				now = pt.End
+			}
+		}
+	}
+
+	for ii := range insts {
+		inst := &insts[ii]
+		key := instrumentKey{
+			name:  inst.Descriptor.Name,
+			nkind: inst.Descriptor.NumberKind,
+		}
+		sm := ss.series[key]
+		if sm == nil {
+			sm = map[attribute.Distinct]*series{}
+			ss.series[key] = sm
+		}
+
+		all := inst.Points
+		points := all[:0]
+		for _, pt := range all {
+			if t.convert(sm, ss.round, inst.Descriptor, &pt) {
+				points = append(points, pt)
+			}
+		}
+		// appendCumulative below reallocates from this tail,
+		// which still holds the aggregations of points that
+		// were shifted down over the omitted ones.
+		for i := len(points); i < len(all); i++ {
+			all[i] = data.Point{}
+		}
+		inst.Points = points
+	}
+
+	t.prune(ss)
+
+	if t.tempo != aggregation.CumulativeTemporality || now.IsZero() {
+		return insts
+	}
+	for ii := range insts {
+		inst := &insts[ii]
+		sm := ss.series[instrumentKey{
+			name:  inst.Descriptor.Name,
+			nkind: inst.Descriptor.NumberKind,
+		}]
+		for _, s := range sm {
+			if s.round != ss.round {
+				s.conv.appendCumulative(s, inst, now, t.acfg)
+				s.round = ss.round
+			}
+		}
+	}
+	return insts
+}
+
+// prune removes the series of one scope that have been absent from
+// the input for the inactive number of collections, and the
+// instruments left with no series.
+func (t *temporality) prune(ss *scopeState) {
+	for key, sm := range ss.series {
+		for ds, s := range sm {
+			if ss.round-s.seen >= t.inactive {
+				delete(sm, ds)
+			}
+		}
+		if len(sm) == 0 {
+			delete(ss.series, key)
+		}
+	}
+}
+
+// convert converts one point, returning false if it should be
+// omitted from the output.
+func (t *temporality) convert(sm map[attribute.Distinct]*series, round uint64, desc sdkinstrument.Descriptor, pt *data.Point) bool {
+	if pt.Aggregation == nil || pt.Temporality == t.tempo || pt.Temporality == aggregation.UndefinedTemporality {
+		return true
+	}
+	conv := converterFor(pt.Aggregation.Kind(), desc.NumberKind, t.tempo == aggregation.DeltaTemporality)
+	if conv == nil {
+		return true
+	}
+	s := sm[pt.Attributes.Equivalent()]
+	if s == nil || s.conv != conv {
+		s = &series{
+			conv:  conv,
+			attrs: pt.Attributes,
+		}
+		sm[pt.Attributes.Equivalent()] = s
+	}
+	s.round = round
+	s.seen = round
+	s.metadata = pt.Metadata
+
+	keep, ok := true, false
+	switch t.tempo {
+	case aggregation.CumulativeTemporality:
+		ok = conv.toCumulative(s, pt, t.acfg)
+	case aggregation.DeltaTemporality:
+		keep, ok = conv.toDelta(s, pt, t.acfg)
+	}
+	if !ok {
+		otel.Handle(fmt.Errorf("%s: %w: %T", desc.Name, aggregator.ErrAggregationKindMismatch, pt.Aggregation))
+	}
+	return keep
+}
+
+// converter converts the points of one aggregation kind.
+type converter interface {
+	// toCumulative adds a delta point to the series and
+	// replaces it with the cumulative value, returning false
+	// when the point's aggregation is not of the converter's
+	// storage type.
+	toCumulative(s *series, pt *data.Point, acfg aggregator.Config) (ok bool)
+
+	// toDelta replaces a cumulative point with the difference
+	// from the last point of the series, returning false for
+	// keep when there was no change and false for ok when the
+	// point's aggregation is not of the converter's storage
+	// type.
+	toDelta(s *series, pt *data.Point, acfg aggregator.Config) (keep, ok bool)
+
+	// appendCumulative outputs the series cumulative value.
+	appendCumulative(s *series, inst *data.Instrument, end time.Time, acfg aggregator.Config)
+}
+
+// converterFor returns the converter for an aggregation kind, or nil
+// if the conversion is not supported.
+func converterFor(kind aggregation.Kind, nkind number.Kind, toDelta bool) converter {
+	if nkind == number.Int64Kind {
+		return converterForNumber[int64, number.Int64Traits](kind, toDelta)
+	}
+	return converterForNumber[float64, number.Float64Traits](kind, toDelta)
+}
+
+func converterForNumber[N number.Any, Traits number.Traits[N]](kind aggregation.Kind, toDelta bool) converter {
+	switch kind {
+	case aggregation.MonotonicSumKind:
+		return methodsConverter[
+			N,
+			sum.State[N, Traits, sum.Monotonic],
+			sum.Methods[N, Traits, sum.Monotonic],
+		]{}
+	case aggregation.NonMonotonicSumKind:
+		return methodsConverter[
+			N,
+			sum.State[N, Traits, sum.NonMonotonic],
+			sum.Methods[N, Traits, sum.NonMonotonic],
+		]{}
+	case aggregation.CustomKind:
+		return methodsConverter[
+			N,
+			custom.State[N, Traits],
+			custom.Methods[N, Traits],
+		]{}
+	case aggregation.ExplicitHistogramKind:
+		return methodsConverter[
+			N,
+			explicit.State[N, Traits],
+			explicit.Methods[N, Traits],
+		]{}
+	case aggregation.CountKind:
+		return methodsConverter[
+			N,
+			count.State[N, Traits],
+			count.Methods[N, Traits],
+		]{}
+	}
+	if toDelta {
+		return nil
+	}
+	switch kind {
+	case aggregation.HistogramKind:
+		return methodsConverter[
+			N,
+			histogram.Histogram[N, Traits],
+			histogram.Methods[N, Traits],
+		]{}
+	case aggregation.MinMaxSumCountKind:
+		return methodsConverter[
+			N,
+			minmaxsumcount.State[N, Traits],
+			minmaxsumcount.Methods[N, Traits],
+		]{}
+	}
+	return nil
+}
+
+// methodsConverter implements converter using aggregator Methods.
+type methodsConverter[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]] struct{}
+
+func (methodsConverter[N, Storage, Methods]) newStorage(acfg aggregator.Config) *Storage {
+	var methods Methods
+	ptr := new(Storage)
+	methods.Init(ptr, acfg)
+	return ptr
+}
+
+// storageOf returns the storage of an aggregation, which for points
+// with exemplars is the storage of the aggregation they wrap.
+func (methodsConverter[N, Storage, Methods]) storageOf(agg aggregation.Aggregation) (*Storage, bool) {
+	var methods Methods
+	if unwr, ok := agg.(exemplar.Unwrapper); ok {
+		agg = unwr.Unwrap()
+	}
+	return methods.ToStorage(agg)
+}
+
+func (c methodsConverter[N, Storage, Methods]) toCumulative(s *series, pt *data.Point, acfg aggregator.Config) bool {
+	var methods Methods
+	in, ok := c.storageOf(pt.Aggregation)
+	if !ok {
+		return false
+	}
+	if s.storage == nil {
+		s.storage = c.newStorage(acfg)
+		s.start = pt.Start
+	}
+	state := s.storage.(*Storage)
+
+	methods.Merge(in, state)
+	methods.Copy(state, in)
+
+	pt.Temporality = aggregation.CumulativeTemporality
+	pt.Start = s.start
+	s.end = pt.End
+	return true
+}
+
+func (c methodsConverter[N, Storage, Methods]) toDelta(s *series, pt *data.Point, acfg aggregator.Config) (bool, bool) {
+	var methods Methods
+	in, ok := c.storageOf(pt.Aggregation)
+	if !ok {
+		return true, false
+	}
+	pt.Temporality = aggregation.DeltaTemporality
+
+	if s.storage == nil || !s.start.Equal(pt.Start) {
+		// The first point of a cumulative series is the
+		// delta since its start.
+		s.storage = c.newStorage(acfg)
+		s.start = pt.Start
+		s.end = pt.End
+		methods.Copy(in, s.storage.(*Storage))
+		return true, true
+	}
+	state := s.storage.(*Storage)
+
+	// state = in - state, then exchange the two so that the
+	// point holds the difference and the state holds the new
+	// cumulative value.
+	methods.SubtractSwap(state, in)
+	tmp := c.newStorage(acfg)
+	methods.Move(in, tmp)
+	methods.Move(state, in)
+	methods.Move(tmp, state)
+
+	pt.Start = s.end
+	s.end = pt.End
+	return methods.HasChange(in), true
+}
+
+func (c methodsConverter[N, Storage, Methods]) appendCumulative(s *series, inst *data.Instrument, end time.Time, acfg aggregator.Config) {
+	var methods Methods
+	if s.storage == nil {
+		return
+	}
+
+	// Possibly re-use the underlying storage.
+	point := data.ReallocateFrom(&inst.Points)
+	out, ok := c.storageOf(point.Aggregation)
+	if !ok {
+		out = c.newStorage(acfg)
+	}
+	methods.Copy(s.storage.(*Storage), out)
+
+	point.Attributes = s.attrs
+	point.Metadata = s.metadata
+	point.Aggregation = methods.ToAggregation(out)
+	point.Temporality = aggregation.CumulativeTemporality
+	point.Start = s.start
+	point.End = end
+	point.Exemplars = point.Exemplars[:0]
+	s.end = end
+}
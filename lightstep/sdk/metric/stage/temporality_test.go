@@ -0,0 +1,263 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/explicit"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/data"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/exemplar"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestCumulativeToDelta(t *testing.T) {
+	stage := NewTemporality(delta)
+	desc := test.Descriptor("updown", sdkinstrument.AsyncUpDownCounter, number.Float64Kind)
+	time3 := time2.Add(time2.Sub(time1))
+
+	collect := func(pts ...data.Point) []data.Instrument {
+		return stage.Process(testLib, []data.Instrument{test.Instrument(desc, pts...)})
+	}
+	attrsA := attribute.String("a", "1")
+	attrsB := attribute.String("b", "1")
+
+	test.RequireEqualMetrics(t,
+		collect(
+			test.Point(time0, time1, sum.NewNonMonotonicFloat64(10), cumulative, attrsA),
+			test.Point(time0, time1, sum.NewNonMonotonicFloat64(3), cumulative, attrsB),
+		),
+		test.Instrument(desc,
+			test.Point(time0, time1, sum.NewNonMonotonicFloat64(10), delta, attrsA),
+			test.Point(time0, time1, sum.NewNonMonotonicFloat64(3), delta, attrsB),
+		),
+	)
+
+	// B is unchanged and omitted.
+	test.RequireEqualMetrics(t,
+		collect(
+			test.Point(time0, time2, sum.NewNonMonotonicFloat64(7), cumulative, attrsA),
+			test.Point(time0, time2, sum.NewNonMonotonicFloat64(3), cumulative, attrsB),
+		),
+		test.Instrument(desc,
+			test.Point(time1, time2, sum.NewNonMonotonicFloat64(-3), delta, attrsA),
+		),
+	)
+
+	// A new start time resets the series.
+	test.RequireEqualMetrics(t,
+		collect(
+			test.Point(time2, time3, sum.NewNonMonotonicFloat64(4), cumulative, attrsA),
+		),
+		test.Instrument(desc,
+			test.Point(time2, time3, sum.NewNonMonotonicFloat64(4), delta, attrsA),
+		),
+	)
+}
+
+func TestTemporalityUnsupported(t *testing.T) {
+	histo := histogram.NewFloat64(histogram.NewConfig(), 1, 2, 3)
+	hdesc := test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Float64Kind)
+	gdesc := test.Descriptor("gauge", sdkinstrument.AsyncGauge, number.Int64Kind)
+	gval := gauge.NewInt64(7)
+
+	// Cumulative histograms are not converted to delta, and
+	// gauges are never converted.
+	out := NewTemporality(delta).Process(testLib, []data.Instrument{
+		test.Instrument(hdesc, test.Point(time0, time1, histo, cumulative)),
+		test.Instrument(gdesc, test.Point(time0, time1, gval, cumulative)),
+	})
+	test.RequireEqualMetrics(t, out,
+		test.Instrument(hdesc, test.Point(time0, time1, histo, cumulative)),
+		test.Instrument(gdesc, test.Point(time0, time1, gval, cumulative)),
+	)
+}
+
+func TestDeltaToCumulativeHistogram(t *testing.T) {
+	stage := NewTemporality(cumulative)
+	desc := test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Float64Kind)
+
+	stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, test.Point(time0, time1, histogram.NewFloat64(histogram.NewConfig(), 1, 2), delta)),
+	})
+	out := stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, test.Point(time1, time2, histogram.NewFloat64(histogram.NewConfig(), 3), delta)),
+	})
+
+	require.Equal(t, 1, len(out))
+	require.Equal(t, 1, len(out[0].Points))
+	pt := out[0].Points[0]
+	require.Equal(t, cumulative, pt.Temporality)
+	require.Equal(t, time0, pt.Start)
+	require.Equal(t, time2, pt.End)
+
+	h := pt.Aggregation.(*histogram.Float64)
+	require.Equal(t, uint64(3), h.Count())
+	require.Equal(t, 6.0, h.Sum().CoerceToFloat64(number.Float64Kind))
+}
+
+func TestDeltaToCumulativeInactive(t *testing.T) {
+	stage := NewTemporality(cumulative)
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	attrsA := attribute.String("a", "1")
+	attrsB := attribute.String("b", "1")
+
+	stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc,
+			test.Point(time0, time1, sum.NewMonotonicInt64(1), delta, attrsA),
+			test.Point(time0, time1, sum.NewMonotonicInt64(2), delta, attrsB),
+		),
+	})
+
+	// B is repeated until it has been absent for the inactive
+	// number of collections.
+	for i := 1; i <= sdkinstrument.DefaultInactiveCollectionPeriods; i++ {
+		end := time1.Add(time.Duration(i) * time.Second)
+		out := stage.Process(testLib, []data.Instrument{
+			test.Instrument(desc,
+				test.Point(end.Add(-time.Second), end, sum.NewMonotonicInt64(1), delta, attrsA),
+			),
+		})
+		expect := []data.Point{
+			test.Point(time0, end, sum.NewMonotonicInt64(int64(i+1)), cumulative, attrsA),
+		}
+		if i < sdkinstrument.DefaultInactiveCollectionPeriods {
+			expect = append(expect, test.Point(time0, end, sum.NewMonotonicInt64(2), cumulative, attrsB))
+		}
+		test.RequireEqualMetrics(t, out, test.Instrument(desc, expect...))
+	}
+
+	// Once A is absent as well, the instrument state is removed.
+	for i := 0; i < sdkinstrument.DefaultInactiveCollectionPeriods; i++ {
+		stage.Process(testLib, nil)
+	}
+	require.Equal(t, 0, len(stage.(*temporality).scopes[testLib].series))
+}
+
+func TestCumulativeToDeltaExplicitHistogram(t *testing.T) {
+	stage := NewTemporality(delta)
+	desc := test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Int64Kind)
+	bounds := []float64{1, 5, 10}
+
+	stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, test.Point(time0, time1, explicit.NewInt64(bounds, 1, 7), cumulative)),
+	})
+	out := stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, test.Point(time0, time2, explicit.NewInt64(bounds, 1, 7, 3, 20), cumulative)),
+	})
+
+	require.Equal(t, 1, len(out))
+	require.Equal(t, 1, len(out[0].Points))
+	pt := out[0].Points[0]
+	require.Equal(t, delta, pt.Temporality)
+	require.Equal(t, time1, pt.Start)
+
+	h := pt.Aggregation.(*explicit.Int64)
+	require.Equal(t, []uint64{0, 1, 0, 1}, h.BucketCounts())
+	require.Equal(t, uint64(2), h.Count())
+	require.Equal(t, int64(23), number.ToInt64(h.Sum()))
+}
+
+// exemplarPoint returns a point of the exemplar storage that sync
+// instruments use with exemplars enabled, updated with values.
+func exemplarPoint[N number.Any, Storage any, Methods aggregator.Methods[N, Storage]](start, end time.Time, tempo aggregation.Temporality, values ...N) data.Point {
+	var methods exemplar.WeightedMethods[N, Storage, Methods]
+	ptr := new(exemplar.WeightedStorage[N, Storage, Methods])
+	methods.Init(ptr, aggregator.Config{Exemplar: aggregator.ExemplarConfig{Size: 5}})
+	for _, value := range values {
+		methods.Update(ptr, value, aggregator.ExemplarBits{})
+	}
+	return test.Point(start, end, methods.ToAggregation(ptr), tempo)
+}
+
+func TestDeltaToCumulativeExemplars(t *testing.T) {
+	stage := NewTemporality(cumulative)
+	cdesc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	hdesc := test.Descriptor("histo", sdkinstrument.SyncHistogram, number.Float64Kind)
+
+	for i := 1; i <= 3; i++ {
+		start := time0.Add(time.Duration(i-1) * time.Second)
+		end := start.Add(time.Second)
+		out := stage.Process(testLib, []data.Instrument{
+			test.Instrument(cdesc, exemplarPoint[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods](start, end, delta, 1)),
+			test.Instrument(hdesc, exemplarPoint[float64, histogram.Float64, histogram.Float64Methods](start, end, delta, 2)),
+		})
+		require.Equal(t, 2, len(out))
+
+		cpt := out[0].Points[0]
+		require.Equal(t, cumulative, cpt.Temporality)
+		require.Equal(t, time0, cpt.Start)
+		require.Equal(t, end, cpt.End)
+		require.Equal(t, sum.NewMonotonicInt64(int64(i)), cpt.Aggregation.(exemplar.Unwrapper).Unwrap())
+
+		hpt := out[1].Points[0]
+		require.Equal(t, cumulative, hpt.Temporality)
+		require.Equal(t, time0, hpt.Start)
+		h := hpt.Aggregation.(exemplar.Unwrapper).Unwrap().(*histogram.Float64)
+		require.Equal(t, uint64(i), h.Count())
+		require.Equal(t, float64(2*i), h.Sum().CoerceToFloat64(number.Float64Kind))
+	}
+}
+
+func TestCumulativeToDeltaExemplars(t *testing.T) {
+	stage := NewTemporality(delta)
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+
+	stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, exemplarPoint[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods](time0, time1, cumulative, 1, 2)),
+	})
+	out := stage.Process(testLib, []data.Instrument{
+		test.Instrument(desc, exemplarPoint[int64, sum.MonotonicInt64, sum.MonotonicInt64Methods](time0, time2, cumulative, 1, 2, 4)),
+	})
+
+	require.Equal(t, 1, len(out))
+	require.Equal(t, 1, len(out[0].Points))
+	pt := out[0].Points[0]
+	require.Equal(t, delta, pt.Temporality)
+	require.Equal(t, time1, pt.Start)
+	require.Equal(t, sum.NewMonotonicInt64(4), pt.Aggregation.(exemplar.Unwrapper).Unwrap())
+}
+
+func TestTemporalityKindMismatch(t *testing.T) {
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	// A float64 sum does not match an int64 instrument; the
+	// point is output unchanged and the failure is reported.
+	desc := test.Descriptor("counter", sdkinstrument.SyncCounter, number.Int64Kind)
+	out := NewTemporality(cumulative).Process(testLib, []data.Instrument{
+		test.Instrument(desc, test.Point(time0, time1, sum.NewMonotonicFloat64(1), delta)),
+	})
+	test.RequireEqualMetrics(t, out,
+		test.Instrument(desc, test.Point(time0, time1, sum.NewMonotonicFloat64(1), delta)),
+	)
+	require.Equal(t, 1, len(errs))
+	require.True(t, errors.Is(errs[0], aggregator.ErrAggregationKindMismatch))
+}
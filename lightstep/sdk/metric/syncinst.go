@@ -90,70 +90,112 @@ func recordToOpConfig(options []metric.RecordOption) syncstate.OpConfig {
 }
 
 func (i int64Counter) AddWithKeyValues(ctx context.Context, value int64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i int64Counter) Add(ctx context.Context, value int64, options ...metric.AddOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, addToOpConfig(options))
 }
 
 func (i int64UpDownCounter) AddWithKeyValues(ctx context.Context, value int64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i int64UpDownCounter) Add(ctx context.Context, value int64, options ...metric.AddOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, addToOpConfig(options))
 }
 
 func (i int64Gauge) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, recordToOpConfig(options))
 }
 
 func (i int64Histogram) RecordWithKeyValues(ctx context.Context, value int64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i int64Histogram) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveInt64(ctx, value, recordToOpConfig(options))
 }
 
 func (i float64Counter) AddWithKeyValues(ctx context.Context, value float64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i float64Counter) Add(ctx context.Context, value float64, options ...metric.AddOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, addToOpConfig(options))
 }
 
 func (i float64UpDownCounter) AddWithKeyValues(ctx context.Context, value float64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i float64UpDownCounter) Add(ctx context.Context, value float64, options ...metric.AddOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, addToOpConfig(options))
 }
 
 func (i float64Gauge) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, recordToOpConfig(options))
 }
 
 func (i float64Histogram) RecordWithKeyValues(ctx context.Context, value float64, attrs ...attribute.KeyValue) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, syncstate.OpConfig{
 		KeyValues: attrs,
 	})
 }
 
 func (i float64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	if !i.observer.Enabled(ctx) {
+		return
+	}
 	i.observer.ObserveFloat64(ctx, value, recordToOpConfig(options))
 }
 
@@ -16,6 +16,7 @@ package metric
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -24,10 +25,12 @@ import (
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/gauge"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/histogram"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/sum"
+	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/bypass"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/internal/test"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -124,3 +127,104 @@ func TestSyncInsts(t *testing.T) {
 		),
 	)
 }
+
+func TestFeatureFlag(t *testing.T) {
+	var enabled atomic.Bool
+	rdr := NewManualReader("test")
+	provider := NewMeterProvider(
+		WithResource(resource.Empty()),
+		WithReader(rdr),
+		WithFeatureFlags(func(desc sdkinstrument.Descriptor) FeatureFlag {
+			if desc.Name != "gated" {
+				return nil
+			}
+			return func(context.Context) bool {
+				return enabled.Load()
+			}
+		}),
+	)
+
+	gated := must(provider.Meter("test").Int64Counter("gated"))
+	always := must(provider.Meter("test").Int64Counter("always"))
+
+	ctx := context.Background()
+	kvs := []attribute.KeyValue{
+		attribute.String("a", "1"),
+		attribute.String("b", "2"),
+	}
+	adder := gated.(bypass.FastInt64Adder)
+
+	// With the flag off, no attribute set is constructed.
+	require.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		adder.AddWithKeyValues(ctx, 1, kvs...)
+	}))
+	gated.Add(ctx, 1, metric.WithAttributes(kvs...))
+	always.Add(ctx, 1, metric.WithAttributes(kvs...))
+
+	enabled.Store(true)
+	adder.AddWithKeyValues(ctx, 10, kvs...)
+
+	test.RequireEqualMetrics(t,
+		rdr.Produce(nil).Scopes[0].Instruments,
+		test.Instrument(
+			test.Descriptor("gated", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(time.Time{}, time.Time{}, sum.NewMonotonicInt64(10), aggregation.CumulativeTemporality, kvs...),
+		),
+		test.Instrument(
+			test.Descriptor("always", sdkinstrument.SyncCounter, number.Int64Kind),
+			test.Point(time.Time{}, time.Time{}, sum.NewMonotonicInt64(1), aggregation.CumulativeTemporality, kvs...),
+		),
+	)
+}
+
+func TestOverflowCallback(t *testing.T) {
+	type overflow struct {
+		name string
+		set  attribute.Set
+	}
+	for _, perf := range []sdkinstrument.Performance{
+		{AggregatorCardinalityLimit: 5},
+		{InstrumentCardinalityLimit: 5},
+	} {
+		var calls []overflow
+		rdr := NewManualReader("test")
+		provider := NewMeterProvider(
+			WithResource(resource.Empty()),
+			WithReader(rdr),
+			WithOverflowCallback(func(name string, set attribute.Set) {
+				calls = append(calls, overflow{name, set})
+			}),
+			// The callback applies regardless of option order.
+			WithPerformance(perf),
+		)
+		cntr := must(provider.Meter("test").Int64Counter("c"))
+		ctx := context.Background()
+		record := func(from, to int) {
+			for i := from; i < to; i++ {
+				cntr.Add(ctx, 1, metric.WithAttributes(attribute.Int("K", i)))
+			}
+		}
+
+		// A storm of overflowing measurements calls once.
+		record(0, 3)
+		require.Len(t, calls, 0)
+		record(0, 20)
+		record(0, 20)
+		require.Len(t, calls, 1)
+		require.Equal(t, "c", calls[0].name)
+		value, ok := calls[0].set.Value("K")
+		require.True(t, ok)
+		require.GreaterOrEqual(t, value.AsInt64(), int64(3))
+
+		// Each collection interval calls again.
+		_ = rdr.Produce(nil)
+		record(19, 20)
+		require.Len(t, calls, 2)
+		require.Equal(t, attribute.NewSet(attribute.Int("K", 19)), calls[1].set)
+
+		// Measurements within the limit do not.
+		_ = rdr.Produce(nil)
+		record(0, 1)
+		require.Len(t, calls, 2)
+	}
+}
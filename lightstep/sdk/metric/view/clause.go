@@ -16,7 +16,10 @@ package view // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metr
 
 import (
 	"regexp"
+	"slices"
+	"time"
 
+	histostruct "github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
@@ -38,13 +41,37 @@ type ClauseConfig struct {
 	// Properties of the view
 	keys        []attribute.Key // nil implies all keys, []attribute.Key{} implies none
 	renameFunc  RenameInstrumentFunction
+	transform   AttributeTransformFunction
 	description string
 	aggregation aggregation.Kind
 	acfg        aggregator.Config
+	normalize   NormalizationRules
+	valueLimit  uint32
+	quantum     float64
+	rollup      bool
+	dedup       time.Duration
+	emitEmpty   bool
+	lastUpdate  bool
+	overflowCnt bool
+	eitherTempo bool
+	suppress    bool
+	deltaGauge  bool
+	budget      uint32
+	fallback    aggregation.Kind
+	fallbackPol FallbackPolicy
+	shadow      aggregation.Kind
+	shadowAcfg  aggregator.Config
 }
 
 type RenameInstrumentFunction func(string) string
 
+// AttributeTransformFunction rewrites the attribute set of a
+// measurement before it is used to locate a series.  This is called
+// for every new accumulator, so implementations should return the
+// input unchanged, without allocating, when there is nothing to
+// transform.
+type AttributeTransformFunction func(attribute.Set) attribute.Set
+
 const (
 	unsetInstrumentKind = sdkinstrument.Kind(-1)
 	unsetNumberKind     = number.Kind(-1)
@@ -139,6 +166,393 @@ func WithAggregation(kind aggregation.Kind) ClauseOption {
 	})
 }
 
+// WithCustomAggregation configures a user-defined aggregation.  This
+// sets the aggregation Kind to aggregation.CustomKind and the
+// aggregator configuration's Custom field.  Because this modifies the
+// aggregator configuration, it should be applied after any
+// WithAggregatorConfig option.
+func WithCustomAggregation(cc *aggregator.CustomConfig) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.aggregation = aggregation.CustomKind
+		clause.acfg.Custom = cc
+		return clause
+	})
+}
+
+// WithExplicitHistogram configures a histogram with fixed bucket
+// boundaries, which are upper-inclusive and must be finite and
+// increasing.  This sets the aggregation Kind to
+// aggregation.ExplicitHistogramKind and the aggregator
+// configuration's Explicit field.  Because this modifies the
+// aggregator configuration, it should be applied after any
+// WithAggregatorConfig option.
+func WithExplicitHistogram(boundaries ...float64) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.aggregation = aggregation.ExplicitHistogramKind
+		clause.acfg.Explicit = &aggregator.ExplicitConfig{
+			Boundaries: slices.Clone(boundaries),
+		}
+		return clause
+	})
+}
+
+// WithSumOverflow configures the behavior of Int64 sums that exceed
+// the range of int64.  Because this modifies the aggregator
+// configuration, it should be applied after any WithAggregatorConfig
+// option.
+func WithSumOverflow(policy aggregator.SumOverflowPolicy) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.SumOverflow = policy
+		return clause
+	})
+}
+
+// WithNonFinitePolicy configures whether Float64 sum and histogram
+// aggregations accept the NaN and ±Inf values passed to them.
+// Because this modifies the aggregator configuration, it should be
+// applied after any WithAggregatorConfig option.
+func WithNonFinitePolicy(policy aggregator.NonFinitePolicy) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.NonFinite = policy
+		return clause
+	})
+}
+
+// WithCardinalityLimit sets the number of series the view's
+// instruments keep before folding new attribute sets into the
+// overflow attribute set, in place of the Performance setting
+// AggregatorCardinalityLimit.  Each instrument matched by the view
+// observes the limit independently.  It should be applied after any
+// WithAggregatorConfig option, which it would otherwise be replaced
+// by.
+func WithCardinalityLimit(limit uint32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.CardinalityLimit = limit
+		return clause
+	})
+}
+
+// WithExemplarReservoirSize sets the number of exemplars kept per
+// series, in place of the Performance setting ExemplarsEnabled.
+// When exemplars are not otherwise enabled, this enables them for
+// traced measurements.  Zero keeps the default.  It should be
+// applied after any WithAggregatorConfig option, which it would
+// otherwise be replaced by.
+func WithExemplarReservoirSize(size uint32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		if size == 0 {
+			return clause
+		}
+		clause.acfg.Exemplar.Size = size
+		if clause.acfg.Exemplar.Filter == aggregator.AlwaysOffKind {
+			clause.acfg.Exemplar.Filter = aggregator.WhenTracedKind
+		}
+		return clause
+	})
+}
+
+// WithSparseHistogram selects sparse bucket storage for histogram
+// aggregations, which uses less memory when each series has few,
+// widely spread observations.  Because this modifies the aggregator
+// configuration, it should be applied after any WithAggregatorConfig
+// option.
+func WithSparseHistogram() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.HistogramSparse = true
+		return clause
+	})
+}
+
+// WithHistogramCarryScale starts each interval of a sparse histogram
+// at the scale reached by the previous interval; see
+// aggregator.Config.HistogramCarryScale.  Because this modifies the
+// aggregator configuration, it should be applied after any
+// WithAggregatorConfig option.
+func WithHistogramCarryScale() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.HistogramCarryScale = true
+		return clause
+	})
+}
+
+// WithHistogramZeroThreshold sets the magnitude at or below which
+// histogram observations are counted in the zero bucket.  Because
+// this modifies the aggregator configuration, it should be applied
+// after any WithAggregatorConfig option.
+func WithHistogramZeroThreshold(threshold float64) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.HistogramZeroThreshold = threshold
+		return clause
+	})
+}
+
+// WithHistogramMaxSize limits the number of buckets of histogram
+// aggregations, in each of the positive and negative ranges.  When
+// observations span more buckets, the scale is reduced.  Because
+// this modifies the aggregator configuration, it should be applied
+// after any WithAggregatorConfig option.
+func WithHistogramMaxSize(size int32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.Histogram = histostruct.NewConfig(histostruct.WithMaxSize(size))
+		return clause
+	})
+}
+
+// WithHistogramMaxScale limits the scale of histogram aggregations;
+// see aggregator.Config.HistogramMaxScale.  Because this modifies the
+// aggregator configuration, it should be applied after any
+// WithAggregatorConfig option.
+func WithHistogramMaxScale(scale int32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.acfg.HistogramLimitScale = true
+		clause.acfg.HistogramMaxScale = scale
+		return clause
+	})
+}
+
+// WithAttributeTransform configures a function for rewriting
+// attribute sets based on their values, e.g., to place values in
+// buckets or to remove a key conditionally.  This is applied after
+// WithKeys filtering and before attribute normalization.
+func WithAttributeTransform(transform AttributeTransformFunction) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.transform = transform
+		return clause
+	})
+}
+
+// WithAttributeNormalization configures rules for normalizing string
+// attribute values before they are used to locate a series.  This is
+// applied after WithKeys filtering and attribute transformation.
+func WithAttributeNormalization(rules NormalizationRules) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.normalize = rules
+		return clause
+	})
+}
+
+// WithAttributeValueLimit limits the number of distinct values of
+// each attribute key.  Once a key has reached the limit, new values
+// of that key are replaced by the string "_OTHER".  Values are
+// tracked exactly up to the limit and the first values seen are
+// retained for the lifetime of the instrument, so tracking memory is
+// bounded.  This is applied after WithKeys filtering and attribute
+// normalization.  Zero means unlimited.
+func WithAttributeValueLimit(limit uint32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.valueLimit = limit
+		return clause
+	})
+}
+
+// WithValueQuantization rounds each measurement to the nearest
+// multiple of quantum before it is aggregated, with ties rounded to
+// the even multiple.  For example, a quantum of 0.01 rounds to two
+// decimal places.  This prevents small variations in a measurement
+// from being reported as changes.  Integer measurements are rounded
+// the same way, so a quantum less than one has no effect on them.  A
+// quantum that is not positive and finite disables quantization.
+func WithValueQuantization(quantum float64) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.quantum = quantum
+		return clause
+	})
+}
+
+// WithRollupTotal records each measurement of a synchronous
+// instrument a second time, in the series with no attributes, so
+// that the view always includes a grand total.  Measurements whose
+// attributes are empty after WithKeys filtering are recorded once.
+// This has no effect on asynchronous instruments, whose observations
+// are not additive.
+func WithRollupTotal() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.rollup = true
+		return clause
+	})
+}
+
+// WithDeduplication discards a measurement of a synchronous
+// instrument when the same value was counted in the same series
+// within the window, for example when an at-least-once delivery
+// pipeline repeats a measurement.  A few recent distinct values are
+// remembered per series; beyond the window, measurements count
+// normally.  The comparison follows WithValueQuantization.  Zero
+// disables deduplication.
+func WithDeduplication(window time.Duration) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.dedup = window
+		return clause
+	})
+}
+
+// WithEmptyDeltaPoints reports a point for each series of a
+// synchronous instrument with delta temporality in an interval
+// without measurements, for example a histogram with zero count,
+// instead of omitting it.  This shows that the series is live but
+// idle.  A series is reported until it is no longer in use, usually
+// one interval after its last measurement.
+func WithEmptyDeltaPoints() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.emitEmpty = true
+		return clause
+	})
+}
+
+// WithLastUpdateTime records the time of the latest measurement of
+// each series, reported in the LastUpdate field of its points, so
+// that an exporter can detect series that have become stale.  The
+// time is taken from the measurement context, see
+// exemplar.ContextWithTimestamp, or else is the time of the update.
+// This costs one clock reading per measurement and a word of memory
+// per series.
+func WithLastUpdateTime() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.lastUpdate = true
+		return clause
+	})
+}
+
+// OverflowSeriesSuffix is appended to the name of an instrument for
+// its count of overflow attribute sets, see WithOverflowSeriesCount.
+const OverflowSeriesSuffix = ".overflow.series"
+
+// WithOverflowSeriesCount counts the distinct attribute sets whose
+// measurements a synchronous instrument records in the overflow
+// attribute set, once the cardinality limit is reached.  The count
+// is reported by an int64 gauge named with OverflowSeriesSuffix,
+// following the instrument, since the collection before for delta
+// temporality and since the start for cumulative temporality, so
+// that operators can see how many series the overflow masks.  It is
+// not reported while zero.  This costs a word of memory per counted
+// attribute set; the count stops at 16384.
+func WithOverflowSeriesCount() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.overflowCnt = true
+		return clause
+	})
+}
+
+// WithEitherTemporality compiles synchronous instruments to keep
+// both cumulative and delta state, so that a single instrument can be
+// collected with either temporality, see
+// TemporalityProducer.ProduceTemporality.  This is meant for
+// exporters with different temporality preferences sharing one
+// reader, and costs an extra aggregator per series.  Asynchronous
+// instruments report their configured temporality.
+func WithEitherTemporality() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.eitherTempo = true
+		return clause
+	})
+}
+
+// WithSuppressUnchanged omits the points of asynchronous series
+// reported with cumulative temporality whose value has not changed
+// since the previous collection.  This retains the prior value of
+// each series.  Some backends require every point in every interval,
+// so this is not the default.
+func WithSuppressUnchanged() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.suppress = true
+		return clause
+	})
+}
+
+// WithDeltaGauge reports asynchronous gauges collected with delta
+// temporality as the difference between successive observations of
+// each series, e.g., to derive a rate of change.  This is not
+// standard: OpenTelemetry gauges have no temporality, and without
+// this option they report the last value for either temporality.
+// As for monotonic counters, a decrease is taken to be a reset of
+// the source, in which case the difference is the current value, and
+// the first observation of a series is its difference from zero.
+// The points are of the Gauge aggregation with delta temporality.
+func WithDeltaGauge() ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.deltaGauge = true
+		return clause
+	})
+}
+
+// WithSeriesBudget evicts the least recently used series of
+// synchronous instruments with cumulative temporality once they hold
+// budget series, so that a churning set of attribute sets keeps the
+// active series rather than collapsing new ones into the overflow
+// attribute set.  A series is used while an accumulator references
+// it, and is evicted in favor of a new series only after its
+// references are released, in the order of the collection in which
+// they were.  An evicted series reports its final value in the next
+// collection; if its attribute set reappears, the series restarts with
+// the time it was created as its start time, as do other series
+// created after an eviction.  The budget is soft: when every series is
+// in use, new series are created up to the cardinality limit.
+// Synchronous delta temporality removes unused series each
+// collection, so this does not apply to it.
+func WithSeriesBudget(budget uint32) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.budget = budget
+		return clause
+	})
+}
+
+// FallbackPolicy determines how long a series uses the fallback
+// aggregation after its primary aggregation fails.
+type FallbackPolicy int
+
+const (
+	// FallbackForInterval returns to the primary aggregation
+	// after the series is next collected.  The fallback reports
+	// delta temporality, so that its points cover the intervals
+	// in which the primary failed.
+	FallbackForInterval FallbackPolicy = iota
+
+	// FallbackPermanently keeps the fallback aggregation for as
+	// long as the series is in use.
+	FallbackPermanently
+)
+
+// WithFallbackAggregation configures a second aggregation, for
+// example aggregation.MinMaxSumCountKind, that records measurements
+// of a synchronous instrument when the primary aggregation fails by
+// panicking.  The failure is reported through otel.Handle and the
+// series switches to the fallback according to the policy, so that
+// some signal is kept during an aggregator fault.  Fallback points
+// are reported under the primary name with FallbackSuffix, with the
+// fallback aggregation, and the measurements of a series go to
+// either the primary or the fallback.  This has no effect on
+// asynchronous instruments.
+func WithFallbackAggregation(kind aggregation.Kind, policy FallbackPolicy) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.fallback = kind
+		clause.fallbackPol = policy
+		return clause
+	})
+}
+
+// FallbackSuffix is appended to the name of a view's output for the
+// output of its fallback aggregation.
+const FallbackSuffix = ".fallback"
+
+// ShadowSuffix is appended to the name of a view's output for the
+// output of its shadow aggregation.
+const ShadowSuffix = ".shadow"
+
+// WithShadowAggregation configures a second aggregation that records
+// every measurement of the view, after the same attribute filtering
+// and normalization, and is reported as a separate instrument named
+// with ShadowSuffix.  This is meant for comparing a new aggregation or
+// aggregator configuration with the primary one on identical input,
+// for example using the compare package, without changing the primary
+// output.  The shadow does not use the view's fallback aggregation.
+func WithShadowAggregation(kind aggregation.Kind, acfg aggregator.Config) ClauseOption {
+	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
+		clause.shadow = kind
+		clause.shadowAcfg = acfg
+		return clause
+	})
+}
+
 func WithAggregatorConfig(acfg aggregator.Config) ClauseOption {
 	return clauseOptionFunction(func(clause ClauseConfig) ClauseConfig {
 		clause.acfg = acfg
@@ -159,6 +573,66 @@ func (c *ClauseConfig) Keys() []attribute.Key {
 	return c.keys
 }
 
+func (c *ClauseConfig) AttributeTransform() AttributeTransformFunction {
+	return c.transform
+}
+
+func (c *ClauseConfig) AttributeNormalization() NormalizationRules {
+	return c.normalize
+}
+
+func (c *ClauseConfig) AttributeValueLimit() uint32 {
+	return c.valueLimit
+}
+
+func (c *ClauseConfig) ValueQuantum() float64 {
+	return c.quantum
+}
+
+func (c *ClauseConfig) RollupTotal() bool {
+	return c.rollup
+}
+
+func (c *ClauseConfig) DeduplicationWindow() time.Duration {
+	return c.dedup
+}
+
+func (c *ClauseConfig) EmptyDeltaPoints() bool {
+	return c.emitEmpty
+}
+
+func (c *ClauseConfig) LastUpdateTime() bool {
+	return c.lastUpdate
+}
+
+func (c *ClauseConfig) OverflowSeriesCount() bool {
+	return c.overflowCnt
+}
+
+func (c *ClauseConfig) EitherTemporality() bool {
+	return c.eitherTempo
+}
+
+func (c *ClauseConfig) SuppressUnchanged() bool {
+	return c.suppress
+}
+
+func (c *ClauseConfig) DeltaGauge() bool {
+	return c.deltaGauge
+}
+
+func (c *ClauseConfig) SeriesBudget() uint32 {
+	return c.budget
+}
+
+func (c *ClauseConfig) FallbackAggregation() (aggregation.Kind, FallbackPolicy) {
+	return c.fallback, c.fallbackPol
+}
+
+func (c *ClauseConfig) ShadowAggregation() (aggregation.Kind, aggregator.Config) {
+	return c.shadow, c.shadowAcfg
+}
+
 func (c *ClauseConfig) Description() string {
 	return c.description
 }
@@ -0,0 +1,168 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package view // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizationRules is a set of rules for normalizing string
+// attribute values, and optionally keys, so that values which differ
+// only in their encoding are aggregated into the same series.  Rules are combined
+// using bitwise-or.  Regardless of how they are combined, the rules
+// are always applied in the order they are declared below, so the
+// result is deterministic.
+type NormalizationRules uint32
+
+const (
+	// NormalizeTrimSpace removes leading and trailing white
+	// space, as defined by Unicode.
+	NormalizeTrimSpace NormalizationRules = 1 << iota
+
+	// NormalizeNFC converts values to Unicode Normalization Form
+	// C, so that composed and decomposed forms of the same
+	// character compare equal.
+	NormalizeNFC
+
+	// NormalizeCaseFold applies Unicode case folding.
+	NormalizeCaseFold
+
+	// NormalizeBool replaces string values that spell a boolean
+	// with a boolean value.  Recognized spellings are "true",
+	// "t", "1", "false", "f", and "0", without regard to case.
+	// The empty string is not a boolean.  This applies to
+	// single-valued attributes, not to string slices.
+	NormalizeBool
+
+	// NormalizeKeys applies the string rules above, apart from
+	// NormalizeBool, to attribute keys as well as values, e.g.,
+	// so that NormalizeCaseFold merges "Content-Type" and
+	// "content-type".  When keys of a set become equal, the value
+	// of the one that sorted last before normalization is kept,
+	// so the result is deterministic.  Keys are normalized before
+	// the view's keys filter, which should name normalized keys.
+	NormalizeKeys
+)
+
+// Normalize returns the set with each string value, and each key
+// with NormalizeKeys, normalized by the rules.  The input is returned when no value is changed.
+func (r NormalizationRules) Normalize(kvs attribute.Set) attribute.Set {
+	if r == 0 {
+		return kvs
+	}
+	var out []attribute.KeyValue
+	for iter := kvs.Iter(); iter.Next(); {
+		idx, kv := iter.IndexedAttribute()
+		value, changed := r.normalizeValue(kv.Value)
+
+		key := kv.Key
+		if r&NormalizeKeys != 0 {
+			key = attribute.Key(r.normalizeString(string(kv.Key)))
+			changed = changed || key != kv.Key
+		}
+
+		if changed && out == nil {
+			out = make([]attribute.KeyValue, 0, kvs.Len())
+			out = append(out, kvs.ToSlice()[:idx]...)
+		}
+		if out != nil {
+			out = append(out, attribute.KeyValue{Key: key, Value: value})
+		}
+	}
+	if out == nil {
+		return kvs
+	}
+	return attribute.NewSet(out...)
+}
+
+// NormalizeValue returns the value normalized by the rules.
+func (r NormalizationRules) NormalizeValue(value attribute.Value) attribute.Value {
+	value, _ = r.normalizeValue(value)
+	return value
+}
+
+func (r NormalizationRules) normalizeValue(value attribute.Value) (attribute.Value, bool) {
+	switch value.Type() {
+	case attribute.STRING:
+		orig := value.AsString()
+		str := r.normalizeString(orig)
+
+		if r&NormalizeBool != 0 {
+			if b, ok := parseBool(str); ok {
+				return attribute.BoolValue(b), true
+			}
+		}
+		if str == orig {
+			return value, false
+		}
+		return attribute.StringValue(str), true
+
+	case attribute.STRINGSLICE:
+		orig := value.AsStringSlice()
+		var strs []string
+		for i, s := range orig {
+			ns := r.normalizeString(s)
+			if ns != s && strs == nil {
+				strs = make([]string, len(orig))
+				copy(strs, orig[:i])
+			}
+			if strs != nil {
+				strs[i] = ns
+			}
+		}
+		if strs == nil {
+			return value, false
+		}
+		return attribute.StringSliceValue(strs), true
+	}
+	return value, false
+}
+
+// normalizeString applies the string-to-string rules.
+func (r NormalizationRules) normalizeString(str string) string {
+	if r&NormalizeTrimSpace != 0 {
+		str = strings.TrimSpace(str)
+	}
+	if r&NormalizeNFC != 0 && !norm.NFC.IsNormalString(str) {
+		str = norm.NFC.String(str)
+	}
+	if r&NormalizeCaseFold != 0 {
+		// A Caser is stateful and may not be shared.
+		folded := cases.Fold().String(str)
+
+		// Case folding can produce a denormalized
+		// result, so normalize again.
+		if folded != str && r&NormalizeNFC != 0 && !norm.NFC.IsNormalString(folded) {
+			folded = norm.NFC.String(folded)
+		}
+		str = folded
+	}
+	return str
+}
+
+// parseBool recognizes boolean spellings for NormalizeBool.
+func parseBool(str string) (bool, bool) {
+	switch strings.ToLower(str) {
+	case "true", "t", "1":
+		return true, true
+	case "false", "f", "0":
+		return false, true
+	}
+	return false, false
+}
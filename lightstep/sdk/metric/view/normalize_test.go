@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package view
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	composedE   = "caf\u00e9" // é as one code point
+	decomposedE = "café"     // e followed by a combining acute accent
+)
+
+func TestNormalizeEachRule(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules NormalizationRules
+		in    attribute.Value
+		out   attribute.Value
+	}{
+		{"none", 0, attribute.StringValue(" A "), attribute.StringValue(" A ")},
+		{"trim", NormalizeTrimSpace, attribute.StringValue(" \tvalue\n"), attribute.StringValue("value")},
+		{"trim unicode", NormalizeTrimSpace, attribute.StringValue(" 日本　"), attribute.StringValue("日本")},
+		{"trim empty", NormalizeTrimSpace, attribute.StringValue("   "), attribute.StringValue("")},
+		{"nfc", NormalizeNFC, attribute.StringValue(decomposedE), attribute.StringValue(composedE)},
+		{"nfc unchanged", NormalizeNFC, attribute.StringValue(composedE), attribute.StringValue(composedE)},
+		{"fold", NormalizeCaseFold, attribute.StringValue("GET"), attribute.StringValue("get")},
+		{"fold multibyte", NormalizeCaseFold, attribute.StringValue("STRASSE Ω"), attribute.StringValue("strasse ω")},
+		{"fold sharp s", NormalizeCaseFold, attribute.StringValue("Straße"), attribute.StringValue("strasse")},
+		{"bool true", NormalizeBool, attribute.StringValue("True"), attribute.BoolValue(true)},
+		{"bool one", NormalizeBool, attribute.StringValue("1"), attribute.BoolValue(true)},
+		{"bool false", NormalizeBool, attribute.StringValue("FALSE"), attribute.BoolValue(false)},
+		{"bool empty", NormalizeBool, attribute.StringValue(""), attribute.StringValue("")},
+		{"bool other", NormalizeBool, attribute.StringValue("yes"), attribute.StringValue("yes")},
+		{"bool untrimmed", NormalizeBool, attribute.StringValue(" true"), attribute.StringValue(" true")},
+		{"not a string", NormalizeCaseFold | NormalizeBool, attribute.Int64Value(1), attribute.Int64Value(1)},
+		{"slice", NormalizeTrimSpace | NormalizeCaseFold | NormalizeBool,
+			attribute.StringSliceValue([]string{"a", " B", "true"}),
+			attribute.StringSliceValue([]string{"a", "b", "true"})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.out, tc.rules.NormalizeValue(tc.in))
+		})
+	}
+}
+
+func TestNormalizeCombined(t *testing.T) {
+	all := NormalizeTrimSpace | NormalizeNFC | NormalizeCaseFold | NormalizeBool
+
+	// Semantically-equal values collapse to a single value.
+	for _, in := range []string{composedE, decomposedE, " CAFÉ ", "CAFÉ\t"} {
+		require.Equal(t, attribute.StringValue(composedE), all.NormalizeValue(attribute.StringValue(in)), "%q", in)
+	}
+	for _, in := range []string{"true", "True", " TRUE ", "1", "t"} {
+		require.Equal(t, attribute.BoolValue(true), all.NormalizeValue(attribute.StringValue(in)), "%q", in)
+	}
+
+	// Normalization is idempotent.
+	for _, in := range []string{decomposedE, "  ǅ  ", "ΣΑΣ", " \u1e9b\u0323 "} {
+		once := all.NormalizeValue(attribute.StringValue(in))
+		require.Equal(t, once, all.NormalizeValue(once), "%q", in)
+	}
+}
+
+func TestNormalizeSet(t *testing.T) {
+	rules := NormalizeTrimSpace | NormalizeBool
+
+	// An unchanged set is returned as is.
+	in := attribute.NewSet(attribute.String("a", "x"), attribute.Bool("b", true))
+	require.Equal(t, in, rules.Normalize(in))
+
+	in1 := attribute.NewSet(attribute.String("a", " x"), attribute.String("b", "True"), attribute.Int("c", 1))
+	in2 := attribute.NewSet(attribute.String("a", "x "), attribute.String("b", "1"), attribute.Int("c", 1))
+	exp := attribute.NewSet(attribute.String("a", "x"), attribute.Bool("b", true), attribute.Int("c", 1))
+
+	require.Equal(t, exp, rules.Normalize(in1))
+	require.Equal(t, exp, rules.Normalize(in2))
+	require.Equal(t, *attribute.EmptySet(), rules.Normalize(*attribute.EmptySet()))
+}
+
+func TestNormalizeKeys(t *testing.T) {
+	rules := NormalizeCaseFold | NormalizeKeys
+
+	in1 := attribute.NewSet(attribute.String("Content-Type", "text/html"), attribute.Int("Status", 200))
+	in2 := attribute.NewSet(attribute.String("content-type", "TEXT/HTML"), attribute.Int("status", 200))
+	exp := attribute.NewSet(attribute.String("content-type", "text/html"), attribute.Int("status", 200))
+
+	require.Equal(t, exp, rules.Normalize(in1))
+	require.Equal(t, exp, rules.Normalize(in2))
+
+	// Keys are not normalized without the rule.
+	require.Equal(t, attribute.NewSet(attribute.String("Content-Type", "text/html"), attribute.Int("Status", 200)), NormalizeCaseFold.Normalize(in1))
+
+	// Keys that become equal keep the value of the key that
+	// sorted last.
+	both := attribute.NewSet(attribute.String("Content-Type", "a"), attribute.String("content-type", "b"))
+	require.Equal(t, attribute.NewSet(attribute.String("content-type", "b")), rules.Normalize(both))
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	set := attribute.NewSet(
+		attribute.String("Content-Type", "text/html"),
+		attribute.String("http.method", "GET"),
+		attribute.Int("http.status_code", 200),
+	)
+	for _, bc := range []struct {
+		name  string
+		rules NormalizationRules
+	}{
+		{"none", 0},
+		{"values", NormalizeCaseFold},
+		{"keys", NormalizeCaseFold | NormalizeKeys},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = bc.rules.Normalize(set)
+			}
+		})
+	}
+}
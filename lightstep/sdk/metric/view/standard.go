@@ -15,6 +15,9 @@
 package view // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 
 import (
+	"math"
+
+	histostruct "github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/sdkinstrument"
@@ -84,3 +87,35 @@ func StandardConfigForPerformance(perf sdkinstrument.Performance) func(ik sdkins
 		}
 	}
 }
+
+// histogramUnitScale is the scale of the curated histogram units, at
+// which bucket boundaries are about 4.4% apart.
+const histogramUnitScale = 4
+
+// histogramUnitRanges are the curated ranges of values expected in
+// each unit, from sub-millisecond to minutes for time and from bytes
+// to gigabytes for bytes.
+var histogramUnitRanges = map[string][2]float64{
+	"s":    {1e-4, 1e2},
+	"ms":   {1e-1, 1e5},
+	"us":   {1e2, 1e8},
+	"ns":   {1e5, 1e11},
+	"By":   {1, 1e9},
+	"KiBy": {1.0 / 1024, 1e9 / 1024},
+	"MiBy": {1.0 / (1024 * 1024), 1e9 / (1024 * 1024)},
+}
+
+// StandardHistogramUnit returns a histogram configuration for the
+// recognized time and byte units.  The exponential histogram has no
+// fixed boundaries; instead its size is chosen so that the expected
+// range of the unit is covered at a fixed resolution.  Values outside
+// the range are still counted, at reduced resolution.
+func StandardHistogramUnit(unit string) (histostruct.Config, bool) {
+	r, ok := histogramUnitRanges[unit]
+	if !ok {
+		return histostruct.Config{}, false
+	}
+	// The number of buckets of base 2**(2**-scale) spanning r.
+	size := math.Ceil(math.Log2(r[1]/r[0]) * (1 << histogramUnitScale))
+	return histostruct.NewConfig(histostruct.WithMaxSize(int32(size))), true
+}
@@ -15,6 +15,9 @@
 package view // import "github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/view"
 
 import (
+	"time"
+
+	histostruct "github.com/lightstep/go-expohisto/structure"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/aggregator/aggregation"
 	"github.com/lightstep/otel-launcher-go/lightstep/sdk/metric/number"
@@ -29,11 +32,47 @@ import (
 //   - Aggregation Kind
 //   - Aggregation Temporality
 //   - Aggregator configuration for int64, float64
+//   - Histogram configuration by unit
 type Config struct {
 	Clauses  []ClauseConfig
 	Defaults DefaultConfig
+
+	// MaxPointsPerCollection limits the number of points in
+	// each collection, or zero for no limit.
+	MaxPointsPerCollection int
+
+	// TimestampRounding is the granularity of point timestamps,
+	// or zero for no rounding.
+	TimestampRounding time.Duration
+
+	// CollisionPolicy determines how outputs with the same name
+	// that cannot share a stream are compiled.
+	CollisionPolicy CollisionPolicy
 }
 
+// CollisionPolicy determines how the compiler treats a view output
+// with the same name as an earlier one that it cannot share a stream
+// with, for example because it has a different aggregation.
+type CollisionPolicy int
+
+const (
+	// CollisionError reports a duplicate instrument conflict,
+	// and both outputs are produced under the same name.
+	CollisionError CollisionPolicy = iota
+
+	// CollisionMerge shares the earlier output when the two have
+	// the same name, number kind, unit, aggregation and
+	// synchronousness, in which case the aggregator
+	// configuration and attribute keys of the earlier output
+	// apply to both.  Otherwise, it behaves as CollisionError.
+	CollisionMerge
+
+	// CollisionSuffix produces the later output under its name
+	// with the first numeric suffix, ".1", ".2", and so on, that
+	// is unused or has an output it can share.
+	CollisionSuffix
+)
+
 // DefaultConfig contains configurable aspects that apply to all
 // instruments in a View.
 type DefaultConfig struct {
@@ -43,6 +82,10 @@ type DefaultConfig struct {
 		Int64       aggregator.Config
 		Float64     aggregator.Config
 	}
+
+	// HistogramUnit, when set, selects the histogram
+	// configuration for instruments with a recognized unit.
+	HistogramUnit aggregator.HistogramUnitSelector
 }
 
 // Aggregation returns the default aggregation.Kind for each instrument kind.
@@ -63,6 +106,15 @@ func (d *DefaultConfig) AggregationConfig(k sdkinstrument.Kind, nk number.Kind)
 	return d.ByInstrumentKind[k].Float64
 }
 
+// HistogramConfig returns the default histogram configuration for a
+// unit and true, or false when the unit is not recognized.
+func (d *DefaultConfig) HistogramConfig(unit string) (histostruct.Config, bool) {
+	if d.HistogramUnit == nil {
+		return histostruct.Config{}, false
+	}
+	return d.HistogramUnit(unit)
+}
+
 // WithClause adds a clause to the Views configuration.
 func WithClause(options ...ClauseOption) Option {
 	return optionFunction(func(cfg Config) Config {
@@ -116,6 +168,74 @@ func WithDefaultAggregationConfigSelector(d aggregator.ConfigSelector) Option {
 	})
 }
 
+// WithDefaultHistogramUnitSelector configures the default histogram
+// configuration for instruments with a recognized unit, for example
+// StandardHistogramUnit.  It applies when neither the view clause nor
+// a hint configures the histogram.  Otherwise, and for unrecognized
+// units, the default aggregator.Config applies.  This overwrites
+// previous settings of the same option.
+func WithDefaultHistogramUnitSelector(d aggregator.HistogramUnitSelector) Option {
+	return optionFunction(func(cfg Config) Config {
+		cfg.Defaults.HistogramUnit = d
+		return cfg
+	})
+}
+
+// WithMaxPointsPerCollection limits the number of points produced by
+// each collection of the reader, to protect a destination with a
+// limit on the size of its requests.  When the limit is exceeded:
+//
+//   - Delta temporality points are kept first, since they cannot be
+//     reported again.  An instrument with delta points keeps at
+//     least one, so when more instruments than the limit have delta
+//     points, the rest carry their points into the next collection,
+//     those carried by the most consecutive collections reported
+//     first.  If the points kept exceed the limit themselves, each
+//     instrument is given an equal share of the limit and its excess
+//     points are folded into its overflow attribute set, so no data
+//     is lost.
+//   - Cumulative temporality points fill the remainder, those that
+//     changed since they were last reported first, then those
+//     deferred by the most consecutive collections, then the others
+//     in output order, so that every series is reported.  The rest
+//     are deferred to a later collection, which reports their values
+//     at that time.
+//
+// The number of deferred, carried, and folded points is reported
+// through otel.Handle.  The output of
+// ManualReader.ProduceWithTemporality is not limited.
+func WithMaxPointsPerCollection(limit int) Option {
+	return optionFunction(func(cfg Config) Config {
+		cfg.MaxPointsPerCollection = limit
+		return cfg
+	})
+}
+
+// WithTimestampRounding rounds the start and end timestamps of the
+// reader's points to the nearest multiple of granularity, for
+// destinations that work better with fewer distinct timestamps.  The
+// unrounded times are kept for computing the next interval, and each
+// is rounded the same way every time it is used, so the delta
+// intervals of successive collections remain contiguous.  Collections
+// closer together than the granularity may produce intervals with
+// equal start and end.  Exemplar timestamps are not rounded.
+func WithTimestampRounding(granularity time.Duration) Option {
+	return optionFunction(func(cfg Config) Config {
+		cfg.TimestampRounding = granularity
+		return cfg
+	})
+}
+
+// WithCollisionPolicy configures how outputs with the same name that
+// cannot share a stream are compiled.  The default is
+// CollisionError.
+func WithCollisionPolicy(policy CollisionPolicy) Option {
+	return optionFunction(func(cfg Config) Config {
+		cfg.CollisionPolicy = policy
+		return cfg
+	})
+}
+
 // Option applies a configuration option value to a view Config.
 type Option interface {
 	apply(Config) Config
@@ -61,6 +61,32 @@ func (v *Views) checkTemporality(err error, tempo *aggregation.Temporality, def
 	return err
 }
 
+func (v *Views) checkCustom(err error, agg *aggregation.Kind, def aggregation.Kind, acfgs ...*aggregator.Config) error {
+	if *agg != aggregation.CustomKind {
+		return err
+	}
+	for _, acfg := range acfgs {
+		if acfg.Custom == nil {
+			err = multierr.Append(err, fmt.Errorf("custom aggregation without a custom configuration"))
+			*agg = def
+			return err
+		}
+	}
+	return err
+}
+
+func (v *Views) checkExplicit(err error, acfg *aggregator.Config) error {
+	if acfg.Explicit == nil {
+		return err
+	}
+	valid, newErr := acfg.Explicit.Validate()
+	if newErr != nil {
+		err = multierr.Append(err, newErr)
+		acfg.Explicit = &valid
+	}
+	return err
+}
+
 func (v *Views) checkAggConfig(err error, acfg *aggregator.Config) error {
 	var newErr error
 	// Use performance-specific cardinality defaults.
@@ -91,11 +117,20 @@ func Validate(v *Views) (*Views, error) {
 
 	// Make a deep copy
 	valid := &Views{
-		Name: v.Name,
+		Name:        v.Name,
+		Performance: v.Performance,
 	}
 
 	valid.Clauses = make([]ClauseConfig, len(v.Clauses))
 	valid.Defaults = v.Defaults
+	valid.MaxPointsPerCollection = v.MaxPointsPerCollection
+	valid.TimestampRounding = v.TimestampRounding
+	valid.CollisionPolicy = v.CollisionPolicy
+
+	if valid.CollisionPolicy < CollisionError || valid.CollisionPolicy > CollisionSuffix {
+		err = multierr.Append(err, fmt.Errorf("invalid collision policy: %d", valid.CollisionPolicy))
+		valid.CollisionPolicy = CollisionError
+	}
 
 	for i := range valid.Clauses {
 		valid.Clauses[i] = v.Clauses[i]
@@ -109,6 +144,9 @@ func Validate(v *Views) (*Views, error) {
 		err = v.checkTemporality(err, &valid.Defaults.ByInstrumentKind[i].Temporality, StandardTemporality(kind))
 		err = v.checkAggConfig(err, &valid.Defaults.ByInstrumentKind[i].Int64)
 		err = v.checkAggConfig(err, &valid.Defaults.ByInstrumentKind[i].Float64)
+		err = v.checkCustom(err, &valid.Defaults.ByInstrumentKind[i].Aggregation, StandardAggregationKind(kind), &valid.Defaults.ByInstrumentKind[i].Int64, &valid.Defaults.ByInstrumentKind[i].Float64)
+		err = v.checkExplicit(err, &valid.Defaults.ByInstrumentKind[i].Int64)
+		err = v.checkExplicit(err, &valid.Defaults.ByInstrumentKind[i].Float64)
 	}
 
 	for i := range valid.Clauses {
@@ -116,6 +154,16 @@ func Validate(v *Views) (*Views, error) {
 
 		err = v.checkAggregation(err, &clause.aggregation, aggregation.UndefinedKind)
 		err = v.checkAggConfig(err, &clause.acfg)
+		err = v.checkCustom(err, &clause.aggregation, aggregation.UndefinedKind, &clause.acfg)
+		err = v.checkExplicit(err, &clause.acfg)
+		err = v.checkAggregation(err, &clause.fallback, aggregation.UndefinedKind)
+
+		if clause.shadow != aggregation.UndefinedKind {
+			err = v.checkAggregation(err, &clause.shadow, aggregation.UndefinedKind)
+			err = v.checkAggConfig(err, &clause.shadowAcfg)
+			err = v.checkCustom(err, &clause.shadow, aggregation.UndefinedKind, &clause.shadowAcfg)
+			err = v.checkExplicit(err, &clause.shadowAcfg)
+		}
 
 		if clause.instrumentName != "" && clause.instrumentNameRegexp != nil {
 			err = multierr.Append(err, fmt.Errorf("view has instrument name and regexp matches"))
@@ -16,6 +16,7 @@ package view
 
 import (
 	"encoding/json"
+	"math"
 	"regexp"
 	"testing"
 
@@ -155,6 +156,60 @@ func TestEmptyKeyString(t *testing.T) {
 	require.Contains(t, err.Error(), "view has empty string in keys")
 }
 
+func TestCustomAggregationWithoutConfig(t *testing.T) {
+	views := New("test", safePerf, WithClause(
+		WithCustomAggregation(nil),
+	))
+
+	valid, err := Validate(views)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "custom aggregation without a custom configuration")
+	require.Equal(t, aggregation.UndefinedKind, valid.Clauses[0].Aggregation())
+}
+
+func TestExplicitHistogramBoundaries(t *testing.T) {
+	views := New("test", safePerf, WithClause(
+		WithExplicitHistogram(10, 1, math.NaN(), 5, 5),
+	))
+
+	valid, err := Validate(views)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "explicit histogram boundaries are not finite and increasing")
+	require.Equal(t, aggregation.ExplicitHistogramKind, valid.Clauses[0].Aggregation())
+	require.Equal(t, []float64{1, 5, 10}, valid.Clauses[0].AggregatorConfig().Explicit.Boundaries)
+}
+
+func TestInvalidCollisionPolicy(t *testing.T) {
+	views := New("test", safePerf, WithCollisionPolicy(CollisionPolicy(-1)))
+
+	valid, err := Validate(views)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid collision policy")
+	require.Equal(t, CollisionError, valid.CollisionPolicy)
+
+	valid, err = Validate(New("test", safePerf, WithCollisionPolicy(CollisionSuffix)))
+	require.NoError(t, err)
+	require.Equal(t, CollisionSuffix, valid.CollisionPolicy)
+}
+
+func TestHistogramZeroThreshold(t *testing.T) {
+	valid, err := Validate(New("test", safePerf, WithClause(
+		WithHistogramZeroThreshold(-1),
+	)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid histogram zero threshold")
+	require.Equal(t, 0.0, valid.Clauses[0].AggregatorConfig().HistogramZeroThreshold)
+
+	valid, err = Validate(New("test", safePerf, WithClause(
+		WithHistogramZeroThreshold(0.001),
+	)))
+	require.NoError(t, err)
+	require.Equal(t, 0.001, valid.Clauses[0].AggregatorConfig().HistogramZeroThreshold)
+}
+
 func TestStandardTemporality(t *testing.T) {
 	views := New("test", safePerf,
 		WithDefaultAggregationTemporalitySelector(StandardTemporality),
@@ -265,3 +320,26 @@ func TestHintEncoding(t *testing.T) {
 		},
 	}, hint)
 }
+
+func TestHistogramLimits(t *testing.T) {
+	valid, err := Validate(New("test", safePerf, WithClause(
+		WithHistogramMaxScale(30),
+		WithHistogramMaxSize(-1),
+	)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid histogram max scale")
+	acfg := valid.Clauses[0].AggregatorConfig()
+	require.True(t, acfg.HistogramLimitScale)
+	require.Equal(t, int32(20), acfg.HistogramMaxScale)
+	require.Equal(t, histogram.NewConfig(histogram.WithMaxSize(histogram.DefaultMaxSize)), acfg.Histogram)
+
+	valid, err = Validate(New("test", safePerf, WithClause(
+		WithHistogramMaxScale(0),
+		WithHistogramMaxSize(40),
+	)))
+	require.NoError(t, err)
+	acfg = valid.Clauses[0].AggregatorConfig()
+	require.True(t, acfg.HistogramLimitScale)
+	require.Equal(t, int32(0), acfg.HistogramMaxScale)
+	require.Equal(t, histogram.NewConfig(histogram.WithMaxSize(40)), acfg.Histogram)
+}